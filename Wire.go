@@ -21,10 +21,12 @@
 package main
 
 import (
+	"github.com/devtron-labs/devtron/api/apiToken"
 	appStoreRestHandler "github.com/devtron-labs/devtron/api/appStore"
 	appStoreDeployment "github.com/devtron-labs/devtron/api/appStore/deployment"
 	appStoreDiscover "github.com/devtron-labs/devtron/api/appStore/discover"
 	appStoreValues "github.com/devtron-labs/devtron/api/appStore/values"
+	"github.com/devtron-labs/devtron/api/auditLog"
 	chartRepo "github.com/devtron-labs/devtron/api/chartRepo"
 	"github.com/devtron-labs/devtron/api/cluster"
 	"github.com/devtron-labs/devtron/api/connector"
@@ -71,19 +73,25 @@ import (
 	"github.com/devtron-labs/devtron/pkg/appWorkflow"
 	"github.com/devtron-labs/devtron/pkg/attributes"
 	"github.com/devtron-labs/devtron/pkg/commonService"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
+	repository3 "github.com/devtron-labs/devtron/pkg/configHistory/repository"
 	delete2 "github.com/devtron-labs/devtron/pkg/delete"
 	"github.com/devtron-labs/devtron/pkg/deploymentGroup"
+	"github.com/devtron-labs/devtron/pkg/deploymentPolicy"
 	"github.com/devtron-labs/devtron/pkg/dex"
 	"github.com/devtron-labs/devtron/pkg/event"
 	"github.com/devtron-labs/devtron/pkg/git"
 	"github.com/devtron-labs/devtron/pkg/gitops"
+	"github.com/devtron-labs/devtron/pkg/imageSigning"
 	jira2 "github.com/devtron-labs/devtron/pkg/jira"
 	"github.com/devtron-labs/devtron/pkg/notifier"
 	"github.com/devtron-labs/devtron/pkg/pipeline"
 	"github.com/devtron-labs/devtron/pkg/projectManagementService/jira"
+	"github.com/devtron-labs/devtron/pkg/sbom"
 	"github.com/devtron-labs/devtron/pkg/security"
 	"github.com/devtron-labs/devtron/pkg/sql"
 	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/devtron-labs/devtron/pkg/vault"
 	util2 "github.com/devtron-labs/devtron/util"
 	"github.com/devtron-labs/devtron/util/k8s"
 	"github.com/devtron-labs/devtron/util/rbac"
@@ -101,6 +109,8 @@ func InitializeApp() (*App, error) {
 		user.UserWireSet,
 		sso.SsoConfigWireSet,
 		cluster.ClusterWireSet,
+		auditLog.AuditLogWireSet,
+		apiToken.ApiTokenWireSet,
 		dashboard.DashboardWireSet,
 		client.HelmAppWireSet,
 		k8s.K8sApplicationWireSet,
@@ -151,6 +161,8 @@ func InitializeApp() (*App, error) {
 
 		app.NewAppService,
 		wire.Bind(new(app.AppService), new(*app.AppServiceImpl)),
+		app.NewChartTemplateRendererImpl,
+		wire.Bind(new(app.ChartTemplateRenderer), new(*app.ChartTemplateRendererImpl)),
 
 		bulkUpdate.NewBulkUpdateRepository,
 		wire.Bind(new(bulkUpdate.BulkUpdateRepository), new(*bulkUpdate.BulkUpdateRepositoryImpl)),
@@ -232,6 +244,10 @@ func InitializeApp() (*App, error) {
 		wire.Bind(new(eClient.EventClient), new(*eClient.EventRESTClientImpl)),
 
 		util3.NewTokenCache,
+		repository.NewScheduledJobLeaseRepositoryImpl,
+		wire.Bind(new(repository.ScheduledJobLeaseRepository), new(*repository.ScheduledJobLeaseRepositoryImpl)),
+		util3.NewScheduledJobRunnerImpl,
+		wire.Bind(new(util3.ScheduledJobRunner), new(*util3.ScheduledJobRunnerImpl)),
 
 		eClient.NewEventSimpleFactoryImpl,
 		wire.Bind(new(eClient.EventFactory), new(*eClient.EventSimpleFactoryImpl)),
@@ -335,6 +351,8 @@ func InitializeApp() (*App, error) {
 
 		pipeline.NewCiServiceImpl,
 		wire.Bind(new(pipeline.CiService), new(*pipeline.CiServiceImpl)),
+		pipeline.NewCiScheduledTriggerServiceImpl,
+		wire.Bind(new(pipeline.CiScheduledTriggerService), new(*pipeline.CiScheduledTriggerServiceImpl)),
 
 		pipelineConfig.NewCiWorkflowRepositoryImpl,
 		wire.Bind(new(pipelineConfig.CiWorkflowRepository), new(*pipelineConfig.CiWorkflowRepositoryImpl)),
@@ -402,6 +420,24 @@ func InitializeApp() (*App, error) {
 		repository.NewSESNotificationRepositoryImpl,
 		wire.Bind(new(repository.SESNotificationRepository), new(*repository.SESNotificationRepositoryImpl)),
 
+		notifier.NewWebhookNotificationServiceImpl,
+		wire.Bind(new(notifier.WebhookNotificationService), new(*notifier.WebhookNotificationServiceImpl)),
+
+		repository.NewWebhookNotificationRepositoryImpl,
+		wire.Bind(new(repository.WebhookNotificationRepository), new(*repository.WebhookNotificationRepositoryImpl)),
+
+		notifier.NewSMTPNotificationServiceImpl,
+		wire.Bind(new(notifier.SMTPNotificationService), new(*notifier.SMTPNotificationServiceImpl)),
+
+		repository.NewSMTPNotificationRepositoryImpl,
+		wire.Bind(new(repository.SMTPNotificationRepository), new(*repository.SMTPNotificationRepositoryImpl)),
+
+		notifier.NewNotificationSubscriptionServiceImpl,
+		wire.Bind(new(notifier.NotificationSubscriptionService), new(*notifier.NotificationSubscriptionServiceImpl)),
+
+		repository.NewNotificationSubscriptionRepositoryImpl,
+		wire.Bind(new(repository.NotificationSubscriptionRepository), new(*repository.NotificationSubscriptionRepositoryImpl)),
+
 		notifier.NewNotificationConfigBuilderImpl,
 		wire.Bind(new(notifier.NotificationConfigBuilder), new(*notifier.NotificationConfigBuilderImpl)),
 
@@ -461,6 +497,28 @@ func InitializeApp() (*App, error) {
 
 		pipeline.NewWorkflowDagExecutorImpl,
 		wire.Bind(new(pipeline.WorkflowDagExecutor), new(*pipeline.WorkflowDagExecutorImpl)),
+		pipelineConfig.NewDeploymentApprovalRepositoryImpl,
+		wire.Bind(new(pipelineConfig.DeploymentApprovalRepository), new(*pipelineConfig.DeploymentApprovalRepositoryImpl)),
+		pipeline.NewDeploymentApprovalServiceImpl,
+		wire.Bind(new(pipeline.DeploymentApprovalService), new(*pipeline.DeploymentApprovalServiceImpl)),
+		pipelineConfig.NewDeploymentAutoRollbackRepositoryImpl,
+		wire.Bind(new(pipelineConfig.DeploymentAutoRollbackRepository), new(*pipelineConfig.DeploymentAutoRollbackRepositoryImpl)),
+		pipeline.NewDeploymentAutoRollbackServiceImpl,
+		wire.Bind(new(pipeline.DeploymentAutoRollbackService), new(*pipeline.DeploymentAutoRollbackServiceImpl)),
+		pipeline.NewDeploymentDryRunServiceImpl,
+		wire.Bind(new(pipeline.DeploymentDryRunService), new(*pipeline.DeploymentDryRunServiceImpl)),
+		pipelineConfig.NewCanaryAnalysisRepositoryImpl,
+		wire.Bind(new(pipelineConfig.CanaryAnalysisRepository), new(*pipelineConfig.CanaryAnalysisRepositoryImpl)),
+		pipeline.NewCanaryAnalysisServiceImpl,
+		wire.Bind(new(pipeline.CanaryAnalysisService), new(*pipeline.CanaryAnalysisServiceImpl)),
+		pipelineConfig.NewDeploymentWindowRepositoryImpl,
+		wire.Bind(new(pipelineConfig.DeploymentWindowRepository), new(*pipelineConfig.DeploymentWindowRepositoryImpl)),
+		pipeline.NewDeploymentWindowServiceImpl,
+		wire.Bind(new(pipeline.DeploymentWindowService), new(*pipeline.DeploymentWindowServiceImpl)),
+		restHandler.NewDeploymentWindowRestHandlerImpl,
+		wire.Bind(new(restHandler.DeploymentWindowRestHandler), new(*restHandler.DeploymentWindowRestHandlerImpl)),
+		router.NewDeploymentWindowRouterImpl,
+		wire.Bind(new(router.DeploymentWindowRouter), new(*router.DeploymentWindowRouterImpl)),
 		appClone.NewAppCloneServiceImpl,
 		wire.Bind(new(appClone.AppCloneService), new(*appClone.AppCloneServiceImpl)),
 		pipeline.GetCdConfig,
@@ -481,7 +539,6 @@ func InitializeApp() (*App, error) {
 		pubsub2.NewNatsPublishClientImpl,
 		wire.Bind(new(pubsub2.NatsPublishClient), new(*pubsub2.NatsPublishClientImpl)),
 
-
 		//Batch actions
 		batch.NewWorkflowActionImpl,
 		wire.Bind(new(batch.WorkflowAction), new(*batch.WorkflowActionImpl)),
@@ -525,10 +582,18 @@ func InitializeApp() (*App, error) {
 		wire.Bind(new(restHandler.ImageScanRestHandler), new(*restHandler.ImageScanRestHandlerImpl)),
 		security.NewImageScanServiceImpl,
 		wire.Bind(new(security.ImageScanService), new(*security.ImageScanServiceImpl)),
+		security.NewVulnerabilityTrendServiceImpl,
+		wire.Bind(new(security.VulnerabilityTrendService), new(*security.VulnerabilityTrendServiceImpl)),
+		security2.NewVulnerabilityTrendRepositoryImpl,
+		wire.Bind(new(security2.VulnerabilityTrendRepository), new(*security2.VulnerabilityTrendRepositoryImpl)),
 		security2.NewImageScanHistoryRepositoryImpl,
 		wire.Bind(new(security2.ImageScanHistoryRepository), new(*security2.ImageScanHistoryRepositoryImpl)),
 		security2.NewImageScanResultRepositoryImpl,
 		wire.Bind(new(security2.ImageScanResultRepository), new(*security2.ImageScanResultRepositoryImpl)),
+		security2.NewCiArtifactSbomRepositoryImpl,
+		wire.Bind(new(security2.CiArtifactSbomRepository), new(*security2.CiArtifactSbomRepositoryImpl)),
+		sbom.NewSbomServiceImpl,
+		wire.Bind(new(sbom.SbomService), new(*sbom.SbomServiceImpl)),
 		security2.NewImageScanObjectMetaRepositoryImpl,
 		wire.Bind(new(security2.ImageScanObjectMetaRepository), new(*security2.ImageScanObjectMetaRepositoryImpl)),
 		security2.NewCveStoreRepositoryImpl,
@@ -539,10 +604,86 @@ func InitializeApp() (*App, error) {
 		wire.Bind(new(router.PolicyRouter), new(*router.PolicyRouterImpl)),
 		restHandler.NewPolicyRestHandlerImpl,
 		wire.Bind(new(restHandler.PolicyRestHandler), new(*restHandler.PolicyRestHandlerImpl)),
+		security.GetImageScannerConfig,
 		security.NewPolicyServiceImpl,
 		wire.Bind(new(security.PolicyService), new(*security.PolicyServiceImpl)),
 		security2.NewPolicyRepositoryImpl,
 		wire.Bind(new(security2.CvePolicyRepository), new(*security2.CvePolicyRepositoryImpl)),
+		router.NewDeploymentPolicyRouterImpl,
+		wire.Bind(new(router.DeploymentPolicyRouter), new(*router.DeploymentPolicyRouterImpl)),
+		restHandler.NewDeploymentPolicyRestHandlerImpl,
+		wire.Bind(new(restHandler.DeploymentPolicyRestHandler), new(*restHandler.DeploymentPolicyRestHandlerImpl)),
+		deploymentPolicy.NewDeploymentPolicyServiceImpl,
+		wire.Bind(new(deploymentPolicy.DeploymentPolicyService), new(*deploymentPolicy.DeploymentPolicyServiceImpl)),
+		security2.NewDeploymentPolicyRepositoryImpl,
+		wire.Bind(new(security2.DeploymentPolicyRepository), new(*security2.DeploymentPolicyRepositoryImpl)),
+
+		router.NewImageSigningRouterImpl,
+		wire.Bind(new(router.ImageSigningRouter), new(*router.ImageSigningRouterImpl)),
+		restHandler.NewImageSigningRestHandlerImpl,
+		wire.Bind(new(restHandler.ImageSigningRestHandler), new(*restHandler.ImageSigningRestHandlerImpl)),
+		imageSigning.NewImageSigningServiceImpl,
+		wire.Bind(new(imageSigning.ImageSigningService), new(*imageSigning.ImageSigningServiceImpl)),
+		repository.NewImageSigningKeyRepositoryImpl,
+		wire.Bind(new(repository.ImageSigningKeyRepository), new(*repository.ImageSigningKeyRepositoryImpl)),
+		security2.NewImageSignatureRepositoryImpl,
+		wire.Bind(new(security2.ImageSignatureRepository), new(*security2.ImageSignatureRepositoryImpl)),
+
+		router.NewCveExceptionRouterImpl,
+		wire.Bind(new(router.CveExceptionRouter), new(*router.CveExceptionRouterImpl)),
+		restHandler.NewCveExceptionRestHandlerImpl,
+		wire.Bind(new(restHandler.CveExceptionRestHandler), new(*restHandler.CveExceptionRestHandlerImpl)),
+		security.NewCveExceptionServiceImpl,
+		wire.Bind(new(security.CveExceptionService), new(*security.CveExceptionServiceImpl)),
+		security.NewImageRescanServiceImpl,
+		wire.Bind(new(security.ImageRescanService), new(*security.ImageRescanServiceImpl)),
+		security2.NewCveExceptionRepositoryImpl,
+		wire.Bind(new(security2.CveExceptionRepository), new(*security2.CveExceptionRepositoryImpl)),
+
+		router.NewVaultRouterImpl,
+		wire.Bind(new(router.VaultRouter), new(*router.VaultRouterImpl)),
+		restHandler.NewVaultRestHandlerImpl,
+		wire.Bind(new(restHandler.VaultRestHandler), new(*restHandler.VaultRestHandlerImpl)),
+		vault.NewVaultServiceImpl,
+		wire.Bind(new(vault.VaultService), new(*vault.VaultServiceImpl)),
+		repository.NewVaultConfigRepositoryImpl,
+		wire.Bind(new(repository.VaultConfigRepository), new(*repository.VaultConfigRepositoryImpl)),
+
+		router.NewSecretRotationRouterImpl,
+		wire.Bind(new(router.SecretRotationRouter), new(*router.SecretRotationRouterImpl)),
+		restHandler.NewSecretRotationRestHandlerImpl,
+		wire.Bind(new(restHandler.SecretRotationRestHandler), new(*restHandler.SecretRotationRestHandlerImpl)),
+		pipeline.NewSecretRotationServiceImpl,
+		wire.Bind(new(pipeline.SecretRotationService), new(*pipeline.SecretRotationServiceImpl)),
+		pipelineConfig.NewSecretRotationRepositoryImpl,
+		wire.Bind(new(pipelineConfig.SecretRotationRepository), new(*pipelineConfig.SecretRotationRepositoryImpl)),
+
+		router.NewArtifactRetentionRouterImpl,
+		wire.Bind(new(router.ArtifactRetentionRouter), new(*router.ArtifactRetentionRouterImpl)),
+		restHandler.NewArtifactRetentionRestHandlerImpl,
+		wire.Bind(new(restHandler.ArtifactRetentionRestHandler), new(*restHandler.ArtifactRetentionRestHandlerImpl)),
+		pipeline.NewArtifactRetentionServiceImpl,
+		wire.Bind(new(pipeline.ArtifactRetentionService), new(*pipeline.ArtifactRetentionServiceImpl)),
+		pipelineConfig.NewArtifactRetentionRepositoryImpl,
+		wire.Bind(new(pipelineConfig.ArtifactRetentionRepository), new(*pipelineConfig.ArtifactRetentionRepositoryImpl)),
+
+		router.NewLogStreamRouterImpl,
+		wire.Bind(new(router.LogStreamRouter), new(*router.LogStreamRouterImpl)),
+		restHandler.NewLogStreamRestHandlerImpl,
+		wire.Bind(new(restHandler.LogStreamRestHandler), new(*restHandler.LogStreamRestHandlerImpl)),
+		pipeline.NewLogStreamServiceImpl,
+		wire.Bind(new(pipeline.LogStreamService), new(*pipeline.LogStreamServiceImpl)),
+
+		router.NewConfigHistoryRouterImpl,
+		wire.Bind(new(router.ConfigHistoryRouter), new(*router.ConfigHistoryRouterImpl)),
+		restHandler.NewConfigHistoryRestHandlerImpl,
+		wire.Bind(new(restHandler.ConfigHistoryRestHandler), new(*restHandler.ConfigHistoryRestHandlerImpl)),
+		pipeline.NewConfigHistoryRestoreServiceImpl,
+		wire.Bind(new(pipeline.ConfigHistoryRestoreService), new(*pipeline.ConfigHistoryRestoreServiceImpl)),
+		configHistory.NewConfigHistoryServiceImpl,
+		wire.Bind(new(configHistory.ConfigHistoryService), new(*configHistory.ConfigHistoryServiceImpl)),
+		repository3.NewConfigHistoryRepositoryImpl,
+		wire.Bind(new(repository3.ConfigHistoryRepository), new(*repository3.ConfigHistoryRepositoryImpl)),
 
 		argocdServer.NewArgoK8sClientImpl,
 		wire.Bind(new(argocdServer.ArgoK8sClient), new(*argocdServer.ArgoK8sClientImpl)),
@@ -632,7 +773,6 @@ func InitializeApp() (*App, error) {
 		delete2.NewDeleteServiceFullModeImpl,
 		wire.Bind(new(delete2.DeleteServiceFullMode), new(*delete2.DeleteServiceFullModeImpl)),
 
-
 		appStoreDeploymentFullMode.NewAppStoreDeploymentFullModeServiceImpl,
 		wire.Bind(new(appStoreDeploymentFullMode.AppStoreDeploymentFullModeService), new(*appStoreDeploymentFullMode.AppStoreDeploymentFullModeServiceImpl)),
 		appStoreDeploymentGitopsTool.NewAppStoreDeploymentArgoCdServiceImpl,
@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package apiToken
+
+import (
+	"encoding/json"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/pkg/apiToken"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type ApiTokenRestHandler interface {
+	GetAllApiTokens(w http.ResponseWriter, r *http.Request)
+	GetApiTokenById(w http.ResponseWriter, r *http.Request)
+	CreateApiToken(w http.ResponseWriter, r *http.Request)
+	UpdateApiToken(w http.ResponseWriter, r *http.Request)
+	RevokeApiToken(w http.ResponseWriter, r *http.Request)
+}
+
+type ApiTokenRestHandlerImpl struct {
+	logger          *zap.SugaredLogger
+	userService     user.UserService
+	apiTokenService apiToken.ApiTokenService
+	auditLogService auditLog.AuditLogService
+	validator       *validator.Validate
+}
+
+func NewApiTokenRestHandlerImpl(logger *zap.SugaredLogger, userService user.UserService,
+	apiTokenService apiToken.ApiTokenService, auditLogService auditLog.AuditLogService,
+	validator *validator.Validate) *ApiTokenRestHandlerImpl {
+	return &ApiTokenRestHandlerImpl{
+		logger:          logger,
+		userService:     userService,
+		apiTokenService: apiTokenService,
+		auditLogService: auditLogService,
+		validator:       validator,
+	}
+}
+
+func (handler ApiTokenRestHandlerImpl) GetAllApiTokens(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	res, err := handler.apiTokenService.GetAllActiveApiTokens()
+	if err != nil {
+		handler.logger.Errorw("service err, GetAllApiTokens", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler ApiTokenRestHandlerImpl) GetApiTokenById(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := handler.apiTokenService.GetApiTokenById(id)
+	if err != nil {
+		handler.logger.Errorw("service err, GetApiTokenById", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler ApiTokenRestHandlerImpl) CreateApiToken(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	var request apiToken.ApiTokenRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		handler.logger.Errorw("request err, CreateApiToken", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		handler.logger.Errorw("validation err, CreateApiToken", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	request.UserId = userId
+	res, err := handler.apiTokenService.CreateApiToken(&request)
+	if err != nil {
+		handler.logger.Errorw("service err, CreateApiToken", "err", err, "name", request.Name)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityApiToken,
+		EntityId:   strconv.Itoa(res.Id),
+		Request:    r,
+		Current:    request,
+	})
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler ApiTokenRestHandlerImpl) UpdateApiToken(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	var request apiToken.ApiTokenUpdateRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		handler.logger.Errorw("request err, UpdateApiToken", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = handler.validator.Struct(request)
+	if err != nil {
+		handler.logger.Errorw("validation err, UpdateApiToken", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	request.UserId = userId
+	previous, _ := handler.apiTokenService.GetApiTokenById(id)
+	res, err := handler.apiTokenService.UpdateApiToken(id, &request)
+	if err != nil {
+		handler.logger.Errorw("service err, UpdateApiToken", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityApiToken,
+		EntityId:   strconv.Itoa(id),
+		Request:    r,
+		Previous:   previous,
+		Current:    res,
+	})
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler ApiTokenRestHandlerImpl) RevokeApiToken(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	previous, _ := handler.apiTokenService.GetApiTokenById(id)
+	err = handler.apiTokenService.RevokeApiToken(id, userId)
+	if err != nil {
+		handler.logger.Errorw("service err, RevokeApiToken", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityApiToken,
+		EntityId:   strconv.Itoa(id),
+		Request:    r,
+		Previous:   previous,
+	})
+	common.WriteJsonResp(w, nil, true, http.StatusOK)
+}
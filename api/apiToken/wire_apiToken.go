@@ -0,0 +1,17 @@
+package apiToken
+
+import (
+	"github.com/devtron-labs/devtron/pkg/apiToken"
+	"github.com/google/wire"
+)
+
+//depends on sql, user, session manager, logger
+
+var ApiTokenWireSet = wire.NewSet(
+	apiToken.NewApiTokenServiceImpl,
+	wire.Bind(new(apiToken.ApiTokenService), new(*apiToken.ApiTokenServiceImpl)),
+	NewApiTokenRestHandlerImpl,
+	wire.Bind(new(ApiTokenRestHandler), new(*ApiTokenRestHandlerImpl)),
+	NewApiTokenRouterImpl,
+	wire.Bind(new(ApiTokenRouter), new(*ApiTokenRouterImpl)),
+)
@@ -41,8 +41,11 @@ type DockerConfig struct {
 
 type DockerBuildConfig struct {
 	GitCheckoutPath        string            `json:"gitCheckoutPath,omitempty" validate:"required"`
-	DockerfileRelativePath string            `json:"dockerfileRelativePath,omitempty" validate:"required"`
+	DockerfileRelativePath string            `json:"dockerfileRelativePath,omitempty"` //required unless buildType is BUILDPACK
 	Args                   map[string]string `json:"args,omitempty"`
+	TargetPlatform         string            `json:"targetPlatform,omitempty"`
+	BuildType              string            `json:"buildType,omitempty"`
+	BuilderImage           string            `json:"builderImage,omitempty"`
 }
 
 type DeploymentTemplate struct {
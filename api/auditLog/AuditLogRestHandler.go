@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package auditLog
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/auditLog/repository"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type AuditLogRestHandler interface {
+	GetAuditLogs(w http.ResponseWriter, r *http.Request)
+	ExportAuditLogs(w http.ResponseWriter, r *http.Request)
+}
+
+type AuditLogRestHandlerImpl struct {
+	logger          *zap.SugaredLogger
+	userService     user.UserService
+	auditLogService auditLog.AuditLogService
+}
+
+func NewAuditLogRestHandlerImpl(logger *zap.SugaredLogger, userService user.UserService,
+	auditLogService auditLog.AuditLogService) *AuditLogRestHandlerImpl {
+	return &AuditLogRestHandlerImpl{
+		logger:          logger,
+		userService:     userService,
+		auditLogService: auditLogService,
+	}
+}
+
+func (handler AuditLogRestHandlerImpl) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	filter, err := handler.parseFilter(r)
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := handler.auditLogService.GetAuditLogs(filter)
+	if err != nil {
+		handler.logger.Errorw("service err, GetAuditLogs", "err", err, "filter", filter)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler AuditLogRestHandlerImpl) ExportAuditLogs(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, handler.userService, userId); !ok {
+		return
+	}
+	filter, err := handler.parseFilter(r)
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	if len(r.URL.Query().Get("size")) == 0 {
+		filter.Size = 0
+	}
+	format := r.URL.Query().Get("format")
+	if len(format) == 0 {
+		format = auditLog.ExportFormatCsv
+	}
+	if format != auditLog.ExportFormatCsv && format != auditLog.ExportFormatJson {
+		common.WriteJsonResp(w, fmt.Errorf("unsupported format %s", format), nil, http.StatusBadRequest)
+		return
+	}
+	content, err := handler.auditLogService.ExportAuditLogs(filter, format)
+	if err != nil {
+		handler.logger.Errorw("service err, ExportAuditLogs", "err", err, "filter", filter)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	contentType := "text/csv"
+	if format == auditLog.ExportFormatJson {
+		contentType = "application/json"
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=audit-log."+format)
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(content)
+	if err != nil {
+		handler.logger.Errorw("error in writing audit log export", "err", err)
+	}
+}
+
+func (handler AuditLogRestHandlerImpl) parseFilter(r *http.Request) (*repository.AuditLogFilter, error) {
+	v := r.URL.Query()
+	filter := &repository.AuditLogFilter{
+		Resource:   v.Get("resource"),
+		Action:     v.Get("action"),
+		EntityType: v.Get("entityType"),
+		EntityId:   v.Get("entityId"),
+		Size:       20,
+	}
+	if userId := v.Get("userId"); len(userId) > 0 {
+		id, err := strconv.Atoi(userId)
+		if err != nil {
+			return nil, fmt.Errorf("invalid userId %s", userId)
+		}
+		filter.UserId = int32(id)
+	}
+	if from := v.Get("from"); len(from) > 0 {
+		fromTime, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from %s, expected RFC3339", from)
+		}
+		filter.From = fromTime
+	}
+	if to := v.Get("to"); len(to) > 0 {
+		toTime, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to %s, expected RFC3339", to)
+		}
+		filter.To = toTime
+	}
+	if offset := v.Get("offset"); len(offset) > 0 {
+		value, err := strconv.Atoi(offset)
+		if err != nil || value < 0 {
+			return nil, fmt.Errorf("invalid offset %s", offset)
+		}
+		filter.Offset = value
+	}
+	if size := v.Get("size"); len(size) > 0 {
+		value, err := strconv.Atoi(size)
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("invalid size %s", size)
+		}
+		filter.Size = value
+	}
+	return filter, nil
+}
@@ -0,0 +1,20 @@
+package auditLog
+
+import (
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/auditLog/repository"
+	"github.com/google/wire"
+)
+
+//depends on sql, user, logger
+
+var AuditLogWireSet = wire.NewSet(
+	repository.NewAuditLogRepositoryImpl,
+	wire.Bind(new(repository.AuditLogRepository), new(*repository.AuditLogRepositoryImpl)),
+	auditLog.NewAuditLogServiceImpl,
+	wire.Bind(new(auditLog.AuditLogService), new(*auditLog.AuditLogServiceImpl)),
+	NewAuditLogRestHandlerImpl,
+	wire.Bind(new(AuditLogRestHandler), new(*AuditLogRestHandlerImpl)),
+	NewAuditLogRouterImpl,
+	wire.Bind(new(AuditLogRouter), new(*AuditLogRouterImpl)),
+)
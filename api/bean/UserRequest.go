@@ -36,6 +36,7 @@ type UserInfo struct {
 	Status      string       `json:"status,omitempty"`
 	Groups      []string     `json:"groups"`
 	SuperAdmin  bool         `json:"superAdmin,notnull"`
+	UserType    string       `json:"-"`
 }
 
 type RoleGroup struct {
@@ -43,6 +43,11 @@ type ValuesOverrideRequest struct {
 	CdWorkflowId       int                   `json:"cdWorkflowId"`
 	UserId             int32                 `json:"-"`
 	DeploymentType     models.DeploymentType `json:"-"`
+	//super admins can deploy outside of deployment windows, every override is audited
+	DeploymentWindowOverride       bool   `json:"deploymentWindowOverride"`
+	DeploymentWindowOverrideReason string `json:"deploymentWindowOverrideReason"`
+	//auto rollback redeploys the values of this earlier release instead of rendering the current config
+	RollbackPipelineOverrideId int `json:"-"`
 }
 
 type ReleaseStatusUpdateRequest struct {
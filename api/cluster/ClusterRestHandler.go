@@ -22,6 +22,7 @@ import (
 	"encoding/json"
 	"errors"
 	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
 	delete2 "github.com/devtron-labs/devtron/pkg/delete"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
 	"net/http"
@@ -50,12 +51,13 @@ type ClusterRestHandler interface {
 }
 
 type ClusterRestHandlerImpl struct {
-	clusterService cluster.ClusterService
-	logger         *zap.SugaredLogger
-	userService    user.UserService
-	validator      *validator.Validate
-	enforcer       casbin.Enforcer
-	deleteService  delete2.DeleteService
+	clusterService  cluster.ClusterService
+	logger          *zap.SugaredLogger
+	userService     user.UserService
+	validator       *validator.Validate
+	enforcer        casbin.Enforcer
+	deleteService   delete2.DeleteService
+	auditLogService auditLog.AuditLogService
 }
 
 func NewClusterRestHandlerImpl(clusterService cluster.ClusterService,
@@ -64,14 +66,16 @@ func NewClusterRestHandlerImpl(clusterService cluster.ClusterService,
 	validator *validator.Validate,
 	enforcer casbin.Enforcer,
 	deleteService delete2.DeleteService,
+	auditLogService auditLog.AuditLogService,
 ) *ClusterRestHandlerImpl {
 	return &ClusterRestHandlerImpl{
-		clusterService: clusterService,
-		logger:         logger,
-		userService:    userService,
-		validator:      validator,
-		enforcer:       enforcer,
-		deleteService:  deleteService,
+		clusterService:  clusterService,
+		logger:          logger,
+		userService:     userService,
+		validator:       validator,
+		enforcer:        enforcer,
+		deleteService:   deleteService,
+		auditLogService: auditLogService,
 	}
 }
 
@@ -121,6 +125,15 @@ func (impl ClusterRestHandlerImpl) Save(w http.ResponseWriter, r *http.Request)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceCluster,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityCluster,
+		EntityId:   strconv.Itoa(bean.Id),
+		Request:    r,
+		Current:    bean,
+	})
 
 	/*	isTriggered, err := impl.installedAppService.DeployDefaultChartOnCluster(bean, userId)
 		if err != nil {
@@ -243,12 +256,23 @@ func (impl ClusterRestHandlerImpl) Update(w http.ResponseWriter, r *http.Request
 		}(ctx.Done(), cn.CloseNotify())
 	}
 	ctx = context.WithValue(r.Context(), "token", token)
+	previous, _ := impl.clusterService.FindById(bean.Id)
 	_, err = impl.clusterService.Update(ctx, &bean, userId)
 	if err != nil {
 		impl.logger.Errorw("service err, Update", "error", err, "payload", bean)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceCluster,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityCluster,
+		EntityId:   strconv.Itoa(bean.Id),
+		Request:    r,
+		Previous:   previous,
+		Current:    bean,
+	})
 
 	common.WriteJsonResp(w, err, bean, http.StatusOK)
 }
@@ -322,11 +346,21 @@ func (impl ClusterRestHandlerImpl) DeleteCluster(w http.ResponseWriter, r *http.
 		return
 	}
 	//RBAC enforcer Ends
+	previous, _ := impl.clusterService.FindById(bean.Id)
 	err = impl.deleteService.DeleteCluster(&bean, userId)
 	if err != nil {
 		impl.logger.Errorw("error in deleting cluster", "err", err, "id", bean.Id, "name", bean.ClusterName)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceCluster,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityCluster,
+		EntityId:   strconv.Itoa(bean.Id),
+		Request:    r,
+		Previous:   previous,
+	})
 	common.WriteJsonResp(w, err, CLUSTER_DELETE_SUCCESS_RESP, http.StatusOK)
 }
@@ -0,0 +1,272 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type ArtifactRetentionRestHandler interface {
+	SavePolicy(w http.ResponseWriter, r *http.Request)
+	GetPolicies(w http.ResponseWriter, r *http.Request)
+	DeletePolicy(w http.ResponseWriter, r *http.Request)
+	ApplyRetention(w http.ResponseWriter, r *http.Request)
+	GetRun(w http.ResponseWriter, r *http.Request)
+	GetRuns(w http.ResponseWriter, r *http.Request)
+}
+
+type ArtifactRetentionRestHandlerImpl struct {
+	logger                   *zap.SugaredLogger
+	artifactRetentionService pipeline.ArtifactRetentionService
+	userService              user.UserService
+	enforcer                 casbin.Enforcer
+	enforcerUtil             rbac.EnforcerUtil
+	validator                *validator.Validate
+	auditLogService          auditLog.AuditLogService
+}
+
+func NewArtifactRetentionRestHandlerImpl(logger *zap.SugaredLogger, artifactRetentionService pipeline.ArtifactRetentionService,
+	userService user.UserService, enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil, validator *validator.Validate,
+	auditLogService auditLog.AuditLogService) *ArtifactRetentionRestHandlerImpl {
+	return &ArtifactRetentionRestHandlerImpl{
+		logger:                   logger,
+		artifactRetentionService: artifactRetentionService,
+		userService:              userService,
+		enforcer:                 enforcer,
+		enforcerUtil:             enforcerUtil,
+		validator:                validator,
+		auditLogService:          auditLogService,
+	}
+}
+
+func (impl ArtifactRetentionRestHandlerImpl) SavePolicy(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var request pipeline.ArtifactRetentionPolicyDto
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		impl.logger.Errorw("request err, SaveArtifactRetentionPolicy", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(request)
+	if err != nil {
+		impl.logger.Errorw("validation err, SaveArtifactRetentionPolicy", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.checkPolicyAuth(token, request.AppId); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	request.UserId = userId
+	impl.logger.Infow("request payload, SaveArtifactRetentionPolicy", "payload", request)
+	res, err := impl.artifactRetentionService.SavePolicy(&request)
+	if err != nil {
+		impl.logger.Errorw("service err, SaveArtifactRetentionPolicy", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceGlobal,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityArtifactRetention,
+		EntityId:   strconv.Itoa(res.Id),
+		Request:    r,
+		Current:    res,
+	})
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ArtifactRetentionRestHandlerImpl) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionGet, "*"); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	res, err := impl.artifactRetentionService.GetPolicies()
+	if err != nil {
+		impl.logger.Errorw("service err, GetArtifactRetentionPolicies", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ArtifactRetentionRestHandlerImpl) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	appId, err := strconv.Atoi(mux.Vars(r)["appId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.checkPolicyAuth(token, appId); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	err = impl.artifactRetentionService.DeletePolicy(appId, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, DeleteArtifactRetentionPolicy", "err", err, "appId", appId)
+		if util.IsErrNoRows(err) {
+			common.WriteJsonResp(w, err, "retention policy not found", http.StatusNotFound)
+			return
+		}
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceGlobal,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityArtifactRetention,
+		EntityId:   strconv.Itoa(appId),
+		Request:    r,
+	})
+	common.WriteJsonResp(w, nil, "retention policy deleted", http.StatusOK)
+}
+
+// ApplyRetention runs every policy at once, so it needs global access even for app policies
+func (impl ArtifactRetentionRestHandlerImpl) ApplyRetention(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionDelete, "*"); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	dryRun := r.URL.Query().Get("dryRun") == "true"
+	run, err := impl.artifactRetentionService.ApplyRetention(dryRun, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, ApplyArtifactRetention", "err", err, "dryRun", dryRun)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	if !dryRun {
+		impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+			UserId:     userId,
+			Resource:   casbin.ResourceGlobal,
+			Action:     casbin.ActionDelete,
+			EntityType: auditLog.EntityArtifactRetention,
+			EntityId:   "run/" + strconv.Itoa(run.Id),
+			Request:    r,
+		})
+	}
+	common.WriteJsonResp(w, nil, run, http.StatusOK)
+}
+
+func (impl ArtifactRetentionRestHandlerImpl) GetRun(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionGet, "*"); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := impl.artifactRetentionService.GetRun(id)
+	if err != nil {
+		impl.logger.Errorw("service err, GetArtifactRetentionRun", "err", err, "id", id)
+		if util.IsErrNoRows(err) {
+			common.WriteJsonResp(w, err, "retention run not found", http.StatusNotFound)
+			return
+		}
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ArtifactRetentionRestHandlerImpl) GetRuns(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionGet, "*"); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	offset, size := 0, 20
+	if v := r.URL.Query().Get("offset"); len(v) > 0 {
+		if offset, err = strconv.Atoi(v); err != nil {
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+	}
+	if v := r.URL.Query().Get("size"); len(v) > 0 {
+		if size, err = strconv.Atoi(v); err != nil {
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+	}
+	res, err := impl.artifactRetentionService.GetRuns(offset, size)
+	if err != nil {
+		impl.logger.Errorw("service err, GetArtifactRetentionRuns", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+// checkPolicyAuth allows app admins to manage the policy of their app, the global policy needs global access
+func (impl ArtifactRetentionRestHandlerImpl) checkPolicyAuth(token string, appId int) bool {
+	if appId == 0 {
+		return impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionUpdate, "*")
+	}
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+	return impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionUpdate, object)
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+)
+
+type ConfigHistoryRestHandler interface {
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	GetVersion(w http.ResponseWriter, r *http.Request)
+	GetDiff(w http.ResponseWriter, r *http.Request)
+	RestoreVersion(w http.ResponseWriter, r *http.Request)
+}
+
+type ConfigHistoryRestHandlerImpl struct {
+	logger                      *zap.SugaredLogger
+	configHistoryService        configHistory.ConfigHistoryService
+	configHistoryRestoreService pipeline.ConfigHistoryRestoreService
+	userService                 user.UserService
+	enforcer                    casbin.Enforcer
+	enforcerUtil                rbac.EnforcerUtil
+	auditLogService             auditLog.AuditLogService
+}
+
+func NewConfigHistoryRestHandlerImpl(logger *zap.SugaredLogger,
+	configHistoryService configHistory.ConfigHistoryService,
+	configHistoryRestoreService pipeline.ConfigHistoryRestoreService,
+	userService user.UserService, enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil,
+	auditLogService auditLog.AuditLogService) *ConfigHistoryRestHandlerImpl {
+	return &ConfigHistoryRestHandlerImpl{
+		logger:                      logger,
+		configHistoryService:        configHistoryService,
+		configHistoryRestoreService: configHistoryRestoreService,
+		userService:                 userService,
+		enforcer:                    enforcer,
+		enforcerUtil:                enforcerUtil,
+		auditLogService:             auditLogService,
+	}
+}
+
+func (impl ConfigHistoryRestHandlerImpl) GetHistory(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	v := r.URL.Query()
+	appId, err := strconv.Atoi(v.Get("appId"))
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	envId := 0
+	if param := v.Get("envId"); len(param) > 0 {
+		envId, err = strconv.Atoi(param)
+		if err != nil {
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+	}
+	configType := v.Get("configType")
+	if configType != configHistory.ConfigTypeDeploymentTemplate && configType != configHistory.ConfigTypeConfigMap && configType != configHistory.ConfigTypeSecret {
+		common.WriteJsonResp(w, fmt.Errorf("invalid config type %s", configType), nil, http.StatusBadRequest)
+		return
+	}
+	if ok := impl.checkAuth(w, r, appId, envId, casbin.ActionGet); !ok {
+		return
+	}
+	res, err := impl.configHistoryService.GetHistory(appId, envId, configType)
+	if err != nil {
+		impl.logger.Errorw("service err, GetHistory", "err", err, "appId", appId, "envId", envId, "configType", configType)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ConfigHistoryRestHandlerImpl) GetVersion(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := impl.configHistoryService.GetVersion(id)
+	if err != nil {
+		impl.logger.Errorw("service err, GetVersion", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	if ok := impl.checkAuth(w, r, res.AppId, res.EnvId, casbin.ActionGet); !ok {
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ConfigHistoryRestHandlerImpl) GetDiff(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	v := r.URL.Query()
+	fromId, err := strconv.Atoi(v.Get("from"))
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	toId, err := strconv.Atoi(v.Get("to"))
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := impl.configHistoryService.GetDiff(fromId, toId)
+	if err != nil {
+		impl.logger.Errorw("service err, GetDiff", "err", err, "from", fromId, "to", toId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	// both versions belong to the same app and env, checked by the service
+	if ok := impl.checkAuth(w, r, res.From.AppId, res.From.EnvId, casbin.ActionGet); !ok {
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ConfigHistoryRestHandlerImpl) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	version, err := impl.configHistoryService.GetVersionForRestore(id)
+	if err != nil {
+		impl.logger.Errorw("service err, RestoreVersion", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	if ok := impl.checkAuth(w, r, version.AppId, version.EnvId, casbin.ActionUpdate); !ok {
+		return
+	}
+	impl.logger.Infow("request payload, RestoreVersion", "id", id, "appId", version.AppId, "envId", version.EnvId, "configType", version.ConfigType)
+	res, err := impl.configHistoryRestoreService.RestoreVersion(id, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, RestoreVersion", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	entityType := auditLog.EntityConfigMap
+	if version.ConfigType == configHistory.ConfigTypeSecret {
+		entityType = auditLog.EntitySecret
+	} else if version.ConfigType == configHistory.ConfigTypeDeploymentTemplate && version.EnvId > 0 {
+		entityType = auditLog.EntityEnvDeploymentTemplate
+	} else if version.ConfigType == configHistory.ConfigTypeDeploymentTemplate {
+		entityType = auditLog.EntityDeploymentTemplate
+	}
+	entityId := strconv.Itoa(version.AppId)
+	if version.EnvId > 0 {
+		entityId = fmt.Sprintf("%d/%d", version.AppId, version.EnvId)
+	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionUpdate,
+		EntityType: entityType,
+		EntityId:   entityId,
+		Request:    r,
+		Current:    res,
+	})
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+// checkAuth writes the error response itself, env level configs need access to the environment as well
+func (impl ConfigHistoryRestHandlerImpl) checkAuth(w http.ResponseWriter, r *http.Request, appId int, envId int, action string) bool {
+	token := r.Header.Get("token")
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, action, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return false
+	}
+	if envId > 0 {
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(appId, envId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, action, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
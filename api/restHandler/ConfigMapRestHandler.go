@@ -23,6 +23,7 @@ import (
 	"github.com/devtron-labs/devtron/api/bean"
 	"github.com/devtron-labs/devtron/api/restHandler/common"
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
 	"github.com/devtron-labs/devtron/pkg/pipeline"
 	"github.com/devtron-labs/devtron/pkg/team"
 	"github.com/devtron-labs/devtron/pkg/user"
@@ -65,12 +66,14 @@ type ConfigMapRestHandlerImpl struct {
 	pipelineRepository pipelineConfig.PipelineRepository
 	enforcerUtil       rbac.EnforcerUtil
 	configMapService   pipeline.ConfigMapService
+	auditLogService    auditLog.AuditLogService
 }
 
 func NewConfigMapRestHandlerImpl(pipelineBuilder pipeline.PipelineBuilder, Logger *zap.SugaredLogger,
 	chartService pipeline.ChartService, userAuthService user.UserService, teamService team.TeamService,
 	enforcer casbin.Enforcer, pipelineRepository pipelineConfig.PipelineRepository,
-	enforcerUtil rbac.EnforcerUtil, configMapService pipeline.ConfigMapService) *ConfigMapRestHandlerImpl {
+	enforcerUtil rbac.EnforcerUtil, configMapService pipeline.ConfigMapService,
+	auditLogService auditLog.AuditLogService) *ConfigMapRestHandlerImpl {
 	return &ConfigMapRestHandlerImpl{
 		pipelineBuilder:    pipelineBuilder,
 		Logger:             Logger,
@@ -81,6 +84,7 @@ func NewConfigMapRestHandlerImpl(pipelineBuilder pipeline.PipelineBuilder, Logge
 		pipelineRepository: pipelineRepository,
 		enforcerUtil:       enforcerUtil,
 		configMapService:   configMapService,
+		auditLogService:    auditLogService,
 	}
 }
 
@@ -111,12 +115,24 @@ func (handler ConfigMapRestHandlerImpl) CMGlobalAddUpdate(w http.ResponseWriter,
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CMGlobalFetch(configMapRequest.AppId)
 	res, err := handler.configMapService.CMGlobalAddUpdate(&configMapRequest)
 	if err != nil {
 		handler.Logger.Errorw("service err, CMGlobalAddUpdate", "err", err, "payload", configMapRequest)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CMGlobalFetch(configMapRequest.AppId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityConfigMap,
+		EntityId:   strconv.Itoa(configMapRequest.AppId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -151,12 +167,24 @@ func (handler ConfigMapRestHandlerImpl) CMEnvironmentAddUpdate(w http.ResponseWr
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CMEnvironmentFetch(configMapRequest.AppId, configMapRequest.EnvironmentId)
 	res, err := handler.configMapService.CMEnvironmentAddUpdate(&configMapRequest)
 	if err != nil {
 		handler.Logger.Errorw("service err, CMEnvironmentAddUpdate", "err", err, "payload", configMapRequest)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CMEnvironmentFetch(configMapRequest.AppId, configMapRequest.EnvironmentId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityConfigMap,
+		EntityId:   fmt.Sprintf("%d/%d", configMapRequest.AppId, configMapRequest.EnvironmentId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -257,12 +285,25 @@ func (handler ConfigMapRestHandlerImpl) CSGlobalAddUpdate(w http.ResponseWriter,
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CSGlobalFetch(configMapRequest.AppId)
 	res, err := handler.configMapService.CSGlobalAddUpdate(&configMapRequest)
 	if err != nil {
 		handler.Logger.Errorw("service err, CSGlobalAddUpdate", "err", err, "payload", configMapRequest)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CSGlobalFetch(configMapRequest.AppId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntitySecret,
+		EntityId:   strconv.Itoa(configMapRequest.AppId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+		MaskValues: true,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -298,12 +339,25 @@ func (handler ConfigMapRestHandlerImpl) CSEnvironmentAddUpdate(w http.ResponseWr
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CSEnvironmentFetch(configMapRequest.AppId, configMapRequest.EnvironmentId)
 	res, err := handler.configMapService.CSEnvironmentAddUpdate(&configMapRequest)
 	if err != nil {
 		handler.Logger.Errorw("service err, CSEnvironmentAddUpdate", "err", err, "payload", configMapRequest)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CSEnvironmentFetch(configMapRequest.AppId, configMapRequest.EnvironmentId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntitySecret,
+		EntityId:   fmt.Sprintf("%d/%d", configMapRequest.AppId, configMapRequest.EnvironmentId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+		MaskValues: true,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -408,12 +462,24 @@ func (handler ConfigMapRestHandlerImpl) CMGlobalDelete(w http.ResponseWriter, r
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CMGlobalFetch(appId)
 	res, err := handler.configMapService.CMGlobalDelete(name, id, userId)
 	if err != nil {
 		handler.Logger.Errorw("service err, CMGlobalDelete", "err", err, "appId", appId, "id", id, "name", name)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CMGlobalFetch(appId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityConfigMap,
+		EntityId:   strconv.Itoa(appId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -459,12 +525,24 @@ func (handler ConfigMapRestHandlerImpl) CMEnvironmentDelete(w http.ResponseWrite
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CMEnvironmentFetch(appId, envId)
 	res, err := handler.configMapService.CMEnvironmentDelete(name, id, userId)
 	if err != nil {
 		handler.Logger.Errorw("service err, CMEnvironmentDelete", "err", err, "appId", appId, "envId", envId, "id", id)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CMEnvironmentFetch(appId, envId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityConfigMap,
+		EntityId:   fmt.Sprintf("%d/%d", appId, envId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -499,12 +577,25 @@ func (handler ConfigMapRestHandlerImpl) CSGlobalDelete(w http.ResponseWriter, r
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CSGlobalFetch(appId)
 	res, err := handler.configMapService.CSGlobalDelete(name, id, userId)
 	if err != nil {
 		handler.Logger.Errorw("service err, CSGlobalDelete", "err", err, "appId", appId, "id", id, "name", name)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CSGlobalFetch(appId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntitySecret,
+		EntityId:   strconv.Itoa(appId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+		MaskValues: true,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -550,12 +641,25 @@ func (handler ConfigMapRestHandlerImpl) CSEnvironmentDelete(w http.ResponseWrite
 	}
 	//RBAC END
 
+	previous, _ := handler.configMapService.CSEnvironmentFetch(appId, envId)
 	res, err := handler.configMapService.CSEnvironmentDelete(name, id, userId)
 	if err != nil {
 		handler.Logger.Errorw("service err, CSEnvironmentDelete", "err", err, "appId", appId, "envId", envId, "id", id)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.configMapService.CSEnvironmentFetch(appId, envId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntitySecret,
+		EntityId:   fmt.Sprintf("%d/%d", appId, envId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+		MaskValues: true,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -700,5 +804,19 @@ func (handler ConfigMapRestHandlerImpl) ConfigSecretBulkPatch(w http.ResponseWri
 			return
 		}
 	}
+	entityType := auditLog.EntityConfigMap
+	if bulkPatchRequest.Type == "CS" {
+		entityType = auditLog.EntitySecret
+	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionUpdate,
+		EntityType: entityType,
+		EntityId:   bulkPatchRequest.Name,
+		Request:    r,
+		Current:    bulkPatchRequest,
+		MaskValues: true,
+	})
 	common.WriteJsonResp(w, err, true, http.StatusOK)
 }
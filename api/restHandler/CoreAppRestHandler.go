@@ -488,6 +488,9 @@ func (handler CoreAppRestHandlerImpl) buildDockerConfig(appId int) (*appBean.Doc
 			Args:                   ciConfig.DockerBuildConfig.Args,
 			DockerfileRelativePath: ciConfig.DockerBuildConfig.DockerfilePath,
 			GitCheckoutPath:        gitMaterial.CheckoutPath,
+			TargetPlatform:         ciConfig.DockerBuildConfig.TargetPlatform,
+			BuildType:              string(ciConfig.DockerBuildConfig.BuildType),
+			BuilderImage:           ciConfig.DockerBuildConfig.BuilderImage,
 		},
 	}
 
@@ -1246,6 +1249,9 @@ func (handler CoreAppRestHandlerImpl) createDockerConfig(appId int, dockerConfig
 		GitMaterialId:  gitMaterial.Id,
 		DockerfilePath: dockerConfig.BuildConfig.DockerfileRelativePath,
 		Args:           dockerBuildArgs,
+		TargetPlatform: dockerConfig.BuildConfig.TargetPlatform,
+		BuildType:      bean.CiBuildType(dockerConfig.BuildConfig.BuildType),
+		BuilderImage:   dockerConfig.BuildConfig.BuilderImage,
 	}
 	createDockerConfigRequest.DockerBuildConfig = dockerBuildConfigRequest
 
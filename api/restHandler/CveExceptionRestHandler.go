@@ -0,0 +1,240 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	security2 "github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/cluster"
+	"github.com/devtron-labs/devtron/pkg/security"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type CveExceptionRestHandler interface {
+	RequestException(w http.ResponseWriter, r *http.Request)
+	ApproveException(w http.ResponseWriter, r *http.Request)
+	RejectException(w http.ResponseWriter, r *http.Request)
+	RevokeException(w http.ResponseWriter, r *http.Request)
+	GetExceptions(w http.ResponseWriter, r *http.Request)
+}
+
+type CveExceptionRestHandlerImpl struct {
+	logger              *zap.SugaredLogger
+	cveExceptionService security.CveExceptionService
+	userService         user.UserService
+	enforcer            casbin.Enforcer
+	enforcerUtil        rbac.EnforcerUtil
+	environmentService  cluster.EnvironmentService
+	auditLogService     auditLog.AuditLogService
+	validator           *validator.Validate
+}
+
+func NewCveExceptionRestHandlerImpl(logger *zap.SugaredLogger,
+	cveExceptionService security.CveExceptionService,
+	userService user.UserService, enforcer casbin.Enforcer,
+	enforcerUtil rbac.EnforcerUtil, environmentService cluster.EnvironmentService,
+	auditLogService auditLog.AuditLogService, validator *validator.Validate) *CveExceptionRestHandlerImpl {
+	return &CveExceptionRestHandlerImpl{
+		logger:              logger,
+		cveExceptionService: cveExceptionService,
+		userService:         userService,
+		enforcer:            enforcer,
+		enforcerUtil:        enforcerUtil,
+		environmentService:  environmentService,
+		auditLogService:     auditLogService,
+		validator:           validator,
+	}
+}
+
+func (impl CveExceptionRestHandlerImpl) RequestException(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req security.CveExceptionRequest
+	err = decoder.Decode(&req)
+	if err != nil {
+		impl.logger.Errorw("request err, RequestException", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(req)
+	if err != nil {
+		impl.logger.Errorw("validation err, RequestException", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	impl.logger.Infow("request payload, RequestException", "payload", req)
+	//AUTH - same access as vulnerability policies
+	if ok := impl.checkAuth(w, r, userId, req.AppId, req.EnvId, casbin.ActionCreate); !ok {
+		return
+	}
+	//AUTH
+
+	res, err := impl.cveExceptionService.RequestException(&req, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, RequestException", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	impl.saveAuditLog(r, userId, casbin.ActionCreate, nil, res)
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl CveExceptionRestHandlerImpl) ApproveException(w http.ResponseWriter, r *http.Request) {
+	impl.changeStatus(w, r, "ApproveException", impl.cveExceptionService.ApproveException)
+}
+
+func (impl CveExceptionRestHandlerImpl) RejectException(w http.ResponseWriter, r *http.Request) {
+	impl.changeStatus(w, r, "RejectException", impl.cveExceptionService.RejectException)
+}
+
+func (impl CveExceptionRestHandlerImpl) RevokeException(w http.ResponseWriter, r *http.Request) {
+	impl.changeStatus(w, r, "RevokeException", impl.cveExceptionService.RevokeException)
+}
+
+// changeStatus is shared by approve, reject and revoke, all of which are allowed to super admins only
+func (impl CveExceptionRestHandlerImpl) changeStatus(w http.ResponseWriter, r *http.Request, method string, change func(id int, userId int32) (*security.CveExceptionDto, error)) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+		return
+	}
+	previous, err := impl.cveExceptionService.GetException(id)
+	if err != nil {
+		impl.logger.Errorw("service err, "+method, "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	res, err := change(id, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, "+method, "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	impl.saveAuditLog(r, userId, casbin.ActionUpdate, previous, res)
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl CveExceptionRestHandlerImpl) GetExceptions(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	v := r.URL.Query()
+	filter := &security2.CveExceptionFilter{
+		CveStoreName:   v.Get("cveName"),
+		Status:         v.Get("status"),
+		IncludeExpired: v.Get("includeExpired") == "true",
+		Size:           20,
+	}
+	for key, value := range map[string]*int{"appId": &filter.AppId, "envId": &filter.EnvId, "offset": &filter.Offset, "size": &filter.Size} {
+		if param := v.Get(key); len(param) > 0 {
+			*value, err = strconv.Atoi(param)
+			if err != nil || *value < 0 {
+				impl.logger.Errorw("request err, GetExceptions", "err", err, key, param)
+				common.WriteJsonResp(w, fmt.Errorf("invalid %s", key), nil, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	//AUTH - listing across environments is limited to super admins
+	if filter.EnvId == 0 {
+		if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+			return
+		}
+	} else if ok := impl.checkAuth(w, r, userId, filter.AppId, filter.EnvId, casbin.ActionGet); !ok {
+		return
+	}
+	//AUTH
+
+	res, err := impl.cveExceptionService.GetExceptions(filter)
+	if err != nil {
+		impl.logger.Errorw("service err, GetExceptions", "err", err, "filter", filter)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+// checkAuth writes the error response itself, app level exceptions need access to both app and env
+func (impl CveExceptionRestHandlerImpl) checkAuth(w http.ResponseWriter, r *http.Request, userId int32, appId int, envId int, action string) bool {
+	token := r.Header.Get("token")
+	if appId > 0 {
+		object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, action, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return false
+		}
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(appId, envId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, action, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return false
+		}
+		return true
+	}
+	environment, err := impl.environmentService.FindById(envId)
+	if err != nil {
+		common.WriteJsonResp(w, err, "Failed to get environment by id", http.StatusInternalServerError)
+		return false
+	}
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, action, environment.EnvironmentIdentifier); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (impl CveExceptionRestHandlerImpl) saveAuditLog(r *http.Request, userId int32, action string, previous *security.CveExceptionDto, current *security.CveExceptionDto) {
+	request := &auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceGlobalEnvironment,
+		Action:     action,
+		EntityType: auditLog.EntityCveException,
+		EntityId:   strconv.Itoa(current.Id),
+		Request:    r,
+		Current:    current,
+	}
+	//a typed nil would be recorded as null instead of a create
+	if previous != nil {
+		request.Previous = previous
+	}
+	impl.auditLogService.SaveAuditLog(request)
+}
@@ -0,0 +1,307 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	appRepository "github.com/devtron-labs/devtron/internal/sql/repository/app"
+	"github.com/devtron-labs/devtron/internal/sql/repository/chartConfig"
+	security2 "github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/pkg/app"
+	"github.com/devtron-labs/devtron/pkg/cluster"
+	"github.com/devtron-labs/devtron/pkg/deploymentPolicy"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type DeploymentPolicyRestHandler interface {
+	SavePolicy(w http.ResponseWriter, r *http.Request)
+	GetPolicies(w http.ResponseWriter, r *http.Request)
+	EvaluateValues(w http.ResponseWriter, r *http.Request)
+	GetViolations(w http.ResponseWriter, r *http.Request)
+}
+
+type DeploymentPolicyRestHandlerImpl struct {
+	logger                      *zap.SugaredLogger
+	deploymentPolicyService     deploymentPolicy.DeploymentPolicyService
+	userService                 user.UserService
+	enforcer                    casbin.Enforcer
+	enforcerUtil                rbac.EnforcerUtil
+	environmentService          cluster.EnvironmentService
+	validator                   *validator.Validate
+	appRepository               appRepository.AppRepository
+	envConfigOverrideRepository chartConfig.EnvConfigOverrideRepository
+	chartTemplateRenderer       app.ChartTemplateRenderer
+}
+
+type EvaluateDeploymentPolicyRequest struct {
+	AppId  int             `json:"appId" validate:"required"`
+	EnvId  int             `json:"envId" validate:"required"`
+	Values json.RawMessage `json:"values" validate:"required"`
+}
+
+func NewDeploymentPolicyRestHandlerImpl(logger *zap.SugaredLogger,
+	deploymentPolicyService deploymentPolicy.DeploymentPolicyService,
+	userService user.UserService, enforcer casbin.Enforcer,
+	enforcerUtil rbac.EnforcerUtil, environmentService cluster.EnvironmentService,
+	validator *validator.Validate, appRepository appRepository.AppRepository,
+	envConfigOverrideRepository chartConfig.EnvConfigOverrideRepository,
+	chartTemplateRenderer app.ChartTemplateRenderer) *DeploymentPolicyRestHandlerImpl {
+	return &DeploymentPolicyRestHandlerImpl{
+		logger:                      logger,
+		deploymentPolicyService:     deploymentPolicyService,
+		userService:                 userService,
+		enforcer:                    enforcer,
+		enforcerUtil:                enforcerUtil,
+		environmentService:          environmentService,
+		validator:                   validator,
+		appRepository:               appRepository,
+		envConfigOverrideRepository: envConfigOverrideRepository,
+		chartTemplateRenderer:       chartTemplateRenderer,
+	}
+}
+
+func (impl DeploymentPolicyRestHandlerImpl) SavePolicy(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req deploymentPolicy.DeploymentPolicyRequest
+	err = decoder.Decode(&req)
+	if err != nil {
+		impl.logger.Errorw("request err, SavePolicy", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(req)
+	if err != nil {
+		impl.logger.Errorw("validation err, SavePolicy", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	impl.logger.Infow("request payload, SavePolicy", "payload", req)
+	token := r.Header.Get("token")
+	//AUTH - same access as vulnerability policies
+	if req.AppId > 0 && req.EnvId > 0 {
+		object := impl.enforcerUtil.GetAppRBACNameByAppId(req.AppId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionCreate, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(req.AppId, req.EnvId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionCreate, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	} else if req.AppId == 0 && req.EnvId > 0 {
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionCreate, "*"); !ok {
+			common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+			return
+		}
+	} else if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+		return
+	}
+	//AUTH
+
+	res, err := impl.deploymentPolicyService.SavePolicy(&req, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, SavePolicy", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl DeploymentPolicyRestHandlerImpl) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	v := r.URL.Query()
+	level := v.Get("level")
+	var ids [3]int
+	for i, key := range []string{"clusterId", "envId", "appId"} {
+		if param := v.Get(key); len(param) > 0 {
+			ids[i], err = strconv.Atoi(param)
+			if err != nil {
+				impl.logger.Errorw("request err, GetPolicies", "err", err, key, param)
+				common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	clusterId, environmentId, appId := ids[0], ids[1], ids[2]
+	var policyLevel security2.PolicyLevel
+	if level == security2.Global.String() {
+		policyLevel = security2.Global
+	} else if level == security2.Cluster.String() && clusterId > 0 {
+		policyLevel = security2.Cluster
+	} else if level == security2.Environment.String() && environmentId > 0 {
+		policyLevel = security2.Environment
+	} else if level == security2.Application.String() && environmentId > 0 && appId > 0 {
+		policyLevel = security2.Application
+	} else {
+		common.WriteJsonResp(w, fmt.Errorf("invalid level %s or missing ids", level), nil, http.StatusBadRequest)
+		return
+	}
+
+	token := r.Header.Get("token")
+	//AUTH - check from casbin db
+	if policyLevel == security2.Application {
+		object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	} else if policyLevel == security2.Environment {
+		environment, err := impl.environmentService.FindById(environmentId)
+		if err != nil {
+			common.WriteJsonResp(w, err, "Failed to get environment by id", http.StatusInternalServerError)
+			return
+		}
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionGet, environment.EnvironmentIdentifier); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	}
+	//AUTH
+
+	res, err := impl.deploymentPolicyService.GetPolicies(policyLevel, clusterId, environmentId, appId)
+	if err != nil {
+		impl.logger.Errorw("service err, GetPolicies", "err", err, "policyLevel", policyLevel, "clusterId", clusterId, "environmentId", environmentId, "appId", appId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+// EvaluateValues is a dry run of the admission checks for deployment values before triggering a release,
+// values are rendered with the chart of the environment like they would be on trigger
+func (impl DeploymentPolicyRestHandlerImpl) EvaluateValues(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req EvaluateDeploymentPolicyRequest
+	err = decoder.Decode(&req)
+	if err != nil {
+		impl.logger.Errorw("request err, EvaluateValues", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(req)
+	if err != nil {
+		impl.logger.Errorw("validation err, EvaluateValues", "err", err, "appId", req.AppId, "envId", req.EnvId)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(req.AppId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	environment, err := impl.environmentService.FindById(req.EnvId)
+	if err != nil {
+		common.WriteJsonResp(w, err, "Failed to get environment by id", http.StatusInternalServerError)
+		return
+	}
+	envOverride, err := impl.envConfigOverrideRepository.ActiveEnvConfigOverride(req.AppId, req.EnvId)
+	if err != nil {
+		impl.logger.Errorw("service err, EvaluateValues", "err", err, "appId", req.AppId, "envId", req.EnvId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	if envOverride.Id == 0 {
+		common.WriteJsonResp(w, fmt.Errorf("no deployment template found"), "no deployment template found for the app in the environment", http.StatusNotFound)
+		return
+	}
+	application, err := impl.appRepository.FindById(req.AppId)
+	if err != nil {
+		impl.logger.Errorw("service err, EvaluateValues", "err", err, "appId", req.AppId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	releaseName := fmt.Sprintf("%s-%s", application.AppName, environment.Environment)
+	res, err := impl.deploymentPolicyService.EvaluateManifests(environment.ClusterId, req.EnvId, req.AppId, func() ([]map[string]interface{}, error) {
+		return impl.chartTemplateRenderer.Render(envOverride.Chart.ChartRefId, releaseName, envOverride.Namespace, req.Values)
+	})
+	if err != nil {
+		impl.logger.Errorw("service err, EvaluateValues", "err", err, "appId", req.AppId, "envId", req.EnvId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl DeploymentPolicyRestHandlerImpl) GetViolations(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	pipelineId, err := strconv.Atoi(vars["pipelineId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	offset := 0
+	if offsetQueryParam := r.URL.Query().Get("offset"); offsetQueryParam != "" {
+		offset, err = strconv.Atoi(offsetQueryParam)
+		if err != nil || offset < 0 {
+			common.WriteJsonResp(w, err, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	size := 20
+	if sizeQueryParam := r.URL.Query().Get("size"); sizeQueryParam != "" {
+		size, err = strconv.Atoi(sizeQueryParam)
+		if err != nil || size <= 0 {
+			common.WriteJsonResp(w, err, "invalid size", http.StatusBadRequest)
+			return
+		}
+	}
+	token := r.Header.Get("token")
+	appObject, _ := impl.enforcerUtil.GetTeamAndEnvironmentRbacObjectByCDPipelineId(pipelineId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, appObject); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	res, err := impl.deploymentPolicyService.GetViolations(pipelineId, offset, size)
+	if err != nil {
+		impl.logger.Errorw("service err, GetViolations", "err", err, "pipelineId", pipelineId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
@@ -0,0 +1,285 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/pkg/cluster"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type DeploymentWindowRestHandler interface {
+	CreateWindow(w http.ResponseWriter, r *http.Request)
+	UpdateWindow(w http.ResponseWriter, r *http.Request)
+	DeleteWindow(w http.ResponseWriter, r *http.Request)
+	GetWindowById(w http.ResponseWriter, r *http.Request)
+	GetWindowsByEnvironmentId(w http.ResponseWriter, r *http.Request)
+	CheckDeploymentAllowed(w http.ResponseWriter, r *http.Request)
+	GetOverrideAudit(w http.ResponseWriter, r *http.Request)
+}
+
+type DeploymentWindowRestHandlerImpl struct {
+	logger                  *zap.SugaredLogger
+	enforcer                casbin.Enforcer
+	userService             user.UserService
+	validator               *validator.Validate
+	deploymentWindowService pipeline.DeploymentWindowService
+	environmentService      cluster.EnvironmentService
+}
+
+func NewDeploymentWindowRestHandlerImpl(logger *zap.SugaredLogger, enforcer casbin.Enforcer,
+	userService user.UserService, validator *validator.Validate,
+	deploymentWindowService pipeline.DeploymentWindowService,
+	environmentService cluster.EnvironmentService) *DeploymentWindowRestHandlerImpl {
+	return &DeploymentWindowRestHandlerImpl{
+		logger:                  logger,
+		enforcer:                enforcer,
+		userService:             userService,
+		validator:               validator,
+		deploymentWindowService: deploymentWindowService,
+		environmentService:      environmentService,
+	}
+}
+
+func (handler DeploymentWindowRestHandlerImpl) CreateWindow(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var request pipeline.DeploymentWindowDto
+	err = decoder.Decode(&request)
+	if err != nil {
+		handler.logger.Errorw("request err, CreateWindow", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	request.UserId = userId
+	err = handler.validator.Struct(request)
+	if err != nil {
+		handler.logger.Errorw("validation err, CreateWindow", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+
+	token := r.Header.Get("token")
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionCreate, "*"); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+
+	handler.logger.Infow("request payload, CreateWindow", "payload", request)
+	res, err := handler.deploymentWindowService.CreateWindow(&request)
+	if err != nil {
+		handler.logger.Errorw("service err, CreateWindow", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) UpdateWindow(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var request pipeline.DeploymentWindowDto
+	err = decoder.Decode(&request)
+	if err != nil {
+		handler.logger.Errorw("request err, UpdateWindow", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	request.UserId = userId
+	err = handler.validator.Struct(request)
+	if err != nil {
+		handler.logger.Errorw("validation err, UpdateWindow", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+
+	token := r.Header.Get("token")
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionUpdate, "*"); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+
+	handler.logger.Infow("request payload, UpdateWindow", "payload", request)
+	res, err := handler.deploymentWindowService.UpdateWindow(&request)
+	if err != nil {
+		handler.logger.Errorw("service err, UpdateWindow", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) DeleteWindow(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+
+	token := r.Header.Get("token")
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionDelete, "*"); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+
+	err = handler.deploymentWindowService.DeleteWindow(id, userId)
+	if err != nil {
+		handler.logger.Errorw("service err, DeleteWindow", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, id, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) GetWindowById(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := handler.deploymentWindowService.GetWindowById(id)
+	if err != nil {
+		handler.logger.Errorw("service err, GetWindowById", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	if ok := handler.enforceEnvironmentGet(r.Header.Get("token"), res.EnvironmentId); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) GetWindowsByEnvironmentId(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	envId, err := strconv.Atoi(vars["envId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	if ok := handler.enforceEnvironmentGet(r.Header.Get("token"), envId); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+	res, err := handler.deploymentWindowService.GetWindowsByEnvironmentId(envId)
+	if err != nil {
+		handler.logger.Errorw("service err, GetWindowsByEnvironmentId", "err", err, "envId", envId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) CheckDeploymentAllowed(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	envId, err := strconv.Atoi(vars["envId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	appId, err := strconv.Atoi(vars["appId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	if ok := handler.enforceEnvironmentGet(r.Header.Get("token"), envId); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+	res, err := handler.deploymentWindowService.CheckDeploymentAllowed(appId, envId, time.Now())
+	if err != nil {
+		handler.logger.Errorw("service err, CheckDeploymentAllowed", "err", err, "appId", appId, "envId", envId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) GetOverrideAudit(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	envId, err := strconv.Atoi(vars["envId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	if ok := handler.enforceEnvironmentGet(r.Header.Get("token"), envId); !ok {
+		common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+		return
+	}
+	res, err := handler.deploymentWindowService.GetOverrideAuditByEnvironmentId(envId)
+	if err != nil {
+		handler.logger.Errorw("service err, GetOverrideAudit", "err", err, "envId", envId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler DeploymentWindowRestHandlerImpl) enforceEnvironmentGet(token string, envId int) bool {
+	environment, err := handler.environmentService.FindById(envId)
+	if err != nil {
+		handler.logger.Errorw("error in fetching environment", "err", err, "envId", envId)
+		return false
+	}
+	return handler.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionGet, environment.EnvironmentIdentifier)
+}
@@ -24,13 +24,16 @@ import (
 	security2 "github.com/devtron-labs/devtron/internal/sql/repository/security"
 	"github.com/devtron-labs/devtron/internal/util"
 	"github.com/devtron-labs/devtron/pkg/cluster"
+	"github.com/devtron-labs/devtron/pkg/sbom"
 	"github.com/devtron-labs/devtron/pkg/security"
 	"github.com/devtron-labs/devtron/pkg/user"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
 	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 type ImageScanRestHandler interface {
@@ -38,6 +41,10 @@ type ImageScanRestHandler interface {
 	FetchExecutionDetail(w http.ResponseWriter, r *http.Request)
 	FetchMinScanResultByAppIdAndEnvId(w http.ResponseWriter, r *http.Request)
 	VulnerabilityExposure(w http.ResponseWriter, r *http.Request)
+	DownloadSbom(w http.ResponseWriter, r *http.Request)
+	SearchSbomPackage(w http.ResponseWriter, r *http.Request)
+	VulnerabilityTrend(w http.ResponseWriter, r *http.Request)
+	ExportVulnerabilityTrend(w http.ResponseWriter, r *http.Request)
 }
 
 type ImageScanRestHandlerImpl struct {
@@ -47,11 +54,14 @@ type ImageScanRestHandlerImpl struct {
 	enforcer           casbin.Enforcer
 	enforcerUtil       rbac.EnforcerUtil
 	environmentService cluster.EnvironmentService
+	sbomService        sbom.SbomService
+	trendService       security.VulnerabilityTrendService
 }
 
 func NewImageScanRestHandlerImpl(logger *zap.SugaredLogger,
 	imageScanService security.ImageScanService, userService user.UserService, enforcer casbin.Enforcer,
-	enforcerUtil rbac.EnforcerUtil, environmentService cluster.EnvironmentService) *ImageScanRestHandlerImpl {
+	enforcerUtil rbac.EnforcerUtil, environmentService cluster.EnvironmentService, sbomService sbom.SbomService,
+	trendService security.VulnerabilityTrendService) *ImageScanRestHandlerImpl {
 	return &ImageScanRestHandlerImpl{
 		logger:             logger,
 		imageScanService:   imageScanService,
@@ -59,6 +69,8 @@ func NewImageScanRestHandlerImpl(logger *zap.SugaredLogger,
 		enforcer:           enforcer,
 		enforcerUtil:       enforcerUtil,
 		environmentService: environmentService,
+		sbomService:        sbomService,
+		trendService:       trendService,
 	}
 }
 
@@ -326,3 +338,156 @@ func (impl ImageScanRestHandlerImpl) VulnerabilityExposure(w http.ResponseWriter
 	results.VulnerabilityExposure = vulnerabilityExposure
 	common.WriteJsonResp(w, err, results, http.StatusOK)
 }
+
+func (impl ImageScanRestHandlerImpl) DownloadSbom(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	artifactId, err := strconv.Atoi(mux.Vars(r)["artifactId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	document, err := impl.sbomService.GetSbomByArtifactId(artifactId)
+	if err != nil {
+		impl.logger.Errorw("service err, DownloadSbom", "err", err, "artifactId", artifactId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	token := r.Header.Get("token")
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(document.AppId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename="+document.FileName)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(document.Content)
+	if err != nil {
+		impl.logger.Errorw("error in writing sbom", "err", err, "artifactId", artifactId)
+	}
+}
+
+func (impl ImageScanRestHandlerImpl) SearchSbomPackage(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	v := r.URL.Query()
+	packageName := strings.TrimSpace(v.Get("package"))
+	version := strings.TrimSpace(v.Get("version"))
+	if len(packageName) == 0 {
+		common.WriteJsonResp(w, fmt.Errorf("package is required"), nil, http.StatusBadRequest)
+		return
+	}
+	deployments, err := impl.sbomService.FindDeployedArtifactsByPackage(packageName, version)
+	if err != nil {
+		impl.logger.Errorw("service err, SearchSbomPackage", "err", err, "package", packageName, "version", version)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	// only the apps and environments the user can see are returned
+	token := r.Header.Get("token")
+	result := make([]*security2.SbomComponentDeployment, 0)
+	for _, deployment := range deployments {
+		object := impl.enforcerUtil.GetAppRBACNameByAppId(deployment.AppId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+			continue
+		}
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(deployment.AppId, deployment.EnvId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionGet, object); !ok {
+			continue
+		}
+		result = append(result, deployment)
+	}
+	common.WriteJsonResp(w, nil, result, http.StatusOK)
+}
+
+func (impl ImageScanRestHandlerImpl) VulnerabilityTrend(w http.ResponseWriter, r *http.Request) {
+	request, deployments, ok := impl.getTrendDeployments(w, r)
+	if !ok {
+		return
+	}
+	res, err := impl.trendService.GetVulnerabilityTrend(request, deployments)
+	if err != nil {
+		impl.logger.Errorw("service err, VulnerabilityTrend", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ImageScanRestHandlerImpl) ExportVulnerabilityTrend(w http.ResponseWriter, r *http.Request) {
+	report := r.URL.Query().Get("report")
+	if len(report) == 0 {
+		report = security.TrendReportTrend
+	}
+	if report != security.TrendReportTrend && report != security.TrendReportPackages {
+		common.WriteJsonResp(w, fmt.Errorf("unsupported report %s", report), nil, http.StatusBadRequest)
+		return
+	}
+	request, deployments, ok := impl.getTrendDeployments(w, r)
+	if !ok {
+		return
+	}
+	content, err := impl.trendService.ExportVulnerabilityTrend(request, deployments, report)
+	if err != nil {
+		impl.logger.Errorw("service err, ExportVulnerabilityTrend", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Disposition", "attachment; filename=vulnerability-"+report+".csv")
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	_, err = w.Write(content)
+	if err != nil {
+		impl.logger.Errorw("error in writing vulnerability trend export", "err", err)
+	}
+}
+
+// getTrendDeployments writes the error response itself, deployments of app+envs the user has no access to are left out
+func (impl ImageScanRestHandlerImpl) getTrendDeployments(w http.ResponseWriter, r *http.Request) (*security.VulnerabilityTrendRequest, []*security2.DeployedArtifact, bool) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	decoder := json.NewDecoder(r.Body)
+	var request *security.VulnerabilityTrendRequest
+	err = decoder.Decode(&request)
+	if err != nil || request == nil {
+		impl.logger.Errorw("request err, VulnerabilityTrend", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return nil, nil, false
+	}
+	deployments, err := impl.trendService.FetchDeployedArtifacts(request)
+	if err != nil {
+		impl.logger.Errorw("service err, VulnerabilityTrend", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	token := r.Header.Get("token")
+	authorized := make(map[string]bool)
+	var result []*security2.DeployedArtifact
+	for _, deployment := range deployments {
+		key := fmt.Sprintf("%d-%d", deployment.AppId, deployment.EnvId)
+		ok, checked := authorized[key]
+		if !checked {
+			object := impl.enforcerUtil.GetAppRBACNameByAppId(deployment.AppId)
+			ok = impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object)
+			if ok {
+				object = impl.enforcerUtil.GetEnvRBACNameByAppId(deployment.AppId, deployment.EnvId)
+				ok = impl.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionGet, object)
+			}
+			authorized[key] = ok
+		}
+		if ok {
+			result = append(result, deployment)
+		}
+	}
+	return request, result, true
+}
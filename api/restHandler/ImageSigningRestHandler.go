@@ -0,0 +1,290 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	security2 "github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/pkg/cluster"
+	"github.com/devtron-labs/devtron/pkg/imageSigning"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type ImageSigningRestHandler interface {
+	SaveKey(w http.ResponseWriter, r *http.Request)
+	UpdateKey(w http.ResponseWriter, r *http.Request)
+	GetAllKeys(w http.ResponseWriter, r *http.Request)
+	DeleteKey(w http.ResponseWriter, r *http.Request)
+	SavePolicy(w http.ResponseWriter, r *http.Request)
+	GetPolicy(w http.ResponseWriter, r *http.Request)
+}
+
+type ImageSigningRestHandlerImpl struct {
+	logger              *zap.SugaredLogger
+	imageSigningService imageSigning.ImageSigningService
+	userService         user.UserService
+	enforcer            casbin.Enforcer
+	enforcerUtil        rbac.EnforcerUtil
+	environmentService  cluster.EnvironmentService
+	validator           *validator.Validate
+}
+
+func NewImageSigningRestHandlerImpl(logger *zap.SugaredLogger,
+	imageSigningService imageSigning.ImageSigningService,
+	userService user.UserService, enforcer casbin.Enforcer,
+	enforcerUtil rbac.EnforcerUtil, environmentService cluster.EnvironmentService,
+	validator *validator.Validate) *ImageSigningRestHandlerImpl {
+	return &ImageSigningRestHandlerImpl{
+		logger:              logger,
+		imageSigningService: imageSigningService,
+		userService:         userService,
+		enforcer:            enforcer,
+		enforcerUtil:        enforcerUtil,
+		environmentService:  environmentService,
+		validator:           validator,
+	}
+}
+
+func (impl ImageSigningRestHandlerImpl) SaveKey(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req imageSigning.ImageSigningKeyBean
+	err = decoder.Decode(&req)
+	if err != nil {
+		impl.logger.Errorw("request err, SaveKey", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(req)
+	if err != nil {
+		impl.logger.Errorw("validation err, SaveKey", "err", err, "id", req.Id)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	//private key is not logged
+	impl.logger.Infow("request payload, SaveKey", "id", req.Id, "isDefault", req.IsDefault)
+	if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+		return
+	}
+	res, err := impl.imageSigningService.SaveKey(&req, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, SaveKey", "err", err, "id", req.Id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ImageSigningRestHandlerImpl) UpdateKey(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req imageSigning.ImageSigningKeyBean
+	err = decoder.Decode(&req)
+	if err != nil {
+		impl.logger.Errorw("request err, UpdateKey", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(req)
+	if err != nil {
+		impl.logger.Errorw("validation err, UpdateKey", "err", err, "id", req.Id)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	impl.logger.Infow("request payload, UpdateKey", "id", req.Id, "isDefault", req.IsDefault)
+	if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+		return
+	}
+	res, err := impl.imageSigningService.UpdateKey(&req, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, UpdateKey", "err", err, "id", req.Id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ImageSigningRestHandlerImpl) GetAllKeys(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	//only public keys are returned, policies at any level refer to them
+	res, err := impl.imageSigningService.GetAllKeys()
+	if err != nil {
+		impl.logger.Errorw("service err, GetAllKeys", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ImageSigningRestHandlerImpl) DeleteKey(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["id"]
+	if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+		return
+	}
+	err = impl.imageSigningService.DeleteKey(id, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, DeleteKey", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, id, http.StatusOK)
+}
+
+func (impl ImageSigningRestHandlerImpl) SavePolicy(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req imageSigning.ImageSignaturePolicyRequest
+	err = decoder.Decode(&req)
+	if err != nil {
+		impl.logger.Errorw("request err, SavePolicy", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(req)
+	if err != nil {
+		impl.logger.Errorw("validation err, SavePolicy", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	impl.logger.Infow("request payload, SavePolicy", "payload", req)
+	token := r.Header.Get("token")
+	//AUTH - same access as vulnerability policies
+	if req.AppId > 0 && req.EnvId > 0 {
+		object := impl.enforcerUtil.GetAppRBACNameByAppId(req.AppId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionCreate, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(req.AppId, req.EnvId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionCreate, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	} else if req.AppId == 0 && req.EnvId > 0 {
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionCreate, "*"); !ok {
+			common.WriteJsonResp(w, errors.New("unauthorized"), nil, http.StatusForbidden)
+			return
+		}
+	} else if ok := common.CheckSuperAdmin(w, impl.userService, userId); !ok {
+		return
+	}
+	//AUTH
+
+	res, err := impl.imageSigningService.SavePolicy(&req, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, SavePolicy", "err", err, "payload", req)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl ImageSigningRestHandlerImpl) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	v := r.URL.Query()
+	level := v.Get("level")
+	var ids [3]int
+	for i, key := range []string{"clusterId", "envId", "appId"} {
+		if param := v.Get(key); len(param) > 0 {
+			ids[i], err = strconv.Atoi(param)
+			if err != nil {
+				impl.logger.Errorw("request err, GetPolicy", "err", err, key, param)
+				common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	clusterId, environmentId, appId := ids[0], ids[1], ids[2]
+	var policyLevel security2.PolicyLevel
+	if level == security2.Global.String() {
+		policyLevel = security2.Global
+	} else if level == security2.Cluster.String() && clusterId > 0 {
+		policyLevel = security2.Cluster
+	} else if level == security2.Environment.String() && environmentId > 0 {
+		policyLevel = security2.Environment
+	} else if level == security2.Application.String() && environmentId > 0 && appId > 0 {
+		policyLevel = security2.Application
+	} else {
+		common.WriteJsonResp(w, fmt.Errorf("invalid level %s or missing ids", level), nil, http.StatusBadRequest)
+		return
+	}
+
+	token := r.Header.Get("token")
+	//AUTH - check from casbin db
+	if policyLevel == security2.Application {
+		object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	} else if policyLevel == security2.Environment {
+		environment, err := impl.environmentService.FindById(environmentId)
+		if err != nil {
+			common.WriteJsonResp(w, err, "Failed to get environment by id", http.StatusInternalServerError)
+			return
+		}
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobalEnvironment, casbin.ActionGet, environment.EnvironmentIdentifier); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	}
+	//AUTH
+
+	res, err := impl.imageSigningService.GetPolicy(policyLevel, clusterId, environmentId, appId)
+	if err != nil {
+		impl.logger.Errorw("service err, GetPolicy", "err", err, "policyLevel", policyLevel, "clusterId", clusterId, "environmentId", environmentId, "appId", appId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
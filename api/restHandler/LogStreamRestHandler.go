@@ -0,0 +1,175 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/api/sse"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type LogStreamRestHandler interface {
+	StreamLogs(w http.ResponseWriter, r *http.Request)
+}
+
+type logStreamContextKey struct{}
+
+// logStreamContext is what the sse validator and processor of a single connection share through the request
+type logStreamContext struct {
+	namespace string
+	request   *pipeline.LogStreamRequest
+}
+
+type LogStreamRestHandlerImpl struct {
+	logger           *zap.SugaredLogger
+	logStreamService pipeline.LogStreamService
+	userService      user.UserService
+	enforcer         casbin.Enforcer
+	enforcerUtil     rbac.EnforcerUtil
+	sse              *sse.SSE
+}
+
+func NewLogStreamRestHandlerImpl(logger *zap.SugaredLogger, logStreamService pipeline.LogStreamService,
+	userService user.UserService, enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil, sse *sse.SSE) *LogStreamRestHandlerImpl {
+	return &LogStreamRestHandlerImpl{
+		logger:           logger,
+		logStreamService: logStreamService,
+		userService:      userService,
+		enforcer:         enforcer,
+		enforcerUtil:     enforcerUtil,
+		sse:              sse,
+	}
+}
+
+// StreamLogs streams the logs of a ci workflow or a pre/post cd stage as server sent events. The client resumes
+// after a reconnect from the Last-Event-ID header, or the offset query param, which is the last line number it has.
+func (impl LogStreamRestHandlerImpl) StreamLogs(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	pipelineId, err := strconv.Atoi(vars["pipelineId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	workflowId, err := strconv.Atoi(vars["workflowId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	offset := 0
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if len(lastEventId) == 0 {
+		lastEventId = r.URL.Query().Get("offset")
+	}
+	if len(lastEventId) > 0 {
+		offset, err = strconv.Atoi(lastEventId)
+		if err != nil || offset < 0 {
+			impl.logger.Errorw("request err, StreamLogs", "err", err, "pipelineId", pipelineId, "workflowId", workflowId, "offset", lastEventId)
+			common.WriteJsonResp(w, fmt.Errorf("invalid offset %s", lastEventId), nil, http.StatusBadRequest)
+			return
+		}
+	}
+	request := &pipeline.LogStreamRequest{
+		Type:       pipeline.LogStreamType(strings.ToUpper(vars["type"])),
+		PipelineId: pipelineId,
+		WorkflowId: workflowId,
+		Offset:     offset,
+	}
+	appId, envId, err := impl.logStreamService.ValidateRequest(request)
+	if err != nil {
+		impl.logger.Errorw("service err, StreamLogs", "err", err, "request", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	//RBAC
+	token := r.Header.Get("token")
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	if envId > 0 {
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(appId, envId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionGet, object); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	}
+	//RBAC
+	// the broker matches connections by namespace prefix, so every connection gets its own
+	streamContext := &logStreamContext{
+		namespace: "/log-stream/" + uuid.NewV4().String(),
+		request:   request,
+	}
+	r = r.WithContext(context.WithValue(r.Context(), logStreamContextKey{}, streamContext))
+	w.Header().Set("X-Accel-Buffering", "no")
+	sse.SubscribeHandler(impl.sse.Broker, impl.streamNamespace, impl.processLogStream).ServeHTTP(w, r)
+}
+
+func (impl LogStreamRestHandlerImpl) streamNamespace(r *http.Request) (string, error) {
+	return r.Context().Value(logStreamContextKey{}).(*logStreamContext).namespace, nil
+}
+
+func (impl LogStreamRestHandlerImpl) processLogStream(r *http.Request, receive <-chan int, send chan<- int) {
+	streamContext := r.Context().Value(logStreamContextKey{}).(*logStreamContext)
+	ctx, cancel := context.WithCancel(r.Context())
+	// the subscribe handler always signals once its connection is done
+	go func() {
+		<-receive
+		cancel()
+	}()
+	emit := func(event *pipeline.LogStreamEvent) error {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		select {
+		case impl.sse.OutboundChannel <- sse.SSEMessage{Event: event.Event, Data: data, Namespace: streamContext.namespace, Id: strconv.Itoa(event.Id)}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	err := impl.logStreamService.StreamLogs(ctx, streamContext.request, emit)
+	if err != nil && ctx.Err() == nil {
+		impl.logger.Errorw("error in streaming logs", "request", streamContext.request, "err", err)
+		_ = emit(&pipeline.LogStreamEvent{Event: pipeline.LOG_STREAM_EVENT_ERROR, Id: streamContext.request.Offset, Data: &pipeline.LogStreamError{Message: "error in streaming logs, reconnect to retry"}})
+	}
+	// the last events must be queued on the connection before it is closed
+	impl.sse.Broker.Sync()
+	select {
+	case send <- 1:
+	case <-ctx.Done():
+	}
+}
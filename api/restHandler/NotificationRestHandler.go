@@ -44,9 +44,12 @@ import (
 	"gopkg.in/go-playground/validator.v9"
 )
 
-const(
-	SLACK_CONFIG_DELETE_SUCCESS_RESP = "Slack config deleted successfully."
-	SES_CONFIG_DELETE_SUCCESS_RESP = "SES config deleted successfully."
+const (
+	SLACK_CONFIG_DELETE_SUCCESS_RESP   = "Slack config deleted successfully."
+	SES_CONFIG_DELETE_SUCCESS_RESP     = "SES config deleted successfully."
+	WEBHOOK_CONFIG_DELETE_SUCCESS_RESP = "Webhook config deleted successfully."
+	SMTP_CONFIG_DELETE_SUCCESS_RESP    = "SMTP config deleted successfully."
+	SMTP_TEST_MAIL_SUCCESS_RESP        = "Test mail sent successfully."
 )
 
 type NotificationRestHandler interface {
@@ -56,6 +59,9 @@ type NotificationRestHandler interface {
 	SaveNotificationChannelConfig(w http.ResponseWriter, r *http.Request)
 	FindSESConfig(w http.ResponseWriter, r *http.Request)
 	FindSlackConfig(w http.ResponseWriter, r *http.Request)
+	FindWebhookConfig(w http.ResponseWriter, r *http.Request)
+	FindSMTPConfig(w http.ResponseWriter, r *http.Request)
+	SendSMTPTestMail(w http.ResponseWriter, r *http.Request)
 	FindAllNotificationConfig(w http.ResponseWriter, r *http.Request)
 	GetAllNotificationSettings(w http.ResponseWriter, r *http.Request)
 	DeleteNotificationSettings(w http.ResponseWriter, r *http.Request)
@@ -64,6 +70,10 @@ type NotificationRestHandler interface {
 	RecipientListingSuggestion(w http.ResponseWriter, r *http.Request)
 	FindAllNotificationConfigAutocomplete(w http.ResponseWriter, r *http.Request)
 	GetOptionsForNotificationSettings(w http.ResponseWriter, r *http.Request)
+
+	SaveSubscription(w http.ResponseWriter, r *http.Request)
+	GetSubscriptions(w http.ResponseWriter, r *http.Request)
+	DeleteSubscription(w http.ResponseWriter, r *http.Request)
 }
 type NotificationRestHandlerImpl struct {
 	dockerRegistryConfig pipeline.DockerRegistryConfig
@@ -75,11 +85,14 @@ type NotificationRestHandlerImpl struct {
 	notificationService  notifier.NotificationConfigService
 	slackService         notifier.SlackNotificationService
 	sesService           notifier.SESNotificationService
+	webhookService       notifier.WebhookNotificationService
+	smtpService          notifier.SMTPNotificationService
 	enforcer             casbin.Enforcer
 	teamService          team.TeamService
 	environmentService   cluster.EnvironmentService
 	pipelineBuilder      pipeline.PipelineBuilder
 	enforcerUtil         rbac.EnforcerUtil
+	subscriptionService  notifier.NotificationSubscriptionService
 }
 
 type ChannelDto struct {
@@ -90,9 +103,10 @@ func NewNotificationRestHandlerImpl(dockerRegistryConfig pipeline.DockerRegistry
 	logger *zap.SugaredLogger, gitRegistryConfig pipeline.GitRegistryConfig,
 	dbConfigService pipeline.DbConfigService, userAuthService user.UserService,
 	validator *validator.Validate, notificationService notifier.NotificationConfigService,
-	slackService notifier.SlackNotificationService, sesService notifier.SESNotificationService, enforcer casbin.Enforcer,
+	slackService notifier.SlackNotificationService, sesService notifier.SESNotificationService,
+	webhookService notifier.WebhookNotificationService, smtpService notifier.SMTPNotificationService, enforcer casbin.Enforcer,
 	teamService team.TeamService, environmentService cluster.EnvironmentService, pipelineBuilder pipeline.PipelineBuilder,
-	enforcerUtil rbac.EnforcerUtil) *NotificationRestHandlerImpl {
+	enforcerUtil rbac.EnforcerUtil, subscriptionService notifier.NotificationSubscriptionService) *NotificationRestHandlerImpl {
 	return &NotificationRestHandlerImpl{
 		dockerRegistryConfig: dockerRegistryConfig,
 		logger:               logger,
@@ -103,11 +117,14 @@ func NewNotificationRestHandlerImpl(dockerRegistryConfig pipeline.DockerRegistry
 		notificationService:  notificationService,
 		slackService:         slackService,
 		sesService:           sesService,
+		webhookService:       webhookService,
+		smtpService:          smtpService,
 		enforcer:             enforcer,
 		teamService:          teamService,
 		environmentService:   environmentService,
 		pipelineBuilder:      pipelineBuilder,
 		enforcerUtil:         enforcerUtil,
+		subscriptionService:  subscriptionService,
 	}
 }
 
@@ -515,12 +532,78 @@ func (impl NotificationRestHandlerImpl) SaveNotificationChannelConfig(w http.Res
 		}
 		w.Header().Set("Content-Type", "application/json")
 		common.WriteJsonResp(w, nil, res, http.StatusOK)
+	} else if util.Webhook == channelReq.Channel {
+		var webhookReq *notifier.WebhookChannelConfig
+		err = json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(data))).Decode(&webhookReq)
+		if err != nil {
+			impl.logger.Errorw("request err, SaveNotificationChannelConfig", "err", err, "webhookReq", webhookReq)
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+
+		err = impl.validator.Struct(webhookReq)
+		if err != nil {
+			impl.logger.Errorw("validation err, SaveNotificationChannelConfig", "err", err, "webhookReq", webhookReq)
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+
+		// RBAC enforcer applying
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionCreate, "*"); !ok {
+			response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+			return
+		}
+		//RBAC enforcer Ends
+
+		res, cErr := impl.webhookService.SaveOrEditNotificationConfig(webhookReq.WebhookConfigDtos, userId)
+		if cErr != nil {
+			impl.logger.Errorw("service err, SaveNotificationChannelConfig", "err", cErr, "webhookReq", webhookReq)
+			common.WriteJsonResp(w, cErr, nil, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		common.WriteJsonResp(w, nil, res, http.StatusOK)
+	} else if util.SMTP == channelReq.Channel {
+		var smtpReq *notifier.SMTPChannelConfig
+		err = json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(data))).Decode(&smtpReq)
+		if err != nil {
+			impl.logger.Errorw("request err, SaveNotificationChannelConfig", "err", err, "smtpReq", smtpReq)
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+
+		err = impl.validator.Struct(smtpReq)
+		if err != nil {
+			impl.logger.Errorw("validation err, SaveNotificationChannelConfig", "err", err, "smtpReq", smtpReq)
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+
+		// RBAC enforcer applying
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionCreate, "*"); !ok {
+			response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+			return
+		}
+		//RBAC enforcer Ends
+
+		res, cErr := impl.smtpService.SaveOrEditNotificationConfig(smtpReq.SMTPConfigDtos, userId)
+		if cErr != nil {
+			impl.logger.Errorw("service err, SaveNotificationChannelConfig", "err", cErr, "smtpReq", smtpReq)
+			common.WriteJsonResp(w, cErr, nil, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		common.WriteJsonResp(w, nil, res, http.StatusOK)
+	} else {
+		common.WriteJsonResp(w, fmt.Errorf(" The channel you requested is not supported"), nil, http.StatusBadRequest)
 	}
 }
 
 type ChannelResponseDTO struct {
-	SlackConfigs []*notifier.SlackConfigDto `json:"slackConfigs"`
-	SESConfigs   []*notifier.SESConfigDto   `json:"sesConfigs"`
+	SlackConfigs   []*notifier.SlackConfigDto   `json:"slackConfigs"`
+	SESConfigs     []*notifier.SESConfigDto     `json:"sesConfigs"`
+	WebhookConfigs []*notifier.WebhookConfigDto `json:"webhookConfigs"`
+	SMTPConfigs    []*notifier.SMTPConfigDto    `json:"smtpConfigs"`
 }
 
 func (impl NotificationRestHandlerImpl) FindAllNotificationConfig(w http.ResponseWriter, r *http.Request) {
@@ -581,6 +664,24 @@ func (impl NotificationRestHandlerImpl) FindAllNotificationConfig(w http.Respons
 	if pass {
 		channelsResponse.SESConfigs = sesConfigs
 	}
+	webhookConfigs, fErr := impl.webhookService.FetchAllWebhookNotificationConfig()
+	if fErr != nil && fErr != pg.ErrNoRows {
+		impl.logger.Errorw("service err, FindAllNotificationConfig", "err", fErr)
+		common.WriteJsonResp(w, fErr, nil, http.StatusInternalServerError)
+		return
+	}
+	if pass {
+		channelsResponse.WebhookConfigs = webhookConfigs
+	}
+	smtpConfigs, fErr := impl.smtpService.FetchAllSMTPNotificationConfig()
+	if fErr != nil && fErr != pg.ErrNoRows {
+		impl.logger.Errorw("service err, FindAllNotificationConfig", "err", fErr)
+		common.WriteJsonResp(w, fErr, nil, http.StatusInternalServerError)
+		return
+	}
+	if pass {
+		channelsResponse.SMTPConfigs = smtpConfigs
+	}
 	w.Header().Set("Content-Type", "application/json")
 	common.WriteJsonResp(w, fErr, channelsResponse, http.StatusOK)
 }
@@ -639,6 +740,108 @@ func (impl NotificationRestHandlerImpl) FindSlackConfig(w http.ResponseWriter, r
 	common.WriteJsonResp(w, fErr, sesConfig, http.StatusOK)
 }
 
+func (impl NotificationRestHandlerImpl) FindWebhookConfig(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		impl.logger.Errorw("request err, FindWebhookConfig", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionGet, "*"); !ok {
+		response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+		return
+	}
+
+	webhookConfig, fErr := impl.webhookService.FetchWebhookNotificationConfigById(id)
+	if fErr != nil && fErr != pg.ErrNoRows {
+		impl.logger.Errorw("service err, FindWebhookConfig, cannot find webhook config", "err", fErr, "id", id)
+		common.WriteJsonResp(w, fErr, nil, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	common.WriteJsonResp(w, fErr, webhookConfig, http.StatusOK)
+}
+
+func (impl NotificationRestHandlerImpl) FindSMTPConfig(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		impl.logger.Errorw("request err, FindSMTPConfig", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionGet, "*"); !ok {
+		response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+		return
+	}
+
+	smtpConfig, fErr := impl.smtpService.FetchSMTPNotificationConfigById(id)
+	if fErr != nil && fErr != pg.ErrNoRows {
+		impl.logger.Errorw("service err, FindSMTPConfig, cannot find smtp config", "err", fErr, "id", id)
+		common.WriteJsonResp(w, fErr, nil, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	common.WriteJsonResp(w, fErr, smtpConfig, http.StatusOK)
+}
+
+func (impl NotificationRestHandlerImpl) SendSMTPTestMail(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var request notifier.SMTPTestMailRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		impl.logger.Errorw("request err, SendSMTPTestMail", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(request)
+	if err == nil && request.Config != nil {
+		err = impl.validator.Struct(request.Config)
+	} else if err == nil && request.ConfigId == 0 {
+		err = fmt.Errorf("either configId or config is required")
+	}
+	if err != nil {
+		impl.logger.Errorw("validation err, SendSMTPTestMail", "err", err, "toEmail", request.ToEmail)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+
+	// RBAC enforcer applying
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionCreate, "*"); !ok {
+		response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+		return
+	}
+	//RBAC enforcer Ends
+
+	err = impl.smtpService.SendTestMail(&request)
+	if err != nil {
+		impl.logger.Errorw("service err, SendSMTPTestMail", "err", err, "configId", request.ConfigId, "toEmail", request.ToEmail)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, SMTP_TEST_MAIL_SUCCESS_RESP, http.StatusOK)
+}
+
 func (impl NotificationRestHandlerImpl) RecipientListingSuggestion(w http.ResponseWriter, r *http.Request) {
 	userId, err := impl.userAuthService.GetLoggedInUser(r)
 	if userId == 0 || err != nil {
@@ -710,6 +913,20 @@ func (impl NotificationRestHandlerImpl) FindAllNotificationConfigAutocomplete(w
 			common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 			return
 		}
+	} else if cType == string(util.Webhook) {
+		channelsResponse, err = impl.webhookService.FetchAllWebhookNotificationConfigAutocomplete()
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("service err, FindAllNotificationConfigAutocomplete", "err", err)
+			common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+			return
+		}
+	} else if cType == string(util.SMTP) {
+		channelsResponse, err = impl.smtpService.FetchAllSMTPNotificationConfigAutocomplete()
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("service err, FindAllNotificationConfigAutocomplete", "err", err)
+			common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+			return
+		}
 	}
 	if channelsResponse == nil {
 		channelsResponse = make([]*notifier.NotificationChannelAutoResponse, 0)
@@ -787,7 +1004,7 @@ func (impl NotificationRestHandlerImpl) GetOptionsForNotificationSettings(w http
 	common.WriteJsonResp(w, err, filteredSettingViews, http.StatusOK)
 }
 
-func (impl NotificationRestHandlerImpl) DeleteNotificationChannelConfig(w http.ResponseWriter, r *http.Request){
+func (impl NotificationRestHandlerImpl) DeleteNotificationChannelConfig(w http.ResponseWriter, r *http.Request) {
 	userId, err := impl.userAuthService.GetLoggedInUser(r)
 	if userId == 0 || err != nil {
 		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
@@ -865,7 +1082,135 @@ func (impl NotificationRestHandlerImpl) DeleteNotificationChannelConfig(w http.R
 			return
 		}
 		common.WriteJsonResp(w, nil, SES_CONFIG_DELETE_SUCCESS_RESP, http.StatusOK)
+	} else if util.Webhook == channelReq.Channel {
+		var deleteReq *notifier.WebhookConfigDto
+		err = json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(data))).Decode(&deleteReq)
+		if err != nil {
+			impl.logger.Errorw("request err, DeleteNotificationChannelConfig", "err", err, "deleteReq", deleteReq)
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+
+		// RBAC enforcer applying
+		token := r.Header.Get("token")
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionCreate, "*"); !ok {
+			response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+			return
+		}
+		//RBAC enforcer Ends
+
+		cErr := impl.webhookService.DeleteNotificationConfig(deleteReq, userId)
+		if cErr != nil {
+			impl.logger.Errorw("service err, DeleteNotificationChannelConfig", "err", cErr, "deleteReq", deleteReq)
+			common.WriteJsonResp(w, cErr, nil, http.StatusInternalServerError)
+			return
+		}
+		common.WriteJsonResp(w, nil, WEBHOOK_CONFIG_DELETE_SUCCESS_RESP, http.StatusOK)
+	} else if util.SMTP == channelReq.Channel {
+		var deleteReq *notifier.SMTPConfigDto
+		err = json.NewDecoder(ioutil.NopCloser(bytes.NewBuffer(data))).Decode(&deleteReq)
+		if err != nil {
+			impl.logger.Errorw("request err, DeleteNotificationChannelConfig", "err", err, "deleteReq", deleteReq)
+			common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+			return
+		}
+
+		// RBAC enforcer applying
+		token := r.Header.Get("token")
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceNotification, casbin.ActionCreate, "*"); !ok {
+			response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+			return
+		}
+		//RBAC enforcer Ends
+
+		cErr := impl.smtpService.DeleteNotificationConfig(deleteReq, userId)
+		if cErr != nil {
+			impl.logger.Errorw("service err, DeleteNotificationChannelConfig", "err", cErr, "deleteReq", deleteReq)
+			common.WriteJsonResp(w, cErr, nil, http.StatusInternalServerError)
+			return
+		}
+		common.WriteJsonResp(w, nil, SMTP_CONFIG_DELETE_SUCCESS_RESP, http.StatusOK)
 	} else {
 		common.WriteJsonResp(w, fmt.Errorf(" The channel you requested is not supported"), nil, http.StatusBadRequest)
 	}
-}
\ No newline at end of file
+}
+func (impl NotificationRestHandlerImpl) SaveSubscription(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var request notifier.NotificationSubscriptionDto
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		impl.logger.Errorw("request err, SaveSubscription", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(request)
+	if err != nil {
+		impl.logger.Errorw("validation err, SaveSubscription", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+
+	// RBAC enforcer applying, users can only subscribe to apps they can read
+	token := r.Header.Get("token")
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(request.AppId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, object); !ok {
+		response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+		return
+	}
+	if request.EnvId > 0 {
+		object = impl.enforcerUtil.GetEnvRBACNameByAppId(request.AppId, request.EnvId)
+		if ok := impl.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionGet, object); !ok {
+			response.WriteResponse(http.StatusForbidden, "FORBIDDEN", w, errors.New("unauthorized"))
+			return
+		}
+	}
+	//RBAC enforcer Ends
+
+	res, err := impl.subscriptionService.Subscribe(&request, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, SaveSubscription", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl NotificationRestHandlerImpl) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	res, err := impl.subscriptionService.GetSubscriptions(userId)
+	if err != nil {
+		impl.logger.Errorw("service err, GetSubscriptions", "err", err, "userId", userId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl NotificationRestHandlerImpl) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	// subscriptions are owned by the user, the service only deletes their own
+	err = impl.subscriptionService.DeleteSubscription(id, userId)
+	if err != nil {
+		impl.logger.Errorw("service err, DeleteSubscription", "err", err, "id", id)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, id, http.StatusOK)
+}
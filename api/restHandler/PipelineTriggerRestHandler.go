@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"github.com/devtron-labs/devtron/api/bean"
 	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	"github.com/devtron-labs/devtron/pkg/app"
 	"github.com/devtron-labs/devtron/pkg/deploymentGroup"
 	"github.com/devtron-labs/devtron/pkg/pipeline"
@@ -30,43 +31,59 @@ import (
 	"github.com/devtron-labs/devtron/pkg/user"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
 	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 	"gopkg.in/go-playground/validator.v9"
 	"net/http"
+	"strconv"
 )
 
 type PipelineTriggerRestHandler interface {
 	OverrideConfig(w http.ResponseWriter, r *http.Request)
+	DryRunOverrideConfig(w http.ResponseWriter, r *http.Request)
 	ReleaseStatusUpdate(w http.ResponseWriter, r *http.Request)
 	StartStopApp(w http.ResponseWriter, r *http.Request)
 	StartStopDeploymentGroup(w http.ResponseWriter, r *http.Request)
+	RaiseDeploymentApproval(w http.ResponseWriter, r *http.Request)
+	SubmitDeploymentApprovalAction(w http.ResponseWriter, r *http.Request)
+	FetchDeploymentApprovals(w http.ResponseWriter, r *http.Request)
+	FetchAutoRollbackHistory(w http.ResponseWriter, r *http.Request)
 }
 
 type PipelineTriggerRestHandlerImpl struct {
-	appService             app.AppService
-	userAuthService        user.UserService
-	validator              *validator.Validate
-	enforcer               casbin.Enforcer
-	teamService            team.TeamService
-	logger                 *zap.SugaredLogger
-	workflowDagExecutor    pipeline.WorkflowDagExecutor
-	enforcerUtil           rbac.EnforcerUtil
-	deploymentGroupService deploymentGroup.DeploymentGroupService
+	appService                    app.AppService
+	userAuthService               user.UserService
+	validator                     *validator.Validate
+	enforcer                      casbin.Enforcer
+	teamService                   team.TeamService
+	logger                        *zap.SugaredLogger
+	workflowDagExecutor           pipeline.WorkflowDagExecutor
+	enforcerUtil                  rbac.EnforcerUtil
+	deploymentGroupService        deploymentGroup.DeploymentGroupService
+	deploymentApprovalService     pipeline.DeploymentApprovalService
+	deploymentAutoRollbackService pipeline.DeploymentAutoRollbackService
+	deploymentDryRunService       pipeline.DeploymentDryRunService
 }
 
 func NewPipelineRestHandler(appService app.AppService, userAuthService user.UserService, validator *validator.Validate,
 	enforcer casbin.Enforcer, teamService team.TeamService, logger *zap.SugaredLogger, enforcerUtil rbac.EnforcerUtil,
-	workflowDagExecutor pipeline.WorkflowDagExecutor, deploymentGroupService deploymentGroup.DeploymentGroupService) *PipelineTriggerRestHandlerImpl {
+	workflowDagExecutor pipeline.WorkflowDagExecutor, deploymentGroupService deploymentGroup.DeploymentGroupService,
+	deploymentApprovalService pipeline.DeploymentApprovalService,
+	deploymentAutoRollbackService pipeline.DeploymentAutoRollbackService,
+	deploymentDryRunService pipeline.DeploymentDryRunService) *PipelineTriggerRestHandlerImpl {
 	pipelineHandler := &PipelineTriggerRestHandlerImpl{
-		appService:             appService,
-		userAuthService:        userAuthService,
-		validator:              validator,
-		enforcer:               enforcer,
-		teamService:            teamService,
-		logger:                 logger,
-		workflowDagExecutor:    workflowDagExecutor,
-		enforcerUtil:           enforcerUtil,
-		deploymentGroupService: deploymentGroupService,
+		appService:                    appService,
+		userAuthService:               userAuthService,
+		validator:                     validator,
+		enforcer:                      enforcer,
+		teamService:                   teamService,
+		logger:                        logger,
+		workflowDagExecutor:           workflowDagExecutor,
+		enforcerUtil:                  enforcerUtil,
+		deploymentGroupService:        deploymentGroupService,
+		deploymentApprovalService:     deploymentApprovalService,
+		deploymentAutoRollbackService: deploymentAutoRollbackService,
+		deploymentDryRunService:       deploymentDryRunService,
 	}
 	return pipelineHandler
 }
@@ -119,6 +136,53 @@ func (handler PipelineTriggerRestHandlerImpl) OverrideConfig(w http.ResponseWrit
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
+func (handler PipelineTriggerRestHandlerImpl) DryRunOverrideConfig(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	userId, err := handler.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var overrideRequest bean.ValuesOverrideRequest
+	err = decoder.Decode(&overrideRequest)
+	if err != nil {
+		handler.logger.Errorw("request err, DryRunOverrideConfig", "err", err, "payload", overrideRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	overrideRequest.UserId = userId
+	handler.logger.Infow("request payload, DryRunOverrideConfig", "payload", overrideRequest)
+	err = handler.validator.Struct(overrideRequest)
+	if err != nil {
+		handler.logger.Errorw("validation err, DryRunOverrideConfig", "err", err, "payload", overrideRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+
+	//rbac block starts from here, same as an actual trigger
+	object := handler.enforcerUtil.GetAppRBACNameByAppId(overrideRequest.AppId)
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionTrigger, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	object = handler.enforcerUtil.GetAppRBACByAppIdAndPipelineId(overrideRequest.AppId, overrideRequest.PipelineId)
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionTrigger, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	//rback block ends here
+
+	ctx := context.WithValue(r.Context(), "token", token)
+	res, err := handler.deploymentDryRunService.DryRun(&overrideRequest, r.URL.Query().Get("compareWith"), ctx)
+	if err != nil {
+		handler.logger.Errorw("service err, DryRunOverrideConfig", "err", err, "payload", overrideRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
 func (handler PipelineTriggerRestHandlerImpl) StartStopApp(w http.ResponseWriter, r *http.Request) {
 	decoder := json.NewDecoder(r.Body)
 	userId, err := handler.userAuthService.GetLoggedInUser(r)
@@ -250,3 +314,156 @@ func (handler PipelineTriggerRestHandlerImpl) ReleaseStatusUpdate(w http.Respons
 	}
 	common.WriteJsonResp(w, err, resJson, http.StatusOK)
 }
+
+func (handler PipelineTriggerRestHandlerImpl) RaiseDeploymentApproval(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	userId, err := handler.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var approvalRequest pipeline.DeploymentApprovalTriggerRequest
+	err = decoder.Decode(&approvalRequest)
+	if err != nil {
+		handler.logger.Errorw("request err, RaiseDeploymentApproval", "err", err, "payload", approvalRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	approvalRequest.UserId = userId
+	err = handler.validator.Struct(approvalRequest)
+	if err != nil {
+		handler.logger.Errorw("validation err, RaiseDeploymentApproval", "err", err, "payload", approvalRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	//rbac block starts from here
+	appObject, envObject := handler.enforcerUtil.GetTeamAndEnvironmentRbacObjectByCDPipelineId(approvalRequest.PipelineId)
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionTrigger, appObject); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceEnvironment, casbin.ActionTrigger, envObject); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	//rback block ends here
+	res, err := handler.deploymentApprovalService.RaiseApprovalRequest(&approvalRequest)
+	if err != nil {
+		handler.logger.Errorw("service err, RaiseDeploymentApproval", "err", err, "payload", approvalRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler PipelineTriggerRestHandlerImpl) SubmitDeploymentApprovalAction(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	userId, err := handler.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var actionRequest pipeline.DeploymentApprovalActionRequest
+	err = decoder.Decode(&actionRequest)
+	if err != nil {
+		handler.logger.Errorw("request err, SubmitDeploymentApprovalAction", "err", err, "payload", actionRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	actionRequest.UserId = userId
+	err = handler.validator.Struct(actionRequest)
+	if err != nil {
+		handler.logger.Errorw("validation err, SubmitDeploymentApprovalAction", "err", err, "payload", actionRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	//approvers are configured per pipeline, the service rejects users who are not one of them
+	res, err := handler.deploymentApprovalService.SubmitApprovalAction(&actionRequest)
+	if err != nil {
+		handler.logger.Errorw("service err, SubmitDeploymentApprovalAction", "err", err, "payload", actionRequest)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	if res.Status == pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED {
+		err = handler.workflowDagExecutor.HandleDeploymentApprovalSuccess(res.PipelineId, res.CiArtifactId, userId)
+		if err != nil {
+			handler.logger.Errorw("error in triggering approved deployment", "err", err, "payload", actionRequest)
+		}
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler PipelineTriggerRestHandlerImpl) FetchDeploymentApprovals(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	pipelineId, err := strconv.Atoi(vars["pipelineId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	//rbac block starts from here
+	appObject, _ := handler.enforcerUtil.GetTeamAndEnvironmentRbacObjectByCDPipelineId(pipelineId)
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, appObject); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	//rback block ends here
+	res, err := handler.deploymentApprovalService.FetchApprovalRequests(pipelineId)
+	if err != nil {
+		handler.logger.Errorw("service err, FetchDeploymentApprovals", "err", err, "pipelineId", pipelineId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (handler PipelineTriggerRestHandlerImpl) FetchAutoRollbackHistory(w http.ResponseWriter, r *http.Request) {
+	userId, err := handler.userAuthService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	vars := mux.Vars(r)
+	pipelineId, err := strconv.Atoi(vars["pipelineId"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	offset := 0
+	if offsetQueryParam := r.URL.Query().Get("offset"); offsetQueryParam != "" {
+		offset, err = strconv.Atoi(offsetQueryParam)
+		if err != nil || offset < 0 {
+			common.WriteJsonResp(w, err, "invalid offset", http.StatusBadRequest)
+			return
+		}
+	}
+	size := 20
+	if sizeQueryParam := r.URL.Query().Get("size"); sizeQueryParam != "" {
+		size, err = strconv.Atoi(sizeQueryParam)
+		if err != nil || size <= 0 {
+			common.WriteJsonResp(w, err, "invalid size", http.StatusBadRequest)
+			return
+		}
+	}
+	token := r.Header.Get("token")
+	//rbac block starts from here
+	appObject, _ := handler.enforcerUtil.GetTeamAndEnvironmentRbacObjectByCDPipelineId(pipelineId)
+	if ok := handler.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionGet, appObject); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	//rback block ends here
+	res, err := handler.deploymentAutoRollbackService.GetAutoRollbackHistory(pipelineId, offset, size)
+	if err != nil {
+		handler.logger.Errorw("service err, FetchAutoRollbackHistory", "err", err, "pipelineId", pipelineId)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
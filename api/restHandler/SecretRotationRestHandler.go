@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type SecretRotationRestHandler interface {
+	RotateSecret(w http.ResponseWriter, r *http.Request)
+	GetRotationJob(w http.ResponseWriter, r *http.Request)
+}
+
+type SecretRotationRestHandlerImpl struct {
+	logger                *zap.SugaredLogger
+	secretRotationService pipeline.SecretRotationService
+	userService           user.UserService
+	enforcer              casbin.Enforcer
+	enforcerUtil          rbac.EnforcerUtil
+	validator             *validator.Validate
+	auditLogService       auditLog.AuditLogService
+}
+
+func NewSecretRotationRestHandlerImpl(logger *zap.SugaredLogger, secretRotationService pipeline.SecretRotationService,
+	userService user.UserService, enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil, validator *validator.Validate,
+	auditLogService auditLog.AuditLogService) *SecretRotationRestHandlerImpl {
+	return &SecretRotationRestHandlerImpl{
+		logger:                logger,
+		secretRotationService: secretRotationService,
+		userService:           userService,
+		enforcer:              enforcer,
+		enforcerUtil:          enforcerUtil,
+		validator:             validator,
+		auditLogService:       auditLogService,
+	}
+}
+
+func (impl SecretRotationRestHandlerImpl) RotateSecret(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var request pipeline.SecretRotationRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		impl.logger.Errorw("request err, RotateSecret", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(request)
+	if err != nil {
+		impl.logger.Errorw("validation err, RotateSecret", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	request.UserId = userId
+	impl.logger.Infow("request payload, RotateSecret", "secretName", request.SecretName, "key", request.Key, "targets", len(request.Targets))
+	// rotation edits the secret and redeploys, so both are needed on every target
+	token := r.Header.Get("token")
+	for _, target := range request.Targets {
+		if ok := impl.checkAuth(token, target.AppId, target.EnvId, casbin.ActionUpdate); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+		if ok := impl.checkAuth(token, target.AppId, target.EnvId, casbin.ActionTrigger); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	}
+	res, err := impl.secretRotationService.RotateSecret(&request)
+	if err != nil {
+		impl.logger.Errorw("service err, RotateSecret", "err", err, "secretName", request.SecretName)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	impl.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntitySecretRotation,
+		EntityId:   strconv.Itoa(res.Id),
+		Request:    r,
+		Current:    res,
+		MaskValues: true,
+	})
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl SecretRotationRestHandlerImpl) GetRotationJob(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	res, err := impl.secretRotationService.GetRotationJob(id)
+	if err != nil {
+		impl.logger.Errorw("service err, GetRotationJob", "err", err, "id", id)
+		if util.IsErrNoRows(err) {
+			common.WriteJsonResp(w, err, "secret rotation job not found", http.StatusNotFound)
+			return
+		}
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	token := r.Header.Get("token")
+	for _, pipeline := range res.Pipelines {
+		if ok := impl.checkAuth(token, pipeline.AppId, pipeline.EnvId, casbin.ActionGet); !ok {
+			common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+			return
+		}
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl SecretRotationRestHandlerImpl) checkAuth(token string, appId int, envId int, action string) bool {
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, action, object); !ok {
+		return false
+	}
+	object = impl.enforcerUtil.GetEnvRBACNameByAppId(appId, envId)
+	return impl.enforcer.Enforce(token, casbin.ResourceEnvironment, action, object)
+}
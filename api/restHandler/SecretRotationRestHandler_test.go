@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package restHandler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+type rotationUserServiceStub struct {
+	user.UserService
+}
+
+func (impl rotationUserServiceStub) GetLoggedInUser(r *http.Request) (int32, error) {
+	return 2, nil
+}
+
+// rotationEnforcerStub allows the resource, action and object triples in policies
+type rotationEnforcerStub struct {
+	casbin.Enforcer
+	policies map[string]bool
+}
+
+func (impl rotationEnforcerStub) Enforce(rvals ...interface{}) bool {
+	return impl.policies[fmt.Sprintf("%s/%s/%s", rvals[1], rvals[2], rvals[3])]
+}
+
+type rotationEnforcerUtilStub struct {
+	rbac.EnforcerUtil
+}
+
+func (impl rotationEnforcerUtilStub) GetAppRBACNameByAppId(appId int) string {
+	return fmt.Sprintf("team/app%d", appId)
+}
+
+func (impl rotationEnforcerUtilStub) GetEnvRBACNameByAppId(appId int, envId int) string {
+	return fmt.Sprintf("env%d/app%d", envId, appId)
+}
+
+type rotationServiceStub struct {
+	pipeline.SecretRotationService
+	requests []*pipeline.SecretRotationRequest
+}
+
+func (impl *rotationServiceStub) RotateSecret(request *pipeline.SecretRotationRequest) (*pipeline.SecretRotationJobDto, error) {
+	impl.requests = append(impl.requests, request)
+	return &pipeline.SecretRotationJobDto{Id: 1, SecretName: request.SecretName, Key: request.Key}, nil
+}
+
+type rotationAuditLogServiceStub struct {
+	auditLog.AuditLogService
+}
+
+func (impl rotationAuditLogServiceStub) SaveAuditLog(request *auditLog.AuditLogRequest) {
+}
+
+// rotationPolicies gives update and trigger on app 1 in env 1 and 2, env 3 has update only
+func rotationPolicies() map[string]bool {
+	return map[string]bool{
+		"applications/update/team/app1":  true,
+		"applications/trigger/team/app1": true,
+		"environment/update/env1/app1":   true,
+		"environment/trigger/env1/app1":  true,
+		"environment/update/env2/app1":   true,
+		"environment/trigger/env2/app1":  true,
+		"environment/update/env3/app1":   true,
+	}
+}
+
+func TestSecretRotationRestHandlerImpl_RotateSecret(t *testing.T) {
+	tests := []struct {
+		name       string
+		targets    string
+		wantStatus int
+	}{
+		{name: "all targets allowed", targets: `[{"appId":1,"envId":1},{"appId":1,"envId":2}]`, wantStatus: http.StatusOK},
+		{name: "trigger missing on one env", targets: `[{"appId":1,"envId":1},{"appId":1,"envId":3}]`, wantStatus: http.StatusForbidden},
+		{name: "no access to the env", targets: `[{"appId":1,"envId":4}]`, wantStatus: http.StatusForbidden},
+		{name: "no access to the app", targets: `[{"appId":1,"envId":1},{"appId":2,"envId":1}]`, wantStatus: http.StatusForbidden},
+		{name: "no targets", targets: `[]`, wantStatus: http.StatusBadRequest},
+		{name: "invalid target", targets: `[{"appId":1,"envId":0}]`, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &rotationServiceStub{}
+			handler := NewSecretRotationRestHandlerImpl(zap.NewNop().Sugar(), service, rotationUserServiceStub{},
+				rotationEnforcerStub{policies: rotationPolicies()}, rotationEnforcerUtilStub{}, validator.New(), rotationAuditLogServiceStub{})
+			body := `{"secretName":"db-secret","key":"password","value":"secret","targets":` + tt.targets + `}`
+			r := httptest.NewRequest(http.MethodPost, "/orchestrator/secret-rotation", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.RotateSecret(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("RotateSecret() status = %d, want %d, body %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if wantCalled := tt.wantStatus == http.StatusOK; wantCalled != (len(service.requests) == 1) {
+				t.Errorf("RotateSecret() called the service %d times", len(service.requests))
+			}
+			if len(service.requests) == 1 && service.requests[0].UserId != 2 {
+				t.Errorf("RotateSecret() userId = %d, want 2", service.requests[0].UserId)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package restHandler
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/restHandler/common"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/pkg/vault"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"go.uber.org/zap"
+	"gopkg.in/go-playground/validator.v9"
+	"net/http"
+	"strconv"
+)
+
+type VaultRestHandler interface {
+	SaveConfig(w http.ResponseWriter, r *http.Request)
+	GetConfig(w http.ResponseWriter, r *http.Request)
+	PreviewReferences(w http.ResponseWriter, r *http.Request)
+}
+
+type VaultReferencePreviewRequest struct {
+	AppId      int      `json:"appId" validate:"number,gt=0"`
+	References []string `json:"references" validate:"min=1"`
+}
+
+type VaultRestHandlerImpl struct {
+	logger          *zap.SugaredLogger
+	vaultService    vault.VaultService
+	userService     user.UserService
+	enforcer        casbin.Enforcer
+	enforcerUtil    rbac.EnforcerUtil
+	validator       *validator.Validate
+	auditLogService auditLog.AuditLogService
+}
+
+func NewVaultRestHandlerImpl(logger *zap.SugaredLogger, vaultService vault.VaultService, userService user.UserService,
+	enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil, validator *validator.Validate,
+	auditLogService auditLog.AuditLogService) *VaultRestHandlerImpl {
+	return &VaultRestHandlerImpl{
+		logger:          logger,
+		vaultService:    vaultService,
+		userService:     userService,
+		enforcer:        enforcer,
+		enforcerUtil:    enforcerUtil,
+		validator:       validator,
+		auditLogService: auditLogService,
+	}
+}
+
+func (impl VaultRestHandlerImpl) SaveConfig(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionCreate, "*"); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	var request vault.VaultConfigDto
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		impl.logger.Errorw("request err, SaveVaultConfig", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(request)
+	if err != nil {
+		impl.logger.Errorw("validation err, SaveVaultConfig", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	request.UserId = userId
+	impl.logger.Infow("request payload, SaveVaultConfig", "address", request.Address, "authMethod", request.AuthMethod, "mount", request.Mount)
+	previous, err := impl.vaultService.GetConfig()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("service err, SaveVaultConfig", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	res, err := impl.vaultService.SaveConfig(&request)
+	if err != nil {
+		impl.logger.Errorw("service err, SaveVaultConfig", "err", err, "address", request.Address)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	auditRequest := &auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceGlobal,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityVaultConfig,
+		EntityId:   strconv.Itoa(res.Id),
+		Request:    r,
+		Current:    res,
+	}
+	//a typed nil would be recorded as null instead of a create
+	if previous != nil {
+		auditRequest.Action = casbin.ActionUpdate
+		auditRequest.Previous = previous
+	}
+	impl.auditLogService.SaveAuditLog(auditRequest)
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+func (impl VaultRestHandlerImpl) GetConfig(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	token := r.Header.Get("token")
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceGlobal, casbin.ActionGet, "*"); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	res, err := impl.vaultService.GetConfig()
+	if err != nil {
+		if util.IsErrNoRows(err) {
+			common.WriteJsonResp(w, nil, nil, http.StatusOK)
+			return
+		}
+		impl.logger.Errorw("service err, GetVaultConfig", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
+
+// PreviewReferences is meant for the secret editor, so access to edit the app is enough
+func (impl VaultRestHandlerImpl) PreviewReferences(w http.ResponseWriter, r *http.Request) {
+	userId, err := impl.userService.GetLoggedInUser(r)
+	if userId == 0 || err != nil {
+		common.WriteJsonResp(w, err, "Unauthorized User", http.StatusUnauthorized)
+		return
+	}
+	var request VaultReferencePreviewRequest
+	err = json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		impl.logger.Errorw("request err, PreviewVaultReferences", "err", err)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	err = impl.validator.Struct(request)
+	if err != nil {
+		impl.logger.Errorw("validation err, PreviewVaultReferences", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusBadRequest)
+		return
+	}
+	token := r.Header.Get("token")
+	object := impl.enforcerUtil.GetAppRBACNameByAppId(request.AppId)
+	if ok := impl.enforcer.Enforce(token, casbin.ResourceApplications, casbin.ActionUpdate, object); !ok {
+		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return
+	}
+	res, err := impl.vaultService.PreviewReferences(request.References)
+	if err != nil {
+		impl.logger.Errorw("service err, PreviewVaultReferences", "err", err, "payload", request)
+		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return
+	}
+	common.WriteJsonResp(w, nil, res, http.StatusOK)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/devtron-labs/devtron/internal/sql/repository"
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
 	"github.com/devtron-labs/devtron/pkg/bean"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
 	"github.com/gorilla/mux"
@@ -172,12 +173,37 @@ func (handler PipelineConfigRestHandlerImpl) PatchCiPipelines(w http.ResponseWri
 		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
 		return
 	}
+	var previous interface{}
+	if patchRequest.CiPipeline != nil && patchRequest.CiPipeline.Id > 0 {
+		previous, _ = handler.pipelineBuilder.GetCiPipelineById(patchRequest.CiPipeline.Id)
+	}
 	createResp, err := handler.pipelineBuilder.PatchCiPipeline(&patchRequest)
 	if err != nil {
 		handler.Logger.Errorw("service err, PatchCiPipelines", "err", err, "PatchCiPipelines", patchRequest)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	auditRequest := &auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityCiPipeline,
+		Request:    r,
+		Previous:   previous,
+	}
+	if patchRequest.CiPipeline != nil {
+		auditRequest.EntityId = strconv.Itoa(patchRequest.CiPipeline.Id)
+		if patchRequest.Action == bean.DELETE {
+			auditRequest.Action = casbin.ActionDelete
+		} else if patchRequest.CiPipeline.Id > 0 {
+			auditRequest.Action = casbin.ActionUpdate
+			auditRequest.Current, _ = handler.pipelineBuilder.GetCiPipelineById(patchRequest.CiPipeline.Id)
+		} else {
+			auditRequest.EntityId = patchRequest.CiPipeline.Name
+			auditRequest.Current = patchRequest.CiPipeline
+		}
+	}
+	handler.auditLogService.SaveAuditLog(auditRequest)
 	common.WriteJsonResp(w, err, createResp, http.StatusOK)
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/devtron-labs/devtron/api/restHandler/common"
 	"github.com/devtron-labs/devtron/internal/sql/repository/security"
 	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
 	"github.com/devtron-labs/devtron/pkg/bean"
 	"github.com/devtron-labs/devtron/pkg/pipeline"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
@@ -245,6 +246,10 @@ func (handler PipelineConfigRestHandlerImpl) PatchCdPipeline(w http.ResponseWrit
 		return
 	}
 
+	var previous interface{}
+	if cdPipeline.Pipeline != nil && cdPipeline.Pipeline.Id > 0 {
+		previous, _ = handler.pipelineBuilder.GetCdPipelineById(cdPipeline.Pipeline.Id)
+	}
 	ctx := context.WithValue(r.Context(), "token", token)
 	createResp, err := handler.pipelineBuilder.PatchCdPipelines(&cdPipeline, ctx)
 	if err != nil {
@@ -252,6 +257,27 @@ func (handler PipelineConfigRestHandlerImpl) PatchCdPipeline(w http.ResponseWrit
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	auditRequest := &auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityCdPipeline,
+		Request:    r,
+		Previous:   previous,
+	}
+	if cdPipeline.Pipeline != nil {
+		auditRequest.EntityId = strconv.Itoa(cdPipeline.Pipeline.Id)
+		if cdPipeline.Action == bean.CD_DELETE {
+			auditRequest.Action = casbin.ActionDelete
+		} else if cdPipeline.Pipeline.Id > 0 {
+			auditRequest.Action = casbin.ActionUpdate
+			auditRequest.Current, _ = handler.pipelineBuilder.GetCdPipelineById(cdPipeline.Pipeline.Id)
+		} else {
+			auditRequest.EntityId = cdPipeline.Pipeline.Name
+			auditRequest.Current = cdPipeline.Pipeline
+		}
+	}
+	handler.auditLogService.SaveAuditLog(auditRequest)
 	common.WriteJsonResp(w, err, createResp, http.StatusOK)
 }
 
@@ -393,12 +419,31 @@ func (handler PipelineConfigRestHandlerImpl) EnvConfigOverrideUpdate(w http.Resp
 		common.WriteJsonResp(w, err2, nil, http.StatusBadRequest)
 		return
 	}
+	previous := &pipeline.EnvironmentProperties{
+		Id:                envConfigOverride.Id,
+		EnvOverrideValues: json.RawMessage(envConfigOverride.EnvOverrideValues),
+		Active:            envConfigOverride.Active,
+		Namespace:         envConfigOverride.Namespace,
+		EnvironmentId:     envId,
+		ChartRefId:        envConfigOverride.Chart.ChartRefId,
+		IsOverride:        envConfigOverride.IsOverride,
+	}
 	createResp, err := handler.propertiesConfigService.UpdateEnvironmentProperties(appId, &envConfigProperties, userId)
 	if err != nil {
 		handler.Logger.Errorw("service err, EnvConfigOverrideUpdate", "err", err, "payload", envConfigProperties)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceEnvironment,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityEnvDeploymentTemplate,
+		EntityId:   fmt.Sprintf("%d/%d", appId, envId),
+		Request:    r,
+		Previous:   previous,
+		Current:    createResp,
+	})
 	common.WriteJsonResp(w, err, createResp, http.StatusOK)
 }
 
@@ -791,12 +836,24 @@ func (handler PipelineConfigRestHandlerImpl) UpdateAppOverride(w http.ResponseWr
 		common.WriteJsonResp(w, err2, nil, http.StatusBadRequest)
 		return
 	}
+	previous, _ := handler.chartService.FindLatestChartForAppByAppId(templateRequest.AppId)
 	createResp, err := handler.chartService.UpdateAppOverride(&templateRequest)
 	if err != nil {
 		handler.Logger.Errorw("service err, UpdateAppOverride", "err", err, "payload", templateRequest)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
 		return
 	}
+	current, _ := handler.chartService.FindLatestChartForAppByAppId(templateRequest.AppId)
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceApplications,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityDeploymentTemplate,
+		EntityId:   strconv.Itoa(templateRequest.AppId),
+		Request:    r,
+		Previous:   previous,
+		Current:    current,
+	})
 	common.WriteJsonResp(w, err, createResp, http.StatusOK)
 
 }
@@ -1013,7 +1070,7 @@ func (handler PipelineConfigRestHandlerImpl) EnvConfigOverrideReset(w http.Respo
 		common.WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
 		return
 	}
-	isSuccess, err := handler.propertiesConfigService.ResetEnvironmentProperties(id)
+	isSuccess, err := handler.propertiesConfigService.ResetEnvironmentProperties(id, userId)
 	if err != nil {
 		handler.Logger.Errorw("service err, EnvConfigOverrideReset", "err", err, "appId", appId, "environmentId", environmentId)
 		common.WriteJsonResp(w, err, nil, http.StatusInternalServerError)
@@ -38,6 +38,7 @@ import (
 	"github.com/devtron-labs/devtron/internal/util"
 	"github.com/devtron-labs/devtron/pkg/appClone"
 	"github.com/devtron-labs/devtron/pkg/appWorkflow"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
 	"github.com/devtron-labs/devtron/pkg/bean"
 	request "github.com/devtron-labs/devtron/pkg/cluster"
 	"github.com/devtron-labs/devtron/pkg/pipeline"
@@ -109,6 +110,7 @@ type PipelineConfigRestHandlerImpl struct {
 	policyService           security2.PolicyService
 	scanResultRepository    security.ImageScanResultRepository
 	gitProviderRepo         repository.GitProviderRepository
+	auditLogService         auditLog.AuditLogService
 }
 
 func NewPipelineRestHandlerImpl(pipelineBuilder pipeline.PipelineBuilder, Logger *zap.SugaredLogger,
@@ -129,7 +131,8 @@ func NewPipelineRestHandlerImpl(pipelineBuilder pipeline.PipelineBuilder, Logger
 	appCloneService appClone.AppCloneService,
 	appWorkflowService appWorkflow.AppWorkflowService,
 	materialRepository pipelineConfig.MaterialRepository, policyService security2.PolicyService,
-	scanResultRepository security.ImageScanResultRepository, gitProviderRepo repository.GitProviderRepository) *PipelineConfigRestHandlerImpl {
+	scanResultRepository security.ImageScanResultRepository, gitProviderRepo repository.GitProviderRepository,
+	auditLogService auditLog.AuditLogService) *PipelineConfigRestHandlerImpl {
 	return &PipelineConfigRestHandlerImpl{
 		pipelineBuilder:         pipelineBuilder,
 		Logger:                  Logger,
@@ -156,6 +159,7 @@ func NewPipelineRestHandlerImpl(pipelineBuilder pipeline.PipelineBuilder, Logger
 		policyService:           policyService,
 		scanResultRepository:    scanResultRepository,
 		gitProviderRepo:         gitProviderRepo,
+		auditLogService:         auditLogService,
 	}
 }
 
@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package common
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"net/http"
+)
+
+// CheckSuperAdmin writes the error response itself, for apis limited to super admins
+func CheckSuperAdmin(w http.ResponseWriter, userService user.UserService, userId int32) bool {
+	isSuperAdmin, err := userService.IsSuperAdmin(int(userId))
+	if err != nil {
+		WriteJsonResp(w, err, nil, http.StatusInternalServerError)
+		return false
+	}
+	if !isSuperAdmin {
+		WriteJsonResp(w, fmt.Errorf("unauthorized user"), "Unauthorized User", http.StatusForbidden)
+		return false
+	}
+	return true
+}
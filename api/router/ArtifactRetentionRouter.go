@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type ArtifactRetentionRouter interface {
+	InitArtifactRetentionRouter(artifactRetentionRouter *mux.Router)
+}
+
+type ArtifactRetentionRouterImpl struct {
+	artifactRetentionRestHandler restHandler.ArtifactRetentionRestHandler
+}
+
+func NewArtifactRetentionRouterImpl(artifactRetentionRestHandler restHandler.ArtifactRetentionRestHandler) *ArtifactRetentionRouterImpl {
+	return &ArtifactRetentionRouterImpl{artifactRetentionRestHandler: artifactRetentionRestHandler}
+}
+
+func (impl ArtifactRetentionRouterImpl) InitArtifactRetentionRouter(artifactRetentionRouter *mux.Router) {
+	artifactRetentionRouter.Path("/policy").HandlerFunc(impl.artifactRetentionRestHandler.SavePolicy).Methods("POST")
+	artifactRetentionRouter.Path("/policy").HandlerFunc(impl.artifactRetentionRestHandler.GetPolicies).Methods("GET")
+	artifactRetentionRouter.Path("/policy/{appId}").HandlerFunc(impl.artifactRetentionRestHandler.DeletePolicy).Methods("DELETE")
+	artifactRetentionRouter.Path("/run").HandlerFunc(impl.artifactRetentionRestHandler.ApplyRetention).Methods("POST")
+	artifactRetentionRouter.Path("/run").HandlerFunc(impl.artifactRetentionRestHandler.GetRuns).Methods("GET")
+	artifactRetentionRouter.Path("/run/{id}").HandlerFunc(impl.artifactRetentionRestHandler.GetRun).Methods("GET")
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type ConfigHistoryRouter interface {
+	InitConfigHistoryRouter(configRouter *mux.Router)
+}
+
+type ConfigHistoryRouterImpl struct {
+	configHistoryRestHandler restHandler.ConfigHistoryRestHandler
+}
+
+func NewConfigHistoryRouterImpl(configHistoryRestHandler restHandler.ConfigHistoryRestHandler) *ConfigHistoryRouterImpl {
+	return &ConfigHistoryRouterImpl{
+		configHistoryRestHandler: configHistoryRestHandler,
+	}
+}
+
+func (impl ConfigHistoryRouterImpl) InitConfigHistoryRouter(configRouter *mux.Router) {
+	configRouter.Path("").HandlerFunc(impl.configHistoryRestHandler.GetHistory).Methods("GET")
+	configRouter.Path("/diff").HandlerFunc(impl.configHistoryRestHandler.GetDiff).Methods("GET")
+	configRouter.Path("/{id:[0-9]+}").HandlerFunc(impl.configHistoryRestHandler.GetVersion).Methods("GET")
+	configRouter.Path("/{id:[0-9]+}/restore").HandlerFunc(impl.configHistoryRestHandler.RestoreVersion).Methods("POST")
+}
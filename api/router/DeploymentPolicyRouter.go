@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type DeploymentPolicyRouter interface {
+	InitDeploymentPolicyRouter(configRouter *mux.Router)
+}
+
+type DeploymentPolicyRouterImpl struct {
+	deploymentPolicyRestHandler restHandler.DeploymentPolicyRestHandler
+}
+
+func NewDeploymentPolicyRouterImpl(deploymentPolicyRestHandler restHandler.DeploymentPolicyRestHandler) *DeploymentPolicyRouterImpl {
+	return &DeploymentPolicyRouterImpl{
+		deploymentPolicyRestHandler: deploymentPolicyRestHandler,
+	}
+}
+
+func (impl DeploymentPolicyRouterImpl) InitDeploymentPolicyRouter(configRouter *mux.Router) {
+	configRouter.Path("/save").HandlerFunc(impl.deploymentPolicyRestHandler.SavePolicy).Methods("POST")
+	configRouter.Path("/list").HandlerFunc(impl.deploymentPolicyRestHandler.GetPolicies).Methods("GET")
+	configRouter.Path("/evaluate").HandlerFunc(impl.deploymentPolicyRestHandler.EvaluateValues).Methods("POST")
+	configRouter.Path("/violations/{pipelineId}").HandlerFunc(impl.deploymentPolicyRestHandler.GetViolations).Methods("GET")
+}
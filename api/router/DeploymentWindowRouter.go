@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type DeploymentWindowRouter interface {
+	initDeploymentWindowRouter(deploymentWindowRouter *mux.Router)
+}
+
+type DeploymentWindowRouterImpl struct {
+	deploymentWindowRestHandler restHandler.DeploymentWindowRestHandler
+}
+
+func NewDeploymentWindowRouterImpl(deploymentWindowRestHandler restHandler.DeploymentWindowRestHandler) *DeploymentWindowRouterImpl {
+	return &DeploymentWindowRouterImpl{deploymentWindowRestHandler: deploymentWindowRestHandler}
+}
+
+func (router DeploymentWindowRouterImpl) initDeploymentWindowRouter(deploymentWindowRouter *mux.Router) {
+	deploymentWindowRouter.Path("").
+		HandlerFunc(router.deploymentWindowRestHandler.CreateWindow).Methods("POST")
+	deploymentWindowRouter.Path("").
+		HandlerFunc(router.deploymentWindowRestHandler.UpdateWindow).Methods("PUT")
+	deploymentWindowRouter.Path("/{id}").
+		HandlerFunc(router.deploymentWindowRestHandler.GetWindowById).Methods("GET")
+	deploymentWindowRouter.Path("/{id}").
+		HandlerFunc(router.deploymentWindowRestHandler.DeleteWindow).Methods("DELETE")
+	deploymentWindowRouter.Path("/env/{envId}").
+		HandlerFunc(router.deploymentWindowRestHandler.GetWindowsByEnvironmentId).Methods("GET")
+	deploymentWindowRouter.Path("/env/{envId}/app/{appId}/status").
+		HandlerFunc(router.deploymentWindowRestHandler.CheckDeploymentAllowed).Methods("GET")
+	deploymentWindowRouter.Path("/env/{envId}/override-audit").
+		HandlerFunc(router.deploymentWindowRestHandler.GetOverrideAudit).Methods("GET")
+}
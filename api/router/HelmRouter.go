@@ -58,9 +58,14 @@ type HelmRouterImpl struct {
 
 func (router HelmRouterImpl) initHelmRouter(helmRouter *mux.Router) {
 	helmRouter.Path("/cd-pipeline/trigger").HandlerFunc(router.restHandler.OverrideConfig).Methods("POST")
+	helmRouter.Path("/cd-pipeline/trigger/dry-run").HandlerFunc(router.restHandler.DryRunOverrideConfig).Methods("POST")
 	helmRouter.Path("/update-release-status").HandlerFunc(router.restHandler.ReleaseStatusUpdate).Methods("POST")
 	helmRouter.Path("/stop-start-app").HandlerFunc(router.restHandler.StartStopApp).Methods("POST")
 	helmRouter.Path("/stop-start-dg").HandlerFunc(router.restHandler.StartStopDeploymentGroup).Methods("POST")
+	helmRouter.Path("/cd-pipeline/approval/request").HandlerFunc(router.restHandler.RaiseDeploymentApproval).Methods("POST")
+	helmRouter.Path("/cd-pipeline/approval/action").HandlerFunc(router.restHandler.SubmitDeploymentApprovalAction).Methods("POST")
+	helmRouter.Path("/cd-pipeline/{pipelineId}/approval").HandlerFunc(router.restHandler.FetchDeploymentApprovals).Methods("GET")
+	helmRouter.Path("/cd-pipeline/{pipelineId}/auto-rollback").HandlerFunc(router.restHandler.FetchAutoRollbackHistory).Methods("GET")
 	helmRouter.Path("/release/").
 		Handler(sse2.SubscribeHandler(sse.Broker, PollTopic, fetchReleaseData)).
 		Methods("GET").
@@ -78,7 +83,7 @@ func fetchReleaseData(r *http.Request, receive <-chan int, send chan<- int) {
 		}
 		time.Sleep(1 * time.Second)
 		data := []byte(time.Now().String() + "-" + strconv.Itoa(i))
-		sse.OutboundChannel <- sse2.SSEMessage{"", data, "/" + name}
+		sse.OutboundChannel <- sse2.SSEMessage{Data: data, Namespace: "/" + name}
 	}
 	send <- 1
 }
@@ -42,4 +42,12 @@ func (impl ImageScanRouterImpl) InitImageScanRouter(configRouter *mux.Router) {
 
 	configRouter.Path("/cve/exposure").HandlerFunc(impl.imageScanRestHandler.VulnerabilityExposure).Methods("POST")
 
+	configRouter.Path("/sbom/artifact/{artifactId:[0-9]+}").HandlerFunc(impl.imageScanRestHandler.DownloadSbom).Methods("GET")
+	//package=openssl&version=1.1.1k, version is optional
+	configRouter.Path("/sbom/search").HandlerFunc(impl.imageScanRestHandler.SearchSbomPackage).Methods("GET")
+
+	configRouter.Path("/trend").HandlerFunc(impl.imageScanRestHandler.VulnerabilityTrend).Methods("POST")
+	//report=trend|packages
+	configRouter.Path("/trend/export").HandlerFunc(impl.imageScanRestHandler.ExportVulnerabilityTrend).Methods("POST")
+
 }
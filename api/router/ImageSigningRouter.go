@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type ImageSigningRouter interface {
+	InitImageSigningRouter(configRouter *mux.Router)
+}
+
+type ImageSigningRouterImpl struct {
+	imageSigningRestHandler restHandler.ImageSigningRestHandler
+}
+
+func NewImageSigningRouterImpl(imageSigningRestHandler restHandler.ImageSigningRestHandler) *ImageSigningRouterImpl {
+	return &ImageSigningRouterImpl{
+		imageSigningRestHandler: imageSigningRestHandler,
+	}
+}
+
+func (impl ImageSigningRouterImpl) InitImageSigningRouter(configRouter *mux.Router) {
+	configRouter.Path("/key").HandlerFunc(impl.imageSigningRestHandler.SaveKey).Methods("POST")
+	configRouter.Path("/key").HandlerFunc(impl.imageSigningRestHandler.UpdateKey).Methods("PUT")
+	configRouter.Path("/key").HandlerFunc(impl.imageSigningRestHandler.GetAllKeys).Methods("GET")
+	configRouter.Path("/key/{id}").HandlerFunc(impl.imageSigningRestHandler.DeleteKey).Methods("DELETE")
+	configRouter.Path("/policy/save").HandlerFunc(impl.imageSigningRestHandler.SavePolicy).Methods("POST")
+	configRouter.Path("/policy/list").HandlerFunc(impl.imageSigningRestHandler.GetPolicy).Methods("GET")
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type LogStreamRouter interface {
+	InitLogStreamRouter(logStreamRouter *mux.Router)
+}
+
+type LogStreamRouterImpl struct {
+	logStreamRestHandler restHandler.LogStreamRestHandler
+}
+
+func NewLogStreamRouterImpl(logStreamRestHandler restHandler.LogStreamRestHandler) *LogStreamRouterImpl {
+	return &LogStreamRouterImpl{logStreamRestHandler: logStreamRestHandler}
+}
+
+// InitLogStreamRouter serves both ci workflows and pre/post cd stage runners, workflowId is the runner id for cd
+func (impl LogStreamRouterImpl) InitLogStreamRouter(logStreamRouter *mux.Router) {
+	logStreamRouter.Path("/{type:ci|cd}/pipeline/{pipelineId}/workflow/{workflowId}").HandlerFunc(impl.logStreamRestHandler.StreamLogs).Methods("GET")
+}
@@ -60,10 +60,29 @@ func (impl NotificationRouterImpl) InitNotificationRegRouter(configRouter *mux.R
 	configRouter.Path("/channel/slack/{id}").
 		HandlerFunc(impl.notificationRestHandler.FindSlackConfig).
 		Methods("GET")
+	configRouter.Path("/channel/webhook/{id}").
+		HandlerFunc(impl.notificationRestHandler.FindWebhookConfig).
+		Methods("GET")
+	configRouter.Path("/channel/smtp/{id}").
+		HandlerFunc(impl.notificationRestHandler.FindSMTPConfig).
+		Methods("GET")
+	configRouter.Path("/channel/smtp/test").
+		HandlerFunc(impl.notificationRestHandler.SendSMTPTestMail).
+		Methods("POST")
 	configRouter.Path("/channel").
 		HandlerFunc(impl.notificationRestHandler.DeleteNotificationChannelConfig).
 		Methods("DELETE")
 
+	configRouter.Path("/subscription").
+		HandlerFunc(impl.notificationRestHandler.SaveSubscription).
+		Methods("POST")
+	configRouter.Path("/subscription").
+		HandlerFunc(impl.notificationRestHandler.GetSubscriptions).
+		Methods("GET")
+	configRouter.Path("/subscription/{id}").
+		HandlerFunc(impl.notificationRestHandler.DeleteSubscription).
+		Methods("DELETE")
+
 	configRouter.Path("/recipient").
 		Queries("value", "{value}").
 		HandlerFunc(impl.notificationRestHandler.RecipientListingSuggestion).
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type SecretRotationRouter interface {
+	InitSecretRotationRouter(secretRotationRouter *mux.Router)
+}
+
+type SecretRotationRouterImpl struct {
+	secretRotationRestHandler restHandler.SecretRotationRestHandler
+}
+
+func NewSecretRotationRouterImpl(secretRotationRestHandler restHandler.SecretRotationRestHandler) *SecretRotationRouterImpl {
+	return &SecretRotationRouterImpl{secretRotationRestHandler: secretRotationRestHandler}
+}
+
+func (impl SecretRotationRouterImpl) InitSecretRotationRouter(secretRotationRouter *mux.Router) {
+	secretRotationRouter.Path("").HandlerFunc(impl.secretRotationRestHandler.RotateSecret).Methods("POST")
+	secretRotationRouter.Path("/{id:[0-9]+}").HandlerFunc(impl.secretRotationRestHandler.GetRotationJob).Methods("GET")
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package router
+
+import (
+	"github.com/devtron-labs/devtron/api/restHandler"
+	"github.com/gorilla/mux"
+)
+
+type VaultRouter interface {
+	InitVaultRouter(vaultRouter *mux.Router)
+}
+
+type VaultRouterImpl struct {
+	vaultRestHandler restHandler.VaultRestHandler
+}
+
+func NewVaultRouterImpl(vaultRestHandler restHandler.VaultRestHandler) *VaultRouterImpl {
+	return &VaultRouterImpl{vaultRestHandler: vaultRestHandler}
+}
+
+func (impl VaultRouterImpl) InitVaultRouter(vaultRouter *mux.Router) {
+	vaultRouter.Path("/config").HandlerFunc(impl.vaultRestHandler.SaveConfig).Methods("POST")
+	vaultRouter.Path("/config").HandlerFunc(impl.vaultRestHandler.GetConfig).Methods("GET")
+	vaultRouter.Path("/preview").HandlerFunc(impl.vaultRestHandler.PreviewReferences).Methods("POST")
+}
@@ -51,6 +51,8 @@ type CiCompleteEvent struct {
 	PipelineName     string                      `json:"pipelineName"`
 	DataSource       string                      `json:"dataSource"`
 	MaterialType     string                      `json:"materialType" validate:"required"`
+	SbomLocation     string                      `json:"sbomLocation"`    //set once the runner has uploaded the sbom, the document is too large for the event
+	PlatformDigests  map[string]string           `json:"platformDigests"` //platform to digest, sent for multi-arch builds
 }
 
 const CI_COMPLETE_TOPIC = "CI-RUNNER.CI-COMPLETE"
@@ -155,13 +157,15 @@ func (impl *CiEventHandlerImpl) BuildCiArtifactRequest(event CiCompleteEvent) (*
 	}
 
 	request := &pipeline.CiArtifactWebhookRequest{
-		Image:        event.DockerImage,
-		ImageDigest:  event.Digest,
-		DataSource:   event.DataSource,
-		PipelineName: event.PipelineName,
-		MaterialInfo: rawMaterialInfo,
-		UserId:       event.TriggeredBy,
-		WorkflowId:   event.WorkflowId,
+		Image:           event.DockerImage,
+		ImageDigest:     event.Digest,
+		DataSource:      event.DataSource,
+		PipelineName:    event.PipelineName,
+		MaterialInfo:    rawMaterialInfo,
+		UserId:          event.TriggeredBy,
+		WorkflowId:      event.WorkflowId,
+		SbomLocation:    event.SbomLocation,
+		PlatformDigests: event.PlatformDigests,
 	}
 	return request, nil
 }
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pubsub
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+)
+
+func TestBuildCiArtifactRequest(t *testing.T) {
+	workflowId := 7
+	commitTime := time.Date(2021, 3, 10, 11, 0, 0, 0, time.UTC)
+	event := CiCompleteEvent{}
+	err := json.Unmarshal([]byte(`{
+		"ciProjectDetails": [{"gitRepository": "https://github.com/devtron-labs/devtron.git", "commitHash": "e3b0c442", "commitTime": "2021-03-10T11:00:00Z", "author": "dev", "message": "build", "sourceType": "SOURCE_TYPE_BRANCH_FIXED", "sourceValue": "main"}],
+		"dockerImage": "registry/app:e3b0c442",
+		"digest": "sha256:list",
+		"pipelineId": 3,
+		"workflowId": 7,
+		"pipelineName": "ci-build",
+		"dataSource": "CI-RUNNER",
+		"materialType": "git",
+		"sbomLocation": "arsenal-v1/ci-artifacts/7/sbom.json",
+		"platformDigests": {"linux/amd64": "sha256:amd64", "linux/arm64": "sha256:arm64"}
+	}`), &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impl := &CiEventHandlerImpl{logger: util.NewSugardLogger()}
+	got, err := impl.BuildCiArtifactRequest(event)
+	if err != nil {
+		t.Fatalf("BuildCiArtifactRequest() error = %v", err)
+	}
+	materialInfo, err := json.Marshal([]repository.CiMaterialInfo{{
+		Material: repository.Material{
+			GitConfiguration: repository.GitConfiguration{URL: "https://github.com/devtron-labs/devtron.git"},
+			Type:             "git",
+		},
+		Changed: true,
+		Modifications: []repository.Modification{{
+			Revision:     "e3b0c442",
+			ModifiedTime: commitTime.Format(time.RFC3339),
+			Author:       "dev",
+			Branch:       "main",
+			Message:      "build",
+		}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &pipeline.CiArtifactWebhookRequest{
+		Image:           "registry/app:e3b0c442",
+		ImageDigest:     "sha256:list",
+		DataSource:      "CI-RUNNER",
+		PipelineName:    "ci-build",
+		MaterialInfo:    materialInfo,
+		UserId:          1,
+		WorkflowId:      &workflowId,
+		SbomLocation:    "arsenal-v1/ci-artifacts/7/sbom.json",
+		PlatformDigests: map[string]string{"linux/amd64": "sha256:amd64", "linux/arm64": "sha256:arm64"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BuildCiArtifactRequest() = %+v, want %+v", got, want)
+	}
+}
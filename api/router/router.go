@@ -19,7 +19,9 @@ package router
 
 import (
 	"encoding/json"
+	"github.com/devtron-labs/devtron/api/apiToken"
 	appStore "github.com/devtron-labs/devtron/api/appStore"
+	"github.com/devtron-labs/devtron/api/auditLog"
 	chartRepo "github.com/devtron-labs/devtron/api/chartRepo"
 	"github.com/devtron-labs/devtron/api/cluster"
 	client "github.com/devtron-labs/devtron/api/helm-app"
@@ -31,6 +33,8 @@ import (
 	"github.com/devtron-labs/devtron/client/dashboard"
 	pubsub2 "github.com/devtron-labs/devtron/client/pubsub"
 	"github.com/devtron-labs/devtron/client/telemetry"
+	"github.com/devtron-labs/devtron/pkg/pipeline"
+	"github.com/devtron-labs/devtron/pkg/security"
 	"github.com/devtron-labs/devtron/pkg/terminal"
 	"github.com/devtron-labs/devtron/util"
 	"github.com/devtron-labs/devtron/util/k8s"
@@ -92,6 +96,19 @@ type MuxRouter struct {
 	helmAppRouter                    client.HelmAppRouter
 	k8sApplicationRouter             k8s.K8sApplicationRouter
 	pProfRouter                      PProfRouter
+	deploymentWindowRouter           DeploymentWindowRouter
+	deploymentPolicyRouter           DeploymentPolicyRouter
+	auditLogRouter                   auditLog.AuditLogRouter
+	apiTokenRouter                   apiToken.ApiTokenRouter
+	ciScheduledTriggerService        pipeline.CiScheduledTriggerService
+	configHistoryRouter              ConfigHistoryRouter
+	imageSigningRouter               ImageSigningRouter
+	cveExceptionRouter               CveExceptionRouter
+	imageRescanService               security.ImageRescanService
+	vaultRouter                      VaultRouter
+	secretRotationRouter             SecretRotationRouter
+	artifactRetentionRouter          ArtifactRetentionRouter
+	logStreamRouter                  LogStreamRouter
 }
 
 func NewMuxRouter(logger *zap.SugaredLogger, HelmRouter HelmRouter, PipelineConfigRouter PipelineConfigRouter,
@@ -113,7 +130,12 @@ func NewMuxRouter(logger *zap.SugaredLogger, HelmRouter HelmRouter, PipelineConf
 	policyRouter PolicyRouter, gitOpsConfigRouter GitOpsConfigRouter, dashboardRouter dashboard.DashboardRouter, attributesRouter AttributesRouter,
 	commonRouter CommonRouter, grafanaRouter GrafanaRouter, ssoLoginRouter sso.SsoLoginRouter, telemetryRouter TelemetryRouter, telemetryWatcher telemetry.TelemetryEventClient, bulkUpdateRouter BulkUpdateRouter, webhookListenerRouter WebhookListenerRouter, appLabelsRouter AppLabelRouter,
 	coreAppRouter CoreAppRouter, helmAppRouter client.HelmAppRouter, k8sApplicationRouter k8s.K8sApplicationRouter,
-	pProfRouter PProfRouter) *MuxRouter {
+	pProfRouter PProfRouter, deploymentWindowRouter DeploymentWindowRouter, deploymentPolicyRouter DeploymentPolicyRouter,
+	auditLogRouter auditLog.AuditLogRouter, apiTokenRouter apiToken.ApiTokenRouter, ciScheduledTriggerService pipeline.CiScheduledTriggerService,
+	configHistoryRouter ConfigHistoryRouter, imageSigningRouter ImageSigningRouter,
+	cveExceptionRouter CveExceptionRouter, imageRescanService security.ImageRescanService,
+	vaultRouter VaultRouter, secretRotationRouter SecretRotationRouter, artifactRetentionRouter ArtifactRetentionRouter,
+	logStreamRouter LogStreamRouter) *MuxRouter {
 	r := &MuxRouter{
 		Router:                           mux.NewRouter(),
 		HelmRouter:                       HelmRouter,
@@ -166,6 +188,19 @@ func NewMuxRouter(logger *zap.SugaredLogger, HelmRouter HelmRouter, PipelineConf
 		helmAppRouter:                    helmAppRouter,
 		k8sApplicationRouter:             k8sApplicationRouter,
 		pProfRouter:                      pProfRouter,
+		deploymentWindowRouter:           deploymentWindowRouter,
+		deploymentPolicyRouter:           deploymentPolicyRouter,
+		auditLogRouter:                   auditLogRouter,
+		apiTokenRouter:                   apiTokenRouter,
+		ciScheduledTriggerService:        ciScheduledTriggerService,
+		configHistoryRouter:              configHistoryRouter,
+		imageSigningRouter:               imageSigningRouter,
+		cveExceptionRouter:               cveExceptionRouter,
+		imageRescanService:               imageRescanService,
+		vaultRouter:                      vaultRouter,
+		secretRotationRouter:             secretRotationRouter,
+		artifactRetentionRouter:          artifactRetentionRouter,
+		logStreamRouter:                  logStreamRouter,
 	}
 	return r
 }
@@ -283,6 +318,36 @@ func (r MuxRouter) Init() {
 	policyRouter := r.Router.PathPrefix("/orchestrator/security/policy").Subrouter()
 	r.policyRouter.InitPolicyRouter(policyRouter)
 
+	deploymentPolicyRouter := r.Router.PathPrefix("/orchestrator/security/deployment-policy").Subrouter()
+	r.deploymentPolicyRouter.InitDeploymentPolicyRouter(deploymentPolicyRouter)
+
+	imageSigningRouter := r.Router.PathPrefix("/orchestrator/security/signing").Subrouter()
+	r.imageSigningRouter.InitImageSigningRouter(imageSigningRouter)
+
+	cveExceptionRouter := r.Router.PathPrefix("/orchestrator/security/cve-exception").Subrouter()
+	r.cveExceptionRouter.InitCveExceptionRouter(cveExceptionRouter)
+
+	vaultRouter := r.Router.PathPrefix("/orchestrator/vault").Subrouter()
+	r.vaultRouter.InitVaultRouter(vaultRouter)
+
+	secretRotationRouter := r.Router.PathPrefix("/orchestrator/secret-rotation").Subrouter()
+	r.secretRotationRouter.InitSecretRotationRouter(secretRotationRouter)
+
+	artifactRetentionRouter := r.Router.PathPrefix("/orchestrator/artifact-retention").Subrouter()
+	r.artifactRetentionRouter.InitArtifactRetentionRouter(artifactRetentionRouter)
+
+	logStreamRouter := r.Router.PathPrefix("/orchestrator/log-stream").Subrouter()
+	r.logStreamRouter.InitLogStreamRouter(logStreamRouter)
+
+	auditLogRouter := r.Router.PathPrefix("/orchestrator/audit-log").Subrouter()
+	r.auditLogRouter.InitAuditLogRouter(auditLogRouter)
+
+	apiTokenRouter := r.Router.PathPrefix("/orchestrator/api-token").Subrouter()
+	r.apiTokenRouter.InitApiTokenRouter(apiTokenRouter)
+
+	configHistoryRouter := r.Router.PathPrefix("/orchestrator/config-history").Subrouter()
+	r.configHistoryRouter.InitConfigHistoryRouter(configHistoryRouter)
+
 	gitOpsRouter := r.Router.PathPrefix("/orchestrator/gitops").Subrouter()
 	r.gitOpsConfigRouter.InitGitOpsConfigRouter(gitOpsRouter)
 
@@ -322,4 +387,7 @@ func (r MuxRouter) Init() {
 
 	pProfListenerRouter := r.Router.PathPrefix("/orchestrator/debug/pprof").Subrouter()
 	r.pProfRouter.initPProfRouter(pProfListenerRouter)
+
+	deploymentWindowRouter := r.Router.PathPrefix("/orchestrator/deployment-window").Subrouter()
+	r.deploymentWindowRouter.initDeploymentWindowRouter(deploymentWindowRouter)
 }
@@ -33,6 +33,7 @@ type Broker struct {
 	register    chan *Connection
 	unregister  chan *Connection
 	shutdown    chan bool
+	sync        chan chan bool
 	createdTime time.Time
 }
 
@@ -52,6 +53,7 @@ func NewBroker() *Broker {
 		register:    make(chan *Connection),
 		unregister:  make(chan *Connection),
 		shutdown:    make(chan bool),
+		sync:        make(chan chan bool),
 		createdTime: time.Now(),
 	}
 	return &broker
@@ -65,6 +67,13 @@ func (br *Broker) Start() {
 	go br.run()
 }
 
+// Sync returns once the messages sent to the outbound channel before it have been queued on their connections
+func (br *Broker) Sync() {
+	done := make(chan bool)
+	br.sync <- done
+	<-done
+}
+
 func (br *Broker) run() {
 	for {
 		select {
@@ -79,6 +88,8 @@ func (br *Broker) run() {
 			br.unregisterConnection(conn)
 		case msg := <-br.notifier:
 			br.broadcastMessage(msg)
+		case done := <-br.sync:
+			close(done)
 		}
 	}
 }
@@ -71,6 +71,27 @@ func (conn *Connection) BroadcastMessage(receive <-chan int) {
 		case <-conn.request.Context().Done():
 			return
 		case <-receive:
+			// the processor is done, whatever it sent is still written out
+			conn.drain()
+			return
+		}
+	}
+}
+
+func (conn *Connection) drain() {
+	for {
+		select {
+		case msg, ok := <-conn.outboundMessage:
+			if !ok {
+				return
+			}
+			if _, err := conn.response.Write(msg); err != nil {
+				return
+			}
+		default:
+			if flusher, ok := conn.response.(http.Flusher); ok {
+				flusher.Flush()
+			}
 			return
 		}
 	}
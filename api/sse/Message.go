@@ -21,10 +21,16 @@ type SSEMessage struct {
 	Event     string
 	Data      []byte
 	Namespace string
+	Id        string //sent back by the browser as Last-Event-ID on reconnect
 }
 
 func (msg SSEMessage) format() []byte {
-	res := make([]byte, 0, 6+5+len(msg.Event)+len(msg.Data)+3)
+	res := make([]byte, 0, 6+5+len(msg.Event)+len(msg.Data)+3+4+len(msg.Id))
+	if msg.Id != "" {
+		res = append(res, "id:"...)
+		res = append(res, msg.Id...)
+		res = append(res, '\n')
+	}
 	if msg.Event != "" {
 		res = append(res, "event:"...)
 		res = append(res, msg.Event...)
@@ -28,6 +28,7 @@ import (
 
 	"github.com/devtron-labs/devtron/api/bean"
 	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog"
 	"github.com/devtron-labs/devtron/pkg/user"
 	"github.com/devtron-labs/devtron/util/response"
 	"github.com/go-pg/pg"
@@ -65,12 +66,14 @@ type UserRestHandlerImpl struct {
 	logger           *zap.SugaredLogger
 	enforcer         casbin.Enforcer
 	roleGroupService user.RoleGroupService
+	auditLogService  auditLog.AuditLogService
 }
 
 func NewUserRestHandlerImpl(userService user.UserService, validator *validator.Validate,
-	logger *zap.SugaredLogger, enforcer casbin.Enforcer, roleGroupService user.RoleGroupService) *UserRestHandlerImpl {
+	logger *zap.SugaredLogger, enforcer casbin.Enforcer, roleGroupService user.RoleGroupService,
+	auditLogService auditLog.AuditLogService) *UserRestHandlerImpl {
 	userAuthHandler := &UserRestHandlerImpl{userService: userService, validator: validator, logger: logger,
-		enforcer: enforcer, roleGroupService: roleGroupService}
+		enforcer: enforcer, roleGroupService: roleGroupService, auditLogService: auditLogService}
 	return userAuthHandler
 }
 
@@ -167,6 +170,15 @@ func (handler UserRestHandlerImpl) CreateUser(w http.ResponseWriter, r *http.Req
 		}
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityUser,
+		EntityId:   userInfo.EmailId,
+		Request:    r,
+		Current:    res,
+	})
 
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
@@ -258,12 +270,23 @@ func (handler UserRestHandlerImpl) UpdateUser(w http.ResponseWriter, r *http.Req
 	if userInfo.EmailId == "admin@github.com/devtron-labs" {
 		userInfo.EmailId = "admin"
 	}
+	previous, _ := handler.userService.GetById(userInfo.Id)
 	res, err := handler.userService.UpdateUser(&userInfo)
 	if err != nil {
 		handler.logger.Errorw("service err, UpdateUser", "err", err, "payload", userInfo)
 		common.WriteJsonResp(w, err, "", http.StatusInternalServerError)
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityUser,
+		EntityId:   strconv.Itoa(int(userInfo.Id)),
+		Request:    r,
+		Previous:   previous,
+		Current:    res,
+	})
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
 
@@ -386,6 +409,15 @@ func (handler UserRestHandlerImpl) DeleteUser(w http.ResponseWriter, r *http.Req
 		common.WriteJsonResp(w, err, "", http.StatusInternalServerError)
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityUser,
+		EntityId:   strconv.Itoa(id),
+		Request:    r,
+		Previous:   user,
+	})
 
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
@@ -492,6 +524,15 @@ func (handler UserRestHandlerImpl) CreateRoleGroup(w http.ResponseWriter, r *htt
 		}
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionCreate,
+		EntityType: auditLog.EntityRoleGroup,
+		EntityId:   request.Name,
+		Request:    r,
+		Current:    res,
+	})
 
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
@@ -546,12 +587,23 @@ func (handler UserRestHandlerImpl) UpdateRoleGroup(w http.ResponseWriter, r *htt
 		return
 	}
 
+	previous, _ := handler.roleGroupService.FetchRoleGroupsById(request.Id)
 	res, err := handler.roleGroupService.UpdateRoleGroup(&request)
 	if err != nil {
 		handler.logger.Errorw("service err, UpdateRoleGroup", "err", err, "payload", request)
 		common.WriteJsonResp(w, err, "", http.StatusInternalServerError)
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionUpdate,
+		EntityType: auditLog.EntityRoleGroup,
+		EntityId:   strconv.Itoa(int(request.Id)),
+		Request:    r,
+		Previous:   previous,
+		Current:    res,
+	})
 
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
@@ -638,6 +690,15 @@ func (handler UserRestHandlerImpl) DeleteRoleGroup(w http.ResponseWriter, r *htt
 		common.WriteJsonResp(w, err, "", http.StatusInternalServerError)
 		return
 	}
+	handler.auditLogService.SaveAuditLog(&auditLog.AuditLogRequest{
+		UserId:     userId,
+		Resource:   casbin.ResourceUser,
+		Action:     casbin.ActionDelete,
+		EntityType: auditLog.EntityRoleGroup,
+		EntityId:   strconv.Itoa(id),
+		Request:    r,
+		Previous:   userGroup,
+	})
 
 	common.WriteJsonResp(w, err, res, http.StatusOK)
 }
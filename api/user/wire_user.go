@@ -31,6 +31,8 @@ var UserWireSet = wire.NewSet(
 	wire.Bind(new(user.UserService), new(*user.UserServiceImpl)),
 	repository.NewUserRepositoryImpl,
 	wire.Bind(new(repository.UserRepository), new(*repository.UserRepositoryImpl)),
+	repository.NewApiTokenRepositoryImpl,
+	wire.Bind(new(repository.ApiTokenRepository), new(*repository.ApiTokenRepositoryImpl)),
 	user.NewRoleGroupServiceImpl,
 	wire.Bind(new(user.RoleGroupService), new(*user.RoleGroupServiceImpl)),
 	repository.NewRoleGroupRepositoryImpl,
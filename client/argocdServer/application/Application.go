@@ -76,6 +76,8 @@ type ServiceClient interface {
 	TerminateOperation(ctx context.Context, query *application.OperationTerminateRequest) (*application.OperationTerminateResponse, error)
 	// PatchResource patch single application resource
 	PatchResource(ctx context.Context, query *application.ApplicationResourcePatchRequest) (*application.ApplicationResourceResponse, error)
+	// RunResourceAction runs a resource action (e.g. resume, abort of a rollout) on a single application resource
+	RunResourceAction(ctx context.Context, query *application.ResourceActionRunRequest) (*application.ApplicationResponse, error)
 	// DeleteResource deletes a single application resource
 	DeleteResource(ctx context.Context, query *application.ApplicationResourceDeleteRequest) (*application.ApplicationResponse, error)
 	// Delete deletes an application
@@ -265,6 +267,19 @@ func (c ServiceClientImpl) PatchResource(ctxt context.Context, query *applicatio
 	return resp, err
 }
 
+func (c ServiceClientImpl) RunResourceAction(ctxt context.Context, query *application.ResourceActionRunRequest) (*application.ApplicationResponse, error) {
+	ctx, cancel := context.WithTimeout(ctxt, TimeoutFast)
+	defer cancel()
+	token, ok := ctxt.Value("token").(string)
+	if !ok {
+		return nil, errors.New("Unauthorized")
+	}
+	conn := argocdServer.GetConnection(token, c.settings)
+	defer util.Close(conn, c.logger)
+	asc := application.NewApplicationServiceClient(conn)
+	return asc.RunResourceAction(ctx, query)
+}
+
 func (c ServiceClientImpl) DeleteResource(ctxt context.Context, query *application.ApplicationResourceDeleteRequest) (*application.ApplicationResponse, error) {
 	ctx, cancel := context.WithTimeout(ctxt, TimeoutSlow)
 	defer cancel()
@@ -29,6 +29,7 @@ import (
 	"github.com/devtron-labs/devtron/internal/sql/repository"
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	"github.com/devtron-labs/devtron/pkg/attributes"
+	"github.com/devtron-labs/devtron/pkg/notifier"
 	util "github.com/devtron-labs/devtron/util/event"
 	"go.uber.org/zap"
 	"net/http"
@@ -56,22 +57,22 @@ type EventClient interface {
 }
 
 type Event struct {
-	EventTypeId        int                 `json:"eventTypeId"`
-	EventName          string              `json:"eventName"`
-	PipelineId         int                 `json:"pipelineId"`
-	PipelineType       string              `json:"pipelineType"`
-	CorrelationId      string              `json:"correlationId"`
-	Payload            *Payload            `json:"payload"`
-	EventTime          string              `json:"eventTime"`
-	TeamId             int                 `json:"teamId"`
-	AppId              int                 `json:"appId"`
-	EnvId              int                 `json:"envId"`
+	EventTypeId        int               `json:"eventTypeId"`
+	EventName          string            `json:"eventName"`
+	PipelineId         int               `json:"pipelineId"`
+	PipelineType       string            `json:"pipelineType"`
+	CorrelationId      string            `json:"correlationId"`
+	Payload            *Payload          `json:"payload"`
+	EventTime          string            `json:"eventTime"`
+	TeamId             int               `json:"teamId"`
+	AppId              int               `json:"appId"`
+	EnvId              int               `json:"envId"`
 	CdWorkflowType     bean.WorkflowType `json:"cdWorkflowType,omitempty"`
-	CdWorkflowRunnerId int                 `json:"cdWorkflowRunnerId"`
-	CiWorkflowRunnerId int                 `json:"ciWorkflowRunnerId"`
-	CiArtifactId       int                 `json:"ciArtifactId"`
-	BaseUrl            string              `json:"baseUrl"`
-	UserId             int                 `json:"-"`
+	CdWorkflowRunnerId int               `json:"cdWorkflowRunnerId"`
+	CiWorkflowRunnerId int               `json:"ciWorkflowRunnerId"`
+	CiArtifactId       int               `json:"ciArtifactId"`
+	BaseUrl            string            `json:"baseUrl"`
+	UserId             int               `json:"-"`
 }
 
 type Payload struct {
@@ -87,6 +88,9 @@ type Payload struct {
 	DownloadLink          string               `json:"downloadLink"`
 	BuildHistoryLink      string               `json:"buildHistoryLink"`
 	MaterialTriggerInfo   *MaterialTriggerInfo `json:"material"`
+	Approvers             string               `json:"approvers,omitempty"`
+	RollbackReason        string               `json:"rollbackReason,omitempty"`
+	BlockedCves           string               `json:"blockedCves,omitempty"`
 }
 
 type CiPipelineMaterialResponse struct {
@@ -112,21 +116,26 @@ type MaterialTriggerInfo struct {
 }
 
 type EventRESTClientImpl struct {
-	logger               *zap.SugaredLogger
-	client               *http.Client
-	config               *EventClientConfig
-	pubsubClient         *pubsub.PubSubClient
-	ciPipelineRepository pipelineConfig.CiPipelineRepository
-	pipelineRepository   pipelineConfig.PipelineRepository
-	attributesRepository repository.AttributesRepository
+	logger                     *zap.SugaredLogger
+	client                     *http.Client
+	config                     *EventClientConfig
+	pubsubClient               *pubsub.PubSubClient
+	ciPipelineRepository       pipelineConfig.CiPipelineRepository
+	pipelineRepository         pipelineConfig.PipelineRepository
+	attributesRepository       repository.AttributesRepository
+	webhookNotificationService notifier.WebhookNotificationService
+	smtpNotificationService    notifier.SMTPNotificationService
+	subscriptionService        notifier.NotificationSubscriptionService
 }
 
 func NewEventRESTClientImpl(logger *zap.SugaredLogger, client *http.Client, config *EventClientConfig, pubsubClient *pubsub.PubSubClient,
 	ciPipelineRepository pipelineConfig.CiPipelineRepository, pipelineRepository pipelineConfig.PipelineRepository,
-	attributesRepository repository.AttributesRepository) *EventRESTClientImpl {
+	attributesRepository repository.AttributesRepository, webhookNotificationService notifier.WebhookNotificationService,
+	smtpNotificationService notifier.SMTPNotificationService, subscriptionService notifier.NotificationSubscriptionService) *EventRESTClientImpl {
 	return &EventRESTClientImpl{logger: logger, client: client, config: config, pubsubClient: pubsubClient,
 		ciPipelineRepository: ciPipelineRepository, pipelineRepository: pipelineRepository,
-		attributesRepository: attributesRepository}
+		attributesRepository: attributesRepository, webhookNotificationService: webhookNotificationService,
+		smtpNotificationService: smtpNotificationService, subscriptionService: subscriptionService}
 }
 
 func (impl *EventRESTClientImpl) buildFinalPayload(event Event, cdPipeline *pipelineConfig.Pipeline, ciPipeline *pipelineConfig.CiPipeline) *Payload {
@@ -230,6 +239,19 @@ func (impl *EventRESTClientImpl) WriteEvent(event Event) (bool, error) {
 
 func (impl *EventRESTClientImpl) SendEvent(event Event) (bool, error) {
 	impl.logger.Debugw("event before send", "event", event)
+	// webhook and smtp channels are delivered from here, the notifier only knows slack and ses
+	notificationEvent := &notifier.NotificationEvent{
+		PipelineType: event.PipelineType,
+		EventTypeId:  event.EventTypeId,
+		PipelineId:   event.PipelineId,
+		TeamId:       event.TeamId,
+		AppId:        event.AppId,
+		EnvId:        event.EnvId,
+		Data:         event,
+	}
+	impl.webhookNotificationService.SendEventNotification(notificationEvent)
+	impl.smtpNotificationService.SendEventNotification(notificationEvent)
+	impl.subscriptionService.HandleEvent(notificationEvent)
 	body, err := json.Marshal(event)
 	if err != nil {
 		impl.logger.Errorw("error while marshaling event request ", "err", err)
@@ -2,9 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"github.com/devtron-labs/devtron/api/apiToken"
 	appStoreDeployment "github.com/devtron-labs/devtron/api/appStore/deployment"
 	appStoreDiscover "github.com/devtron-labs/devtron/api/appStore/discover"
 	appStoreValues "github.com/devtron-labs/devtron/api/appStore/values"
+	"github.com/devtron-labs/devtron/api/auditLog"
 	"github.com/devtron-labs/devtron/api/chartRepo"
 	"github.com/devtron-labs/devtron/api/cluster"
 	client "github.com/devtron-labs/devtron/api/helm-app"
@@ -36,6 +38,8 @@ type MuxRouter struct {
 	appStoreDiscoverRouter   appStoreDiscover.AppStoreDiscoverRouter
 	appStoreValuesRouter     appStoreValues.AppStoreValuesRouter
 	appStoreDeploymentRouter appStoreDeployment.AppStoreDeploymentRouter
+	auditLogRouter           auditLog.AuditLogRouter
+	apiTokenRouter           apiToken.ApiTokenRouter
 }
 
 func NewMuxRouter(
@@ -53,7 +57,8 @@ func NewMuxRouter(
 	appStoreDiscoverRouter appStoreDiscover.AppStoreDiscoverRouter,
 	appStoreValuesRouter appStoreValues.AppStoreValuesRouter,
 	appStoreDeploymentRouter appStoreDeployment.AppStoreDeploymentRouter,
-
+	auditLogRouter auditLog.AuditLogRouter,
+	apiTokenRouter apiToken.ApiTokenRouter,
 ) *MuxRouter {
 	r := &MuxRouter{
 		Router:                   mux.NewRouter(),
@@ -71,6 +76,8 @@ func NewMuxRouter(
 		appStoreDiscoverRouter:   appStoreDiscoverRouter,
 		appStoreValuesRouter:     appStoreValuesRouter,
 		appStoreDeploymentRouter: appStoreDeploymentRouter,
+		auditLogRouter:           auditLogRouter,
+		apiTokenRouter:           apiTokenRouter,
 	}
 	return r
 }
@@ -149,4 +156,10 @@ func (r *MuxRouter) Init() {
 	appStoreDeploymentSubRouter := r.Router.PathPrefix("/orchestrator/app-store/deployment").Subrouter()
 	r.appStoreDeploymentRouter.Init(appStoreDeploymentSubRouter)
 	// app-store deployment router ends
+
+	auditLogRouter := r.Router.PathPrefix("/orchestrator/audit-log").Subrouter()
+	r.auditLogRouter.InitAuditLogRouter(auditLogRouter)
+
+	apiTokenRouter := r.Router.PathPrefix("/orchestrator/api-token").Subrouter()
+	r.apiTokenRouter.InitApiTokenRouter(apiTokenRouter)
 }
@@ -5,9 +5,11 @@ package main
 
 import (
 	"github.com/devtron-labs/authenticator/middleware"
+	"github.com/devtron-labs/devtron/api/apiToken"
 	appStoreDeployment "github.com/devtron-labs/devtron/api/appStore/deployment"
 	appStoreDiscover "github.com/devtron-labs/devtron/api/appStore/discover"
 	appStoreValues "github.com/devtron-labs/devtron/api/appStore/values"
+	"github.com/devtron-labs/devtron/api/auditLog"
 	chartRepo "github.com/devtron-labs/devtron/api/chartRepo"
 	"github.com/devtron-labs/devtron/api/cluster"
 	"github.com/devtron-labs/devtron/api/connector"
@@ -39,6 +41,8 @@ func InitializeApp() (*App, error) {
 		AuthWireSet,
 		team.TeamsWireSet,
 		cluster.ClusterWireSetEa,
+		auditLog.AuditLogWireSet,
+		apiToken.ApiTokenWireSet,
 		dashboard.DashboardWireSet,
 		client.HelmAppWireSet,
 		k8s.K8sApplicationWireSet,
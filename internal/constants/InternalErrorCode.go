@@ -71,6 +71,8 @@ const (
 	UserNotFoundForToken                 string = "6006"
 	UserCreateFetchRoleFailed            string = "6007"
 	UserUpdateFetchRoleFailed            string = "6008"
+	UserApiTokenInvalid                  string = "6009"
+	UserApiTokenExpired                  string = "6010"
 
 	AppDetailResourceTreeNotFound string = "7000"
 
@@ -31,24 +31,36 @@ import (
 )
 
 type CiArtifact struct {
-	tableName        struct{}  `sql:"ci_artifact" pg:",discard_unknown_columns"`
-	Id               int       `sql:"id,pk"`
-	PipelineId       int       `sql:"pipeline_id,notnull"` //id of the ci pipeline from which this webhook was triggered
-	Image            string    `sql:"image,notnull"`
-	ImageDigest      string    `sql:"image_digest,notnull"`
-	MaterialInfo     string    `sql:"material_info"` //git material metadata json array string
-	DataSource       string    `sql:"data_source,notnull"`
-	WorkflowId       *int      `sql:"ci_workflow_id"`
-	ParentCiArtifact int       `sql:"parent_ci_artifact"`
-	ScanEnabled      bool      `sql:"scan_enabled,notnull"`
-	Scanned          bool      `sql:"scanned,notnull"`
-	DeployedTime     time.Time `sql:"-"`
-	Deployed         bool      `sql:"-"`
-	Latest           bool      `sql:"-"`
-	RunningOnParent  bool      `sql:"-"`
+	tableName        struct{}          `sql:"ci_artifact" pg:",discard_unknown_columns"`
+	Id               int               `sql:"id,pk"`
+	PipelineId       int               `sql:"pipeline_id,notnull"` //id of the ci pipeline from which this webhook was triggered
+	Image            string            `sql:"image,notnull"`
+	ImageDigest      string            `sql:"image_digest,notnull"`
+	MaterialInfo     string            `sql:"material_info"` //git material metadata json array string
+	DataSource       string            `sql:"data_source,notnull"`
+	WorkflowId       *int              `sql:"ci_workflow_id"`
+	ParentCiArtifact int               `sql:"parent_ci_artifact"`
+	ScanEnabled      bool              `sql:"scan_enabled,notnull"`
+	Scanned          bool              `sql:"scanned,notnull"`
+	PlatformDigests  map[string]string `sql:"platform_digests"` //digest of each platform image of a multi arch build, ImageDigest is of the manifest list
+	DeployedTime     time.Time         `sql:"-"`
+	Deployed         bool              `sql:"-"`
+	Latest           bool              `sql:"-"`
+	RunningOnParent  bool              `sql:"-"`
 	sql.AuditLog
 }
 
+// ScanDigests returns the image digest and the digests of the platform images, scan results can be on any of them
+func (artifact *CiArtifact) ScanDigests() []string {
+	digests := []string{artifact.ImageDigest}
+	for _, digest := range artifact.PlatformDigests {
+		if len(digest) > 0 && digest != artifact.ImageDigest {
+			digests = append(digests, digest)
+		}
+	}
+	return digests
+}
+
 type CiArtifactRepository interface {
 	Save(artifact *CiArtifact) error
 	Get(id int) (artifact *CiArtifact, err error)
@@ -65,6 +77,11 @@ type CiArtifactRepository interface {
 	GetByImageDigest(imageDigest string) (artifact *CiArtifact, err error)
 	GetByIds(ids []int) ([]*CiArtifact, error)
 	GetArtifactByCdWorkflowId(cdWorkflowId int) (artifact *CiArtifact, err error)
+	// FindRetentionCandidates returns the artifacts of the ci pipeline beyond the latest keepCount which were never
+	// deployed, approved or picked as a rollback target and have no linked ci artifacts
+	FindRetentionCandidates(ciPipelineId int, keepCount int) ([]*CiArtifact, error)
+	// DeleteWithDependents deletes the artifact along with its sbom and image signatures
+	DeleteWithDependents(id int) error
 }
 
 type CiArtifactRepositoryImpl struct {
@@ -535,3 +552,37 @@ func (impl CiArtifactRepositoryImpl) GetArtifactByCdWorkflowId(cdWorkflowId int)
 		Select()
 	return artifact, err
 }
+
+func (impl CiArtifactRepositoryImpl) FindRetentionCandidates(ciPipelineId int, keepCount int) ([]*CiArtifact, error) {
+	var artifacts []*CiArtifact
+	err := impl.dbConnection.Model(&artifacts).
+		Column("ci_artifact.*").
+		Where("ci_artifact.pipeline_id = ?", ciPipelineId).
+		Where("ci_artifact.id NOT IN (SELECT id FROM ci_artifact WHERE pipeline_id = ? ORDER BY id DESC LIMIT ?)", ciPipelineId, keepCount).
+		Where("NOT EXISTS (SELECT 1 FROM cd_workflow WHERE ci_artifact_id = ci_artifact.id)").
+		Where("NOT EXISTS (SELECT 1 FROM pipeline_config_override WHERE ci_artifact_id = ci_artifact.id)").
+		Where("NOT EXISTS (SELECT 1 FROM deployment_approval_request WHERE ci_artifact_id = ci_artifact.id)").
+		Where("NOT EXISTS (SELECT 1 FROM deployment_auto_rollback WHERE ci_artifact_id = ci_artifact.id OR rollback_ci_artifact_id = ci_artifact.id)").
+		Where("NOT EXISTS (SELECT 1 FROM ci_artifact child WHERE child.parent_ci_artifact = ci_artifact.id)").
+		Order("ci_artifact.id ASC").
+		Select()
+	return artifacts, err
+}
+
+func (impl CiArtifactRepositoryImpl) DeleteWithDependents(id int) error {
+	err := impl.dbConnection.RunInTransaction(func(tx *pg.Tx) error {
+		queries := []string{
+			"DELETE FROM ci_artifact_sbom_component WHERE sbom_id IN (SELECT id FROM ci_artifact_sbom WHERE ci_artifact_id = ?)",
+			"DELETE FROM ci_artifact_sbom WHERE ci_artifact_id = ?",
+			"DELETE FROM image_signature WHERE ci_artifact_id = ?",
+			"DELETE FROM ci_artifact WHERE id = ?",
+		}
+		for _, query := range queries {
+			if _, err := tx.Exec(query, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return err
+}
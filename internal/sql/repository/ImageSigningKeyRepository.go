@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package repository
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+)
+
+const IMAGE_SIGNING_ALGORITHM_ECDSA_P256 = "ecdsa-p256"
+
+// ImageSigningKey is kept like registry credentials, the default active key signs images built by ci
+type ImageSigningKey struct {
+	tableName struct{} `sql:"image_signing_key" pg:",discard_unknown_columns"`
+	Id        string   `sql:"id,pk"`
+	Algorithm string   `sql:"algorithm,notnull"`
+	PublicKey string   `sql:"public_key,notnull"`
+	// PrivateKey is PEM encoded and stored in plain text, same as the password and secret access key of
+	// DockerArtifactStore, access to the table has to be restricted like for registry credentials
+	PrivateKey string `sql:"private_key,notnull"`
+	IsDefault  bool   `sql:"is_default,notnull"`
+	Active     bool   `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+type ImageSigningKeyRepository interface {
+	Save(key *ImageSigningKey) error
+	Update(key *ImageSigningKey) error
+	FindOne(id string) (*ImageSigningKey, error)
+	FindActiveDefault() (*ImageSigningKey, error)
+	FindAllActive() ([]*ImageSigningKey, error)
+	FindActiveByIds(ids []string) ([]*ImageSigningKey, error)
+}
+
+type ImageSigningKeyRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewImageSigningKeyRepositoryImpl(dbConnection *pg.DB) *ImageSigningKeyRepositoryImpl {
+	return &ImageSigningKeyRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (impl ImageSigningKeyRepositoryImpl) Save(key *ImageSigningKey) error {
+	//there can be only one default
+	model, err := impl.FindActiveDefault()
+	if err == pg.ErrNoRows {
+		key.IsDefault = true
+	} else if err == nil && model.Id != key.Id && key.IsDefault {
+		model.IsDefault = false
+		err = impl.Update(model)
+		if err != nil {
+			return err
+		}
+	}
+	return impl.dbConnection.Insert(key)
+}
+
+func (impl ImageSigningKeyRepositoryImpl) Update(key *ImageSigningKey) error {
+	return impl.dbConnection.Update(key)
+}
+
+func (impl ImageSigningKeyRepositoryImpl) FindOne(id string) (*ImageSigningKey, error) {
+	key := &ImageSigningKey{}
+	err := impl.dbConnection.Model(key).
+		Where("id = ?", id).
+		Where("active = ?", true).
+		Select()
+	return key, err
+}
+
+func (impl ImageSigningKeyRepositoryImpl) FindActiveDefault() (*ImageSigningKey, error) {
+	key := &ImageSigningKey{}
+	err := impl.dbConnection.Model(key).
+		Where("is_default = ?", true).
+		Where("active = ?", true).
+		Select()
+	return key, err
+}
+
+func (impl ImageSigningKeyRepositoryImpl) FindAllActive() ([]*ImageSigningKey, error) {
+	var keys []*ImageSigningKey
+	err := impl.dbConnection.Model(&keys).
+		Where("active = ?", true).
+		Order("id asc").
+		Select()
+	return keys, err
+}
+
+func (impl ImageSigningKeyRepositoryImpl) FindActiveByIds(ids []string) ([]*ImageSigningKey, error) {
+	var keys []*ImageSigningKey
+	if len(ids) == 0 {
+		return keys, nil
+	}
+	err := impl.dbConnection.Model(&keys).
+		Where("id in (?)", pg.In(ids)).
+		Where("active = ?", true).
+		Select()
+	return keys, err
+}
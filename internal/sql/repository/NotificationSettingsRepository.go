@@ -41,6 +41,8 @@ type NotificationSettingsRepository interface {
 	FindNotificationSettingBuildOptions(settingRequest *SearchRequest) ([]*SettingOptionDTO, error)
 	FetchNotificationSettingGroupBy(viewId int) ([]NotificationSettings, error)
 	FindNotificationSettingsByConfigIdAndConfigType(configId int, configType string) ([]*NotificationSettings, error)
+	FindNotificationSettingsForEvent(pipelineType string, eventTypeId int, pipelineId int, teamId int, appId int, envId int, configType string) ([]*NotificationSettings, error)
+	FindNotificationSettingsByConfigType(configType string) ([]*NotificationSettings, error)
 }
 
 type NotificationSettingsRepositoryImpl struct {
@@ -310,10 +312,36 @@ func (impl *NotificationSettingsRepositoryImpl) FetchNotificationSettingGroupBy(
 
 func (impl *NotificationSettingsRepositoryImpl) FindNotificationSettingsByConfigIdAndConfigType(configId int, configType string) ([]*NotificationSettings, error) {
 	var notificationSettings []*NotificationSettings
-	err := impl.dbConnection.Model(&notificationSettings).Where("config::text like ?","%dest\":\""+configType+"%").
-		Where("config::text like ?","%configId\":" + strconv.Itoa(configId)+"%").Select()
+	err := impl.dbConnection.Model(&notificationSettings).Where("config::text like ?", "%dest\":\""+configType+"%").
+		Where("config::text like ?", "%configId\":"+strconv.Itoa(configId)+"%").Select()
 	if err != nil {
 		return nil, err
 	}
 	return notificationSettings, nil
-}
\ No newline at end of file
+}
+
+// FindNotificationSettingsForEvent matches settings made for the pipeline itself or for a team, app and env combination covering it
+func (impl *NotificationSettingsRepositoryImpl) FindNotificationSettingsForEvent(pipelineType string, eventTypeId int, pipelineId int, teamId int, appId int, envId int, configType string) ([]*NotificationSettings, error) {
+	var notificationSettings []*NotificationSettings
+	err := impl.dbConnection.Model(&notificationSettings).
+		Where("pipeline_type = ?", pipelineType).
+		Where("event_type_id = ?", eventTypeId).
+		Where("(pipeline_id = ? OR (pipeline_id IS NULL AND (team_id IS NULL OR team_id = ?) AND (app_id IS NULL OR app_id = ?) AND (env_id IS NULL OR env_id = ?)))", pipelineId, teamId, appId, envId).
+		Where("config::text like ?", "%dest\":\""+configType+"%").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return notificationSettings, nil
+}
+
+func (impl *NotificationSettingsRepositoryImpl) FindNotificationSettingsByConfigType(configType string) ([]*NotificationSettings, error) {
+	var notificationSettings []*NotificationSettings
+	err := impl.dbConnection.Model(&notificationSettings).
+		Where("config::text like ?", "%dest\":\""+configType+"%").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	return notificationSettings, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package repository
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"time"
+)
+
+type NotificationSubscriptionRepository interface {
+	Save(subscription *NotificationSubscription) error
+	Update(subscription *NotificationSubscription) error
+	FindById(id int) (*NotificationSubscription, error)
+	FindActiveByUserId(userId int32) ([]*NotificationSubscription, error)
+	FindActiveForEvent(appId int, envId int, pipelineType string, eventTypeId int) ([]*NotificationSubscription, error)
+	SaveDigestEvent(digestEvent *NotificationDigestEvent) error
+	FindPendingDigestEvents() ([]*NotificationDigestEvent, error)
+	MarkDigestEventsSent(ids []int) error
+	MarkDigestEventsSentBySubscriptionId(subscriptionId int) error
+}
+
+type NotificationSubscriptionRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewNotificationSubscriptionRepositoryImpl(dbConnection *pg.DB) *NotificationSubscriptionRepositoryImpl {
+	return &NotificationSubscriptionRepositoryImpl{dbConnection: dbConnection}
+}
+
+type NotificationSubscription struct {
+	tableName    struct{} `sql:"notification_subscription" pg:",discard_unknown_columns"`
+	Id           int      `sql:"id,pk"`
+	UserId       int32    `sql:"user_id,notnull"`
+	AppId        int      `sql:"app_id,notnull"`
+	EnvId        *int     `sql:"env_id"`
+	PipelineType string   `sql:"pipeline_type"`
+	EventTypeIds []int    `sql:"event_type_ids,notnull" pg:",array"`
+	Digest       bool     `sql:"digest,notnull"`
+	Active       bool     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+type NotificationDigestEvent struct {
+	tableName      struct{}  `sql:"notification_digest_event" pg:",discard_unknown_columns"`
+	Id             int       `sql:"id,pk"`
+	SubscriptionId int       `sql:"subscription_id,notnull"`
+	UserId         int32     `sql:"user_id,notnull"`
+	AppId          int       `sql:"app_id,notnull"`
+	EventTypeId    int       `sql:"event_type_id,notnull"`
+	Summary        string    `sql:"summary,notnull"`
+	Link           string    `sql:"link"`
+	Sent           bool      `sql:"sent,notnull"`
+	CreatedOn      time.Time `sql:"created_on,notnull"`
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) Save(subscription *NotificationSubscription) error {
+	return impl.dbConnection.Insert(subscription)
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) Update(subscription *NotificationSubscription) error {
+	return impl.dbConnection.Update(subscription)
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) FindById(id int) (*NotificationSubscription, error) {
+	subscription := &NotificationSubscription{}
+	err := impl.dbConnection.Model(subscription).
+		Where("id = ?", id).
+		Where("active = ?", true).
+		Select()
+	return subscription, err
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) FindActiveByUserId(userId int32) ([]*NotificationSubscription, error) {
+	var subscriptions []*NotificationSubscription
+	err := impl.dbConnection.Model(&subscriptions).
+		Where("user_id = ?", userId).
+		Where("active = ?", true).
+		Order("id desc").
+		Select()
+	return subscriptions, err
+}
+
+// FindActiveForEvent matches subscriptions on the app, with env and pipeline type narrowing it down when set
+func (impl *NotificationSubscriptionRepositoryImpl) FindActiveForEvent(appId int, envId int, pipelineType string, eventTypeId int) ([]*NotificationSubscription, error) {
+	var subscriptions []*NotificationSubscription
+	err := impl.dbConnection.Model(&subscriptions).
+		Where("app_id = ?", appId).
+		Where("(env_id IS NULL OR env_id = ?)", envId).
+		Where("(pipeline_type IS NULL OR pipeline_type = '' OR pipeline_type = ?)", pipelineType).
+		Where("? = ANY(event_type_ids)", eventTypeId).
+		Where("active = ?", true).
+		Select()
+	return subscriptions, err
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) SaveDigestEvent(digestEvent *NotificationDigestEvent) error {
+	return impl.dbConnection.Insert(digestEvent)
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) FindPendingDigestEvents() ([]*NotificationDigestEvent, error) {
+	var digestEvents []*NotificationDigestEvent
+	err := impl.dbConnection.Model(&digestEvents).
+		Where("sent = ?", false).
+		Order("id asc").
+		Select()
+	return digestEvents, err
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) MarkDigestEventsSent(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := impl.dbConnection.Model((*NotificationDigestEvent)(nil)).
+		Set("sent = ?", true).
+		Where("id in (?)", pg.In(ids)).
+		Update()
+	return err
+}
+
+func (impl *NotificationSubscriptionRepositoryImpl) MarkDigestEventsSentBySubscriptionId(subscriptionId int) error {
+	_, err := impl.dbConnection.Model((*NotificationDigestEvent)(nil)).
+		Set("sent = ?", true).
+		Where("subscription_id = ?", subscriptionId).
+		Where("sent = ?", false).
+		Update()
+	return err
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package repository
+
+import (
+	"time"
+
+	"github.com/go-pg/pg"
+)
+
+// ScheduledJobLease is claimed by the orchestrator replica running a scheduled job, leases are compared against db time
+type ScheduledJobLease struct {
+	tableName   struct{}  `sql:"scheduled_job_lease" pg:",discard_unknown_columns"`
+	JobName     string    `sql:"job_name,pk"`
+	LeasedBy    string    `sql:"leased_by,notnull"`
+	LeasedUntil time.Time `sql:"leased_until,notnull"`
+}
+
+type ScheduledJobLeaseRepository interface {
+	// Acquire claims the job for holder for leaseSeconds, it returns false while another holder's lease is live
+	Acquire(jobName string, holder string, leaseSeconds int) (bool, error)
+	// Extend moves the lease of holder to leaseSeconds from now, 0 releases it
+	Extend(jobName string, holder string, leaseSeconds int) error
+}
+
+type ScheduledJobLeaseRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewScheduledJobLeaseRepositoryImpl(dbConnection *pg.DB) *ScheduledJobLeaseRepositoryImpl {
+	return &ScheduledJobLeaseRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (impl *ScheduledJobLeaseRepositoryImpl) Acquire(jobName string, holder string, leaseSeconds int) (bool, error) {
+	// single statement so that concurrent replicas serialise on the row, only one of them sees an expired lease
+	query := "INSERT INTO scheduled_job_lease (job_name, leased_by, leased_until) VALUES (?, ?, now() + ? * interval '1 second') " +
+		"ON CONFLICT (job_name) DO UPDATE SET leased_by = EXCLUDED.leased_by, leased_until = EXCLUDED.leased_until " +
+		"WHERE scheduled_job_lease.leased_until < now()"
+	res, err := impl.dbConnection.Exec(query, jobName, holder, leaseSeconds)
+	if err != nil {
+		return false, err
+	}
+	return res.RowsAffected() == 1, nil
+}
+
+func (impl *ScheduledJobLeaseRepositoryImpl) Extend(jobName string, holder string, leaseSeconds int) error {
+	_, err := impl.dbConnection.Model((*ScheduledJobLease)(nil)).
+		Set("leased_until = now() + ? * interval '1 second'", leaseSeconds).
+		Where("job_name = ?", jobName).
+		Where("leased_by = ?", holder).
+		Update()
+	return err
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package repository
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+)
+
+type VaultConfig struct {
+	tableName      struct{} `sql:"vault_config" pg:",discard_unknown_columns"`
+	Id             int      `sql:"id,pk"`
+	Address        string   `sql:"address,notnull"`
+	AuthMethod     string   `sql:"auth_method,notnull"`
+	AuthMount      string   `sql:"auth_mount"`
+	Token          string   `sql:"token"`
+	RoleId         string   `sql:"role_id"`
+	SecretId       string   `sql:"secret_id"`
+	KubernetesRole string   `sql:"kubernetes_role"`
+	Mount          string   `sql:"mount,notnull"`
+	KvVersion      int      `sql:"kv_version,notnull"`
+	Namespace      string   `sql:"namespace"`
+	Active         bool     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+type VaultConfigRepository interface {
+	Save(vaultConfig *VaultConfig) error
+	Update(vaultConfig *VaultConfig) error
+	FindActive() (*VaultConfig, error)
+}
+
+type VaultConfigRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewVaultConfigRepositoryImpl(dbConnection *pg.DB) *VaultConfigRepositoryImpl {
+	return &VaultConfigRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (impl *VaultConfigRepositoryImpl) Save(vaultConfig *VaultConfig) error {
+	return impl.dbConnection.Insert(vaultConfig)
+}
+
+func (impl *VaultConfigRepositoryImpl) Update(vaultConfig *VaultConfig) error {
+	return impl.dbConnection.Update(vaultConfig)
+}
+
+func (impl *VaultConfigRepositoryImpl) FindActive() (*VaultConfig, error) {
+	vaultConfig := &VaultConfig{}
+	err := impl.dbConnection.Model(vaultConfig).
+		Where("active = ?", true).
+		Order("id DESC").
+		Limit(1).
+		Select()
+	return vaultConfig, err
+}
@@ -53,6 +53,7 @@ type PipelineOverrideRepository interface {
 	GetLatestConfigByRequestIdentifier(requestIdentifier string) (pipelineOverride *PipelineOverride, err error)
 	GetLatestConfigByEnvironmentConfigOverrideId(envConfigOverrideId int) (pipelineOverride *PipelineOverride, err error)
 	Update(pipelineOverride *PipelineOverride) error
+	Delete(pipelineOverride *PipelineOverride) error
 	GetCurrentPipelineReleaseCounter(pipelineId int) (releaseCounter int, err error)
 	GetByPipelineIdAndReleaseNo(pipelineId, releaseNo int) (pipelineOverrides []*PipelineOverride, err error)
 	GetAllRelease(appId, environmentId int) (pipelineOverrides []*PipelineOverride, err error)
@@ -62,6 +63,7 @@ type PipelineOverrideRepository interface {
 	GetByDeployedImage(appId, environmentId int, images []string) (pipelineOverride *PipelineOverride, err error)
 	GetLatestReleaseByPipelineIds(pipelineIds []int) (pipelineOverrides []*PipelineOverride, err error)
 	GetLatestReleaseDeploymentType(pipelineIds []int) ([]*PipelineOverride, error)
+	FindLastHealthyRelease(pipelineId int, beforeReleaseCounter int, excludeCiArtifactId int) (*PipelineOverride, error)
 }
 
 type PipelineOverrideRepositoryImpl struct {
@@ -76,6 +78,10 @@ func (impl PipelineOverrideRepositoryImpl) Update(pipelineOverride *PipelineOver
 	_, err := impl.dbConnection.Model(pipelineOverride).WherePK().UpdateNotNull()
 	return err
 }
+
+func (impl PipelineOverrideRepositoryImpl) Delete(pipelineOverride *PipelineOverride) error {
+	return impl.dbConnection.Delete(pipelineOverride)
+}
 func (impl PipelineOverrideRepositoryImpl) UpdateStatusByRequestIdentifier(requestId string, newStatus models.ChartStatus) (int, error) {
 	pipelineOverride := &PipelineOverride{RequestIdentifier: requestId, Status: newStatus}
 	res, err := impl.dbConnection.Model(pipelineOverride).
@@ -216,3 +222,20 @@ func (impl PipelineOverrideRepositoryImpl) FindById(id int) (*PipelineOverride,
 		Select()
 	return &pipelineOverride, err
 }
+
+// FindLastHealthyRelease returns the latest release before beforeReleaseCounter whose deployment turned Healthy
+func (impl PipelineOverrideRepositoryImpl) FindLastHealthyRelease(pipelineId int, beforeReleaseCounter int, excludeCiArtifactId int) (*PipelineOverride, error) {
+	pipelineOverride := &PipelineOverride{}
+	err := impl.dbConnection.Model(pipelineOverride).
+		Column("pipeline_override.*", "CiArtifact").
+		Join("INNER JOIN cd_workflow_runner wfr ON wfr.cd_workflow_id = pipeline_override.cd_workflow_id").
+		Where("pipeline_override.pipeline_id = ?", pipelineId).
+		Where("pipeline_override.pipeline_release_counter < ?", beforeReleaseCounter).
+		Where("pipeline_override.ci_artifact_id <> ?", excludeCiArtifactId).
+		Where("wfr.workflow_type = ?", "DEPLOY").
+		Where("wfr.status = ?", "Healthy").
+		Order("pipeline_override.pipeline_release_counter DESC").
+		Limit(1).
+		Select()
+	return pipelineOverride, err
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipelineConfig
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"time"
+)
+
+type ArtifactRetentionStatus string
+
+const (
+	ARTIFACT_RETENTION_RUNNING          ArtifactRetentionStatus = "RUNNING"
+	ARTIFACT_RETENTION_SUCCEEDED        ArtifactRetentionStatus = "SUCCEEDED"
+	ARTIFACT_RETENTION_PARTIALLY_FAILED ArtifactRetentionStatus = "PARTIALLY_FAILED"
+	ARTIFACT_RETENTION_FAILED           ArtifactRetentionStatus = "FAILED"
+)
+
+type ArtifactRetentionItemType string
+
+const (
+	ARTIFACT_RETENTION_ITEM_ARTIFACT ArtifactRetentionItemType = "ARTIFACT"
+	ARTIFACT_RETENTION_ITEM_LOG      ArtifactRetentionItemType = "LOG"
+)
+
+// ArtifactRetentionPolicy limits the artifacts and build logs kept for ci pipelines, AppId 0 is the global policy
+type ArtifactRetentionPolicy struct {
+	tableName         struct{} `sql:"artifact_retention_policy" pg:",discard_unknown_columns"`
+	Id                int      `sql:"id,pk"`
+	AppId             int      `sql:"app_id,notnull"`
+	KeepArtifactCount int      `sql:"keep_artifact_count,notnull"` //0 keeps all artifacts
+	LogRetentionDays  int      `sql:"log_retention_days,notnull"`  //0 keeps all logs
+	Active            bool     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+// ArtifactRetentionRun is a single application of the retention policies along with the report of what was removed
+type ArtifactRetentionRun struct {
+	tableName        struct{}                       `sql:"artifact_retention_run" pg:",discard_unknown_columns"`
+	Id               int                            `sql:"id,pk"`
+	Status           ArtifactRetentionStatus        `sql:"status,notnull"`
+	DryRun           bool                           `sql:"dry_run,notnull"`
+	ArtifactsDeleted int                            `sql:"artifacts_deleted,notnull"`
+	LogsDeleted      int                            `sql:"logs_deleted,notnull"`
+	FailedCount      int                            `sql:"failed_count,notnull"`
+	Report           []*ArtifactRetentionReportItem `sql:"report"`
+	Message          string                         `sql:"message"`
+	StartedOn        time.Time                      `sql:"started_on,notnull"`
+	FinishedOn       time.Time                      `sql:"finished_on"`
+	sql.AuditLog
+}
+
+type ArtifactRetentionReportItem struct {
+	Type         ArtifactRetentionItemType `json:"type"`
+	AppId        int                       `json:"appId"`
+	CiPipelineId int                       `json:"ciPipelineId"`
+	CiArtifactId int                       `json:"ciArtifactId,omitempty"`
+	CiWorkflowId int                       `json:"ciWorkflowId,omitempty"`
+	Image        string                    `json:"image,omitempty"`
+	Locations    []string                  `json:"locations,omitempty"` //blob objects removed with the item
+	Error        string                    `json:"error,omitempty"`
+}
+
+type ArtifactRetentionRepository interface {
+	SavePolicy(policy *ArtifactRetentionPolicy) error
+	UpdatePolicy(policy *ArtifactRetentionPolicy) error
+	FindActivePolicies() ([]*ArtifactRetentionPolicy, error)
+	FindActivePolicyByAppId(appId int) (*ArtifactRetentionPolicy, error)
+
+	SaveRun(run *ArtifactRetentionRun) error
+	UpdateRun(run *ArtifactRetentionRun) error
+	FindRunById(id int) (*ArtifactRetentionRun, error)
+	FindRuns(offset int, limit int) ([]*ArtifactRetentionRun, error)
+}
+
+type ArtifactRetentionRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewArtifactRetentionRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *ArtifactRetentionRepositoryImpl {
+	return &ArtifactRetentionRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl ArtifactRetentionRepositoryImpl) SavePolicy(policy *ArtifactRetentionPolicy) error {
+	return impl.dbConnection.Insert(policy)
+}
+
+func (impl ArtifactRetentionRepositoryImpl) UpdatePolicy(policy *ArtifactRetentionPolicy) error {
+	return impl.dbConnection.Update(policy)
+}
+
+func (impl ArtifactRetentionRepositoryImpl) FindActivePolicies() ([]*ArtifactRetentionPolicy, error) {
+	var policies []*ArtifactRetentionPolicy
+	err := impl.dbConnection.Model(&policies).
+		Where("active = ?", true).
+		Order("app_id ASC").
+		Select()
+	return policies, err
+}
+
+func (impl ArtifactRetentionRepositoryImpl) FindActivePolicyByAppId(appId int) (*ArtifactRetentionPolicy, error) {
+	policy := &ArtifactRetentionPolicy{}
+	err := impl.dbConnection.Model(policy).
+		Where("app_id = ?", appId).
+		Where("active = ?", true).
+		Select()
+	return policy, err
+}
+
+func (impl ArtifactRetentionRepositoryImpl) SaveRun(run *ArtifactRetentionRun) error {
+	return impl.dbConnection.Insert(run)
+}
+
+func (impl ArtifactRetentionRepositoryImpl) UpdateRun(run *ArtifactRetentionRun) error {
+	return impl.dbConnection.Update(run)
+}
+
+func (impl ArtifactRetentionRepositoryImpl) FindRunById(id int) (*ArtifactRetentionRun, error) {
+	run := &ArtifactRetentionRun{}
+	err := impl.dbConnection.Model(run).
+		Where("id = ?", id).
+		Select()
+	return run, err
+}
+
+// FindRuns returns the runs without the report, which can be large
+func (impl ArtifactRetentionRepositoryImpl) FindRuns(offset int, limit int) ([]*ArtifactRetentionRun, error) {
+	var runs []*ArtifactRetentionRun
+	err := impl.dbConnection.Model(&runs).
+		Column("id", "status", "dry_run", "artifacts_deleted", "logs_deleted", "failed_count", "message", "started_on", "finished_on", "created_on", "created_by", "updated_on", "updated_by").
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Select()
+	return runs, err
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipelineConfig
+
+import (
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+)
+
+type CanaryAnalysisStatus string
+
+const (
+	CANARY_ANALYSIS_RUNNING   CanaryAnalysisStatus = "Running"
+	CANARY_ANALYSIS_SUCCEEDED CanaryAnalysisStatus = "Succeeded"
+	CANARY_ANALYSIS_ABORTED   CanaryAnalysisStatus = "Aborted"
+	CANARY_ANALYSIS_SKIPPED   CanaryAnalysisStatus = "Skipped"
+)
+
+type CanaryStepAction string
+
+const (
+	CANARY_STEP_ACTION_PROMOTE      CanaryStepAction = "PROMOTE"
+	CANARY_STEP_ACTION_ABORT        CanaryStepAction = "ABORT"
+	CANARY_STEP_ACTION_INCONCLUSIVE CanaryStepAction = "INCONCLUSIVE" //metrics could not be evaluated, step is retried
+)
+
+type CanaryAnalysisMetric struct {
+	tableName  struct{} `sql:"canary_analysis_metric" pg:",discard_unknown_columns"`
+	Id         int      `sql:"id,pk"`
+	PipelineId int      `sql:"pipeline_id,notnull"`
+	Name       string   `sql:"name,notnull"`
+	Query      string   `sql:"query,notnull"`
+	Operator   string   `sql:"operator,notnull"`
+	Threshold  float64  `sql:"threshold,notnull"`
+	Active     bool     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+// CanaryAnalysisStepResult is the outcome of evaluating the canary metrics at one paused step of a deployment
+type CanaryAnalysisStepResult struct {
+	tableName          struct{}         `sql:"canary_analysis_step_result" pg:",discard_unknown_columns"`
+	Id                 int              `sql:"id,pk"`
+	CdWorkflowRunnerId int              `sql:"cd_workflow_runner_id,notnull"`
+	StepIndex          int              `sql:"step_index,notnull"`
+	CanaryWeight       int              `sql:"canary_weight"`
+	Passed             bool             `sql:"passed,notnull"`
+	Action             CanaryStepAction `sql:"action,notnull"`
+	MetricResults      string           `sql:"metric_results"` //json of metric values evaluated in this step
+	Message            string           `sql:"message"`
+	Attempts           int              `sql:"attempts,notnull"`
+	sql.AuditLog
+}
+
+type CanaryAnalysisRepository interface {
+	SaveMetric(metric *CanaryAnalysisMetric, tx *pg.Tx) error
+	UpdateMetric(metric *CanaryAnalysisMetric, tx *pg.Tx) error
+	FindActiveMetricsByPipelineId(pipelineId int) ([]*CanaryAnalysisMetric, error)
+
+	SaveStepResult(result *CanaryAnalysisStepResult) error
+	UpdateStepResult(result *CanaryAnalysisStepResult) error
+	FindStepResultsByRunnerId(cdWorkflowRunnerId int) ([]*CanaryAnalysisStepResult, error)
+	FindStepResultByRunnerIdAndStepIndex(cdWorkflowRunnerId int, stepIndex int) (*CanaryAnalysisStepResult, error)
+
+	// FindRunnersPendingAnalysis returns the latest deploy runner of every pipeline having canary metrics, whose analysis is not yet complete
+	FindRunnersPendingAnalysis() ([]*CdWorkflowRunner, error)
+	UpdateRunnerAnalysisStatus(cdWorkflowRunnerId int, status CanaryAnalysisStatus) error
+}
+
+type CanaryAnalysisRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewCanaryAnalysisRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *CanaryAnalysisRepositoryImpl {
+	return &CanaryAnalysisRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl CanaryAnalysisRepositoryImpl) SaveMetric(metric *CanaryAnalysisMetric, tx *pg.Tx) error {
+	return tx.Insert(metric)
+}
+
+func (impl CanaryAnalysisRepositoryImpl) UpdateMetric(metric *CanaryAnalysisMetric, tx *pg.Tx) error {
+	return tx.Update(metric)
+}
+
+func (impl CanaryAnalysisRepositoryImpl) FindActiveMetricsByPipelineId(pipelineId int) ([]*CanaryAnalysisMetric, error) {
+	var metrics []*CanaryAnalysisMetric
+	err := impl.dbConnection.Model(&metrics).
+		Where("pipeline_id = ?", pipelineId).
+		Where("active = ?", true).
+		Order("id ASC").
+		Select()
+	return metrics, err
+}
+
+func (impl CanaryAnalysisRepositoryImpl) SaveStepResult(result *CanaryAnalysisStepResult) error {
+	return impl.dbConnection.Insert(result)
+}
+
+func (impl CanaryAnalysisRepositoryImpl) UpdateStepResult(result *CanaryAnalysisStepResult) error {
+	return impl.dbConnection.Update(result)
+}
+
+func (impl CanaryAnalysisRepositoryImpl) FindStepResultsByRunnerId(cdWorkflowRunnerId int) ([]*CanaryAnalysisStepResult, error) {
+	var results []*CanaryAnalysisStepResult
+	err := impl.dbConnection.Model(&results).
+		Where("cd_workflow_runner_id = ?", cdWorkflowRunnerId).
+		Order("step_index ASC").
+		Select()
+	return results, err
+}
+
+func (impl CanaryAnalysisRepositoryImpl) FindStepResultByRunnerIdAndStepIndex(cdWorkflowRunnerId int, stepIndex int) (*CanaryAnalysisStepResult, error) {
+	result := &CanaryAnalysisStepResult{}
+	err := impl.dbConnection.Model(result).
+		Where("cd_workflow_runner_id = ?", cdWorkflowRunnerId).
+		Where("step_index = ?", stepIndex).
+		Limit(1).
+		Select()
+	return result, err
+}
+
+func (impl CanaryAnalysisRepositoryImpl) FindRunnersPendingAnalysis() ([]*CdWorkflowRunner, error) {
+	var runners []*CdWorkflowRunner
+	query := "SELECT wfr.* FROM cd_workflow_runner wfr" +
+		" INNER JOIN (SELECT max(r.id) AS id FROM cd_workflow_runner r" +
+		" INNER JOIN cd_workflow wf ON wf.id = r.cd_workflow_id" +
+		" WHERE r.workflow_type = ? AND wf.pipeline_id IN (SELECT DISTINCT pipeline_id FROM canary_analysis_metric WHERE active = true)" +
+		" GROUP BY wf.pipeline_id) latest ON latest.id = wfr.id" +
+		" WHERE COALESCE(wfr.canary_analysis_status, '') IN ('', ?);"
+	_, err := impl.dbConnection.Query(&runners, query, bean.CD_WORKFLOW_TYPE_DEPLOY, CANARY_ANALYSIS_RUNNING)
+	return runners, err
+}
+
+func (impl CanaryAnalysisRepositoryImpl) UpdateRunnerAnalysisStatus(cdWorkflowRunnerId int, status CanaryAnalysisStatus) error {
+	_, err := impl.dbConnection.Model(&CdWorkflowRunner{}).
+		Set("canary_analysis_status = ?", status).
+		Where("id = ?", cdWorkflowRunnerId).
+		Update()
+	return err
+}
@@ -116,43 +116,50 @@ const WORKFLOW_EXECUTOR_TYPE_AWF = "AWF"
 const WORKFLOW_EXECUTOR_TYPE_SYSTEM = "SYSTEM"
 
 type CdWorkflowRunner struct {
-	tableName    struct{}             `sql:"cd_workflow_runner" pg:",discard_unknown_columns"`
-	Id           int                  `sql:"id,pk"`
-	Name         string               `sql:"name"`
-	WorkflowType bean.WorkflowType  `sql:"workflow_type"` //pre,post,deploy
-	ExecutorType WorkflowExecutorType `sql:"executor_type"` //awf, system
-	Status       string               `sql:"status"`
-	PodStatus    string               `sql:"pod_status"`
-	Message      string               `sql:"message"`
-	StartedOn    time.Time            `sql:"started_on"`
-	FinishedOn   time.Time            `sql:"finished_on"`
-	Namespace    string               `sql:"namespace"`
-	LogLocation  string               `sql:"log_file_path"`
-	TriggeredBy  int32                `sql:"triggered_by"`
-	CdWorkflowId int                  `sql:"cd_workflow_id"`
-	CdWorkflow   *CdWorkflow
+	tableName            struct{}             `sql:"cd_workflow_runner" pg:",discard_unknown_columns"`
+	Id                   int                  `sql:"id,pk"`
+	Name                 string               `sql:"name"`
+	WorkflowType         bean.WorkflowType    `sql:"workflow_type"` //pre,post,deploy
+	ExecutorType         WorkflowExecutorType `sql:"executor_type"` //awf, system
+	Status               string               `sql:"status"`
+	PodStatus            string               `sql:"pod_status"`
+	Message              string               `sql:"message"`
+	StartedOn            time.Time            `sql:"started_on"`
+	FinishedOn           time.Time            `sql:"finished_on"`
+	Namespace            string               `sql:"namespace"`
+	LogLocation          string               `sql:"log_file_path"`
+	TriggeredBy          int32                `sql:"triggered_by"`
+	CdWorkflowId         int                  `sql:"cd_workflow_id"`
+	CanaryAnalysisStatus CanaryAnalysisStatus `sql:"canary_analysis_status"`
+	//result of image signature verification done before deployment
+	ImageSignatureStatus  string `sql:"image_signature_status"`
+	ImageSignatureMessage string `sql:"image_signature_message"`
+	CdWorkflow            *CdWorkflow
 }
 
 type CdWorkflowWithArtifact struct {
-	Id           int       `json:"id"`
-	CdWorkflowId int       `json:"cd_workflow_id"`
-	Name         string    `json:"name"`
-	Status       string    `json:"status"`
-	PodStatus    string    `json:"pod_status"`
-	Message      string    `json:"message"`
-	StartedOn    time.Time `json:"started_on"`
-	FinishedOn   time.Time `json:"finished_on"`
-	PipelineId   int       `json:"pipeline_id"`
-	Namespace    string    `json:"namespace"`
-	LogFilePath  string    `json:"log_file_path"`
-	TriggeredBy  int32     `json:"triggered_by"`
-	EmailId      string    `json:"email_id"`
-	Image        string    `json:"image"`
-	MaterialInfo string    `json:"material_info,omitempty"`
-	DataSource   string    `json:"data_source,omitempty"`
-	CiArtifactId int       `json:"ci_artifact_id,omitempty"`
-	WorkflowType string    `json:"workflow_type,omitempty"`
-	ExecutorType string    `json:"executor_type,omitempty"`
+	Id                    int       `json:"id"`
+	CdWorkflowId          int       `json:"cd_workflow_id"`
+	Name                  string    `json:"name"`
+	Status                string    `json:"status"`
+	PodStatus             string    `json:"pod_status"`
+	Message               string    `json:"message"`
+	StartedOn             time.Time `json:"started_on"`
+	FinishedOn            time.Time `json:"finished_on"`
+	PipelineId            int       `json:"pipeline_id"`
+	Namespace             string    `json:"namespace"`
+	LogFilePath           string    `json:"log_file_path"`
+	TriggeredBy           int32     `json:"triggered_by"`
+	EmailId               string    `json:"email_id"`
+	Image                 string    `json:"image"`
+	MaterialInfo          string    `json:"material_info,omitempty"`
+	DataSource            string    `json:"data_source,omitempty"`
+	CiArtifactId          int       `json:"ci_artifact_id,omitempty"`
+	WorkflowType          string    `json:"workflow_type,omitempty"`
+	ExecutorType          string    `json:"executor_type,omitempty"`
+	CanaryAnalysisStatus  string    `json:"canary_analysis_status,omitempty"`
+	ImageSignatureStatus  string    `json:"image_signature_status,omitempty"`
+	ImageSignatureMessage string    `json:"image_signature_message,omitempty"`
 }
 
 type TriggerWorkflowStatus struct {
@@ -38,6 +38,9 @@ type CiPipelineMaterial struct {
 	ScmVersion   string     `sql:"scm_version"` //gocd scm version
 	Active       bool       `sql:"active,notnull"`
 	GitTag       string     `sql:"-"`
+	// IncludePaths and ExcludePaths are glob filters on changed files for webhook triggered builds
+	IncludePaths []string `sql:"include_paths" pg:",array"`
+	ExcludePaths []string `sql:"exclude_paths" pg:",array"`
 	CiPipeline   *CiPipeline
 	GitMaterial  *GitMaterial
 	sql.AuditLog
@@ -46,6 +49,8 @@ type CiPipelineMaterial struct {
 type CiPipelineMaterialRepository interface {
 	Save(tx *pg.Tx, pipeline ...*CiPipelineMaterial) error
 	Update(tx *pg.Tx, material ...*CiPipelineMaterial) error
+	// UpdatePathFilters also writes empty filters, which Update skips as it only sets not null columns
+	UpdatePathFilters(tx *pg.Tx, materials ...*CiPipelineMaterial) error
 	FindByCiPipelineIdsIn(ids []int) ([]*CiPipelineMaterial, error)
 	GetById(id int) (*CiPipelineMaterial, error)
 	GetByPipelineId(id int) ([]*CiPipelineMaterial, error)
@@ -119,3 +124,13 @@ func (impl CiPipelineMaterialRepositoryImpl) Update(tx *pg.Tx, materials ...*CiP
 
 	return nil
 }
+
+func (impl CiPipelineMaterialRepositoryImpl) UpdatePathFilters(tx *pg.Tx, materials ...*CiPipelineMaterial) error {
+	for _, material := range materials {
+		_, err := tx.Model(material).Column("include_paths", "exclude_paths").WherePK().Update()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -42,6 +42,9 @@ type CiPipeline struct {
 	IsExternal       bool   `sql:"external,notnull"`
 	ParentCiPipeline int    `sql:"parent_ci_pipeline"`
 	ScanEnabled      bool   `sql:"scan_enabled,notnull"`
+	CronSchedule     string `sql:"cron_schedule"`
+	SkipUnchanged    bool   `sql:"skip_unchanged_scheduled_build,notnull"`
+	SbomEnabled      bool   `sql:"sbom_enabled,notnull"`
 	sql.AuditLog
 	CiPipelineMaterials []*CiPipelineMaterial
 	CiTemplate          *CiTemplate
@@ -85,6 +88,8 @@ type CiPipelineRepository interface {
 	FindByCiAndAppDetailsById(pipelineId int) (pipeline *CiPipeline, err error)
 	FindByIdsIn(ids []int) ([]*CiPipeline, error)
 	Update(pipeline *CiPipeline, tx *pg.Tx) error
+	UpdateCronSchedule(pipeline *CiPipeline, tx *pg.Tx) error
+	UpdateSbomEnabled(pipeline *CiPipeline, tx *pg.Tx) error
 	PipelineExistsByName(names []string) (found []string, err error)
 	FindByName(pipelineName string) (pipeline *CiPipeline, err error)
 	FindByParentCiPipelineId(parentCiPipelineId int) ([]*CiPipeline, error)
@@ -93,6 +98,8 @@ type CiPipelineRepository interface {
 	FetchCiPipelinesForDG(parentId int, childCiPipelineIds []int) (*CiPipeline, int, error)
 	FinDByParentCiPipelineAndAppId(parentCiPipeline int, appIds []int) ([]*CiPipeline, error)
 	FindAllPipelineInLast24Hour() (pipelines []*CiPipeline, err error)
+	FindAllScheduled() ([]*CiPipeline, error)
+	FindAllActive() ([]*CiPipeline, error)
 }
 type CiPipelineRepositoryImpl struct {
 	dbConnection *pg.DB
@@ -144,6 +151,24 @@ func (impl CiPipelineRepositoryImpl) Update(pipeline *CiPipeline, tx *pg.Tx) err
 	return err
 }
 
+// UpdateCronSchedule writes schedule columns explicitly as UpdateNotNull would skip clearing them
+func (impl CiPipelineRepositoryImpl) UpdateCronSchedule(pipeline *CiPipeline, tx *pg.Tx) error {
+	_, err := tx.Model(pipeline).
+		Column("cron_schedule", "skip_unchanged_scheduled_build", "updated_on", "updated_by").
+		WherePK().
+		Update()
+	return err
+}
+
+// UpdateSbomEnabled writes sbom_enabled explicitly as UpdateNotNull would skip turning it off
+func (impl CiPipelineRepositoryImpl) UpdateSbomEnabled(pipeline *CiPipeline, tx *pg.Tx) error {
+	_, err := tx.Model(pipeline).
+		Column("sbom_enabled", "updated_on", "updated_by").
+		WherePK().
+		Update()
+	return err
+}
+
 func (impl CiPipelineRepositoryImpl) UpdateCiPipelineScript(script *CiPipelineScript, tx *pg.Tx) error {
 	r, err := tx.Model(script).WherePK().UpdateNotNull()
 	impl.logger.Debugf("total rows saved %d", r.RowsAffected())
@@ -308,3 +333,23 @@ func (impl CiPipelineRepositoryImpl) FindAllPipelineInLast24Hour() (pipelines []
 		Select()
 	return pipelines, err
 }
+
+func (impl CiPipelineRepositoryImpl) FindAllActive() ([]*CiPipeline, error) {
+	var ciPipelines []*CiPipeline
+	err := impl.dbConnection.Model(&ciPipelines).
+		Where("active = ?", true).
+		Where("deleted = ?", false).
+		Select()
+	return ciPipelines, err
+}
+
+func (impl CiPipelineRepositoryImpl) FindAllScheduled() ([]*CiPipeline, error) {
+	var ciPipelines []*CiPipeline
+	err := impl.dbConnection.Model(&ciPipelines).
+		Where("active = ?", true).
+		Where("deleted = ?", false).
+		Where("cron_schedule IS NOT NULL").
+		Where("cron_schedule <> ''").
+		Select()
+	return ciPipelines, err
+}
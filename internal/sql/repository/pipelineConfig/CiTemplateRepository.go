@@ -40,6 +40,9 @@ type CiTemplate struct {
 	Version           string   `sql:"version"` //gocd etage
 	Active            bool     `sql:"active,notnull"`
 	GitMaterialId     int      `sql:"git_material_id"`
+	TargetPlatform    string   `sql:"target_platform"` //comma separated platforms like linux/amd64,linux/arm64, empty for the runner platform
+	BuildType         string   `sql:"build_type"`      //DOCKERFILE or BUILDPACK
+	BuilderImage      string   `sql:"builder_image"`   //cnb builder image for BUILDPACK builds
 	sql.AuditLog
 	App            *app.App
 	DockerRegistry *repository.DockerArtifactStore
@@ -50,6 +53,8 @@ type CiTemplateRepository interface {
 	Save(material *CiTemplate) error
 	FindByAppId(appId int) (ciTemplate *CiTemplate, err error)
 	Update(material *CiTemplate) error
+	// UpdateBuildOptions writes the optional build settings, Update skips them when they are cleared
+	UpdateBuildOptions(material *CiTemplate) error
 	FindByDockerRegistryId(dockerRegistryId string) (ciTemplates []*CiTemplate, err error)
 }
 
@@ -74,6 +79,12 @@ func (impl CiTemplateRepositoryImpl) Update(material *CiTemplate) error {
 	impl.logger.Infof("total rows saved %d", r.RowsAffected())
 	return err
 }
+
+func (impl CiTemplateRepositoryImpl) UpdateBuildOptions(material *CiTemplate) error {
+	_, err := impl.dbConnection.Model(material).Column("target_platform", "build_type", "builder_image").WherePK().Update()
+	return err
+}
+
 func (impl CiTemplateRepositoryImpl) FindByAppId(appId int) (ciTemplate *CiTemplate, err error) {
 	template := &CiTemplate{}
 	err = impl.dbConnection.Model(template).
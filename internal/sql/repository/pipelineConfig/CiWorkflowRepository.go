@@ -29,6 +29,7 @@ type CiWorkflowRepository interface {
 
 	SaveWorkFlow(wf *CiWorkflow) error
 	FindLastTriggeredWorkflow(pipelineId int) (*CiWorkflow, error)
+	FindLastSucceededWorkflow(pipelineId int) (*CiWorkflow, error)
 	UpdateWorkFlow(wf *CiWorkflow) error
 	FindByStatusesIn(activeStatuses []string) ([]*CiWorkflow, error)
 	FindByPipelineId(pipelineId int, offset int, size int) ([]WorkflowWithArtifact, error)
@@ -37,6 +38,8 @@ type CiWorkflowRepository interface {
 
 	FindLastTriggeredWorkflowByCiIds(pipelineId []int) (ciWorkflow []*CiWorkflow, err error)
 	FindLastTriggeredWorkflowByArtifactId(ciArtifactId int) (ciWorkflow *CiWorkflow, err error)
+	// FindWithLogsFinishedBefore returns the workflows of the pipeline finished before the time which still have logs or archives
+	FindWithLogsFinishedBefore(pipelineId int, before time.Time) ([]*CiWorkflow, error)
 }
 
 type CiWorkflowRepositoryImpl struct {
@@ -139,6 +142,17 @@ func (impl *CiWorkflowRepositoryImpl) FindLastTriggeredWorkflow(pipelineId int)
 	return workflow, err
 }
 
+func (impl *CiWorkflowRepositoryImpl) FindLastSucceededWorkflow(pipelineId int) (ciWorkflow *CiWorkflow, err error) {
+	workflow := &CiWorkflow{}
+	err = impl.dbConnection.Model(workflow).
+		Where("ci_pipeline_id = ?", pipelineId).
+		Where("status = ?", "Succeeded").
+		Order("started_on Desc").
+		Limit(1).
+		Select()
+	return workflow, err
+}
+
 func (impl *CiWorkflowRepositoryImpl) FindByStatusesIn(activeStatuses []string) ([]*CiWorkflow, error) {
 	var ciWorkFlows []*CiWorkflow
 	err := impl.dbConnection.Model(&ciWorkFlows).
@@ -216,3 +230,14 @@ func (impl *CiWorkflowRepositoryImpl) FindLastTriggeredWorkflowByArtifactId(ciAr
 		Select()
 	return workflow, err
 }
+
+func (impl *CiWorkflowRepositoryImpl) FindWithLogsFinishedBefore(pipelineId int, before time.Time) ([]*CiWorkflow, error) {
+	var workflows []*CiWorkflow
+	err := impl.dbConnection.Model(&workflows).
+		Where("ci_pipeline_id = ?", pipelineId).
+		Where("finished_on < ?", before).
+		Where("(log_file_path <> '' OR ci_artifact_location <> '')").
+		Order("id ASC").
+		Select()
+	return workflows, err
+}
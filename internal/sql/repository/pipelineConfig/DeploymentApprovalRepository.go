@@ -0,0 +1,177 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipelineConfig
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+)
+
+type DeploymentApprovalStatus string
+
+const (
+	DEPLOYMENT_APPROVAL_REQUESTED DeploymentApprovalStatus = "REQUESTED"
+	DEPLOYMENT_APPROVAL_APPROVED  DeploymentApprovalStatus = "APPROVED"
+	DEPLOYMENT_APPROVAL_REJECTED  DeploymentApprovalStatus = "REJECTED"
+	DEPLOYMENT_APPROVAL_CANCELLED DeploymentApprovalStatus = "CANCELLED"
+)
+
+type DeploymentApprovalUserResponse string
+
+const (
+	DEPLOYMENT_APPROVAL_USER_APPROVED DeploymentApprovalUserResponse = "APPROVED"
+	DEPLOYMENT_APPROVAL_USER_REJECTED DeploymentApprovalUserResponse = "REJECTED"
+)
+
+type DeploymentApprovalConfig struct {
+	tableName      struct{} `sql:"deployment_approval_config" pg:",discard_unknown_columns"`
+	Id             int      `sql:"id,pk"`
+	PipelineId     int      `sql:"pipeline_id,notnull"`
+	RequiredCount  int      `sql:"required_count,notnull"`
+	ApproverEmails []string `sql:"approver_emails" pg:",array"`
+	ApproverGroups []string `sql:"approver_groups" pg:",array"`
+	Active         bool     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+type DeploymentApprovalRequest struct {
+	tableName    struct{}                 `sql:"deployment_approval_request" pg:",discard_unknown_columns"`
+	Id           int                      `sql:"id,pk"`
+	PipelineId   int                      `sql:"pipeline_id,notnull"`
+	CiArtifactId int                      `sql:"ci_artifact_id,notnull"`
+	Status       DeploymentApprovalStatus `sql:"status,notnull"`
+	Active       bool                     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+type DeploymentApprovalUserData struct {
+	tableName         struct{}                       `sql:"deployment_approval_user_data" pg:",discard_unknown_columns"`
+	Id                int                            `sql:"id,pk"`
+	ApprovalRequestId int                            `sql:"approval_request_id,notnull"`
+	UserId            int32                          `sql:"user_id,notnull"`
+	UserResponse      DeploymentApprovalUserResponse `sql:"user_response,notnull"`
+	Comment           string                         `sql:"comment"`
+	sql.AuditLog
+}
+
+type DeploymentApprovalRepository interface {
+	SaveConfig(config *DeploymentApprovalConfig, tx *pg.Tx) error
+	UpdateConfig(config *DeploymentApprovalConfig, tx *pg.Tx) error
+	FindConfigByPipelineId(pipelineId int) (*DeploymentApprovalConfig, error)
+	FindConfigByPipelineIds(pipelineIds []int) ([]*DeploymentApprovalConfig, error)
+
+	SaveRequest(request *DeploymentApprovalRequest) error
+	UpdateRequest(request *DeploymentApprovalRequest) error
+	FindRequestById(id int) (*DeploymentApprovalRequest, error)
+	FindActiveRequest(pipelineId int, ciArtifactId int) (*DeploymentApprovalRequest, error)
+	FindActiveRequestsByPipelineId(pipelineId int) ([]*DeploymentApprovalRequest, error)
+
+	SaveUserData(userData *DeploymentApprovalUserData) error
+	FindUserDataByRequestId(approvalRequestId int) ([]*DeploymentApprovalUserData, error)
+}
+
+type DeploymentApprovalRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewDeploymentApprovalRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *DeploymentApprovalRepositoryImpl {
+	return &DeploymentApprovalRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl DeploymentApprovalRepositoryImpl) SaveConfig(config *DeploymentApprovalConfig, tx *pg.Tx) error {
+	return tx.Insert(config)
+}
+
+func (impl DeploymentApprovalRepositoryImpl) UpdateConfig(config *DeploymentApprovalConfig, tx *pg.Tx) error {
+	return tx.Update(config)
+}
+
+func (impl DeploymentApprovalRepositoryImpl) FindConfigByPipelineId(pipelineId int) (*DeploymentApprovalConfig, error) {
+	config := &DeploymentApprovalConfig{}
+	err := impl.dbConnection.Model(config).
+		Where("pipeline_id = ?", pipelineId).
+		Where("active = ?", true).
+		Limit(1).
+		Select()
+	return config, err
+}
+
+func (impl DeploymentApprovalRepositoryImpl) FindConfigByPipelineIds(pipelineIds []int) ([]*DeploymentApprovalConfig, error) {
+	var configs []*DeploymentApprovalConfig
+	if len(pipelineIds) == 0 {
+		return configs, nil
+	}
+	err := impl.dbConnection.Model(&configs).
+		Where("pipeline_id in (?)", pg.In(pipelineIds)).
+		Where("active = ?", true).
+		Select()
+	return configs, err
+}
+
+func (impl DeploymentApprovalRepositoryImpl) SaveRequest(request *DeploymentApprovalRequest) error {
+	return impl.dbConnection.Insert(request)
+}
+
+func (impl DeploymentApprovalRepositoryImpl) UpdateRequest(request *DeploymentApprovalRequest) error {
+	return impl.dbConnection.Update(request)
+}
+
+func (impl DeploymentApprovalRepositoryImpl) FindRequestById(id int) (*DeploymentApprovalRequest, error) {
+	request := &DeploymentApprovalRequest{}
+	err := impl.dbConnection.Model(request).
+		Where("id = ?", id).
+		Select()
+	return request, err
+}
+
+func (impl DeploymentApprovalRepositoryImpl) FindActiveRequest(pipelineId int, ciArtifactId int) (*DeploymentApprovalRequest, error) {
+	request := &DeploymentApprovalRequest{}
+	err := impl.dbConnection.Model(request).
+		Where("pipeline_id = ?", pipelineId).
+		Where("ci_artifact_id = ?", ciArtifactId).
+		Where("active = ?", true).
+		Order("id DESC").
+		Limit(1).
+		Select()
+	return request, err
+}
+
+func (impl DeploymentApprovalRepositoryImpl) FindActiveRequestsByPipelineId(pipelineId int) ([]*DeploymentApprovalRequest, error) {
+	var requests []*DeploymentApprovalRequest
+	err := impl.dbConnection.Model(&requests).
+		Where("pipeline_id = ?", pipelineId).
+		Where("active = ?", true).
+		Order("id DESC").
+		Select()
+	return requests, err
+}
+
+func (impl DeploymentApprovalRepositoryImpl) SaveUserData(userData *DeploymentApprovalUserData) error {
+	return impl.dbConnection.Insert(userData)
+}
+
+func (impl DeploymentApprovalRepositoryImpl) FindUserDataByRequestId(approvalRequestId int) ([]*DeploymentApprovalUserData, error) {
+	var userData []*DeploymentApprovalUserData
+	err := impl.dbConnection.Model(&userData).
+		Where("approval_request_id = ?", approvalRequestId).
+		Order("id ASC").
+		Select()
+	return userData, err
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipelineConfig
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"time"
+)
+
+type DeploymentAutoRollbackStatus string
+
+const (
+	AUTO_ROLLBACK_DEGRADED    DeploymentAutoRollbackStatus = "DEGRADED"
+	AUTO_ROLLBACK_RECOVERED   DeploymentAutoRollbackStatus = "RECOVERED"
+	AUTO_ROLLBACK_ROLLED_BACK DeploymentAutoRollbackStatus = "ROLLED_BACK"
+	AUTO_ROLLBACK_FAILED      DeploymentAutoRollbackStatus = "FAILED"
+)
+
+type DeploymentAutoRollbackConfig struct {
+	tableName              struct{} `sql:"deployment_auto_rollback_config" pg:",discard_unknown_columns"`
+	Id                     int      `sql:"id,pk"`
+	PipelineId             int      `sql:"pipeline_id,notnull"`
+	DegradedTimeoutMinutes int      `sql:"degraded_timeout_minutes,notnull"`
+	Active                 bool     `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+// DeploymentAutoRollback tracks a degraded release and the rollback done for it
+type DeploymentAutoRollback struct {
+	tableName                  struct{}                     `sql:"deployment_auto_rollback" pg:",discard_unknown_columns"`
+	Id                         int                          `sql:"id,pk"`
+	PipelineId                 int                          `sql:"pipeline_id,notnull"`
+	PipelineOverrideId         int                          `sql:"pipeline_override_id,notnull"`
+	CiArtifactId               int                          `sql:"ci_artifact_id,notnull"`
+	Status                     DeploymentAutoRollbackStatus `sql:"status,notnull"`
+	DegradedSince              time.Time                    `sql:"degraded_since,notnull"`
+	RollbackCiArtifactId       int                          `sql:"rollback_ci_artifact_id"`
+	RollbackCdWorkflowRunnerId int                          `sql:"rollback_cd_workflow_runner_id"`
+	Message                    string                       `sql:"message"`
+	sql.AuditLog
+}
+
+type DeploymentAutoRollbackRepository interface {
+	SaveConfig(config *DeploymentAutoRollbackConfig, tx *pg.Tx) error
+	UpdateConfig(config *DeploymentAutoRollbackConfig, tx *pg.Tx) error
+	FindConfigByPipelineId(pipelineId int) (*DeploymentAutoRollbackConfig, error)
+
+	Save(rollback *DeploymentAutoRollback) error
+	Update(rollback *DeploymentAutoRollback) error
+	FindByPipelineOverrideId(pipelineOverrideId int) (*DeploymentAutoRollback, error)
+	FindByStatus(status DeploymentAutoRollbackStatus) ([]*DeploymentAutoRollback, error)
+	FindByPipelineId(pipelineId int, offset int, limit int) ([]*DeploymentAutoRollback, error)
+}
+
+type DeploymentAutoRollbackRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewDeploymentAutoRollbackRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *DeploymentAutoRollbackRepositoryImpl {
+	return &DeploymentAutoRollbackRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) SaveConfig(config *DeploymentAutoRollbackConfig, tx *pg.Tx) error {
+	return tx.Insert(config)
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) UpdateConfig(config *DeploymentAutoRollbackConfig, tx *pg.Tx) error {
+	return tx.Update(config)
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) FindConfigByPipelineId(pipelineId int) (*DeploymentAutoRollbackConfig, error) {
+	config := &DeploymentAutoRollbackConfig{}
+	err := impl.dbConnection.Model(config).
+		Where("pipeline_id = ?", pipelineId).
+		Where("active = ?", true).
+		Limit(1).
+		Select()
+	return config, err
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) Save(rollback *DeploymentAutoRollback) error {
+	return impl.dbConnection.Insert(rollback)
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) Update(rollback *DeploymentAutoRollback) error {
+	return impl.dbConnection.Update(rollback)
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) FindByPipelineOverrideId(pipelineOverrideId int) (*DeploymentAutoRollback, error) {
+	rollback := &DeploymentAutoRollback{}
+	err := impl.dbConnection.Model(rollback).
+		Where("pipeline_override_id = ?", pipelineOverrideId).
+		Order("id DESC").
+		Limit(1).
+		Select()
+	return rollback, err
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) FindByStatus(status DeploymentAutoRollbackStatus) ([]*DeploymentAutoRollback, error) {
+	var rollbacks []*DeploymentAutoRollback
+	err := impl.dbConnection.Model(&rollbacks).
+		Where("status = ?", status).
+		Order("id ASC").
+		Select()
+	return rollbacks, err
+}
+
+func (impl DeploymentAutoRollbackRepositoryImpl) FindByPipelineId(pipelineId int, offset int, limit int) ([]*DeploymentAutoRollback, error) {
+	var rollbacks []*DeploymentAutoRollback
+	err := impl.dbConnection.Model(&rollbacks).
+		Where("pipeline_id = ?", pipelineId).
+		Order("id DESC").
+		Offset(offset).
+		Limit(limit).
+		Select()
+	return rollbacks, err
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipelineConfig
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"time"
+)
+
+type DeploymentWindowType string
+
+const (
+	DEPLOYMENT_WINDOW_ALLOW DeploymentWindowType = "ALLOW"
+	DEPLOYMENT_WINDOW_DENY  DeploymentWindowType = "DENY"
+)
+
+type DeploymentWindow struct {
+	tableName       struct{}             `sql:"deployment_window" pg:",discard_unknown_columns"`
+	Id              int                  `sql:"id,pk"`
+	Name            string               `sql:"name,notnull"`
+	EnvironmentId   int                  `sql:"environment_id,notnull"`
+	AppId           int                  `sql:"app_id"`
+	WindowType      DeploymentWindowType `sql:"window_type,notnull"`
+	CronExpression  string               `sql:"cron_expression"`
+	DurationMinutes int                  `sql:"duration_minutes"`
+	StartTime       time.Time            `sql:"start_time"`
+	EndTime         time.Time            `sql:"end_time"`
+	TimeZone        string               `sql:"time_zone,notnull"`
+	Active          bool                 `sql:"active,notnull"`
+	sql.AuditLog
+}
+
+type DeploymentWindowOverrideAudit struct {
+	tableName          struct{} `sql:"deployment_window_override_audit" pg:",discard_unknown_columns"`
+	Id                 int      `sql:"id,pk"`
+	DeploymentWindowId int      `sql:"deployment_window_id"`
+	EnvironmentId      int      `sql:"environment_id,notnull"`
+	PipelineId         int      `sql:"pipeline_id,notnull"`
+	CiArtifactId       int      `sql:"ci_artifact_id,notnull"`
+	Reason             string   `sql:"reason"`
+	WindowMessage      string   `sql:"window_message"`
+	sql.AuditLog
+}
+
+type DeploymentWindowRepository interface {
+	Save(window *DeploymentWindow) error
+	Update(window *DeploymentWindow) error
+	FindById(id int) (*DeploymentWindow, error)
+	FindAllActive() ([]*DeploymentWindow, error)
+	FindActiveByEnvironmentId(environmentId int) ([]*DeploymentWindow, error)
+	SaveOverrideAudit(audit *DeploymentWindowOverrideAudit) error
+	FindOverrideAuditByEnvironmentId(environmentId int) ([]*DeploymentWindowOverrideAudit, error)
+}
+
+type DeploymentWindowRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewDeploymentWindowRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *DeploymentWindowRepositoryImpl {
+	return &DeploymentWindowRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl DeploymentWindowRepositoryImpl) Save(window *DeploymentWindow) error {
+	return impl.dbConnection.Insert(window)
+}
+
+func (impl DeploymentWindowRepositoryImpl) Update(window *DeploymentWindow) error {
+	return impl.dbConnection.Update(window)
+}
+
+func (impl DeploymentWindowRepositoryImpl) FindById(id int) (*DeploymentWindow, error) {
+	window := &DeploymentWindow{}
+	err := impl.dbConnection.Model(window).
+		Where("id = ?", id).
+		Where("active = ?", true).
+		Select()
+	return window, err
+}
+
+func (impl DeploymentWindowRepositoryImpl) FindAllActive() ([]*DeploymentWindow, error) {
+	var windows []*DeploymentWindow
+	err := impl.dbConnection.Model(&windows).
+		Where("active = ?", true).
+		Order("id ASC").
+		Select()
+	return windows, err
+}
+
+func (impl DeploymentWindowRepositoryImpl) FindActiveByEnvironmentId(environmentId int) ([]*DeploymentWindow, error) {
+	var windows []*DeploymentWindow
+	err := impl.dbConnection.Model(&windows).
+		Where("environment_id = ?", environmentId).
+		Where("active = ?", true).
+		Order("id ASC").
+		Select()
+	return windows, err
+}
+
+func (impl DeploymentWindowRepositoryImpl) SaveOverrideAudit(audit *DeploymentWindowOverrideAudit) error {
+	return impl.dbConnection.Insert(audit)
+}
+
+func (impl DeploymentWindowRepositoryImpl) FindOverrideAuditByEnvironmentId(environmentId int) ([]*DeploymentWindowOverrideAudit, error) {
+	var audits []*DeploymentWindowOverrideAudit
+	err := impl.dbConnection.Model(&audits).
+		Where("environment_id = ?", environmentId).
+		Order("id DESC").
+		Select()
+	return audits, err
+}
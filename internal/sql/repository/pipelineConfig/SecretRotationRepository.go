@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipelineConfig
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+)
+
+type SecretRotationStatus string
+
+const (
+	SECRET_ROTATION_RUNNING          SecretRotationStatus = "RUNNING"
+	SECRET_ROTATION_SUCCEEDED        SecretRotationStatus = "SUCCEEDED"
+	SECRET_ROTATION_PARTIALLY_FAILED SecretRotationStatus = "PARTIALLY_FAILED"
+	SECRET_ROTATION_FAILED           SecretRotationStatus = "FAILED"
+
+	// statuses of a single pipeline of a rotation job
+	SECRET_ROTATION_PENDING        SecretRotationStatus = "PENDING"
+	SECRET_ROTATION_SECRET_UPDATED SecretRotationStatus = "SECRET_UPDATED"
+	SECRET_ROTATION_TRIGGERED      SecretRotationStatus = "TRIGGERED"
+	SECRET_ROTATION_SKIPPED        SecretRotationStatus = "SKIPPED"
+)
+
+// SecretRotationJob is a single rotation of a secret key across a set of apps and environments
+type SecretRotationJob struct {
+	tableName  struct{}             `sql:"secret_rotation_job" pg:",discard_unknown_columns"`
+	Id         int                  `sql:"id,pk"`
+	SecretName string               `sql:"secret_name,notnull"`
+	SecretKey  string               `sql:"secret_key,notnull"`
+	Status     SecretRotationStatus `sql:"status,notnull"`
+	Message    string               `sql:"message"`
+	sql.AuditLog
+}
+
+// SecretRotationPipeline tracks the secret update and redeploy of one app and environment of a rotation job
+type SecretRotationPipeline struct {
+	tableName          struct{}             `sql:"secret_rotation_pipeline" pg:",discard_unknown_columns"`
+	Id                 int                  `sql:"id,pk"`
+	JobId              int                  `sql:"job_id,notnull"`
+	AppId              int                  `sql:"app_id,notnull"`
+	EnvId              int                  `sql:"env_id,notnull"`
+	PipelineId         int                  `sql:"pipeline_id"`
+	CiArtifactId       int                  `sql:"ci_artifact_id"`
+	PipelineOverrideId int                  `sql:"pipeline_override_id"`
+	ConfigLevel        string               `sql:"config_level"`
+	Status             SecretRotationStatus `sql:"status,notnull"`
+	Message            string               `sql:"message"`
+	sql.AuditLog
+}
+
+type SecretRotationRepository interface {
+	SaveJob(job *SecretRotationJob, tx *pg.Tx) error
+	UpdateJob(job *SecretRotationJob) error
+	FindJobById(id int) (*SecretRotationJob, error)
+
+	SavePipeline(pipeline *SecretRotationPipeline, tx *pg.Tx) error
+	UpdatePipeline(pipeline *SecretRotationPipeline) error
+	FindPipelinesByJobId(jobId int) ([]*SecretRotationPipeline, error)
+	GetConnection() *pg.DB
+}
+
+type SecretRotationRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewSecretRotationRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *SecretRotationRepositoryImpl {
+	return &SecretRotationRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl SecretRotationRepositoryImpl) GetConnection() *pg.DB {
+	return impl.dbConnection
+}
+
+func (impl SecretRotationRepositoryImpl) SaveJob(job *SecretRotationJob, tx *pg.Tx) error {
+	return tx.Insert(job)
+}
+
+func (impl SecretRotationRepositoryImpl) UpdateJob(job *SecretRotationJob) error {
+	return impl.dbConnection.Update(job)
+}
+
+func (impl SecretRotationRepositoryImpl) FindJobById(id int) (*SecretRotationJob, error) {
+	job := &SecretRotationJob{}
+	err := impl.dbConnection.Model(job).
+		Where("id = ?", id).
+		Select()
+	return job, err
+}
+
+func (impl SecretRotationRepositoryImpl) SavePipeline(pipeline *SecretRotationPipeline, tx *pg.Tx) error {
+	return tx.Insert(pipeline)
+}
+
+func (impl SecretRotationRepositoryImpl) UpdatePipeline(pipeline *SecretRotationPipeline) error {
+	return impl.dbConnection.Update(pipeline)
+}
+
+func (impl SecretRotationRepositoryImpl) FindPipelinesByJobId(jobId int) ([]*SecretRotationPipeline, error) {
+	var pipelines []*SecretRotationPipeline
+	err := impl.dbConnection.Model(&pipelines).
+		Where("job_id = ?", jobId).
+		Order("id ASC").
+		Select()
+	return pipelines, err
+}
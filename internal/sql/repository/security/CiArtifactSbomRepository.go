@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+)
+
+type CiArtifactSbom struct {
+	tableName      struct{} `sql:"ci_artifact_sbom" pg:",discard_unknown_columns"`
+	Id             int      `sql:"id,pk"`
+	CiArtifactId   int      `sql:"ci_artifact_id,notnull"`
+	ImageDigest    string   `sql:"image_digest,notnull"`
+	Format         string   `sql:"format,notnull"`
+	SpecVersion    string   `sql:"spec_version"`
+	Document       string   `sql:"document,notnull"`
+	ComponentCount int      `sql:"component_count,notnull"`
+	sql.AuditLog
+}
+
+type CiArtifactSbomComponent struct {
+	tableName struct{} `sql:"ci_artifact_sbom_component" pg:",discard_unknown_columns"`
+	Id        int      `sql:"id,pk"`
+	SbomId    int      `sql:"sbom_id,notnull"`
+	Name      string   `sql:"name,notnull"`
+	Version   string   `sql:"version"`
+	Purl      string   `sql:"purl"`
+	Type      string   `sql:"type"`
+}
+
+// SbomComponentDeployment is an sbom component found in an image currently deployed to an environment
+type SbomComponentDeployment struct {
+	AppId           int    `json:"appId"`
+	AppName         string `json:"appName"`
+	EnvId           int    `json:"envId"`
+	EnvironmentName string `json:"environmentName"`
+	CiArtifactId    int    `json:"ciArtifactId"`
+	Image           string `json:"image"`
+	ImageDigest     string `json:"imageDigest"`
+	PackageName     string `json:"packageName"`
+	PackageVersion  string `json:"packageVersion"`
+	Purl            string `json:"purl"`
+}
+
+type CiArtifactSbomRepository interface {
+	Save(sbom *CiArtifactSbom, components []*CiArtifactSbomComponent) error
+	FindLatestByImageDigest(imageDigest string) (*CiArtifactSbom, error)
+	FindDeployedByComponent(name string, version string) ([]*SbomComponentDeployment, error)
+}
+
+type CiArtifactSbomRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewCiArtifactSbomRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *CiArtifactSbomRepositoryImpl {
+	return &CiArtifactSbomRepositoryImpl{
+		dbConnection: dbConnection,
+		logger:       logger,
+	}
+}
+
+func (impl CiArtifactSbomRepositoryImpl) Save(sbom *CiArtifactSbom, components []*CiArtifactSbomComponent) error {
+	tx, err := impl.dbConnection.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err = tx.Insert(sbom); err != nil {
+		return err
+	}
+	if len(components) > 0 {
+		for _, component := range components {
+			component.SbomId = sbom.Id
+		}
+		if err = tx.Insert(&components); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (impl CiArtifactSbomRepositoryImpl) FindLatestByImageDigest(imageDigest string) (*CiArtifactSbom, error) {
+	sbom := &CiArtifactSbom{}
+	err := impl.dbConnection.Model(sbom).
+		Where("image_digest = ?", imageDigest).
+		Order("id desc").
+		Limit(1).
+		Select()
+	return sbom, err
+}
+
+// FindDeployedByComponent looks up the components in the artifact of the latest successful deployment of every cd pipeline,
+// a release which failed or was stopped before deploying is not running. The sbom of a linked ci artifact is the one of its
+// parent. The version is matched only when given
+func (impl CiArtifactSbomRepositoryImpl) FindDeployedByComponent(name string, version string) ([]*SbomComponentDeployment, error) {
+	var deployments []*SbomComponentDeployment
+	query := "SELECT DISTINCT p.app_id, a.app_name, p.environment_id AS env_id, e.environment_name," +
+		" ca.id AS ci_artifact_id, ca.image, ca.image_digest, c.name AS package_name, c.version AS package_version, c.purl" +
+		" FROM pipeline p" +
+		" INNER JOIN cd_workflow wf ON wf.id = (SELECT cw.id FROM cd_workflow cw" +
+		" INNER JOIN cd_workflow_runner wfr ON wfr.cd_workflow_id = cw.id" +
+		" WHERE cw.pipeline_id = p.id AND wfr.workflow_type = 'DEPLOY' AND wfr.status IN ('Succeeded', 'Healthy')" +
+		" ORDER BY wfr.id DESC LIMIT 1)" +
+		" INNER JOIN ci_artifact ca ON ca.id = wf.ci_artifact_id" +
+		" INNER JOIN ci_artifact_sbom s ON s.id = (SELECT max(id) FROM ci_artifact_sbom WHERE ci_artifact_id IN (ca.id, ca.parent_ci_artifact))" +
+		" INNER JOIN ci_artifact_sbom_component c ON c.sbom_id = s.id" +
+		" INNER JOIN app a ON a.id = p.app_id" +
+		" INNER JOIN environment e ON e.id = p.environment_id" +
+		" WHERE p.deleted = false AND lower(c.name) = lower(?)"
+	params := []interface{}{name}
+	if len(version) > 0 {
+		query += " AND c.version = ?"
+		params = append(params, version)
+	}
+	query += " ORDER BY a.app_name, e.environment_name"
+	_, err := impl.dbConnection.Query(&deployments, query, params...)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployed sbom components", "name", name, "version", version, "err", err)
+		return nil, err
+	}
+	return deployments, nil
+}
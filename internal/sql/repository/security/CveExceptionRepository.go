@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"time"
+)
+
+const (
+	CveExceptionPending  = "PENDING"
+	CveExceptionApproved = "APPROVED"
+	CveExceptionRejected = "REJECTED"
+	CveExceptionRevoked  = "REVOKED"
+)
+
+// CveException allows a cve for an env, or for one app in the env, until ExpiresOn.
+// Only approved and unexpired exceptions are applied over the cve policies.
+type CveException struct {
+	tableName      struct{}  `sql:"cve_exception" pg:",discard_unknown_columns"`
+	Id             int       `sql:"id,pk"`
+	CveStoreName   string    `sql:"cve_store_name,notnull"`
+	AppId          int       `sql:"app_id"`
+	EnvId          int       `sql:"env_id,notnull"`
+	Justification  string    `sql:"justification,notnull"`
+	Status         string    `sql:"status,notnull"`
+	ApprovedBy     int32     `sql:"approved_by"`
+	ApprovedOn     time.Time `sql:"approved_on"`
+	ExpiresOn      time.Time `sql:"expires_on,notnull"`
+	ExpiryNotified bool      `sql:"expiry_notified,notnull"`
+	sql.AuditLog
+}
+
+type CveExceptionFilter struct {
+	AppId          int
+	EnvId          int
+	CveStoreName   string
+	Status         string
+	IncludeExpired bool
+	Offset         int
+	Size           int
+}
+
+type CveExceptionRepository interface {
+	Save(exception *CveException) error
+	Update(exception *CveException) error
+	FindById(id int) (*CveException, error)
+	FindByFilter(filter *CveExceptionFilter) ([]*CveException, error)
+	FindActive(envId int, appId int) ([]*CveException, error)
+	// FindExpiringBefore returns approved exceptions expiring before the given time whose owners are not yet notified
+	FindExpiringBefore(before time.Time) ([]*CveException, error)
+}
+
+type CveExceptionRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewCveExceptionRepositoryImpl(dbConnection *pg.DB) *CveExceptionRepositoryImpl {
+	return &CveExceptionRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (impl *CveExceptionRepositoryImpl) Save(exception *CveException) error {
+	return impl.dbConnection.Insert(exception)
+}
+
+func (impl *CveExceptionRepositoryImpl) Update(exception *CveException) error {
+	return impl.dbConnection.Update(exception)
+}
+
+func (impl *CveExceptionRepositoryImpl) FindById(id int) (*CveException, error) {
+	exception := &CveException{}
+	err := impl.dbConnection.Model(exception).
+		Where("id = ?", id).
+		Select()
+	return exception, err
+}
+
+func (impl *CveExceptionRepositoryImpl) FindByFilter(filter *CveExceptionFilter) ([]*CveException, error) {
+	var exceptions []*CveException
+	query := impl.dbConnection.Model(&exceptions)
+	if filter.AppId > 0 {
+		query = query.Where("app_id = ?", filter.AppId)
+	}
+	if filter.EnvId > 0 {
+		query = query.Where("env_id = ?", filter.EnvId)
+	}
+	if len(filter.CveStoreName) > 0 {
+		query = query.Where("cve_store_name = ?", filter.CveStoreName)
+	}
+	if len(filter.Status) > 0 {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.IncludeExpired {
+		query = query.Where("expires_on > ?", time.Now())
+	}
+	if filter.Size > 0 {
+		query = query.Offset(filter.Offset).Limit(filter.Size)
+	}
+	err := query.Order("id DESC").Select()
+	return exceptions, err
+}
+
+func (impl *CveExceptionRepositoryImpl) FindExpiringBefore(before time.Time) ([]*CveException, error) {
+	var exceptions []*CveException
+	err := impl.dbConnection.Model(&exceptions).
+		Where("status = ?", CveExceptionApproved).
+		Where("expiry_notified = false").
+		Where("expires_on > ?", time.Now()).
+		Where("expires_on <= ?", before).
+		Select()
+	return exceptions, err
+}
+
+// FindActive returns the approved and unexpired exceptions, env level exceptions apply to every app of the env
+func (impl *CveExceptionRepositoryImpl) FindActive(envId int, appId int) ([]*CveException, error) {
+	var exceptions []*CveException
+	if envId == 0 {
+		return exceptions, nil
+	}
+	query := impl.dbConnection.Model(&exceptions).
+		Where("env_id = ?", envId).
+		Where("status = ?", CveExceptionApproved).
+		Where("expires_on > ?", time.Now())
+	if appId > 0 {
+		query = query.Where("(app_id is null or app_id = ?)", appId)
+	} else {
+		query = query.Where("app_id is null")
+	}
+	err := query.Select()
+	return exceptions, err
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+)
+
+// DeploymentPolicy enables a manifest admission rule at global, cluster, environment or app+env level.
+// Config carries rule specific parameters as json, e.g. the registry allow list.
+type DeploymentPolicy struct {
+	tableName     struct{}               `sql:"deployment_policy" pg:",discard_unknown_columns"`
+	Id            int                    `sql:"id,pk"`
+	Global        bool                   `sql:"global,notnull"`
+	ClusterId     int                    `sql:"cluster_id"`
+	EnvironmentId int                    `sql:"env_id"`
+	AppId         int                    `sql:"app_id"`
+	Rule          string                 `sql:"rule,notnull"`
+	Action        DeploymentPolicyAction `sql:"action,notnull"`
+	Config        string                 `sql:"config"`
+	Deleted       bool                   `sql:"deleted,notnull"`
+	sql.AuditLog
+}
+
+type DeploymentPolicyAction int
+
+const (
+	DeploymentPolicyInherit DeploymentPolicyAction = iota
+	DeploymentPolicyDisable
+	DeploymentPolicyWarn
+	DeploymentPolicyBlock
+)
+
+func (d DeploymentPolicyAction) String() string {
+	return [...]string{"inherit", "disable", "warn", "block"}[d]
+}
+
+func (d DeploymentPolicyAction) ValuesOf(action string) DeploymentPolicyAction {
+	if action == "disable" {
+		return DeploymentPolicyDisable
+	} else if action == "warn" {
+		return DeploymentPolicyWarn
+	} else if action == "block" {
+		return DeploymentPolicyBlock
+	}
+	return DeploymentPolicyInherit
+}
+
+func (policy *DeploymentPolicy) PolicyLevel() PolicyLevel {
+	if policy.AppId != 0 {
+		return Application
+	} else if policy.EnvironmentId != 0 {
+		return Environment
+	} else if policy.ClusterId != 0 {
+		return Cluster
+	} else {
+		return Global
+	}
+}
+
+// DeploymentPolicyViolation is recorded for every rule that failed while triggering a release
+type DeploymentPolicyViolation struct {
+	tableName          struct{}               `sql:"deployment_policy_violation" pg:",discard_unknown_columns"`
+	Id                 int                    `sql:"id,pk"`
+	PipelineId         int                    `sql:"pipeline_id,notnull"`
+	PipelineOverrideId int                    `sql:"pipeline_override_id"`
+	Rule               string                 `sql:"rule,notnull"`
+	Action             DeploymentPolicyAction `sql:"action,notnull"`
+	PolicyOrigin       string                 `sql:"policy_origin"`
+	Message            string                 `sql:"message"`
+	sql.AuditLog
+}
+
+type DeploymentPolicyRepository interface {
+	SavePolicy(policy *DeploymentPolicy) (*DeploymentPolicy, error)
+	UpdatePolicy(policy *DeploymentPolicy) (*DeploymentPolicy, error)
+	GetById(id int) (*DeploymentPolicy, error)
+	GetApplicablePolicies(clusterId, environmentId, appId int) (policies []*DeploymentPolicy, err error)
+	SaveViolations(violations []*DeploymentPolicyViolation) error
+	FindViolationsByPipelineId(pipelineId int, offset int, size int) ([]*DeploymentPolicyViolation, error)
+}
+
+type DeploymentPolicyRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewDeploymentPolicyRepositoryImpl(dbConnection *pg.DB) *DeploymentPolicyRepositoryImpl {
+	return &DeploymentPolicyRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (impl *DeploymentPolicyRepositoryImpl) SavePolicy(policy *DeploymentPolicy) (*DeploymentPolicy, error) {
+	err := impl.dbConnection.Insert(policy)
+	return policy, err
+}
+
+func (impl *DeploymentPolicyRepositoryImpl) UpdatePolicy(policy *DeploymentPolicy) (*DeploymentPolicy, error) {
+	_, err := impl.dbConnection.Model(policy).WherePK().UpdateNotNull()
+	return policy, err
+}
+
+func (impl *DeploymentPolicyRepositoryImpl) GetById(id int) (*DeploymentPolicy, error) {
+	policy := &DeploymentPolicy{Id: id}
+	err := impl.dbConnection.Model(policy).WherePK().Select()
+	return policy, err
+}
+
+// GetApplicablePolicies returns every non deleted policy on the chain global -> cluster -> env -> app+env.
+// Pass 0 for the levels which should not be considered.
+func (impl *DeploymentPolicyRepositoryImpl) GetApplicablePolicies(clusterId, environmentId, appId int) (policies []*DeploymentPolicy, err error) {
+	err = impl.dbConnection.Model(&policies).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("global = true")
+			if clusterId > 0 {
+				q = q.WhereOr("cluster_id = ? and env_id is null and app_id is null", clusterId)
+			}
+			if environmentId > 0 {
+				q = q.WhereOr("env_id = ? and app_id is null", environmentId)
+			}
+			if environmentId > 0 && appId > 0 {
+				q = q.WhereOr("env_id = ? and app_id = ?", environmentId, appId)
+			}
+			return q, nil
+		}).
+		Where("deleted = false").
+		Order("id ASC").
+		Select()
+	return policies, err
+}
+
+func (impl *DeploymentPolicyRepositoryImpl) SaveViolations(violations []*DeploymentPolicyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	err := impl.dbConnection.Insert(&violations)
+	return err
+}
+
+func (impl *DeploymentPolicyRepositoryImpl) FindViolationsByPipelineId(pipelineId int, offset int, size int) ([]*DeploymentPolicyViolation, error) {
+	var violations []*DeploymentPolicyViolation
+	err := impl.dbConnection.Model(&violations).
+		Where("pipeline_id = ?", pipelineId).
+		Order("id DESC").
+		Offset(offset).
+		Limit(size).
+		Select()
+	return violations, err
+}
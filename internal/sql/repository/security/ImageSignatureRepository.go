@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
+)
+
+// ImageSignature is the signature of image@digest of a ci artifact
+type ImageSignature struct {
+	tableName    struct{} `sql:"image_signature" pg:",discard_unknown_columns"`
+	Id           int      `sql:"id,pk"`
+	CiArtifactId int      `sql:"ci_artifact_id,notnull"`
+	KeyId        string   `sql:"key_id,notnull"`
+	Image        string   `sql:"image,notnull"`
+	ImageDigest  string   `sql:"image_digest,notnull"`
+	Signature    string   `sql:"signature,notnull"`
+	sql.AuditLog
+}
+
+// ImageSignaturePolicy decides at global, cluster, environment or app+env level what happens to images
+// which are unsigned or not signed by one of KeyIds, any active key is trusted when KeyIds is empty
+type ImageSignaturePolicy struct {
+	tableName     struct{}                   `sql:"image_signature_policy" pg:",discard_unknown_columns"`
+	Id            int                        `sql:"id,pk"`
+	Global        bool                       `sql:"global,notnull"`
+	ClusterId     int                        `sql:"cluster_id"`
+	EnvironmentId int                        `sql:"env_id"`
+	AppId         int                        `sql:"app_id"`
+	Action        ImageSignaturePolicyAction `sql:"action,notnull"`
+	KeyIds        []string                   `sql:"key_ids" pg:",array"`
+	Deleted       bool                       `sql:"deleted,notnull"`
+	sql.AuditLog
+}
+
+type ImageSignaturePolicyAction int
+
+const (
+	ImageSignatureInherit ImageSignaturePolicyAction = iota
+	ImageSignatureAllow
+	ImageSignatureWarn
+	ImageSignatureBlock
+)
+
+func (d ImageSignaturePolicyAction) String() string {
+	return [...]string{"inherit", "allow", "warn", "block"}[d]
+}
+
+func (d ImageSignaturePolicyAction) ValuesOf(action string) ImageSignaturePolicyAction {
+	if action == "allow" {
+		return ImageSignatureAllow
+	} else if action == "warn" {
+		return ImageSignatureWarn
+	} else if action == "block" {
+		return ImageSignatureBlock
+	}
+	return ImageSignatureInherit
+}
+
+func (policy *ImageSignaturePolicy) PolicyLevel() PolicyLevel {
+	if policy.AppId != 0 {
+		return Application
+	} else if policy.EnvironmentId != 0 {
+		return Environment
+	} else if policy.ClusterId != 0 {
+		return Cluster
+	} else {
+		return Global
+	}
+}
+
+type ImageSignatureRepository interface {
+	SaveSignatures(signatures []*ImageSignature) error
+	FindSignaturesByArtifactId(ciArtifactId int) ([]*ImageSignature, error)
+	SavePolicy(policy *ImageSignaturePolicy) (*ImageSignaturePolicy, error)
+	UpdatePolicy(policy *ImageSignaturePolicy) (*ImageSignaturePolicy, error)
+	GetApplicablePolicies(clusterId, environmentId, appId int) (policies []*ImageSignaturePolicy, err error)
+}
+
+type ImageSignatureRepositoryImpl struct {
+	dbConnection *pg.DB
+}
+
+func NewImageSignatureRepositoryImpl(dbConnection *pg.DB) *ImageSignatureRepositoryImpl {
+	return &ImageSignatureRepositoryImpl{dbConnection: dbConnection}
+}
+
+func (impl *ImageSignatureRepositoryImpl) SaveSignatures(signatures []*ImageSignature) error {
+	if len(signatures) == 0 {
+		return nil
+	}
+	return impl.dbConnection.Insert(&signatures)
+}
+
+func (impl *ImageSignatureRepositoryImpl) FindSignaturesByArtifactId(ciArtifactId int) ([]*ImageSignature, error) {
+	var signatures []*ImageSignature
+	err := impl.dbConnection.Model(&signatures).
+		Where("ci_artifact_id = ?", ciArtifactId).
+		Order("id DESC").
+		Select()
+	return signatures, err
+}
+
+func (impl *ImageSignatureRepositoryImpl) SavePolicy(policy *ImageSignaturePolicy) (*ImageSignaturePolicy, error) {
+	err := impl.dbConnection.Insert(policy)
+	return policy, err
+}
+
+func (impl *ImageSignatureRepositoryImpl) UpdatePolicy(policy *ImageSignaturePolicy) (*ImageSignaturePolicy, error) {
+	err := impl.dbConnection.Update(policy)
+	return policy, err
+}
+
+// GetApplicablePolicies returns every non deleted policy on the chain global -> cluster -> env -> app+env.
+// Pass 0 for the levels which should not be considered.
+func (impl *ImageSignatureRepositoryImpl) GetApplicablePolicies(clusterId, environmentId, appId int) (policies []*ImageSignaturePolicy, err error) {
+	err = impl.dbConnection.Model(&policies).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("global = true")
+			if clusterId > 0 {
+				q = q.WhereOr("cluster_id = ? and env_id is null and app_id is null", clusterId)
+			}
+			if environmentId > 0 {
+				q = q.WhereOr("env_id = ? and app_id is null", environmentId)
+			}
+			if environmentId > 0 && appId > 0 {
+				q = q.WhereOr("env_id = ? and app_id = ?", environmentId, appId)
+			}
+			return q, nil
+		}).
+		Where("deleted = false").
+		Order("id ASC").
+		Select()
+	return policies, err
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// DeployedArtifact is a successful deployment of an artifact on an app+env
+type DeployedArtifact struct {
+	AppId      int       `sql:"app_id"`
+	AppName    string    `sql:"app_name"`
+	TeamId     int       `sql:"team_id"`
+	TeamName   string    `sql:"team_name"`
+	EnvId      int       `sql:"env_id"`
+	EnvName    string    `sql:"env_name"`
+	ArtifactId int       `sql:"artifact_id"`
+	Image      string    `sql:"image"`
+	DeployedOn time.Time `sql:"deployed_on"`
+}
+
+// ImageCve is a cve found in a scan of an image, Name is empty for a scan which found no cves
+type ImageCve struct {
+	ScanId    int       `sql:"scan_id"`
+	ScannedOn time.Time `sql:"scanned_on"`
+	Image     string    `sql:"image"`
+	Name      string    `sql:"name"`
+	Severity  Severity  `sql:"severity"`
+	Package   string    `sql:"package"`
+}
+
+type VulnerabilityTrendFilter struct {
+	TeamIds []int
+	AppIds  []int
+	EnvIds  []int
+	To      time.Time
+}
+
+type VulnerabilityTrendRepository interface {
+	// FindDeployedArtifacts returns the deployments done till filter.To ordered by deployment time, deployments before
+	// the requested range are needed to know what was running at its start
+	FindDeployedArtifacts(filter *VulnerabilityTrendFilter) ([]*DeployedArtifact, error)
+	// FindCvesByImages returns the cves of every scan of the images ordered by scan time, rescans find cves published
+	// after the build so the scan in effect at a point in time is picked by the caller
+	FindCvesByImages(images []string) ([]*ImageCve, error)
+}
+
+type VulnerabilityTrendRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewVulnerabilityTrendRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *VulnerabilityTrendRepositoryImpl {
+	return &VulnerabilityTrendRepositoryImpl{
+		dbConnection: dbConnection,
+		logger:       logger,
+	}
+}
+
+func (impl VulnerabilityTrendRepositoryImpl) FindDeployedArtifacts(filter *VulnerabilityTrendFilter) ([]*DeployedArtifact, error) {
+	var models []*DeployedArtifact
+	var params []interface{}
+	var query strings.Builder
+	query.WriteString("SELECT p.app_id, a.app_name, a.team_id, t.name as team_name, p.environment_id as env_id, e.environment_name as env_name," +
+		" cia.id as artifact_id, cia.image, wfr.started_on as deployed_on" +
+		" FROM cd_workflow_runner wfr" +
+		" INNER JOIN cd_workflow wf ON wf.id = wfr.cd_workflow_id" +
+		" INNER JOIN pipeline p ON p.id = wf.pipeline_id" +
+		" INNER JOIN ci_artifact cia ON cia.id = wf.ci_artifact_id" +
+		" INNER JOIN app a ON a.id = p.app_id" +
+		" INNER JOIN team t ON t.id = a.team_id" +
+		" INNER JOIN environment e ON e.id = p.environment_id" +
+		" WHERE wfr.workflow_type = 'DEPLOY' AND wfr.status IN ('Succeeded', 'Healthy')" +
+		" AND p.deleted = false AND a.active = true AND e.active = true AND wfr.started_on <= ?")
+	params = append(params, filter.To)
+	if len(filter.TeamIds) > 0 {
+		query.WriteString(" AND a.team_id IN (?)")
+		params = append(params, pg.In(filter.TeamIds))
+	}
+	if len(filter.AppIds) > 0 {
+		query.WriteString(" AND p.app_id IN (?)")
+		params = append(params, pg.In(filter.AppIds))
+	}
+	if len(filter.EnvIds) > 0 {
+		query.WriteString(" AND p.environment_id IN (?)")
+		params = append(params, pg.In(filter.EnvIds))
+	}
+	query.WriteString(" ORDER BY wfr.started_on, wfr.id")
+	_, err := impl.dbConnection.Query(&models, query.String(), params...)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployed artifacts", "filter", filter, "err", err)
+		return nil, err
+	}
+	return models, nil
+}
+
+func (impl VulnerabilityTrendRepositoryImpl) FindCvesByImages(images []string) ([]*ImageCve, error) {
+	var models []*ImageCve
+	if len(images) == 0 {
+		return models, nil
+	}
+	query := "SELECT his.id as scan_id, his.execution_time as scanned_on, his.image, cs.name, cs.severity, cs.package" +
+		" FROM image_scan_execution_history his" +
+		" LEFT JOIN image_scan_execution_result res ON res.image_scan_execution_history_id = his.id" +
+		" LEFT JOIN cve_store cs ON cs.name = res.cve_store_name" +
+		" WHERE his.image IN (?)" +
+		" ORDER BY his.execution_time, his.id"
+	_, err := impl.dbConnection.Query(&models, query, pg.In(images))
+	if err != nil {
+		impl.logger.Errorw("error in fetching cves of images", "err", err)
+		return nil, err
+	}
+	return models, nil
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package apiToken
+
+import (
+	"fmt"
+	"github.com/devtron-labs/authenticator/middleware"
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	"github.com/devtron-labs/devtron/pkg/user/repository"
+	"github.com/go-pg/pg"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+var tokenNameRegex = regexp.MustCompile("^[a-z0-9]([a-z0-9_-]*[a-z0-9])?$")
+
+type ApiTokenRequest struct {
+	Name        string            `json:"name" validate:"required,max=50"`
+	Description string            `json:"description"`
+	ExpireAt    *time.Time        `json:"expireAt"` // nil creates a token which never expires
+	RoleFilters []bean.RoleFilter `json:"roleFilters" validate:"required,min=1"`
+	UserId      int32             `json:"-"`
+}
+
+type ApiTokenUpdateRequest struct {
+	Description string            `json:"description"`
+	RoleFilters []bean.RoleFilter `json:"roleFilters" validate:"required,min=1"`
+	UserId      int32             `json:"-"`
+}
+
+type ApiTokenDto struct {
+	Id             int               `json:"id"`
+	Name           string            `json:"name"`
+	Description    string            `json:"description"`
+	UserId         int32             `json:"userId"`
+	UserIdentifier string            `json:"userIdentifier"`
+	ExpireAt       *time.Time        `json:"expireAt,omitempty"`
+	LastUsedAt     *time.Time        `json:"lastUsedAt,omitempty"`
+	RoleFilters    []bean.RoleFilter `json:"roleFilters,omitempty"`
+	CreatedOn      time.Time         `json:"createdOn"`
+	// Token is only returned once, at creation
+	Token string `json:"token,omitempty"`
+}
+
+type ApiTokenService interface {
+	CreateApiToken(request *ApiTokenRequest) (*ApiTokenDto, error)
+	GetAllActiveApiTokens() ([]*ApiTokenDto, error)
+	GetApiTokenById(id int) (*ApiTokenDto, error)
+	UpdateApiToken(id int, request *ApiTokenUpdateRequest) (*ApiTokenDto, error)
+	RevokeApiToken(id int, userId int32) error
+}
+
+type ApiTokenServiceImpl struct {
+	logger             *zap.SugaredLogger
+	apiTokenRepository repository.ApiTokenRepository
+	userService        user.UserService
+	sessionManager     *middleware.SessionManager
+}
+
+func NewApiTokenServiceImpl(logger *zap.SugaredLogger, apiTokenRepository repository.ApiTokenRepository,
+	userService user.UserService, sessionManager *middleware.SessionManager) *ApiTokenServiceImpl {
+	return &ApiTokenServiceImpl{
+		logger:             logger,
+		apiTokenRepository: apiTokenRepository,
+		userService:        userService,
+		sessionManager:     sessionManager,
+	}
+}
+
+// CreateApiToken creates the service user holding the token permissions and signs a token for it.
+// only the hash of the token is stored, the token is not retrievable later
+func (impl ApiTokenServiceImpl) CreateApiToken(request *ApiTokenRequest) (*ApiTokenDto, error) {
+	if !tokenNameRegex.MatchString(request.Name) {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "token name may contain lowercase alphanumeric characters, '-' and '_' only"}
+	}
+	now := time.Now()
+	if request.ExpireAt != nil && !request.ExpireAt.After(now) {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "token expiry must be in future"}
+	}
+	existing, err := impl.apiTokenRepository.FindActiveByName(request.Name)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching api token", "name", request.Name, "err", err)
+		return nil, err
+	} else if err == nil && existing.Id > 0 {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusConflict, UserMessage: fmt.Sprintf("api token %s already exists", request.Name)}
+	}
+
+	emailId := casbin.ApiTokenUserEmailPrefix + request.Name
+	users, err := impl.userService.CreateUser(&bean.UserInfo{
+		EmailId:     emailId,
+		RoleFilters: request.RoleFilters,
+		Groups:      []string{},
+		UserId:      request.UserId,
+		UserType:    repository.UserTypeApiToken,
+	})
+	if err != nil {
+		impl.logger.Errorw("error in creating api token user", "emailId", emailId, "err", err)
+		return nil, err
+	} else if len(users) == 0 {
+		return nil, fmt.Errorf("api token user not created for %s", emailId)
+	}
+	serviceUser := users[0]
+
+	var secondsBeforeExpiry int64
+	if request.ExpireAt != nil {
+		secondsBeforeExpiry = int64(request.ExpireAt.Sub(now).Seconds())
+	}
+	token, err := impl.sessionManager.Create(emailId, secondsBeforeExpiry, uuid.NewV4().String())
+	if err != nil {
+		impl.logger.Errorw("error in signing api token", "name", request.Name, "err", err)
+		return nil, err
+	}
+	apiToken := &repository.ApiToken{
+		UserId:      serviceUser.Id,
+		Name:        request.Name,
+		Description: request.Description,
+		TokenHash:   repository.HashApiToken(token),
+	}
+	if request.ExpireAt != nil {
+		apiToken.ExpireAt = *request.ExpireAt
+	}
+	apiToken.CreatedBy = request.UserId
+	apiToken.UpdatedBy = request.UserId
+	apiToken.CreatedOn = now
+	apiToken.UpdatedOn = now
+	err = impl.apiTokenRepository.Save(apiToken)
+	if err != nil {
+		impl.logger.Errorw("error in saving api token", "name", request.Name, "err", err)
+		return nil, err
+	}
+	apiToken.User = &repository.UserModel{Id: serviceUser.Id, EmailId: emailId}
+	dto := adaptApiToken(apiToken)
+	dto.RoleFilters = serviceUser.RoleFilters
+	dto.Token = token
+	return dto, nil
+}
+
+func (impl ApiTokenServiceImpl) GetAllActiveApiTokens() ([]*ApiTokenDto, error) {
+	apiTokens, err := impl.apiTokenRepository.FindAllActive()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching api tokens", "err", err)
+		return nil, err
+	}
+	dtos := make([]*ApiTokenDto, 0)
+	for _, apiToken := range apiTokens {
+		dtos = append(dtos, adaptApiToken(apiToken))
+	}
+	return dtos, nil
+}
+
+func (impl ApiTokenServiceImpl) GetApiTokenById(id int) (*ApiTokenDto, error) {
+	apiToken, err := impl.findActiveApiToken(id)
+	if err != nil {
+		return nil, err
+	}
+	dto := adaptApiToken(apiToken)
+	serviceUser, err := impl.userService.GetById(apiToken.UserId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching api token user", "userId", apiToken.UserId, "err", err)
+		return nil, err
+	}
+	dto.RoleFilters = serviceUser.RoleFilters
+	return dto, nil
+}
+
+// UpdateApiToken changes the description and the permissions, the token itself stays valid
+func (impl ApiTokenServiceImpl) UpdateApiToken(id int, request *ApiTokenUpdateRequest) (*ApiTokenDto, error) {
+	apiToken, err := impl.findActiveApiToken(id)
+	if err != nil {
+		return nil, err
+	}
+	serviceUser, err := impl.userService.UpdateUser(&bean.UserInfo{
+		Id:          apiToken.UserId,
+		EmailId:     apiToken.User.EmailId,
+		RoleFilters: request.RoleFilters,
+		Groups:      []string{},
+		UserId:      request.UserId,
+		UserType:    repository.UserTypeApiToken,
+	})
+	if err != nil {
+		impl.logger.Errorw("error in updating api token user", "userId", apiToken.UserId, "err", err)
+		return nil, err
+	}
+	apiToken.Description = request.Description
+	apiToken.UpdatedBy = request.UserId
+	apiToken.UpdatedOn = time.Now()
+	err = impl.apiTokenRepository.Update(apiToken)
+	if err != nil {
+		impl.logger.Errorw("error in updating api token", "id", id, "err", err)
+		return nil, err
+	}
+	dto := adaptApiToken(apiToken)
+	dto.RoleFilters = serviceUser.RoleFilters
+	return dto, nil
+}
+
+// RevokeApiToken marks the token revoked and deactivates its service user so that casbin drops its roles as well
+func (impl ApiTokenServiceImpl) RevokeApiToken(id int, userId int32) error {
+	apiToken, err := impl.findActiveApiToken(id)
+	if err != nil {
+		return err
+	}
+	apiToken.Revoked = true
+	apiToken.UpdatedBy = userId
+	apiToken.UpdatedOn = time.Now()
+	err = impl.apiTokenRepository.Update(apiToken)
+	if err != nil {
+		impl.logger.Errorw("error in revoking api token", "id", id, "err", err)
+		return err
+	}
+	_, err = impl.userService.DeleteUser(&bean.UserInfo{Id: apiToken.UserId, UserId: userId})
+	if err != nil {
+		impl.logger.Errorw("error in deleting api token user", "userId", apiToken.UserId, "err", err)
+		return err
+	}
+	return nil
+}
+
+func (impl ApiTokenServiceImpl) findActiveApiToken(id int) (*repository.ApiToken, error) {
+	apiToken, err := impl.apiTokenRepository.FindActiveById(id)
+	if err == pg.ErrNoRows {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusNotFound, UserMessage: fmt.Sprintf("api token %d not found", id)}
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching api token", "id", id, "err", err)
+		return nil, err
+	}
+	return apiToken, nil
+}
+
+func adaptApiToken(apiToken *repository.ApiToken) *ApiTokenDto {
+	dto := &ApiTokenDto{
+		Id:          apiToken.Id,
+		Name:        apiToken.Name,
+		Description: apiToken.Description,
+		UserId:      apiToken.UserId,
+		CreatedOn:   apiToken.CreatedOn,
+	}
+	if apiToken.User != nil {
+		dto.UserIdentifier = apiToken.User.EmailId
+	}
+	if !apiToken.ExpireAt.IsZero() {
+		expireAt := apiToken.ExpireAt
+		dto.ExpireAt = &expireAt
+	}
+	if !apiToken.LastUsedAt.IsZero() {
+		lastUsedAt := apiToken.LastUsedAt
+		dto.LastUsedAt = &lastUsedAt
+	}
+	return dto
+}
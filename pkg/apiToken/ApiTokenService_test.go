@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package apiToken
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/user"
+	"github.com/devtron-labs/devtron/pkg/user/repository"
+	"github.com/go-pg/pg"
+)
+
+// apiTokenRepositoryStub keeps active tokens by id
+type apiTokenRepositoryStub struct {
+	repository.ApiTokenRepository
+	tokens map[int]*repository.ApiToken
+}
+
+func (impl apiTokenRepositoryStub) FindActiveByName(name string) (*repository.ApiToken, error) {
+	for _, apiToken := range impl.tokens {
+		if apiToken.Name == name && !apiToken.Revoked {
+			return apiToken, nil
+		}
+	}
+	return &repository.ApiToken{}, pg.ErrNoRows
+}
+
+func (impl apiTokenRepositoryStub) FindActiveById(id int) (*repository.ApiToken, error) {
+	apiToken, ok := impl.tokens[id]
+	if !ok || apiToken.Revoked {
+		return nil, pg.ErrNoRows
+	}
+	return apiToken, nil
+}
+
+func (impl apiTokenRepositoryStub) Update(apiToken *repository.ApiToken) error {
+	impl.tokens[apiToken.Id] = apiToken
+	return nil
+}
+
+type tokenUserServiceStub struct {
+	user.UserService
+	deleted map[int32]bool
+}
+
+func (impl tokenUserServiceStub) DeleteUser(userInfo *bean.UserInfo) (bool, error) {
+	impl.deleted[userInfo.Id] = true
+	return true, nil
+}
+
+func TestCreateApiTokenValidation(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name       string
+		request    *ApiTokenRequest
+		wantStatus int
+	}{
+		{name: "name with upper case", request: &ApiTokenRequest{Name: "CI-token"}, wantStatus: http.StatusBadRequest},
+		{name: "name ending with separator", request: &ApiTokenRequest{Name: "ci-"}, wantStatus: http.StatusBadRequest},
+		{name: "expiry in past", request: &ApiTokenRequest{Name: "ci", ExpireAt: &past}, wantStatus: http.StatusBadRequest},
+		{name: "name of an active token", request: &ApiTokenRequest{Name: "deployer"}, wantStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl := ApiTokenServiceImpl{
+				logger:             util.NewSugardLogger(),
+				apiTokenRepository: apiTokenRepositoryStub{tokens: map[int]*repository.ApiToken{1: {Id: 1, Name: "deployer"}}},
+			}
+			_, err := impl.CreateApiToken(tt.request)
+			apiErr, ok := err.(*util.ApiError)
+			if !ok || apiErr.HttpStatusCode != tt.wantStatus {
+				t.Errorf("CreateApiToken() error = %v, want status %d", err, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRevokeApiToken(t *testing.T) {
+	tests := []struct {
+		name        string
+		id          int
+		wantErr     bool
+		wantRevoked bool
+	}{
+		{name: "active token is revoked with its user", id: 1, wantRevoked: true},
+		{name: "revoked token can not be revoked again", id: 2, wantErr: true},
+		{name: "unknown token", id: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := map[int]*repository.ApiToken{
+				1: {Id: 1, Name: "ci", UserId: 11},
+				2: {Id: 2, Name: "deployer", UserId: 12, Revoked: true},
+			}
+			userService := tokenUserServiceStub{deleted: map[int32]bool{}}
+			impl := ApiTokenServiceImpl{
+				logger:             util.NewSugardLogger(),
+				apiTokenRepository: apiTokenRepositoryStub{tokens: tokens},
+				userService:        userService,
+			}
+			err := impl.RevokeApiToken(tt.id, 2)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RevokeApiToken() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantRevoked {
+				return
+			}
+			if !tokens[tt.id].Revoked || tokens[tt.id].UpdatedBy != 2 {
+				t.Errorf("RevokeApiToken() token = %+v, want revoked by 2", tokens[tt.id])
+			}
+			if !userService.deleted[tokens[tt.id].UserId] {
+				t.Errorf("RevokeApiToken() token user %d not deleted", tokens[tt.id].UserId)
+			}
+		})
+	}
+}
@@ -24,9 +24,14 @@ import (
 	"github.com/devtron-labs/devtron/internal/sql/repository/app"
 	chartRepoRepository "github.com/devtron-labs/devtron/pkg/chartRepo/repository"
 	repository2 "github.com/devtron-labs/devtron/pkg/cluster/repository"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
+	"github.com/devtron-labs/devtron/pkg/deploymentPolicy"
+	"github.com/devtron-labs/devtron/pkg/imageSigning"
 	"github.com/devtron-labs/devtron/pkg/sql"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
 	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/devtron-labs/devtron/pkg/vault"
+	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
@@ -61,38 +66,44 @@ import (
 )
 
 type AppServiceImpl struct {
-	environmentConfigRepository   chartConfig.EnvConfigOverrideRepository
-	pipelineOverrideRepository    chartConfig.PipelineOverrideRepository
-	mergeUtil                     *MergeUtil
-	logger                        *zap.SugaredLogger
-	ciArtifactRepository          repository.CiArtifactRepository
-	pipelineRepository            pipelineConfig.PipelineRepository
-	gitFactory                    *GitFactory
-	dbMigrationConfigRepository   pipelineConfig.DbMigrationConfigRepository
-	eventClient                   client.EventClient
-	eventFactory                  client.EventFactory
-	acdClient                     application.ServiceClient
-	tokenCache                    *util3.TokenCache
-	acdAuthConfig                 *util3.ACDAuthConfig
-	enforcer                      casbin.Enforcer
-	enforcerUtil                  rbac.EnforcerUtil
-	user                          user.UserService
-	appListingRepository          repository.AppListingRepository
-	appRepository                 app.AppRepository
-	envRepository                 repository2.EnvironmentRepository
-	pipelineConfigRepository      chartConfig.PipelineConfigRepository
-	configMapRepository           chartConfig.ConfigMapRepository
-	chartRepository               chartRepoRepository.ChartRepository
-	appRepo                       app.AppRepository
-	appLevelMetricsRepository     repository.AppLevelMetricsRepository
-	envLevelMetricsRepository     repository.EnvLevelAppMetricsRepository
-	ciPipelineMaterialRepository  pipelineConfig.CiPipelineMaterialRepository
-	cdWorkflowRepository          pipelineConfig.CdWorkflowRepository
-	commonService                 commonService.CommonService
-	imageScanDeployInfoRepository security.ImageScanDeployInfoRepository
-	imageScanHistoryRepository    security.ImageScanHistoryRepository
-	ArgoK8sClient                 argocdServer.ArgoK8sClient
-	gitOpsRepository              repository.GitOpsConfigRepository
+	environmentConfigRepository      chartConfig.EnvConfigOverrideRepository
+	pipelineOverrideRepository       chartConfig.PipelineOverrideRepository
+	mergeUtil                        *MergeUtil
+	logger                           *zap.SugaredLogger
+	ciArtifactRepository             repository.CiArtifactRepository
+	pipelineRepository               pipelineConfig.PipelineRepository
+	gitFactory                       *GitFactory
+	dbMigrationConfigRepository      pipelineConfig.DbMigrationConfigRepository
+	eventClient                      client.EventClient
+	eventFactory                     client.EventFactory
+	acdClient                        application.ServiceClient
+	tokenCache                       *util3.TokenCache
+	acdAuthConfig                    *util3.ACDAuthConfig
+	enforcer                         casbin.Enforcer
+	enforcerUtil                     rbac.EnforcerUtil
+	user                             user.UserService
+	appListingRepository             repository.AppListingRepository
+	appRepository                    app.AppRepository
+	envRepository                    repository2.EnvironmentRepository
+	pipelineConfigRepository         chartConfig.PipelineConfigRepository
+	configMapRepository              chartConfig.ConfigMapRepository
+	chartRepository                  chartRepoRepository.ChartRepository
+	appRepo                          app.AppRepository
+	appLevelMetricsRepository        repository.AppLevelMetricsRepository
+	envLevelMetricsRepository        repository.EnvLevelAppMetricsRepository
+	ciPipelineMaterialRepository     pipelineConfig.CiPipelineMaterialRepository
+	cdWorkflowRepository             pipelineConfig.CdWorkflowRepository
+	commonService                    commonService.CommonService
+	imageScanDeployInfoRepository    security.ImageScanDeployInfoRepository
+	imageScanHistoryRepository       security.ImageScanHistoryRepository
+	ArgoK8sClient                    argocdServer.ArgoK8sClient
+	gitOpsRepository                 repository.GitOpsConfigRepository
+	deploymentAutoRollbackRepository pipelineConfig.DeploymentAutoRollbackRepository
+	deploymentPolicyService          deploymentPolicy.DeploymentPolicyService
+	chartTemplateRenderer            ChartTemplateRenderer
+	configHistoryService             configHistory.ConfigHistoryService
+	vaultService                     vault.VaultService
+	imageSigningService              imageSigning.ImageSigningService
 }
 
 type AppService interface {
@@ -105,6 +116,15 @@ type AppService interface {
 	GetCmSecretNew(appId int, envId int) (*bean.ConfigMapJson, *bean.ConfigSecretJson, error)
 	MarkImageScanDeployed(appId int, envId int, imageDigest string, clusterId int) error
 	GetChartRepoName(gitRepoUrl string) string
+	GetDeploymentValues(overrideRequest *bean.ValuesOverrideRequest, ctx context.Context) (*DeploymentValues, error)
+}
+
+// DeploymentValues are the final values of a release along with the configs they were rendered from
+type DeploymentValues struct {
+	Pipeline       *pipelineConfig.Pipeline
+	EnvOverride    *chartConfig.EnvConfigOverride
+	MergedValues   []byte
+	ReleaseCounter int
 }
 
 func NewAppService(
@@ -129,39 +149,49 @@ func NewAppService(
 	cdWorkflowRepository pipelineConfig.CdWorkflowRepository, commonService commonService.CommonService,
 	imageScanDeployInfoRepository security.ImageScanDeployInfoRepository, imageScanHistoryRepository security.ImageScanHistoryRepository,
 	ArgoK8sClient argocdServer.ArgoK8sClient,
-	gitFactory *GitFactory, gitOpsRepository repository.GitOpsConfigRepository) *AppServiceImpl {
+	gitFactory *GitFactory, gitOpsRepository repository.GitOpsConfigRepository,
+	deploymentAutoRollbackRepository pipelineConfig.DeploymentAutoRollbackRepository,
+	deploymentPolicyService deploymentPolicy.DeploymentPolicyService,
+	configHistoryService configHistory.ConfigHistoryService, vaultService vault.VaultService,
+	chartTemplateRenderer ChartTemplateRenderer, imageSigningService imageSigning.ImageSigningService) *AppServiceImpl {
 	appServiceImpl := &AppServiceImpl{
-		environmentConfigRepository:   environmentConfigRepository,
-		mergeUtil:                     mergeUtil,
-		pipelineOverrideRepository:    pipelineOverrideRepository,
-		logger:                        logger,
-		ciArtifactRepository:          ciArtifactRepository,
-		pipelineRepository:            pipelineRepository,
-		dbMigrationConfigRepository:   dbMigrationConfigRepository,
-		eventClient:                   eventClient,
-		eventFactory:                  eventFactory,
-		acdClient:                     acdClient,
-		tokenCache:                    cache,
-		acdAuthConfig:                 authConfig,
-		enforcer:                      enforcer,
-		enforcerUtil:                  enforcerUtil,
-		user:                          user,
-		appListingRepository:          appListingRepository,
-		appRepository:                 appRepository,
-		envRepository:                 envRepository,
-		pipelineConfigRepository:      pipelineConfigRepository,
-		configMapRepository:           configMapRepository,
-		chartRepository:               chartRepository,
-		appLevelMetricsRepository:     appLevelMetricsRepository,
-		envLevelMetricsRepository:     envLevelMetricsRepository,
-		ciPipelineMaterialRepository:  ciPipelineMaterialRepository,
-		cdWorkflowRepository:          cdWorkflowRepository,
-		commonService:                 commonService,
-		imageScanDeployInfoRepository: imageScanDeployInfoRepository,
-		imageScanHistoryRepository:    imageScanHistoryRepository,
-		ArgoK8sClient:                 ArgoK8sClient,
-		gitFactory:                    gitFactory,
-		gitOpsRepository:              gitOpsRepository,
+		environmentConfigRepository:      environmentConfigRepository,
+		mergeUtil:                        mergeUtil,
+		pipelineOverrideRepository:       pipelineOverrideRepository,
+		logger:                           logger,
+		ciArtifactRepository:             ciArtifactRepository,
+		pipelineRepository:               pipelineRepository,
+		dbMigrationConfigRepository:      dbMigrationConfigRepository,
+		eventClient:                      eventClient,
+		eventFactory:                     eventFactory,
+		acdClient:                        acdClient,
+		tokenCache:                       cache,
+		acdAuthConfig:                    authConfig,
+		enforcer:                         enforcer,
+		enforcerUtil:                     enforcerUtil,
+		user:                             user,
+		appListingRepository:             appListingRepository,
+		appRepository:                    appRepository,
+		envRepository:                    envRepository,
+		pipelineConfigRepository:         pipelineConfigRepository,
+		configMapRepository:              configMapRepository,
+		chartRepository:                  chartRepository,
+		appLevelMetricsRepository:        appLevelMetricsRepository,
+		envLevelMetricsRepository:        envLevelMetricsRepository,
+		ciPipelineMaterialRepository:     ciPipelineMaterialRepository,
+		cdWorkflowRepository:             cdWorkflowRepository,
+		commonService:                    commonService,
+		imageScanDeployInfoRepository:    imageScanDeployInfoRepository,
+		imageScanHistoryRepository:       imageScanHistoryRepository,
+		ArgoK8sClient:                    ArgoK8sClient,
+		gitFactory:                       gitFactory,
+		gitOpsRepository:                 gitOpsRepository,
+		deploymentAutoRollbackRepository: deploymentAutoRollbackRepository,
+		deploymentPolicyService:          deploymentPolicyService,
+		chartTemplateRenderer:            chartTemplateRenderer,
+		configHistoryService:             configHistoryService,
+		vaultService:                     vaultService,
+		imageSigningService:              imageSigningService,
 	}
 	return appServiceImpl
 }
@@ -270,6 +300,7 @@ func (impl AppServiceImpl) UpdateApplicationStatusAndCheckIsHealthy(app v1alpha1
 				isHealthy = true
 				go impl.WriteCDSuccessEvent(newDeploymentStatus.AppId, appName, newDeploymentStatus.EnvId, evnName, pipelineOverride)
 			}
+			impl.trackReleaseHealthForAutoRollback(pipelineOverride, newDeploymentStatus.Status)
 		} else {
 			impl.logger.Debug("event received for older triggered revision: " + gitHash)
 		}
@@ -277,6 +308,53 @@ func (impl AppServiceImpl) UpdateApplicationStatusAndCheckIsHealthy(app v1alpha1
 	return isHealthy, nil
 }
 
+// trackReleaseHealthForAutoRollback records since when the latest release of a pipeline with auto rollback is Degraded,
+// the rollback itself is triggered by DeploymentAutoRollbackService once the release stays Degraded beyond the configured timeout
+func (impl AppServiceImpl) trackReleaseHealthForAutoRollback(pipelineOverride *chartConfig.PipelineOverride, healthStatus string) {
+	if pipelineOverride.DeploymentType == models.DEPLOYMENTTYPE_ROLLBACK {
+		//not rolling back a rollback
+		return
+	}
+	_, err := impl.deploymentAutoRollbackRepository.FindConfigByPipelineId(pipelineOverride.PipelineId)
+	if err != nil {
+		if !IsErrNoRows(err) {
+			impl.logger.Errorw("error in fetching auto rollback config", "pipelineId", pipelineOverride.PipelineId, "err", err)
+		}
+		return
+	}
+	autoRollback, err := impl.deploymentAutoRollbackRepository.FindByPipelineOverrideId(pipelineOverride.Id)
+	if err != nil && !IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching auto rollback", "pipelineOverrideId", pipelineOverride.Id, "err", err)
+		return
+	}
+	if healthStatus == application.Degraded {
+		if autoRollback.Id == 0 {
+			autoRollback = &pipelineConfig.DeploymentAutoRollback{
+				PipelineId:         pipelineOverride.PipelineId,
+				PipelineOverrideId: pipelineOverride.Id,
+				CiArtifactId:       pipelineOverride.CiArtifactId,
+				Status:             pipelineConfig.AUTO_ROLLBACK_DEGRADED,
+				DegradedSince:      time.Now(),
+				AuditLog:           sql.AuditLog{CreatedOn: time.Now(), CreatedBy: 1, UpdatedOn: time.Now(), UpdatedBy: 1},
+			}
+			err = impl.deploymentAutoRollbackRepository.Save(autoRollback)
+		} else if autoRollback.Status == pipelineConfig.AUTO_ROLLBACK_RECOVERED {
+			autoRollback.Status = pipelineConfig.AUTO_ROLLBACK_DEGRADED
+			autoRollback.DegradedSince = time.Now()
+			autoRollback.UpdatedOn = time.Now()
+			err = impl.deploymentAutoRollbackRepository.Update(autoRollback)
+		}
+	} else if autoRollback.Id > 0 && autoRollback.Status == pipelineConfig.AUTO_ROLLBACK_DEGRADED {
+		autoRollback.Status = pipelineConfig.AUTO_ROLLBACK_RECOVERED
+		autoRollback.Message = "release turned " + healthStatus
+		autoRollback.UpdatedOn = time.Now()
+		err = impl.deploymentAutoRollbackRepository.Update(autoRollback)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in tracking release health for auto rollback", "pipelineOverrideId", pipelineOverride.Id, "err", err)
+	}
+}
+
 func (impl *AppServiceImpl) WriteCDSuccessEvent(appId int, appName string, envId int, envName string, override *chartConfig.PipelineOverride) {
 	event := impl.eventFactory.Build(util.Success, &override.PipelineId, appId, &envId, util.CD)
 	impl.logger.Debugw("event WriteCDSuccessEvent", "event", event)
@@ -452,65 +530,11 @@ func (impl AppServiceImpl) TriggerRelease(overrideRequest *bean.ValuesOverrideRe
 		impl.logger.Errorf("invalid req", "err", err, "req", overrideRequest)
 		return 0, err
 	}
-	envOverride, err := impl.environmentConfigRepository.ActiveEnvConfigOverride(overrideRequest.AppId, pipeline.EnvironmentId)
+	envOverride, err := impl.getEnvOverrideForTrigger(overrideRequest, pipeline, false)
 	if err != nil {
-		impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
 		return 0, err
 	}
 
-	if envOverride.Id == 0 {
-		chart, err := impl.chartRepository.FindLatestChartForAppByAppId(overrideRequest.AppId)
-		if err != nil {
-			impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
-			return 0, err
-		}
-		envOverride, err = impl.environmentConfigRepository.FindChartByAppIdAndEnvIdAndChartRefId(overrideRequest.AppId, pipeline.EnvironmentId, chart.ChartRefId)
-		if err != nil && !errors2.IsNotFound(err) {
-			impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
-			return 0, err
-		}
-
-		//creating new env override config
-		if errors2.IsNotFound(err) || envOverride == nil {
-			environment, err := impl.envRepository.FindById(pipeline.EnvironmentId)
-			if err != nil && !IsErrNoRows(err) {
-				return 0, err
-			}
-			envOverride = &chartConfig.EnvConfigOverride{
-				Active:            true,
-				ManualReviewed:    true,
-				Status:            models.CHARTSTATUS_SUCCESS,
-				TargetEnvironment: pipeline.EnvironmentId,
-				ChartId:           chart.Id,
-				AuditLog:          sql.AuditLog{UpdatedBy: overrideRequest.UserId, UpdatedOn: time.Now(), CreatedOn: time.Now(), CreatedBy: overrideRequest.UserId},
-				Namespace:         environment.Namespace,
-				IsOverride:        false,
-				EnvOverrideValues: "{}",
-				Latest:            false,
-			}
-			err = impl.environmentConfigRepository.Save(envOverride)
-			if err != nil {
-				impl.logger.Errorw("error in creating envconfig", "data", envOverride, "error", err)
-				return 0, err
-			}
-		}
-
-		env, err := impl.envRepository.FindById(envOverride.TargetEnvironment)
-		if err != nil {
-			impl.logger.Errorw("unable to find env", "err", err)
-			return 0, err
-		}
-		envOverride.Environment = env
-		envOverride.Chart = chart
-	} else if envOverride.Id > 0 && !envOverride.IsOverride {
-		chart, err := impl.chartRepository.FindLatestChartForAppByAppId(overrideRequest.AppId)
-		if err != nil {
-			impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
-			return 0, err
-		}
-		envOverride.Chart = chart
-	}
-
 	artifact, err := impl.ciArtifactRepository.Get(overrideRequest.CiArtifactId)
 	if err != nil {
 		return 0, err
@@ -529,31 +553,8 @@ func (impl AppServiceImpl) TriggerRelease(overrideRequest *bean.ValuesOverrideRe
 		return 0, err
 	}
 
-	//fetch pipeline config from strategy table, if pipeline is automatic fetch always default, else depends on request
-	var strategy *chartConfig.PipelineStrategy
-	//forceTrigger true if CD triggered Auto, triggered occurred from CI
-	if overrideRequest.ForceTrigger {
-		strategy, err = impl.pipelineConfigRepository.GetDefaultStrategyByPipelineId(overrideRequest.PipelineId)
-	} else {
-		var deploymentTemplate pipelineConfig.DeploymentTemplate
-		if overrideRequest.DeploymentTemplate == "ROLLING" {
-			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_ROLLING
-		} else if overrideRequest.DeploymentTemplate == "BLUE-GREEN" {
-			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_BLUE_GREEN
-		} else if overrideRequest.DeploymentTemplate == "CANARY" {
-			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_CANARY
-		} else if overrideRequest.DeploymentTemplate == "RECREATE" {
-			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_RECREATE
-		}
-
-		if len(deploymentTemplate) > 0 {
-			strategy, err = impl.pipelineConfigRepository.FindByStrategyAndPipelineId(deploymentTemplate, overrideRequest.PipelineId)
-		} else {
-			strategy, err = impl.pipelineConfigRepository.GetDefaultStrategyByPipelineId(overrideRequest.PipelineId)
-		}
-	}
-	if err != nil && errors2.IsNotFound(err) == false {
-		impl.logger.Errorf("invalid state", "err", err, "req", strategy)
+	strategy, err := impl.getStrategyForTrigger(overrideRequest)
+	if err != nil {
 		return 0, err
 	}
 
@@ -565,7 +566,9 @@ func (impl AppServiceImpl) TriggerRelease(overrideRequest *bean.ValuesOverrideRe
 
 	chartVersion := envOverride.Chart.ChartVersion
 	configMapJson, err := impl.getConfigMapAndSecretJsonV2(overrideRequest.AppId, envOverride.TargetEnvironment, overrideRequest.PipelineId, chartVersion)
-	if err != nil {
+	if vaultErr, ok := err.(*vaultSecretError); ok {
+		return 0, vaultErr.err
+	} else if err != nil {
 		impl.logger.Errorw("error in fetching config map n secret ", "err", err)
 		configMapJson = nil
 	}
@@ -617,6 +620,168 @@ func (impl AppServiceImpl) TriggerRelease(overrideRequest *bean.ValuesOverrideRe
 	return releaseId, saveErr
 }
 
+// getEnvOverrideForTrigger returns the env config a release is rendered from, the app level config is used when
+// env is not overridden. env config row is created on first trigger unless it is a dry run
+func (impl AppServiceImpl) getEnvOverrideForTrigger(overrideRequest *bean.ValuesOverrideRequest, pipeline *pipelineConfig.Pipeline, dryRun bool) (*chartConfig.EnvConfigOverride, error) {
+	envOverride, err := impl.environmentConfigRepository.ActiveEnvConfigOverride(overrideRequest.AppId, pipeline.EnvironmentId)
+	if err != nil {
+		impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
+		return nil, err
+	}
+
+	if envOverride.Id == 0 {
+		chart, err := impl.chartRepository.FindLatestChartForAppByAppId(overrideRequest.AppId)
+		if err != nil {
+			impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
+			return nil, err
+		}
+		envOverride, err = impl.environmentConfigRepository.FindChartByAppIdAndEnvIdAndChartRefId(overrideRequest.AppId, pipeline.EnvironmentId, chart.ChartRefId)
+		if err != nil && !errors2.IsNotFound(err) {
+			impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
+			return nil, err
+		}
+
+		//creating new env override config
+		if errors2.IsNotFound(err) || envOverride == nil {
+			environment, err := impl.envRepository.FindById(pipeline.EnvironmentId)
+			if err != nil && !IsErrNoRows(err) {
+				return nil, err
+			}
+			envOverride = &chartConfig.EnvConfigOverride{
+				Active:            true,
+				ManualReviewed:    true,
+				Status:            models.CHARTSTATUS_SUCCESS,
+				TargetEnvironment: pipeline.EnvironmentId,
+				ChartId:           chart.Id,
+				AuditLog:          sql.AuditLog{UpdatedBy: overrideRequest.UserId, UpdatedOn: time.Now(), CreatedOn: time.Now(), CreatedBy: overrideRequest.UserId},
+				Namespace:         environment.Namespace,
+				IsOverride:        false,
+				EnvOverrideValues: "{}",
+				Latest:            false,
+			}
+			if !dryRun {
+				err = impl.environmentConfigRepository.Save(envOverride)
+				if err != nil {
+					impl.logger.Errorw("error in creating envconfig", "data", envOverride, "error", err)
+					return nil, err
+				}
+			}
+		}
+
+		env, err := impl.envRepository.FindById(envOverride.TargetEnvironment)
+		if err != nil {
+			impl.logger.Errorw("unable to find env", "err", err)
+			return nil, err
+		}
+		envOverride.Environment = env
+		envOverride.Chart = chart
+	} else if envOverride.Id > 0 && !envOverride.IsOverride {
+		chart, err := impl.chartRepository.FindLatestChartForAppByAppId(overrideRequest.AppId)
+		if err != nil {
+			impl.logger.Errorf("invalid state", "err", err, "req", overrideRequest)
+			return nil, err
+		}
+		envOverride.Chart = chart
+	}
+	return envOverride, nil
+}
+
+// getStrategyForTrigger fetches pipeline config from strategy table, if pipeline is automatic fetch always default, else depends on request
+func (impl AppServiceImpl) getStrategyForTrigger(overrideRequest *bean.ValuesOverrideRequest) (*chartConfig.PipelineStrategy, error) {
+	var strategy *chartConfig.PipelineStrategy
+	var err error
+	//forceTrigger true if CD triggered Auto, triggered occurred from CI
+	if overrideRequest.ForceTrigger {
+		strategy, err = impl.pipelineConfigRepository.GetDefaultStrategyByPipelineId(overrideRequest.PipelineId)
+	} else {
+		var deploymentTemplate pipelineConfig.DeploymentTemplate
+		if overrideRequest.DeploymentTemplate == "ROLLING" {
+			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_ROLLING
+		} else if overrideRequest.DeploymentTemplate == "BLUE-GREEN" {
+			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_BLUE_GREEN
+		} else if overrideRequest.DeploymentTemplate == "CANARY" {
+			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_CANARY
+		} else if overrideRequest.DeploymentTemplate == "RECREATE" {
+			deploymentTemplate = pipelineConfig.DEPLOYMENT_TEMPLATE_RECREATE
+		}
+
+		if len(deploymentTemplate) > 0 {
+			strategy, err = impl.pipelineConfigRepository.FindByStrategyAndPipelineId(deploymentTemplate, overrideRequest.PipelineId)
+		} else {
+			strategy, err = impl.pipelineConfigRepository.GetDefaultStrategyByPipelineId(overrideRequest.PipelineId)
+		}
+	}
+	if err != nil && errors2.IsNotFound(err) == false {
+		impl.logger.Errorf("invalid state", "err", err, "req", strategy)
+		return nil, err
+	}
+	return strategy, nil
+}
+
+// GetDeploymentValues computes the values a trigger of the request would commit to git, nothing is saved
+func (impl AppServiceImpl) GetDeploymentValues(overrideRequest *bean.ValuesOverrideRequest, ctx context.Context) (*DeploymentValues, error) {
+	if overrideRequest.DeploymentType == models.DEPLOYMENTTYPE_UNKNOWN {
+		overrideRequest.DeploymentType = models.DEPLOYMENTTYPE_DEPLOY
+	}
+	pipeline, err := impl.pipelineRepository.FindById(overrideRequest.PipelineId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching pipeline", "pipelineId", overrideRequest.PipelineId, "err", err)
+		return nil, err
+	}
+	overrideRequest.AppId = pipeline.AppId
+	envOverride, err := impl.getEnvOverrideForTrigger(overrideRequest, pipeline, true)
+	if err != nil {
+		return nil, err
+	}
+	artifact, err := impl.ciArtifactRepository.Get(overrideRequest.CiArtifactId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching artifact", "ciArtifactId", overrideRequest.CiArtifactId, "err", err)
+		return nil, err
+	}
+	dbMigrationOverride, err := impl.getDbMigrationOverride(overrideRequest, artifact, false)
+	if err != nil {
+		impl.logger.Errorw("error in fetching db migration config", "req", overrideRequest, "err", err)
+		return nil, err
+	}
+	strategy, err := impl.getStrategyForTrigger(overrideRequest)
+	if err != nil {
+		return nil, err
+	}
+	valid, err := impl.validateVersionForStrategy(envOverride, strategy)
+	if err != nil || !valid {
+		impl.logger.Errorw("error in validating pipeline strategy ", "strategy", strategy, "err", err)
+		return nil, err
+	}
+	configMapJson, err := impl.getConfigMapAndSecretJsonV2(overrideRequest.AppId, envOverride.TargetEnvironment, overrideRequest.PipelineId, envOverride.Chart.ChartVersion)
+	if vaultErr, ok := err.(*vaultSecretError); ok {
+		return nil, vaultErr.err
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching config map n secret ", "err", err)
+		configMapJson = nil
+	}
+	releaseCounter, err := impl.pipelineOverrideRepository.GetCurrentPipelineReleaseCounter(pipeline.Id)
+	if err != nil {
+		return nil, err
+	}
+	override := &chartConfig.PipelineOverride{PipelineId: pipeline.Id, PipelineReleaseCounter: releaseCounter + 1}
+	overrideJson, err := impl.getReleaseOverride(envOverride, overrideRequest, artifact, pipeline, override, strategy)
+	if err != nil {
+		return nil, err
+	}
+	merged, err := impl.mergeValues(envOverride, dbMigrationOverride, overrideJson, strategy, configMapJson)
+	if err != nil {
+		return nil, err
+	}
+	appName := fmt.Sprintf("%s-%s", pipeline.App.AppName, envOverride.Environment.Name)
+	merged = impl.hpaCheckBeforeTrigger(ctx, appName, envOverride.Namespace, merged, pipeline.AppId)
+	return &DeploymentValues{
+		Pipeline:       pipeline,
+		EnvOverride:    envOverride,
+		MergedValues:   merged,
+		ReleaseCounter: override.PipelineReleaseCounter,
+	}, nil
+}
+
 func (impl AppServiceImpl) MarkImageScanDeployed(appId int, envId int, imageDigest string, clusterId int) error {
 	impl.logger.Debugw("mark image scan deployed for normal app, from cd auto or manual trigger", "imageDigest", imageDigest)
 	executionHistory, err := impl.imageScanHistoryRepository.FindByImageDigest(imageDigest)
@@ -800,6 +965,16 @@ func (impl AppServiceImpl) GetConfigMapAndSecretJson(appId int, envId int, pipel
 	return merged, nil
 }
 
+// vaultSecretError stops the release, other config map and secret errors fall back to releasing without them
+// but secrets referring to vault must not silently disappear from the release
+type vaultSecretError struct {
+	err error
+}
+
+func (e *vaultSecretError) Error() string {
+	return e.err.Error()
+}
+
 func (impl AppServiceImpl) getConfigMapAndSecretJsonV2(appId int, envId int, pipelineId int, chartVersion string) ([]byte, error) {
 
 	var configMapJson string
@@ -860,6 +1035,33 @@ func (impl AppServiceImpl) getConfigMapAndSecretJsonV2(appId int, envId int, pip
 			return []byte("{}"), err
 		}
 	}
+	// secrets of vault references are released as external secrets, the values are read from vault in the cluster
+	// and never become part of the values committed to git
+	for _, secret := range secretResponse.Secrets {
+		if secret.External {
+			continue
+		}
+		entries, err := impl.vaultService.ExternalSecretData(secret.Data)
+		if err != nil {
+			impl.logger.Errorw("error in building vault external secret", "appId", appId, "envId", envId, "secret", secret.Name, "err", err)
+			return []byte("{}"), &vaultSecretError{err: err}
+		}
+		if entries == nil {
+			continue
+		}
+		if chartMajorVersion <= 3 && chartMinorVersion < 8 {
+			return []byte("{}"), &vaultSecretError{err: &ApiError{HttpStatusCode: http.StatusUnprocessableEntity,
+				UserMessage: fmt.Sprintf("secret %s has vault references which need reference chart 3.8.0 or later", secret.Name)}}
+		}
+		secretData, err := json.Marshal(entries)
+		if err != nil {
+			return []byte("{}"), err
+		}
+		secret.External = true
+		secret.ExternalType = util2.HashiCorpVault
+		secret.SecretData = secretData
+		secret.Data = nil
+	}
 	secretResponseR.ConfigSecretJson = secretResponse
 
 	configMapByte, err := json.Marshal(configResponseR)
@@ -1003,9 +1205,22 @@ func (impl AppServiceImpl) getReleaseOverride(envOverride *chartConfig.EnvConfig
 		appMetrics = envLevelMetrics.AppMetrics
 	}
 
+	tag := imageTag[1]
+	// signatures are for the digest of the artifact and the tag could have been pushed again since, releases under a
+	// signature policy are pinned to the digest which was verified
+	if strings.HasPrefix(artifact.ImageDigest, "sha256:") && !strings.Contains(tag, "@") {
+		hasPolicy, err := impl.imageSigningService.HasPolicy(pipeline.Environment.ClusterId, pipeline.EnvironmentId, pipeline.AppId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching image signature policy", "pipelineId", pipeline.Id, "err", err)
+			return "", err
+		}
+		if hasPolicy {
+			tag = fmt.Sprintf("%s@%s", tag, artifact.ImageDigest)
+		}
+	}
 	releaseAttribute := ReleaseAttributes{
 		Name:           imageTag[0],
-		Tag:            imageTag[1],
+		Tag:            tag,
 		PipelineName:   pipeline.Name,
 		ReleaseVersion: strconv.Itoa(pipelineOverride.PipelineReleaseCounter),
 		DeploymentType: string(strategy.Strategy),
@@ -1037,36 +1252,60 @@ func (impl AppServiceImpl) GetChartRepoName(gitRepoUrl string) string {
 	return chartRepoName
 }
 
-func (impl AppServiceImpl) mergeAndSave(envOverride *chartConfig.EnvConfigOverride,
-	overrideRequest *bean.ValuesOverrideRequest,
-	dbMigrationOverride []byte,
-	artifact *repository.CiArtifact,
-	pipeline *pipelineConfig.Pipeline, configMapJson []byte, strategy *chartConfig.PipelineStrategy, ctx context.Context) (releaseId int, overrideId int, err error) {
-
-	//register release , obtain release id TODO: populate releaseId to template
-	override, err := impl.savePipelineOverride(overrideRequest, envOverride.Id)
+// checkDeploymentPolicies evaluates the manifests rendered from the final values against deployment policies before they are committed to git.
+// violations are recorded against the release, release is stopped only when a violated policy blocks.
+func (impl AppServiceImpl) checkDeploymentPolicies(pipeline *pipelineConfig.Pipeline, envOverride *chartConfig.EnvConfigOverride, pipelineOverrideId int, merged []byte, userId int32) error {
+	releaseName := fmt.Sprintf("%s-%s", pipeline.App.AppName, envOverride.Environment.Name)
+	evaluation, err := impl.deploymentPolicyService.EvaluateManifests(pipeline.Environment.ClusterId, pipeline.EnvironmentId, pipeline.AppId, func() ([]map[string]interface{}, error) {
+		return impl.chartTemplateRenderer.Render(envOverride.Chart.ChartRefId, releaseName, envOverride.Namespace, merged)
+	})
 	if err != nil {
-		return 0, 0, err
+		impl.logger.Errorw("error in evaluating deployment policies", "pipelineId", pipeline.Id, "err", err)
+		return err
 	}
-	//TODO: check status and apply lock
-	overrideJson, err := impl.getReleaseOverride(envOverride, overrideRequest, artifact, pipeline, override, strategy)
+	if len(evaluation.Violations) == 0 {
+		return nil
+	}
+	if evaluation.Blocked {
+		// override of a blocked release is deleted, violations are kept against the pipeline only
+		pipelineOverrideId = 0
+	}
+	err = impl.deploymentPolicyService.SaveViolations(pipeline.Id, pipelineOverrideId, evaluation.Violations, userId)
 	if err != nil {
-		return 0, 0, err
+		return err
+	}
+	var violatedRules []string
+	for _, violation := range evaluation.Violations {
+		violatedRules = append(violatedRules, fmt.Sprintf("%s(%s)", violation.Rule, violation.Action))
+	}
+	if !evaluation.Blocked {
+		impl.logger.Warnw("deployment policy violations found, proceeding with release", "pipelineId", pipeline.Id, "rules", violatedRules)
+		return nil
 	}
+	return &ApiError{
+		HttpStatusCode:  http.StatusUnprocessableEntity,
+		Code:            "422",
+		InternalMessage: fmt.Sprintf("release blocked by deployment policy: %s", strings.Join(violatedRules, ", ")),
+		UserMessage:     evaluation,
+	}
+}
 
+func (impl AppServiceImpl) mergeValues(envOverride *chartConfig.EnvConfigOverride, dbMigrationOverride []byte, overrideJson string,
+	strategy *chartConfig.PipelineStrategy, configMapJson []byte) ([]byte, error) {
 	//merge three values on the fly
 	//ordering is important here
 	//global < environment < db< release
 	var merged []byte
+	var err error
 	if !envOverride.IsOverride {
 		merged, err = impl.mergeUtil.JsonPatch([]byte("{}"), []byte(envOverride.Chart.GlobalOverride))
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
 	} else {
 		merged, err = impl.mergeUtil.JsonPatch([]byte("{}"), []byte(envOverride.EnvOverrideValues))
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
 	}
 
@@ -1074,28 +1313,70 @@ func (impl AppServiceImpl) mergeAndSave(envOverride *chartConfig.EnvConfigOverri
 	if strategy != nil && len(strategy.Config) > 0 {
 		merged, err = impl.mergeUtil.JsonPatch(merged, []byte(strategy.Config))
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
 	}
 	merged, err = impl.mergeUtil.JsonPatch(merged, dbMigrationOverride)
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
 	merged, err = impl.mergeUtil.JsonPatch(merged, []byte(overrideJson))
 	if err != nil {
-		return 0, 0, err
+		return nil, err
 	}
 
 	if configMapJson != nil {
 		merged, err = impl.mergeUtil.JsonPatch(merged, configMapJson)
 		if err != nil {
-			return 0, 0, err
+			return nil, err
 		}
 	}
+	return merged, nil
+}
+
+func (impl AppServiceImpl) mergeAndSave(envOverride *chartConfig.EnvConfigOverride,
+	overrideRequest *bean.ValuesOverrideRequest,
+	dbMigrationOverride []byte,
+	artifact *repository.CiArtifact,
+	pipeline *pipelineConfig.Pipeline, configMapJson []byte, strategy *chartConfig.PipelineStrategy, ctx context.Context) (releaseId int, overrideId int, err error) {
+
+	//register release , obtain release id TODO: populate releaseId to template
+	override, err := impl.savePipelineOverride(overrideRequest, envOverride.Id)
+	if err != nil {
+		return 0, 0, err
+	}
+	//TODO: check status and apply lock
+	overrideJson, err := impl.getReleaseOverride(envOverride, overrideRequest, artifact, pipeline, override, strategy)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var merged []byte
+	if overrideRequest.RollbackPipelineOverrideId > 0 {
+		merged, err = impl.getRollbackValues(overrideRequest.RollbackPipelineOverrideId, overrideJson)
+	} else {
+		merged, err = impl.mergeValues(envOverride, dbMigrationOverride, overrideJson, strategy, configMapJson)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
 
 	appName := fmt.Sprintf("%s-%s", pipeline.App.AppName, envOverride.Environment.Name)
 	merged = impl.hpaCheckBeforeTrigger(ctx, appName, envOverride.Namespace, merged, pipeline.AppId)
 
+	err = impl.checkDeploymentPolicies(pipeline, envOverride, override.Id, merged, overrideRequest.UserId)
+	if err != nil {
+		// release is not committed, its override must not be picked up as the latest release of the pipeline
+		if deleteErr := impl.pipelineOverrideRepository.Delete(override); deleteErr != nil {
+			impl.logger.Errorw("error in deleting override of stopped release", "pipelineOverrideId", override.Id, "err", deleteErr)
+		}
+		return 0, 0, err
+	}
+	// config versions not deployed yet are first deployed by this override, failure here must not block the release
+	if overrideRequest.RollbackPipelineOverrideId == 0 {
+		_ = impl.configHistoryService.LinkPipelineOverride(pipeline.AppId, pipeline.EnvironmentId, override.Id)
+	}
+
 	chartRepoName := impl.GetChartRepoName(envOverride.Chart.GitRepoUrl)
 	chartGitAttr := &ChartConfig{
 		FileName:       fmt.Sprintf("_%d-values.yaml", envOverride.TargetEnvironment),
@@ -1135,6 +1416,20 @@ func (impl AppServiceImpl) mergeAndSave(envOverride *chartConfig.EnvConfigOverri
 	return override.PipelineReleaseCounter, override.Id, nil
 }
 
+// getRollbackValues returns the merged values of an earlier release with the release override of the new one applied,
+// so that a rollback also reverts config changed since that release
+func (impl AppServiceImpl) getRollbackValues(pipelineOverrideId int, overrideJson string) ([]byte, error) {
+	rollbackOverride, err := impl.pipelineOverrideRepository.FindById(pipelineOverrideId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching release to roll back to", "pipelineOverrideId", pipelineOverrideId, "err", err)
+		return nil, err
+	}
+	if len(rollbackOverride.PipelineMergedValues) == 0 {
+		return nil, fmt.Errorf("values of release %d are not available", pipelineOverrideId)
+	}
+	return impl.mergeUtil.JsonPatch([]byte(rollbackOverride.PipelineMergedValues), []byte(overrideJson))
+}
+
 func (impl AppServiceImpl) savePipelineOverride(overrideRequest *bean.ValuesOverrideRequest, envOverrideId int) (override *chartConfig.PipelineOverride, err error) {
 	currentReleaseNo, err := impl.pipelineOverrideRepository.GetCurrentPipelineReleaseCounter(overrideRequest.PipelineId)
 	if err != nil {
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package app
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/util"
+	chartRepoRepository "github.com/devtron-labs/devtron/pkg/chartRepo/repository"
+	"github.com/ghodss/yaml"
+	"go.uber.org/zap"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// RefChartDir is the directory holding the reference charts, chart refs are located relative to it
+type RefChartDir string
+
+type ChartTemplateRenderer interface {
+	// Render renders the reference chart with values the way argocd would and returns the objects of all yaml templates
+	Render(chartRefId int, releaseName string, namespace string, values []byte) ([]map[string]interface{}, error)
+}
+
+type ChartTemplateRendererImpl struct {
+	logger             *zap.SugaredLogger
+	chartRefRepository chartRepoRepository.ChartRefRepository
+	refChartDir        RefChartDir
+}
+
+func NewChartTemplateRendererImpl(logger *zap.SugaredLogger, chartRefRepository chartRepoRepository.ChartRefRepository,
+	refChartDir RefChartDir) *ChartTemplateRendererImpl {
+	return &ChartTemplateRendererImpl{
+		logger:             logger,
+		chartRefRepository: chartRefRepository,
+		refChartDir:        refChartDir,
+	}
+}
+
+func (impl ChartTemplateRendererImpl) Render(chartRefId int, releaseName string, namespace string, values []byte) ([]map[string]interface{}, error) {
+	chartRef, err := impl.chartRefRepository.FindById(chartRefId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching chart ref", "chartRefId", chartRefId, "err", err)
+		return nil, err
+	}
+	refChart, err := chartutil.Load(path.Join(string(impl.refChartDir), chartRef.Location))
+	if err != nil {
+		impl.logger.Errorw("error in loading reference chart", "location", chartRef.Location, "err", err)
+		return nil, err
+	}
+	valuesYaml, err := yaml.JSONToYAML(values)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := renderutil.Render(refChart, &chart.Config{Raw: string(valuesYaml)}, renderutil.Options{
+		ReleaseOptions: chartutil.ReleaseOptions{Name: releaseName, Namespace: namespace, IsUpgrade: true},
+	})
+	if err != nil {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusUnprocessableEntity, InternalMessage: err.Error(), UserMessage: fmt.Sprintf("chart rendering failed: %s", err.Error())}
+	}
+	var objects []map[string]interface{}
+	for name, content := range templates {
+		if !strings.HasSuffix(name, ".yaml") && !strings.HasSuffix(name, ".yml") {
+			continue
+		}
+		for _, document := range strings.Split(content, "\n---") {
+			object := make(map[string]interface{})
+			err = yaml.Unmarshal([]byte(document), &object)
+			if err != nil {
+				return nil, &util.ApiError{HttpStatusCode: http.StatusUnprocessableEntity, InternalMessage: err.Error(), UserMessage: fmt.Sprintf("invalid manifest rendered by %s", name)}
+			}
+			if kind, _ := object["kind"].(string); len(kind) == 0 {
+				continue
+			}
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
@@ -275,6 +275,9 @@ func (impl *AppCloneServiceImpl) CreateCiTemplate(oldAppId, newAppId int, userId
 			GitMaterialId:  dockerfileGitMaterial,
 			DockerfilePath: refCiConf.DockerBuildConfig.DockerfilePath,
 			Args:           refCiConf.DockerBuildConfig.Args,
+			TargetPlatform: refCiConf.DockerBuildConfig.TargetPlatform,
+			BuildType:      refCiConf.DockerBuildConfig.BuildType,
+			BuilderImage:   refCiConf.DockerBuildConfig.BuilderImage,
 		},
 		DockerRegistryUrl: refCiConf.DockerRegistry,
 		CiTemplateName:    refCiConf.CiTemplateName,
@@ -676,6 +679,8 @@ func (impl *AppCloneServiceImpl) CreateCiPipeline(req *cloneCiPipelineRequest) (
 						Type:  refCiMaterial.Source.Type,
 						Value: refCiMaterial.Source.Value,
 					},
+					IncludePaths: refCiMaterial.IncludePaths,
+					ExcludePaths: refCiMaterial.ExcludePaths,
 				}
 				ciMaterilas = append(ciMaterilas, ciMaterial)
 			}
@@ -0,0 +1,335 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package auditLog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/pkg/auditLog/repository"
+	repository2 "github.com/devtron-labs/devtron/pkg/user/repository"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	EntityDeploymentTemplate    = "deployment_template"
+	EntityEnvDeploymentTemplate = "env_deployment_template"
+	EntityConfigMap             = "config_map"
+	EntitySecret                = "secret"
+	EntityCiPipeline            = "ci_pipeline"
+	EntityCdPipeline            = "cd_pipeline"
+	EntityUser                  = "user"
+	EntityRoleGroup             = "role_group"
+	EntityCluster               = "cluster"
+	EntityApiToken              = "api_token"
+	EntityCveException          = "cve_exception"
+	EntityVaultConfig           = "vault_config"
+	EntitySecretRotation        = "secret_rotation"
+	EntityArtifactRetention     = "artifact_retention"
+
+	ExportFormatJson = "json"
+	ExportFormatCsv  = "csv"
+
+	maskedValue   = "********"
+	maxExportSize = 10000
+)
+
+// values for these keys are never written to the audit trail, e.g. cluster bearer token
+var sensitiveKeys = []string{"token", "password", "secretkey", "accesskey", "tls_client_key", "key_data"}
+
+type AuditLogRequest struct {
+	UserId     int32
+	Resource   string
+	Action     string
+	EntityType string
+	EntityId   string
+	Request    *http.Request
+	// Previous and Current are serialised as json, nil for create and delete respectively
+	Previous interface{}
+	Current  interface{}
+	// MaskValues hides every value while still recording which fields changed, used for secrets
+	MaskValues bool
+}
+
+type AuditLogDiff struct {
+	Path     string `json:"path"`
+	Previous string `json:"previous,omitempty"`
+	Current  string `json:"current,omitempty"`
+}
+
+type AuditLogDto struct {
+	Id            int             `json:"id"`
+	UserId        int32           `json:"userId"`
+	EmailId       string          `json:"emailId"`
+	Resource      string          `json:"resource"`
+	Action        string          `json:"action"`
+	EntityType    string          `json:"entityType"`
+	EntityId      string          `json:"entityId"`
+	RequestMethod string          `json:"requestMethod"`
+	RequestPath   string          `json:"requestPath"`
+	PreviousValue json.RawMessage `json:"previousValue,omitempty"`
+	CurrentValue  json.RawMessage `json:"currentValue,omitempty"`
+	Diff          []*AuditLogDiff `json:"diff"`
+	CreatedOn     time.Time       `json:"createdOn"`
+}
+
+type AuditLogListResponse struct {
+	TotalCount int            `json:"totalCount"`
+	AuditLogs  []*AuditLogDto `json:"auditLogs"`
+}
+
+type AuditLogService interface {
+	// SaveAuditLog never fails the calling api, errors are only logged
+	SaveAuditLog(request *AuditLogRequest)
+	GetAuditLogs(filter *repository.AuditLogFilter) (*AuditLogListResponse, error)
+	ExportAuditLogs(filter *repository.AuditLogFilter, format string) ([]byte, error)
+}
+
+type AuditLogServiceImpl struct {
+	logger             *zap.SugaredLogger
+	auditLogRepository repository.AuditLogRepository
+	userRepository     repository2.UserRepository
+}
+
+func NewAuditLogServiceImpl(logger *zap.SugaredLogger, auditLogRepository repository.AuditLogRepository,
+	userRepository repository2.UserRepository) *AuditLogServiceImpl {
+	return &AuditLogServiceImpl{
+		logger:             logger,
+		auditLogRepository: auditLogRepository,
+		userRepository:     userRepository,
+	}
+}
+
+func (impl AuditLogServiceImpl) SaveAuditLog(request *AuditLogRequest) {
+	masked := make(map[string]bool)
+	previous := impl.flatten(request.Previous, request.MaskValues, masked)
+	current := impl.flatten(request.Current, request.MaskValues, masked)
+	diff := buildDiff(previous, current, masked)
+	diffJson, err := json.Marshal(diff)
+	if err != nil {
+		impl.logger.Errorw("error in marshaling audit diff", "entityType", request.EntityType, "entityId", request.EntityId, "err", err)
+		return
+	}
+	auditLog := &repository.AuditLog{
+		UserId:        request.UserId,
+		Resource:      request.Resource,
+		Action:        request.Action,
+		EntityType:    request.EntityType,
+		EntityId:      request.EntityId,
+		PreviousValue: toJson(previous, masked),
+		CurrentValue:  toJson(current, masked),
+		Diff:          string(diffJson),
+		CreatedOn:     time.Now(),
+	}
+	if request.Request != nil {
+		auditLog.RequestMethod = request.Request.Method
+		auditLog.RequestPath = request.Request.URL.Path
+	}
+	user, err := impl.userRepository.GetByIdIncludeDeleted(request.UserId)
+	if err != nil {
+		impl.logger.Warnw("error in fetching user for audit log", "userId", request.UserId, "err", err)
+	} else {
+		auditLog.EmailId = user.EmailId
+	}
+	err = impl.auditLogRepository.Save(auditLog)
+	if err != nil {
+		impl.logger.Errorw("error in saving audit log", "auditLog", auditLog, "err", err)
+	}
+}
+
+func (impl AuditLogServiceImpl) GetAuditLogs(filter *repository.AuditLogFilter) (*AuditLogListResponse, error) {
+	auditLogs, count, err := impl.auditLogRepository.FindByFilter(filter)
+	if err != nil {
+		impl.logger.Errorw("error in fetching audit logs", "filter", filter, "err", err)
+		return nil, err
+	}
+	response := &AuditLogListResponse{TotalCount: count, AuditLogs: make([]*AuditLogDto, 0)}
+	for _, auditLog := range auditLogs {
+		dto := &AuditLogDto{
+			Id:            auditLog.Id,
+			UserId:        auditLog.UserId,
+			EmailId:       auditLog.EmailId,
+			Resource:      auditLog.Resource,
+			Action:        auditLog.Action,
+			EntityType:    auditLog.EntityType,
+			EntityId:      auditLog.EntityId,
+			RequestMethod: auditLog.RequestMethod,
+			RequestPath:   auditLog.RequestPath,
+			CreatedOn:     auditLog.CreatedOn,
+		}
+		if len(auditLog.PreviousValue) > 0 {
+			dto.PreviousValue = json.RawMessage(auditLog.PreviousValue)
+		}
+		if len(auditLog.CurrentValue) > 0 {
+			dto.CurrentValue = json.RawMessage(auditLog.CurrentValue)
+		}
+		if len(auditLog.Diff) > 0 {
+			err = json.Unmarshal([]byte(auditLog.Diff), &dto.Diff)
+			if err != nil {
+				impl.logger.Errorw("error in parsing audit diff", "id", auditLog.Id, "err", err)
+			}
+		}
+		response.AuditLogs = append(response.AuditLogs, dto)
+	}
+	return response, nil
+}
+
+func (impl AuditLogServiceImpl) ExportAuditLogs(filter *repository.AuditLogFilter, format string) ([]byte, error) {
+	if filter.Size <= 0 || filter.Size > maxExportSize {
+		filter.Size = maxExportSize
+	}
+	response, err := impl.GetAuditLogs(filter)
+	if err != nil {
+		return nil, err
+	}
+	if format == ExportFormatJson {
+		return json.Marshal(response.AuditLogs)
+	} else if format != ExportFormatCsv {
+		return nil, fmt.Errorf("unsupported export format %s", format)
+	}
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	err = writer.Write([]string{"id", "createdOn", "userId", "emailId", "resource", "action", "entityType", "entityId", "requestMethod", "requestPath", "diff"})
+	if err != nil {
+		return nil, err
+	}
+	for _, auditLog := range response.AuditLogs {
+		var changes []string
+		for _, diff := range auditLog.Diff {
+			changes = append(changes, fmt.Sprintf("%s: %s -> %s", diff.Path, diff.Previous, diff.Current))
+		}
+		err = writer.Write([]string{strconv.Itoa(auditLog.Id), auditLog.CreatedOn.Format(time.RFC3339), strconv.Itoa(int(auditLog.UserId)), auditLog.EmailId,
+			auditLog.Resource, auditLog.Action, auditLog.EntityType, auditLog.EntityId, auditLog.RequestMethod, auditLog.RequestPath, strings.Join(changes, "\n")})
+		if err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// flatten converts the entity to path -> value pairs so that previous and current state can be compared field by field.
+// paths whose values must not be stored are collected in masked
+func (impl AuditLogServiceImpl) flatten(entity interface{}, maskValues bool, masked map[string]bool) map[string]string {
+	if entity == nil {
+		return nil
+	}
+	entityJson, err := json.Marshal(entity)
+	if err != nil {
+		impl.logger.Errorw("error in marshaling entity for audit log", "err", err)
+		return nil
+	}
+	var value interface{}
+	if err = json.Unmarshal(entityJson, &value); err != nil || value == nil {
+		return nil
+	}
+	flattened := make(map[string]string)
+	flattenValue(value, "", maskValues, flattened, masked)
+	return flattened
+}
+
+func flattenValue(value interface{}, path string, maskValues bool, flattened map[string]string, masked map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := key
+			if len(path) > 0 {
+				childPath = path + "." + key
+			}
+			flattenValue(child, childPath, maskValues || isSensitiveKey(key), flattened, masked)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flattenValue(child, fmt.Sprintf("%s[%d]", path, i), maskValues, flattened, masked)
+		}
+	default:
+		if v == nil {
+			return
+		}
+		if maskValues {
+			masked[path] = true
+		}
+		if s, ok := v.(string); ok {
+			flattened[path] = s
+		} else {
+			flattened[path] = fmt.Sprintf("%v", v)
+		}
+	}
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if strings.Contains(key, sensitiveKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// buildDiff compares raw values, masked paths are reported as changed without exposing the values
+func buildDiff(previous map[string]string, current map[string]string, masked map[string]bool) []*AuditLogDiff {
+	diff := make([]*AuditLogDiff, 0)
+	for path, previousValue := range previous {
+		currentValue, ok := current[path]
+		if !ok || currentValue != previousValue {
+			diff = append(diff, &AuditLogDiff{Path: path, Previous: previousValue, Current: currentValue})
+		}
+	}
+	for path, currentValue := range current {
+		if _, ok := previous[path]; !ok {
+			diff = append(diff, &AuditLogDiff{Path: path, Current: currentValue})
+		}
+	}
+	for _, d := range diff {
+		if masked[d.Path] {
+			if len(d.Previous) > 0 {
+				d.Previous = maskedValue
+			}
+			if len(d.Current) > 0 {
+				d.Current = maskedValue
+			}
+		}
+	}
+	sort.Slice(diff, func(i, j int) bool {
+		return diff[i].Path < diff[j].Path
+	})
+	return diff
+}
+
+func toJson(flattened map[string]string, masked map[string]bool) string {
+	if flattened == nil {
+		return ""
+	}
+	for path := range flattened {
+		if masked[path] {
+			flattened[path] = maskedValue
+		}
+	}
+	value, err := json.Marshal(flattened)
+	if err != nil {
+		return ""
+	}
+	return string(value)
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package auditLog
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/auditLog/repository"
+	repository2 "github.com/devtron-labs/devtron/pkg/user/repository"
+)
+
+type auditLogRepositoryStub struct {
+	repository.AuditLogRepository
+	saved []*repository.AuditLog
+}
+
+func (impl *auditLogRepositoryStub) Save(auditLog *repository.AuditLog) error {
+	impl.saved = append(impl.saved, auditLog)
+	return nil
+}
+
+type auditUserRepositoryStub struct {
+	repository2.UserRepository
+}
+
+func (impl auditUserRepositoryStub) GetByIdIncludeDeleted(id int32) (*repository2.UserModel, error) {
+	return &repository2.UserModel{Id: id, EmailId: "admin@example.com"}, nil
+}
+
+func TestSaveAuditLogMasking(t *testing.T) {
+	type cluster struct {
+		Name   string            `json:"name"`
+		Config map[string]string `json:"config"`
+	}
+	type secret struct {
+		Name string            `json:"name"`
+		Data map[string]string `json:"data"`
+	}
+	tests := []struct {
+		name         string
+		request      *AuditLogRequest
+		wantDiff     []*AuditLogDiff
+		wantPrevious map[string]string
+		wantCurrent  map[string]string
+		secrets      []string
+	}{
+		{
+			name: "sensitive keys are masked, other fields are kept",
+			request: &AuditLogRequest{
+				Previous: cluster{Name: "prod", Config: map[string]string{"bearer_token": "old-token"}},
+				Current:  cluster{Name: "production", Config: map[string]string{"bearer_token": "new-token"}},
+			},
+			wantDiff: []*AuditLogDiff{
+				{Path: "config.bearer_token", Previous: maskedValue, Current: maskedValue},
+				{Path: "name", Previous: "prod", Current: "production"},
+			},
+			wantPrevious: map[string]string{"name": "prod", "config.bearer_token": maskedValue},
+			wantCurrent:  map[string]string{"name": "production", "config.bearer_token": maskedValue},
+			secrets:      []string{"old-token", "new-token"},
+		},
+		{
+			name: "unchanged sensitive value is not in the diff",
+			request: &AuditLogRequest{
+				Previous: cluster{Name: "prod", Config: map[string]string{"Password": "s3cr3t-pass", "tls_client_key": "client-key-data"}},
+				Current:  cluster{Name: "prod", Config: map[string]string{"Password": "s3cr3t-pass", "tls_client_key": "client-key-data"}},
+			},
+			wantDiff:     []*AuditLogDiff{},
+			wantPrevious: map[string]string{"name": "prod", "config.Password": maskedValue, "config.tls_client_key": maskedValue},
+			wantCurrent:  map[string]string{"name": "prod", "config.Password": maskedValue, "config.tls_client_key": maskedValue},
+			secrets:      []string{"s3cr3t-pass", "client-key-data"},
+		},
+		{
+			name: "mask values hides every value of a secret",
+			request: &AuditLogRequest{
+				Previous:   secret{Name: "db", Data: map[string]string{"user": "app", "url": "postgres://old"}},
+				Current:    secret{Name: "db", Data: map[string]string{"user": "app", "url": "postgres://new", "pool": "10"}},
+				MaskValues: true,
+			},
+			wantDiff: []*AuditLogDiff{
+				{Path: "data.pool", Current: maskedValue},
+				{Path: "data.url", Previous: maskedValue, Current: maskedValue},
+			},
+			wantPrevious: map[string]string{"name": maskedValue, "data.user": maskedValue, "data.url": maskedValue},
+			wantCurrent:  map[string]string{"name": maskedValue, "data.user": maskedValue, "data.url": maskedValue, "data.pool": maskedValue},
+			secrets:      []string{"postgres://old", "postgres://new"},
+		},
+		{
+			name: "created secret has no previous value",
+			request: &AuditLogRequest{
+				Current:    secret{Name: "db", Data: map[string]string{"url": "postgres://new"}},
+				MaskValues: true,
+			},
+			wantDiff: []*AuditLogDiff{
+				{Path: "data.url", Current: maskedValue},
+				{Path: "name", Current: maskedValue},
+			},
+			wantCurrent: map[string]string{"name": maskedValue, "data.url": maskedValue},
+			secrets:     []string{"postgres://new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditLogRepository := &auditLogRepositoryStub{}
+			impl := AuditLogServiceImpl{
+				logger:             util.NewSugardLogger(),
+				auditLogRepository: auditLogRepository,
+				userRepository:     auditUserRepositoryStub{},
+			}
+			tt.request.UserId = 2
+			impl.SaveAuditLog(tt.request)
+			if len(auditLogRepository.saved) != 1 {
+				t.Fatalf("SaveAuditLog() saved %d audit logs, want 1", len(auditLogRepository.saved))
+			}
+			saved := auditLogRepository.saved[0]
+			var diff []*AuditLogDiff
+			if err := json.Unmarshal([]byte(saved.Diff), &diff); err != nil {
+				t.Fatalf("SaveAuditLog() diff %s is not json: %v", saved.Diff, err)
+			}
+			if !reflect.DeepEqual(diff, tt.wantDiff) {
+				got, _ := json.Marshal(diff)
+				t.Errorf("SaveAuditLog() diff = %s", got)
+			}
+			assertFlattened(t, "previous", saved.PreviousValue, tt.wantPrevious)
+			assertFlattened(t, "current", saved.CurrentValue, tt.wantCurrent)
+			for _, value := range tt.secrets {
+				if strings.Contains(saved.PreviousValue+saved.CurrentValue+saved.Diff, value) {
+					t.Errorf("SaveAuditLog() stored secret value %s", value)
+				}
+			}
+			if saved.EmailId != "admin@example.com" {
+				t.Errorf("SaveAuditLog() emailId = %s", saved.EmailId)
+			}
+		})
+	}
+}
+
+func assertFlattened(t *testing.T, name string, value string, want map[string]string) {
+	if want == nil {
+		if len(value) > 0 {
+			t.Errorf("SaveAuditLog() %s = %s, want empty", name, value)
+		}
+		return
+	}
+	var got map[string]string
+	if err := json.Unmarshal([]byte(value), &got); err != nil {
+		t.Fatalf("SaveAuditLog() %s %s is not json: %v", name, value, err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SaveAuditLog() %s = %v, want %v", name, got, want)
+	}
+}
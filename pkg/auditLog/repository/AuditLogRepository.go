@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package repository
+
+import (
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"time"
+)
+
+// AuditLog is append only, rows are never updated or deleted by the application
+type AuditLog struct {
+	tableName     struct{}  `sql:"audit_log" pg:",discard_unknown_columns"`
+	Id            int       `sql:"id,pk"`
+	UserId        int32     `sql:"user_id,notnull"`
+	EmailId       string    `sql:"email_id"`
+	Resource      string    `sql:"resource,notnull"`
+	Action        string    `sql:"action,notnull"`
+	EntityType    string    `sql:"entity_type,notnull"`
+	EntityId      string    `sql:"entity_id"`
+	RequestMethod string    `sql:"request_method"`
+	RequestPath   string    `sql:"request_path"`
+	PreviousValue string    `sql:"previous_value"`
+	CurrentValue  string    `sql:"current_value"`
+	Diff          string    `sql:"diff"`
+	CreatedOn     time.Time `sql:"created_on,notnull"`
+}
+
+type AuditLogFilter struct {
+	UserId     int32
+	Resource   string
+	Action     string
+	EntityType string
+	EntityId   string
+	From       time.Time
+	To         time.Time
+	Offset     int
+	Size       int
+}
+
+type AuditLogRepository interface {
+	Save(auditLog *AuditLog) error
+	FindByFilter(filter *AuditLogFilter) ([]*AuditLog, int, error)
+}
+
+type AuditLogRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewAuditLogRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *AuditLogRepositoryImpl {
+	return &AuditLogRepositoryImpl{
+		dbConnection: dbConnection,
+		logger:       logger,
+	}
+}
+
+func (impl AuditLogRepositoryImpl) Save(auditLog *AuditLog) error {
+	return impl.dbConnection.Insert(auditLog)
+}
+
+// FindByFilter returns one page of matching rows, latest first, along with the total number of matching rows
+func (impl AuditLogRepositoryImpl) FindByFilter(filter *AuditLogFilter) ([]*AuditLog, int, error) {
+	var auditLogs []*AuditLog
+	query := impl.dbConnection.Model(&auditLogs)
+	if filter.UserId > 0 {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if len(filter.Resource) > 0 {
+		query = query.Where("resource = ?", filter.Resource)
+	}
+	if len(filter.Action) > 0 {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if len(filter.EntityType) > 0 {
+		query = query.Where("entity_type = ?", filter.EntityType)
+	}
+	if len(filter.EntityId) > 0 {
+		query = query.Where("entity_id = ?", filter.EntityId)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_on >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_on <= ?", filter.To)
+	}
+	count, err := query.Order("id DESC").
+		Offset(filter.Offset).
+		Limit(filter.Size).
+		SelectAndCount()
+	return auditLogs, count, err
+}
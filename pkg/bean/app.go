@@ -76,6 +76,8 @@ type CiMaterial struct {
 	ScmVersion      string            `json:"scmVersion,omitempty"`
 	Id              int               `json:"id,omitempty"`
 	GitMaterialName string            `json:"gitMaterialName"`
+	IncludePaths    []string          `json:"includePaths,omitempty"` // globs on changed files, webhook builds are skipped if no changed file matches
+	ExcludePaths    []string          `json:"excludePaths,omitempty"`
 }
 
 type CiPipeline struct {
@@ -99,6 +101,9 @@ type CiPipeline struct {
 	PipelineType             PipelineType      `json:"pipelineType,omitempty"`
 	ScanEnabled              bool              `json:"scanEnabled,notnull"`
 	AppWorkflowId            int               `json:"appWorkflowId,omitempty"`
+	CronSchedule             string            `json:"cronSchedule,omitempty"`  //standard 5 field cron, builds latest commit of each material on schedule
+	SkipUnchanged            bool              `json:"skipUnchanged,omitempty"` //skip scheduled build if commits are same as last successful build
+	SbomEnabled              bool              `json:"sbomEnabled,omitempty"`   //generate sbom for built images, also generated when scan is enabled
 }
 
 type CiPipelineMin struct {
@@ -254,11 +259,21 @@ type TestExecutorImageProperties struct {
 
 type DockerBuildConfig struct {
 	GitMaterialId  int               `json:"gitMaterialId,omitempty" validate:"required"`
-	DockerfilePath string            `json:"dockerfileRelativePath,omitempty" validate:"required"`
-	Args           map[string]string `json:"args,omitempty"`
+	DockerfilePath string            `json:"dockerfileRelativePath,omitempty"` //required for DOCKERFILE builds
+	Args           map[string]string `json:"args,omitempty"`                   //build args, passed as build time env for BUILDPACK builds
+	TargetPlatform string            `json:"targetPlatform,omitempty"`         //comma separated, e.g. linux/amd64,linux/arm64 builds a manifest list
+	BuildType      CiBuildType       `json:"buildType,omitempty"`              //DOCKERFILE when empty
+	BuilderImage   string            `json:"builderImage,omitempty"`           //cnb builder image, required for BUILDPACK builds
 	//Name Tag DockerfilePath RepoUrl
 }
 
+type CiBuildType string
+
+const (
+	DOCKERFILE_BUILD_TYPE CiBuildType = "DOCKERFILE"
+	BUILDPACK_BUILD_TYPE  CiBuildType = "BUILDPACK" //cloud native buildpacks, no dockerfile needed
+)
+
 type PipelineCreateResponse struct {
 	AppName string `json:"appName,omitempty"`
 	AppId   int    `json:"appId,omitempty"`
@@ -434,9 +449,34 @@ type CDPipelineConfigObject struct {
 	CdArgoSetup                   bool                              `json:"isClusterCdActive"`
 	ParentPipelineId              int                               `json:"parentPipelineId"`
 	ParentPipelineType            string                            `json:"parentPipelineType"`
+	ApprovalConfig                *DeploymentApprovalConfig         `json:"approvalConfig,omitempty"`
+	AutoRollbackConfig            *DeploymentAutoRollbackConfig     `json:"autoRollbackConfig,omitempty"`
+	CanaryAnalysisConfig          *CanaryAnalysisConfig             `json:"canaryAnalysisConfig,omitempty"`
 	//Downstream         []int                             `json:"downstream"` //PipelineCounter of downstream	(for future reference only)
 }
 
+type DeploymentApprovalConfig struct {
+	RequiredCount  int      `json:"requiredCount" validate:"number,min=1"`
+	ApproverEmails []string `json:"approverEmails"`
+	ApproverGroups []string `json:"approverGroups"`
+}
+
+type DeploymentAutoRollbackConfig struct {
+	DegradedTimeoutMinutes int `json:"degradedTimeoutMinutes" validate:"number,min=1"` //rollback when latest release stays Degraded for this long
+}
+
+// CanaryAnalysisConfig holds the metric thresholds checked whenever a CANARY rollout pauses at a step
+type CanaryAnalysisConfig struct {
+	Metrics []*CanaryAnalysisMetric `json:"metrics" validate:"min=1,dive"`
+}
+
+type CanaryAnalysisMetric struct {
+	Name      string  `json:"name" validate:"required"`
+	Query     string  `json:"query" validate:"required"` //promQL returning a single value, {{namespace}} and {{appName}} are substituted
+	Operator  string  `json:"operator" validate:"oneof=< <= > >="`
+	Threshold float64 `json:"threshold"`
+}
+
 type PreStageConfigMapSecretNames struct {
 	ConfigMaps []string `json:"configMaps"`
 	Secrets    []string `json:"secrets"`
@@ -0,0 +1,420 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package configHistory
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/configHistory/repository"
+	repository2 "github.com/devtron-labs/devtron/pkg/user/repository"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	ConfigTypeDeploymentTemplate = "DEPLOYMENT_TEMPLATE"
+	ConfigTypeConfigMap          = "CONFIGMAP"
+	ConfigTypeSecret             = "SECRET"
+
+	DiffAdded    = "ADDED"
+	DiffRemoved  = "REMOVED"
+	DiffModified = "MODIFIED"
+
+	maskedValue = "********"
+)
+
+// values under these keys of a secret are never returned, only the fact that they changed
+var secretValueKeys = map[string]bool{"data": true, "defaultData": true, "secretData": true, "defaultSecretData": true}
+
+type ConfigHistoryRequest struct {
+	AppId      int
+	EnvId      int
+	ConfigType string
+	// Data is the json stored in the config row, values override for templates and the config list for cm/cs
+	Data    string
+	Comment string
+	UserId  int32
+}
+
+type ConfigHistoryDto struct {
+	Id                 int             `json:"id"`
+	AppId              int             `json:"appId"`
+	EnvId              int             `json:"envId,omitempty"`
+	ConfigType         string          `json:"configType"`
+	Version            int             `json:"version"`
+	Comment            string          `json:"comment,omitempty"`
+	PipelineOverrideId int             `json:"pipelineOverrideId,omitempty"`
+	Deployed           bool            `json:"deployed"`
+	CreatedOn          time.Time       `json:"createdOn"`
+	CreatedBy          int32           `json:"createdBy"`
+	EmailId            string          `json:"emailId"`
+	Data               json.RawMessage `json:"data,omitempty"`
+}
+
+type ConfigDiff struct {
+	Path       string `json:"path"`
+	ChangeType string `json:"changeType"`
+	Previous   string `json:"previous,omitempty"`
+	Current    string `json:"current,omitempty"`
+}
+
+type ConfigHistoryDiff struct {
+	From    *ConfigHistoryDto `json:"from"`
+	To      *ConfigHistoryDto `json:"to"`
+	Changes []*ConfigDiff     `json:"changes"`
+}
+
+type ConfigHistoryService interface {
+	// SaveHistory never fails the calling save, errors are only logged
+	SaveHistory(request *ConfigHistoryRequest)
+	LinkPipelineOverride(appId int, envId int, pipelineOverrideId int) error
+	GetHistory(appId int, envId int, configType string) ([]*ConfigHistoryDto, error)
+	// GetVersion masks secret values, GetVersionForRestore returns the stored data as is
+	GetVersion(id int) (*ConfigHistoryDto, error)
+	GetVersionForRestore(id int) (*repository.ConfigHistory, error)
+	GetDiff(fromId int, toId int) (*ConfigHistoryDiff, error)
+}
+
+type ConfigHistoryServiceImpl struct {
+	logger                  *zap.SugaredLogger
+	configHistoryRepository repository.ConfigHistoryRepository
+	userRepository          repository2.UserRepository
+}
+
+func NewConfigHistoryServiceImpl(logger *zap.SugaredLogger, configHistoryRepository repository.ConfigHistoryRepository,
+	userRepository repository2.UserRepository) *ConfigHistoryServiceImpl {
+	return &ConfigHistoryServiceImpl{
+		logger:                  logger,
+		configHistoryRepository: configHistoryRepository,
+		userRepository:          userRepository,
+	}
+}
+
+func (impl ConfigHistoryServiceImpl) SaveHistory(request *ConfigHistoryRequest) {
+	version := 1
+	latest, err := impl.configHistoryRepository.FindLatest(request.AppId, request.EnvId, request.ConfigType)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching latest config history", "appId", request.AppId, "envId", request.EnvId, "configType", request.ConfigType, "err", err)
+		return
+	} else if err == nil {
+		if latest.Data == request.Data {
+			// the row holds both config maps and secrets, saving one leaves the other unchanged
+			return
+		}
+		version = latest.Version + 1
+	} else if len(request.Data) == 0 {
+		return
+	}
+	history := &repository.ConfigHistory{
+		AppId:      request.AppId,
+		EnvId:      request.EnvId,
+		ConfigType: request.ConfigType,
+		Version:    version,
+		Data:       request.Data,
+		Comment:    request.Comment,
+		CreatedOn:  time.Now(),
+		CreatedBy:  request.UserId,
+	}
+	err = impl.configHistoryRepository.Save(history)
+	if err != nil {
+		impl.logger.Errorw("error in saving config history", "appId", request.AppId, "envId", request.EnvId, "configType", request.ConfigType, "version", version, "err", err)
+	}
+}
+
+func (impl ConfigHistoryServiceImpl) LinkPipelineOverride(appId int, envId int, pipelineOverrideId int) error {
+	linked, err := impl.configHistoryRepository.LinkPipelineOverride(appId, envId, pipelineOverrideId)
+	if err != nil {
+		impl.logger.Errorw("error in linking config history to pipeline override", "appId", appId, "envId", envId, "pipelineOverrideId", pipelineOverrideId, "err", err)
+		return err
+	}
+	impl.logger.Debugw("config history linked to pipeline override", "pipelineOverrideId", pipelineOverrideId, "versions", linked)
+	return nil
+}
+
+func (impl ConfigHistoryServiceImpl) GetHistory(appId int, envId int, configType string) ([]*ConfigHistoryDto, error) {
+	histories, err := impl.configHistoryRepository.FindAll(appId, envId, configType)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching config history", "appId", appId, "envId", envId, "configType", configType, "err", err)
+		return nil, err
+	}
+	emails := impl.getUserEmails(histories)
+	dtos := make([]*ConfigHistoryDto, 0)
+	for _, history := range histories {
+		dto := adaptConfigHistory(history)
+		dto.EmailId = emails[history.CreatedBy]
+		dtos = append(dtos, dto)
+	}
+	return dtos, nil
+}
+
+func (impl ConfigHistoryServiceImpl) GetVersion(id int) (*ConfigHistoryDto, error) {
+	history, err := impl.GetVersionForRestore(id)
+	if err != nil {
+		return nil, err
+	}
+	dto := adaptConfigHistory(history)
+	dto.EmailId = impl.getUserEmails([]*repository.ConfigHistory{history})[history.CreatedBy]
+	value, err := parseConfig(history)
+	if err != nil {
+		impl.logger.Errorw("error in parsing config history", "id", id, "err", err)
+		return nil, err
+	}
+	if secrets, ok := value.(map[string]interface{}); ok && history.ConfigType == ConfigTypeSecret {
+		for name, secret := range secrets {
+			secrets[name] = maskSecretValues(secret, false)
+		}
+	}
+	dto.Data, err = json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return dto, nil
+}
+
+func (impl ConfigHistoryServiceImpl) GetVersionForRestore(id int) (*repository.ConfigHistory, error) {
+	history, err := impl.configHistoryRepository.FindById(id)
+	if err == pg.ErrNoRows {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusNotFound, UserMessage: fmt.Sprintf("config version %d not found", id)}
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching config history", "id", id, "err", err)
+		return nil, err
+	}
+	return history, nil
+}
+
+// GetDiff compares two versions of the same config, paths of config maps and secrets are keyed by their name
+func (impl ConfigHistoryServiceImpl) GetDiff(fromId int, toId int) (*ConfigHistoryDiff, error) {
+	from, err := impl.GetVersionForRestore(fromId)
+	if err != nil {
+		return nil, err
+	}
+	to, err := impl.GetVersionForRestore(toId)
+	if err != nil {
+		return nil, err
+	}
+	if from.AppId != to.AppId || from.EnvId != to.EnvId || from.ConfigType != to.ConfigType {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "versions belong to different configs and can not be compared"}
+	}
+	fromValue, err := parseConfig(from)
+	if err != nil {
+		impl.logger.Errorw("error in parsing config history", "id", fromId, "err", err)
+		return nil, err
+	}
+	toValue, err := parseConfig(to)
+	if err != nil {
+		impl.logger.Errorw("error in parsing config history", "id", toId, "err", err)
+		return nil, err
+	}
+	previous := make(map[string]string)
+	current := make(map[string]string)
+	secretPaths := make(map[string]bool)
+	if from.ConfigType == ConfigTypeSecret {
+		flattenSecrets(fromValue, previous, secretPaths)
+		flattenSecrets(toValue, current, secretPaths)
+	} else {
+		flatten(fromValue, "", previous)
+		flatten(toValue, "", current)
+	}
+
+	changes := make([]*ConfigDiff, 0)
+	for path, previousValue := range previous {
+		currentValue, ok := current[path]
+		if !ok {
+			changes = append(changes, &ConfigDiff{Path: path, ChangeType: DiffRemoved, Previous: previousValue})
+		} else if currentValue != previousValue {
+			changes = append(changes, &ConfigDiff{Path: path, ChangeType: DiffModified, Previous: previousValue, Current: currentValue})
+		}
+	}
+	for path, currentValue := range current {
+		if _, ok := previous[path]; !ok {
+			changes = append(changes, &ConfigDiff{Path: path, ChangeType: DiffAdded, Current: currentValue})
+		}
+	}
+	for _, change := range changes {
+		if secretPaths[change.Path] {
+			if len(change.Previous) > 0 {
+				change.Previous = maskedValue
+			}
+			if len(change.Current) > 0 {
+				change.Current = maskedValue
+			}
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	emails := impl.getUserEmails([]*repository.ConfigHistory{from, to})
+	diff := &ConfigHistoryDiff{
+		From:    adaptConfigHistory(from),
+		To:      adaptConfigHistory(to),
+		Changes: changes,
+	}
+	diff.From.EmailId = emails[from.CreatedBy]
+	diff.To.EmailId = emails[to.CreatedBy]
+	return diff, nil
+}
+
+func (impl ConfigHistoryServiceImpl) getUserEmails(histories []*repository.ConfigHistory) map[int32]string {
+	emails := make(map[int32]string)
+	var userIds []int32
+	for _, history := range histories {
+		if _, ok := emails[history.CreatedBy]; !ok {
+			emails[history.CreatedBy] = ""
+			userIds = append(userIds, history.CreatedBy)
+		}
+	}
+	if len(userIds) == 0 {
+		return emails
+	}
+	users, err := impl.userRepository.GetByIds(userIds)
+	if err != nil {
+		impl.logger.Warnw("error in fetching users for config history", "userIds", userIds, "err", err)
+		return emails
+	}
+	for _, user := range users {
+		emails[user.Id] = user.EmailId
+	}
+	return emails
+}
+
+func adaptConfigHistory(history *repository.ConfigHistory) *ConfigHistoryDto {
+	return &ConfigHistoryDto{
+		Id:                 history.Id,
+		AppId:              history.AppId,
+		EnvId:              history.EnvId,
+		ConfigType:         history.ConfigType,
+		Version:            history.Version,
+		Comment:            history.Comment,
+		PipelineOverrideId: history.PipelineOverrideId,
+		Deployed:           history.PipelineOverrideId > 0,
+		CreatedOn:          history.CreatedOn,
+		CreatedBy:          history.CreatedBy,
+	}
+}
+
+// parseConfig returns the stored json, config map and secret lists are converted to a map keyed by name
+func parseConfig(history *repository.ConfigHistory) (interface{}, error) {
+	if len(history.Data) == 0 {
+		return nil, nil
+	}
+	var value interface{}
+	err := json.Unmarshal([]byte(history.Data), &value)
+	if err != nil {
+		return nil, err
+	}
+	if history.ConfigType == ConfigTypeDeploymentTemplate {
+		return value, nil
+	}
+	configsList, ok := value.(map[string]interface{})
+	if !ok {
+		return value, nil
+	}
+	configs, ok := configsList["maps"].([]interface{})
+	if !ok {
+		configs, _ = configsList["secrets"].([]interface{})
+	}
+	byName := make(map[string]interface{})
+	for _, config := range configs {
+		if item, ok := config.(map[string]interface{}); ok {
+			byName[fmt.Sprintf("%v", item["name"])] = item
+		}
+	}
+	return byName, nil
+}
+
+func flatten(value interface{}, path string, flattened map[string]string) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := key
+			if len(path) > 0 {
+				childPath = path + "." + key
+			}
+			flatten(child, childPath, flattened)
+		}
+	case []interface{}:
+		for i, child := range v {
+			flatten(child, fmt.Sprintf("%s[%d]", path, i), flattened)
+		}
+	case nil:
+		return
+	case string:
+		flattened[path] = v
+	default:
+		flattened[path] = fmt.Sprintf("%v", v)
+	}
+}
+
+// flattenSecrets flattens secrets keyed by name and collects the paths of values under the secret value keys,
+// the structure is walked instead of parsing paths back as secret names and keys may contain dots
+func flattenSecrets(value interface{}, flattened map[string]string, secretPaths map[string]bool) {
+	secrets, ok := value.(map[string]interface{})
+	if !ok {
+		flatten(value, "", flattened)
+		return
+	}
+	for name, secret := range secrets {
+		fields, ok := secret.(map[string]interface{})
+		if !ok {
+			flatten(secret, name, flattened)
+			continue
+		}
+		for key, field := range fields {
+			path := name + "." + key
+			if !secretValueKeys[key] {
+				flatten(field, path, flattened)
+				continue
+			}
+			values := make(map[string]string)
+			flatten(field, path, values)
+			for valuePath, v := range values {
+				flattened[valuePath] = v
+				secretPaths[valuePath] = true
+			}
+		}
+	}
+}
+
+// maskSecretValues replaces every value under the secret value keys of a single secret
+func maskSecretValues(value interface{}, mask bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		masked := make(map[string]interface{})
+		for key, child := range v {
+			masked[key] = maskSecretValues(child, mask || secretValueKeys[key])
+		}
+		return masked
+	case []interface{}:
+		masked := make([]interface{}, 0)
+		for _, child := range v {
+			masked = append(masked, maskSecretValues(child, mask))
+		}
+		return masked
+	case nil:
+		return nil
+	default:
+		if mask {
+			return maskedValue
+		}
+		return v
+	}
+}
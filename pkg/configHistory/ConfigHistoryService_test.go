@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package configHistory
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFlattenSecrets(t *testing.T) {
+	tests := []struct {
+		name            string
+		secrets         string
+		wantFlattened   map[string]string
+		wantSecretPaths map[string]bool
+	}{
+		{
+			name:            "data values are secret, other fields are not",
+			secrets:         `{"db-secret":{"name":"db-secret","type":"environment","data":{"password":"p1"}}}`,
+			wantFlattened:   map[string]string{"db-secret.name": "db-secret", "db-secret.type": "environment", "db-secret.data.password": "p1"},
+			wantSecretPaths: map[string]bool{"db-secret.data.password": true},
+		},
+		{
+			name:            "secret name with dots",
+			secrets:         `{"db.prod.secret":{"external":false,"defaultData":{"tls.key":"k1"}}}`,
+			wantFlattened:   map[string]string{"db.prod.secret.external": "false", "db.prod.secret.defaultData.tls.key": "k1"},
+			wantSecretPaths: map[string]bool{"db.prod.secret.defaultData.tls.key": true},
+		},
+		{
+			name:            "secret named like a value key",
+			secrets:         `{"data":{"mountPath":"/etc/data","secretData":[{"key":"a","name":"b"}]}}`,
+			wantFlattened:   map[string]string{"data.mountPath": "/etc/data", "data.secretData[0].key": "a", "data.secretData[0].name": "b"},
+			wantSecretPaths: map[string]bool{"data.secretData[0].key": true, "data.secretData[0].name": true},
+		},
+		{
+			name:            "data stored as a plain value",
+			secrets:         `{"raw":{"data":"c2VjcmV0"}}`,
+			wantFlattened:   map[string]string{"raw.data": "c2VjcmV0"},
+			wantSecretPaths: map[string]bool{"raw.data": true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var secrets interface{}
+			if err := json.Unmarshal([]byte(tt.secrets), &secrets); err != nil {
+				t.Fatal(err)
+			}
+			flattened := make(map[string]string)
+			secretPaths := make(map[string]bool)
+			flattenSecrets(secrets, flattened, secretPaths)
+			if !reflect.DeepEqual(flattened, tt.wantFlattened) {
+				t.Errorf("flattenSecrets() flattened = %v, want %v", flattened, tt.wantFlattened)
+			}
+			if !reflect.DeepEqual(secretPaths, tt.wantSecretPaths) {
+				t.Errorf("flattenSecrets() secretPaths = %v, want %v", secretPaths, tt.wantSecretPaths)
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package repository
+
+import (
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ConfigHistory is an immutable snapshot of a deployment template, config map or secret list.
+// EnvId is 0 (stored as null) for app level configs
+type ConfigHistory struct {
+	tableName          struct{}  `sql:"config_history" pg:",discard_unknown_columns"`
+	Id                 int       `sql:"id,pk"`
+	AppId              int       `sql:"app_id,notnull"`
+	EnvId              int       `sql:"env_id"`
+	ConfigType         string    `sql:"config_type,notnull"`
+	Version            int       `sql:"version,notnull"`
+	Data               string    `sql:"data"`
+	Comment            string    `sql:"comment"`
+	PipelineOverrideId int       `sql:"pipeline_override_id"`
+	CreatedOn          time.Time `sql:"created_on,notnull"`
+	CreatedBy          int32     `sql:"created_by,notnull"`
+}
+
+type ConfigHistoryRepository interface {
+	Save(history *ConfigHistory) error
+	FindById(id int) (*ConfigHistory, error)
+	FindLatest(appId int, envId int, configType string) (*ConfigHistory, error)
+	// FindAll skips the data column, versions are fetched one by one for diff
+	FindAll(appId int, envId int, configType string) ([]*ConfigHistory, error)
+	LinkPipelineOverride(appId int, envId int, pipelineOverrideId int) (int, error)
+}
+
+type ConfigHistoryRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewConfigHistoryRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *ConfigHistoryRepositoryImpl {
+	return &ConfigHistoryRepositoryImpl{
+		dbConnection: dbConnection,
+		logger:       logger,
+	}
+}
+
+func (impl ConfigHistoryRepositoryImpl) Save(history *ConfigHistory) error {
+	return impl.dbConnection.Insert(history)
+}
+
+func (impl ConfigHistoryRepositoryImpl) FindById(id int) (*ConfigHistory, error) {
+	history := &ConfigHistory{}
+	err := impl.dbConnection.Model(history).Where("id = ?", id).Select()
+	return history, err
+}
+
+func (impl ConfigHistoryRepositoryImpl) FindLatest(appId int, envId int, configType string) (*ConfigHistory, error) {
+	history := &ConfigHistory{}
+	query := impl.dbConnection.Model(history).
+		Where("app_id = ?", appId).
+		Where("config_type = ?", configType)
+	if envId > 0 {
+		query = query.Where("env_id = ?", envId)
+	} else {
+		query = query.Where("env_id is null")
+	}
+	err := query.Order("version DESC").Limit(1).Select()
+	return history, err
+}
+
+func (impl ConfigHistoryRepositoryImpl) FindAll(appId int, envId int, configType string) ([]*ConfigHistory, error) {
+	var histories []*ConfigHistory
+	query := impl.dbConnection.Model(&histories).
+		Column("id", "app_id", "env_id", "config_type", "version", "comment", "pipeline_override_id", "created_on", "created_by").
+		Where("app_id = ?", appId).
+		Where("config_type = ?", configType)
+	if envId > 0 {
+		query = query.Where("env_id = ?", envId)
+	} else {
+		query = query.Where("env_id is null")
+	}
+	err := query.Order("version DESC").Select()
+	return histories, err
+}
+
+// LinkPipelineOverride marks the latest app and env level versions of every config type as first deployed by the override,
+// versions which were already deployed keep their original override
+func (impl ConfigHistoryRepositoryImpl) LinkPipelineOverride(appId int, envId int, pipelineOverrideId int) (int, error) {
+	query := "UPDATE config_history SET pipeline_override_id = ? WHERE pipeline_override_id IS NULL AND id IN (" +
+		" SELECT MAX(id) FROM config_history WHERE app_id = ? AND (env_id = ? OR env_id IS NULL) GROUP BY config_type, env_id)"
+	res, err := impl.dbConnection.Exec(query, pipelineOverrideId, appId, envId)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected(), nil
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/devtron-labs/devtron/internal/sql/repository/app"
 	repository2 "github.com/devtron-labs/devtron/pkg/cluster/repository"
+	"net/http"
 	"strings"
 	"time"
 
@@ -495,10 +496,21 @@ func (impl *DeploymentGroupServiceImpl) TriggerReleaseForDeploymentGroup(trigger
 	}
 	//trigger
 	// apply mapping
-	_, err = impl.workflowDagExecutor.TriggerBulkDeploymentAsync(requests, triggerRequest.UserId)
+	res, err := impl.workflowDagExecutor.TriggerBulkDeploymentAsync(requests, triggerRequest.UserId)
 	if err != nil {
 		return nil, err
 	}
+	if blocked, ok := res.([]*pipeline.BulkTriggerBlockedResponse); ok && len(blocked) > 0 {
+		impl.logger.Infow("deployments blocked by deployment windows", "req", triggerRequest, "blocked", blocked)
+		if len(blocked) == len(requests) {
+			return nil, &util.ApiError{
+				HttpStatusCode:  http.StatusForbidden,
+				InternalMessage: blocked[0].Reason,
+				UserMessage:     blocked[0].Reason,
+			}
+		}
+		return blocked, nil
+	}
 	return nil, nil
 }
 
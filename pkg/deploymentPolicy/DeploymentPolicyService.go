@@ -0,0 +1,372 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package deploymentPolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/pkg/cluster/repository"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+type DeploymentPolicyRequest struct {
+	ClusterId int             `json:"clusterId,omitempty"`
+	EnvId     int             `json:"envId,omitempty"`
+	AppId     int             `json:"appId,omitempty"`
+	Rule      string          `json:"rule" validate:"required"`
+	Action    string          `json:"action" validate:"oneof=inherit disable warn block"`
+	Config    json.RawMessage `json:"config,omitempty"`
+}
+
+type DeploymentPolicyDto struct {
+	Id           int             `json:"id,omitempty"`
+	Rule         string          `json:"rule"`
+	Description  string          `json:"description"`
+	Action       string          `json:"action"`
+	Config       json.RawMessage `json:"config,omitempty"`
+	Inherited    bool            `json:"inherited"`
+	IsOverridden bool            `json:"isOverridden"`
+	PolicyOrigin string          `json:"policyOrigin,omitempty"`
+}
+
+type DeploymentPolicyResult struct {
+	Level     string                 `json:"level"`
+	ClusterId int                    `json:"clusterId,omitempty"`
+	EnvId     int                    `json:"envId,omitempty"`
+	AppId     int                    `json:"appId,omitempty"`
+	Policies  []*DeploymentPolicyDto `json:"policies"`
+}
+
+type PolicyViolation struct {
+	Rule         string   `json:"rule"`
+	Action       string   `json:"action"`
+	PolicyOrigin string   `json:"policyOrigin"`
+	Messages     []string `json:"messages"`
+}
+
+type ManifestEvaluationResult struct {
+	Blocked    bool               `json:"blocked"`
+	Violations []*PolicyViolation `json:"violations"`
+}
+
+type PolicyViolationDto struct {
+	Id                 int       `json:"id"`
+	PipelineId         int       `json:"pipelineId"`
+	PipelineOverrideId int       `json:"pipelineOverrideId"`
+	Rule               string    `json:"rule"`
+	Action             string    `json:"action"`
+	PolicyOrigin       string    `json:"policyOrigin"`
+	Message            string    `json:"message"`
+	CreatedOn          time.Time `json:"createdOn"`
+	CreatedBy          int32     `json:"createdBy"`
+}
+
+type DeploymentPolicyService interface {
+	RegisterRule(rule ManifestPolicyRule)
+	SavePolicy(request *DeploymentPolicyRequest, userId int32) (*DeploymentPolicyDto, error)
+	GetPolicies(policyLevel security.PolicyLevel, clusterId, environmentId, appId int) (*DeploymentPolicyResult, error)
+	// EvaluateManifests checks the rendered release against the policies applicable for the app+env,
+	// renderManifests is only called when some policy applies
+	EvaluateManifests(clusterId, environmentId, appId int, renderManifests func() ([]map[string]interface{}, error)) (*ManifestEvaluationResult, error)
+	SaveViolations(pipelineId int, pipelineOverrideId int, violations []*PolicyViolation, userId int32) error
+	GetViolations(pipelineId int, offset int, size int) ([]*PolicyViolationDto, error)
+}
+
+type DeploymentPolicyServiceImpl struct {
+	logger                     *zap.SugaredLogger
+	deploymentPolicyRepository security.DeploymentPolicyRepository
+	environmentRepository      repository.EnvironmentRepository
+	rules                      map[string]ManifestPolicyRule
+	ruleNames                  []string
+}
+
+func NewDeploymentPolicyServiceImpl(logger *zap.SugaredLogger,
+	deploymentPolicyRepository security.DeploymentPolicyRepository,
+	environmentRepository repository.EnvironmentRepository) *DeploymentPolicyServiceImpl {
+	impl := &DeploymentPolicyServiceImpl{
+		logger:                     logger,
+		deploymentPolicyRepository: deploymentPolicyRepository,
+		environmentRepository:      environmentRepository,
+		rules:                      make(map[string]ManifestPolicyRule),
+	}
+	impl.RegisterRule(noLatestTagRule{})
+	impl.RegisterRule(requireResourceLimitsRule{})
+	impl.RegisterRule(noPrivilegedContainerRule{})
+	impl.RegisterRule(allowedRegistriesRule{})
+	return impl
+}
+
+func (impl *DeploymentPolicyServiceImpl) RegisterRule(rule ManifestPolicyRule) {
+	if _, ok := impl.rules[rule.Name()]; !ok {
+		impl.ruleNames = append(impl.ruleNames, rule.Name())
+	}
+	impl.rules[rule.Name()] = rule
+}
+
+/*
+global: na
+cluster: clusterId
+environment: envId
+application: appId, envId
+
+action inherit removes the policy defined at the requested level
+*/
+func (impl *DeploymentPolicyServiceImpl) SavePolicy(request *DeploymentPolicyRequest, userId int32) (*DeploymentPolicyDto, error) {
+	rule, ok := impl.rules[request.Rule]
+	if !ok {
+		return nil, fmt.Errorf("unsupported rule %s", request.Rule)
+	}
+	if request.AppId > 0 && request.EnvId == 0 {
+		return nil, fmt.Errorf("envId is required for application level policy")
+	}
+	action := security.DeploymentPolicyInherit.ValuesOf(request.Action)
+	if action != security.DeploymentPolicyInherit && action != security.DeploymentPolicyDisable {
+		if err := rule.ValidateConfig(request.Config); err != nil {
+			return nil, err
+		}
+	}
+	clusterId := request.ClusterId
+	if request.EnvId > 0 {
+		//cluster is implied by environment, policies are stored at the most specific level only
+		clusterId = 0
+	}
+	policies, err := impl.deploymentPolicyRepository.GetApplicablePolicies(clusterId, request.EnvId, request.AppId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment policies", "request", request, "err", err)
+		return nil, err
+	}
+	isGlobal := clusterId == 0 && request.EnvId == 0 && request.AppId == 0
+	var policy *security.DeploymentPolicy
+	for _, p := range policies {
+		if p.Rule == request.Rule && p.Global == isGlobal && p.ClusterId == clusterId && p.EnvironmentId == request.EnvId && p.AppId == request.AppId {
+			policy = p
+			break
+		}
+	}
+
+	if action == security.DeploymentPolicyInherit {
+		if policy == nil {
+			return &DeploymentPolicyDto{Rule: request.Rule, Action: action.String(), Inherited: true}, nil
+		}
+		policy.Deleted = true
+		policy.UpdatedOn = time.Now()
+		policy.UpdatedBy = userId
+		if _, err = impl.deploymentPolicyRepository.UpdatePolicy(policy); err != nil {
+			impl.logger.Errorw("error in deleting deployment policy", "id", policy.Id, "err", err)
+			return nil, err
+		}
+		return &DeploymentPolicyDto{Id: policy.Id, Rule: request.Rule, Action: action.String(), Inherited: true}, nil
+	}
+
+	if policy == nil {
+		policy = &security.DeploymentPolicy{
+			Global:        isGlobal,
+			ClusterId:     clusterId,
+			EnvironmentId: request.EnvId,
+			AppId:         request.AppId,
+			Rule:          request.Rule,
+			Action:        action,
+			Config:        string(request.Config),
+			AuditLog: sql.AuditLog{
+				CreatedOn: time.Now(),
+				CreatedBy: userId,
+				UpdatedOn: time.Now(),
+				UpdatedBy: userId,
+			},
+		}
+		policy, err = impl.deploymentPolicyRepository.SavePolicy(policy)
+	} else {
+		policy.Action = action
+		policy.Config = string(request.Config)
+		policy.UpdatedOn = time.Now()
+		policy.UpdatedBy = userId
+		policy, err = impl.deploymentPolicyRepository.UpdatePolicy(policy)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving deployment policy", "request", request, "err", err)
+		return nil, fmt.Errorf("error in saving deployment policy")
+	}
+	return impl.buildPolicyDto(rule, policy, policy.PolicyLevel()), nil
+}
+
+func (impl *DeploymentPolicyServiceImpl) GetPolicies(policyLevel security.PolicyLevel, clusterId, environmentId, appId int) (*DeploymentPolicyResult, error) {
+	if policyLevel == security.Environment || policyLevel == security.Application {
+		env, err := impl.environmentRepository.FindById(environmentId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching environment", "envId", environmentId, "err", err)
+			return nil, err
+		}
+		clusterId = env.ClusterId
+	}
+	if policyLevel != security.Application {
+		appId = 0
+	}
+	if policyLevel == security.Global || policyLevel == security.Cluster {
+		environmentId = 0
+	}
+	if policyLevel == security.Global {
+		clusterId = 0
+	}
+	effective, err := impl.getEffectivePolicies(clusterId, environmentId, appId)
+	if err != nil {
+		return nil, err
+	}
+	result := &DeploymentPolicyResult{
+		Level:     policyLevel.String(),
+		ClusterId: clusterId,
+		EnvId:     environmentId,
+		AppId:     appId,
+	}
+	for _, ruleName := range impl.ruleNames {
+		rule := impl.rules[ruleName]
+		policy, ok := effective[ruleName]
+		if !ok {
+			result.Policies = append(result.Policies, &DeploymentPolicyDto{
+				Rule:        ruleName,
+				Description: rule.Description(),
+				Action:      security.DeploymentPolicyDisable.String(),
+				Inherited:   true,
+			})
+			continue
+		}
+		result.Policies = append(result.Policies, impl.buildPolicyDto(rule, policy, policyLevel))
+	}
+	return result, nil
+}
+
+func (impl *DeploymentPolicyServiceImpl) buildPolicyDto(rule ManifestPolicyRule, policy *security.DeploymentPolicy, policyLevel security.PolicyLevel) *DeploymentPolicyDto {
+	dto := &DeploymentPolicyDto{
+		Id:           policy.Id,
+		Rule:         policy.Rule,
+		Description:  rule.Description(),
+		Action:       policy.Action.String(),
+		PolicyOrigin: policy.PolicyLevel().String(),
+	}
+	if len(policy.Config) > 0 {
+		dto.Config = json.RawMessage(policy.Config)
+	}
+	if policy.PolicyLevel() != policyLevel {
+		dto.Inherited = true
+	} else {
+		dto.IsOverridden = policyLevel != security.Global
+	}
+	return dto
+}
+
+// getEffectivePolicies resolves one policy per rule, the most specific level wins
+func (impl *DeploymentPolicyServiceImpl) getEffectivePolicies(clusterId, environmentId, appId int) (map[string]*security.DeploymentPolicy, error) {
+	policies, err := impl.deploymentPolicyRepository.GetApplicablePolicies(clusterId, environmentId, appId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment policies", "clusterId", clusterId, "envId", environmentId, "appId", appId, "err", err)
+		return nil, err
+	}
+	effective := make(map[string]*security.DeploymentPolicy)
+	for _, policy := range policies {
+		if _, ok := impl.rules[policy.Rule]; !ok {
+			continue
+		}
+		if existing, ok := effective[policy.Rule]; ok && existing.PolicyLevel() > policy.PolicyLevel() {
+			continue
+		}
+		effective[policy.Rule] = policy
+	}
+	return effective, nil
+}
+
+func (impl *DeploymentPolicyServiceImpl) EvaluateManifests(clusterId, environmentId, appId int, renderManifests func() ([]map[string]interface{}, error)) (*ManifestEvaluationResult, error) {
+	result := &ManifestEvaluationResult{Violations: []*PolicyViolation{}}
+	effective, err := impl.getEffectivePolicies(clusterId, environmentId, appId)
+	if err != nil {
+		return nil, err
+	}
+	if len(effective) == 0 {
+		return result, nil
+	}
+	manifests, err := renderManifests()
+	if err != nil {
+		impl.logger.Errorw("error in rendering manifests for policy evaluation", "appId", appId, "environmentId", environmentId, "err", err)
+		return nil, err
+	}
+	for _, ruleName := range impl.ruleNames {
+		policy, ok := effective[ruleName]
+		if !ok || (policy.Action != security.DeploymentPolicyWarn && policy.Action != security.DeploymentPolicyBlock) {
+			continue
+		}
+		messages := impl.rules[ruleName].Evaluate(manifests, json.RawMessage(policy.Config))
+		if len(messages) == 0 {
+			continue
+		}
+		result.Violations = append(result.Violations, &PolicyViolation{
+			Rule:         ruleName,
+			Action:       policy.Action.String(),
+			PolicyOrigin: policy.PolicyLevel().String(),
+			Messages:     messages,
+		})
+		if policy.Action == security.DeploymentPolicyBlock {
+			result.Blocked = true
+		}
+	}
+	return result, nil
+}
+
+func (impl *DeploymentPolicyServiceImpl) SaveViolations(pipelineId int, pipelineOverrideId int, violations []*PolicyViolation, userId int32) error {
+	var models []*security.DeploymentPolicyViolation
+	for _, violation := range violations {
+		models = append(models, &security.DeploymentPolicyViolation{
+			PipelineId:         pipelineId,
+			PipelineOverrideId: pipelineOverrideId,
+			Rule:               violation.Rule,
+			Action:             security.DeploymentPolicyInherit.ValuesOf(violation.Action),
+			PolicyOrigin:       violation.PolicyOrigin,
+			Message:            strings.Join(violation.Messages, "\n"),
+			AuditLog:           sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+		})
+	}
+	err := impl.deploymentPolicyRepository.SaveViolations(models)
+	if err != nil {
+		impl.logger.Errorw("error in saving deployment policy violations", "pipelineId", pipelineId, "err", err)
+	}
+	return err
+}
+
+func (impl *DeploymentPolicyServiceImpl) GetViolations(pipelineId int, offset int, size int) ([]*PolicyViolationDto, error) {
+	violations, err := impl.deploymentPolicyRepository.FindViolationsByPipelineId(pipelineId, offset, size)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment policy violations", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	dtos := make([]*PolicyViolationDto, 0)
+	for _, violation := range violations {
+		dtos = append(dtos, &PolicyViolationDto{
+			Id:                 violation.Id,
+			PipelineId:         violation.PipelineId,
+			PipelineOverrideId: violation.PipelineOverrideId,
+			Rule:               violation.Rule,
+			Action:             violation.Action.String(),
+			PolicyOrigin:       violation.PolicyOrigin,
+			Message:            violation.Message,
+			CreatedOn:          violation.CreatedOn,
+			CreatedBy:          violation.CreatedBy,
+		})
+	}
+	return dtos, nil
+}
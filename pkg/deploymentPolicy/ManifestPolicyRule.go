@@ -0,0 +1,286 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package deploymentPolicy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	NoLatestTagRule           = "NO_LATEST_TAG"
+	RequireResourceLimitsRule = "REQUIRE_RESOURCE_LIMITS"
+	NoPrivilegedContainerRule = "NO_PRIVILEGED_CONTAINER"
+	AllowedRegistriesRule     = "ALLOWED_REGISTRIES"
+)
+
+// ManifestPolicyRule is evaluated against the kubernetes objects rendered from the chart templates with the final values.
+// New rules can be plugged in through DeploymentPolicyService.RegisterRule.
+type ManifestPolicyRule interface {
+	Name() string
+	Description() string
+	// ValidateConfig is called while saving a policy, config is the rule specific json sent by user
+	ValidateConfig(config json.RawMessage) error
+	// Evaluate returns one message per violation, empty when manifests are compliant
+	Evaluate(manifests []map[string]interface{}, config json.RawMessage) []string
+}
+
+//------------------ no latest tag
+
+type noLatestTagRule struct{}
+
+func (r noLatestTagRule) Name() string {
+	return NoLatestTagRule
+}
+
+func (r noLatestTagRule) Description() string {
+	return "images must be pinned to a tag other than latest or to a digest"
+}
+
+func (r noLatestTagRule) ValidateConfig(config json.RawMessage) error {
+	return nil
+}
+
+func (r noLatestTagRule) Evaluate(manifests []map[string]interface{}, config json.RawMessage) []string {
+	var messages []string
+	for _, container := range collectContainers(manifests) {
+		if strings.Contains(container.image, "@") {
+			continue
+		}
+		tag := imageTag(container.image)
+		if tag == "" {
+			messages = append(messages, fmt.Sprintf("%s: image %s has no tag, latest will be used", container, container.image))
+		} else if tag == "latest" {
+			messages = append(messages, fmt.Sprintf("%s: image %s uses latest tag", container, container.image))
+		}
+	}
+	return messages
+}
+
+//------------------ resource limits
+
+type requireResourceLimitsRule struct{}
+
+func (r requireResourceLimitsRule) Name() string {
+	return RequireResourceLimitsRule
+}
+
+func (r requireResourceLimitsRule) Description() string {
+	return "cpu and memory limits must be set for every container"
+}
+
+func (r requireResourceLimitsRule) ValidateConfig(config json.RawMessage) error {
+	return nil
+}
+
+func (r requireResourceLimitsRule) Evaluate(manifests []map[string]interface{}, config json.RawMessage) []string {
+	var messages []string
+	for _, container := range collectContainers(manifests) {
+		for _, missing := range missingLimits(container.spec["resources"]) {
+			messages = append(messages, fmt.Sprintf("%s: resources.limits.%s is not set", container, missing))
+		}
+	}
+	return messages
+}
+
+func missingLimits(resources interface{}) []string {
+	var limits map[string]interface{}
+	if resourceValues, ok := resources.(map[string]interface{}); ok {
+		limits, _ = resourceValues["limits"].(map[string]interface{})
+	}
+	var missing []string
+	for _, key := range []string{"cpu", "memory"} {
+		if limits == nil || limits[key] == nil || fmt.Sprintf("%v", limits[key]) == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+//------------------ privileged containers
+
+type noPrivilegedContainerRule struct{}
+
+func (r noPrivilegedContainerRule) Name() string {
+	return NoPrivilegedContainerRule
+}
+
+func (r noPrivilegedContainerRule) Description() string {
+	return "containers must not run in privileged mode"
+}
+
+func (r noPrivilegedContainerRule) ValidateConfig(config json.RawMessage) error {
+	return nil
+}
+
+func (r noPrivilegedContainerRule) Evaluate(manifests []map[string]interface{}, config json.RawMessage) []string {
+	var messages []string
+	for _, container := range collectContainers(manifests) {
+		securityContext, _ := container.spec["securityContext"].(map[string]interface{})
+		if securityContext != nil && securityContext["privileged"] == true {
+			messages = append(messages, fmt.Sprintf("%s: securityContext.privileged is true", container))
+		}
+	}
+	return messages
+}
+
+//------------------ allowed registries
+
+type allowedRegistriesRule struct{}
+
+type AllowedRegistriesConfig struct {
+	Registries []string `json:"registries"`
+}
+
+func (r allowedRegistriesRule) Name() string {
+	return AllowedRegistriesRule
+}
+
+func (r allowedRegistriesRule) Description() string {
+	return "images must be pulled from one of the configured registries"
+}
+
+func (r allowedRegistriesRule) ValidateConfig(config json.RawMessage) error {
+	registriesConfig := &AllowedRegistriesConfig{}
+	if len(config) > 0 {
+		if err := json.Unmarshal(config, registriesConfig); err != nil {
+			return fmt.Errorf("invalid config for %s: %s", AllowedRegistriesRule, err.Error())
+		}
+	}
+	if len(registriesConfig.Registries) == 0 {
+		return fmt.Errorf("at least one registry is required for %s", AllowedRegistriesRule)
+	}
+	return nil
+}
+
+func (r allowedRegistriesRule) Evaluate(manifests []map[string]interface{}, config json.RawMessage) []string {
+	registriesConfig := &AllowedRegistriesConfig{}
+	if len(config) > 0 {
+		_ = json.Unmarshal(config, registriesConfig)
+	}
+	var messages []string
+	for _, container := range collectContainers(manifests) {
+		name := normaliseImageName(container.image)
+		allowed := false
+		for _, registry := range registriesConfig.Registries {
+			registry = strings.TrimSuffix(strings.TrimSpace(registry), "/")
+			if len(registry) > 0 && strings.HasPrefix(name, registry+"/") {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			messages = append(messages, fmt.Sprintf("%s: image %s is not from an allowed registry", container, container.image))
+		}
+	}
+	return messages
+}
+
+//------------------ helpers
+
+// containerKeys are the pod spec fields holding containers, pod specs are nested differently per workload kind
+// (Deployment, Rollout, StatefulSet, CronJob..) so they are found by walking the whole object
+var containerKeys = map[string]bool{"containers": true, "initContainers": true, "ephemeralContainers": true}
+
+type manifestContainer struct {
+	kind     string
+	resource string
+	name     string
+	image    string
+	spec     map[string]interface{}
+}
+
+func (c manifestContainer) String() string {
+	return fmt.Sprintf("%s/%s container %s", c.kind, c.resource, c.name)
+}
+
+// collectContainers returns every container of every pod template in the rendered manifests, sorted for stable messages
+func collectContainers(manifests []map[string]interface{}) []manifestContainer {
+	var containers []manifestContainer
+	for _, manifest := range manifests {
+		kind, _ := manifest["kind"].(string)
+		var resource string
+		if metadata, ok := manifest["metadata"].(map[string]interface{}); ok {
+			resource, _ = metadata["name"].(string)
+		}
+		walkValues(manifest, "", func(path string, key string, value interface{}) {
+			list, ok := value.([]interface{})
+			if !ok || !containerKeys[key] {
+				return
+			}
+			for _, item := range list {
+				spec, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, _ := spec["image"].(string)
+				if len(image) == 0 {
+					continue
+				}
+				name, _ := spec["name"].(string)
+				containers = append(containers, manifestContainer{kind: kind, resource: resource, name: name, image: image, spec: spec})
+			}
+		})
+	}
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].String() < containers[j].String()
+	})
+	return containers
+}
+
+// walkValues calls fn for the root and for every nested key, path is dot separated
+func walkValues(value interface{}, path string, fn func(path string, key string, value interface{})) {
+	fn(path, path[strings.LastIndex(path, ".")+1:], value)
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := key
+			if len(path) > 0 {
+				childPath = path + "." + key
+			}
+			walkValues(child, childPath, fn)
+		}
+	case []interface{}:
+		for i, child := range v {
+			walkValues(child, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+func imageTag(image string) string {
+	lastSlash := strings.LastIndex(image, "/")
+	lastColon := strings.LastIndex(image, ":")
+	if lastColon > lastSlash {
+		return image[lastColon+1:]
+	}
+	return ""
+}
+
+// normaliseImageName prefixes docker hub images with docker.io so that registries can be matched by prefix
+func normaliseImageName(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return image
+	}
+	if len(parts) == 1 {
+		return "docker.io/library/" + image
+	}
+	return "docker.io/" + image
+}
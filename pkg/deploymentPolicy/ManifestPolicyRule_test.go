@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package deploymentPolicy
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func deploymentManifest(containers ...map[string]interface{}) map[string]interface{} {
+	var items []interface{}
+	for _, container := range containers {
+		items = append(items, container)
+	}
+	return map[string]interface{}{
+		"kind":     "Deployment",
+		"metadata": map[string]interface{}{"name": "app"},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": items},
+			},
+		},
+	}
+}
+
+func cronJobManifest(containers ...map[string]interface{}) map[string]interface{} {
+	var items []interface{}
+	for _, container := range containers {
+		items = append(items, container)
+	}
+	return map[string]interface{}{
+		"kind":     "CronJob",
+		"metadata": map[string]interface{}{"name": "job"},
+		"spec": map[string]interface{}{
+			"jobTemplate": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{"initContainers": items},
+					},
+				},
+			},
+		},
+	}
+}
+
+func container(name string, image string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "image": image}
+}
+
+func withLimits(spec map[string]interface{}, limits map[string]interface{}) map[string]interface{} {
+	spec["resources"] = map[string]interface{}{"limits": limits}
+	return spec
+}
+
+func TestNoLatestTagRule_Evaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests []map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "pinned tag and digest are compliant",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "registry.io/app:1.2"), container("sidecar", "envoy@sha256:abc"))},
+		},
+		{
+			name:      "latest tag",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "registry.io/app:latest"))},
+			want:      []string{"Deployment/app container app: image registry.io/app:latest uses latest tag"},
+		},
+		{
+			name:      "missing tag with registry port",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "localhost:5000/app"))},
+			want:      []string{"Deployment/app container app: image localhost:5000/app has no tag, latest will be used"},
+		},
+		{
+			name:      "init container of a cron job",
+			manifests: []map[string]interface{}{cronJobManifest(container("migrate", "busybox"))},
+			want:      []string{"CronJob/job container migrate: image busybox has no tag, latest will be used"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (noLatestTagRule{}).Evaluate(tt.manifests, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequireResourceLimitsRule_Evaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		manifests []map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "cpu and memory limits set",
+			manifests: []map[string]interface{}{deploymentManifest(withLimits(container("app", "app:1"), map[string]interface{}{"cpu": "1", "memory": "1Gi"}))},
+		},
+		{
+			name:      "no resources",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "app:1"))},
+			want: []string{
+				"Deployment/app container app: resources.limits.cpu is not set",
+				"Deployment/app container app: resources.limits.memory is not set",
+			},
+		},
+		{
+			name:      "empty memory limit",
+			manifests: []map[string]interface{}{deploymentManifest(withLimits(container("app", "app:1"), map[string]interface{}{"cpu": 0.5, "memory": ""}))},
+			want:      []string{"Deployment/app container app: resources.limits.memory is not set"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (requireResourceLimitsRule{}).Evaluate(tt.manifests, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoPrivilegedContainerRule_Evaluate(t *testing.T) {
+	privileged := container("app", "app:1")
+	privileged["securityContext"] = map[string]interface{}{"privileged": true}
+	unprivileged := container("sidecar", "envoy:1")
+	unprivileged["securityContext"] = map[string]interface{}{"privileged": false}
+	tests := []struct {
+		name      string
+		manifests []map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "no security context",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "app:1"))},
+		},
+		{
+			name:      "privileged container among others",
+			manifests: []map[string]interface{}{deploymentManifest(unprivileged, privileged)},
+			want:      []string{"Deployment/app container app: securityContext.privileged is true"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (noPrivilegedContainerRule{}).Evaluate(tt.manifests, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowedRegistriesRule(t *testing.T) {
+	config := json.RawMessage(`{"registries":["registry.io/team/", "docker.io/library"]}`)
+	tests := []struct {
+		name      string
+		manifests []map[string]interface{}
+		want      []string
+	}{
+		{
+			name:      "allowed registry and docker hub library image",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "registry.io/team/app:1"), container("cache", "redis:6"))},
+		},
+		{
+			name:      "registry prefix must match a path segment",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "registry.io/teamb/app:1"))},
+			want:      []string{"Deployment/app container app: image registry.io/teamb/app:1 is not from an allowed registry"},
+		},
+		{
+			name:      "docker hub user image",
+			manifests: []map[string]interface{}{deploymentManifest(container("app", "someone/app:1"))},
+			want:      []string{"Deployment/app container app: image someone/app:1 is not from an allowed registry"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (allowedRegistriesRule{}).Evaluate(tt.manifests, config); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	configTests := []struct {
+		name    string
+		config  json.RawMessage
+		wantErr bool
+	}{
+		{name: "registries set", config: config},
+		{name: "no config", wantErr: true},
+		{name: "empty registries", config: json.RawMessage(`{"registries":[]}`), wantErr: true},
+		{name: "invalid json", config: json.RawMessage(`{"registries":`), wantErr: true},
+	}
+	for _, tt := range configTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := (allowedRegistriesRule{}).ValidateConfig(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormaliseImageName(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{image: "nginx", want: "docker.io/library/nginx"},
+		{image: "someone/app:1", want: "docker.io/someone/app:1"},
+		{image: "registry.io/app:1", want: "registry.io/app:1"},
+		{image: "localhost/app", want: "localhost/app"},
+		{image: "host:5000/app", want: "host:5000/app"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			if got := normaliseImageName(tt.image); got != tt.want {
+				t.Errorf("normaliseImageName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,573 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package imageSigning
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/internal/util"
+	repository2 "github.com/devtron-labs/devtron/pkg/cluster/repository"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	SignatureVerified = "VERIFIED"
+	SignatureUnsigned = "UNSIGNED"
+	SignatureInvalid  = "INVALID"
+)
+
+type ImageSigningKeyBean struct {
+	Id         string `json:"id" validate:"required"`
+	PublicKey  string `json:"publicKey,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"` //PEM encoded EC key, a P-256 key is generated when empty
+	IsDefault  bool   `json:"isDefault"`
+	Algorithm  string `json:"algorithm,omitempty"`
+}
+
+type ImageSignaturePolicyRequest struct {
+	ClusterId int      `json:"clusterId,omitempty"`
+	EnvId     int      `json:"envId,omitempty"`
+	AppId     int      `json:"appId,omitempty"`
+	Action    string   `json:"action" validate:"oneof=inherit allow warn block"`
+	KeyIds    []string `json:"keyIds,omitempty"`
+}
+
+type ImageSignaturePolicyDto struct {
+	Id           int      `json:"id,omitempty"`
+	Action       string   `json:"action"`
+	KeyIds       []string `json:"keyIds"`
+	Inherited    bool     `json:"inherited"`
+	IsOverridden bool     `json:"isOverridden"`
+	PolicyOrigin string   `json:"policyOrigin,omitempty"`
+}
+
+type ImageSignaturePolicyResult struct {
+	Level     string                   `json:"level"`
+	ClusterId int                      `json:"clusterId,omitempty"`
+	EnvId     int                      `json:"envId,omitempty"`
+	AppId     int                      `json:"appId,omitempty"`
+	Policy    *ImageSignaturePolicyDto `json:"policy"`
+}
+
+type ImageSignatureVerification struct {
+	Status  string `json:"status"`
+	Action  string `json:"action"`
+	Allowed bool   `json:"allowed"`
+	Message string `json:"message"`
+}
+
+type ImageSigningService interface {
+	SaveKey(request *ImageSigningKeyBean, userId int32) (*ImageSigningKeyBean, error)
+	UpdateKey(request *ImageSigningKeyBean, userId int32) (*ImageSigningKeyBean, error)
+	GetAllKeys() ([]*ImageSigningKeyBean, error)
+	DeleteKey(id string, userId int32) error
+	// SignArtifacts signs image@digest of the artifacts with the default key, nothing is signed if no key is configured
+	SignArtifacts(artifacts []*repository.CiArtifact, userId int32) error
+	SavePolicy(request *ImageSignaturePolicyRequest, userId int32) (*ImageSignaturePolicyDto, error)
+	GetPolicy(policyLevel security.PolicyLevel, clusterId, environmentId, appId int) (*ImageSignaturePolicyResult, error)
+	// VerifyArtifact checks the artifact signatures against the policy applicable for app+env. Signatures are over
+	// image@digest, releases under a policy pin the image to the artifact digest so a retagged image can not pass as verified
+	VerifyArtifact(artifact *repository.CiArtifact, clusterId, environmentId, appId int) (*ImageSignatureVerification, error)
+	HasPolicy(clusterId, environmentId, appId int) (bool, error)
+}
+
+type ImageSigningServiceImpl struct {
+	logger                    *zap.SugaredLogger
+	imageSigningKeyRepository repository.ImageSigningKeyRepository
+	imageSignatureRepository  security.ImageSignatureRepository
+	environmentRepository     repository2.EnvironmentRepository
+}
+
+func NewImageSigningServiceImpl(logger *zap.SugaredLogger,
+	imageSigningKeyRepository repository.ImageSigningKeyRepository,
+	imageSignatureRepository security.ImageSignatureRepository,
+	environmentRepository repository2.EnvironmentRepository) *ImageSigningServiceImpl {
+	return &ImageSigningServiceImpl{
+		logger:                    logger,
+		imageSigningKeyRepository: imageSigningKeyRepository,
+		imageSignatureRepository:  imageSignatureRepository,
+		environmentRepository:     environmentRepository,
+	}
+}
+
+func (impl *ImageSigningServiceImpl) SaveKey(request *ImageSigningKeyBean, userId int32) (*ImageSigningKeyBean, error) {
+	_, err := impl.imageSigningKeyRepository.FindOne(request.Id)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching signing key", "id", request.Id, "err", err)
+		return nil, err
+	} else if err == nil {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusConflict, UserMessage: fmt.Sprintf("signing key %s already exists", request.Id)}
+	}
+	privateKey, err := impl.parseOrGenerateKey(request.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	privatePem, publicPem, err := encodeKeyPair(privateKey)
+	if err != nil {
+		impl.logger.Errorw("error in encoding signing key", "id", request.Id, "err", err)
+		return nil, err
+	}
+	model := &repository.ImageSigningKey{
+		Id:         request.Id,
+		Algorithm:  repository.IMAGE_SIGNING_ALGORITHM_ECDSA_P256,
+		PublicKey:  publicPem,
+		PrivateKey: privatePem,
+		IsDefault:  request.IsDefault,
+		Active:     true,
+		AuditLog:   sql.AuditLog{CreatedBy: userId, CreatedOn: time.Now(), UpdatedBy: userId, UpdatedOn: time.Now()},
+	}
+	if err = impl.imageSigningKeyRepository.Save(model); err != nil {
+		impl.logger.Errorw("error in saving signing key", "id", request.Id, "err", err)
+		return nil, err
+	}
+	return impl.buildKeyBean(model), nil
+}
+
+// UpdateKey only changes the default flag, key material is immutable as signatures refer to the key id
+func (impl *ImageSigningServiceImpl) UpdateKey(request *ImageSigningKeyBean, userId int32) (*ImageSigningKeyBean, error) {
+	model, err := impl.imageSigningKeyRepository.FindOne(request.Id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching signing key", "id", request.Id, "err", err)
+		return nil, err
+	}
+	if request.IsDefault && !model.IsDefault {
+		defaultKey, err := impl.imageSigningKeyRepository.FindActiveDefault()
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in fetching default signing key", "err", err)
+			return nil, err
+		} else if err == nil {
+			defaultKey.IsDefault = false
+			defaultKey.UpdatedOn = time.Now()
+			defaultKey.UpdatedBy = userId
+			if err = impl.imageSigningKeyRepository.Update(defaultKey); err != nil {
+				impl.logger.Errorw("error in updating default signing key", "id", defaultKey.Id, "err", err)
+				return nil, err
+			}
+		}
+	}
+	model.IsDefault = request.IsDefault
+	model.UpdatedOn = time.Now()
+	model.UpdatedBy = userId
+	if err = impl.imageSigningKeyRepository.Update(model); err != nil {
+		impl.logger.Errorw("error in updating signing key", "id", request.Id, "err", err)
+		return nil, err
+	}
+	return impl.buildKeyBean(model), nil
+}
+
+func (impl *ImageSigningServiceImpl) GetAllKeys() ([]*ImageSigningKeyBean, error) {
+	models, err := impl.imageSigningKeyRepository.FindAllActive()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching signing keys", "err", err)
+		return nil, err
+	}
+	beans := make([]*ImageSigningKeyBean, 0)
+	for _, model := range models {
+		beans = append(beans, impl.buildKeyBean(model))
+	}
+	return beans, nil
+}
+
+func (impl *ImageSigningServiceImpl) DeleteKey(id string, userId int32) error {
+	model, err := impl.imageSigningKeyRepository.FindOne(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching signing key", "id", id, "err", err)
+		return err
+	}
+	model.Active = false
+	model.IsDefault = false
+	model.UpdatedOn = time.Now()
+	model.UpdatedBy = userId
+	err = impl.imageSigningKeyRepository.Update(model)
+	if err != nil {
+		impl.logger.Errorw("error in deleting signing key", "id", id, "err", err)
+	}
+	return err
+}
+
+// buildKeyBean never returns the private key
+func (impl *ImageSigningServiceImpl) buildKeyBean(model *repository.ImageSigningKey) *ImageSigningKeyBean {
+	return &ImageSigningKeyBean{
+		Id:        model.Id,
+		Algorithm: model.Algorithm,
+		PublicKey: model.PublicKey,
+		IsDefault: model.IsDefault,
+	}
+}
+
+func (impl *ImageSigningServiceImpl) parseOrGenerateKey(privateKey string) (*ecdsa.PrivateKey, error) {
+	if len(privateKey) == 0 {
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "private key is not PEM encoded"}
+	}
+	var key interface{}
+	var err error
+	if block.Type == "EC PRIVATE KEY" {
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in parsing signing key", "err", err)
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "invalid private key", InternalMessage: err.Error()}
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "only ECDSA P-256 keys are supported"}
+	}
+	return ecKey, nil
+}
+
+func encodeKeyPair(key *ecdsa.PrivateKey) (string, string, error) {
+	privateBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", "", err
+	}
+	publicBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privatePem := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes})
+	publicPem := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes})
+	return string(privatePem), string(publicPem), nil
+}
+
+func signaturePayload(image string, imageDigest string) []byte {
+	payload := sha256.Sum256([]byte(image + "@" + imageDigest))
+	return payload[:]
+}
+
+// signImage returns the base64 encoded signature of image@digest
+func signImage(privateKey *ecdsa.PrivateKey, image string, imageDigest string) (string, error) {
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, signaturePayload(image, imageDigest))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(signature), nil
+}
+
+func (impl *ImageSigningServiceImpl) SignArtifacts(artifacts []*repository.CiArtifact, userId int32) error {
+	key, err := impl.imageSigningKeyRepository.FindActiveDefault()
+	if err == pg.ErrNoRows {
+		impl.logger.Debugw("no default signing key, skipping image signing")
+		return nil
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching default signing key", "err", err)
+		return err
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return fmt.Errorf("invalid private key for signing key %s", key.Id)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		impl.logger.Errorw("error in parsing signing key", "id", key.Id, "err", err)
+		return err
+	}
+	privateKey, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return fmt.Errorf("signing key %s is not an ECDSA key", key.Id)
+	}
+	var signatures []*security.ImageSignature
+	for _, artifact := range artifacts {
+		if len(artifact.ImageDigest) == 0 {
+			// a signature over the tag alone would also vouch for whatever is pushed to the tag later
+			impl.logger.Warnw("image digest not known, skipping image signing", "ciArtifactId", artifact.Id, "image", artifact.Image)
+			continue
+		}
+		signature, err := signImage(privateKey, artifact.Image, artifact.ImageDigest)
+		if err != nil {
+			impl.logger.Errorw("error in signing image", "ciArtifactId", artifact.Id, "err", err)
+			return err
+		}
+		signatures = append(signatures, &security.ImageSignature{
+			CiArtifactId: artifact.Id,
+			KeyId:        key.Id,
+			Image:        artifact.Image,
+			ImageDigest:  artifact.ImageDigest,
+			Signature:    signature,
+			AuditLog:     sql.AuditLog{CreatedBy: userId, CreatedOn: time.Now(), UpdatedBy: userId, UpdatedOn: time.Now()},
+		})
+	}
+	err = impl.imageSignatureRepository.SaveSignatures(signatures)
+	if err != nil {
+		impl.logger.Errorw("error in saving image signatures", "err", err)
+	}
+	return err
+}
+
+/*
+global: na
+cluster: clusterId
+environment: envId
+application: appId, envId
+
+action inherit removes the policy defined at the requested level
+*/
+func (impl *ImageSigningServiceImpl) SavePolicy(request *ImageSignaturePolicyRequest, userId int32) (*ImageSignaturePolicyDto, error) {
+	if request.AppId > 0 && request.EnvId == 0 {
+		return nil, fmt.Errorf("envId is required for application level policy")
+	}
+	action := security.ImageSignatureInherit.ValuesOf(request.Action)
+	if len(request.KeyIds) > 0 {
+		keys, err := impl.imageSigningKeyRepository.FindActiveByIds(request.KeyIds)
+		if err != nil {
+			impl.logger.Errorw("error in fetching signing keys", "keyIds", request.KeyIds, "err", err)
+			return nil, err
+		}
+		if len(keys) != len(request.KeyIds) {
+			return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "one or more signing keys not found"}
+		}
+	}
+	clusterId := request.ClusterId
+	if request.EnvId > 0 {
+		//cluster is implied by environment, policies are stored at the most specific level only
+		clusterId = 0
+	}
+	policies, err := impl.imageSignatureRepository.GetApplicablePolicies(clusterId, request.EnvId, request.AppId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching image signature policies", "request", request, "err", err)
+		return nil, err
+	}
+	isGlobal := clusterId == 0 && request.EnvId == 0 && request.AppId == 0
+	var policy *security.ImageSignaturePolicy
+	for _, p := range policies {
+		if p.Global == isGlobal && p.ClusterId == clusterId && p.EnvironmentId == request.EnvId && p.AppId == request.AppId {
+			policy = p
+			break
+		}
+	}
+
+	if action == security.ImageSignatureInherit {
+		if policy == nil {
+			return &ImageSignaturePolicyDto{Action: action.String(), KeyIds: []string{}, Inherited: true}, nil
+		}
+		policy.Deleted = true
+		policy.UpdatedOn = time.Now()
+		policy.UpdatedBy = userId
+		if _, err = impl.imageSignatureRepository.UpdatePolicy(policy); err != nil {
+			impl.logger.Errorw("error in deleting image signature policy", "id", policy.Id, "err", err)
+			return nil, err
+		}
+		return &ImageSignaturePolicyDto{Id: policy.Id, Action: action.String(), KeyIds: []string{}, Inherited: true}, nil
+	}
+
+	if policy == nil {
+		policy = &security.ImageSignaturePolicy{
+			Global:        isGlobal,
+			ClusterId:     clusterId,
+			EnvironmentId: request.EnvId,
+			AppId:         request.AppId,
+			Action:        action,
+			KeyIds:        request.KeyIds,
+			AuditLog: sql.AuditLog{
+				CreatedOn: time.Now(),
+				CreatedBy: userId,
+				UpdatedOn: time.Now(),
+				UpdatedBy: userId,
+			},
+		}
+		policy, err = impl.imageSignatureRepository.SavePolicy(policy)
+	} else {
+		policy.Action = action
+		policy.KeyIds = request.KeyIds
+		policy.UpdatedOn = time.Now()
+		policy.UpdatedBy = userId
+		policy, err = impl.imageSignatureRepository.UpdatePolicy(policy)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving image signature policy", "request", request, "err", err)
+		return nil, fmt.Errorf("error in saving image signature policy")
+	}
+	return buildPolicyDto(policy, policy.PolicyLevel()), nil
+}
+
+func (impl *ImageSigningServiceImpl) GetPolicy(policyLevel security.PolicyLevel, clusterId, environmentId, appId int) (*ImageSignaturePolicyResult, error) {
+	if policyLevel == security.Environment || policyLevel == security.Application {
+		env, err := impl.environmentRepository.FindById(environmentId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching environment", "envId", environmentId, "err", err)
+			return nil, err
+		}
+		clusterId = env.ClusterId
+	}
+	if policyLevel != security.Application {
+		appId = 0
+	}
+	if policyLevel == security.Global || policyLevel == security.Cluster {
+		environmentId = 0
+	}
+	if policyLevel == security.Global {
+		clusterId = 0
+	}
+	policy, err := impl.getEffectivePolicy(clusterId, environmentId, appId)
+	if err != nil {
+		return nil, err
+	}
+	result := &ImageSignaturePolicyResult{
+		Level:     policyLevel.String(),
+		ClusterId: clusterId,
+		EnvId:     environmentId,
+		AppId:     appId,
+	}
+	if policy == nil {
+		result.Policy = &ImageSignaturePolicyDto{Action: security.ImageSignatureAllow.String(), KeyIds: []string{}, Inherited: true}
+	} else {
+		result.Policy = buildPolicyDto(policy, policyLevel)
+	}
+	return result, nil
+}
+
+func buildPolicyDto(policy *security.ImageSignaturePolicy, policyLevel security.PolicyLevel) *ImageSignaturePolicyDto {
+	dto := &ImageSignaturePolicyDto{
+		Id:           policy.Id,
+		Action:       policy.Action.String(),
+		KeyIds:       policy.KeyIds,
+		PolicyOrigin: policy.PolicyLevel().String(),
+	}
+	if dto.KeyIds == nil {
+		dto.KeyIds = []string{}
+	}
+	if policy.PolicyLevel() != policyLevel {
+		dto.Inherited = true
+	} else {
+		dto.IsOverridden = policyLevel != security.Global
+	}
+	return dto
+}
+
+// getEffectivePolicy returns the policy of the most specific level, nil if none is defined
+func (impl *ImageSigningServiceImpl) getEffectivePolicy(clusterId, environmentId, appId int) (*security.ImageSignaturePolicy, error) {
+	policies, err := impl.imageSignatureRepository.GetApplicablePolicies(clusterId, environmentId, appId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching image signature policies", "clusterId", clusterId, "envId", environmentId, "appId", appId, "err", err)
+		return nil, err
+	}
+	var effective *security.ImageSignaturePolicy
+	for _, policy := range policies {
+		if effective != nil && effective.PolicyLevel() > policy.PolicyLevel() {
+			continue
+		}
+		effective = policy
+	}
+	return effective, nil
+}
+
+func (impl *ImageSigningServiceImpl) HasPolicy(clusterId, environmentId, appId int) (bool, error) {
+	policy, err := impl.getEffectivePolicy(clusterId, environmentId, appId)
+	if err != nil {
+		return false, err
+	}
+	return policy != nil, nil
+}
+
+func (impl *ImageSigningServiceImpl) VerifyArtifact(artifact *repository.CiArtifact, clusterId, environmentId, appId int) (*ImageSignatureVerification, error) {
+	policy, err := impl.getEffectivePolicy(clusterId, environmentId, appId)
+	if err != nil {
+		return nil, err
+	}
+	action := security.ImageSignatureAllow
+	var keyIds []string
+	if policy != nil {
+		action = policy.Action
+		keyIds = policy.KeyIds
+	}
+	var keys []*repository.ImageSigningKey
+	if len(keyIds) > 0 {
+		keys, err = impl.imageSigningKeyRepository.FindActiveByIds(keyIds)
+	} else {
+		keys, err = impl.imageSigningKeyRepository.FindAllActive()
+	}
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching signing keys", "keyIds", keyIds, "err", err)
+		return nil, err
+	}
+	signatures, err := impl.imageSignatureRepository.FindSignaturesByArtifactId(artifact.Id)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching image signatures", "ciArtifactId", artifact.Id, "err", err)
+		return nil, err
+	}
+
+	result := &ImageSignatureVerification{Action: action.String()}
+	if len(artifact.ImageDigest) == 0 {
+		result.Status = SignatureInvalid
+		result.Message = fmt.Sprintf("image %s has no digest and can not be verified", artifact.Image)
+	} else if len(signatures) == 0 {
+		result.Status = SignatureUnsigned
+		result.Message = fmt.Sprintf("image %s is not signed", artifact.Image)
+	} else {
+		result.Status = SignatureInvalid
+		result.Message = fmt.Sprintf("image %s is not signed by a trusted key", artifact.Image)
+		for _, signature := range signatures {
+			if keyId, ok := impl.verifySignature(artifact, signature, keys); ok {
+				result.Status = SignatureVerified
+				result.Message = fmt.Sprintf("image %s signed by key %s", artifact.Image, keyId)
+				break
+			}
+		}
+	}
+	result.Allowed = result.Status == SignatureVerified || action != security.ImageSignatureBlock
+	return result, nil
+}
+
+// verifySignature checks that the signature is over the artifact's own image and digest and was made by one of the keys
+func (impl *ImageSigningServiceImpl) verifySignature(artifact *repository.CiArtifact, signature *security.ImageSignature, keys []*repository.ImageSigningKey) (string, bool) {
+	if len(artifact.ImageDigest) == 0 || signature.Image != artifact.Image || signature.ImageDigest != artifact.ImageDigest {
+		return "", false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature.Signature)
+	if err != nil {
+		return "", false
+	}
+	for _, key := range keys {
+		if key.Id != signature.KeyId {
+			continue
+		}
+		block, _ := pem.Decode([]byte(key.PublicKey))
+		if block == nil {
+			impl.logger.Errorw("invalid public key for signing key", "id", key.Id)
+			return "", false
+		}
+		parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			impl.logger.Errorw("error in parsing public key", "id", key.Id, "err", err)
+			return "", false
+		}
+		publicKey, ok := parsed.(*ecdsa.PublicKey)
+		if !ok {
+			return "", false
+		}
+		return key.Id, ecdsa.VerifyASN1(publicKey, signaturePayload(artifact.Image, artifact.ImageDigest), sig)
+	}
+	return "", false
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package imageSigning
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"go.uber.org/zap"
+)
+
+func TestVerifySignature(t *testing.T) {
+	newKey := func(id string) (*ecdsa.PrivateKey, *repository.ImageSigningKey) {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privatePem, publicPem, err := encodeKeyPair(privateKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return privateKey, &repository.ImageSigningKey{Id: id, PublicKey: publicPem, PrivateKey: privatePem}
+	}
+	trustedPrivate, trusted := newKey("trusted")
+	untrustedPrivate, _ := newKey("untrusted")
+	image, digest := "registry.example.com/app:v1", "sha256:aaaa"
+	sign := func(privateKey *ecdsa.PrivateKey, keyId string, signedImage string, signedDigest string) *security.ImageSignature {
+		signature, err := signImage(privateKey, signedImage, signedDigest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &security.ImageSignature{KeyId: keyId, Image: signedImage, ImageDigest: signedDigest, Signature: signature}
+	}
+	tampered := sign(trustedPrivate, "trusted", image, digest)
+	tampered.Signature = sign(trustedPrivate, "trusted", image, "sha256:bbbb").Signature
+	tests := []struct {
+		name      string
+		artifact  *repository.CiArtifact
+		signature *security.ImageSignature
+		want      bool
+	}{
+		{name: "signed by trusted key", artifact: &repository.CiArtifact{Image: image, ImageDigest: digest}, signature: sign(trustedPrivate, "trusted", image, digest), want: true},
+		{name: "tag pushed again with another digest", artifact: &repository.CiArtifact{Image: image, ImageDigest: "sha256:bbbb"}, signature: sign(trustedPrivate, "trusted", image, digest), want: false},
+		{name: "signature of another image", artifact: &repository.CiArtifact{Image: "registry.example.com/app:v2", ImageDigest: digest}, signature: sign(trustedPrivate, "trusted", image, digest), want: false},
+		{name: "signed by untrusted key", artifact: &repository.CiArtifact{Image: image, ImageDigest: digest}, signature: sign(untrustedPrivate, "untrusted", image, digest), want: false},
+		{name: "untrusted key claiming a trusted key id", artifact: &repository.CiArtifact{Image: image, ImageDigest: digest}, signature: sign(untrustedPrivate, "trusted", image, digest), want: false},
+		{name: "signature does not match stored digest", artifact: &repository.CiArtifact{Image: image, ImageDigest: digest}, signature: tampered, want: false},
+		{name: "artifact without digest", artifact: &repository.CiArtifact{Image: image}, signature: sign(trustedPrivate, "trusted", image, ""), want: false},
+	}
+	impl := &ImageSigningServiceImpl{logger: zap.NewNop().Sugar()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := impl.verifySignature(tt.artifact, tt.signature, []*repository.ImageSigningKey{trusted}); got != tt.want {
+				t.Errorf("verifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -172,7 +172,8 @@ func (impl NotificationConfigBuilderImpl) buildNotificationSetting(notificationS
 func (impl NotificationConfigBuilderImpl) BuildNotificationSettingWithPipeline(teamId *int, envId *int, appId *int, pipelineId *int, pipelineType util.PipelineType, eventTypeId int, viewId int, providers []*Provider) (repository.NotificationSettings, error) {
 
 	for _, provider := range providers {
-		if len(provider.Recipient) > 0 {
+		// mails go through ses unless the rule picked smtp
+		if len(provider.Recipient) > 0 && provider.Destination != util.SMTP {
 			if strings.Contains(provider.Recipient, "@") {
 				provider.Destination = util.SES
 			} else {
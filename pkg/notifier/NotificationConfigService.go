@@ -53,6 +53,8 @@ type NotificationConfigServiceImpl struct {
 	pipelineRepository             pipelineConfig.PipelineRepository
 	slackRepository                repository.SlackNotificationRepository
 	sesRepository                  repository.SESNotificationRepository
+	webhookRepository              repository.WebhookNotificationRepository
+	smtpRepository                 repository.SMTPNotificationRepository
 	teamRepository                 repository2.TeamRepository
 	environmentRepository          repository3.EnvironmentRepository
 	appRepository                  app.AppRepository
@@ -82,6 +84,18 @@ type Providers struct {
 	Providers []Provider `json:"providers"`
 }
 
+// NotificationEvent carries the fields notification settings are matched on for the channels delivered by devtron itself,
+// Data is the event the payload and mail templates are rendered with
+type NotificationEvent struct {
+	PipelineType string
+	EventTypeId  int
+	PipelineId   int
+	TeamId       int
+	AppId        int
+	EnvId        int
+	Data         interface{}
+}
+
 type NSDeleteRequest struct {
 	Id []*int `json:"id"`
 }
@@ -164,7 +178,8 @@ type ProvidersConfig struct {
 
 func NewNotificationConfigServiceImpl(logger *zap.SugaredLogger, notificationSettingsRepository repository.NotificationSettingsRepository, notificationConfigBuilder NotificationConfigBuilder, ciPipelineRepository pipelineConfig.CiPipelineRepository,
 	pipelineRepository pipelineConfig.PipelineRepository, slackRepository repository.SlackNotificationRepository,
-	sesRepository repository.SESNotificationRepository, teamRepository repository2.TeamRepository,
+	sesRepository repository.SESNotificationRepository, webhookRepository repository.WebhookNotificationRepository,
+	smtpRepository repository.SMTPNotificationRepository, teamRepository repository2.TeamRepository,
 	environmentRepository repository3.EnvironmentRepository, appRepository app.AppRepository,
 	userRepository repository4.UserRepository, ciPipelineMaterialRepository pipelineConfig.CiPipelineMaterialRepository) *NotificationConfigServiceImpl {
 	return &NotificationConfigServiceImpl{
@@ -175,6 +190,8 @@ func NewNotificationConfigServiceImpl(logger *zap.SugaredLogger, notificationSet
 		ciPipelineRepository:           ciPipelineRepository,
 		sesRepository:                  sesRepository,
 		slackRepository:                slackRepository,
+		webhookRepository:              webhookRepository,
+		smtpRepository:                 smtpRepository,
 		teamRepository:                 teamRepository,
 		environmentRepository:          environmentRepository,
 		appRepository:                  appRepository,
@@ -363,11 +380,20 @@ func (impl *NotificationConfigServiceImpl) BuildNotificationSettingsResponse(not
 
 		if config.Providers != nil && len(config.Providers) > 0 {
 			var slackIds []*int
+			var webhookIds []int
 			var userIds []int32
+			var smtpUserIds []int32
 			var directRecipients []string
+			var smtpRecipients []string
 			for _, item := range config.Providers {
 				if item.Destination == util.Slack {
 					slackIds = append(slackIds, &item.ConfigId)
+				} else if item.Destination == util.Webhook {
+					webhookIds = append(webhookIds, item.ConfigId)
+				} else if item.Destination == util.SMTP && len(item.Recipient) > 0 {
+					smtpRecipients = append(smtpRecipients, item.Recipient)
+				} else if item.Destination == util.SMTP {
+					smtpUserIds = append(smtpUserIds, int32(item.ConfigId))
 				} else if item.Destination == util.SES {
 					userIds = append(userIds, int32(item.ConfigId))
 				} else {
@@ -385,6 +411,16 @@ func (impl *NotificationConfigServiceImpl) BuildNotificationSettingsResponse(not
 					providerConfigs = append(providerConfigs, &ProvidersConfig{Id: item.Id, ConfigName: item.ConfigName, Dest: string(util.Slack)})
 				}
 			}
+			if len(webhookIds) > 0 {
+				webhookConfigs, err := impl.webhookRepository.FindByIdsIn(webhookIds)
+				if err != nil && err != pg.ErrNoRows {
+					impl.logger.Errorw("error in fetching webhook config", "err", err)
+					return notificationSettingsResponses, deletedItemCount, err
+				}
+				for _, item := range webhookConfigs {
+					providerConfigs = append(providerConfigs, &ProvidersConfig{Id: item.Id, ConfigName: item.ConfigName, Dest: string(util.Webhook)})
+				}
+			}
 
 			if len(userIds) > 0 {
 				sesConfigs, err := impl.userRepository.GetByIds(userIds)
@@ -396,6 +432,19 @@ func (impl *NotificationConfigServiceImpl) BuildNotificationSettingsResponse(not
 					providerConfigs = append(providerConfigs, &ProvidersConfig{Id: int(item.Id), ConfigName: item.EmailId, Dest: string(util.SES)})
 				}
 			}
+			if len(smtpUserIds) > 0 {
+				users, err := impl.userRepository.GetByIds(smtpUserIds)
+				if err != nil && err != pg.ErrNoRows {
+					impl.logger.Errorw("error in fetching user", "error", err)
+					return notificationSettingsResponses, deletedItemCount, err
+				}
+				for _, item := range users {
+					providerConfigs = append(providerConfigs, &ProvidersConfig{Id: int(item.Id), ConfigName: item.EmailId, Dest: string(util.SMTP)})
+				}
+			}
+			for _, item := range smtpRecipients {
+				providerConfigs = append(providerConfigs, &ProvidersConfig{Dest: string(util.SMTP), Recipient: item})
+			}
 			for _, item := range directRecipients {
 				if strings.Contains(item, "https://") {
 					providerConfigs = append(providerConfigs, &ProvidersConfig{Dest: string(util.Slack), Recipient: item})
@@ -460,12 +509,15 @@ func (impl *NotificationConfigServiceImpl) buildProvidersConfig(config config) (
 	if len(config.Providers) > 0 {
 		sesConfigNamesMap := map[int]string{}
 		slackConfigNameMap := map[int]string{}
+		webhookConfigNameMap := map[int]string{}
 		for _, c := range config.Providers {
 			if util.Slack == c.Destination {
 				if _, ok := slackConfigNameMap[c.ConfigId]; ok {
 					continue
 				}
 				slackConfigNameMap[c.ConfigId] = ""
+			} else if util.Webhook == c.Destination {
+				webhookConfigNameMap[c.ConfigId] = ""
 			} else if util.SES == c.Destination {
 				if _, ok := sesConfigNamesMap[c.ConfigId]; ok {
 					continue
@@ -483,6 +535,10 @@ func (impl *NotificationConfigServiceImpl) buildProvidersConfig(config config) (
 		for k := range sesConfigNamesMap {
 			sesIds = append(sesIds, k)
 		}
+		webhookIds := make([]int, 0, len(webhookConfigNameMap))
+		for k := range webhookConfigNameMap {
+			webhookIds = append(webhookIds, k)
+		}
 
 		if len(slackIds) > 0 {
 			slackConfigs, err := impl.slackRepository.FindByIdsIn(slackIds)
@@ -504,12 +560,24 @@ func (impl *NotificationConfigServiceImpl) buildProvidersConfig(config config) (
 				sesConfigNamesMap[s.Id] = s.ConfigName
 			}
 		}
+		if len(webhookIds) > 0 {
+			webhookConfigs, err := impl.webhookRepository.FindByIdsIn(webhookIds)
+			if err != nil {
+				impl.logger.Errorw("error on fetch webhook configs", "err", err)
+				return []ProvidersConfig{}, err
+			}
+			for _, s := range webhookConfigs {
+				webhookConfigNameMap[s.Id] = s.ConfigName
+			}
+		}
 		for _, c := range config.Providers {
 			var configName string
 			if c.Destination == util.Slack {
 				configName = slackConfigNameMap[c.ConfigId]
 			} else if c.Destination == util.SES {
 				configName = sesConfigNamesMap[c.ConfigId]
+			} else if c.Destination == util.Webhook {
+				configName = webhookConfigNameMap[c.ConfigId]
 			}
 			providerConfig := ProvidersConfig{
 				Id:         c.ConfigId,
@@ -0,0 +1,363 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package notifier
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/caarlos0/env"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/devtron-labs/devtron/pkg/user/casbin"
+	repository2 "github.com/devtron-labs/devtron/pkg/user/repository"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	util2 "github.com/devtron-labs/devtron/util/event"
+	"github.com/devtron-labs/devtron/util/rbac"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const notificationDigestJobName = "notification-digest"
+
+type NotificationDigestConfig struct {
+	DigestCron string `env:"NOTIFICATION_DIGEST_CRON" envDefault:"0 * * * *"`
+}
+
+const notificationDigestTemplate = `<html><body>
+<h3>{{len .}} pipeline events since the last digest</h3>
+<ul>
+{{range .}}<li>{{.CreatedOn.Format "2006-01-02 15:04 MST"}} - {{.Summary}}{{if .Link}} (<a href="{{.Link}}">view</a>){{end}}</li>
+{{end}}</ul>
+</body></html>`
+
+var notificationDigestBody = template.Must(template.New("digest").Parse(notificationDigestTemplate))
+
+// only the frequent pipeline events are batched in digest mode, the rest are always sent right away
+var digestEventTypes = map[int]bool{
+	int(util2.Trigger): true,
+	int(util2.Success): true,
+	int(util2.Fail):    true,
+}
+
+type NotificationSubscriptionService interface {
+	Subscribe(request *NotificationSubscriptionDto, userId int32) (*NotificationSubscriptionDto, error)
+	GetSubscriptions(userId int32) ([]*NotificationSubscriptionDto, error)
+	DeleteSubscription(id int, userId int32) error
+	// HandleEvent mails the subscribers of the event, or queues it for their digest
+	HandleEvent(event *NotificationEvent)
+	// SendDigests mails every recipient one summary of the events queued since the last run
+	SendDigests()
+}
+
+type NotificationSubscriptionServiceImpl struct {
+	logger                             *zap.SugaredLogger
+	notificationSubscriptionRepository repository.NotificationSubscriptionRepository
+	smtpNotificationService            SMTPNotificationService
+	userRepository                     repository2.UserRepository
+	enforcer                           casbin.Enforcer
+	enforcerUtil                       rbac.EnforcerUtil
+}
+
+type NotificationSubscriptionDto struct {
+	Id           int    `json:"id"`
+	AppId        int    `json:"appId" validate:"required,number"`
+	EnvId        int    `json:"envId"`
+	PipelineType string `json:"pipelineType" validate:"omitempty,oneof=CI CD"`
+	EventTypeIds []int  `json:"eventTypeIds" validate:"required,min=1"`
+	Digest       bool   `json:"digest"`
+}
+
+func NewNotificationSubscriptionServiceImpl(logger *zap.SugaredLogger,
+	notificationSubscriptionRepository repository.NotificationSubscriptionRepository,
+	smtpNotificationService SMTPNotificationService, userRepository repository2.UserRepository,
+	enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil,
+	scheduledJobRunner util3.ScheduledJobRunner) (*NotificationSubscriptionServiceImpl, error) {
+	cfg := &NotificationDigestConfig{}
+	err := env.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	impl := &NotificationSubscriptionServiceImpl{
+		logger:                             logger,
+		notificationSubscriptionRepository: notificationSubscriptionRepository,
+		smtpNotificationService:            smtpNotificationService,
+		userRepository:                     userRepository,
+		enforcer:                           enforcer,
+		enforcerUtil:                       enforcerUtil,
+	}
+	err = scheduledJobRunner.Schedule(notificationDigestJobName, cfg.DigestCron, impl.SendDigests)
+	if err != nil {
+		logger.Errorw("error in starting notification digest cron", "cron", cfg.DigestCron, "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+func (impl *NotificationSubscriptionServiceImpl) Subscribe(request *NotificationSubscriptionDto, userId int32) (*NotificationSubscriptionDto, error) {
+	for _, eventTypeId := range request.EventTypeIds {
+		if _, ok := smtpEventTypeNames[eventTypeId]; !ok {
+			return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("invalid event type %d", eventTypeId)}
+		}
+	}
+	var envId *int
+	if request.EnvId > 0 {
+		envId = &request.EnvId
+	}
+	if request.Id > 0 {
+		subscription, err := impl.notificationSubscriptionRepository.FindById(request.Id)
+		if err != nil {
+			impl.logger.Errorw("error in fetching notification subscription", "id", request.Id, "err", err)
+			return nil, err
+		}
+		if subscription.UserId != userId {
+			return nil, &util.ApiError{HttpStatusCode: http.StatusNotFound, UserMessage: "subscription not found"}
+		}
+		subscription.AppId = request.AppId
+		subscription.EnvId = envId
+		subscription.PipelineType = request.PipelineType
+		subscription.EventTypeIds = request.EventTypeIds
+		subscription.Digest = request.Digest
+		subscription.UpdatedOn = time.Now()
+		subscription.UpdatedBy = userId
+		if err = impl.notificationSubscriptionRepository.Update(subscription); err != nil {
+			impl.logger.Errorw("error in updating notification subscription", "id", request.Id, "err", err)
+			return nil, err
+		}
+		return adaptNotificationSubscription(subscription), nil
+	}
+	subscription := &repository.NotificationSubscription{
+		UserId:       userId,
+		AppId:        request.AppId,
+		EnvId:        envId,
+		PipelineType: request.PipelineType,
+		EventTypeIds: request.EventTypeIds,
+		Digest:       request.Digest,
+		Active:       true,
+		AuditLog: sql.AuditLog{
+			CreatedBy: userId,
+			CreatedOn: time.Now(),
+			UpdatedOn: time.Now(),
+			UpdatedBy: userId,
+		},
+	}
+	if err := impl.notificationSubscriptionRepository.Save(subscription); err != nil {
+		impl.logger.Errorw("error in saving notification subscription", "request", request, "err", err)
+		return nil, err
+	}
+	return adaptNotificationSubscription(subscription), nil
+}
+
+func (impl *NotificationSubscriptionServiceImpl) GetSubscriptions(userId int32) ([]*NotificationSubscriptionDto, error) {
+	subscriptions, err := impl.notificationSubscriptionRepository.FindActiveByUserId(userId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching notification subscriptions", "userId", userId, "err", err)
+		return nil, err
+	}
+	responses := make([]*NotificationSubscriptionDto, 0)
+	for _, subscription := range subscriptions {
+		responses = append(responses, adaptNotificationSubscription(subscription))
+	}
+	return responses, nil
+}
+
+func (impl *NotificationSubscriptionServiceImpl) DeleteSubscription(id int, userId int32) error {
+	subscription, err := impl.notificationSubscriptionRepository.FindById(id)
+	if err == pg.ErrNoRows || (err == nil && subscription.UserId != userId) {
+		return &util.ApiError{HttpStatusCode: http.StatusNotFound, UserMessage: "subscription not found"}
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching notification subscription", "id", id, "err", err)
+		return err
+	}
+	subscription.Active = false
+	subscription.UpdatedOn = time.Now()
+	subscription.UpdatedBy = userId
+	if err = impl.notificationSubscriptionRepository.Update(subscription); err != nil {
+		impl.logger.Errorw("error in deleting notification subscription", "id", id, "err", err)
+		return err
+	}
+	if err = impl.notificationSubscriptionRepository.MarkDigestEventsSentBySubscriptionId(id); err != nil {
+		impl.logger.Errorw("error in dropping queued digest events", "subscriptionId", id, "err", err)
+	}
+	return nil
+}
+
+func (impl *NotificationSubscriptionServiceImpl) HandleEvent(event *NotificationEvent) {
+	subscriptions, err := impl.notificationSubscriptionRepository.FindActiveForEvent(event.AppId, event.EnvId, event.PipelineType, event.EventTypeId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching notification subscriptions for event", "event", event, "err", err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+	mail, err := impl.smtpNotificationService.RenderEventMail(event)
+	if err != nil {
+		impl.logger.Errorw("error in rendering subscription mail", "event", event, "err", err)
+		return
+	}
+	// a user may match through more than one subscription, e.g. app wide and env specific,
+	// the event is mailed once and a non digest subscription wins over a digest one
+	digestSubscriptions := make(map[int32]*repository.NotificationSubscription)
+	instantUsers := make(map[int32]bool)
+	for _, subscription := range subscriptions {
+		if subscription.Digest && digestEventTypes[event.EventTypeId] {
+			digestSubscriptions[subscription.UserId] = subscription
+		} else {
+			instantUsers[subscription.UserId] = true
+		}
+	}
+	for userId, subscription := range digestSubscriptions {
+		if instantUsers[userId] {
+			continue
+		}
+		digestEvent := &repository.NotificationDigestEvent{
+			SubscriptionId: subscription.Id,
+			UserId:         userId,
+			AppId:          event.AppId,
+			EventTypeId:    event.EventTypeId,
+			Summary:        mail.Subject,
+			Link:           mail.Link,
+			CreatedOn:      time.Now(),
+		}
+		if err = impl.notificationSubscriptionRepository.SaveDigestEvent(digestEvent); err != nil {
+			impl.logger.Errorw("error in queueing digest event", "subscriptionId", subscription.Id, "err", err)
+		}
+	}
+	var userIds []int32
+	for userId := range instantUsers {
+		userIds = append(userIds, userId)
+	}
+	if len(userIds) == 0 {
+		return
+	}
+	emails := impl.getAuthorisedEmails(userIds, map[int]bool{event.AppId: true})
+	var recipients []string
+	for _, email := range emails {
+		recipients = append(recipients, email.email)
+	}
+	if len(recipients) == 0 {
+		return
+	}
+	go func() {
+		if err := impl.smtpNotificationService.SendMail(recipients, mail.Subject, mail.Body); err != nil {
+			impl.logger.Errorw("error in sending subscription mail", "appId", event.AppId, "err", err)
+		}
+	}()
+}
+
+func (impl *NotificationSubscriptionServiceImpl) SendDigests() {
+	digestEvents, err := impl.notificationSubscriptionRepository.FindPendingDigestEvents()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching pending digest events", "err", err)
+		return
+	}
+	if len(digestEvents) == 0 {
+		return
+	}
+	userEvents := make(map[int32][]*repository.NotificationDigestEvent)
+	appIds := make(map[int]bool)
+	var userIds []int32
+	for _, digestEvent := range digestEvents {
+		if _, ok := userEvents[digestEvent.UserId]; !ok {
+			userIds = append(userIds, digestEvent.UserId)
+		}
+		userEvents[digestEvent.UserId] = append(userEvents[digestEvent.UserId], digestEvent)
+		appIds[digestEvent.AppId] = true
+	}
+	emails := impl.getAuthorisedEmails(userIds, appIds)
+	for _, userId := range userIds {
+		var ids []int
+		var authorisedEvents []*repository.NotificationDigestEvent
+		for _, digestEvent := range userEvents[userId] {
+			ids = append(ids, digestEvent.Id)
+			// access may have been revoked since the event was queued
+			if email, ok := emails[userId]; ok && email.apps[digestEvent.AppId] {
+				authorisedEvents = append(authorisedEvents, digestEvent)
+			}
+		}
+		if len(authorisedEvents) > 0 {
+			var body bytes.Buffer
+			if err = notificationDigestBody.Execute(&body, authorisedEvents); err != nil {
+				impl.logger.Errorw("error in rendering notification digest", "userId", userId, "err", err)
+				continue
+			}
+			subject := fmt.Sprintf("Devtron digest | %d pipeline events", len(authorisedEvents))
+			if err = impl.smtpNotificationService.SendMail([]string{emails[userId].email}, subject, body.String()); err != nil {
+				// left pending to be retried with the next digest
+				impl.logger.Errorw("error in sending notification digest", "userId", userId, "err", err)
+				continue
+			}
+		}
+		if err = impl.notificationSubscriptionRepository.MarkDigestEventsSent(ids); err != nil {
+			impl.logger.Errorw("error in marking digest events sent", "userId", userId, "err", err)
+		}
+	}
+}
+
+type authorisedEmail struct {
+	email string
+	apps  map[int]bool
+}
+
+// getAuthorisedEmails resolves active users along with the apps among appIds they can still read
+func (impl *NotificationSubscriptionServiceImpl) getAuthorisedEmails(userIds []int32, appIds map[int]bool) map[int32]*authorisedEmail {
+	emails := make(map[int32]*authorisedEmail)
+	users, err := impl.userRepository.GetByIds(userIds)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching subscribed users", "userIds", userIds, "err", err)
+		return emails
+	}
+	objects := make(map[int]string)
+	for appId := range appIds {
+		objects[appId] = impl.enforcerUtil.GetAppRBACNameByAppId(appId)
+	}
+	for _, user := range users {
+		if !user.Active || user.UserType == repository2.UserTypeApiToken {
+			continue
+		}
+		apps := make(map[int]bool)
+		for appId, object := range objects {
+			if ok := impl.enforcer.EnforceByEmail(strings.ToLower(user.EmailId), casbin.ResourceApplications, casbin.ActionGet, object); ok {
+				apps[appId] = true
+			}
+		}
+		if len(apps) > 0 {
+			emails[user.Id] = &authorisedEmail{email: user.EmailId, apps: apps}
+		}
+	}
+	return emails
+}
+
+func adaptNotificationSubscription(subscription *repository.NotificationSubscription) *NotificationSubscriptionDto {
+	subscriptionDto := &NotificationSubscriptionDto{
+		Id:           subscription.Id,
+		AppId:        subscription.AppId,
+		PipelineType: subscription.PipelineType,
+		EventTypeIds: subscription.EventTypeIds,
+		Digest:       subscription.Digest,
+	}
+	if subscription.EnvId != nil {
+		subscriptionDto.EnvId = *subscription.EnvId
+	}
+	return subscriptionDto
+}
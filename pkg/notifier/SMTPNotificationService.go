@@ -0,0 +1,509 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	repository2 "github.com/devtron-labs/devtron/pkg/user/repository"
+	util2 "github.com/devtron-labs/devtron/util/event"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const SMTP_CONFIG_TYPE = "smtp"
+
+const (
+	SmtpTlsModeNone     = "NONE"
+	SmtpTlsModeStartTls = "STARTTLS"
+	SmtpTlsModeSsl      = "SSL"
+	smtpSecretMask      = "**********"
+	smtpDialTimeout     = 10 * time.Second
+)
+
+const smtpMailSubjectTemplate = `{{.Event.Payload.AppName}} | {{.Event.PipelineType}} {{.EventType}}{{if .Event.Payload.EnvName}} | {{.Event.Payload.EnvName}}{{end}}`
+
+const smtpMailBodyTemplate = `<html><body>
+<h3>{{.Event.PipelineType}} pipeline {{.Event.Payload.PipelineName}} {{.EventType}}</h3>
+<table>
+<tr><td>Application</td><td>{{.Event.Payload.AppName}}</td></tr>
+{{if .Event.Payload.EnvName}}<tr><td>Environment</td><td>{{.Event.Payload.EnvName}}</td></tr>{{end}}
+{{if .Event.Payload.Stage}}<tr><td>Stage</td><td>{{.Event.Payload.Stage}}</td></tr>{{end}}
+{{if .Event.Payload.TriggeredBy}}<tr><td>Triggered by</td><td>{{.Event.Payload.TriggeredBy}}</td></tr>{{end}}
+{{if .Event.Payload.DockerImageUrl}}<tr><td>Image</td><td>{{.Event.Payload.DockerImageUrl}}</td></tr>{{end}}
+{{if .Event.Payload.RollbackReason}}<tr><td>Rollback reason</td><td>{{.Event.Payload.RollbackReason}}</td></tr>{{end}}
+{{if .Event.Payload.BlockedCves}}<tr><td>Blocked CVEs</td><td>{{.Event.Payload.BlockedCves}}</td></tr>{{end}}
+</table>
+{{if .Event.Payload.BuildHistoryLink}}<p><a href="{{.Event.BaseUrl}}{{.Event.Payload.BuildHistoryLink}}">View build</a></p>{{end}}
+{{if .Event.Payload.DeploymentHistoryLink}}<p><a href="{{.Event.BaseUrl}}{{.Event.Payload.DeploymentHistoryLink}}">View deployment</a></p>{{end}}
+</body></html>`
+
+const smtpMailLinkTemplate = `{{if .Event.Payload.BuildHistoryLink}}{{.Event.BaseUrl}}{{.Event.Payload.BuildHistoryLink}}{{else if .Event.Payload.DeploymentHistoryLink}}{{.Event.BaseUrl}}{{.Event.Payload.DeploymentHistoryLink}}{{end}}`
+
+var (
+	smtpMailSubject = template.Must(template.New("subject").Parse(smtpMailSubjectTemplate))
+	smtpMailBody    = template.Must(template.New("body").Parse(smtpMailBodyTemplate))
+	smtpMailLink    = template.Must(template.New("link").Parse(smtpMailLinkTemplate))
+)
+
+var smtpEventTypeNames = map[int]string{
+	int(util2.Trigger):            "triggered",
+	int(util2.Success):            "succeeded",
+	int(util2.Fail):               "failed",
+	int(util2.Approval):           "awaiting approval",
+	int(util2.AutoRollback):       "rolled back",
+	int(util2.VulnerabilityFound): "has newly blocked vulnerabilities",
+}
+
+type SMTPNotificationService interface {
+	SaveOrEditNotificationConfig(channelReq []*SMTPConfigDto, userId int32) ([]int, error)
+	FetchSMTPNotificationConfigById(id int) (*SMTPConfigDto, error)
+	FetchAllSMTPNotificationConfig() ([]*SMTPConfigDto, error)
+	FetchAllSMTPNotificationConfigAutocomplete() ([]*NotificationChannelAutoResponse, error)
+	DeleteNotificationConfig(deleteReq *SMTPConfigDto, userId int32) error
+	SendTestMail(request *SMTPTestMailRequest) error
+	// SendEventNotification mails the recipients of smtp providers configured for the event through the default config
+	SendEventNotification(event *NotificationEvent)
+	RenderEventMail(event *NotificationEvent) (*EventMail, error)
+	// SendMail sends a mail through the default config
+	SendMail(to []string, subject string, body string) error
+}
+
+type SMTPNotificationServiceImpl struct {
+	logger                         *zap.SugaredLogger
+	smtpRepository                 repository.SMTPNotificationRepository
+	notificationSettingsRepository repository.NotificationSettingsRepository
+	userRepository                 repository2.UserRepository
+}
+
+type SMTPChannelConfig struct {
+	Channel        util2.Channel    `json:"channel" validate:"required"`
+	SMTPConfigDtos []*SMTPConfigDto `json:"configs"`
+}
+
+type SMTPConfigDto struct {
+	OwnerId      int32  `json:"userId" validate:"number"`
+	Host         string `json:"host" validate:"required"`
+	Port         string `json:"port" validate:"required,numeric"`
+	TlsMode      string `json:"tlsMode" validate:"required,oneof=NONE STARTTLS SSL"`
+	AuthUser     string `json:"authUser"`
+	AuthPassword string `json:"authPassword"`
+	FromEmail    string `json:"fromEmail" validate:"email,required"`
+	ConfigName   string `json:"configName" validate:"required"`
+	Description  string `json:"description"`
+	Id           int    `json:"id" validate:"number"`
+	Default      bool   `json:"default,notnull"`
+}
+
+// SMTPTestMailRequest tests a saved config by id, or the given one before it is saved
+type EventMail struct {
+	Subject string
+	Body    string
+	Link    string
+}
+
+type SMTPTestMailRequest struct {
+	ConfigId int            `json:"configId"`
+	Config   *SMTPConfigDto `json:"config"`
+	ToEmail  string         `json:"toEmail" validate:"email,required"`
+}
+
+func NewSMTPNotificationServiceImpl(logger *zap.SugaredLogger, smtpRepository repository.SMTPNotificationRepository,
+	notificationSettingsRepository repository.NotificationSettingsRepository, userRepository repository2.UserRepository) *SMTPNotificationServiceImpl {
+	return &SMTPNotificationServiceImpl{
+		logger:                         logger,
+		smtpRepository:                 smtpRepository,
+		notificationSettingsRepository: notificationSettingsRepository,
+		userRepository:                 userRepository,
+	}
+}
+
+func (impl *SMTPNotificationServiceImpl) SaveOrEditNotificationConfig(channelReq []*SMTPConfigDto, userId int32) ([]int, error) {
+	var responseIds []int
+	for _, config := range channelReq {
+		if err := validateSMTPConfig(config); err != nil {
+			return []int{}, err
+		}
+	}
+	smtpConfigs := buildSMTPNewConfigs(channelReq, userId)
+	for _, config := range smtpConfigs {
+		if config.Default {
+			_, err := impl.smtpRepository.UpdateSMTPConfigDefault()
+			if err != nil && !util.IsErrNoRows(err) {
+				impl.logger.Errorw("err while updating smtp config", "err", err)
+				return []int{}, err
+			}
+		} else {
+			existingDefault, err := impl.smtpRepository.FindDefault()
+			if err != nil && !util.IsErrNoRows(err) {
+				impl.logger.Errorw("err while updating smtp config", "err", err)
+				return []int{}, err
+			} else if util.IsErrNoRows(err) || existingDefault.Id == config.Id {
+				config.Default = true
+			}
+		}
+		if config.Id != 0 {
+			model, err := impl.smtpRepository.FindOne(config.Id)
+			if err != nil {
+				impl.logger.Errorw("err while fetching smtp config", "err", err)
+				return []int{}, err
+			}
+			impl.buildConfigUpdateModel(config, model, userId)
+			_, uErr := impl.smtpRepository.UpdateSMTPConfig(model)
+			if uErr != nil {
+				impl.logger.Errorw("err while updating smtp config", "err", uErr)
+				return []int{}, uErr
+			}
+		} else {
+			_, iErr := impl.smtpRepository.SaveSMTPConfig(config)
+			if iErr != nil {
+				impl.logger.Errorw("err while inserting smtp config", "err", iErr)
+				return []int{}, iErr
+			}
+		}
+		responseIds = append(responseIds, config.Id)
+	}
+	return responseIds, nil
+}
+
+func (impl *SMTPNotificationServiceImpl) FetchSMTPNotificationConfigById(id int) (*SMTPConfigDto, error) {
+	smtpConfig, err := impl.smtpRepository.FindOne(id)
+	if err != nil {
+		impl.logger.Errorw("cannot find smtp config", "id", id, "err", err)
+		return nil, err
+	}
+	return impl.adaptSMTPConfig(smtpConfig), nil
+}
+
+func (impl *SMTPNotificationServiceImpl) FetchAllSMTPNotificationConfig() ([]*SMTPConfigDto, error) {
+	responseDto := make([]*SMTPConfigDto, 0)
+	smtpConfigs, err := impl.smtpRepository.FindAll()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("cannot find all smtp config", "err", err)
+		return responseDto, err
+	}
+	for _, smtpConfig := range smtpConfigs {
+		responseDto = append(responseDto, impl.adaptSMTPConfig(smtpConfig))
+	}
+	return responseDto, nil
+}
+
+func (impl *SMTPNotificationServiceImpl) FetchAllSMTPNotificationConfigAutocomplete() ([]*NotificationChannelAutoResponse, error) {
+	var responseDto []*NotificationChannelAutoResponse
+	smtpConfigs, err := impl.smtpRepository.FindAll()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("cannot find all smtp config", "err", err)
+		return []*NotificationChannelAutoResponse{}, err
+	}
+	for _, smtpConfig := range smtpConfigs {
+		responseDto = append(responseDto, &NotificationChannelAutoResponse{
+			Id:         smtpConfig.Id,
+			ConfigName: smtpConfig.ConfigName,
+		})
+	}
+	return responseDto, nil
+}
+
+func (impl *SMTPNotificationServiceImpl) DeleteNotificationConfig(deleteReq *SMTPConfigDto, userId int32) error {
+	existingConfig, err := impl.smtpRepository.FindOne(deleteReq.Id)
+	if err != nil {
+		impl.logger.Errorw("No matching entry found for delete", "err", err, "id", deleteReq.Id)
+		return err
+	}
+	// notifications are sent through the default config, it can only go once nothing depends on it
+	if existingConfig.Default {
+		configs, err := impl.smtpRepository.FindAll()
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in fetching smtp configs", "err", err)
+			return err
+		}
+		if len(configs) > 1 {
+			return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "Please mark another config as default before deleting the default config"}
+		}
+		notifications, err := impl.notificationSettingsRepository.FindNotificationSettingsByConfigType(SMTP_CONFIG_TYPE)
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in deleting smtp config", "config", deleteReq)
+			return err
+		}
+		if len(notifications) > 0 {
+			impl.logger.Errorw("found notifications using this config, cannot delete", "config", deleteReq)
+			return fmt.Errorf(" Please delete all notifications using this config before deleting")
+		}
+	}
+	existingConfig.UpdatedOn = time.Now()
+	existingConfig.UpdatedBy = userId
+	err = impl.smtpRepository.MarkSMTPConfigDeleted(existingConfig)
+	if err != nil {
+		impl.logger.Errorw("error in deleting smtp config", "err", err, "id", existingConfig.Id)
+		return err
+	}
+	return nil
+}
+
+func (impl *SMTPNotificationServiceImpl) SendTestMail(request *SMTPTestMailRequest) error {
+	var smtpConfig *repository.SMTPConfig
+	if request.Config != nil {
+		if err := validateSMTPConfig(request.Config); err != nil {
+			return err
+		}
+		smtpConfig = buildSMTPNewConfigs([]*SMTPConfigDto{request.Config}, 0)[0]
+		if request.Config.Id > 0 && request.Config.AuthPassword == smtpSecretMask {
+			existingConfig, err := impl.smtpRepository.FindOne(request.Config.Id)
+			if err != nil {
+				impl.logger.Errorw("error in fetching smtp config", "id", request.Config.Id, "err", err)
+				return err
+			}
+			smtpConfig.AuthPassword = existingConfig.AuthPassword
+		}
+	} else {
+		var err error
+		smtpConfig, err = impl.smtpRepository.FindOne(request.ConfigId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching smtp config", "id", request.ConfigId, "err", err)
+			return err
+		}
+	}
+	err := sendSMTPMail(smtpConfig, []string{request.ToEmail}, "Devtron test mail",
+		"<html><body><p>SMTP config "+smtpConfig.ConfigName+" is working.</p></body></html>")
+	if err != nil {
+		impl.logger.Errorw("error in sending test mail", "config", smtpConfig.ConfigName, "err", err)
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("could not send mail: %s", err.Error()), InternalMessage: err.Error()}
+	}
+	return nil
+}
+
+func (impl *SMTPNotificationServiceImpl) SendEventNotification(event *NotificationEvent) {
+	settings, err := impl.notificationSettingsRepository.FindNotificationSettingsForEvent(event.PipelineType, event.EventTypeId, event.PipelineId, event.TeamId, event.AppId, event.EnvId, SMTP_CONFIG_TYPE)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching notification settings for smtp", "event", event, "err", err)
+		return
+	}
+	recipientMap := make(map[string]bool)
+	var userIds []int32
+	for _, setting := range settings {
+		var providers []*Provider
+		if err := json.Unmarshal([]byte(setting.Config), &providers); err != nil {
+			impl.logger.Errorw("error in parsing notification providers", "settingId", setting.Id, "err", err)
+			continue
+		}
+		for _, provider := range providers {
+			if provider.Destination != util2.SMTP {
+				continue
+			}
+			if len(provider.Recipient) > 0 {
+				recipientMap[provider.Recipient] = true
+			} else if provider.ConfigId > 0 {
+				userIds = append(userIds, int32(provider.ConfigId))
+			}
+		}
+	}
+	if len(userIds) > 0 {
+		users, err := impl.userRepository.GetByIds(userIds)
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in fetching users for smtp notification", "userIds", userIds, "err", err)
+			return
+		}
+		for _, user := range users {
+			recipientMap[user.EmailId] = true
+		}
+	}
+	if len(recipientMap) == 0 {
+		return
+	}
+	smtpConfig, err := impl.smtpRepository.FindDefault()
+	if err != nil {
+		impl.logger.Errorw("no default smtp config found, skipping mail notification", "event", event, "err", err)
+		return
+	}
+	var recipients []string
+	for recipient := range recipientMap {
+		recipients = append(recipients, recipient)
+	}
+	mail, err := impl.RenderEventMail(event)
+	if err != nil {
+		impl.logger.Errorw("error in rendering mail notification", "event", event, "err", err)
+		return
+	}
+	go func() {
+		if err := sendSMTPMail(smtpConfig, recipients, mail.Subject, mail.Body); err != nil {
+			impl.logger.Errorw("error in sending mail notification", "config", smtpConfig.ConfigName, "err", err)
+		}
+	}()
+}
+
+func (impl *SMTPNotificationServiceImpl) RenderEventMail(event *NotificationEvent) (*EventMail, error) {
+	data := map[string]interface{}{"Event": event.Data, "EventType": smtpEventTypeNames[event.EventTypeId]}
+	var subject, body, link bytes.Buffer
+	if err := smtpMailSubject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := smtpMailBody.Execute(&body, data); err != nil {
+		return nil, err
+	}
+	if err := smtpMailLink.Execute(&link, data); err != nil {
+		return nil, err
+	}
+	return &EventMail{Subject: subject.String(), Body: body.String(), Link: link.String()}, nil
+}
+
+func (impl *SMTPNotificationServiceImpl) SendMail(to []string, subject string, body string) error {
+	smtpConfig, err := impl.smtpRepository.FindDefault()
+	if err != nil {
+		impl.logger.Errorw("error in fetching default smtp config", "err", err)
+		return err
+	}
+	return sendSMTPMail(smtpConfig, to, subject, body)
+}
+
+func sendSMTPMail(smtpConfig *repository.SMTPConfig, to []string, subject string, body string) error {
+	addr := net.JoinHostPort(smtpConfig.Host, smtpConfig.Port)
+	tlsConfig := &tls.Config{ServerName: smtpConfig.Host}
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	var conn net.Conn
+	var err error
+	if smtpConfig.TlsMode == SmtpTlsModeSsl {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, smtpConfig.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if smtpConfig.TlsMode == SmtpTlsModeStartTls {
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if len(smtpConfig.AuthUser) > 0 {
+		if err = client.Auth(smtp.PlainAuth("", smtpConfig.AuthUser, smtpConfig.AuthPassword, smtpConfig.Host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(smtpConfig.FromEmail); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err = client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	message := "From: " + smtpConfig.FromEmail + "\r\n" +
+		"To: " + strings.Join(to, ", ") + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/html; charset=\"UTF-8\"\r\n\r\n" + body
+	if _, err = writer.Write([]byte(message)); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (impl *SMTPNotificationServiceImpl) adaptSMTPConfig(smtpConfig *repository.SMTPConfig) *SMTPConfigDto {
+	smtpConfigDto := &SMTPConfigDto{
+		OwnerId:     smtpConfig.OwnerId,
+		Host:        smtpConfig.Host,
+		Port:        smtpConfig.Port,
+		TlsMode:     smtpConfig.TlsMode,
+		AuthUser:    smtpConfig.AuthUser,
+		FromEmail:   smtpConfig.FromEmail,
+		ConfigName:  smtpConfig.ConfigName,
+		Description: smtpConfig.Description,
+		Id:          smtpConfig.Id,
+		Default:     smtpConfig.Default,
+	}
+	if len(smtpConfig.AuthPassword) > 0 {
+		smtpConfigDto.AuthPassword = smtpSecretMask
+	}
+	return smtpConfigDto
+}
+
+// validateSMTPConfig rejects credentials on a config without tls, they would be sent to the server in plain text
+func validateSMTPConfig(config *SMTPConfigDto) error {
+	if config.TlsMode == SmtpTlsModeNone && (len(config.AuthUser) > 0 || len(config.AuthPassword) > 0) {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "smtp credentials without tls", UserMessage: "tls mode NONE can not be used with a username or password, use STARTTLS or SSL"}
+	}
+	return nil
+}
+
+func buildSMTPNewConfigs(smtpReq []*SMTPConfigDto, userId int32) []*repository.SMTPConfig {
+	var smtpConfigs []*repository.SMTPConfig
+	for _, c := range smtpReq {
+		smtpConfig := &repository.SMTPConfig{
+			Id:           c.Id,
+			Host:         c.Host,
+			Port:         c.Port,
+			TlsMode:      c.TlsMode,
+			AuthUser:     c.AuthUser,
+			AuthPassword: c.AuthPassword,
+			FromEmail:    c.FromEmail,
+			ConfigName:   c.ConfigName,
+			Description:  c.Description,
+			Default:      c.Default,
+			OwnerId:      userId,
+			AuditLog: sql.AuditLog{
+				CreatedBy: userId,
+				CreatedOn: time.Now(),
+				UpdatedOn: time.Now(),
+				UpdatedBy: userId,
+			},
+		}
+		smtpConfigs = append(smtpConfigs, smtpConfig)
+	}
+	return smtpConfigs
+}
+
+func (impl *SMTPNotificationServiceImpl) buildConfigUpdateModel(smtpConfig *repository.SMTPConfig, model *repository.SMTPConfig, userId int32) {
+	model.Host = smtpConfig.Host
+	model.Port = smtpConfig.Port
+	model.TlsMode = smtpConfig.TlsMode
+	model.AuthUser = smtpConfig.AuthUser
+	// the password is masked when fetched, an unchanged mask keeps the stored one
+	if smtpConfig.AuthPassword != smtpSecretMask {
+		model.AuthPassword = smtpConfig.AuthPassword
+	}
+	model.FromEmail = smtpConfig.FromEmail
+	model.ConfigName = smtpConfig.ConfigName
+	model.Description = smtpConfig.Description
+	model.Default = smtpConfig.Default
+	model.UpdatedOn = time.Now()
+	model.UpdatedBy = userId
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package notifier
+
+import "testing"
+
+func TestValidateSMTPConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *SMTPConfigDto
+		wantErr bool
+	}{
+		{name: "no tls without credentials", config: &SMTPConfigDto{TlsMode: SmtpTlsModeNone}},
+		{name: "no tls with a username", config: &SMTPConfigDto{TlsMode: SmtpTlsModeNone, AuthUser: "devtron"}, wantErr: true},
+		{name: "no tls with a masked password", config: &SMTPConfigDto{TlsMode: SmtpTlsModeNone, AuthPassword: smtpSecretMask}, wantErr: true},
+		{name: "starttls with credentials", config: &SMTPConfigDto{TlsMode: SmtpTlsModeStartTls, AuthUser: "devtron", AuthPassword: "secret"}},
+		{name: "ssl with credentials", config: &SMTPConfigDto{TlsMode: SmtpTlsModeSsl, AuthUser: "devtron", AuthPassword: "secret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSMTPConfig(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("validateSMTPConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,412 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package notifier
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	util2 "github.com/devtron-labs/devtron/util/event"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const WEBHOOK_CONFIG_TYPE = "webhook"
+
+const (
+	// WebhookSignatureHeader carries the hmac of the timestamp and body, joined by a dot, so that receivers can reject
+	// replayed requests by the timestamp
+	WebhookSignatureHeader = "X-Devtron-Signature"
+	WebhookTimestampHeader = "X-Devtron-Timestamp"
+	webhookSecretMask      = "**********"
+	webhookRequestTimeout  = 10 * time.Second
+)
+
+type WebhookNotificationService interface {
+	SaveOrEditNotificationConfig(channelReq []WebhookConfigDto, userId int32) ([]int, error)
+	FetchWebhookNotificationConfigById(id int) (*WebhookConfigDto, error)
+	FetchAllWebhookNotificationConfig() ([]*WebhookConfigDto, error)
+	FetchAllWebhookNotificationConfigAutocomplete() ([]*NotificationChannelAutoResponse, error)
+	DeleteNotificationConfig(deleteReq *WebhookConfigDto, userId int32) error
+	// SendEventNotification posts the event to every webhook configured for it, requests are sent in the background
+	SendEventNotification(event *NotificationEvent)
+}
+
+type WebhookNotificationServiceImpl struct {
+	logger                         *zap.SugaredLogger
+	webhookRepository              repository.WebhookNotificationRepository
+	notificationSettingsRepository repository.NotificationSettingsRepository
+	client                         *http.Client
+}
+
+type WebhookChannelConfig struct {
+	Channel           util2.Channel      `json:"channel" validate:"required"`
+	WebhookConfigDtos []WebhookConfigDto `json:"configs"`
+}
+
+type WebhookConfigDto struct {
+	OwnerId     int32             `json:"userId" validate:"number"`
+	WebhookUrl  string            `json:"webhookUrl" validate:"required,url"`
+	ConfigName  string            `json:"configName" validate:"required"`
+	Header      map[string]string `json:"header"`
+	Payload     string            `json:"payload"`
+	Secret      string            `json:"secret"`
+	Description string            `json:"description"`
+	Id          int               `json:"id" validate:"number"`
+}
+
+var webhookTemplateFuncs = template.FuncMap{
+	"toJson": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+func NewWebhookNotificationServiceImpl(logger *zap.SugaredLogger, webhookRepository repository.WebhookNotificationRepository,
+	notificationSettingsRepository repository.NotificationSettingsRepository) *WebhookNotificationServiceImpl {
+	return &WebhookNotificationServiceImpl{
+		logger:                         logger,
+		webhookRepository:              webhookRepository,
+		notificationSettingsRepository: notificationSettingsRepository,
+		client:                         &http.Client{Timeout: webhookRequestTimeout},
+	}
+}
+
+func (impl *WebhookNotificationServiceImpl) SaveOrEditNotificationConfig(channelReq []WebhookConfigDto, userId int32) ([]int, error) {
+	var responseIds []int
+	for _, req := range channelReq {
+		if _, err := parseWebhookPayload(req.Payload); err != nil {
+			impl.logger.Errorw("invalid webhook payload template", "configName", req.ConfigName, "err", err)
+			return []int{}, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("invalid payload template for %s: %s", req.ConfigName, err.Error())}
+		}
+	}
+	webhookConfigs, err := buildWebhookNewConfigs(channelReq, userId)
+	if err != nil {
+		impl.logger.Errorw("err while building webhook config", "err", err)
+		return []int{}, err
+	}
+	for _, config := range webhookConfigs {
+		if config.Id != 0 {
+			model, err := impl.webhookRepository.FindOne(config.Id)
+			if err != nil {
+				impl.logger.Errorw("err while fetching webhook config", "err", err)
+				return []int{}, err
+			}
+			impl.buildConfigUpdateModel(config, model, userId)
+			model, uErr := impl.webhookRepository.UpdateWebhookConfig(model)
+			if uErr != nil {
+				impl.logger.Errorw("err while updating webhook config", "err", uErr)
+				return []int{}, uErr
+			}
+		} else {
+			_, iErr := impl.webhookRepository.SaveWebhookConfig(config)
+			if iErr != nil {
+				impl.logger.Errorw("err while inserting webhook config", "err", iErr)
+				return []int{}, iErr
+			}
+		}
+		responseIds = append(responseIds, config.Id)
+	}
+	return responseIds, nil
+}
+
+func (impl *WebhookNotificationServiceImpl) FetchWebhookNotificationConfigById(id int) (*WebhookConfigDto, error) {
+	webhookConfig, err := impl.webhookRepository.FindOne(id)
+	if err != nil {
+		impl.logger.Errorw("cannot find webhook config", "id", id, "err", err)
+		return nil, err
+	}
+	return impl.adaptWebhookConfig(webhookConfig), nil
+}
+
+func (impl *WebhookNotificationServiceImpl) FetchAllWebhookNotificationConfig() ([]*WebhookConfigDto, error) {
+	responseDto := make([]*WebhookConfigDto, 0)
+	webhookConfigs, err := impl.webhookRepository.FindAll()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("cannot find all webhook config", "err", err)
+		return responseDto, err
+	}
+	for _, webhookConfig := range webhookConfigs {
+		responseDto = append(responseDto, impl.adaptWebhookConfig(webhookConfig))
+	}
+	return responseDto, nil
+}
+
+func (impl *WebhookNotificationServiceImpl) FetchAllWebhookNotificationConfigAutocomplete() ([]*NotificationChannelAutoResponse, error) {
+	var responseDto []*NotificationChannelAutoResponse
+	webhookConfigs, err := impl.webhookRepository.FindAll()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("cannot find all webhook config", "err", err)
+		return []*NotificationChannelAutoResponse{}, err
+	}
+	for _, webhookConfig := range webhookConfigs {
+		responseDto = append(responseDto, &NotificationChannelAutoResponse{
+			Id:         webhookConfig.Id,
+			ConfigName: webhookConfig.ConfigName,
+		})
+	}
+	return responseDto, nil
+}
+
+func (impl *WebhookNotificationServiceImpl) DeleteNotificationConfig(deleteReq *WebhookConfigDto, userId int32) error {
+	existingConfig, err := impl.webhookRepository.FindOne(deleteReq.Id)
+	if err != nil {
+		impl.logger.Errorw("No matching entry found for delete", "err", err, "id", deleteReq.Id)
+		return err
+	}
+	notifications, err := impl.notificationSettingsRepository.FindNotificationSettingsByConfigIdAndConfigType(deleteReq.Id, WEBHOOK_CONFIG_TYPE)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in deleting webhook config", "config", deleteReq)
+		return err
+	}
+	if len(notifications) > 0 {
+		impl.logger.Errorw("found notifications using this config, cannot delete", "config", deleteReq)
+		return fmt.Errorf(" Please delete all notifications using this config before deleting")
+	}
+	existingConfig.UpdatedOn = time.Now()
+	existingConfig.UpdatedBy = userId
+	err = impl.webhookRepository.MarkWebhookConfigDeleted(existingConfig)
+	if err != nil {
+		impl.logger.Errorw("error in deleting webhook config", "err", err, "id", existingConfig.Id)
+		return err
+	}
+	return nil
+}
+
+func (impl *WebhookNotificationServiceImpl) SendEventNotification(event *NotificationEvent) {
+	settings, err := impl.notificationSettingsRepository.FindNotificationSettingsForEvent(event.PipelineType, event.EventTypeId, event.PipelineId, event.TeamId, event.AppId, event.EnvId, WEBHOOK_CONFIG_TYPE)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching notification settings for webhook", "event", event, "err", err)
+		return
+	}
+	configIdMap := make(map[int]bool)
+	for _, setting := range settings {
+		var providers []*Provider
+		if err := json.Unmarshal([]byte(setting.Config), &providers); err != nil {
+			impl.logger.Errorw("error in parsing notification providers", "settingId", setting.Id, "err", err)
+			continue
+		}
+		for _, provider := range providers {
+			if provider.Destination == util2.Webhook {
+				configIdMap[provider.ConfigId] = true
+			}
+		}
+	}
+	if len(configIdMap) == 0 {
+		return
+	}
+	var configIds []int
+	for id := range configIdMap {
+		configIds = append(configIds, id)
+	}
+	webhookConfigs, err := impl.webhookRepository.FindByIdsIn(configIds)
+	if err != nil {
+		impl.logger.Errorw("error in fetching webhook configs", "ids", configIds, "err", err)
+		return
+	}
+	for _, webhookConfig := range webhookConfigs {
+		go impl.sendWebhook(webhookConfig, event.Data)
+	}
+}
+
+func (impl *WebhookNotificationServiceImpl) sendWebhook(webhookConfig *repository.WebhookConfig, data interface{}) {
+	body, err := renderWebhookPayload(webhookConfig.Payload, data)
+	if err != nil {
+		impl.logger.Errorw("error in rendering webhook payload", "configId", webhookConfig.Id, "err", err)
+		return
+	}
+	req, err := http.NewRequest(http.MethodPost, webhookConfig.WebHookUrl, bytes.NewBuffer(body))
+	if err != nil {
+		impl.logger.Errorw("error in building webhook request", "configId", webhookConfig.Id, "err", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	headers, err := parseWebhookHeaders(webhookConfig.Header)
+	if err != nil {
+		impl.logger.Errorw("error in parsing webhook headers", "configId", webhookConfig.Id, "err", err)
+		return
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if len(webhookConfig.Secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+signWebhookPayload(webhookConfig.Secret, timestamp, body))
+	}
+	resp, err := impl.client.Do(req)
+	if err != nil {
+		impl.logger.Errorw("error in sending webhook notification", "configId", webhookConfig.Id, "err", err)
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		impl.logger.Errorw("webhook notification rejected", "configId", webhookConfig.Id, "status", resp.StatusCode)
+		return
+	}
+	impl.logger.Debugw("webhook notification sent", "configId", webhookConfig.Id, "status", resp.StatusCode)
+}
+
+func parseWebhookPayload(payload string) (*template.Template, error) {
+	return template.New("payload").Funcs(webhookTemplateFuncs).Option("missingkey=zero").Parse(payload)
+}
+
+// renderWebhookPayload sends the event as plain json when no template is configured
+func renderWebhookPayload(payload string, data interface{}) ([]byte, error) {
+	if len(strings.TrimSpace(payload)) == 0 {
+		return json.Marshal(data)
+	}
+	tmpl, err := parseWebhookPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func signWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func parseWebhookHeaders(header string) (map[string]string, error) {
+	if len(header) == 0 {
+		return nil, nil
+	}
+	headers := make(map[string]string)
+	err := json.Unmarshal([]byte(header), &headers)
+	return headers, err
+}
+
+func (impl *WebhookNotificationServiceImpl) adaptWebhookConfig(webhookConfig *repository.WebhookConfig) *WebhookConfigDto {
+	webhookConfigDto := &WebhookConfigDto{
+		OwnerId:     webhookConfig.OwnerId,
+		WebhookUrl:  webhookConfig.WebHookUrl,
+		ConfigName:  webhookConfig.ConfigName,
+		Payload:     webhookConfig.Payload,
+		Description: webhookConfig.Description,
+		Id:          webhookConfig.Id,
+	}
+	// header values often hold tokens, they are masked like the secret
+	headers, err := parseWebhookHeaders(webhookConfig.Header)
+	if err != nil {
+		impl.logger.Errorw("error in parsing webhook headers", "configId", webhookConfig.Id, "err", err)
+	}
+	for key := range headers {
+		headers[key] = webhookSecretMask
+	}
+	webhookConfigDto.Header = headers
+	if len(webhookConfig.Secret) > 0 {
+		webhookConfigDto.Secret = webhookSecretMask
+	}
+	return webhookConfigDto
+}
+
+func buildWebhookNewConfigs(webhookReq []WebhookConfigDto, userId int32) ([]*repository.WebhookConfig, error) {
+	var webhookConfigs []*repository.WebhookConfig
+	for _, c := range webhookReq {
+		var header []byte
+		if len(c.Header) > 0 {
+			var err error
+			header, err = json.Marshal(c.Header)
+			if err != nil {
+				return nil, err
+			}
+		}
+		webhookConfig := &repository.WebhookConfig{
+			Id:          c.Id,
+			WebHookUrl:  c.WebhookUrl,
+			ConfigName:  c.ConfigName,
+			Header:      string(header),
+			Payload:     c.Payload,
+			Secret:      c.Secret,
+			Description: c.Description,
+			OwnerId:     userId,
+			AuditLog: sql.AuditLog{
+				CreatedBy: userId,
+				CreatedOn: time.Now(),
+				UpdatedOn: time.Now(),
+				UpdatedBy: userId,
+			},
+		}
+		webhookConfigs = append(webhookConfigs, webhookConfig)
+	}
+	return webhookConfigs, nil
+}
+
+func (impl *WebhookNotificationServiceImpl) buildConfigUpdateModel(webhookConfig *repository.WebhookConfig, model *repository.WebhookConfig, userId int32) {
+	model.Id = webhookConfig.Id
+	model.WebHookUrl = webhookConfig.WebHookUrl
+	model.ConfigName = webhookConfig.ConfigName
+	model.Header = impl.mergeMaskedHeaders(webhookConfig.Header, model)
+	model.Payload = webhookConfig.Payload
+	// the secret is masked when fetched, an unchanged mask keeps the stored one
+	if webhookConfig.Secret != webhookSecretMask {
+		model.Secret = webhookConfig.Secret
+	}
+	model.Description = webhookConfig.Description
+	model.UpdatedOn = time.Now()
+	model.UpdatedBy = userId
+}
+
+// mergeMaskedHeaders keeps the stored value of every header sent back with the mask it was fetched with
+func (impl *WebhookNotificationServiceImpl) mergeMaskedHeaders(header string, model *repository.WebhookConfig) string {
+	headers, err := parseWebhookHeaders(header)
+	if err != nil || len(headers) == 0 {
+		return header
+	}
+	storedHeaders, err := parseWebhookHeaders(model.Header)
+	if err != nil {
+		impl.logger.Errorw("error in parsing stored webhook headers", "configId", model.Id, "err", err)
+	}
+	masked := false
+	for key, value := range headers {
+		if value != webhookSecretMask {
+			continue
+		}
+		if storedValue, ok := storedHeaders[key]; ok {
+			headers[key] = storedValue
+			masked = true
+		}
+	}
+	if !masked {
+		return header
+	}
+	merged, err := json.Marshal(headers)
+	if err != nil {
+		impl.logger.Errorw("error in building webhook headers", "configId", model.Id, "err", err)
+		return header
+	}
+	return string(merged)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package notifier
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	body := []byte(`{"eventType":"trigger"}`)
+	signature := signWebhookPayload("secret", "1615374000", body)
+	// hmac-sha256 of 1615374000.{"eventType":"trigger"} with key secret
+	if want := "9757cb2efc061b99d8c48cc553a73a140a15141c94cad304818f913fd8e34f3e"; signature != want {
+		t.Fatalf("signWebhookPayload() = %s, want %s", signature, want)
+	}
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+	}{
+		{name: "another secret", secret: "other", timestamp: "1615374000", body: body},
+		{name: "replayed with a new timestamp", secret: "secret", timestamp: "1615374060", body: body},
+		{name: "another body", secret: "secret", timestamp: "1615374000", body: []byte(`{"eventType":"success"}`)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signWebhookPayload(tt.secret, tt.timestamp, tt.body); got == signature {
+				t.Errorf("signWebhookPayload() = %s, want a signature different from the original", got)
+			}
+		})
+	}
+}
+
+func TestWebhookHeaderMasking(t *testing.T) {
+	impl := &WebhookNotificationServiceImpl{logger: util.NewSugardLogger()}
+	stored := &repository.WebhookConfig{Id: 1, Header: `{"Authorization":"Bearer token","X-Team":"payments"}`, Secret: "secret"}
+	fetched := impl.adaptWebhookConfig(stored)
+	wantFetched := map[string]string{"Authorization": webhookSecretMask, "X-Team": webhookSecretMask}
+	if !reflect.DeepEqual(fetched.Header, wantFetched) || fetched.Secret != webhookSecretMask {
+		t.Fatalf("adaptWebhookConfig() header = %v, secret = %s, want masked values", fetched.Header, fetched.Secret)
+	}
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   map[string]string
+	}{
+		{name: "masked values keep the stored ones", header: fetched.Header, want: map[string]string{"Authorization": "Bearer token", "X-Team": "payments"}},
+		{name: "changed value is saved", header: map[string]string{"Authorization": "Bearer rotated", "X-Team": webhookSecretMask}, want: map[string]string{"Authorization": "Bearer rotated", "X-Team": "payments"}},
+		{name: "removed header is dropped", header: map[string]string{"X-Team": webhookSecretMask}, want: map[string]string{"X-Team": "payments"}},
+		{name: "mask of a new header is kept as is", header: map[string]string{"X-New": webhookSecretMask}, want: map[string]string{"X-New": webhookSecretMask}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configs, err := buildWebhookNewConfigs([]WebhookConfigDto{{Id: 1, Header: tt.header, Secret: webhookSecretMask}}, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			model := *stored
+			impl.buildConfigUpdateModel(configs[0], &model, 1)
+			got := make(map[string]string)
+			if err := json.Unmarshal([]byte(model.Header), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updated header = %v, want %v", got, tt.want)
+			}
+			if model.Secret != "secret" {
+				t.Errorf("updated secret = %s, want the stored secret", model.Secret)
+			}
+		})
+	}
+}
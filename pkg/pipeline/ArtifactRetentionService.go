@@ -0,0 +1,440 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	"github.com/caarlos0/env"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const artifactRetentionUserId int32 = 1 //system user
+
+const (
+	artifactRetentionJobName = "artifact-retention"
+	// artifactRetentionRunJobName is held by scheduled and manual runs alike for as long as they delete
+	artifactRetentionRunJobName = "artifact-retention-run"
+)
+
+type ArtifactRetentionConfig struct {
+	RetentionEnabled bool   `env:"ARTIFACT_RETENTION_ENABLED" envDefault:"true"`
+	RetentionCron    string `env:"ARTIFACT_RETENTION_CRON" envDefault:"0 3 * * *"`
+}
+
+type ArtifactRetentionPolicyDto struct {
+	Id                int   `json:"id"`
+	AppId             int   `json:"appId" validate:"number,gte=0"`             //0 for the global policy
+	KeepArtifactCount int   `json:"keepArtifactCount" validate:"number,gte=0"` //0 keeps all artifacts
+	LogRetentionDays  int   `json:"logRetentionDays" validate:"number,gte=0"`  //0 keeps all logs
+	UserId            int32 `json:"-"`
+}
+
+type ArtifactRetentionService interface {
+	SavePolicy(request *ArtifactRetentionPolicyDto) (*ArtifactRetentionPolicyDto, error)
+	GetPolicies() ([]*ArtifactRetentionPolicyDto, error)
+	// DeletePolicy removes the policy of the app, the global policy when appId is 0
+	DeletePolicy(appId int, userId int32) error
+	// ApplyRetention starts a run of the active policies and returns it, the run is completed in the background.
+	// A dry run only reports what would be removed.
+	ApplyRetention(dryRun bool, userId int32) (*pipelineConfig.ArtifactRetentionRun, error)
+	GetRun(id int) (*pipelineConfig.ArtifactRetentionRun, error)
+	GetRuns(offset int, limit int) ([]*pipelineConfig.ArtifactRetentionRun, error)
+}
+
+type ArtifactRetentionServiceImpl struct {
+	logger                      *zap.SugaredLogger
+	scheduledJobRunner          util3.ScheduledJobRunner
+	config                      *ArtifactRetentionConfig
+	ciConfig                    *CiConfig
+	artifactRetentionRepository pipelineConfig.ArtifactRetentionRepository
+	ciPipelineRepository        pipelineConfig.CiPipelineRepository
+	ciArtifactRepository        repository.CiArtifactRepository
+	ciWorkflowRepository        pipelineConfig.CiWorkflowRepository
+	ciLogService                CiLogService
+}
+
+func NewArtifactRetentionServiceImpl(logger *zap.SugaredLogger, ciConfig *CiConfig,
+	artifactRetentionRepository pipelineConfig.ArtifactRetentionRepository,
+	ciPipelineRepository pipelineConfig.CiPipelineRepository,
+	ciArtifactRepository repository.CiArtifactRepository,
+	ciWorkflowRepository pipelineConfig.CiWorkflowRepository,
+	ciLogService CiLogService, scheduledJobRunner util3.ScheduledJobRunner) (*ArtifactRetentionServiceImpl, error) {
+	cfg := &ArtifactRetentionConfig{}
+	err := env.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	impl := &ArtifactRetentionServiceImpl{
+		logger:                      logger,
+		config:                      cfg,
+		ciConfig:                    ciConfig,
+		artifactRetentionRepository: artifactRetentionRepository,
+		ciPipelineRepository:        ciPipelineRepository,
+		ciArtifactRepository:        ciArtifactRepository,
+		ciWorkflowRepository:        ciWorkflowRepository,
+		ciLogService:                ciLogService,
+		scheduledJobRunner:          scheduledJobRunner,
+	}
+	if !cfg.RetentionEnabled {
+		return impl, nil
+	}
+	err = scheduledJobRunner.Schedule(artifactRetentionJobName, cfg.RetentionCron, impl.applyScheduledRetention)
+	if err != nil {
+		logger.Errorw("error in starting artifact retention cron", "cron", cfg.RetentionCron, "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+func (impl *ArtifactRetentionServiceImpl) SavePolicy(request *ArtifactRetentionPolicyDto) (*ArtifactRetentionPolicyDto, error) {
+	policy, err := impl.artifactRetentionRepository.FindActivePolicyByAppId(request.AppId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching retention policy", "appId", request.AppId, "err", err)
+		return nil, err
+	}
+	if err == pg.ErrNoRows {
+		policy = &pipelineConfig.ArtifactRetentionPolicy{
+			AppId:    request.AppId,
+			Active:   true,
+			AuditLog: sql.AuditLog{CreatedOn: time.Now(), CreatedBy: request.UserId},
+		}
+	}
+	policy.KeepArtifactCount = request.KeepArtifactCount
+	policy.LogRetentionDays = request.LogRetentionDays
+	policy.UpdatedOn = time.Now()
+	policy.UpdatedBy = request.UserId
+	if policy.Id == 0 {
+		err = impl.artifactRetentionRepository.SavePolicy(policy)
+	} else {
+		err = impl.artifactRetentionRepository.UpdatePolicy(policy)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving retention policy", "policy", policy, "err", err)
+		return nil, err
+	}
+	return impl.toPolicyDto(policy), nil
+}
+
+func (impl *ArtifactRetentionServiceImpl) GetPolicies() ([]*ArtifactRetentionPolicyDto, error) {
+	policies, err := impl.artifactRetentionRepository.FindActivePolicies()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching retention policies", "err", err)
+		return nil, err
+	}
+	dtos := make([]*ArtifactRetentionPolicyDto, 0, len(policies))
+	for _, policy := range policies {
+		dtos = append(dtos, impl.toPolicyDto(policy))
+	}
+	return dtos, nil
+}
+
+func (impl *ArtifactRetentionServiceImpl) DeletePolicy(appId int, userId int32) error {
+	policy, err := impl.artifactRetentionRepository.FindActivePolicyByAppId(appId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching retention policy", "appId", appId, "err", err)
+		return err
+	}
+	policy.Active = false
+	policy.UpdatedOn = time.Now()
+	policy.UpdatedBy = userId
+	err = impl.artifactRetentionRepository.UpdatePolicy(policy)
+	if err != nil {
+		impl.logger.Errorw("error in deleting retention policy", "appId", appId, "err", err)
+	}
+	return err
+}
+
+func (impl *ArtifactRetentionServiceImpl) toPolicyDto(policy *pipelineConfig.ArtifactRetentionPolicy) *ArtifactRetentionPolicyDto {
+	return &ArtifactRetentionPolicyDto{
+		Id:                policy.Id,
+		AppId:             policy.AppId,
+		KeepArtifactCount: policy.KeepArtifactCount,
+		LogRetentionDays:  policy.LogRetentionDays,
+	}
+}
+
+func (impl *ArtifactRetentionServiceImpl) applyScheduledRetention() {
+	_, err := impl.ApplyRetention(false, artifactRetentionUserId)
+	if err != nil {
+		impl.logger.Errorw("error in scheduled artifact retention", "err", err)
+	}
+}
+
+func (impl *ArtifactRetentionServiceImpl) ApplyRetention(dryRun bool, userId int32) (*pipelineConfig.ArtifactRetentionRun, error) {
+	// runs delete blobs and rows, two of them over the same pipelines would only race each other
+	release, acquired, err := impl.scheduledJobRunner.Acquire(artifactRetentionRunJobName)
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusConflict, InternalMessage: "artifact retention is already running", UserMessage: "artifact retention is already running"}
+	}
+	run := &pipelineConfig.ArtifactRetentionRun{
+		Status:    pipelineConfig.ARTIFACT_RETENTION_RUNNING,
+		DryRun:    dryRun,
+		StartedOn: time.Now(),
+		AuditLog:  sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+	}
+	err = impl.artifactRetentionRepository.SaveRun(run)
+	if err != nil {
+		release()
+		impl.logger.Errorw("error in saving artifact retention run", "err", err)
+		return nil, err
+	}
+	go func() {
+		defer release()
+		impl.processRun(run)
+	}()
+	return run, nil
+}
+
+func (impl *ArtifactRetentionServiceImpl) processRun(run *pipelineConfig.ArtifactRetentionRun) {
+	err := impl.applyPolicies(run)
+	run.Status = pipelineConfig.ARTIFACT_RETENTION_SUCCEEDED
+	if err != nil {
+		run.Status = pipelineConfig.ARTIFACT_RETENTION_FAILED
+		run.Message = err.Error()
+	} else if run.FailedCount > 0 {
+		run.Status = pipelineConfig.ARTIFACT_RETENTION_PARTIALLY_FAILED
+		run.Message = fmt.Sprintf("%d items could not be removed", run.FailedCount)
+	}
+	run.FinishedOn = time.Now()
+	run.UpdatedOn = time.Now()
+	err = impl.artifactRetentionRepository.UpdateRun(run)
+	if err != nil {
+		impl.logger.Errorw("error in updating artifact retention run", "runId", run.Id, "err", err)
+	}
+	impl.logger.Infow("artifact retention run completed", "runId", run.Id, "status", run.Status, "dryRun", run.DryRun,
+		"artifactsDeleted", run.ArtifactsDeleted, "logsDeleted", run.LogsDeleted, "failed", run.FailedCount)
+}
+
+// applyPolicies applies the app policy of every ci pipeline, or the global one when its app has none
+func (impl *ArtifactRetentionServiceImpl) applyPolicies(run *pipelineConfig.ArtifactRetentionRun) error {
+	policies, err := impl.artifactRetentionRepository.FindActivePolicies()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching retention policies", "err", err)
+		return err
+	}
+	policyByApp := make(map[int]*pipelineConfig.ArtifactRetentionPolicy)
+	for _, policy := range policies {
+		policyByApp[policy.AppId] = policy
+	}
+	if len(policyByApp) == 0 {
+		return nil
+	}
+	var ciPipelines []*pipelineConfig.CiPipeline
+	if _, ok := policyByApp[0]; ok {
+		ciPipelines, err = impl.ciPipelineRepository.FindAllActive()
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in fetching ci pipelines", "err", err)
+			return err
+		}
+	} else {
+		for appId := range policyByApp {
+			appPipelines, err := impl.ciPipelineRepository.FindByAppId(appId)
+			if err != nil && err != pg.ErrNoRows {
+				impl.logger.Errorw("error in fetching ci pipelines", "appId", appId, "err", err)
+				return err
+			}
+			ciPipelines = append(ciPipelines, appPipelines...)
+		}
+	}
+	for _, ciPipeline := range ciPipelines {
+		policy, ok := policyByApp[ciPipeline.AppId]
+		if !ok {
+			policy = policyByApp[0]
+		}
+		if policy.KeepArtifactCount > 0 {
+			impl.purgeArtifacts(run, ciPipeline, policy.KeepArtifactCount)
+		}
+		if policy.LogRetentionDays > 0 {
+			impl.purgeLogs(run, ciPipeline, time.Now().AddDate(0, 0, -policy.LogRetentionDays))
+		}
+	}
+	return nil
+}
+
+// purgeArtifacts deletes the artifact rows beyond the latest keepCount, deployed and rollback artifacts are never candidates.
+// Images are left in the registry, registries have their own lifecycle policies.
+func (impl *ArtifactRetentionServiceImpl) purgeArtifacts(run *pipelineConfig.ArtifactRetentionRun, ciPipeline *pipelineConfig.CiPipeline, keepCount int) {
+	artifacts, err := impl.ciArtifactRepository.FindRetentionCandidates(ciPipeline.Id, keepCount)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching retention candidates", "ciPipelineId", ciPipeline.Id, "err", err)
+		run.FailedCount++
+		return
+	}
+	for _, artifact := range artifacts {
+		item := &pipelineConfig.ArtifactRetentionReportItem{
+			Type:         pipelineConfig.ARTIFACT_RETENTION_ITEM_ARTIFACT,
+			AppId:        ciPipeline.AppId,
+			CiPipelineId: ciPipeline.Id,
+			CiArtifactId: artifact.Id,
+			Image:        artifact.Image,
+		}
+		run.Report = append(run.Report, item)
+		if run.DryRun {
+			run.ArtifactsDeleted++
+			continue
+		}
+		err = impl.ciArtifactRepository.DeleteWithDependents(artifact.Id)
+		if err != nil {
+			impl.logger.Errorw("error in deleting artifact", "ciArtifactId", artifact.Id, "err", err)
+			item.Error = err.Error()
+			run.FailedCount++
+			continue
+		}
+		run.ArtifactsDeleted++
+	}
+}
+
+// purgeLogs removes the build logs and artifact archives of the workflows finished before the time
+func (impl *ArtifactRetentionServiceImpl) purgeLogs(run *pipelineConfig.ArtifactRetentionRun, ciPipeline *pipelineConfig.CiPipeline, before time.Time) {
+	workflows, err := impl.ciWorkflowRepository.FindWithLogsFinishedBefore(ciPipeline.Id, before)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching workflows for log retention", "ciPipelineId", ciPipeline.Id, "err", err)
+		run.FailedCount++
+		return
+	}
+	if len(workflows) == 0 {
+		return
+	}
+	ciWorkflowConfig, err := impl.ciWorkflowRepository.FindConfigByPipelineId(ciPipeline.Id)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching ci workflow config", "ciPipelineId", ciPipeline.Id, "err", err)
+		run.FailedCount++
+		return
+	}
+	for _, workflow := range workflows {
+		item := &pipelineConfig.ArtifactRetentionReportItem{
+			Type:         pipelineConfig.ARTIFACT_RETENTION_ITEM_LOG,
+			AppId:        ciPipeline.AppId,
+			CiPipelineId: ciPipeline.Id,
+			CiWorkflowId: workflow.Id,
+		}
+		run.Report = append(run.Report, item)
+		var requests []CiLogRequest
+		if len(workflow.LogLocation) > 0 {
+			requests = append(requests, impl.buildLogRequest(ciWorkflowConfig, "", workflow.LogLocation))
+		}
+		if bucket, key, ok := parseS3Location(workflow.CiArtifactLocation); ok && impl.ciConfig.CloudProvider != BLOB_STORAGE_AZURE {
+			requests = append(requests, impl.buildLogRequest(ciWorkflowConfig, bucket, key))
+		}
+		for _, request := range requests {
+			item.Locations = append(item.Locations, request.LogsBucket+"/"+strings.TrimPrefix(request.LogsFilePath, "/"))
+		}
+		if run.DryRun {
+			run.LogsDeleted++
+			continue
+		}
+		if err = impl.deleteObjects(requests); err != nil {
+			item.Error = err.Error()
+			run.FailedCount++
+			continue
+		}
+		// cleared so that the logs api reports them as removed instead of failing on the missing object
+		workflow.LogLocation = ""
+		workflow.CiArtifactLocation = ""
+		err = impl.ciWorkflowRepository.UpdateWorkFlow(workflow)
+		if err != nil {
+			impl.logger.Errorw("error in updating workflow after log retention", "ciWorkflowId", workflow.Id, "err", err)
+			item.Error = err.Error()
+			run.FailedCount++
+			continue
+		}
+		run.LogsDeleted++
+	}
+}
+
+func (impl *ArtifactRetentionServiceImpl) deleteObjects(requests []CiLogRequest) error {
+	for _, request := range requests {
+		if err := impl.ciLogService.DeleteLogs(request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildLogRequest builds the storage request the same way as the historic logs api, an empty bucket is the logs bucket of the pipeline
+func (impl *ArtifactRetentionServiceImpl) buildLogRequest(ciWorkflowConfig *pipelineConfig.CiWorkflowConfig, bucket string, path string) CiLogRequest {
+	if bucket == "" {
+		bucket = ciWorkflowConfig.LogsBucket
+	}
+	if bucket == "" {
+		bucket = impl.ciConfig.DefaultBuildLogsBucket
+	}
+	region := ciWorkflowConfig.CiCacheRegion
+	if region == "" {
+		region = impl.ciConfig.DefaultCacheBucketRegion
+	}
+	request := CiLogRequest{
+		LogsBucket:    bucket,
+		LogsFilePath:  path,
+		Region:        region,
+		CloudProvider: impl.ciConfig.CloudProvider,
+		AzureBlobConfig: &AzureBlobConfig{
+			Enabled:            impl.ciConfig.CloudProvider == BLOB_STORAGE_AZURE,
+			AccountName:        impl.ciConfig.AzureAccountName,
+			BlobContainerCiLog: impl.ciConfig.AzureBlobContainerCiLog,
+			AccountKey:         impl.ciConfig.AzureAccountKey,
+		},
+	}
+	if impl.ciConfig.CloudProvider == BLOB_STORAGE_MINIO {
+		request.MinioEndpoint = impl.ciConfig.MinioEndpoint
+		request.AccessKey = impl.ciConfig.MinioAccessKey
+		request.SecretKet = impl.ciConfig.MinioSecretKey
+	}
+	return request
+}
+
+// parseS3Location splits s3://bucket/key, the format ci artifact archives are saved with
+func parseS3Location(location string) (string, string, bool) {
+	if !strings.HasPrefix(location, "s3://") {
+		return "", "", false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(location, "s3://"), "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
+
+func (impl *ArtifactRetentionServiceImpl) GetRun(id int) (*pipelineConfig.ArtifactRetentionRun, error) {
+	run, err := impl.artifactRetentionRepository.FindRunById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching artifact retention run", "id", id, "err", err)
+	}
+	return run, err
+}
+
+func (impl *ArtifactRetentionServiceImpl) GetRuns(offset int, limit int) ([]*pipelineConfig.ArtifactRetentionRun, error) {
+	runs, err := impl.artifactRetentionRepository.FindRuns(offset, limit)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching artifact retention runs", "err", err)
+		return nil, err
+	}
+	return runs, nil
+}
@@ -0,0 +1,157 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+)
+
+type retentionPolicyRepositoryStub struct {
+	pipelineConfig.ArtifactRetentionRepository
+	policies []*pipelineConfig.ArtifactRetentionPolicy
+}
+
+func (impl retentionPolicyRepositoryStub) FindActivePolicies() ([]*pipelineConfig.ArtifactRetentionPolicy, error) {
+	return impl.policies, nil
+}
+
+type retentionCiPipelineRepositoryStub struct {
+	pipelineConfig.CiPipelineRepository
+	ciPipelines []*pipelineConfig.CiPipeline
+}
+
+func (impl retentionCiPipelineRepositoryStub) FindAllActive() ([]*pipelineConfig.CiPipeline, error) {
+	return impl.ciPipelines, nil
+}
+
+func (impl retentionCiPipelineRepositoryStub) FindByAppId(appId int) ([]*pipelineConfig.CiPipeline, error) {
+	var ciPipelines []*pipelineConfig.CiPipeline
+	for _, ciPipeline := range impl.ciPipelines {
+		if ciPipeline.AppId == appId {
+			ciPipelines = append(ciPipelines, ciPipeline)
+		}
+	}
+	return ciPipelines, nil
+}
+
+// retentionCiArtifactRepositoryStub returns one candidate per pipeline and records the keep count it was asked with
+type retentionCiArtifactRepositoryStub struct {
+	repository.CiArtifactRepository
+	keepCounts map[int]int
+	deleted    []int
+	failDelete map[int]bool
+}
+
+func (impl *retentionCiArtifactRepositoryStub) FindRetentionCandidates(ciPipelineId int, keepCount int) ([]*repository.CiArtifact, error) {
+	impl.keepCounts[ciPipelineId] = keepCount
+	return []*repository.CiArtifact{{Id: ciPipelineId * 100, Image: fmt.Sprintf("image:%d", ciPipelineId)}}, nil
+}
+
+func (impl *retentionCiArtifactRepositoryStub) DeleteWithDependents(id int) error {
+	if impl.failDelete[id] {
+		return fmt.Errorf("artifact %d is locked", id)
+	}
+	impl.deleted = append(impl.deleted, id)
+	return nil
+}
+
+func TestArtifactRetentionApplyPolicies(t *testing.T) {
+	ciPipelines := []*pipelineConfig.CiPipeline{{Id: 1, AppId: 10}, {Id: 2, AppId: 20}, {Id: 3, AppId: 30}}
+	tests := []struct {
+		name           string
+		policies       []*pipelineConfig.ArtifactRetentionPolicy
+		dryRun         bool
+		failDelete     map[int]bool
+		wantKeepCounts map[int]int
+		wantDeleted    []int
+		wantReported   int
+		wantFailed     int
+	}{
+		{
+			name:           "no policy keeps everything",
+			wantKeepCounts: map[int]int{},
+		},
+		{
+			name:           "app policy overrides the global one",
+			policies:       []*pipelineConfig.ArtifactRetentionPolicy{{AppId: 0, KeepArtifactCount: 5}, {AppId: 20, KeepArtifactCount: 2}},
+			wantKeepCounts: map[int]int{1: 5, 2: 2, 3: 5},
+			wantDeleted:    []int{100, 200, 300},
+			wantReported:   3,
+		},
+		{
+			name:           "app policy without a global one only covers its app",
+			policies:       []*pipelineConfig.ArtifactRetentionPolicy{{AppId: 30, KeepArtifactCount: 1}},
+			wantKeepCounts: map[int]int{3: 1},
+			wantDeleted:    []int{300},
+			wantReported:   1,
+		},
+		{
+			name:           "app policy keeping all artifacts is not purged",
+			policies:       []*pipelineConfig.ArtifactRetentionPolicy{{AppId: 0, KeepArtifactCount: 5}, {AppId: 10, KeepArtifactCount: 0}},
+			wantKeepCounts: map[int]int{2: 5, 3: 5},
+			wantDeleted:    []int{200, 300},
+			wantReported:   2,
+		},
+		{
+			name:           "dry run only reports",
+			policies:       []*pipelineConfig.ArtifactRetentionPolicy{{AppId: 0, KeepArtifactCount: 5}},
+			dryRun:         true,
+			wantKeepCounts: map[int]int{1: 5, 2: 5, 3: 5},
+			wantReported:   3,
+		},
+		{
+			name:           "failed deletions are counted",
+			policies:       []*pipelineConfig.ArtifactRetentionPolicy{{AppId: 0, KeepArtifactCount: 5}},
+			failDelete:     map[int]bool{200: true},
+			wantKeepCounts: map[int]int{1: 5, 2: 5, 3: 5},
+			wantDeleted:    []int{100, 300},
+			wantReported:   3,
+			wantFailed:     1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ciArtifactRepository := &retentionCiArtifactRepositoryStub{keepCounts: map[int]int{}, failDelete: tt.failDelete}
+			impl := &ArtifactRetentionServiceImpl{
+				logger:                      util.NewSugardLogger(),
+				artifactRetentionRepository: retentionPolicyRepositoryStub{policies: tt.policies},
+				ciPipelineRepository:        retentionCiPipelineRepositoryStub{ciPipelines: ciPipelines},
+				ciArtifactRepository:        ciArtifactRepository,
+			}
+			run := &pipelineConfig.ArtifactRetentionRun{DryRun: tt.dryRun}
+			err := impl.applyPolicies(run)
+			if err != nil {
+				t.Fatalf("applyPolicies() error = %v", err)
+			}
+			if !reflect.DeepEqual(ciArtifactRepository.keepCounts, tt.wantKeepCounts) {
+				t.Errorf("keep counts = %v, want %v", ciArtifactRepository.keepCounts, tt.wantKeepCounts)
+			}
+			if !reflect.DeepEqual(ciArtifactRepository.deleted, tt.wantDeleted) {
+				t.Errorf("deleted = %v, want %v", ciArtifactRepository.deleted, tt.wantDeleted)
+			}
+			if len(run.Report) != tt.wantReported || run.ArtifactsDeleted != tt.wantReported-tt.wantFailed || run.FailedCount != tt.wantFailed {
+				t.Errorf("reported = %d, deleted = %d, failed = %d, want %d reported and %d failed", len(run.Report), run.ArtifactsDeleted, run.FailedCount, tt.wantReported, tt.wantFailed)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// os/arch with an optional variant, as accepted by docker buildx --platform
+var targetPlatformRegex = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/v[0-9]+)?$`)
+
+// normalizeDockerBuildConfig validates the build type specific fields of the config and normalizes it in place
+func normalizeDockerBuildConfig(config *bean.DockerBuildConfig) error {
+	targetPlatform, err := normalizeTargetPlatform(config.TargetPlatform)
+	if err != nil {
+		return err
+	}
+	config.TargetPlatform = targetPlatform
+	config.BuilderImage = strings.TrimSpace(config.BuilderImage)
+	switch config.BuildType {
+	case "", bean.DOCKERFILE_BUILD_TYPE:
+		config.BuildType = bean.DOCKERFILE_BUILD_TYPE
+		if len(strings.TrimSpace(config.DockerfilePath)) == 0 {
+			return badBuildConfigError("dockerfile path is required for dockerfile builds")
+		}
+		config.BuilderImage = ""
+	case bean.BUILDPACK_BUILD_TYPE:
+		if len(config.BuilderImage) == 0 {
+			return badBuildConfigError("builder image is required for buildpack builds")
+		}
+		if strings.Contains(config.TargetPlatform, ",") {
+			return badBuildConfigError("buildpack builds support a single target platform")
+		}
+	default:
+		return badBuildConfigError(fmt.Sprintf("unsupported build type %s", config.BuildType))
+	}
+	return nil
+}
+
+// normalizeTargetPlatform validates a comma separated platform list and returns it trimmed and without duplicates
+func normalizeTargetPlatform(targetPlatform string) (string, error) {
+	var platforms []string
+	seen := make(map[string]bool)
+	for _, platform := range strings.Split(targetPlatform, ",") {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		if len(platform) == 0 || seen[platform] {
+			continue
+		}
+		if !targetPlatformRegex.MatchString(platform) {
+			return "", badBuildConfigError(fmt.Sprintf("invalid target platform %s, expected os/arch like linux/amd64", platform))
+		}
+		seen[platform] = true
+		platforms = append(platforms, platform)
+	}
+	return strings.Join(platforms, ","), nil
+}
+
+func badBuildConfigError(msg string) error {
+	return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: msg, UserMessage: msg}
+}
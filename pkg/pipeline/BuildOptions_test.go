@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/devtron-labs/devtron/pkg/bean"
+)
+
+func TestNormalizeDockerBuildConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *bean.DockerBuildConfig
+		want    *bean.DockerBuildConfig
+		wantErr bool
+	}{
+		{
+			name:   "empty build type defaults to dockerfile and drops the builder image",
+			config: &bean.DockerBuildConfig{DockerfilePath: "Dockerfile", BuilderImage: "paketobuildpacks/builder:base"},
+			want:   &bean.DockerBuildConfig{DockerfilePath: "Dockerfile", BuildType: bean.DOCKERFILE_BUILD_TYPE},
+		},
+		{
+			name:    "dockerfile build without a dockerfile path",
+			config:  &bean.DockerBuildConfig{BuildType: bean.DOCKERFILE_BUILD_TYPE, DockerfilePath: " "},
+			wantErr: true,
+		},
+		{
+			name:   "target platforms are trimmed, lowercased and deduplicated",
+			config: &bean.DockerBuildConfig{DockerfilePath: "Dockerfile", TargetPlatform: " linux/AMD64, linux/arm64/v8,linux/amd64,"},
+			want:   &bean.DockerBuildConfig{DockerfilePath: "Dockerfile", BuildType: bean.DOCKERFILE_BUILD_TYPE, TargetPlatform: "linux/amd64,linux/arm64/v8"},
+		},
+		{
+			name:    "invalid target platform",
+			config:  &bean.DockerBuildConfig{DockerfilePath: "Dockerfile", TargetPlatform: "amd64"},
+			wantErr: true,
+		},
+		{
+			name:   "buildpack build",
+			config: &bean.DockerBuildConfig{BuildType: bean.BUILDPACK_BUILD_TYPE, BuilderImage: " paketobuildpacks/builder:base ", TargetPlatform: "linux/amd64"},
+			want:   &bean.DockerBuildConfig{BuildType: bean.BUILDPACK_BUILD_TYPE, BuilderImage: "paketobuildpacks/builder:base", TargetPlatform: "linux/amd64"},
+		},
+		{
+			name:    "buildpack build without a builder image",
+			config:  &bean.DockerBuildConfig{BuildType: bean.BUILDPACK_BUILD_TYPE},
+			wantErr: true,
+		},
+		{
+			name:    "buildpack build for several platforms",
+			config:  &bean.DockerBuildConfig{BuildType: bean.BUILDPACK_BUILD_TYPE, BuilderImage: "paketobuildpacks/builder:base", TargetPlatform: "linux/amd64,linux/arm64"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown build type",
+			config:  &bean.DockerBuildConfig{BuildType: "KANIKO", DockerfilePath: "Dockerfile"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := normalizeDockerBuildConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("normalizeDockerBuildConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(tt.config, tt.want) {
+				t.Errorf("normalizeDockerBuildConfig() = %+v, want %+v", tt.config, tt.want)
+			}
+		})
+	}
+}
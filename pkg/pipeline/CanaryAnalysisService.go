@@ -0,0 +1,552 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	application2 "github.com/argoproj/argo-cd/pkg/apiclient/application"
+	"github.com/caarlos0/env"
+	"github.com/devtron-labs/devtron/client/argocdServer/application"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/devtron-labs/devtron/pkg/cluster/repository"
+	"github.com/devtron-labs/devtron/pkg/prometheus"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/go-pg/pg"
+	"github.com/prometheus/common/model"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	canaryAnalysisCronExpr      = "@every 1m"
+	canaryAnalysisJobName       = "canary-analysis"
+	rolloutGroup                = "argoproj.io"
+	rolloutVersion              = "v1alpha1"
+	rolloutKind                 = "Rollout"
+	rolloutActionResume         = "resume"
+	rolloutActionAbort          = "abort"
+	canaryQueryNamespacePattern = "{{namespace}}"
+	canaryQueryAppNamePattern   = "{{appName}}"
+	canaryQueryWindowPattern    = "{{window}}"
+	canaryMetricQueryTimeout    = 30 * time.Second
+)
+
+type CanaryAnalysisEnvConfig struct {
+	// a paused step is analysed once the canary has served traffic for this long, {{window}} in queries is replaced by it
+	WindowSeconds int `env:"CANARY_ANALYSIS_WINDOW_SECONDS" envDefault:"300"`
+	// a step whose metrics stay inconclusive, query errors or no data, is aborted after this many evaluations
+	MaxInconclusiveAttempts int `env:"CANARY_ANALYSIS_MAX_INCONCLUSIVE_ATTEMPTS" envDefault:"5"`
+}
+
+type CanaryMetricResult struct {
+	Name      string   `json:"name"`
+	Query     string   `json:"query"`
+	Operator  string   `json:"operator"`
+	Threshold float64  `json:"threshold"`
+	Value     *float64 `json:"value,omitempty"`
+	Passed    bool     `json:"passed"`
+	Error     string   `json:"error,omitempty"`
+}
+
+type CanaryStepResultDto struct {
+	StepIndex     int                             `json:"stepIndex"`
+	CanaryWeight  int                             `json:"canaryWeight"`
+	Passed        bool                            `json:"passed"`
+	Action        pipelineConfig.CanaryStepAction `json:"action"`
+	MetricResults []*CanaryMetricResult           `json:"metricResults"`
+	Message       string                          `json:"message,omitempty"`
+	Attempts      int                             `json:"attempts"`
+	AnalysedOn    time.Time                       `json:"analysedOn"`
+}
+
+type CanaryAnalysisDetail struct {
+	Status pipelineConfig.CanaryAnalysisStatus `json:"status"`
+	Steps  []*CanaryStepResultDto              `json:"steps"`
+}
+
+type CanaryAnalysisService interface {
+	SaveCanaryAnalysisConfig(pipelineId int, config *bean.CanaryAnalysisConfig, userId int32, tx *pg.Tx) error
+	GetCanaryAnalysisConfig(pipelineId int) (*bean.CanaryAnalysisConfig, error)
+	GetCanaryAnalysisDetail(cdWorkflowRunner *pipelineConfig.CdWorkflowRunner) (*CanaryAnalysisDetail, error)
+	AnalyseCanaryDeployments()
+}
+
+type CanaryAnalysisServiceImpl struct {
+	logger                   *zap.SugaredLogger
+	config                   *CanaryAnalysisEnvConfig
+	canaryAnalysisRepository pipelineConfig.CanaryAnalysisRepository
+	cdWorkflowRepository     pipelineConfig.CdWorkflowRepository
+	pipelineRepository       pipelineConfig.PipelineRepository
+	environmentRepository    repository.EnvironmentRepository
+	acdClient                application.ServiceClient
+	tokenCache               *util3.TokenCache
+}
+
+func NewCanaryAnalysisServiceImpl(logger *zap.SugaredLogger,
+	canaryAnalysisRepository pipelineConfig.CanaryAnalysisRepository,
+	cdWorkflowRepository pipelineConfig.CdWorkflowRepository,
+	pipelineRepository pipelineConfig.PipelineRepository,
+	environmentRepository repository.EnvironmentRepository,
+	acdClient application.ServiceClient,
+	tokenCache *util3.TokenCache, scheduledJobRunner util3.ScheduledJobRunner) (*CanaryAnalysisServiceImpl, error) {
+	cfg := &CanaryAnalysisEnvConfig{}
+	err := env.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	impl := &CanaryAnalysisServiceImpl{
+		logger:                   logger,
+		config:                   cfg,
+		canaryAnalysisRepository: canaryAnalysisRepository,
+		cdWorkflowRepository:     cdWorkflowRepository,
+		pipelineRepository:       pipelineRepository,
+		environmentRepository:    environmentRepository,
+		acdClient:                acdClient,
+		tokenCache:               tokenCache,
+	}
+	err = scheduledJobRunner.Schedule(canaryAnalysisJobName, canaryAnalysisCronExpr, impl.AnalyseCanaryDeployments)
+	if err != nil {
+		logger.Errorw("error in starting canary analysis", "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+// SaveCanaryAnalysisConfig replaces the canary metrics of a cd pipeline, nil config or no metrics removes them
+func (impl CanaryAnalysisServiceImpl) SaveCanaryAnalysisConfig(pipelineId int, config *bean.CanaryAnalysisConfig, userId int32, tx *pg.Tx) error {
+	if config != nil {
+		//pipeline updates are not struct validated, a metric which can not be evaluated would abort every canary step
+		if err := validateCanaryMetrics(config.Metrics); err != nil {
+			return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: err.Error()}
+		}
+	}
+	existingMetrics, err := impl.canaryAnalysisRepository.FindActiveMetricsByPipelineId(pipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching canary metrics", "pipelineId", pipelineId, "err", err)
+		return err
+	}
+	for _, existingMetric := range existingMetrics {
+		existingMetric.Active = false
+		existingMetric.UpdatedOn = time.Now()
+		existingMetric.UpdatedBy = userId
+		err = impl.canaryAnalysisRepository.UpdateMetric(existingMetric, tx)
+		if err != nil {
+			impl.logger.Errorw("error in deactivating canary metric", "id", existingMetric.Id, "err", err)
+			return err
+		}
+	}
+	if config == nil {
+		return nil
+	}
+	for _, metric := range config.Metrics {
+		model := &pipelineConfig.CanaryAnalysisMetric{
+			PipelineId: pipelineId,
+			Name:       metric.Name,
+			Query:      metric.Query,
+			Operator:   metric.Operator,
+			Threshold:  metric.Threshold,
+			Active:     true,
+			AuditLog:   sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+		}
+		err = impl.canaryAnalysisRepository.SaveMetric(model, tx)
+		if err != nil {
+			impl.logger.Errorw("error in saving canary metric", "pipelineId", pipelineId, "metric", metric.Name, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (impl CanaryAnalysisServiceImpl) GetCanaryAnalysisConfig(pipelineId int) (*bean.CanaryAnalysisConfig, error) {
+	metrics, err := impl.canaryAnalysisRepository.FindActiveMetricsByPipelineId(pipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching canary metrics", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	if len(metrics) == 0 {
+		return nil, nil
+	}
+	config := &bean.CanaryAnalysisConfig{}
+	for _, metric := range metrics {
+		config.Metrics = append(config.Metrics, &bean.CanaryAnalysisMetric{
+			Name:      metric.Name,
+			Query:     metric.Query,
+			Operator:  metric.Operator,
+			Threshold: metric.Threshold,
+		})
+	}
+	return config, nil
+}
+
+func (impl CanaryAnalysisServiceImpl) GetCanaryAnalysisDetail(cdWorkflowRunner *pipelineConfig.CdWorkflowRunner) (*CanaryAnalysisDetail, error) {
+	if len(cdWorkflowRunner.CanaryAnalysisStatus) == 0 {
+		return nil, nil
+	}
+	stepResults, err := impl.canaryAnalysisRepository.FindStepResultsByRunnerId(cdWorkflowRunner.Id)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching canary step results", "cdWorkflowRunnerId", cdWorkflowRunner.Id, "err", err)
+		return nil, err
+	}
+	detail := &CanaryAnalysisDetail{Status: cdWorkflowRunner.CanaryAnalysisStatus, Steps: []*CanaryStepResultDto{}}
+	for _, stepResult := range stepResults {
+		var metricResults []*CanaryMetricResult
+		if len(stepResult.MetricResults) > 0 {
+			err = json.Unmarshal([]byte(stepResult.MetricResults), &metricResults)
+			if err != nil {
+				impl.logger.Errorw("error in unmarshalling canary metric results", "id", stepResult.Id, "err", err)
+				return nil, err
+			}
+		}
+		detail.Steps = append(detail.Steps, &CanaryStepResultDto{
+			StepIndex:     stepResult.StepIndex,
+			CanaryWeight:  stepResult.CanaryWeight,
+			Passed:        stepResult.Passed,
+			Action:        stepResult.Action,
+			MetricResults: metricResults,
+			Message:       stepResult.Message,
+			Attempts:      stepResult.Attempts,
+			AnalysedOn:    stepResult.UpdatedOn,
+		})
+	}
+	return detail, nil
+}
+
+// AnalyseCanaryDeployments evaluates the canary metrics of every rollout paused at a step, then promotes or aborts it
+func (impl CanaryAnalysisServiceImpl) AnalyseCanaryDeployments() {
+	runners, err := impl.canaryAnalysisRepository.FindRunnersPendingAnalysis()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching runners pending canary analysis", "err", err)
+		return
+	}
+	for _, runner := range runners {
+		err = impl.analyseRunner(runner)
+		if err != nil {
+			impl.logger.Errorw("error in canary analysis", "cdWorkflowRunnerId", runner.Id, "err", err)
+		}
+	}
+}
+
+func (impl CanaryAnalysisServiceImpl) analyseRunner(runner *pipelineConfig.CdWorkflowRunner) error {
+	if runner.Status == WorkflowStarting {
+		//argo app not yet synced with this release
+		return nil
+	}
+	cdWorkflow, err := impl.cdWorkflowRepository.FindById(runner.CdWorkflowId)
+	if err != nil {
+		return err
+	}
+	pipeline, err := impl.pipelineRepository.FindById(cdWorkflow.PipelineId)
+	if util.IsErrNoRows(err) {
+		return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_SKIPPED)
+	} else if err != nil {
+		return err
+	}
+	env, err := impl.environmentRepository.FindById(pipeline.EnvironmentId)
+	if err != nil {
+		return err
+	}
+	acdAppName := fmt.Sprintf("%s-%s", pipeline.App.AppName, env.Name)
+	ctx, err := impl.tokenCache.BuildACDSynchContext()
+	if err != nil {
+		return err
+	}
+	rollout, err := impl.getRollout(ctx, acdAppName)
+	if err != nil {
+		return err
+	}
+	steps := getCanarySteps(rollout)
+	if len(steps) == 0 {
+		//not a canary release, nothing to analyse
+		return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_SKIPPED)
+	}
+	status, _ := rollout.Object["status"].(map[string]interface{})
+	if aborted, _ := status["abort"].(bool); aborted {
+		return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_ABORTED)
+	}
+	currentStepIndex := 0
+	if index, ok := status["currentStepIndex"].(float64); ok {
+		currentStepIndex = int(index)
+	}
+	if currentStepIndex >= len(steps) {
+		if runner.Status == application.Healthy {
+			return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_SUCCEEDED)
+		}
+		return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_RUNNING)
+	}
+	pauseConditions, _ := status["pauseConditions"].([]interface{})
+	if len(pauseConditions) == 0 {
+		return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_RUNNING)
+	}
+
+	stepResult, err := impl.canaryAnalysisRepository.FindStepResultByRunnerIdAndStepIndex(runner.Id, currentStepIndex)
+	if err != nil && !util.IsErrNoRows(err) {
+		return err
+	}
+	if stepResult.Id > 0 && stepResult.Action != pipelineConfig.CANARY_STEP_ACTION_INCONCLUSIVE {
+		//step already analysed, waiting for rollout to act on it
+		return nil
+	}
+	window := time.Duration(impl.config.WindowSeconds) * time.Second
+	if pausedSince, ok := getPausedSince(pauseConditions); ok && time.Since(pausedSince) < window {
+		//canary has not yet served traffic for the analysis window
+		return nil
+	}
+	metrics, err := impl.canaryAnalysisRepository.FindActiveMetricsByPipelineId(pipeline.Id)
+	if err != nil && !util.IsErrNoRows(err) {
+		return err
+	}
+	replacer := strings.NewReplacer(canaryQueryNamespacePattern, env.Namespace, canaryQueryAppNamePattern, acdAppName,
+		canaryQueryWindowPattern, fmt.Sprintf("%ds", impl.config.WindowSeconds))
+	metricResults, message := impl.evaluateMetrics(env, metrics, replacer)
+	stepResult.Attempts++
+	action, message := decideCanaryStepAction(metricResults, message, stepResult.Attempts, impl.config.MaxInconclusiveAttempts)
+
+	if action != pipelineConfig.CANARY_STEP_ACTION_INCONCLUSIVE {
+		rolloutAction := rolloutActionResume
+		if action == pipelineConfig.CANARY_STEP_ACTION_ABORT {
+			rolloutAction = rolloutActionAbort
+		}
+		_, err = impl.acdClient.RunResourceAction(ctx, &application2.ResourceActionRunRequest{
+			Name:         &acdAppName,
+			Namespace:    rollout.Namespace,
+			ResourceName: rollout.Name,
+			Group:        rolloutGroup,
+			Version:      rolloutVersion,
+			Kind:         rolloutKind,
+			Action:       rolloutAction,
+		})
+		if err != nil {
+			impl.logger.Errorw("error in running rollout action", "app", acdAppName, "action", rolloutAction, "err", err)
+			return err
+		}
+	}
+	metricResultsJson, err := json.Marshal(metricResults)
+	if err != nil {
+		return err
+	}
+	stepResult.CdWorkflowRunnerId = runner.Id
+	stepResult.StepIndex = currentStepIndex
+	stepResult.CanaryWeight = getCanaryWeight(steps, currentStepIndex)
+	stepResult.Passed = action == pipelineConfig.CANARY_STEP_ACTION_PROMOTE
+	stepResult.Action = action
+	stepResult.MetricResults = string(metricResultsJson)
+	stepResult.Message = message
+	stepResult.UpdatedOn = time.Now()
+	stepResult.UpdatedBy = 1
+	if stepResult.Id > 0 {
+		err = impl.canaryAnalysisRepository.UpdateStepResult(stepResult)
+	} else {
+		stepResult.CreatedOn = time.Now()
+		stepResult.CreatedBy = 1
+		err = impl.canaryAnalysisRepository.SaveStepResult(stepResult)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving canary step result", "cdWorkflowRunnerId", runner.Id, "step", currentStepIndex, "err", err)
+		return err
+	}
+	impl.logger.Infow("canary step analysed", "app", acdAppName, "cdWorkflowRunnerId", runner.Id, "step", currentStepIndex, "action", action, "attempts", stepResult.Attempts)
+	if action == pipelineConfig.CANARY_STEP_ACTION_ABORT {
+		return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_ABORTED)
+	}
+	return impl.updateAnalysisStatus(runner, pipelineConfig.CANARY_ANALYSIS_RUNNING)
+}
+
+// decideCanaryStepAction aborts only on a metric breaching its threshold, or once metrics stayed inconclusive for
+// maxInconclusiveAttempts evaluations. Query errors, missing data or an unreachable prometheus are inconclusive.
+func decideCanaryStepAction(metricResults []*CanaryMetricResult, message string, attempts int, maxInconclusiveAttempts int) (pipelineConfig.CanaryStepAction, string) {
+	inconclusive := len(message) > 0 || len(metricResults) == 0
+	for _, metricResult := range metricResults {
+		if len(metricResult.Error) > 0 {
+			inconclusive = true
+		} else if !metricResult.Passed {
+			return pipelineConfig.CANARY_STEP_ACTION_ABORT, fmt.Sprintf("metric %s breached its threshold", metricResult.Name)
+		}
+	}
+	if !inconclusive {
+		return pipelineConfig.CANARY_STEP_ACTION_PROMOTE, ""
+	}
+	if len(message) == 0 {
+		message = "metrics could not be evaluated"
+	}
+	if attempts >= maxInconclusiveAttempts {
+		return pipelineConfig.CANARY_STEP_ACTION_ABORT, fmt.Sprintf("%s, inconclusive after %d attempts", message, attempts)
+	}
+	return pipelineConfig.CANARY_STEP_ACTION_INCONCLUSIVE, message
+}
+
+func (impl CanaryAnalysisServiceImpl) evaluateMetrics(env *repository.Environment, metrics []*pipelineConfig.CanaryAnalysisMetric, replacer *strings.Replacer) ([]*CanaryMetricResult, string) {
+	var metricResults []*CanaryMetricResult
+	if len(env.Cluster.PrometheusEndpoint) == 0 {
+		return metricResults, "prometheus endpoint not configured for cluster " + env.Cluster.ClusterName
+	}
+	prometheusAPI, err := prometheus.ContextByEnv(env.Name, env.Cluster.PrometheusEndpoint)
+	if err != nil {
+		impl.logger.Errorw("error in getting prometheus api client", "env", env.Name, "err", err)
+		return metricResults, "unable to connect to prometheus: " + err.Error()
+	}
+	for _, metric := range metrics {
+		metricResult := &CanaryMetricResult{
+			Name:      metric.Name,
+			Query:     replacer.Replace(metric.Query),
+			Operator:  metric.Operator,
+			Threshold: metric.Threshold,
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), canaryMetricQueryTimeout)
+		out, _, err := prometheusAPI.Query(ctx, metricResult.Query, time.Now())
+		cancel()
+		if err != nil {
+			metricResult.Error = err.Error()
+		} else if value, err := singleValue(out); err != nil {
+			metricResult.Error = err.Error()
+		} else {
+			metricResult.Value = &value
+			passed, err := compareWithThreshold(value, metric.Operator, metric.Threshold)
+			if err != nil {
+				metricResult.Error = err.Error()
+			}
+			metricResult.Passed = passed
+		}
+		metricResults = append(metricResults, metricResult)
+	}
+	return metricResults, ""
+}
+
+func (impl CanaryAnalysisServiceImpl) getRollout(ctx context.Context, acdAppName string) (*rolloutResource, error) {
+	resources, err := impl.acdClient.ManagedResources(ctx, &application2.ResourcesQuery{ApplicationName: &acdAppName})
+	if err != nil {
+		impl.logger.Errorw("error in fetching managed resources", "app", acdAppName, "err", err)
+		return nil, err
+	}
+	for _, item := range resources.Items {
+		if item.Kind != rolloutKind || item.Group != rolloutGroup || len(item.LiveState) == 0 {
+			continue
+		}
+		rollout := &rolloutResource{Name: item.Name, Namespace: item.Namespace}
+		err = json.Unmarshal([]byte(item.LiveState), &rollout.Object)
+		if err != nil {
+			return nil, err
+		}
+		return rollout, nil
+	}
+	return &rolloutResource{}, nil
+}
+
+func (impl CanaryAnalysisServiceImpl) updateAnalysisStatus(runner *pipelineConfig.CdWorkflowRunner, status pipelineConfig.CanaryAnalysisStatus) error {
+	if runner.CanaryAnalysisStatus == status {
+		return nil
+	}
+	err := impl.canaryAnalysisRepository.UpdateRunnerAnalysisStatus(runner.Id, status)
+	if err != nil {
+		impl.logger.Errorw("error in updating canary analysis status", "cdWorkflowRunnerId", runner.Id, "status", status, "err", err)
+		return err
+	}
+	runner.CanaryAnalysisStatus = status
+	return nil
+}
+
+type rolloutResource struct {
+	Name      string
+	Namespace string
+	Object    map[string]interface{}
+}
+
+// getPausedSince returns when the rollout paused at its current step, argo rollouts sets startTime on its pause conditions
+func getPausedSince(pauseConditions []interface{}) (time.Time, bool) {
+	pauseCondition, _ := pauseConditions[0].(map[string]interface{})
+	startTime, _ := pauseCondition["startTime"].(string)
+	pausedSince, err := time.Parse(time.RFC3339, startTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return pausedSince, true
+}
+
+func getCanarySteps(rollout *rolloutResource) []interface{} {
+	spec, _ := rollout.Object["spec"].(map[string]interface{})
+	strategy, _ := spec["strategy"].(map[string]interface{})
+	canary, _ := strategy["canary"].(map[string]interface{})
+	steps, _ := canary["steps"].([]interface{})
+	return steps
+}
+
+// getCanaryWeight returns the traffic weight set by the last setWeight step before stepIndex
+func getCanaryWeight(steps []interface{}, stepIndex int) int {
+	weight := 0
+	for i := 0; i <= stepIndex && i < len(steps); i++ {
+		step, _ := steps[i].(map[string]interface{})
+		if setWeight, ok := step["setWeight"].(float64); ok {
+			weight = int(setWeight)
+		}
+	}
+	return weight
+}
+
+func singleValue(value model.Value) (float64, error) {
+	switch v := value.(type) {
+	case model.Vector:
+		if len(v) == 0 {
+			return 0, fmt.Errorf("query returned no data")
+		}
+		return float64(v[0].Value), nil
+	case *model.Scalar:
+		return float64(v.Value), nil
+	default:
+		return 0, fmt.Errorf("unsupported query result type %s", value.Type())
+	}
+}
+
+func validateCanaryMetrics(metrics []*bean.CanaryAnalysisMetric) error {
+	for _, metric := range metrics {
+		if metric == nil || len(strings.TrimSpace(metric.Name)) == 0 {
+			return fmt.Errorf("canary metric name is required")
+		}
+		if len(strings.TrimSpace(metric.Query)) == 0 {
+			return fmt.Errorf("query of canary metric %s is required", metric.Name)
+		}
+		if _, ok := canaryThresholdOperators[metric.Operator]; !ok {
+			return fmt.Errorf("operator %q of canary metric %s is invalid, expected one of <, <=, > or >=", metric.Operator, metric.Name)
+		}
+		if math.IsNaN(metric.Threshold) || math.IsInf(metric.Threshold, 0) {
+			return fmt.Errorf("threshold of canary metric %s must be a finite number", metric.Name)
+		}
+	}
+	return nil
+}
+
+var canaryThresholdOperators = map[string]func(value float64, threshold float64) bool{
+	"<":  func(value float64, threshold float64) bool { return value < threshold },
+	"<=": func(value float64, threshold float64) bool { return value <= threshold },
+	">":  func(value float64, threshold float64) bool { return value > threshold },
+	">=": func(value float64, threshold float64) bool { return value >= threshold },
+}
+
+func compareWithThreshold(value float64, operator string, threshold float64) (bool, error) {
+	compare, ok := canaryThresholdOperators[operator]
+	if !ok {
+		return false, fmt.Errorf("unknown threshold operator %q", operator)
+	}
+	return compare(value, threshold), nil
+}
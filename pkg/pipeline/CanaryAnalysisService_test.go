@@ -0,0 +1,114 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"math"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/pkg/bean"
+)
+
+func TestDecideCanaryStepAction(t *testing.T) {
+	value := 0.5
+	passed := &CanaryMetricResult{Name: "error-rate", Value: &value, Passed: true}
+	breached := &CanaryMetricResult{Name: "latency", Value: &value, Passed: false}
+	noData := &CanaryMetricResult{Name: "success-rate", Error: "query returned no data"}
+	type args struct {
+		metricResults []*CanaryMetricResult
+		message       string
+		attempts      int
+	}
+	tests := []struct {
+		name string
+		args args
+		want pipelineConfig.CanaryStepAction
+	}{
+		{name: "all metrics pass", args: args{metricResults: []*CanaryMetricResult{passed}, attempts: 1}, want: pipelineConfig.CANARY_STEP_ACTION_PROMOTE},
+		{name: "threshold breached", args: args{metricResults: []*CanaryMetricResult{passed, breached}, attempts: 1}, want: pipelineConfig.CANARY_STEP_ACTION_ABORT},
+		{name: "breach wins over no data", args: args{metricResults: []*CanaryMetricResult{noData, breached}, attempts: 1}, want: pipelineConfig.CANARY_STEP_ACTION_ABORT},
+		{name: "no data is retried", args: args{metricResults: []*CanaryMetricResult{passed, noData}, attempts: 1}, want: pipelineConfig.CANARY_STEP_ACTION_INCONCLUSIVE},
+		{name: "prometheus unavailable is retried", args: args{message: "prometheus endpoint not configured", attempts: 2}, want: pipelineConfig.CANARY_STEP_ACTION_INCONCLUSIVE},
+		{name: "no metric results is retried", args: args{attempts: 1}, want: pipelineConfig.CANARY_STEP_ACTION_INCONCLUSIVE},
+		{name: "inconclusive beyond max attempts aborts", args: args{metricResults: []*CanaryMetricResult{noData}, attempts: 3}, want: pipelineConfig.CANARY_STEP_ACTION_ABORT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := decideCanaryStepAction(tt.args.metricResults, tt.args.message, tt.args.attempts, 3); got != tt.want {
+				t.Errorf("decideCanaryStepAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCanaryMetrics(t *testing.T) {
+	valid := func() *bean.CanaryAnalysisMetric {
+		return &bean.CanaryAnalysisMetric{Name: "error-rate", Query: "sum(rate(errors{namespace=\"{{namespace}}\"}[1m]))", Operator: "<", Threshold: 0.05}
+	}
+	tests := []struct {
+		name    string
+		modify  func(metric *bean.CanaryAnalysisMetric)
+		wantErr bool
+	}{
+		{name: "valid metric", modify: func(metric *bean.CanaryAnalysisMetric) {}},
+		{name: "missing name", modify: func(metric *bean.CanaryAnalysisMetric) { metric.Name = " " }, wantErr: true},
+		{name: "missing query", modify: func(metric *bean.CanaryAnalysisMetric) { metric.Query = "" }, wantErr: true},
+		{name: "unknown operator", modify: func(metric *bean.CanaryAnalysisMetric) { metric.Operator = "==" }, wantErr: true},
+		{name: "missing operator", modify: func(metric *bean.CanaryAnalysisMetric) { metric.Operator = "" }, wantErr: true},
+		{name: "threshold not a number", modify: func(metric *bean.CanaryAnalysisMetric) { metric.Threshold = math.NaN() }, wantErr: true},
+		{name: "infinite threshold", modify: func(metric *bean.CanaryAnalysisMetric) { metric.Threshold = math.Inf(1) }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metric := valid()
+			tt.modify(metric)
+			err := validateCanaryMetrics([]*bean.CanaryAnalysisMetric{valid(), metric})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateCanaryMetrics() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompareWithThreshold(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    float64
+		want     bool
+		wantErr  bool
+	}{
+		{operator: "<", value: 1, want: true},
+		{operator: "<", value: 2, want: false},
+		{operator: "<=", value: 2, want: true},
+		{operator: ">", value: 2, want: false},
+		{operator: ">=", value: 2, want: true},
+		{operator: "!=", value: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operator, func(t *testing.T) {
+			got, err := compareWithThreshold(tt.value, tt.operator, 2)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compareWithThreshold() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("compareWithThreshold(%v %s 2) = %v, want %v", tt.value, tt.operator, got, tt.want)
+			}
+		})
+	}
+}
@@ -64,6 +64,7 @@ type CdHandlerImpl struct {
 	envRepository                repository2.EnvironmentRepository
 	pipelineRepository           pipelineConfig.PipelineRepository
 	ciWorkflowRepository         pipelineConfig.CiWorkflowRepository
+	canaryAnalysisService        CanaryAnalysisService
 }
 
 func NewCdHandlerImpl(Logger *zap.SugaredLogger, cdConfig *CdConfig, userService user.UserService,
@@ -75,7 +76,8 @@ func NewCdHandlerImpl(Logger *zap.SugaredLogger, cdConfig *CdConfig, userService
 	pipelineRepository pipelineConfig.PipelineRepository,
 	envRepository repository2.EnvironmentRepository,
 	ciWorkflowRepository pipelineConfig.CiWorkflowRepository,
-	ciConfig *CiConfig) *CdHandlerImpl {
+	ciConfig *CiConfig,
+	canaryAnalysisService CanaryAnalysisService) *CdHandlerImpl {
 	return &CdHandlerImpl{
 		Logger:                       Logger,
 		cdConfig:                     cdConfig,
@@ -89,6 +91,7 @@ func NewCdHandlerImpl(Logger *zap.SugaredLogger, cdConfig *CdConfig, userService
 		pipelineRepository:           pipelineRepository,
 		ciWorkflowRepository:         ciWorkflowRepository,
 		ciConfig:                     ciConfig,
+		canaryAnalysisService:        canaryAnalysisService,
 	}
 }
 
@@ -398,6 +401,11 @@ func (impl *CdHandlerImpl) FetchCdWorkflowDetails(appId int, environmentId int,
 		impl.Logger.Errorw("error in fetching ci wf", "artifactId", workflow.CiArtifactId, "err", err)
 		return WorkflowResponse{}, err
 	}
+	canaryAnalysis, err := impl.canaryAnalysisService.GetCanaryAnalysisDetail(workflowR)
+	if err != nil {
+		impl.Logger.Errorw("error in fetching canary analysis", "wfrId", workflowR.Id, "err", err)
+		return WorkflowResponse{}, err
+	}
 	workflowResponse := WorkflowResponse{
 		Id:               workflow.Id,
 		Name:             workflow.Name,
@@ -413,6 +421,7 @@ func (impl *CdHandlerImpl) FetchCdWorkflowDetails(appId int, environmentId int,
 		Artifact:         workflow.Image,
 		Stage:            workflow.WorkflowType,
 		GitTriggers:      ciWf.GitTriggers,
+		CanaryAnalysis:   canaryAnalysis,
 	}
 	return workflowResponse, nil
 }
@@ -480,6 +489,9 @@ func (impl *CdHandlerImpl) converterWFR(wfr pipelineConfig.CdWorkflowRunner) pip
 		workflow.Image = wfr.CdWorkflow.CiArtifact.Image
 		workflow.PipelineId = wfr.CdWorkflow.PipelineId
 		workflow.CiArtifactId = wfr.CdWorkflow.CiArtifactId
+		workflow.CanaryAnalysisStatus = string(wfr.CanaryAnalysisStatus)
+		workflow.ImageSignatureStatus = wfr.ImageSignatureStatus
+		workflow.ImageSignatureMessage = wfr.ImageSignatureMessage
 
 	}
 	return workflow
@@ -30,6 +30,7 @@ import (
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	"github.com/devtron-labs/devtron/pkg/app"
 	bean2 "github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/devtron-labs/devtron/pkg/vault"
 	"go.uber.org/zap"
 	v12 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -54,6 +55,7 @@ type CdWorkflowServiceImpl struct {
 	cdConfig      *CdConfig
 	appService    app.AppService
 	envRepository repository.EnvironmentRepository
+	vaultService  vault.VaultService
 }
 
 type CdWorkflowRequest struct {
@@ -97,9 +99,10 @@ type CdWorkflowRequest struct {
 const PRE = "PRE"
 const POST = "POST"
 
-func NewCdWorkflowServiceImpl(Logger *zap.SugaredLogger, envRepository repository.EnvironmentRepository, cdConfig *CdConfig, appService app.AppService) *CdWorkflowServiceImpl {
+func NewCdWorkflowServiceImpl(Logger *zap.SugaredLogger, envRepository repository.EnvironmentRepository, cdConfig *CdConfig, appService app.AppService,
+	vaultService vault.VaultService) *CdWorkflowServiceImpl {
 	return &CdWorkflowServiceImpl{Logger: Logger, config: cdConfig.ClusterConfig,
-		cdConfig: cdConfig, appService: appService, envRepository: envRepository}
+		cdConfig: cdConfig, appService: appService, envRepository: envRepository, vaultService: vaultService}
 }
 
 func (impl *CdWorkflowServiceImpl) SubmitWorkflow(workflowRequest *CdWorkflowRequest, pipeline *pipelineConfig.Pipeline, env *repository.Environment) (*v1alpha1.Workflow, error) {
@@ -267,30 +270,10 @@ func (impl *CdWorkflowServiceImpl) SubmitWorkflow(workflowRequest *CdWorkflowReq
 	if len(secrets.Secrets) > 0 {
 		entryPoint = "cd-stages-with-env"
 		for i, s := range secrets.Secrets {
-			var datamap map[string][]byte
-			if err := json.Unmarshal(s.Data, &datamap); err != nil {
-				impl.Logger.Errorw("error while unmarshal data", "err", err)
+			secretObject, err := impl.buildStageSecret(s)
+			if err != nil {
 				return nil, err
 			}
-			ownerDelete := true
-			secretObject := v12.Secret{
-				TypeMeta: v1.TypeMeta{
-					Kind:       "Secret",
-					APIVersion: "v1",
-				},
-				ObjectMeta: v1.ObjectMeta{
-					Name: s.Name,
-					OwnerReferences: []v1.OwnerReference{{
-						APIVersion:         "argoproj.io/v1alpha1",
-						Kind:               "Workflow",
-						Name:               "{{workflow.name}}",
-						UID:                "{{workflow.uid}}",
-						BlockOwnerDeletion: &ownerDelete,
-					}},
-				},
-				Data: datamap,
-				Type: "Opaque",
-			}
 			secretJson, err := json.Marshal(secretObject)
 			if err != nil {
 				impl.Logger.Errorw("error in building json", "err", err)
@@ -463,12 +446,6 @@ func (impl *CdWorkflowServiceImpl) SubmitWorkflow(workflowRequest *CdWorkflowReq
 	}
 	//
 
-	wfTemplate, err := json.Marshal(cdWorkflow)
-	if err != nil {
-		impl.Logger.Error(err)
-	}
-	impl.Logger.Debug("---->", string(wfTemplate))
-
 	var wfClient v1alpha12.WorkflowInterface
 
 	if workflowRequest.IsExtRun {
@@ -495,6 +472,52 @@ func (impl *CdWorkflowServiceImpl) SubmitWorkflow(workflowRequest *CdWorkflowReq
 	return createdWf, err
 }
 
+// buildStageSecret builds the manifest of a secret of the stage, owned by the workflow. A secret of vault references is
+// built as an external secret so that the secret values are read in the cluster and never become part of the workflow.
+func (impl *CdWorkflowServiceImpl) buildStageSecret(s *bean.Map) (interface{}, error) {
+	ownerDelete := true
+	objectMeta := v1.ObjectMeta{
+		Name: s.Name,
+		OwnerReferences: []v1.OwnerReference{{
+			APIVersion:         "argoproj.io/v1alpha1",
+			Kind:               "Workflow",
+			Name:               "{{workflow.name}}",
+			UID:                "{{workflow.uid}}",
+			BlockOwnerDeletion: &ownerDelete,
+		}},
+	}
+	entries, err := impl.vaultService.ExternalSecretData(s.Data)
+	if err != nil {
+		impl.Logger.Errorw("error in building vault external secret", "secret", s.Name, "err", err)
+		return nil, err
+	}
+	if entries != nil {
+		return map[string]interface{}{
+			"apiVersion": "kubernetes-client.io/v1",
+			"kind":       "ExternalSecret",
+			"metadata":   objectMeta,
+			"spec": map[string]interface{}{
+				"backendType": "vault",
+				"data":        entries,
+			},
+		}, nil
+	}
+	var datamap map[string][]byte
+	if err := json.Unmarshal(s.Data, &datamap); err != nil {
+		impl.Logger.Errorw("error while unmarshal data", "err", err)
+		return nil, err
+	}
+	return v12.Secret{
+		TypeMeta: v1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: objectMeta,
+		Data:       datamap,
+		Type:       "Opaque",
+	}, nil
+}
+
 func (impl *CdWorkflowServiceImpl) GetWorkflow(name string, namespace string, url string, token string, isExtRun bool) (*v1alpha1.Workflow, error) {
 	impl.Logger.Debugw("getting wf", "name", name)
 	var wfClient v1alpha12.WorkflowInterface
@@ -23,8 +23,10 @@ import (
 	"encoding/json"
 	"fmt"
 	chartRepoRepository "github.com/devtron-labs/devtron/pkg/chartRepo/repository"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
 
 	"github.com/devtron-labs/devtron/internal/sql/repository/app"
+	app2 "github.com/devtron-labs/devtron/pkg/app"
 
 	repository4 "github.com/devtron-labs/devtron/pkg/cluster/repository"
 	"github.com/devtron-labs/devtron/pkg/sql"
@@ -68,6 +70,7 @@ type TemplateRequest struct {
 	IsAppMetricsEnabled     bool            `json:"isAppMetricsEnabled"`
 	Schema                  json.RawMessage `json:"schema"`
 	Readme                  string          `json:"readme"`
+	Comment                 string          `json:"comment,omitempty"`
 	UserId                  int32           `json:"-"`
 }
 
@@ -109,7 +112,8 @@ type AppConfigResponse struct {
 	PreviousAppConfig TemplateRequest `json:"previousAppConfig"`
 }
 
-type RefChartDir string
+// RefChartDir is shared with app, which renders the reference charts for deployment policies
+type RefChartDir = app2.RefChartDir
 type DefaultChart string
 
 type ChartService interface {
@@ -147,6 +151,7 @@ type ChartServiceImpl struct {
 	pipelineRepository        pipelineConfig.PipelineRepository
 	appLevelMetricsRepository repository3.AppLevelMetricsRepository
 	client                    *http.Client
+	configHistoryService      configHistory.ConfigHistoryService
 }
 
 func NewChartServiceImpl(chartRepository chartRepoRepository.ChartRepository,
@@ -167,6 +172,7 @@ func NewChartServiceImpl(chartRepository chartRepoRepository.ChartRepository,
 	appLevelMetricsRepository repository3.AppLevelMetricsRepository,
 	client *http.Client,
 	CustomFormatCheckers *util2.CustomFormatCheckers,
+	configHistoryService configHistory.ConfigHistoryService,
 ) *ChartServiceImpl {
 	return &ChartServiceImpl{
 		chartRepository:           chartRepository,
@@ -186,6 +192,7 @@ func NewChartServiceImpl(chartRepository chartRepoRepository.ChartRepository,
 		pipelineRepository:        pipelineRepository,
 		appLevelMetricsRepository: appLevelMetricsRepository,
 		client:                    client,
+		configHistoryService:      configHistoryService,
 	}
 }
 
@@ -375,6 +382,13 @@ func (impl ChartServiceImpl) Create(templateRequest TemplateRequest, ctx context
 		//If found any error, rollback chart museum
 		return nil, err
 	}
+	impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+		AppId:      templateRequest.AppId,
+		ConfigType: configHistory.ConfigTypeDeploymentTemplate,
+		Data:       chart.GlobalOverride,
+		Comment:    templateRequest.Comment,
+		UserId:     templateRequest.UserId,
+	})
 
 	appLevelMetrics, err := impl.appLevelMetricsRepository.FindByAppId(templateRequest.AppId)
 	if err != nil && err != pg.ErrNoRows {
@@ -750,6 +764,13 @@ func (impl ChartServiceImpl) UpdateAppOverride(templateRequest *TemplateRequest)
 	if err != nil {
 		return nil, err
 	}
+	impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+		AppId:      templateRequest.AppId,
+		ConfigType: configHistory.ConfigTypeDeploymentTemplate,
+		Data:       template.GlobalOverride,
+		Comment:    templateRequest.Comment,
+		UserId:     templateRequest.UserId,
+	})
 
 	if !(chartMajorVersion >= 3 && chartMinorVersion >= 1) {
 		appMetricRequest := AppMetricEnableDisableRequest{UserId: templateRequest.UserId, AppId: templateRequest.AppId, IsAppMetricsEnabled: false}
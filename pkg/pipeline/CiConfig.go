@@ -57,6 +57,7 @@ type CiConfig struct {
 	ExternalCiPayload          string   `env:"EXTERNAL_CI_PAYLOAD" envDefault:"{\"ciProjectDetails\":[{\"gitRepository\":\"https://github.com/srj92/getting-started-nodejs.git\",\"checkoutPath\":\"./abc\",\"commitHash\":\"239077135f8cdeeccb7857e2851348f558cb53d3\",\"commitTime\":\"2019-10-31T20:55:21+05:30\",\"branch\":\"master\",\"message\":\"Update README.md\",\"author\":\"Suraj Gupta \"}],\"dockerImage\":\"445808685819.dkr.ecr.us-east-2.amazonaws.com/orch:23907713-2\",\"digest\":\"test1\",\"dataSource\":\"ext\",\"materialType\":\"git\"}"`
 	CiArtifactLocationFormat   string   `env:"CI_ARTIFACT_LOCATION_FORMAT" envDefault:"%d/%d.zip"`
 	ImageScannerEndpoint       string   `env:"IMAGE_SCANNER_ENDPOINT" envDefault:"http://image-scanner-new-demo-devtroncd-service.devtroncd:80"`
+	SbomFormat                 string   `env:"SBOM_FORMAT" envDefault:"cyclonedx-json"` // cyclonedx-json or spdx-json
 	CloudProvider              string   `env:"BLOB_STORAGE_PROVIDER" envDefault:"S3"`
 	AzureAccountName           string   `env:"AZURE_ACCOUNT_NAME"`
 	AzureBlobContainerCiLog    string   `env:"AZURE_BLOB_CONTAINER_CI_LOG"`
@@ -24,6 +24,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -62,6 +63,7 @@ type CiHandler interface {
 
 	GetBuildHistory(pipelineId int, offset int, size int) ([]WorkflowResponse, error)
 	DownloadCiWorkflowArtifacts(pipelineId int, buildId int) (*os.File, error)
+	FetchCiWorkflowSbom(workflowId int, location string) ([]byte, error)
 	UpdateWorkflow(workflowStatus v1alpha1.WorkflowStatus) (int, error)
 
 	FetchCiStatusForTriggerView(appId int) ([]*pipelineConfig.CiWorkflowStatus, error)
@@ -144,6 +146,7 @@ type WorkflowResponse struct {
 	TriggeredByEmail string                           `json:"triggeredByEmail"`
 	Stage            string                           `json:"stage"`
 	ArtifactId       int                              `json:"artifactId"`
+	CanaryAnalysis   *CanaryAnalysisDetail            `json:"canaryAnalysis,omitempty"`
 }
 
 type GitTriggerInfoResponse struct {
@@ -214,6 +217,15 @@ func (impl *CiHandlerImpl) HandleCIWebhook(gitCiTriggerRequest bean.GitCiTrigger
 		return 0, err
 	}
 
+	skipReason, err := impl.checkPathFilters(ciPipeline.Id, ciMaterials, gitCiTriggerRequest.CiPipelineMaterial)
+	if err != nil {
+		return 0, err
+	}
+	if len(skipReason) > 0 {
+		impl.Logger.Infow("skipping build as no changed path matches the path filters", "ciPipelineId", ciPipeline.Id, "reason", skipReason)
+		return impl.ciService.SaveSkippedWorkflow(ciPipeline, commitHashes, gitCiTriggerRequest.TriggeredBy, skipReason)
+	}
+
 	trigger := Trigger{
 		PipelineId:   ciPipeline.Id,
 		CommitHashes: commitHashes,
@@ -227,6 +239,85 @@ func (impl *CiHandlerImpl) HandleCIWebhook(gitCiTriggerRequest bean.GitCiTrigger
 	return id, nil
 }
 
+// checkPathFilters returns the reason to skip the build if the material has path filters and none of the files changed
+// since the last build matches them. Builds are not skipped when the changes can not be found.
+func (impl *CiHandlerImpl) checkPathFilters(pipelineId int, ciMaterials []*pipelineConfig.CiPipelineMaterial, triggerMaterial bean.CiPipelineMaterial) (string, error) {
+	var ciMaterial *pipelineConfig.CiPipelineMaterial
+	for _, m := range ciMaterials {
+		if m.Id == triggerMaterial.Id {
+			ciMaterial = m
+		}
+	}
+	if ciMaterial == nil || (len(ciMaterial.IncludePaths) == 0 && len(ciMaterial.ExcludePaths) == 0) ||
+		triggerMaterial.Type == string(pipelineConfig.SOURCE_TYPE_WEBHOOK) || len(triggerMaterial.GitCommit.Commit) == 0 {
+		return "", nil
+	}
+	changes, err := impl.getChangedPaths(pipelineId, ciMaterial.Id, triggerMaterial.GitCommit)
+	if err != nil {
+		impl.Logger.Errorw("error in fetching changed paths, building without path filters", "ciPipelineMaterialId", ciMaterial.Id, "err", err)
+		return "", nil
+	} else if len(changes) == 0 {
+		impl.Logger.Warnw("no changed paths found, building without path filters", "ciPipelineMaterialId", ciMaterial.Id, "commit", triggerMaterial.GitCommit.Commit)
+		return "", nil
+	}
+	matched, err := matchPathFilters(ciMaterial.IncludePaths, ciMaterial.ExcludePaths, changes)
+	if err != nil {
+		impl.Logger.Errorw("invalid path filters, building without path filters", "ciPipelineMaterialId", ciMaterial.Id, "err", err)
+		return "", nil
+	} else if len(matched) > 0 {
+		impl.Logger.Debugw("changed path matches path filters", "ciPipelineMaterialId", ciMaterial.Id, "path", matched)
+		return "", nil
+	}
+	return fmt.Sprintf("skipped, none of the %d changed files in commit %s matches the path filters (include: %s, exclude: %s)",
+		len(changes), triggerMaterial.GitCommit.Commit, strings.Join(ciMaterial.IncludePaths, ", "), strings.Join(ciMaterial.ExcludePaths, ", ")), nil
+}
+
+// getChangedPaths collects the files changed since the commit of the last build of the pipeline, else of the commit itself
+func (impl *CiHandlerImpl) getChangedPaths(pipelineId int, ciMaterialId int, gitCommit bean.GitCommit) ([]string, error) {
+	lastWorkflow, err := impl.ciWorkflowRepository.FindLastTriggeredWorkflow(pipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		return nil, err
+	}
+	if err == nil {
+		lastCommit := lastWorkflow.GitTriggers[ciMaterialId].Commit
+		if len(lastCommit) > 0 && lastCommit != gitCommit.Commit {
+			changesResp, err := impl.gitSensorClient.FetchChanges(&gitSensor.FetchScmChangesRequest{
+				PipelineMaterialId: ciMaterialId,
+				From:               lastCommit,
+				To:                 gitCommit.Commit,
+			})
+			if err != nil {
+				impl.Logger.Warnw("error in fetching changes since last build", "ciPipelineMaterialId", ciMaterialId, "from", lastCommit, "err", err)
+			} else if changesResp != nil && len(changesResp.Commits) > 0 {
+				var changes []string
+				seen := make(map[string]bool)
+				for _, commit := range changesResp.Commits {
+					for _, change := range commit.Changes {
+						if !seen[change] {
+							seen[change] = true
+							changes = append(changes, change)
+						}
+					}
+				}
+				if len(changes) > 0 {
+					return changes, nil
+				}
+			}
+		}
+	}
+	if len(gitCommit.Changes) > 0 {
+		return gitCommit.Changes, nil
+	}
+	commit, err := impl.gitSensorClient.GetCommitMetadata(&gitSensor.CommitMetadataRequest{
+		PipelineMaterialId: ciMaterialId,
+		GitHash:            gitCommit.Commit,
+	})
+	if err != nil || commit == nil {
+		return nil, err
+	}
+	return commit.Changes, nil
+}
+
 func (impl *CiHandlerImpl) validateBuildSequence(gitCiTriggerRequest bean.GitCiTriggerRequest, pipelineId int) (bool, error) {
 	isValid := true
 	lastTriggeredBuild, err := impl.ciWorkflowRepository.FindLastTriggeredWorkflow(pipelineId)
@@ -516,6 +607,53 @@ func (impl *CiHandlerImpl) getLogsFromRepository(pipelineId int, ciWorkflow *pip
 	return logReader, cleanUp, err
 }
 
+// FetchCiWorkflowSbom downloads the sbom the ci runner uploaded to the build logs bucket of the workflow
+func (impl *CiHandlerImpl) FetchCiWorkflowSbom(workflowId int, location string) ([]byte, error) {
+	ciWorkflow, err := impl.ciWorkflowRepository.FindById(workflowId)
+	if err != nil {
+		impl.Logger.Errorw("err", "err", err)
+		return nil, err
+	}
+	ciConfig, err := impl.ciWorkflowRepository.FindConfigByPipelineId(ciWorkflow.CiPipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.Logger.Errorw("err", "err", err)
+		return nil, err
+	}
+	if ciConfig.LogsBucket == "" {
+		ciConfig.LogsBucket = impl.ciConfig.DefaultBuildLogsBucket
+	}
+	if ciConfig.CiCacheRegion == "" {
+		ciConfig.CiCacheRegion = impl.ciConfig.DefaultCacheBucketRegion
+	}
+	ciLogRequest := CiLogRequest{
+		PipelineId:    ciWorkflow.CiPipelineId,
+		WorkflowId:    ciWorkflow.Id,
+		WorkflowName:  ciWorkflow.Name + "-sbom",
+		Region:        ciConfig.CiCacheRegion,
+		LogsBucket:    ciConfig.LogsBucket,
+		LogsFilePath:  location,
+		CloudProvider: impl.ciConfig.CloudProvider,
+		AzureBlobConfig: &AzureBlobConfig{
+			Enabled:            impl.ciConfig.CloudProvider == BLOB_STORAGE_AZURE,
+			AccountName:        impl.ciConfig.AzureAccountName,
+			BlobContainerCiLog: impl.ciConfig.AzureBlobContainerCiLog,
+			AccountKey:         impl.ciConfig.AzureAccountKey,
+		},
+	}
+	if impl.ciConfig.CloudProvider == BLOB_STORAGE_MINIO {
+		ciLogRequest.MinioEndpoint = impl.ciConfig.MinioEndpoint
+		ciLogRequest.AccessKey = impl.ciConfig.MinioAccessKey
+		ciLogRequest.SecretKet = impl.ciConfig.MinioSecretKey
+	}
+	sbomFile, cleanUp, err := impl.ciLogService.FetchLogs(ciLogRequest)
+	if err != nil {
+		impl.Logger.Errorw("error in downloading sbom", "workflowId", workflowId, "location", location, "err", err)
+		return nil, err
+	}
+	defer cleanUp()
+	return ioutil.ReadFile(sbomFile.Name())
+}
+
 func (impl *CiHandlerImpl) DownloadCiWorkflowArtifacts(pipelineId int, buildId int) (*os.File, error) {
 	ciWorkflow, err := impl.ciWorkflowRepository.FindById(buildId)
 	if err != nil {
@@ -584,6 +722,10 @@ func (impl *CiHandlerImpl) GetHistoricBuildLogs(pipelineId int, workflowId int,
 		}
 	}
 
+	if len(ciWorkflow.LogLocation) == 0 {
+		// never archived, or removed by the artifact retention policy
+		return nil, &util.ApiError{HttpStatusCode: http.StatusNotFound, InternalMessage: "logs not found for workflow", UserMessage: "logs are not available for this build"}
+	}
 	if ciConfig.LogsBucket == "" {
 		ciConfig.LogsBucket = impl.ciConfig.DefaultBuildLogsBucket
 	}
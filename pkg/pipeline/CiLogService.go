@@ -42,6 +42,8 @@ import (
 type CiLogService interface {
 	FetchRunningWorkflowLogs(ciLogRequest CiLogRequest, token string, host string, isExt bool) (io.ReadCloser, func() error, error)
 	FetchLogs(ciLogRequest CiLogRequest) (*os.File, func() error, error)
+	// DeleteLogs removes the object at LogsFilePath from the logs bucket of the request
+	DeleteLogs(ciLogRequest CiLogRequest) error
 }
 
 type CiLogServiceImpl struct {
@@ -189,6 +191,41 @@ func (impl *CiLogServiceImpl) FetchLogs(ciLogRequest CiLogRequest) (*os.File, fu
 	return file, cleanUpFunc, nil
 }
 
+func (impl *CiLogServiceImpl) DeleteLogs(ciLogRequest CiLogRequest) error {
+	var err error
+	if ciLogRequest.CloudProvider == BLOB_STORAGE_S3 || ciLogRequest.CloudProvider == BLOB_STORAGE_MINIO {
+		config := &aws.Config{
+			Region: aws.String(ciLogRequest.Region),
+		}
+		if ciLogRequest.CloudProvider == BLOB_STORAGE_MINIO {
+			config = &aws.Config{
+				Region:           aws.String("us-west-2"),
+				Endpoint:         aws.String(ciLogRequest.MinioEndpoint),
+				DisableSSL:       aws.Bool(true),
+				S3ForcePathStyle: aws.Bool(true),
+				Credentials:      credentials.NewStaticCredentials(ciLogRequest.AccessKey, ciLogRequest.SecretKet, ""),
+			}
+		}
+		sess, sessErr := session.NewSession(config)
+		if sessErr != nil {
+			return sessErr
+		}
+		_, err = s32.New(sess).DeleteObject(&s32.DeleteObjectInput{
+			Bucket: aws.String(ciLogRequest.LogsBucket),
+			Key:    aws.String(ciLogRequest.LogsFilePath),
+		})
+	} else if ciLogRequest.CloudProvider == BLOB_STORAGE_AZURE {
+		blobClient := AzureBlob{logger: impl.logger}
+		err = blobClient.DeleteBlob(context.Background(), ciLogRequest.LogsFilePath, ciLogRequest.AzureBlobConfig)
+	} else {
+		return fmt.Errorf("unsupported cloud %s", ciLogRequest.CloudProvider)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in deleting logs", "bucket", ciLogRequest.LogsBucket, "path", ciLogRequest.LogsFilePath, "err", err)
+	}
+	return err
+}
+
 type AzureBlob struct {
 	logger *zap.SugaredLogger
 }
@@ -257,6 +294,20 @@ func (impl *AzureBlob) DownloadBlob(context context.Context, blobName string, co
 	return err
 }
 
+func (impl *AzureBlob) DeleteBlob(context context.Context, blobName string, config *AzureBlobConfig) error {
+	containerURL, err := impl.buildContainerUrl(config)
+	if err != nil {
+		return err
+	}
+	blobURL := containerURL.NewBlobURL(blobName)
+	_, err = blobURL.Delete(context, azblob.DeleteSnapshotsOptionInclude, azblob.BlobAccessConditions{})
+	if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+		// already removed, same as s3 which does not fail on missing keys
+		return nil
+	}
+	return err
+}
+
 func (impl *AzureBlob) UploadBlob(context context.Context, blobName string, config *AzureBlobConfig, cacheFileName string) error {
 	containerURL, err := impl.buildContainerUrl(config)
 	if err != nil {
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/gobwas/glob"
+	"net/http"
+	"strings"
+)
+
+// path filters are globs relative to the repo root where `*` stays within a directory and `**` crosses directories,
+// a filter without any glob character matches the path itself and everything under it
+func compilePathFilter(pattern string) (glob.Glob, error) {
+	pattern = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(pattern), "./"), "/")
+	if !strings.ContainsAny(pattern, "*?[{") {
+		pattern = strings.TrimSuffix(pattern, "/")
+		pattern = "{" + pattern + "," + pattern + "/**}"
+	}
+	return glob.Compile(pattern, '/')
+}
+
+func compilePathFilters(patterns []string) ([]glob.Glob, error) {
+	var globs []glob.Glob
+	for _, pattern := range patterns {
+		if len(strings.TrimSpace(pattern)) == 0 {
+			continue
+		}
+		g, err := compilePathFilter(pattern)
+		if err != nil {
+			return nil, err
+		}
+		globs = append(globs, g)
+	}
+	return globs, nil
+}
+
+func validateCiMaterialPathFilters(ciMaterials []*bean.CiMaterial) error {
+	for _, ciMaterial := range ciMaterials {
+		for _, pattern := range append(append([]string{}, ciMaterial.IncludePaths...), ciMaterial.ExcludePaths...) {
+			if _, err := compilePathFilter(pattern); err != nil {
+				return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: fmt.Sprintf("invalid path filter %s", pattern)}
+			}
+		}
+	}
+	return nil
+}
+
+// matchPathFilters returns the first changed file which is included and not excluded, empty if none is
+func matchPathFilters(includePaths []string, excludePaths []string, changes []string) (string, error) {
+	includes, err := compilePathFilters(includePaths)
+	if err != nil {
+		return "", err
+	}
+	excludes, err := compilePathFilters(excludePaths)
+	if err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		change = strings.TrimPrefix(change, "/")
+		if len(includes) > 0 && !matchAny(includes, change) {
+			continue
+		}
+		if matchAny(excludes, change) {
+			continue
+		}
+		return change, nil
+	}
+	return "", nil
+}
+
+func matchAny(globs []glob.Glob, path string) bool {
+	for _, g := range globs {
+		if g.Match(path) {
+			return true
+		}
+	}
+	return false
+}
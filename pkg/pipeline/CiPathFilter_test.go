@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import "testing"
+
+func TestCompilePathFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		path    string
+		want    bool
+		wantErr bool
+	}{
+		{name: "plain directory matches itself", pattern: "services/api", path: "services/api", want: true},
+		{name: "plain directory matches files under it", pattern: "services/api/", path: "services/api/cmd/main.go", want: true},
+		{name: "plain directory does not match a sibling prefix", pattern: "services/api", path: "services/api-gateway/main.go", want: false},
+		{name: "leading ./ and / are ignored", pattern: "./services/api", path: "services/api/main.go", want: true},
+		{name: "single star stays in a directory", pattern: "services/*/main.go", path: "services/api/main.go", want: true},
+		{name: "single star does not cross directories", pattern: "services/*.go", path: "services/api/main.go", want: false},
+		{name: "double star crosses directories", pattern: "services/**.go", path: "services/api/cmd/main.go", want: true},
+		{name: "invalid glob", pattern: "services/[api", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := compilePathFilter(tt.pattern)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("compilePathFilter() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got := g.Match(tt.path); got != tt.want {
+				t.Errorf("compilePathFilter(%q).Match(%q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchPathFilters(t *testing.T) {
+	tests := []struct {
+		name         string
+		includePaths []string
+		excludePaths []string
+		changes      []string
+		want         string
+	}{
+		{name: "no filters match the first change", changes: []string{"README.md", "api/main.go"}, want: "README.md"},
+		{name: "include picks the first included change", includePaths: []string{"api"}, changes: []string{"README.md", "api/main.go"}, want: "api/main.go"},
+		{name: "nothing included", includePaths: []string{"api"}, changes: []string{"README.md", "web/index.js"}, want: ""},
+		{name: "exclude drops changes", excludePaths: []string{"**.md"}, changes: []string{"README.md", "docs/guide.md"}, want: ""},
+		{name: "exclude wins over include", includePaths: []string{"api"}, excludePaths: []string{"api/docs"}, changes: []string{"api/docs/index.md", "api/main.go"}, want: "api/main.go"},
+		{name: "blank filters are ignored", includePaths: []string{" "}, changes: []string{"README.md"}, want: "README.md"},
+		{name: "leading slash of a change is ignored", includePaths: []string{"api"}, changes: []string{"/api/main.go"}, want: "api/main.go"},
+		{name: "no changes", includePaths: []string{"api"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := matchPathFilters(tt.includePaths, tt.excludePaths, tt.changes)
+			if err != nil {
+				t.Errorf("matchPathFilters() error = %v", err)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("matchPathFilters() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/devtron-labs/devtron/client/gitSensor"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+// ciScheduleEvaluationCronExpr is how often pipeline schedules are evaluated, so a schedule fires within a minute of its time
+const ciScheduleEvaluationCronExpr = "@every 1m"
+
+const ciScheduledTriggerJobName = "ci-scheduled-trigger"
+
+// ciScheduleLookback is how far back schedule occurrences are picked up, evaluations can be missed while replicas restart
+const ciScheduleLookback = 5 * time.Minute
+
+const ciScheduledTriggerUserId int32 = 1 //system user
+
+type CiScheduledTriggerService interface {
+	TriggerScheduledBuilds()
+}
+
+type CiScheduledTriggerServiceImpl struct {
+	logger                       *zap.SugaredLogger
+	ciPipelineRepository         pipelineConfig.CiPipelineRepository
+	ciPipelineMaterialRepository pipelineConfig.CiPipelineMaterialRepository
+	ciWorkflowRepository         pipelineConfig.CiWorkflowRepository
+	gitSensorClient              gitSensor.GitSensorClient
+	ciService                    CiService
+}
+
+func NewCiScheduledTriggerServiceImpl(logger *zap.SugaredLogger,
+	ciPipelineRepository pipelineConfig.CiPipelineRepository,
+	ciPipelineMaterialRepository pipelineConfig.CiPipelineMaterialRepository,
+	ciWorkflowRepository pipelineConfig.CiWorkflowRepository,
+	gitSensorClient gitSensor.GitSensorClient,
+	ciService CiService, scheduledJobRunner util3.ScheduledJobRunner) (*CiScheduledTriggerServiceImpl, error) {
+	impl := &CiScheduledTriggerServiceImpl{
+		logger:                       logger,
+		ciPipelineRepository:         ciPipelineRepository,
+		ciPipelineMaterialRepository: ciPipelineMaterialRepository,
+		ciWorkflowRepository:         ciWorkflowRepository,
+		gitSensorClient:              gitSensorClient,
+		ciService:                    ciService,
+	}
+	err := scheduledJobRunner.Schedule(ciScheduledTriggerJobName, ciScheduleEvaluationCronExpr, impl.TriggerScheduledBuilds)
+	if err != nil {
+		logger.Errorw("error in starting ci schedule evaluation", "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+// TriggerScheduledBuilds triggers every pipeline whose latest schedule occurrence has no build started after it
+func (impl *CiScheduledTriggerServiceImpl) TriggerScheduledBuilds() {
+	now := time.Now()
+
+	ciPipelines, err := impl.ciPipelineRepository.FindAllScheduled()
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching scheduled ci pipelines", "err", err)
+		return
+	}
+	for _, ciPipeline := range ciPipelines {
+		schedule, err := cron.ParseStandard(ciPipeline.CronSchedule)
+		if err != nil {
+			impl.logger.Errorw("invalid cron schedule on ci pipeline", "ciPipelineId", ciPipeline.Id, "cronSchedule", ciPipeline.CronSchedule, "err", err)
+			continue
+		}
+		occurrence := lastOccurrence(schedule, now.Add(-ciScheduleLookback), now)
+		if occurrence.IsZero() {
+			continue
+		}
+		err = impl.triggerScheduledBuild(ciPipeline, occurrence)
+		if err != nil {
+			impl.logger.Errorw("error in triggering scheduled build", "ciPipelineId", ciPipeline.Id, "err", err)
+		}
+	}
+}
+
+func (impl *CiScheduledTriggerServiceImpl) triggerScheduledBuild(ciPipeline *pipelineConfig.CiPipeline, occurrence time.Time) error {
+	if ciPipeline.IsExternal || ciPipeline.ParentCiPipeline > 0 {
+		impl.logger.Warnw("skipping scheduled build for linked or external ci pipeline", "ciPipelineId", ciPipeline.Id)
+		return nil
+	}
+	lastTriggeredWorkflow, err := impl.ciWorkflowRepository.FindLastTriggeredWorkflow(ciPipeline.Id)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching last triggered workflow", "ciPipelineId", ciPipeline.Id, "err", err)
+		return err
+	}
+	if lastTriggeredWorkflow.Status == string(v1alpha1.NodePending) || lastTriggeredWorkflow.Status == string(v1alpha1.NodeRunning) {
+		impl.logger.Infow("skipping scheduled build as previous build is still running", "ciPipelineId", ciPipeline.Id, "ciWorkflowId", lastTriggeredWorkflow.Id)
+		return nil
+	}
+	// a build started after the occurrence, by an earlier evaluation or otherwise, already covers it
+	if !lastTriggeredWorkflow.StartedOn.Before(occurrence) {
+		return nil
+	}
+
+	ciMaterials, err := impl.ciPipelineMaterialRepository.GetByPipelineId(ciPipeline.Id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching ci pipeline materials", "ciPipelineId", ciPipeline.Id, "err", err)
+		return err
+	}
+	var materialIds []int
+	ciMaterialMap := make(map[int]*pipelineConfig.CiPipelineMaterial)
+	for _, ciMaterial := range ciMaterials {
+		if ciMaterial.Type != pipelineConfig.SOURCE_TYPE_BRANCH_FIXED {
+			impl.logger.Warnw("skipping scheduled build, only branch based materials can be built on schedule", "ciPipelineId", ciPipeline.Id, "ciMaterialId", ciMaterial.Id)
+			return nil
+		}
+		materialIds = append(materialIds, ciMaterial.Id)
+		ciMaterialMap[ciMaterial.Id] = ciMaterial
+	}
+	if len(materialIds) == 0 {
+		return nil
+	}
+
+	heads, err := impl.gitSensorClient.GetHeadForPipelineMaterials(&gitSensor.HeadRequest{MaterialIds: materialIds})
+	if err != nil {
+		impl.logger.Errorw("error in fetching head for pipeline materials", "ciPipelineId", ciPipeline.Id, "err", err)
+		return err
+	}
+	commitHashes := make(map[int]bean.GitCommit)
+	for _, head := range heads {
+		ciMaterial, ok := ciMaterialMap[head.Id]
+		if !ok || len(head.GitCommit.Commit) == 0 {
+			continue
+		}
+		commitHashes[head.Id] = bean.GitCommit{
+			Commit:                 head.GitCommit.Commit,
+			Author:                 head.GitCommit.Author,
+			Date:                   head.GitCommit.Date,
+			Message:                head.GitCommit.Message,
+			Changes:                head.GitCommit.Changes,
+			GitRepoName:            ciMaterial.GitMaterial.Name[strings.Index(ciMaterial.GitMaterial.Name, "-")+1:],
+			GitRepoUrl:             ciMaterial.GitMaterial.Url,
+			CiConfigureSourceValue: ciMaterial.Value,
+			CiConfigureSourceType:  ciMaterial.Type,
+		}
+	}
+	if len(commitHashes) != len(materialIds) {
+		impl.logger.Warnw("skipping scheduled build, head commit not available for all materials", "ciPipelineId", ciPipeline.Id)
+		return nil
+	}
+
+	if ciPipeline.SkipUnchanged {
+		unchanged, err := impl.isUnchangedSinceLastSuccess(ciPipeline.Id, commitHashes)
+		if err != nil {
+			return err
+		}
+		if unchanged {
+			impl.logger.Infow("skipping scheduled build as commits are unchanged since last successful build", "ciPipelineId", ciPipeline.Id)
+			return nil
+		}
+	}
+
+	trigger := Trigger{
+		PipelineId:   ciPipeline.Id,
+		CommitHashes: commitHashes,
+		CiMaterials:  ciMaterials,
+		TriggeredBy:  ciScheduledTriggerUserId,
+	}
+	id, err := impl.ciService.TriggerCiPipeline(trigger)
+	if err != nil {
+		return err
+	}
+	impl.logger.Infow("scheduled build triggered", "ciPipelineId", ciPipeline.Id, "ciWorkflowId", id)
+	return nil
+}
+
+func (impl *CiScheduledTriggerServiceImpl) isUnchangedSinceLastSuccess(ciPipelineId int, commitHashes map[int]bean.GitCommit) (bool, error) {
+	lastSucceededWorkflow, err := impl.ciWorkflowRepository.FindLastSucceededWorkflow(ciPipelineId)
+	if util.IsErrNoRows(err) {
+		return false, nil
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching last successful workflow", "ciPipelineId", ciPipelineId, "err", err)
+		return false, err
+	}
+	for materialId, gitCommit := range commitHashes {
+		if lastSucceededWorkflow.GitTriggers[materialId].Commit != gitCommit.Commit {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// lastOccurrence returns the latest time of schedule in (from, to], zero when it has none
+func lastOccurrence(schedule cron.Schedule, from time.Time, to time.Time) time.Time {
+	var occurrence time.Time
+	for next := schedule.Next(from); !next.After(to); next = schedule.Next(next) {
+		occurrence = next
+	}
+	return occurrence
+}
+
+func validateCiCronSchedule(ciPipeline *bean.CiPipeline) error {
+	if len(ciPipeline.CronSchedule) == 0 {
+		return nil
+	}
+	if ciPipeline.IsExternal || ciPipeline.ParentCiPipeline > 0 {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "cron schedule on linked or external ci pipeline", UserMessage: "cron schedule is not supported for linked or external ci pipeline"}
+	}
+	if _, err := cron.ParseStandard(ciPipeline.CronSchedule); err != nil {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: "invalid cron schedule " + ciPipeline.CronSchedule}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestLastOccurrence(t *testing.T) {
+	hourly, err := cron.ParseStandard("0 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	everyMinute, err := cron.ParseStandard("* * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(hour, min int) time.Time {
+		return time.Date(2021, 3, 10, hour, min, 0, 0, time.Local)
+	}
+	tests := []struct {
+		name     string
+		schedule cron.Schedule
+		from     time.Time
+		to       time.Time
+		want     time.Time
+	}{
+		{name: "no occurrence in range", schedule: hourly, from: at(10, 1), to: at(10, 59), want: time.Time{}},
+		{name: "occurrence at the end of range", schedule: hourly, from: at(10, 55), to: at(11, 0), want: at(11, 0)},
+		{name: "occurrence at the start of range is excluded", schedule: hourly, from: at(11, 0), to: at(11, 5), want: time.Time{}},
+		{name: "latest of many occurrences", schedule: everyMinute, from: at(10, 55), to: at(11, 0), want: at(11, 0)},
+		{name: "latest occurrence before the end", schedule: hourly, from: at(9, 30), to: at(11, 30), want: at(11, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lastOccurrence(tt.schedule, tt.from, tt.to); !got.Equal(tt.want) {
+				t.Errorf("lastOccurrence() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	"github.com/devtron-labs/devtron/internal/util"
 	"github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/devtron-labs/devtron/pkg/vault"
 	util2 "github.com/devtron-labs/devtron/util/event"
 	"go.uber.org/zap"
 )
@@ -36,6 +37,8 @@ import (
 type CiService interface {
 	TriggerCiPipeline(trigger Trigger) (int, error)
 	GetCiMaterials(pipelineId int, ciMaterials []*pipelineConfig.CiPipelineMaterial) ([]*pipelineConfig.CiPipelineMaterial, error)
+	// SaveSkippedWorkflow records a build which was not run, with the reason as message, so that it shows up in build history
+	SaveSkippedWorkflow(pipeline *pipelineConfig.CiPipeline, commitHashes map[int]bean.GitCommit, userId int32, reason string) (int, error)
 }
 
 type CiServiceImpl struct {
@@ -48,10 +51,12 @@ type CiServiceImpl struct {
 	eventFactory                 client.EventFactory
 	mergeUtil                    *util.MergeUtil
 	ciPipelineRepository         pipelineConfig.CiPipelineRepository
+	vaultService                 vault.VaultService
 }
 
 func NewCiServiceImpl(Logger *zap.SugaredLogger, workflowService WorkflowService, ciPipelineMaterialRepository pipelineConfig.CiPipelineMaterialRepository,
-	ciWorkflowRepository pipelineConfig.CiWorkflowRepository, ciConfig *CiConfig, eventClient client.EventClient, eventFactory client.EventFactory, mergeUtil *util.MergeUtil, ciPipelineRepository pipelineConfig.CiPipelineRepository) *CiServiceImpl {
+	ciWorkflowRepository pipelineConfig.CiWorkflowRepository, ciConfig *CiConfig, eventClient client.EventClient, eventFactory client.EventFactory, mergeUtil *util.MergeUtil, ciPipelineRepository pipelineConfig.CiPipelineRepository,
+	vaultService vault.VaultService) *CiServiceImpl {
 	return &CiServiceImpl{
 		Logger:                       Logger,
 		workflowService:              workflowService,
@@ -62,10 +67,12 @@ func NewCiServiceImpl(Logger *zap.SugaredLogger, workflowService WorkflowService
 		eventFactory:                 eventFactory,
 		mergeUtil:                    mergeUtil,
 		ciPipelineRepository:         ciPipelineRepository,
+		vaultService:                 vaultService,
 	}
 }
 
 const WorkflowStarting = "Starting"
+const WorkflowSkipped = "Skipped"
 const WorkflowAborted = "Aborted"
 const WorkflowFailed = "Failed"
 
@@ -181,6 +188,46 @@ func (impl *CiServiceImpl) BuildPayload(trigger Trigger, pipeline *pipelineConfi
 
 func (impl *CiServiceImpl) saveNewWorkflow(pipeline *pipelineConfig.CiPipeline, wfConfig *pipelineConfig.CiWorkflowConfig,
 	commitHashes map[int]bean.GitCommit, userId int32) (wf *pipelineConfig.CiWorkflow, error error) {
+	ciWorkflow := &pipelineConfig.CiWorkflow{
+		Name:         pipeline.Name + "-" + strconv.Itoa(pipeline.Id),
+		Status:       WorkflowStarting,
+		Message:      "",
+		StartedOn:    time.Now(),
+		CiPipelineId: pipeline.Id,
+		Namespace:    wfConfig.Namespace,
+		GitTriggers:  buildGitTriggers(commitHashes),
+		LogLocation:  "",
+		TriggeredBy:  userId,
+	}
+	err := impl.ciWorkflowRepository.SaveWorkFlow(ciWorkflow)
+	if err != nil {
+		impl.Logger.Errorw("saving workflow error", "err", err)
+		return &pipelineConfig.CiWorkflow{}, err
+	}
+	impl.Logger.Debugw("workflow saved ", "id", ciWorkflow.Id)
+	return ciWorkflow, nil
+}
+
+func (impl *CiServiceImpl) SaveSkippedWorkflow(pipeline *pipelineConfig.CiPipeline, commitHashes map[int]bean.GitCommit, userId int32, reason string) (int, error) {
+	ciWorkflow := &pipelineConfig.CiWorkflow{
+		Name:         pipeline.Name + "-" + strconv.Itoa(pipeline.Id),
+		Status:       WorkflowSkipped,
+		Message:      reason,
+		StartedOn:    time.Now(),
+		FinishedOn:   time.Now(),
+		CiPipelineId: pipeline.Id,
+		GitTriggers:  buildGitTriggers(commitHashes),
+		TriggeredBy:  userId,
+	}
+	err := impl.ciWorkflowRepository.SaveWorkFlow(ciWorkflow)
+	if err != nil {
+		impl.Logger.Errorw("error in saving skipped workflow", "ciPipelineId", pipeline.Id, "err", err)
+		return 0, err
+	}
+	return ciWorkflow.Id, nil
+}
+
+func buildGitTriggers(commitHashes map[int]bean.GitCommit) map[int]pipelineConfig.GitCommit {
 	gitTriggers := make(map[int]pipelineConfig.GitCommit)
 	for k, v := range commitHashes {
 		gitCommit := pipelineConfig.GitCommit{
@@ -205,25 +252,7 @@ func (impl *CiServiceImpl) saveNewWorkflow(pipeline *pipelineConfig.CiPipeline,
 
 		gitTriggers[k] = gitCommit
 	}
-
-	ciWorkflow := &pipelineConfig.CiWorkflow{
-		Name:         pipeline.Name + "-" + strconv.Itoa(pipeline.Id),
-		Status:       WorkflowStarting,
-		Message:      "",
-		StartedOn:    time.Now(),
-		CiPipelineId: pipeline.Id,
-		Namespace:    wfConfig.Namespace,
-		GitTriggers:  gitTriggers,
-		LogLocation:  "",
-		TriggeredBy:  userId,
-	}
-	err := impl.ciWorkflowRepository.SaveWorkFlow(ciWorkflow)
-	if err != nil {
-		impl.Logger.Errorw("saving workflow error", "err", err)
-		return &pipelineConfig.CiWorkflow{}, err
-	}
-	impl.Logger.Debugw("workflow saved ", "id", ciWorkflow.Id)
-	return ciWorkflow, nil
+	return gitTriggers
 }
 
 func (impl *CiServiceImpl) executeCiPipeline(workflowRequest *WorkflowRequest) (*v1alpha1.Workflow, error) {
@@ -331,12 +360,27 @@ func (impl *CiServiceImpl) buildWfRequestForCiPipeline(pipeline *pipelineConfig.
 		impl.Logger.Errorw("err", "err", err)
 		return nil, err
 	}
+	dockerBuildArgs, secretBuildArgs, err := impl.vaultService.ResolveBuildArgs(string(merged))
+	if err != nil {
+		impl.Logger.Errorw("error in resolving vault references in build args", "pipelineId", pipeline.Id, "err", err)
+		return nil, err
+	}
 
 	checkoutPath := pipeline.CiTemplate.GitMaterial.CheckoutPath
 	if checkoutPath == "" {
 		checkoutPath = "./"
 	}
-	dockerfilePath := filepath.Join(pipeline.CiTemplate.GitMaterial.CheckoutPath, pipeline.CiTemplate.DockerfilePath)
+	ciBuildType := pipeline.CiTemplate.BuildType
+	if ciBuildType == "" {
+		ciBuildType = string(bean.DOCKERFILE_BUILD_TYPE)
+	}
+	var dockerfilePath, builderImage string
+	if ciBuildType == string(bean.BUILDPACK_BUILD_TYPE) {
+		// the runner builds the checked out source with the builder, there is no dockerfile
+		builderImage = pipeline.CiTemplate.BuilderImage
+	} else {
+		dockerfilePath = filepath.Join(pipeline.CiTemplate.GitMaterial.CheckoutPath, pipeline.CiTemplate.DockerfilePath)
+	}
 	workflowRequest := &WorkflowRequest{
 		WorkflowNamePrefix:         strconv.Itoa(savedWf.Id) + "-" + savedWf.Name,
 		PipelineName:               pipeline.Name,
@@ -346,8 +390,13 @@ func (impl *CiServiceImpl) buildWfRequestForCiPipeline(pipeline *pipelineConfig.
 		DockerImageTag:             dockerImageTag,
 		DockerRegistryURL:          pipeline.CiTemplate.DockerRegistry.RegistryURL,
 		DockerRepository:           pipeline.CiTemplate.DockerRepository,
-		DockerBuildArgs:            string(merged),
+		DockerBuildArgs:            dockerBuildArgs,
+		SecretBuildArgs:            secretBuildArgs,
 		DockerFileLocation:         dockerfilePath,
+		DockerBuildTargetPlatform:  pipeline.CiTemplate.TargetPlatform,
+		CiBuildType:                ciBuildType,
+		BuilderImage:               builderImage,
+		BuildContext:               checkoutPath,
 		DockerUsername:             pipeline.CiTemplate.DockerRegistry.Username,
 		DockerPassword:             pipeline.CiTemplate.DockerRegistry.Password,
 		AwsRegion:                  pipeline.CiTemplate.DockerRegistry.AWSRegion,
@@ -367,11 +416,17 @@ func (impl *CiServiceImpl) buildWfRequestForCiPipeline(pipeline *pipelineConfig.
 		AfterDockerBuildScripts:    afterDockerBuildScripts,
 		InvalidateCache:            trigger.InvalidateCache,
 		ScanEnabled:                pipeline.ScanEnabled,
+		SbomEnabled:                pipeline.ScanEnabled || pipeline.SbomEnabled,
+		SbomFormat:                 impl.ciConfig.SbomFormat,
 		CloudProvider:              impl.ciConfig.CloudProvider,
 		DefaultAddressPoolBaseCidr: impl.ciConfig.DefaultAddressPoolBaseCidr,
 		DefaultAddressPoolSize:     impl.ciConfig.DefaultAddressPoolSize,
 	}
 
+	if workflowRequest.SbomEnabled {
+		workflowRequest.SbomLocation = fmt.Sprintf("%s/%d/sbom.json", impl.ciConfig.DefaultArtifactKeyPrefix, savedWf.Id)
+	}
+
 	switch workflowRequest.CloudProvider {
 	case BLOB_STORAGE_S3:
 		//No AccessKey is used for uploading artifacts, instead IAM based auth is used
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+type ConfigHistoryRestoreService interface {
+	// RestoreVersion saves the stored data of a version as the current config, which creates a new version in turn
+	RestoreVersion(id int, userId int32) (*configHistory.ConfigHistoryDto, error)
+}
+
+type ConfigHistoryRestoreServiceImpl struct {
+	logger                  *zap.SugaredLogger
+	configHistoryService    configHistory.ConfigHistoryService
+	chartService            ChartService
+	propertiesConfigService PropertiesConfigService
+	configMapService        ConfigMapService
+}
+
+func NewConfigHistoryRestoreServiceImpl(logger *zap.SugaredLogger, configHistoryService configHistory.ConfigHistoryService,
+	chartService ChartService, propertiesConfigService PropertiesConfigService,
+	configMapService ConfigMapService) *ConfigHistoryRestoreServiceImpl {
+	return &ConfigHistoryRestoreServiceImpl{
+		logger:                  logger,
+		configHistoryService:    configHistoryService,
+		chartService:            chartService,
+		propertiesConfigService: propertiesConfigService,
+		configMapService:        configMapService,
+	}
+}
+
+func (impl ConfigHistoryRestoreServiceImpl) RestoreVersion(id int, userId int32) (*configHistory.ConfigHistoryDto, error) {
+	history, err := impl.configHistoryService.GetVersionForRestore(id)
+	if err != nil {
+		return nil, err
+	}
+	comment := fmt.Sprintf("restored version %d", history.Version)
+	switch history.ConfigType {
+	case configHistory.ConfigTypeDeploymentTemplate:
+		err = impl.restoreDeploymentTemplate(history.AppId, history.EnvId, history.Data, comment, userId)
+	case configHistory.ConfigTypeConfigMap, configHistory.ConfigTypeSecret:
+		err = impl.configMapService.RestoreConfigsList(history.AppId, history.EnvId, history.ConfigType == configHistory.ConfigTypeSecret, history.Data, comment, userId)
+	default:
+		err = &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("unsupported config type %s", history.ConfigType)}
+	}
+	if err != nil {
+		impl.logger.Errorw("error in restoring config version", "id", id, "configType", history.ConfigType, "err", err)
+		return nil, err
+	}
+	versions, err := impl.configHistoryService.GetHistory(history.AppId, history.EnvId, history.ConfigType)
+	if err != nil {
+		impl.logger.Errorw("error in fetching config history after restore", "id", id, "err", err)
+		return nil, err
+	} else if len(versions) == 0 {
+		// history is saved best effort, the config is restored even when its version could not be recorded
+		return nil, &util.ApiError{HttpStatusCode: http.StatusNotFound, UserMessage: "config restored but its new version was not found in history"}
+	}
+	return versions[0], nil
+}
+
+func (impl ConfigHistoryRestoreServiceImpl) restoreDeploymentTemplate(appId int, envId int, data string, comment string, userId int32) error {
+	if envId > 0 {
+		envProperties, err := impl.propertiesConfigService.GetLatestEnvironmentProperties(appId, envId)
+		if err != nil {
+			return err
+		} else if envProperties == nil {
+			return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "environment override not found, override the template before restoring"}
+		}
+		envProperties.EnvOverrideValues = json.RawMessage(data)
+		envProperties.Comment = comment
+		envProperties.UserId = userId
+		_, err = impl.propertiesConfigService.UpdateEnvironmentProperties(appId, envProperties, userId)
+		return err
+	}
+	template, err := impl.chartService.FindLatestChartForAppByAppId(appId)
+	if err != nil {
+		return err
+	}
+	template.ValuesOverride = json.RawMessage(data)
+	template.Comment = comment
+	template.UserId = userId
+	_, err = impl.chartService.UpdateAppOverride(template)
+	return err
+}
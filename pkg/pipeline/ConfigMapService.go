@@ -25,9 +25,12 @@ import (
 	"github.com/devtron-labs/devtron/internal/util"
 	chartRepoRepository "github.com/devtron-labs/devtron/pkg/chartRepo/repository"
 	"github.com/devtron-labs/devtron/pkg/commonService"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
+	"github.com/devtron-labs/devtron/pkg/vault"
 	util2 "github.com/devtron-labs/devtron/util"
 	"github.com/go-pg/pg"
 	"go.uber.org/zap"
+	"net/http"
 	"regexp"
 	"time"
 )
@@ -47,6 +50,7 @@ type ConfigDataRequest struct {
 	AppId         int           `json:"appId"`
 	EnvironmentId int           `json:"environmentId,omitempty"`
 	ConfigData    []*ConfigData `json:"configData"`
+	Comment       string        `json:"comment,omitempty"`
 	UserId        int32         `json:"-"`
 }
 
@@ -96,6 +100,8 @@ type ConfigData struct {
 	RoleARN               string           `json:"roleARN"`
 	SubPath               bool             `json:"subPath"`
 	FilePermission        string           `json:"filePermission"`
+	// VaultPreview is filled on fetch for the vault references in secret data, keyed by data key
+	VaultPreview map[string]*vault.SecretPreview `json:"vaultPreview,omitempty"`
 }
 
 const (
@@ -137,6 +143,12 @@ type ConfigMapService interface {
 	CSEnvironmentFetchForEdit(name string, id int, appId int, envId int) (*ConfigDataRequest, error)
 	ConfigSecretGlobalBulkPatch(bulkPatchRequest *BulkPatchRequest) (*BulkPatchRequest, error)
 	ConfigSecretEnvironmentBulkPatch(bulkPatchRequest *BulkPatchRequest) (*BulkPatchRequest, error)
+	// RestoreConfigsList replaces the whole config map or secret list of an app or env with a stored version
+	RestoreConfigsList(appId int, envId int, isSecret bool, data string, comment string, userId int32) error
+	// CSRotateKey sets a new base64 encoded value for a key of a secret in the env override, if the secret is not
+	// overridden in the env the override is created from the app level secret so that other envs keep their value.
+	// Returns true if the env override was created.
+	CSRotateKey(appId int, envId int, name string, key string, value string, comment string, userId int32) (bool, error)
 }
 
 type ConfigMapServiceImpl struct {
@@ -149,6 +161,8 @@ type ConfigMapServiceImpl struct {
 	environmentConfigRepository chartConfig.EnvConfigOverrideRepository
 	commonService               commonService.CommonService
 	appRepository               app.AppRepository
+	configHistoryService        configHistory.ConfigHistoryService
+	vaultService                vault.VaultService
 }
 
 func NewConfigMapServiceImpl(chartRepository chartRepoRepository.ChartRepository,
@@ -157,7 +171,8 @@ func NewConfigMapServiceImpl(chartRepository chartRepoRepository.ChartRepository
 	mergeUtil util.MergeUtil,
 	pipelineConfigRepository chartConfig.PipelineConfigRepository,
 	configMapRepository chartConfig.ConfigMapRepository, environmentConfigRepository chartConfig.EnvConfigOverrideRepository,
-	commonService commonService.CommonService, appRepository app.AppRepository) *ConfigMapServiceImpl {
+	commonService commonService.CommonService, appRepository app.AppRepository,
+	configHistoryService configHistory.ConfigHistoryService, vaultService vault.VaultService) *ConfigMapServiceImpl {
 	return &ConfigMapServiceImpl{
 		chartRepository:             chartRepository,
 		logger:                      logger,
@@ -168,9 +183,64 @@ func NewConfigMapServiceImpl(chartRepository chartRepoRepository.ChartRepository
 		environmentConfigRepository: environmentConfigRepository,
 		commonService:               commonService,
 		appRepository:               appRepository,
+		configHistoryService:        configHistoryService,
+		vaultService:                vaultService,
 	}
 }
 
+// createAppLevel, updateAppLevel, createEnvLevel and updateEnvLevel wrap the repository to version every save,
+// a row holds both lists so both are recorded and the unchanged one is skipped by the history service
+func (impl ConfigMapServiceImpl) createAppLevel(model *chartConfig.ConfigMapAppModel, comment string) (*chartConfig.ConfigMapAppModel, error) {
+	configMap, err := impl.configMapRepository.CreateAppLevel(model)
+	if err == nil {
+		impl.saveConfigHistory(model.AppId, 0, model.ConfigMapData, model.SecretData, comment, model.UpdatedBy)
+	}
+	return configMap, err
+}
+
+func (impl ConfigMapServiceImpl) updateAppLevel(model *chartConfig.ConfigMapAppModel, comment string) (*chartConfig.ConfigMapAppModel, error) {
+	configMap, err := impl.configMapRepository.UpdateAppLevel(model)
+	if err == nil {
+		impl.saveConfigHistory(model.AppId, 0, model.ConfigMapData, model.SecretData, comment, model.UpdatedBy)
+	}
+	return configMap, err
+}
+
+func (impl ConfigMapServiceImpl) createEnvLevel(model *chartConfig.ConfigMapEnvModel, comment string) (*chartConfig.ConfigMapEnvModel, error) {
+	configMap, err := impl.configMapRepository.CreateEnvLevel(model)
+	if err == nil {
+		impl.saveConfigHistory(model.AppId, model.EnvironmentId, model.ConfigMapData, model.SecretData, comment, model.UpdatedBy)
+	}
+	return configMap, err
+}
+
+func (impl ConfigMapServiceImpl) updateEnvLevel(model *chartConfig.ConfigMapEnvModel, comment string) (*chartConfig.ConfigMapEnvModel, error) {
+	configMap, err := impl.configMapRepository.UpdateEnvLevel(model)
+	if err == nil {
+		impl.saveConfigHistory(model.AppId, model.EnvironmentId, model.ConfigMapData, model.SecretData, comment, model.UpdatedBy)
+	}
+	return configMap, err
+}
+
+func (impl ConfigMapServiceImpl) saveConfigHistory(appId int, envId int, configMapData string, secretData string, comment string, userId int32) {
+	impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+		AppId:      appId,
+		EnvId:      envId,
+		ConfigType: configHistory.ConfigTypeConfigMap,
+		Data:       configMapData,
+		Comment:    comment,
+		UserId:     userId,
+	})
+	impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+		AppId:      appId,
+		EnvId:      envId,
+		ConfigType: configHistory.ConfigTypeSecret,
+		Data:       secretData,
+		Comment:    comment,
+		UserId:     userId,
+	})
+}
+
 func (impl ConfigMapServiceImpl) adapter(model *chartConfig.ConfigMapAppModel) (*ConfigMapRequest, error) {
 	configMapRequest := &ConfigMapRequest{
 		Id:            model.Id,
@@ -242,7 +312,7 @@ func (impl ConfigMapServiceImpl) CMGlobalAddUpdate(configMapRequest *ConfigDataR
 		model.UpdatedBy = configMapRequest.UserId
 		model.UpdatedOn = time.Now()
 
-		configMap, err := impl.configMapRepository.UpdateAppLevel(model)
+		configMap, err := impl.updateAppLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while fetching from db", "error", err)
 			return nil, err
@@ -267,7 +337,7 @@ func (impl ConfigMapServiceImpl) CMGlobalAddUpdate(configMapRequest *ConfigDataR
 		model.CreatedOn = time.Now()
 		model.UpdatedOn = time.Now()
 
-		configMap, err := impl.configMapRepository.CreateAppLevel(model)
+		configMap, err := impl.createAppLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while creating app level", "error", err)
 			return nil, err
@@ -365,7 +435,7 @@ func (impl ConfigMapServiceImpl) CMEnvironmentAddUpdate(configMapRequest *Config
 		model.UpdatedBy = configMapRequest.UserId
 		model.UpdatedOn = time.Now()
 
-		configMap, err := impl.configMapRepository.UpdateEnvLevel(model)
+		configMap, err := impl.updateEnvLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while fetching from db", "error", err)
 			return nil, err
@@ -391,7 +461,7 @@ func (impl ConfigMapServiceImpl) CMEnvironmentAddUpdate(configMapRequest *Config
 		model.CreatedOn = time.Now()
 		model.UpdatedOn = time.Now()
 
-		configMap, err := impl.configMapRepository.CreateEnvLevel(model)
+		configMap, err := impl.createEnvLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while creating app level", "error", err)
 			return nil, err
@@ -505,6 +575,15 @@ func (impl ConfigMapServiceImpl) CSGlobalAddUpdate(configMapRequest *ConfigDataR
 		return configMapRequest, err
 	}
 
+	configData.VaultPreview = nil
+	if !configData.External {
+		err = impl.vaultService.ValidateSecretData(configData.Data)
+		if err != nil {
+			impl.logger.Errorw("error in validating vault references", "name", configData.Name, "error", err)
+			return configMapRequest, err
+		}
+	}
+
 	if configMapRequest.Id > 0 {
 		model, err := impl.configMapRepository.GetByIdAppLevel(configMapRequest.Id)
 		if err != nil {
@@ -548,7 +627,7 @@ func (impl ConfigMapServiceImpl) CSGlobalAddUpdate(configMapRequest *ConfigDataR
 		model.UpdatedBy = configMapRequest.UserId
 		model.UpdatedOn = time.Now()
 
-		secret, err := impl.configMapRepository.UpdateAppLevel(model)
+		secret, err := impl.updateAppLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while fetching from db", "error", err)
 			return nil, err
@@ -573,7 +652,7 @@ func (impl ConfigMapServiceImpl) CSGlobalAddUpdate(configMapRequest *ConfigDataR
 		model.CreatedOn = time.Now()
 		model.UpdatedOn = time.Now()
 
-		secret, err := impl.configMapRepository.CreateAppLevel(model)
+		secret, err := impl.createAppLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while creating app level", "error", err)
 			return nil, err
@@ -614,6 +693,9 @@ func (impl ConfigMapServiceImpl) CSGlobalFetch(appId int) (*ConfigDataRequest, e
 	//removing actual values
 	var configs []*ConfigData
 	for _, item := range configDataRequest.ConfigData {
+		if !item.External {
+			item.VaultPreview = impl.vaultService.PreviewSecretData(item.Data)
+		}
 		resultMap := make(map[string]string)
 		resultMapFinal := make(map[string]string)
 
@@ -674,6 +756,15 @@ func (impl ConfigMapServiceImpl) CSEnvironmentAddUpdate(configMapRequest *Config
 		return configMapRequest, err
 	}
 
+	configData.VaultPreview = nil
+	if !configData.External {
+		err = impl.vaultService.ValidateSecretData(configData.Data)
+		if err != nil {
+			impl.logger.Errorw("error in validating vault references", "name", configData.Name, "error", err)
+			return configMapRequest, err
+		}
+	}
+
 	if configMapRequest.Id > 0 {
 		model, err := impl.configMapRepository.GetByIdEnvLevel(configMapRequest.Id)
 		if err != nil {
@@ -717,7 +808,7 @@ func (impl ConfigMapServiceImpl) CSEnvironmentAddUpdate(configMapRequest *Config
 		model.UpdatedBy = configMapRequest.UserId
 		model.UpdatedOn = time.Now()
 
-		configMap, err := impl.configMapRepository.UpdateEnvLevel(model)
+		configMap, err := impl.updateEnvLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while fetching from db", "error", err)
 			return nil, err
@@ -743,7 +834,7 @@ func (impl ConfigMapServiceImpl) CSEnvironmentAddUpdate(configMapRequest *Config
 		model.CreatedOn = time.Now()
 		model.UpdatedOn = time.Now()
 
-		configMap, err := impl.configMapRepository.CreateEnvLevel(model)
+		configMap, err := impl.createEnvLevel(model, configMapRequest.Comment)
 		if err != nil {
 			impl.logger.Errorw("error while creating app level", "error", err)
 			return nil, err
@@ -850,6 +941,9 @@ func (impl ConfigMapServiceImpl) CSEnvironmentFetch(appId int, envId int) (*Conf
 	//removing actual values
 	var configs []*ConfigData
 	for _, item := range configDataRequest.ConfigData {
+		if !item.External {
+			item.VaultPreview = impl.vaultService.PreviewSecretData(item.Data)
+		}
 
 		if item.Data != nil {
 			resultMap := make(map[string]string)
@@ -962,7 +1056,7 @@ func (impl ConfigMapServiceImpl) CMGlobalDelete(name string, id int, userId int3
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateAppLevel(model)
+		_, err = impl.updateAppLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at app level", "error", err)
 			return false, err
@@ -1009,7 +1103,7 @@ func (impl ConfigMapServiceImpl) CMEnvironmentDelete(name string, id int, userId
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateEnvLevel(model)
+		_, err = impl.updateEnvLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at env level", "error", err)
 			return false, err
@@ -1055,7 +1149,7 @@ func (impl ConfigMapServiceImpl) CSGlobalDelete(name string, id int, userId int3
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateAppLevel(model)
+		_, err = impl.updateAppLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at app level", "error", err)
 			return false, err
@@ -1102,7 +1196,7 @@ func (impl ConfigMapServiceImpl) CSEnvironmentDelete(name string, id int, userId
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateEnvLevel(model)
+		_, err = impl.updateEnvLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at env level ", "error", err)
 			return false, err
@@ -1150,7 +1244,7 @@ func (impl ConfigMapServiceImpl) CMGlobalDeleteByAppId(name string, appId int, u
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateAppLevel(model)
+		_, err = impl.updateAppLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at app level", "error", err)
 			return false, err
@@ -1197,7 +1291,7 @@ func (impl ConfigMapServiceImpl) CMEnvironmentDeleteByAppIdAndEnvId(name string,
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateEnvLevel(model)
+		_, err = impl.updateEnvLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at env level", "error", err)
 			return false, err
@@ -1243,7 +1337,7 @@ func (impl ConfigMapServiceImpl) CSGlobalDeleteByAppId(name string, appId int, u
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateAppLevel(model)
+		_, err = impl.updateAppLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at app level", "error", err)
 			return false, err
@@ -1290,7 +1384,7 @@ func (impl ConfigMapServiceImpl) CSEnvironmentDeleteByAppIdAndEnvId(name string,
 		model.UpdatedBy = userId
 		model.UpdatedOn = time.Now()
 
-		_, err = impl.configMapRepository.UpdateEnvLevel(model)
+		_, err = impl.updateEnvLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while updating at env level ", "error", err)
 			return false, err
@@ -1518,7 +1612,7 @@ func (impl ConfigMapServiceImpl) ConfigSecretGlobalBulkPatch(bulkPatchRequest *B
 		}
 		model.UpdatedBy = bulkPatchRequest.UserId
 		model.UpdatedOn = time.Now()
-		_, err = impl.configMapRepository.UpdateAppLevel(model)
+		_, err = impl.updateAppLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while fetching from db", "error", err)
 			return nil, err
@@ -1603,7 +1697,7 @@ func (impl ConfigMapServiceImpl) ConfigSecretEnvironmentBulkPatch(bulkPatchReque
 		}
 		model.UpdatedBy = bulkPatchRequest.UserId
 		model.UpdatedOn = time.Now()
-		_, err = impl.configMapRepository.UpdateEnvLevel(model)
+		_, err = impl.updateEnvLevel(model, "")
 		if err != nil {
 			impl.logger.Errorw("error while fetching from db", "error", err)
 			return nil, err
@@ -1667,3 +1761,147 @@ func (impl ConfigMapServiceImpl) buildBulkPayload(bulkPatchRequest *BulkPatchReq
 	}
 	return bulkPatchRequest, nil
 }
+
+func (impl ConfigMapServiceImpl) RestoreConfigsList(appId int, envId int, isSecret bool, data string, comment string, userId int32) error {
+	if envId > 0 {
+		model, err := impl.configMapRepository.GetByAppIdAndEnvIdEnvLevel(appId, envId)
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error while fetching from db", "appId", appId, "envId", envId, "error", err)
+			return err
+		}
+		if isSecret {
+			model.SecretData = data
+		} else {
+			model.ConfigMapData = data
+		}
+		model.UpdatedBy = userId
+		model.UpdatedOn = time.Now()
+		if model.Id > 0 {
+			_, err = impl.updateEnvLevel(model, comment)
+		} else {
+			model.AppId = appId
+			model.EnvironmentId = envId
+			model.CreatedBy = userId
+			model.CreatedOn = time.Now()
+			_, err = impl.createEnvLevel(model, comment)
+		}
+		if err != nil {
+			impl.logger.Errorw("error while restoring env level configs", "appId", appId, "envId", envId, "error", err)
+		}
+		return err
+	}
+	model, err := impl.configMapRepository.GetByAppIdAppLevel(appId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error while fetching from db", "appId", appId, "error", err)
+		return err
+	}
+	if isSecret {
+		model.SecretData = data
+	} else {
+		model.ConfigMapData = data
+	}
+	model.UpdatedBy = userId
+	model.UpdatedOn = time.Now()
+	if model.Id > 0 {
+		_, err = impl.updateAppLevel(model, comment)
+	} else {
+		model.AppId = appId
+		model.CreatedBy = userId
+		model.CreatedOn = time.Now()
+		_, err = impl.createAppLevel(model, comment)
+	}
+	if err != nil {
+		impl.logger.Errorw("error while restoring app level configs", "appId", appId, "error", err)
+	}
+	return err
+}
+
+func (impl ConfigMapServiceImpl) CSRotateKey(appId int, envId int, name string, key string, value string, comment string, userId int32) (bool, error) {
+	envModel, err := impl.configMapRepository.GetByAppIdAndEnvIdEnvLevel(appId, envId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error while fetching from db", "appId", appId, "envId", envId, "error", err)
+		return false, err
+	}
+	configData, err := impl.findSecret(envModel.SecretData, name)
+	if err != nil {
+		return false, err
+	}
+	overrideCreated := false
+	if configData == nil {
+		appModel, err := impl.configMapRepository.GetByAppIdAppLevel(appId)
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error while fetching from db", "appId", appId, "error", err)
+			return false, err
+		}
+		configData, err = impl.findSecret(appModel.SecretData, name)
+		if err != nil {
+			return false, err
+		} else if configData == nil {
+			msg := fmt.Sprintf("secret %s not found", name)
+			return false, &util.ApiError{HttpStatusCode: http.StatusNotFound, InternalMessage: msg, UserMessage: msg}
+		}
+		overrideCreated = true
+	}
+	changed, err := impl.setSecretKey(configData, key, value)
+	if err != nil || !changed {
+		return false, err
+	}
+	_, err = impl.CSEnvironmentAddUpdate(&ConfigDataRequest{
+		Id:            envModel.Id,
+		AppId:         appId,
+		EnvironmentId: envId,
+		ConfigData:    []*ConfigData{configData},
+		UserId:        userId,
+		Comment:       comment,
+	})
+	return overrideCreated, err
+}
+
+func (impl ConfigMapServiceImpl) findSecret(secretData string, name string) (*ConfigData, error) {
+	if len(secretData) == 0 {
+		return nil, nil
+	}
+	secretsList := &SecretsList{}
+	err := json.Unmarshal([]byte(secretData), secretsList)
+	if err != nil {
+		impl.logger.Errorw("error while Unmarshal", "error", err)
+		return nil, err
+	}
+	for _, item := range secretsList.ConfigData {
+		if item.Name == name {
+			return item, nil
+		}
+	}
+	return nil, nil
+}
+
+// setSecretKey only replaces a key which is already present, external secrets are managed outside devtron.
+// Returns false if the key already has the value.
+func (impl ConfigMapServiceImpl) setSecretKey(configData *ConfigData, key string, value string) (bool, error) {
+	if configData.External {
+		msg := fmt.Sprintf("external secret %s can not be rotated", configData.Name)
+		return false, &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: msg, UserMessage: msg}
+	}
+	data := make(map[string]string)
+	if len(configData.Data) > 0 {
+		err := json.Unmarshal(configData.Data, &data)
+		if err != nil {
+			impl.logger.Errorw("error while Unmarshal", "name", configData.Name, "error", err)
+			return false, err
+		}
+	}
+	existing, ok := data[key]
+	if !ok {
+		msg := fmt.Sprintf("key %s not found in secret %s", key, configData.Name)
+		return false, &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: msg, UserMessage: msg}
+	} else if existing == value {
+		return false, nil
+	}
+	data[key] = value
+	dataByte, err := json.Marshal(data)
+	if err != nil {
+		return false, err
+	}
+	configData.Data = dataByte
+	return true, nil
+}
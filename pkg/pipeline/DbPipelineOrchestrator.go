@@ -118,6 +118,14 @@ const BEFORE_DOCKER_BUILD string = "BEFORE_DOCKER_BUILD"
 const AFTER_DOCKER_BUILD string = "AFTER_DOCKER_BUILD"
 
 func (impl DbPipelineOrchestratorImpl) PatchMaterialValue(createRequest *bean.CiPipeline, userId int32) (*bean.CiPipeline, error) {
+	if err := validateCiCronSchedule(createRequest); err != nil {
+		impl.logger.Errorw("invalid cron schedule", "cronSchedule", createRequest.CronSchedule, "err", err)
+		return nil, err
+	}
+	if err := validateCiMaterialPathFilters(createRequest.CiMaterial); err != nil {
+		impl.logger.Errorw("invalid path filter", "ciPipelineId", createRequest.Id, "err", err)
+		return nil, err
+	}
 	argByte, err := json.Marshal(createRequest.DockerArgs)
 	if err != nil {
 		impl.logger.Error(err)
@@ -141,12 +149,25 @@ func (impl DbPipelineOrchestratorImpl) PatchMaterialValue(createRequest *bean.Ci
 		Deleted:          createRequest.Deleted,
 		ParentCiPipeline: createRequest.ParentCiPipeline,
 		ScanEnabled:      createRequest.ScanEnabled,
+		CronSchedule:     createRequest.CronSchedule,
+		SkipUnchanged:    createRequest.SkipUnchanged,
+		SbomEnabled:      createRequest.SbomEnabled,
 		AuditLog:         sql.AuditLog{UpdatedBy: userId, UpdatedOn: time.Now()},
 	}
 	err = impl.ciPipelineRepository.Update(ciPipelineObject, tx)
 	if err != nil {
 		return nil, err
 	}
+	err = impl.ciPipelineRepository.UpdateCronSchedule(ciPipelineObject, tx)
+	if err != nil {
+		impl.logger.Errorw("error in updating cron schedule", "ciPipelineId", ciPipelineObject.Id, "err", err)
+		return nil, err
+	}
+	err = impl.ciPipelineRepository.UpdateSbomEnabled(ciPipelineObject, tx)
+	if err != nil {
+		impl.logger.Errorw("error in updating sbom flag", "ciPipelineId", ciPipelineObject.Id, "err", err)
+		return nil, err
+	}
 
 	ciPipelineScripts, err := impl.ciPipelineRepository.FindCiScriptsByCiPipelineId(createRequest.Id)
 	if err != nil && !util.IsErrNoRows(err) {
@@ -177,6 +198,8 @@ func (impl DbPipelineOrchestratorImpl) PatchMaterialValue(createRequest *bean.Ci
 			Type:          material.Source.Type,
 			Active:        createRequest.Active,
 			GitMaterialId: material.GitMaterialId,
+			IncludePaths:  material.IncludePaths,
+			ExcludePaths:  material.ExcludePaths,
 			AuditLog:      sql.AuditLog{UpdatedBy: userId, UpdatedOn: time.Now()},
 		}
 		if material.Id == 0 {
@@ -198,6 +221,10 @@ func (impl DbPipelineOrchestratorImpl) PatchMaterialValue(createRequest *bean.Ci
 	if err != nil {
 		return nil, err
 	}
+	err = impl.CiPipelineMaterialRepository.UpdatePathFilters(tx, materialsUpdate...)
+	if err != nil {
+		return nil, err
+	}
 	materials = append(materials, materialsAdd...)
 	materials = append(materials, materialsUpdate...)
 
@@ -388,6 +415,14 @@ func (impl DbPipelineOrchestratorImpl) CreateCiConf(createRequest *bean.CiConfig
 			}
 			scriptNames[s.Name] = true
 		}
+		if err := validateCiCronSchedule(ciPipeline); err != nil {
+			impl.logger.Errorw("invalid cron schedule", "cronSchedule", ciPipeline.CronSchedule, "err", err)
+			return nil, err
+		}
+		if err := validateCiMaterialPathFilters(ciPipeline.CiMaterial); err != nil {
+			impl.logger.Errorw("invalid path filter", "ciPipeline", ciPipeline.Name, "err", err)
+			return nil, err
+		}
 
 		argByte, err := json.Marshal(ciPipeline.DockerArgs)
 		if err != nil {
@@ -415,6 +450,9 @@ func (impl DbPipelineOrchestratorImpl) CreateCiConf(createRequest *bean.CiConfig
 			Active:           true,
 			Deleted:          false,
 			ScanEnabled:      createRequest.ScanEnabled,
+			CronSchedule:     ciPipeline.CronSchedule,
+			SkipUnchanged:    ciPipeline.SkipUnchanged,
+			SbomEnabled:      ciPipeline.SbomEnabled,
 			AuditLog:         sql.AuditLog{UpdatedBy: createRequest.UserId, CreatedBy: createRequest.UserId, UpdatedOn: time.Now(), CreatedOn: time.Now()},
 		}
 		err = impl.ciPipelineRepository.Save(ciPipelineObject, tx)
@@ -455,6 +493,8 @@ func (impl DbPipelineOrchestratorImpl) CreateCiConf(createRequest *bean.CiConfig
 				Type:          r.Source.Type,
 				Path:          r.Path,
 				CheckoutPath:  r.CheckoutPath,
+				IncludePaths:  r.IncludePaths,
+				ExcludePaths:  r.ExcludePaths,
 				CiPipelineId:  ciPipelineObject.Id,
 				Active:        true,
 				AuditLog:      sql.AuditLog{UpdatedBy: createRequest.UserId, CreatedBy: createRequest.UserId, UpdatedOn: time.Now(), CreatedOn: time.Now()},
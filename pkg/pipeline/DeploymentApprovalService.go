@@ -0,0 +1,440 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	bean2 "github.com/devtron-labs/devtron/api/bean"
+	client "github.com/devtron-labs/devtron/client/events"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/devtron-labs/devtron/pkg/user"
+	util2 "github.com/devtron-labs/devtron/util/event"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type DeploymentApprovalActionRequest struct {
+	ApprovalRequestId int                                           `json:"approvalRequestId" validate:"number,required"`
+	Action            pipelineConfig.DeploymentApprovalUserResponse `json:"action" validate:"oneof=APPROVED REJECTED"`
+	Comment           string                                        `json:"comment"`
+	UserId            int32                                         `json:"-"`
+}
+
+type DeploymentApprovalTriggerRequest struct {
+	PipelineId   int   `json:"pipelineId" validate:"number,required"`
+	CiArtifactId int   `json:"ciArtifactId" validate:"number,required"`
+	UserId       int32 `json:"-"`
+}
+
+type DeploymentApprovalUserResponseDto struct {
+	UserId       int32                                         `json:"userId"`
+	EmailId      string                                        `json:"emailId"`
+	UserResponse pipelineConfig.DeploymentApprovalUserResponse `json:"userResponse"`
+	Comment      string                                        `json:"comment"`
+	RespondedOn  time.Time                                     `json:"respondedOn"`
+}
+
+type DeploymentApprovalResponse struct {
+	Id            int                                     `json:"id"`
+	PipelineId    int                                     `json:"pipelineId"`
+	CiArtifactId  int                                     `json:"ciArtifactId"`
+	Status        pipelineConfig.DeploymentApprovalStatus `json:"status"`
+	RequiredCount int                                     `json:"requiredCount"`
+	RequestedBy   int32                                   `json:"requestedBy"`
+	RequestedOn   time.Time                               `json:"requestedOn"`
+	UserResponses []*DeploymentApprovalUserResponseDto    `json:"userResponses"`
+}
+
+type DeploymentApprovalService interface {
+	SaveApprovalConfig(pipelineId int, config *bean.DeploymentApprovalConfig, userId int32, tx *pg.Tx) error
+	GetApprovalConfig(pipelineId int) (*bean.DeploymentApprovalConfig, error)
+	CheckApproved(pipelineId int, ciArtifactId int) (bool, error)
+	RaiseApprovalRequest(request *DeploymentApprovalTriggerRequest) (*DeploymentApprovalResponse, error)
+	SubmitApprovalAction(request *DeploymentApprovalActionRequest) (*DeploymentApprovalResponse, error)
+	FetchApprovalRequests(pipelineId int) ([]*DeploymentApprovalResponse, error)
+}
+
+type DeploymentApprovalServiceImpl struct {
+	logger                       *zap.SugaredLogger
+	deploymentApprovalRepository pipelineConfig.DeploymentApprovalRepository
+	pipelineRepository           pipelineConfig.PipelineRepository
+	ciArtifactRepository         repository.CiArtifactRepository
+	userService                  user.UserService
+	eventClient                  client.EventClient
+	eventFactory                 client.EventFactory
+}
+
+func NewDeploymentApprovalServiceImpl(logger *zap.SugaredLogger,
+	deploymentApprovalRepository pipelineConfig.DeploymentApprovalRepository,
+	pipelineRepository pipelineConfig.PipelineRepository,
+	ciArtifactRepository repository.CiArtifactRepository,
+	userService user.UserService,
+	eventClient client.EventClient,
+	eventFactory client.EventFactory) *DeploymentApprovalServiceImpl {
+	return &DeploymentApprovalServiceImpl{
+		logger:                       logger,
+		deploymentApprovalRepository: deploymentApprovalRepository,
+		pipelineRepository:           pipelineRepository,
+		ciArtifactRepository:         ciArtifactRepository,
+		userService:                  userService,
+		eventClient:                  eventClient,
+		eventFactory:                 eventFactory,
+	}
+}
+
+// SaveApprovalConfig creates, updates or (when config is nil or requires no approvals) deactivates the approval gate of
+// a cd pipeline
+func (impl DeploymentApprovalServiceImpl) SaveApprovalConfig(pipelineId int, config *bean.DeploymentApprovalConfig, userId int32, tx *pg.Tx) error {
+	existing, err := impl.deploymentApprovalRepository.FindConfigByPipelineId(pipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching approval config", "pipelineId", pipelineId, "err", err)
+		return err
+	}
+	if config == nil || config.RequiredCount == 0 {
+		if existing.Id > 0 {
+			existing.Active = false
+			existing.UpdatedOn = time.Now()
+			existing.UpdatedBy = userId
+			err = impl.deploymentApprovalRepository.UpdateConfig(existing, tx)
+			if err != nil {
+				impl.logger.Errorw("error in deactivating approval config", "pipelineId", pipelineId, "err", err)
+				return err
+			}
+		}
+		return nil
+	}
+	if len(config.ApproverEmails) == 0 && len(config.ApproverGroups) == 0 {
+		return &util.ApiError{
+			HttpStatusCode:  http.StatusBadRequest,
+			InternalMessage: "approval config must have at least one approver user or group",
+			UserMessage:     "approval config must have at least one approver user or group",
+		}
+	}
+	//pipeline updates are not struct validated, so the required count is checked here as well
+	if config.RequiredCount < 0 {
+		return &util.ApiError{
+			HttpStatusCode:  http.StatusBadRequest,
+			InternalMessage: "approval config required count must not be negative",
+			UserMessage:     "approval config required count must not be negative, zero turns the approval gate off",
+		}
+	}
+	if existing.Id > 0 {
+		existing.RequiredCount = config.RequiredCount
+		existing.ApproverEmails = config.ApproverEmails
+		existing.ApproverGroups = config.ApproverGroups
+		existing.UpdatedOn = time.Now()
+		existing.UpdatedBy = userId
+		err = impl.deploymentApprovalRepository.UpdateConfig(existing, tx)
+	} else {
+		model := &pipelineConfig.DeploymentApprovalConfig{
+			PipelineId:     pipelineId,
+			RequiredCount:  config.RequiredCount,
+			ApproverEmails: config.ApproverEmails,
+			ApproverGroups: config.ApproverGroups,
+			Active:         true,
+			AuditLog:       sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+		}
+		err = impl.deploymentApprovalRepository.SaveConfig(model, tx)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving approval config", "pipelineId", pipelineId, "err", err)
+		return err
+	}
+	return nil
+}
+
+func (impl DeploymentApprovalServiceImpl) GetApprovalConfig(pipelineId int) (*bean.DeploymentApprovalConfig, error) {
+	config, err := impl.deploymentApprovalRepository.FindConfigByPipelineId(pipelineId)
+	if err != nil {
+		if util.IsErrNoRows(err) {
+			return nil, nil
+		}
+		impl.logger.Errorw("error in fetching approval config", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	return &bean.DeploymentApprovalConfig{
+		RequiredCount:  config.RequiredCount,
+		ApproverEmails: config.ApproverEmails,
+		ApproverGroups: config.ApproverGroups,
+	}, nil
+}
+
+// CheckApproved returns true if the pipeline has no approval gate or the artifact has been approved for it
+func (impl DeploymentApprovalServiceImpl) CheckApproved(pipelineId int, ciArtifactId int) (bool, error) {
+	config, err := impl.GetApprovalConfig(pipelineId)
+	if err != nil {
+		return false, err
+	}
+	if config == nil {
+		return true, nil
+	}
+	request, err := impl.deploymentApprovalRepository.FindActiveRequest(pipelineId, ciArtifactId)
+	if err != nil {
+		if util.IsErrNoRows(err) {
+			return false, nil
+		}
+		impl.logger.Errorw("error in fetching approval request", "pipelineId", pipelineId, "ciArtifactId", ciArtifactId, "err", err)
+		return false, err
+	}
+	return request.Status == pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED, nil
+}
+
+// RaiseApprovalRequest is idempotent, an already pending or approved request for the same artifact is returned as is
+func (impl DeploymentApprovalServiceImpl) RaiseApprovalRequest(request *DeploymentApprovalTriggerRequest) (*DeploymentApprovalResponse, error) {
+	config, err := impl.deploymentApprovalRepository.FindConfigByPipelineId(request.PipelineId)
+	if err != nil {
+		if util.IsErrNoRows(err) {
+			return nil, &util.ApiError{
+				HttpStatusCode:  http.StatusBadRequest,
+				InternalMessage: "approval is not configured for this pipeline",
+				UserMessage:     "approval is not configured for this pipeline",
+			}
+		}
+		impl.logger.Errorw("error in fetching approval config", "pipelineId", request.PipelineId, "err", err)
+		return nil, err
+	}
+	existing, err := impl.deploymentApprovalRepository.FindActiveRequest(request.PipelineId, request.CiArtifactId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching approval request", "req", request, "err", err)
+		return nil, err
+	}
+	if existing.Id > 0 {
+		if existing.Status == pipelineConfig.DEPLOYMENT_APPROVAL_REQUESTED || existing.Status == pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED {
+			return impl.buildApprovalResponse(existing, config)
+		}
+		// rejected requests are closed so that the artifact can be requested again
+		existing.Active = false
+		existing.UpdatedOn = time.Now()
+		existing.UpdatedBy = request.UserId
+		err = impl.deploymentApprovalRepository.UpdateRequest(existing)
+		if err != nil {
+			impl.logger.Errorw("error in closing approval request", "id", existing.Id, "err", err)
+			return nil, err
+		}
+	}
+	pipeline, err := impl.pipelineRepository.FindById(request.PipelineId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching pipeline", "pipelineId", request.PipelineId, "err", err)
+		return nil, err
+	}
+	artifact, err := impl.ciArtifactRepository.Get(request.CiArtifactId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching artifact", "ciArtifactId", request.CiArtifactId, "err", err)
+		return nil, err
+	}
+	model := &pipelineConfig.DeploymentApprovalRequest{
+		PipelineId:   request.PipelineId,
+		CiArtifactId: request.CiArtifactId,
+		Status:       pipelineConfig.DEPLOYMENT_APPROVAL_REQUESTED,
+		Active:       true,
+		AuditLog:     sql.AuditLog{CreatedOn: time.Now(), CreatedBy: request.UserId, UpdatedOn: time.Now(), UpdatedBy: request.UserId},
+	}
+	err = impl.deploymentApprovalRepository.SaveRequest(model)
+	if err != nil {
+		impl.logger.Errorw("error in saving approval request", "req", request, "err", err)
+		return nil, err
+	}
+	impl.sendApprovalNotification(pipeline, artifact, config, request.UserId)
+	return impl.buildApprovalResponse(model, config)
+}
+
+func (impl DeploymentApprovalServiceImpl) SubmitApprovalAction(request *DeploymentApprovalActionRequest) (*DeploymentApprovalResponse, error) {
+	approvalRequest, err := impl.deploymentApprovalRepository.FindRequestById(request.ApprovalRequestId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching approval request", "id", request.ApprovalRequestId, "err", err)
+		return nil, err
+	}
+	if !approvalRequest.Active || approvalRequest.Status != pipelineConfig.DEPLOYMENT_APPROVAL_REQUESTED {
+		return nil, &util.ApiError{
+			HttpStatusCode:  http.StatusConflict,
+			InternalMessage: "approval request is not pending",
+			UserMessage:     fmt.Sprintf("approval request is already %s", strings.ToLower(string(approvalRequest.Status))),
+		}
+	}
+	config, err := impl.deploymentApprovalRepository.FindConfigByPipelineId(approvalRequest.PipelineId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching approval config", "pipelineId", approvalRequest.PipelineId, "err", err)
+		return nil, err
+	}
+	userInfo, err := impl.userService.GetById(request.UserId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching user", "userId", request.UserId, "err", err)
+		return nil, err
+	}
+	if request.Action == pipelineConfig.DEPLOYMENT_APPROVAL_USER_APPROVED && approvalRequest.CreatedBy == request.UserId {
+		return nil, &util.ApiError{
+			HttpStatusCode:  http.StatusForbidden,
+			InternalMessage: "user cannot approve their own deployment request",
+			UserMessage:     "user cannot approve their own deployment request",
+		}
+	}
+	if !isApprover(config, userInfo) {
+		return nil, &util.ApiError{
+			HttpStatusCode:  http.StatusForbidden,
+			InternalMessage: "user is not an approver for this pipeline",
+			UserMessage:     "user is not an approver for this pipeline",
+		}
+	}
+	userResponses, err := impl.deploymentApprovalRepository.FindUserDataByRequestId(approvalRequest.Id)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching approval user data", "id", approvalRequest.Id, "err", err)
+		return nil, err
+	}
+	for _, userResponse := range userResponses {
+		if userResponse.UserId == request.UserId {
+			return nil, &util.ApiError{
+				HttpStatusCode:  http.StatusConflict,
+				InternalMessage: "user has already responded to this approval request",
+				UserMessage:     "user has already responded to this approval request",
+			}
+		}
+	}
+	userData := &pipelineConfig.DeploymentApprovalUserData{
+		ApprovalRequestId: approvalRequest.Id,
+		UserId:            request.UserId,
+		UserResponse:      request.Action,
+		Comment:           request.Comment,
+		AuditLog:          sql.AuditLog{CreatedOn: time.Now(), CreatedBy: request.UserId, UpdatedOn: time.Now(), UpdatedBy: request.UserId},
+	}
+	err = impl.deploymentApprovalRepository.SaveUserData(userData)
+	if err != nil {
+		impl.logger.Errorw("error in saving approval user data", "req", request, "err", err)
+		return nil, err
+	}
+	userResponses = append(userResponses, userData)
+
+	// a single rejection closes the request, otherwise it is approved once the required count is met
+	approvedCount := 0
+	status := pipelineConfig.DEPLOYMENT_APPROVAL_REQUESTED
+	for _, userResponse := range userResponses {
+		if userResponse.UserResponse == pipelineConfig.DEPLOYMENT_APPROVAL_USER_REJECTED {
+			status = pipelineConfig.DEPLOYMENT_APPROVAL_REJECTED
+			break
+		}
+		approvedCount++
+	}
+	if status != pipelineConfig.DEPLOYMENT_APPROVAL_REJECTED && approvedCount >= config.RequiredCount {
+		status = pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED
+	}
+	if status != approvalRequest.Status {
+		approvalRequest.Status = status
+		approvalRequest.UpdatedOn = time.Now()
+		approvalRequest.UpdatedBy = request.UserId
+		err = impl.deploymentApprovalRepository.UpdateRequest(approvalRequest)
+		if err != nil {
+			impl.logger.Errorw("error in updating approval request", "id", approvalRequest.Id, "err", err)
+			return nil, err
+		}
+	}
+	return impl.buildApprovalResponse(approvalRequest, config)
+}
+
+func (impl DeploymentApprovalServiceImpl) FetchApprovalRequests(pipelineId int) ([]*DeploymentApprovalResponse, error) {
+	responses := make([]*DeploymentApprovalResponse, 0)
+	config, err := impl.deploymentApprovalRepository.FindConfigByPipelineId(pipelineId)
+	if err != nil {
+		if util.IsErrNoRows(err) {
+			return responses, nil
+		}
+		impl.logger.Errorw("error in fetching approval config", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	requests, err := impl.deploymentApprovalRepository.FindActiveRequestsByPipelineId(pipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching approval requests", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	for _, request := range requests {
+		response, err := impl.buildApprovalResponse(request, config)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+func (impl DeploymentApprovalServiceImpl) buildApprovalResponse(request *pipelineConfig.DeploymentApprovalRequest, config *pipelineConfig.DeploymentApprovalConfig) (*DeploymentApprovalResponse, error) {
+	userData, err := impl.deploymentApprovalRepository.FindUserDataByRequestId(request.Id)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching approval user data", "id", request.Id, "err", err)
+		return nil, err
+	}
+	userResponses := make([]*DeploymentApprovalUserResponseDto, 0)
+	for _, item := range userData {
+		dto := &DeploymentApprovalUserResponseDto{
+			UserId:       item.UserId,
+			UserResponse: item.UserResponse,
+			Comment:      item.Comment,
+			RespondedOn:  item.CreatedOn,
+		}
+		userInfo, err := impl.userService.GetByIdIncludeDeleted(item.UserId)
+		if err == nil && userInfo != nil {
+			dto.EmailId = userInfo.EmailId
+		}
+		userResponses = append(userResponses, dto)
+	}
+	return &DeploymentApprovalResponse{
+		Id:            request.Id,
+		PipelineId:    request.PipelineId,
+		CiArtifactId:  request.CiArtifactId,
+		Status:        request.Status,
+		RequiredCount: config.RequiredCount,
+		RequestedBy:   request.CreatedBy,
+		RequestedOn:   request.CreatedOn,
+		UserResponses: userResponses,
+	}, nil
+}
+
+func (impl DeploymentApprovalServiceImpl) sendApprovalNotification(pipeline *pipelineConfig.Pipeline, artifact *repository.CiArtifact, config *pipelineConfig.DeploymentApprovalConfig, userId int32) {
+	event := impl.eventFactory.Build(util2.Approval, &pipeline.Id, pipeline.AppId, &pipeline.EnvironmentId, util2.CD)
+	event.UserId = int(userId)
+	event.CiArtifactId = artifact.Id
+	event = impl.eventFactory.BuildExtraCDData(event, nil, 0, bean2.CD_WORKFLOW_TYPE_DEPLOY)
+	if event.Payload != nil {
+		event.Payload.DockerImageUrl = artifact.Image
+		event.Payload.Approvers = strings.Join(append(append([]string{}, config.ApproverEmails...), config.ApproverGroups...), ", ")
+	}
+	_, evtErr := impl.eventClient.WriteEvent(event)
+	if evtErr != nil {
+		impl.logger.Errorw("error in writing approval event", "pipelineId", pipeline.Id, "err", evtErr)
+	}
+}
+
+func isApprover(config *pipelineConfig.DeploymentApprovalConfig, userInfo *bean2.UserInfo) bool {
+	for _, email := range config.ApproverEmails {
+		if strings.EqualFold(email, userInfo.EmailId) {
+			return true
+		}
+	}
+	for _, approverGroup := range config.ApproverGroups {
+		for _, group := range userInfo.Groups {
+			if approverGroup == group {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/go-pg/pg"
+)
+
+// deploymentApprovalRepositoryStub keeps approval configs by pipeline, there are no approval requests
+type deploymentApprovalRepositoryStub struct {
+	pipelineConfig.DeploymentApprovalRepository
+	configs map[int]*pipelineConfig.DeploymentApprovalConfig
+}
+
+func (impl deploymentApprovalRepositoryStub) SaveConfig(config *pipelineConfig.DeploymentApprovalConfig, tx *pg.Tx) error {
+	config.Id = len(impl.configs) + 1
+	impl.configs[config.PipelineId] = config
+	return nil
+}
+
+func (impl deploymentApprovalRepositoryStub) UpdateConfig(config *pipelineConfig.DeploymentApprovalConfig, tx *pg.Tx) error {
+	impl.configs[config.PipelineId] = config
+	return nil
+}
+
+func (impl deploymentApprovalRepositoryStub) FindConfigByPipelineId(pipelineId int) (*pipelineConfig.DeploymentApprovalConfig, error) {
+	config, ok := impl.configs[pipelineId]
+	if !ok || !config.Active {
+		return &pipelineConfig.DeploymentApprovalConfig{}, pg.ErrNoRows
+	}
+	copied := *config
+	return &copied, nil
+}
+
+func (impl deploymentApprovalRepositoryStub) FindActiveRequest(pipelineId int, ciArtifactId int) (*pipelineConfig.DeploymentApprovalRequest, error) {
+	return nil, pg.ErrNoRows
+}
+
+func TestSaveApprovalConfig(t *testing.T) {
+	gate := &bean.DeploymentApprovalConfig{RequiredCount: 2, ApproverEmails: []string{"lead@example.com"}}
+	tests := []struct {
+		name         string
+		existing     *bean.DeploymentApprovalConfig
+		config       *bean.DeploymentApprovalConfig
+		wantErr      bool
+		wantApproved bool
+	}{
+		{name: "gate is created", config: gate, wantApproved: false},
+		{name: "zero required approvals clears the gate", existing: gate, config: &bean.DeploymentApprovalConfig{RequiredCount: 0}, wantApproved: true},
+		{name: "nil config clears the gate", existing: gate, wantApproved: true},
+		{name: "zero required approvals without a gate", config: &bean.DeploymentApprovalConfig{RequiredCount: 0}, wantApproved: true},
+		{name: "negative required approvals", existing: gate, config: &bean.DeploymentApprovalConfig{RequiredCount: -1, ApproverEmails: []string{"lead@example.com"}}, wantErr: true},
+		{name: "gate without approvers", config: &bean.DeploymentApprovalConfig{RequiredCount: 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl := DeploymentApprovalServiceImpl{
+				logger:                       util.NewSugardLogger(),
+				deploymentApprovalRepository: deploymentApprovalRepositoryStub{configs: map[int]*pipelineConfig.DeploymentApprovalConfig{}},
+			}
+			if tt.existing != nil {
+				if err := impl.SaveApprovalConfig(1, tt.existing, 1, nil); err != nil {
+					t.Fatalf("SaveApprovalConfig() of existing gate error = %v", err)
+				}
+			}
+			err := impl.SaveApprovalConfig(1, tt.config, 1, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("SaveApprovalConfig() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			approved, err := impl.CheckApproved(1, 10)
+			if err != nil {
+				t.Fatalf("CheckApproved() error = %v", err)
+			}
+			if approved != tt.wantApproved {
+				t.Errorf("CheckApproved() = %v, want %v", approved, tt.wantApproved)
+			}
+		})
+	}
+}
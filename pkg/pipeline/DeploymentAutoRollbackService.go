@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	bean2 "github.com/devtron-labs/devtron/api/bean"
+	client "github.com/devtron-labs/devtron/client/events"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/chartConfig"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	util2 "github.com/devtron-labs/devtron/util/event"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const autoRollbackEvaluationCronExpr = "@every 1m"
+
+const autoRollbackJobName = "deployment-auto-rollback"
+
+type DeploymentAutoRollbackDto struct {
+	Id                         int                                         `json:"id"`
+	PipelineId                 int                                         `json:"pipelineId"`
+	PipelineOverrideId         int                                         `json:"pipelineOverrideId"`
+	CiArtifactId               int                                         `json:"ciArtifactId"`
+	Status                     pipelineConfig.DeploymentAutoRollbackStatus `json:"status"`
+	DegradedSince              time.Time                                   `json:"degradedSince"`
+	RollbackCiArtifactId       int                                         `json:"rollbackCiArtifactId,omitempty"`
+	RollbackCdWorkflowRunnerId int                                         `json:"rollbackCdWorkflowRunnerId,omitempty"`
+	Message                    string                                      `json:"message,omitempty"`
+}
+
+type DeploymentAutoRollbackService interface {
+	SaveAutoRollbackConfig(pipelineId int, config *bean.DeploymentAutoRollbackConfig, userId int32, tx *pg.Tx) error
+	GetAutoRollbackConfig(pipelineId int) (*bean.DeploymentAutoRollbackConfig, error)
+	GetAutoRollbackHistory(pipelineId int, offset int, limit int) ([]*DeploymentAutoRollbackDto, error)
+	RollbackDegradedReleases()
+}
+
+type DeploymentAutoRollbackServiceImpl struct {
+	logger                           *zap.SugaredLogger
+	deploymentAutoRollbackRepository pipelineConfig.DeploymentAutoRollbackRepository
+	pipelineRepository               pipelineConfig.PipelineRepository
+	pipelineOverrideRepository       chartConfig.PipelineOverrideRepository
+	ciArtifactRepository             repository.CiArtifactRepository
+	workflowDagExecutor              WorkflowDagExecutor
+	eventClient                      client.EventClient
+	eventFactory                     client.EventFactory
+}
+
+func NewDeploymentAutoRollbackServiceImpl(logger *zap.SugaredLogger,
+	deploymentAutoRollbackRepository pipelineConfig.DeploymentAutoRollbackRepository,
+	pipelineRepository pipelineConfig.PipelineRepository,
+	pipelineOverrideRepository chartConfig.PipelineOverrideRepository,
+	ciArtifactRepository repository.CiArtifactRepository,
+	workflowDagExecutor WorkflowDagExecutor,
+	eventClient client.EventClient,
+	eventFactory client.EventFactory, scheduledJobRunner util3.ScheduledJobRunner) (*DeploymentAutoRollbackServiceImpl, error) {
+	impl := &DeploymentAutoRollbackServiceImpl{
+		logger:                           logger,
+		deploymentAutoRollbackRepository: deploymentAutoRollbackRepository,
+		pipelineRepository:               pipelineRepository,
+		pipelineOverrideRepository:       pipelineOverrideRepository,
+		ciArtifactRepository:             ciArtifactRepository,
+		workflowDagExecutor:              workflowDagExecutor,
+		eventClient:                      eventClient,
+		eventFactory:                     eventFactory,
+	}
+	err := scheduledJobRunner.Schedule(autoRollbackJobName, autoRollbackEvaluationCronExpr, impl.RollbackDegradedReleases)
+	if err != nil {
+		logger.Errorw("error in starting auto rollback evaluation", "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+// SaveAutoRollbackConfig creates, updates or (when config is nil or has no degraded timeout) deactivates auto rollback
+// of a cd pipeline
+func (impl DeploymentAutoRollbackServiceImpl) SaveAutoRollbackConfig(pipelineId int, config *bean.DeploymentAutoRollbackConfig, userId int32, tx *pg.Tx) error {
+	existing, err := impl.deploymentAutoRollbackRepository.FindConfigByPipelineId(pipelineId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching auto rollback config", "pipelineId", pipelineId, "err", err)
+		return err
+	}
+	if config == nil || config.DegradedTimeoutMinutes == 0 {
+		if existing.Id > 0 {
+			existing.Active = false
+			existing.UpdatedOn = time.Now()
+			existing.UpdatedBy = userId
+			err = impl.deploymentAutoRollbackRepository.UpdateConfig(existing, tx)
+			if err != nil {
+				impl.logger.Errorw("error in deactivating auto rollback config", "pipelineId", pipelineId, "err", err)
+				return err
+			}
+		}
+		return nil
+	}
+	if config.DegradedTimeoutMinutes < 0 {
+		return &util.ApiError{
+			HttpStatusCode:  http.StatusBadRequest,
+			InternalMessage: "auto rollback degraded timeout must not be negative",
+			UserMessage:     "auto rollback degraded timeout must not be negative, zero turns auto rollback off",
+		}
+	}
+	if existing.Id > 0 {
+		existing.DegradedTimeoutMinutes = config.DegradedTimeoutMinutes
+		existing.UpdatedOn = time.Now()
+		existing.UpdatedBy = userId
+		err = impl.deploymentAutoRollbackRepository.UpdateConfig(existing, tx)
+	} else {
+		model := &pipelineConfig.DeploymentAutoRollbackConfig{
+			PipelineId:             pipelineId,
+			DegradedTimeoutMinutes: config.DegradedTimeoutMinutes,
+			Active:                 true,
+			AuditLog:               sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+		}
+		err = impl.deploymentAutoRollbackRepository.SaveConfig(model, tx)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving auto rollback config", "pipelineId", pipelineId, "err", err)
+		return err
+	}
+	return nil
+}
+
+func (impl DeploymentAutoRollbackServiceImpl) GetAutoRollbackConfig(pipelineId int) (*bean.DeploymentAutoRollbackConfig, error) {
+	config, err := impl.deploymentAutoRollbackRepository.FindConfigByPipelineId(pipelineId)
+	if err != nil {
+		if util.IsErrNoRows(err) {
+			return nil, nil
+		}
+		impl.logger.Errorw("error in fetching auto rollback config", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	return &bean.DeploymentAutoRollbackConfig{DegradedTimeoutMinutes: config.DegradedTimeoutMinutes}, nil
+}
+
+func (impl DeploymentAutoRollbackServiceImpl) GetAutoRollbackHistory(pipelineId int, offset int, limit int) ([]*DeploymentAutoRollbackDto, error) {
+	rollbacks, err := impl.deploymentAutoRollbackRepository.FindByPipelineId(pipelineId, offset, limit)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching auto rollback history", "pipelineId", pipelineId, "err", err)
+		return nil, err
+	}
+	history := make([]*DeploymentAutoRollbackDto, 0, len(rollbacks))
+	for _, rollback := range rollbacks {
+		history = append(history, &DeploymentAutoRollbackDto{
+			Id:                         rollback.Id,
+			PipelineId:                 rollback.PipelineId,
+			PipelineOverrideId:         rollback.PipelineOverrideId,
+			CiArtifactId:               rollback.CiArtifactId,
+			Status:                     rollback.Status,
+			DegradedSince:              rollback.DegradedSince,
+			RollbackCiArtifactId:       rollback.RollbackCiArtifactId,
+			RollbackCdWorkflowRunnerId: rollback.RollbackCdWorkflowRunnerId,
+			Message:                    rollback.Message,
+		})
+	}
+	return history, nil
+}
+
+// RollbackDegradedReleases rolls back every tracked release which stayed Degraded beyond the timeout of its pipeline
+func (impl DeploymentAutoRollbackServiceImpl) RollbackDegradedReleases() {
+	degradedReleases, err := impl.deploymentAutoRollbackRepository.FindByStatus(pipelineConfig.AUTO_ROLLBACK_DEGRADED)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching degraded releases", "err", err)
+		return
+	}
+	for _, degradedRelease := range degradedReleases {
+		err = impl.rollbackIfTimedOut(degradedRelease)
+		if err != nil {
+			impl.logger.Errorw("error in auto rollback of degraded release", "pipelineOverrideId", degradedRelease.PipelineOverrideId, "err", err)
+		}
+	}
+}
+
+func (impl DeploymentAutoRollbackServiceImpl) rollbackIfTimedOut(degradedRelease *pipelineConfig.DeploymentAutoRollback) error {
+	config, err := impl.deploymentAutoRollbackRepository.FindConfigByPipelineId(degradedRelease.PipelineId)
+	if util.IsErrNoRows(err) {
+		return impl.closeDegradedRelease(degradedRelease, pipelineConfig.AUTO_ROLLBACK_RECOVERED, "auto rollback disabled on pipeline")
+	} else if err != nil {
+		return err
+	}
+	if time.Since(degradedRelease.DegradedSince) < time.Duration(config.DegradedTimeoutMinutes)*time.Minute {
+		return nil
+	}
+
+	pipelineOverride, err := impl.pipelineOverrideRepository.FindById(degradedRelease.PipelineOverrideId)
+	if err != nil {
+		return err
+	}
+	releaseCounter, err := impl.pipelineOverrideRepository.GetCurrentPipelineReleaseCounter(degradedRelease.PipelineId)
+	if err != nil {
+		return err
+	}
+	if pipelineOverride.PipelineReleaseCounter != releaseCounter {
+		return impl.closeDegradedRelease(degradedRelease, pipelineConfig.AUTO_ROLLBACK_RECOVERED, "newer release deployed")
+	}
+
+	healthyRelease, err := impl.pipelineOverrideRepository.FindLastHealthyRelease(degradedRelease.PipelineId, pipelineOverride.PipelineReleaseCounter, degradedRelease.CiArtifactId)
+	if util.IsErrNoRows(err) {
+		return impl.closeDegradedRelease(degradedRelease, pipelineConfig.AUTO_ROLLBACK_FAILED, "no earlier healthy release found to roll back to")
+	} else if err != nil {
+		return err
+	}
+	pipeline, err := impl.pipelineRepository.FindById(degradedRelease.PipelineId)
+	if err != nil {
+		return err
+	}
+	artifact, err := impl.ciArtifactRepository.Get(healthyRelease.CiArtifactId)
+	if err != nil {
+		return err
+	}
+
+	reason := fmt.Sprintf("auto rollback: release %d stayed Degraded for more than %d minutes", pipelineOverride.PipelineReleaseCounter, config.DegradedTimeoutMinutes)
+	impl.logger.Infow("rolling back degraded release", "pipelineId", pipeline.Id, "pipelineOverrideId", pipelineOverride.Id, "rollbackCiArtifactId", artifact.Id)
+	runnerId, err := impl.workflowDagExecutor.TriggerAutoRollback(pipeline, artifact, healthyRelease.Id, reason)
+	degradedRelease.RollbackCiArtifactId = artifact.Id
+	degradedRelease.RollbackCdWorkflowRunnerId = runnerId
+	if err != nil {
+		return impl.closeDegradedRelease(degradedRelease, pipelineConfig.AUTO_ROLLBACK_FAILED, err.Error())
+	}
+	err = impl.closeDegradedRelease(degradedRelease, pipelineConfig.AUTO_ROLLBACK_ROLLED_BACK, reason)
+	if err != nil {
+		return err
+	}
+	impl.sendAutoRollbackNotification(pipeline, artifact, reason)
+	return nil
+}
+
+func (impl DeploymentAutoRollbackServiceImpl) closeDegradedRelease(degradedRelease *pipelineConfig.DeploymentAutoRollback, status pipelineConfig.DeploymentAutoRollbackStatus, message string) error {
+	degradedRelease.Status = status
+	degradedRelease.Message = message
+	degradedRelease.UpdatedOn = time.Now()
+	degradedRelease.UpdatedBy = 1
+	err := impl.deploymentAutoRollbackRepository.Update(degradedRelease)
+	if err != nil {
+		impl.logger.Errorw("error in updating auto rollback", "id", degradedRelease.Id, "status", status, "err", err)
+	}
+	return err
+}
+
+func (impl DeploymentAutoRollbackServiceImpl) sendAutoRollbackNotification(pipeline *pipelineConfig.Pipeline, artifact *repository.CiArtifact, reason string) {
+	event := impl.eventFactory.Build(util2.AutoRollback, &pipeline.Id, pipeline.AppId, &pipeline.EnvironmentId, util2.CD)
+	event.UserId = 1
+	event.CiArtifactId = artifact.Id
+	event = impl.eventFactory.BuildExtraCDData(event, nil, 0, bean2.CD_WORKFLOW_TYPE_DEPLOY)
+	if event.Payload != nil {
+		event.Payload.DockerImageUrl = artifact.Image
+		event.Payload.RollbackReason = reason
+	}
+	_, evtErr := impl.eventClient.WriteEvent(event)
+	if evtErr != nil {
+		impl.logger.Errorw("error in writing auto rollback event", "pipelineId", pipeline.Id, "err", evtErr)
+	}
+}
@@ -0,0 +1,377 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	application2 "github.com/argoproj/argo-cd/pkg/apiclient/application"
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/client/argocdServer/application"
+	"github.com/devtron-labs/devtron/internal/sql/repository/chartConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/app"
+	"github.com/ghodss/yaml"
+	"github.com/go-pg/pg"
+	"github.com/pmezard/go-difflib/difflib"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	DryRunCompareLive         = "LIVE"
+	DryRunCompareLastDeployed = "LAST_DEPLOYED"
+
+	ResourceAdded     = "ADDED"
+	ResourceRemoved   = "REMOVED"
+	ResourceModified  = "MODIFIED"
+	ResourceUnchanged = "UNCHANGED"
+)
+
+// metadata fields set by the api server or argocd, never part of a rendered manifest
+var noisyMetadataFields = []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp", "selfLink",
+	"deletionTimestamp", "deletionGracePeriodSeconds", "ownerReferences"}
+
+var noisyAnnotations = []string{"kubectl.kubernetes.io/last-applied-configuration", "deployment.kubernetes.io/revision"}
+
+// argocd sets the instance label on every resource it manages
+var noisyLabels = []string{"app.kubernetes.io/instance"}
+
+type DeploymentDryRunResponse struct {
+	CompareWith    string                `json:"compareWith"`
+	ReleaseCounter int                   `json:"releaseCounter"`
+	Resources      []*ResourceDryRunDiff `json:"resources"`
+}
+
+type ResourceDryRunDiff struct {
+	Group      string `json:"group,omitempty"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	ChangeType string `json:"changeType"`
+	// Diff is a unified diff of the yaml of the resource, secret values are replaced by their checksum
+	Diff string `json:"diff,omitempty"`
+}
+
+type DeploymentDryRunService interface {
+	// DryRun renders the release a trigger of the request would create and compares it with live state or the last release
+	DryRun(overrideRequest *bean.ValuesOverrideRequest, compareWith string, ctx context.Context) (*DeploymentDryRunResponse, error)
+}
+
+type DeploymentDryRunServiceImpl struct {
+	logger                      *zap.SugaredLogger
+	appService                  app.AppService
+	acdClient                   application.ServiceClient
+	pipelineOverrideRepository  chartConfig.PipelineOverrideRepository
+	envConfigOverrideRepository chartConfig.EnvConfigOverrideRepository
+	chartTemplateRenderer       app.ChartTemplateRenderer
+}
+
+func NewDeploymentDryRunServiceImpl(logger *zap.SugaredLogger, appService app.AppService, acdClient application.ServiceClient,
+	pipelineOverrideRepository chartConfig.PipelineOverrideRepository, envConfigOverrideRepository chartConfig.EnvConfigOverrideRepository,
+	chartTemplateRenderer app.ChartTemplateRenderer) *DeploymentDryRunServiceImpl {
+	return &DeploymentDryRunServiceImpl{
+		logger:                      logger,
+		appService:                  appService,
+		acdClient:                   acdClient,
+		pipelineOverrideRepository:  pipelineOverrideRepository,
+		envConfigOverrideRepository: envConfigOverrideRepository,
+		chartTemplateRenderer:       chartTemplateRenderer,
+	}
+}
+
+type renderedResource struct {
+	group     string
+	kind      string
+	namespace string
+	name      string
+	object    map[string]interface{}
+}
+
+func (r *renderedResource) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", r.group, r.kind, r.namespace, r.name)
+}
+
+func (impl DeploymentDryRunServiceImpl) DryRun(overrideRequest *bean.ValuesOverrideRequest, compareWith string, ctx context.Context) (*DeploymentDryRunResponse, error) {
+	if len(compareWith) == 0 {
+		compareWith = DryRunCompareLive
+	} else if compareWith != DryRunCompareLive && compareWith != DryRunCompareLastDeployed {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("invalid compareWith %s, supported values are %s and %s", compareWith, DryRunCompareLive, DryRunCompareLastDeployed)}
+	}
+	values, err := impl.appService.GetDeploymentValues(overrideRequest, ctx)
+	if err != nil {
+		impl.logger.Errorw("error in computing deployment values", "pipelineId", overrideRequest.PipelineId, "err", err)
+		return nil, err
+	}
+	releaseName := fmt.Sprintf("%s-%s", values.Pipeline.App.AppName, values.EnvOverride.Environment.Name)
+	namespace := values.EnvOverride.Namespace
+	desired, err := impl.render(values.EnvOverride.Chart.ChartRefId, releaseName, namespace, values.MergedValues)
+	if err != nil {
+		impl.logger.Errorw("error in rendering chart", "pipelineId", overrideRequest.PipelineId, "err", err)
+		return nil, err
+	}
+
+	var current map[string]*renderedResource
+	if compareWith == DryRunCompareLive {
+		current, err = impl.getLiveResources(releaseName, ctx)
+	} else {
+		current, err = impl.getLastDeployedResources(values, releaseName, namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DeploymentDryRunResponse{
+		CompareWith:    compareWith,
+		ReleaseCounter: values.ReleaseCounter,
+		Resources:      diffResources(desired, current, namespace, compareWith == DryRunCompareLive),
+	}, nil
+}
+
+// render loads the reference chart of the release and renders it locally, the chart pushed to git differs only in name and version
+func (impl DeploymentDryRunServiceImpl) render(chartRefId int, releaseName string, namespace string, values []byte) (map[string]*renderedResource, error) {
+	objects, err := impl.chartTemplateRenderer.Render(chartRefId, releaseName, namespace, values)
+	if err != nil {
+		return nil, err
+	}
+	resources := make(map[string]*renderedResource)
+	for _, object := range objects {
+		resource := newRenderedResource(object)
+		if resource == nil {
+			continue
+		}
+		resources[resource.key()] = resource
+	}
+	return resources, nil
+}
+
+func (impl DeploymentDryRunServiceImpl) getLiveResources(releaseName string, ctx context.Context) (map[string]*renderedResource, error) {
+	resp, err := impl.acdClient.ManagedResources(ctx, &application2.ResourcesQuery{ApplicationName: &releaseName})
+	if err != nil {
+		impl.logger.Errorw("error in fetching managed resources", "application", releaseName, "err", err)
+		return nil, err
+	}
+	resources := make(map[string]*renderedResource)
+	for _, item := range resp.Items {
+		if item.Hook || len(item.LiveState) == 0 || item.LiveState == "null" {
+			continue
+		}
+		object := make(map[string]interface{})
+		err = json.Unmarshal([]byte(item.LiveState), &object)
+		if err != nil {
+			impl.logger.Errorw("error in parsing live state", "kind", item.Kind, "name", item.Name, "err", err)
+			return nil, err
+		}
+		resources[fmt.Sprintf("%s/%s/%s/%s", item.Group, item.Kind, item.Namespace, item.Name)] = &renderedResource{
+			group:     item.Group,
+			kind:      item.Kind,
+			namespace: item.Namespace,
+			name:      item.Name,
+			object:    object,
+		}
+	}
+	return resources, nil
+}
+
+func (impl DeploymentDryRunServiceImpl) getLastDeployedResources(values *app.DeploymentValues, releaseName string, namespace string) (map[string]*renderedResource, error) {
+	lastRelease, err := impl.pipelineOverrideRepository.GetLatestRelease(values.Pipeline.AppId, values.Pipeline.EnvironmentId)
+	if err == pg.ErrNoRows || (err == nil && len(lastRelease.PipelineMergedValues) == 0) {
+		return map[string]*renderedResource{}, nil
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching last release", "pipelineId", values.Pipeline.Id, "err", err)
+		return nil, err
+	}
+	envOverride, err := impl.envConfigOverrideRepository.Get(lastRelease.EnvConfigOverrideId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching env config of last release", "envConfigOverrideId", lastRelease.EnvConfigOverrideId, "err", err)
+		return nil, err
+	}
+	return impl.render(envOverride.Chart.ChartRefId, releaseName, namespace, []byte(lastRelease.PipelineMergedValues))
+}
+
+func newRenderedResource(object map[string]interface{}) *renderedResource {
+	kind, _ := object["kind"].(string)
+	apiVersion, _ := object["apiVersion"].(string)
+	metadata, _ := object["metadata"].(map[string]interface{})
+	if len(kind) == 0 || metadata == nil {
+		return nil
+	}
+	resource := &renderedResource{kind: kind, object: object}
+	if i := strings.Index(apiVersion, "/"); i > 0 {
+		resource.group = apiVersion[:i]
+	}
+	resource.name, _ = metadata["name"].(string)
+	resource.namespace, _ = metadata["namespace"].(string)
+	return resource
+}
+
+// diffResources compares desired resources with the current ones, resources without namespace in the chart
+// are matched in the release namespace or as cluster scoped resources
+func diffResources(desired map[string]*renderedResource, current map[string]*renderedResource, namespace string, prune bool) []*ResourceDryRunDiff {
+	diffs := make([]*ResourceDryRunDiff, 0)
+	matched := make(map[string]bool)
+	fromFile := "last-deployed"
+	if prune {
+		fromFile = "live"
+	}
+	for _, resource := range desired {
+		if _, ok := current[resource.key()]; !ok && len(resource.namespace) == 0 {
+			resource.namespace = namespace
+		}
+		diff := &ResourceDryRunDiff{Group: resource.group, Kind: resource.kind, Namespace: resource.namespace, Name: resource.name}
+		desiredYaml := toComparableYaml(resource.object, nil)
+		existing, ok := current[resource.key()]
+		if !ok {
+			diff.ChangeType = ResourceAdded
+			diff.Diff = unifiedDiff("", desiredYaml, fromFile)
+		} else {
+			matched[resource.key()] = true
+			var shape map[string]interface{}
+			if prune {
+				// live objects carry every field defaulted by the api server, only fields set by the chart are compared
+				shape = resource.object
+			}
+			existingYaml := toComparableYaml(existing.object, shape)
+			if existingYaml == desiredYaml {
+				diff.ChangeType = ResourceUnchanged
+			} else {
+				diff.ChangeType = ResourceModified
+				diff.Diff = unifiedDiff(existingYaml, desiredYaml, fromFile)
+			}
+		}
+		diffs = append(diffs, diff)
+	}
+	for key, resource := range current {
+		if matched[key] {
+			continue
+		}
+		diffs = append(diffs, &ResourceDryRunDiff{
+			Group:      resource.group,
+			Kind:       resource.kind,
+			Namespace:  resource.namespace,
+			Name:       resource.name,
+			ChangeType: ResourceRemoved,
+			Diff:       unifiedDiff(toComparableYaml(resource.object, nil), "", fromFile),
+		})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Kind != diffs[j].Kind {
+			return diffs[i].Kind < diffs[j].Kind
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+	return diffs
+}
+
+// toComparableYaml drops noisy fields and secret values, when shape is given only fields present in shape are kept
+func toComparableYaml(object map[string]interface{}, shape map[string]interface{}) string {
+	cleaned := copyMap(object)
+	delete(cleaned, "status")
+	if metadata, ok := cleaned["metadata"].(map[string]interface{}); ok {
+		for _, field := range noisyMetadataFields {
+			delete(metadata, field)
+		}
+		removeKeys(metadata, "annotations", noisyAnnotations)
+		removeKeys(metadata, "labels", noisyLabels)
+	}
+	if cleaned["kind"] == "Secret" {
+		for _, field := range []string{"data", "stringData"} {
+			if data, ok := cleaned[field].(map[string]interface{}); ok {
+				for key, value := range data {
+					data[key] = fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(fmt.Sprintf("%v", value))))[:19]
+				}
+			}
+		}
+	}
+	var comparable interface{} = cleaned
+	if shape != nil {
+		comparable = pruneToShape(cleaned, shape)
+	}
+	out, err := yaml.Marshal(comparable)
+	if err != nil {
+		return fmt.Sprintf("%v", comparable)
+	}
+	return string(out)
+}
+
+func removeKeys(metadata map[string]interface{}, field string, keys []string) {
+	values, ok := metadata[field].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for _, key := range keys {
+		delete(values, key)
+	}
+	if len(values) == 0 {
+		delete(metadata, field)
+	}
+}
+
+func pruneToShape(value interface{}, shape interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		shapeMap, ok := shape.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		pruned := make(map[string]interface{})
+		for key, child := range v {
+			if shapeChild, ok := shapeMap[key]; ok {
+				pruned[key] = pruneToShape(child, shapeChild)
+			}
+		}
+		return pruned
+	case []interface{}:
+		shapeList, ok := shape.([]interface{})
+		if !ok {
+			return v
+		}
+		pruned := make([]interface{}, 0)
+		for i, child := range v {
+			if i < len(shapeList) {
+				pruned = append(pruned, pruneToShape(child, shapeList[i]))
+			} else {
+				pruned = append(pruned, child)
+			}
+		}
+		return pruned
+	default:
+		return v
+	}
+}
+
+func copyMap(object map[string]interface{}) map[string]interface{} {
+	out, _ := json.Marshal(object)
+	copied := make(map[string]interface{})
+	_ = json.Unmarshal(out, &copied)
+	return copied
+}
+
+func unifiedDiff(from string, to string, fromFile string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(from),
+		B:        difflib.SplitLines(to),
+		FromFile: fromFile,
+		ToFile:   "desired",
+		Context:  3,
+	})
+	return diff
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"strings"
+	"testing"
+)
+
+func secretManifest(field string, data map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata":   map[string]interface{}{"name": "app-secret", "namespace": "prod"},
+		field:        data,
+	}
+}
+
+func TestDryRunSecretMasking(t *testing.T) {
+	tests := []struct {
+		name           string
+		desired        map[string]interface{}
+		current        map[string]interface{}
+		wantChangeType string
+		secrets        []string
+	}{
+		{
+			name:           "added secret",
+			desired:        secretManifest("data", map[string]interface{}{"password": "czNjcjN0LW9sZA=="}),
+			wantChangeType: ResourceAdded,
+			secrets:        []string{"czNjcjN0LW9sZA=="},
+		},
+		{
+			name:           "changed value is shown as a changed hash",
+			desired:        secretManifest("data", map[string]interface{}{"password": "czNjcjN0LW5ldw=="}),
+			current:        secretManifest("data", map[string]interface{}{"password": "czNjcjN0LW9sZA=="}),
+			wantChangeType: ResourceModified,
+			secrets:        []string{"czNjcjN0LW5ldw==", "czNjcjN0LW9sZA=="},
+		},
+		{
+			name:           "string data is masked",
+			desired:        secretManifest("stringData", map[string]interface{}{"url": "postgres://user:s3cr3t@db"}),
+			current:        secretManifest("stringData", map[string]interface{}{"url": "postgres://user:old@db"}),
+			wantChangeType: ResourceModified,
+			secrets:        []string{"s3cr3t", "user:old"},
+		},
+		{
+			name:           "same value is unchanged",
+			desired:        secretManifest("data", map[string]interface{}{"password": "czNjcjN0LW9sZA=="}),
+			current:        secretManifest("data", map[string]interface{}{"password": "czNjcjN0LW9sZA=="}),
+			wantChangeType: ResourceUnchanged,
+		},
+		{
+			name:           "removed secret",
+			current:        secretManifest("data", map[string]interface{}{"password": "czNjcjN0LW9sZA=="}),
+			wantChangeType: ResourceRemoved,
+			secrets:        []string{"czNjcjN0LW9sZA=="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desired := map[string]*renderedResource{}
+			if tt.desired != nil {
+				resource := newRenderedResource(tt.desired)
+				desired[resource.key()] = resource
+			}
+			current := map[string]*renderedResource{}
+			if tt.current != nil {
+				resource := newRenderedResource(tt.current)
+				current[resource.key()] = resource
+			}
+			diffs := diffResources(desired, current, "prod", false)
+			if len(diffs) != 1 {
+				t.Fatalf("diffResources() = %d diffs, want 1", len(diffs))
+			}
+			diff := diffs[0]
+			if diff.ChangeType != tt.wantChangeType {
+				t.Errorf("diffResources() change = %s, want %s", diff.ChangeType, tt.wantChangeType)
+			}
+			if tt.wantChangeType != ResourceUnchanged && !strings.Contains(diff.Diff, "sha256:") {
+				t.Errorf("diffResources() diff does not show the value hash:\n%s", diff.Diff)
+			}
+			for _, secret := range tt.secrets {
+				if strings.Contains(diff.Diff, secret) {
+					t.Errorf("diffResources() diff exposes %s:\n%s", secret, diff.Diff)
+				}
+			}
+			for _, object := range []map[string]interface{}{tt.desired, tt.current} {
+				if object == nil {
+					continue
+				}
+				for _, field := range []string{"data", "stringData"} {
+					if data, ok := object[field].(map[string]interface{}); ok {
+						for _, value := range data {
+							if strings.HasPrefix(value.(string), "sha256:") {
+								t.Errorf("diffResources() masked the rendered object in place")
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestToComparableYamlKeepsOtherKinds(t *testing.T) {
+	configMap := map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "app-cm"},
+		"data":       map[string]interface{}{"LOG_LEVEL": "debug"},
+	}
+	if out := toComparableYaml(configMap, nil); !strings.Contains(out, "LOG_LEVEL: debug") {
+		t.Errorf("toComparableYaml() = %s, want config map data in clear", out)
+	}
+}
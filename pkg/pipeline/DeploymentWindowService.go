@@ -0,0 +1,331 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+type DeploymentWindowDto struct {
+	Id              int                                 `json:"id"`
+	Name            string                              `json:"name" validate:"required,max=250"`
+	EnvironmentId   int                                 `json:"environmentId" validate:"number,required"`
+	AppId           int                                 `json:"appId"` //0 applies the window to every app of the environment
+	WindowType      pipelineConfig.DeploymentWindowType `json:"windowType" validate:"oneof=ALLOW DENY"`
+	CronExpression  string                              `json:"cronExpression,omitempty"` //standard 5 field cron, marks the start of every window
+	DurationMinutes int                                 `json:"durationMinutes,omitempty"`
+	StartTime       *time.Time                          `json:"startTime,omitempty"` //one time window (e.g. release freeze) used when cron is not set
+	EndTime         *time.Time                          `json:"endTime,omitempty"`
+	TimeZone        string                              `json:"timeZone"`
+	UserId          int32                               `json:"-"`
+}
+
+type DeploymentWindowCheckResult struct {
+	Allowed  bool   `json:"allowed"`
+	WindowId int    `json:"windowId,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+type DeploymentWindowOverrideAuditDto struct {
+	Id                 int       `json:"id"`
+	DeploymentWindowId int       `json:"deploymentWindowId"`
+	EnvironmentId      int       `json:"environmentId"`
+	PipelineId         int       `json:"pipelineId"`
+	CiArtifactId       int       `json:"ciArtifactId"`
+	Reason             string    `json:"reason"`
+	WindowMessage      string    `json:"windowMessage"`
+	OverriddenBy       int32     `json:"overriddenBy"`
+	OverriddenOn       time.Time `json:"overriddenOn"`
+}
+
+type DeploymentWindowService interface {
+	CreateWindow(request *DeploymentWindowDto) (*DeploymentWindowDto, error)
+	UpdateWindow(request *DeploymentWindowDto) (*DeploymentWindowDto, error)
+	DeleteWindow(id int, userId int32) error
+	GetWindowById(id int) (*DeploymentWindowDto, error)
+	GetWindowsByEnvironmentId(environmentId int) ([]*DeploymentWindowDto, error)
+	CheckDeploymentAllowed(appId int, environmentId int, at time.Time) (*DeploymentWindowCheckResult, error)
+	SaveOverrideAudit(pipeline *pipelineConfig.Pipeline, ciArtifactId int, result *DeploymentWindowCheckResult, userId int32, reason string) error
+	GetOverrideAuditByEnvironmentId(environmentId int) ([]*DeploymentWindowOverrideAuditDto, error)
+}
+
+type DeploymentWindowServiceImpl struct {
+	logger                     *zap.SugaredLogger
+	deploymentWindowRepository pipelineConfig.DeploymentWindowRepository
+}
+
+func NewDeploymentWindowServiceImpl(logger *zap.SugaredLogger,
+	deploymentWindowRepository pipelineConfig.DeploymentWindowRepository) *DeploymentWindowServiceImpl {
+	return &DeploymentWindowServiceImpl{
+		logger:                     logger,
+		deploymentWindowRepository: deploymentWindowRepository,
+	}
+}
+
+func (impl DeploymentWindowServiceImpl) CreateWindow(request *DeploymentWindowDto) (*DeploymentWindowDto, error) {
+	err := impl.validateWindow(request)
+	if err != nil {
+		return nil, err
+	}
+	model := &pipelineConfig.DeploymentWindow{Active: true}
+	impl.copyToModel(request, model)
+	model.AuditLog = sql.AuditLog{CreatedOn: time.Now(), CreatedBy: request.UserId, UpdatedOn: time.Now(), UpdatedBy: request.UserId}
+	err = impl.deploymentWindowRepository.Save(model)
+	if err != nil {
+		impl.logger.Errorw("error in saving deployment window", "req", request, "err", err)
+		return nil, err
+	}
+	request.Id = model.Id
+	return request, nil
+}
+
+func (impl DeploymentWindowServiceImpl) UpdateWindow(request *DeploymentWindowDto) (*DeploymentWindowDto, error) {
+	err := impl.validateWindow(request)
+	if err != nil {
+		return nil, err
+	}
+	model, err := impl.deploymentWindowRepository.FindById(request.Id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment window", "id", request.Id, "err", err)
+		return nil, err
+	}
+	impl.copyToModel(request, model)
+	model.UpdatedOn = time.Now()
+	model.UpdatedBy = request.UserId
+	err = impl.deploymentWindowRepository.Update(model)
+	if err != nil {
+		impl.logger.Errorw("error in updating deployment window", "req", request, "err", err)
+		return nil, err
+	}
+	return request, nil
+}
+
+func (impl DeploymentWindowServiceImpl) DeleteWindow(id int, userId int32) error {
+	model, err := impl.deploymentWindowRepository.FindById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment window", "id", id, "err", err)
+		return err
+	}
+	model.Active = false
+	model.UpdatedOn = time.Now()
+	model.UpdatedBy = userId
+	err = impl.deploymentWindowRepository.Update(model)
+	if err != nil {
+		impl.logger.Errorw("error in deleting deployment window", "id", id, "err", err)
+		return err
+	}
+	return nil
+}
+
+func (impl DeploymentWindowServiceImpl) GetWindowById(id int) (*DeploymentWindowDto, error) {
+	model, err := impl.deploymentWindowRepository.FindById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment window", "id", id, "err", err)
+		return nil, err
+	}
+	return impl.toDto(model), nil
+}
+
+func (impl DeploymentWindowServiceImpl) GetWindowsByEnvironmentId(environmentId int) ([]*DeploymentWindowDto, error) {
+	models, err := impl.deploymentWindowRepository.FindActiveByEnvironmentId(environmentId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching deployment windows", "environmentId", environmentId, "err", err)
+		return nil, err
+	}
+	windows := make([]*DeploymentWindowDto, 0)
+	for _, model := range models {
+		windows = append(windows, impl.toDto(model))
+	}
+	return windows, nil
+}
+
+// CheckDeploymentAllowed blocks a deployment if any deny window is open, or if allow windows are defined and none of them is open
+func (impl DeploymentWindowServiceImpl) CheckDeploymentAllowed(appId int, environmentId int, at time.Time) (*DeploymentWindowCheckResult, error) {
+	models, err := impl.deploymentWindowRepository.FindActiveByEnvironmentId(environmentId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching deployment windows", "environmentId", environmentId, "err", err)
+		return nil, err
+	}
+	var allowWindows []*pipelineConfig.DeploymentWindow
+	for _, window := range models {
+		if window.AppId > 0 && window.AppId != appId {
+			continue
+		}
+		if window.WindowType == pipelineConfig.DEPLOYMENT_WINDOW_ALLOW {
+			allowWindows = append(allowWindows, window)
+			continue
+		}
+		isOpen, err := isDeploymentWindowOpen(window, at)
+		if err != nil {
+			impl.logger.Errorw("error in evaluating deployment window, ignoring it", "windowId", window.Id, "err", err)
+			continue
+		}
+		if isOpen {
+			return &DeploymentWindowCheckResult{
+				Allowed:  false,
+				WindowId: window.Id,
+				Reason:   fmt.Sprintf("deployment blocked by deployment window: %s", window.Name),
+			}, nil
+		}
+	}
+	// allow windows are evaluated only once no deny window is open, a freeze wins over an overlapping allow window
+	hasAllowWindow := false
+	for _, window := range allowWindows {
+		isOpen, err := isDeploymentWindowOpen(window, at)
+		if err != nil {
+			impl.logger.Errorw("error in evaluating deployment window, ignoring it", "windowId", window.Id, "err", err)
+			continue
+		}
+		if isOpen {
+			return &DeploymentWindowCheckResult{Allowed: true, WindowId: window.Id}, nil
+		}
+		hasAllowWindow = true
+	}
+	if hasAllowWindow {
+		return &DeploymentWindowCheckResult{
+			Allowed: false,
+			Reason:  "deployment blocked, outside of allowed deployment windows",
+		}, nil
+	}
+	return &DeploymentWindowCheckResult{Allowed: true}, nil
+}
+
+func (impl DeploymentWindowServiceImpl) SaveOverrideAudit(pipeline *pipelineConfig.Pipeline, ciArtifactId int, result *DeploymentWindowCheckResult, userId int32, reason string) error {
+	audit := &pipelineConfig.DeploymentWindowOverrideAudit{
+		DeploymentWindowId: result.WindowId,
+		EnvironmentId:      pipeline.EnvironmentId,
+		PipelineId:         pipeline.Id,
+		CiArtifactId:       ciArtifactId,
+		Reason:             reason,
+		WindowMessage:      result.Reason,
+		AuditLog:           sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+	}
+	err := impl.deploymentWindowRepository.SaveOverrideAudit(audit)
+	if err != nil {
+		impl.logger.Errorw("error in saving deployment window override audit", "pipelineId", pipeline.Id, "err", err)
+		return err
+	}
+	impl.logger.Infow("deployment window overridden", "pipelineId", pipeline.Id, "ciArtifactId", ciArtifactId, "userId", userId, "reason", reason)
+	return nil
+}
+
+func (impl DeploymentWindowServiceImpl) GetOverrideAuditByEnvironmentId(environmentId int) ([]*DeploymentWindowOverrideAuditDto, error) {
+	models, err := impl.deploymentWindowRepository.FindOverrideAuditByEnvironmentId(environmentId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching deployment window override audit", "environmentId", environmentId, "err", err)
+		return nil, err
+	}
+	audits := make([]*DeploymentWindowOverrideAuditDto, 0)
+	for _, model := range models {
+		audits = append(audits, &DeploymentWindowOverrideAuditDto{
+			Id:                 model.Id,
+			DeploymentWindowId: model.DeploymentWindowId,
+			EnvironmentId:      model.EnvironmentId,
+			PipelineId:         model.PipelineId,
+			CiArtifactId:       model.CiArtifactId,
+			Reason:             model.Reason,
+			WindowMessage:      model.WindowMessage,
+			OverriddenBy:       model.CreatedBy,
+			OverriddenOn:       model.CreatedOn,
+		})
+	}
+	return audits, nil
+}
+
+func (impl DeploymentWindowServiceImpl) validateWindow(request *DeploymentWindowDto) error {
+	if len(request.TimeZone) == 0 {
+		request.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(request.TimeZone); err != nil {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: "invalid time zone " + request.TimeZone}
+	}
+	if len(request.CronExpression) > 0 {
+		if _, err := cron.ParseStandard(request.CronExpression); err != nil {
+			return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: "invalid cron expression " + request.CronExpression}
+		}
+		if request.DurationMinutes <= 0 {
+			return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "duration is required with cron expression", UserMessage: "duration in minutes is required with cron expression"}
+		}
+		return nil
+	}
+	if request.StartTime == nil || request.EndTime == nil || !request.EndTime.After(*request.StartTime) {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "invalid window", UserMessage: "either cron expression with duration or a valid start and end time is required"}
+	}
+	return nil
+}
+
+func (impl DeploymentWindowServiceImpl) copyToModel(request *DeploymentWindowDto, model *pipelineConfig.DeploymentWindow) {
+	model.Name = request.Name
+	model.EnvironmentId = request.EnvironmentId
+	model.AppId = request.AppId
+	model.WindowType = request.WindowType
+	model.CronExpression = request.CronExpression
+	model.DurationMinutes = request.DurationMinutes
+	model.TimeZone = request.TimeZone
+	model.StartTime = time.Time{}
+	model.EndTime = time.Time{}
+	if len(request.CronExpression) == 0 {
+		model.StartTime = *request.StartTime
+		model.EndTime = *request.EndTime
+	}
+}
+
+func (impl DeploymentWindowServiceImpl) toDto(model *pipelineConfig.DeploymentWindow) *DeploymentWindowDto {
+	dto := &DeploymentWindowDto{
+		Id:              model.Id,
+		Name:            model.Name,
+		EnvironmentId:   model.EnvironmentId,
+		AppId:           model.AppId,
+		WindowType:      model.WindowType,
+		CronExpression:  model.CronExpression,
+		DurationMinutes: model.DurationMinutes,
+		TimeZone:        model.TimeZone,
+	}
+	if !model.StartTime.IsZero() {
+		startTime, endTime := model.StartTime, model.EndTime
+		dto.StartTime = &startTime
+		dto.EndTime = &endTime
+	}
+	return dto
+}
+
+// isDeploymentWindowOpen checks if "at" falls in [start, start+duration) of any cron occurrence, or in the one time window
+func isDeploymentWindowOpen(window *pipelineConfig.DeploymentWindow, at time.Time) (bool, error) {
+	location, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return false, err
+	}
+	at = at.In(location)
+	if len(window.CronExpression) == 0 {
+		return !at.Before(window.StartTime) && at.Before(window.EndTime), nil
+	}
+	schedule, err := cron.ParseStandard(window.CronExpression)
+	if err != nil {
+		return false, err
+	}
+	duration := time.Duration(window.DurationMinutes) * time.Minute
+	lastStart := schedule.Next(at.Add(-duration))
+	return !lastStart.After(at), nil
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+)
+
+func TestIsDeploymentWindowOpen(t *testing.T) {
+	// every day 22:00 for three hours, crosses midnight
+	nightly := &pipelineConfig.DeploymentWindow{CronExpression: "0 22 * * *", DurationMinutes: 180, TimeZone: "UTC"}
+	// weekdays 09:00 to 17:00 in Kolkata (UTC+05:30)
+	officeHours := &pipelineConfig.DeploymentWindow{CronExpression: "0 9 * * 1-5", DurationMinutes: 480, TimeZone: "Asia/Kolkata"}
+	oneTime := &pipelineConfig.DeploymentWindow{
+		StartTime: time.Date(2021, 3, 10, 10, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2021, 3, 12, 10, 0, 0, 0, time.UTC),
+		TimeZone:  "UTC",
+	}
+	tests := []struct {
+		name    string
+		window  *pipelineConfig.DeploymentWindow
+		at      time.Time
+		want    bool
+		wantErr bool
+	}{
+		{name: "cron window at its start", window: nightly, at: time.Date(2021, 3, 10, 22, 0, 0, 0, time.UTC), want: true},
+		{name: "cron window after midnight", window: nightly, at: time.Date(2021, 3, 11, 0, 30, 0, 0, time.UTC), want: true},
+		{name: "cron window at its end", window: nightly, at: time.Date(2021, 3, 11, 1, 0, 0, 0, time.UTC), want: false},
+		{name: "cron window before its start", window: nightly, at: time.Date(2021, 3, 10, 21, 59, 0, 0, time.UTC), want: false},
+		{name: "cron window in its time zone", window: officeHours, at: time.Date(2021, 3, 10, 4, 0, 0, 0, time.UTC), want: true},
+		{name: "cron window outside its time zone hours", window: officeHours, at: time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC), want: false},
+		{name: "cron window on a weekend", window: officeHours, at: time.Date(2021, 3, 13, 4, 0, 0, 0, time.UTC), want: false},
+		{name: "one time window at its start", window: oneTime, at: oneTime.StartTime, want: true},
+		{name: "one time window inside", window: oneTime, at: time.Date(2021, 3, 11, 0, 0, 0, 0, time.UTC), want: true},
+		{name: "one time window at its end", window: oneTime, at: oneTime.EndTime, want: false},
+		{name: "one time window before", window: oneTime, at: time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC), want: false},
+		{name: "invalid time zone", window: &pipelineConfig.DeploymentWindow{CronExpression: "0 22 * * *", DurationMinutes: 60, TimeZone: "Mars/Olympus"}, at: time.Now(), wantErr: true},
+		{name: "invalid cron expression", window: &pipelineConfig.DeploymentWindow{CronExpression: "0 25 * * *", DurationMinutes: 60, TimeZone: "UTC"}, at: time.Now(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := isDeploymentWindowOpen(tt.window, tt.at)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("isDeploymentWindowOpen() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("isDeploymentWindowOpen() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type deploymentWindowRepositoryStub struct {
+	pipelineConfig.DeploymentWindowRepository
+	windows []*pipelineConfig.DeploymentWindow
+}
+
+func (impl deploymentWindowRepositoryStub) FindActiveByEnvironmentId(environmentId int) ([]*pipelineConfig.DeploymentWindow, error) {
+	return impl.windows, nil
+}
+
+func TestCheckDeploymentAllowed(t *testing.T) {
+	at := time.Date(2021, 3, 10, 12, 0, 0, 0, time.UTC)
+	window := func(id int, appId int, windowType pipelineConfig.DeploymentWindowType, open bool) *pipelineConfig.DeploymentWindow {
+		start := at.Add(-time.Hour)
+		if !open {
+			start = at.Add(time.Hour)
+		}
+		return &pipelineConfig.DeploymentWindow{Id: id, Name: fmt.Sprintf("window-%d", id), AppId: appId, WindowType: windowType,
+			StartTime: start, EndTime: start.Add(2 * time.Hour), TimeZone: "UTC"}
+	}
+	tests := []struct {
+		name         string
+		windows      []*pipelineConfig.DeploymentWindow
+		wantAllowed  bool
+		wantWindowId int
+	}{
+		{name: "no windows", wantAllowed: true},
+		{name: "open allow window", windows: []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, true)}, wantAllowed: true, wantWindowId: 1},
+		{name: "closed allow window", windows: []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, false)}, wantAllowed: false},
+		{name: "open deny window", windows: []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_DENY, true)}, wantAllowed: false, wantWindowId: 1},
+		{name: "closed deny window", windows: []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_DENY, false)}, wantAllowed: true},
+		{
+			name:         "open deny window after an open allow window",
+			windows:      []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, true), window(2, 0, pipelineConfig.DEPLOYMENT_WINDOW_DENY, true)},
+			wantAllowed:  false,
+			wantWindowId: 2,
+		},
+		{
+			name:         "open deny window before an open allow window",
+			windows:      []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_DENY, true), window(2, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, true)},
+			wantAllowed:  false,
+			wantWindowId: 1,
+		},
+		{
+			name:         "closed deny window overlapping an open allow window",
+			windows:      []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, true), window(2, 0, pipelineConfig.DEPLOYMENT_WINDOW_DENY, false)},
+			wantAllowed:  true,
+			wantWindowId: 1,
+		},
+		{
+			name:         "open deny window of another app",
+			windows:      []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, true), window(2, 99, pipelineConfig.DEPLOYMENT_WINDOW_DENY, true)},
+			wantAllowed:  true,
+			wantWindowId: 1,
+		},
+		{
+			name:         "one of several allow windows open",
+			windows:      []*pipelineConfig.DeploymentWindow{window(1, 0, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, false), window(2, 7, pipelineConfig.DEPLOYMENT_WINDOW_ALLOW, true)},
+			wantAllowed:  true,
+			wantWindowId: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl := NewDeploymentWindowServiceImpl(util.NewSugardLogger(), deploymentWindowRepositoryStub{windows: tt.windows})
+			got, err := impl.CheckDeploymentAllowed(7, 1, at)
+			if err != nil {
+				t.Fatalf("CheckDeploymentAllowed() error = %v", err)
+			}
+			if got.Allowed != tt.wantAllowed || got.WindowId != tt.wantWindowId {
+				t.Errorf("CheckDeploymentAllowed() = %+v, want allowed %v by window %d", got, tt.wantAllowed, tt.wantWindowId)
+			}
+		})
+	}
+}
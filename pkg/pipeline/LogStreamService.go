@@ -0,0 +1,359 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type LogStreamType string
+
+const (
+	LOG_STREAM_CI LogStreamType = "CI"
+	LOG_STREAM_CD LogStreamType = "CD"
+)
+
+// events sent on a log stream, the id of every event is the line number up to which the client has the logs
+const (
+	LOG_STREAM_EVENT_START  = "START_OF_STREAM"
+	LOG_STREAM_EVENT_LOG    = "LOG"
+	LOG_STREAM_EVENT_MARKER = "MARKER"
+	LOG_STREAM_EVENT_SOURCE = "SOURCE"
+	LOG_STREAM_EVENT_END    = "END_OF_STREAM"
+	LOG_STREAM_EVENT_ERROR  = "ERROR"
+)
+
+const (
+	LOG_SOURCE_LIVE     = "LIVE"
+	LOG_SOURCE_ARCHIVED = "ARCHIVED"
+)
+
+const (
+	logStreamBatchSize    = 200
+	logStreamBufferSize   = 64 * 1024
+	logStreamPollInterval = 3 * time.Second
+	// a workflow which neither logs nor finishes for this long is not streamed any further
+	logStreamIdleTimeout = 10 * time.Minute
+)
+
+// the ci runner logs stage and step boundaries as "STAGE:  name" and "STEP:  name", optionally after the log timestamp
+var logMarkerRegex = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2}(\.\d+)? )?(STAGE|STEP):\s+(.+?)\s*$`)
+
+type LogStreamRequest struct {
+	Type       LogStreamType
+	PipelineId int
+	WorkflowId int //ci workflow id for CI, cd workflow runner id for CD
+	Offset     int //last line number received by the client, lines up to it are not sent again
+}
+
+type LogStreamEvent struct {
+	Event string
+	Id    int
+	Data  interface{}
+}
+
+type LogLine struct {
+	Number int    `json:"n"`
+	Text   string `json:"text"`
+}
+
+type LogLineBatch struct {
+	Lines []*LogLine `json:"lines"`
+}
+
+type LogMarker struct {
+	Number int    `json:"n"`
+	Type   string `json:"type"` //STAGE or STEP
+	Name   string `json:"name"`
+}
+
+type LogStreamStart struct {
+	Type         LogStreamType `json:"type"`
+	WorkflowType string        `json:"workflowType"` //CI, PRE or POST
+	Source       string        `json:"source"`
+	Offset       int           `json:"offset"`
+}
+
+type LogStreamSource struct {
+	Source string `json:"source"`
+}
+
+type LogStreamEnd struct {
+	Status string `json:"status"`
+	Lines  int    `json:"lines"`
+}
+
+type LogStreamError struct {
+	Message string `json:"message"`
+}
+
+type LogStreamService interface {
+	// ValidateRequest checks that the workflow belongs to the pipeline and returns the app and env of the pipeline for rbac, env is 0 for CI
+	ValidateRequest(request *LogStreamRequest) (appId int, envId int, err error)
+	// StreamLogs sends the logs of the workflow from the pod while it runs and from the archive once it is done, until the
+	// workflow completes or the context is cancelled. Lines are numbered the same in both so a client can resume from any offset.
+	StreamLogs(ctx context.Context, request *LogStreamRequest, emit func(event *LogStreamEvent) error) error
+}
+
+type LogStreamServiceImpl struct {
+	logger               *zap.SugaredLogger
+	ciHandler            CiHandler
+	cdHandler            CdHandler
+	ciWorkflowRepository pipelineConfig.CiWorkflowRepository
+	cdWorkflowRepository pipelineConfig.CdWorkflowRepository
+	ciPipelineRepository pipelineConfig.CiPipelineRepository
+	pipelineRepository   pipelineConfig.PipelineRepository
+}
+
+func NewLogStreamServiceImpl(logger *zap.SugaredLogger, ciHandler CiHandler, cdHandler CdHandler,
+	ciWorkflowRepository pipelineConfig.CiWorkflowRepository, cdWorkflowRepository pipelineConfig.CdWorkflowRepository,
+	ciPipelineRepository pipelineConfig.CiPipelineRepository, pipelineRepository pipelineConfig.PipelineRepository) *LogStreamServiceImpl {
+	return &LogStreamServiceImpl{
+		logger:               logger,
+		ciHandler:            ciHandler,
+		cdHandler:            cdHandler,
+		ciWorkflowRepository: ciWorkflowRepository,
+		cdWorkflowRepository: cdWorkflowRepository,
+		ciPipelineRepository: ciPipelineRepository,
+		pipelineRepository:   pipelineRepository,
+	}
+}
+
+// logStreamWorkflow is the state of the ci workflow or cd stage runner being streamed
+type logStreamWorkflow struct {
+	status       string
+	workflowType string
+	hasLogs      bool
+}
+
+func (impl *LogStreamServiceImpl) ValidateRequest(request *LogStreamRequest) (int, int, error) {
+	notFound := &util.ApiError{HttpStatusCode: http.StatusNotFound, InternalMessage: "workflow not found in pipeline", UserMessage: "workflow not found in pipeline"}
+	switch request.Type {
+	case LOG_STREAM_CI:
+		ciWorkflow, err := impl.ciWorkflowRepository.FindById(request.WorkflowId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching ci workflow", "workflowId", request.WorkflowId, "err", err)
+			return 0, 0, err
+		}
+		if ciWorkflow.CiPipelineId != request.PipelineId {
+			return 0, 0, notFound
+		}
+		ciPipeline, err := impl.ciPipelineRepository.FindById(request.PipelineId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching ci pipeline", "pipelineId", request.PipelineId, "err", err)
+			return 0, 0, err
+		}
+		return ciPipeline.AppId, 0, nil
+	case LOG_STREAM_CD:
+		runner, err := impl.cdWorkflowRepository.FindWorkflowRunnerById(request.WorkflowId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching cd workflow runner", "workflowRunnerId", request.WorkflowId, "err", err)
+			return 0, 0, err
+		}
+		if runner.CdWorkflow == nil || runner.CdWorkflow.PipelineId != request.PipelineId {
+			return 0, 0, notFound
+		}
+		if runner.WorkflowType != PRE && runner.WorkflowType != POST {
+			return 0, 0, &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "logs are only available for pre and post stages", UserMessage: "logs are only available for pre and post stages"}
+		}
+		pipeline, err := impl.pipelineRepository.FindById(request.PipelineId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching cd pipeline", "pipelineId", request.PipelineId, "err", err)
+			return 0, 0, err
+		}
+		return pipeline.AppId, pipeline.EnvironmentId, nil
+	}
+	return 0, 0, &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "invalid log stream type", UserMessage: fmt.Sprintf("invalid log stream type %s", request.Type)}
+}
+
+func (impl *LogStreamServiceImpl) StreamLogs(ctx context.Context, request *LogStreamRequest, emit func(event *LogStreamEvent) error) error {
+	workflow, err := impl.getWorkflow(request)
+	if err != nil {
+		return err
+	}
+	source := LOG_SOURCE_LIVE
+	if isTerminalWorkflowStatus(workflow.status) {
+		source = LOG_SOURCE_ARCHIVED
+	}
+	sent := request.Offset
+	err = emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_START, Id: sent, Data: &LogStreamStart{Type: request.Type, WorkflowType: workflow.workflowType, Source: source, Offset: sent}})
+	if err != nil {
+		return err
+	}
+	lastProgress := time.Now()
+	for {
+		if isTerminalWorkflowStatus(workflow.status) && source == LOG_SOURCE_LIVE {
+			// the pod logs have ended, the rest comes from the archive which has the same lines from the start
+			source = LOG_SOURCE_ARCHIVED
+			if err = emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_SOURCE, Id: sent, Data: &LogStreamSource{Source: source}}); err != nil {
+				return err
+			}
+		}
+		if workflow.hasLogs {
+			lines, err := impl.streamOnce(ctx, request, sent, emit)
+			if lines > sent {
+				sent = lines
+				lastProgress = time.Now()
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil && source == LOG_SOURCE_ARCHIVED {
+				impl.logger.Errorw("error in fetching archived logs", "type", request.Type, "workflowId", request.WorkflowId, "err", err)
+				return emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_ERROR, Id: sent, Data: &LogStreamError{Message: "logs are not available for this workflow"}})
+			}
+			if err != nil {
+				// the pod may not be up yet or is being replaced, retried until the workflow completes
+				impl.logger.Debugw("error in streaming running logs", "type", request.Type, "workflowId", request.WorkflowId, "err", err)
+			}
+		}
+		if source == LOG_SOURCE_ARCHIVED || !workflow.hasLogs {
+			return emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_END, Id: sent, Data: &LogStreamEnd{Status: workflow.status, Lines: sent}})
+		}
+		if time.Since(lastProgress) > logStreamIdleTimeout {
+			return emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_ERROR, Id: sent, Data: &LogStreamError{Message: "no logs received from the workflow, reconnect to retry"}})
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logStreamPollInterval):
+		}
+		workflow, err = impl.getWorkflow(request)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// streamOnce sends the lines after offset from the current log source and returns the number of lines read
+func (impl *LogStreamServiceImpl) streamOnce(ctx context.Context, request *LogStreamRequest, offset int, emit func(event *LogStreamEvent) error) (int, error) {
+	var reader *bufio.Reader
+	var cleanUp func() error
+	var err error
+	if request.Type == LOG_STREAM_CI {
+		reader, cleanUp, err = impl.ciHandler.GetRunningWorkflowLogs(request.PipelineId, request.WorkflowId)
+	} else {
+		var envId int
+		if _, envId, err = impl.ValidateRequest(request); err == nil {
+			reader, cleanUp, err = impl.cdHandler.GetRunningWorkflowLogs(envId, request.PipelineId, request.WorkflowId)
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	// a larger buffer keeps archived logs in fewer events, the broker drops connections which fall behind
+	reader = bufio.NewReaderSize(reader, logStreamBufferSize)
+	done := make(chan struct{})
+	defer close(done)
+	if cleanUp != nil {
+		// closing the stream is the only way to unblock a read of a running pod's logs
+		go func() {
+			select {
+			case <-ctx.Done():
+			case <-done:
+			}
+			_ = cleanUp()
+		}()
+	}
+	lineNumber := 0
+	var batch []*LogLine
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_LOG, Id: batch[len(batch)-1].Number, Data: &LogLineBatch{Lines: batch}})
+		batch = nil
+		return err
+	}
+	for {
+		data, err := reader.ReadString('\n')
+		if len(data) > 0 {
+			lineNumber++
+			text := strings.TrimRight(data, "\r\n")
+			// same internal lines the build logs api hides, they are still counted so that numbers match across sources
+			if lineNumber > offset && !strings.Contains(text, "DEVTRON") {
+				batch = append(batch, &LogLine{Number: lineNumber, Text: text})
+				if marker := parseLogMarker(lineNumber, text); marker != nil {
+					if emitErr := flush(); emitErr != nil {
+						return lineNumber, emitErr
+					}
+					if emitErr := emit(&LogStreamEvent{Event: LOG_STREAM_EVENT_MARKER, Id: lineNumber, Data: marker}); emitErr != nil {
+						return lineNumber, emitErr
+					}
+				}
+			}
+		}
+		// live lines are sent as soon as nothing more is buffered, archived ones go out in batches
+		if err != nil || len(batch) >= logStreamBatchSize || reader.Buffered() == 0 {
+			if emitErr := flush(); emitErr != nil {
+				return lineNumber, emitErr
+			}
+		}
+		if err == io.EOF {
+			return lineNumber, nil
+		}
+		if err != nil {
+			return lineNumber, err
+		}
+	}
+}
+
+func (impl *LogStreamServiceImpl) getWorkflow(request *LogStreamRequest) (*logStreamWorkflow, error) {
+	if request.Type == LOG_STREAM_CI {
+		ciWorkflow, err := impl.ciWorkflowRepository.FindById(request.WorkflowId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching ci workflow", "workflowId", request.WorkflowId, "err", err)
+			return nil, err
+		}
+		// skipped builds never had a pod
+		return &logStreamWorkflow{status: ciWorkflow.Status, workflowType: string(LOG_STREAM_CI), hasLogs: ciWorkflow.Status != WorkflowSkipped}, nil
+	}
+	runner, err := impl.cdWorkflowRepository.FindWorkflowRunnerById(request.WorkflowId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching cd workflow runner", "workflowRunnerId", request.WorkflowId, "err", err)
+		return nil, err
+	}
+	return &logStreamWorkflow{status: runner.Status, workflowType: string(runner.WorkflowType), hasLogs: true}, nil
+}
+
+func isTerminalWorkflowStatus(status string) bool {
+	switch status {
+	case string(v1alpha1.NodeSucceeded), string(v1alpha1.NodeError), string(v1alpha1.NodeFailed), WorkflowCancel, WorkflowSkipped, WorkflowAborted:
+		return true
+	}
+	return false
+}
+
+func parseLogMarker(lineNumber int, text string) *LogMarker {
+	match := logMarkerRegex.FindStringSubmatch(strings.TrimSpace(text))
+	if match == nil {
+		return nil
+	}
+	return &LogMarker{Number: lineNumber, Type: match[3], Name: match[4]}
+}
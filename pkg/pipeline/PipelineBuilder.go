@@ -130,6 +130,9 @@ type PipelineBuilderImpl struct {
 	attributesService             attributes.AttributesService
 	aCDAuthConfig                 *util3.ACDAuthConfig
 	gitOpsRepository              repository.GitOpsConfigRepository
+	deploymentApprovalService     DeploymentApprovalService
+	deploymentAutoRollbackService DeploymentAutoRollbackService
+	canaryAnalysisService         CanaryAnalysisService
 }
 
 func NewPipelineBuilderImpl(logger *zap.SugaredLogger,
@@ -156,7 +159,10 @@ func NewPipelineBuilderImpl(logger *zap.SugaredLogger,
 	imageScanResultRepository security.ImageScanResultRepository,
 	ArgoK8sClient argocdServer.ArgoK8sClient,
 	GitFactory *util.GitFactory, attributesService attributes.AttributesService,
-	aCDAuthConfig *util3.ACDAuthConfig, gitOpsRepository repository.GitOpsConfigRepository) *PipelineBuilderImpl {
+	aCDAuthConfig *util3.ACDAuthConfig, gitOpsRepository repository.GitOpsConfigRepository,
+	deploymentApprovalService DeploymentApprovalService,
+	deploymentAutoRollbackService DeploymentAutoRollbackService,
+	canaryAnalysisService CanaryAnalysisService) *PipelineBuilderImpl {
 	return &PipelineBuilderImpl{
 		logger:                        logger,
 		dbPipelineOrchestrator:        dbPipelineOrchestrator,
@@ -185,6 +191,9 @@ func NewPipelineBuilderImpl(logger *zap.SugaredLogger,
 		attributesService:             attributesService,
 		aCDAuthConfig:                 aCDAuthConfig,
 		gitOpsRepository:              gitOpsRepository,
+		deploymentApprovalService:     deploymentApprovalService,
+		deploymentAutoRollbackService: deploymentAutoRollbackService,
+		canaryAnalysisService:         canaryAnalysisService,
 	}
 }
 
@@ -375,7 +384,7 @@ func (impl PipelineBuilderImpl) getCiTemplateVariables(appId int) (ciConfig *bea
 		DockerRegistryUrl: regHost,
 		BeforeDockerBuild: beforeDockerBuild,
 		AfterDockerBuild:  afterDockerBuild,
-		DockerBuildConfig: &bean.DockerBuildConfig{DockerfilePath: template.DockerfilePath, Args: dockerArgs, GitMaterialId: template.GitMaterialId},
+		DockerBuildConfig: &bean.DockerBuildConfig{DockerfilePath: template.DockerfilePath, Args: dockerArgs, GitMaterialId: template.GitMaterialId, TargetPlatform: template.TargetPlatform, BuildType: bean.CiBuildType(template.BuildType), BuilderImage: template.BuilderImage},
 		Version:           template.Version,
 		CiTemplateName:    template.TemplateName,
 		Materials:         materials,
@@ -470,6 +479,9 @@ func (impl PipelineBuilderImpl) GetCiPipeline(appId int) (ciConfig *bean.CiConfi
 			BeforeDockerBuildScripts: beforeDockerBuildScripts,
 			AfterDockerBuildScripts:  afterDockerBuildScripts,
 			ScanEnabled:              pipeline.ScanEnabled,
+			CronSchedule:             pipeline.CronSchedule,
+			SkipUnchanged:            pipeline.SkipUnchanged,
+			SbomEnabled:              pipeline.SbomEnabled,
 		}
 		for _, material := range pipeline.CiPipelineMaterials {
 			ciMaterial := &bean.CiMaterial{
@@ -482,6 +494,8 @@ func (impl PipelineBuilderImpl) GetCiPipeline(appId int) (ciConfig *bean.CiConfi
 				ScmName:         material.ScmName,
 				ScmVersion:      material.ScmVersion,
 				Source:          &bean.SourceTypeConfig{Type: material.Type, Value: material.Value},
+				IncludePaths:    material.IncludePaths,
+				ExcludePaths:    material.ExcludePaths,
 			}
 			ciPipeline.CiMaterial = append(ciPipeline.CiMaterial, ciMaterial)
 		}
@@ -579,6 +593,12 @@ func (impl PipelineBuilderImpl) UpdateCiTemplate(updateRequest *bean.CiConfigReq
 	originalCiConf.DockerRepository = updateRequest.DockerRepository
 	originalCiConf.DockerRegistryUrl = regHost
 
+	err = normalizeDockerBuildConfig(originalCiConf.DockerBuildConfig)
+	if err != nil {
+		impl.logger.Errorw("invalid docker build config", "appId", updateRequest.AppId, "dockerBuildConfig", originalCiConf.DockerBuildConfig, "err", err)
+		return nil, err
+	}
+
 	argByte, err := json.Marshal(originalCiConf.DockerBuildConfig.Args)
 	if err != nil {
 		return nil, err
@@ -602,6 +622,9 @@ func (impl PipelineBuilderImpl) UpdateCiTemplate(updateRequest *bean.CiConfigReq
 		DockerRepository:  originalCiConf.DockerRepository,
 		DockerRegistryId:  originalCiConf.DockerRegistry,
 		Active:            true,
+		TargetPlatform:    originalCiConf.DockerBuildConfig.TargetPlatform,
+		BuildType:         string(originalCiConf.DockerBuildConfig.BuildType),
+		BuilderImage:      originalCiConf.DockerBuildConfig.BuilderImage,
 	}
 
 	err = impl.ciTemplateRepository.Update(ciTemplate)
@@ -609,6 +632,11 @@ func (impl PipelineBuilderImpl) UpdateCiTemplate(updateRequest *bean.CiConfigReq
 		impl.logger.Errorw("error in updating ci template in db", "template", ciTemplate, "err", err)
 		return nil, err
 	}
+	err = impl.ciTemplateRepository.UpdateBuildOptions(ciTemplate)
+	if err != nil {
+		impl.logger.Errorw("error in updating ci template build options in db", "template", ciTemplate, "err", err)
+		return nil, err
+	}
 	return originalCiConf, nil
 }
 
@@ -655,6 +683,12 @@ func (impl PipelineBuilderImpl) CreateCiPipeline(createRequest *bean.CiConfigReq
 	//--ecr config	end
 	//-- template config start
 
+	err = normalizeDockerBuildConfig(createRequest.DockerBuildConfig)
+	if err != nil {
+		impl.logger.Errorw("invalid docker build config", "appId", createRequest.AppId, "dockerBuildConfig", createRequest.DockerBuildConfig, "err", err)
+		return nil, err
+	}
+
 	argByte, err := json.Marshal(createRequest.DockerBuildConfig.Args)
 	if err != nil {
 		return nil, err
@@ -673,6 +707,9 @@ func (impl PipelineBuilderImpl) CreateCiPipeline(createRequest *bean.CiConfigReq
 		DockerRepository:  createRequest.DockerRepository,
 		GitMaterialId:     createRequest.DockerBuildConfig.GitMaterialId,
 		DockerfilePath:    createRequest.DockerBuildConfig.DockerfilePath,
+		TargetPlatform:    createRequest.DockerBuildConfig.TargetPlatform,
+		BuildType:         string(createRequest.DockerBuildConfig.BuildType),
+		BuilderImage:      createRequest.DockerBuildConfig.BuilderImage,
 		Args:              string(argByte),
 		Active:            true,
 		TemplateName:      createRequest.CiTemplateName,
@@ -1085,6 +1122,18 @@ func (impl PipelineBuilderImpl) deleteCdPipeline(pipelineId int, userId int32, c
 		impl.logger.Errorw("err in deleting pipeline from db", "id", pipeline, "err", err)
 		return err
 	}
+	if err = impl.deploymentApprovalService.SaveApprovalConfig(pipelineId, nil, userId, tx); err != nil {
+		impl.logger.Errorw("err in deleting deployment approval config", "id", pipelineId, "err", err)
+		return err
+	}
+	if err = impl.deploymentAutoRollbackService.SaveAutoRollbackConfig(pipelineId, nil, userId, tx); err != nil {
+		impl.logger.Errorw("err in deleting auto rollback config", "id", pipelineId, "err", err)
+		return err
+	}
+	if err = impl.canaryAnalysisService.SaveCanaryAnalysisConfig(pipelineId, nil, userId, tx); err != nil {
+		impl.logger.Errorw("err in deleting canary analysis config", "id", pipelineId, "err", err)
+		return err
+	}
 
 	//delete app workflow mapping
 	appWorkflowMapping, err = impl.appWorkflowRepository.FindWFCDMappingByCDPipelineId(pipelineId)
@@ -1247,6 +1296,28 @@ func (impl PipelineBuilderImpl) createCdPipeline(ctx context.Context, app *app2.
 		return 0, err
 	}
 
+	if pipeline.ApprovalConfig != nil {
+		err = impl.deploymentApprovalService.SaveApprovalConfig(pipelineId, pipeline.ApprovalConfig, userID, tx)
+		if err != nil {
+			impl.logger.Errorw("error in saving deployment approval config", "pipelineId", pipelineId, "err", err)
+			return 0, err
+		}
+	}
+	if pipeline.AutoRollbackConfig != nil {
+		err = impl.deploymentAutoRollbackService.SaveAutoRollbackConfig(pipelineId, pipeline.AutoRollbackConfig, userID, tx)
+		if err != nil {
+			impl.logger.Errorw("error in saving auto rollback config", "pipelineId", pipelineId, "err", err)
+			return 0, err
+		}
+	}
+	if pipeline.CanaryAnalysisConfig != nil {
+		err = impl.canaryAnalysisService.SaveCanaryAnalysisConfig(pipelineId, pipeline.CanaryAnalysisConfig, userID, tx)
+		if err != nil {
+			impl.logger.Errorw("error in saving canary analysis config", "pipelineId", pipelineId, "err", err)
+			return 0, err
+		}
+	}
+
 	//adding ci pipeline to workflow
 	appWorkflowModel, err := impl.appWorkflowRepository.FindByIdAndAppId(pipeline.AppWorkflowId, app.Id)
 	if err != nil && err != pg.ErrNoRows {
@@ -1342,6 +1413,31 @@ func (impl PipelineBuilderImpl) updateCdPipeline(ctx context.Context, pipeline *
 		return err
 	}
 
+	//approval gate is left untouched when the update does not carry it, a required count of zero turns it off
+	if pipeline.ApprovalConfig != nil {
+		err = impl.deploymentApprovalService.SaveApprovalConfig(pipeline.Id, pipeline.ApprovalConfig, userID, tx)
+		if err != nil {
+			impl.logger.Errorw("error in updating deployment approval config", "pipelineId", pipeline.Id, "err", err)
+			return err
+		}
+	}
+	//auto rollback is left untouched when the update does not carry it, a zero timeout turns it off
+	if pipeline.AutoRollbackConfig != nil {
+		err = impl.deploymentAutoRollbackService.SaveAutoRollbackConfig(pipeline.Id, pipeline.AutoRollbackConfig, userID, tx)
+		if err != nil {
+			impl.logger.Errorw("error in updating auto rollback config", "pipelineId", pipeline.Id, "err", err)
+			return err
+		}
+	}
+	//canary metrics are left untouched when the update does not carry them, an empty metric list removes them
+	if pipeline.CanaryAnalysisConfig != nil {
+		err = impl.canaryAnalysisService.SaveCanaryAnalysisConfig(pipeline.Id, pipeline.CanaryAnalysisConfig, userID, tx)
+		if err != nil {
+			impl.logger.Errorw("error in updating canary analysis config", "pipelineId", pipeline.Id, "err", err)
+			return err
+		}
+	}
+
 	// strategies for pipeline ids, there is only one is default
 	existingStrategies, err := impl.pipelineConfigRepository.GetAllStrategyByPipelineId(pipeline.Id)
 	if err != nil && !errors.IsNotFound(err) {
@@ -1560,6 +1656,21 @@ func (impl PipelineBuilderImpl) GetCdPipelinesForApp(appId int) (cdPipelines *be
 				deploymentTemplate = item.Strategy
 			}
 		}
+		approvalConfig, err := impl.deploymentApprovalService.GetApprovalConfig(dbPipeline.Id)
+		if err != nil {
+			impl.logger.Errorw("error in fetching deployment approval config", "pipelineId", dbPipeline.Id, "err", err)
+			return cdPipelines, err
+		}
+		autoRollbackConfig, err := impl.deploymentAutoRollbackService.GetAutoRollbackConfig(dbPipeline.Id)
+		if err != nil {
+			impl.logger.Errorw("error in fetching auto rollback config", "pipelineId", dbPipeline.Id, "err", err)
+			return cdPipelines, err
+		}
+		canaryAnalysisConfig, err := impl.canaryAnalysisService.GetCanaryAnalysisConfig(dbPipeline.Id)
+		if err != nil {
+			impl.logger.Errorw("error in fetching canary analysis config", "pipelineId", dbPipeline.Id, "err", err)
+			return cdPipelines, err
+		}
 		pipeline := &bean.CDPipelineConfigObject{
 			Id:                            dbPipeline.Id,
 			Name:                          dbPipeline.Name,
@@ -1575,6 +1686,9 @@ func (impl PipelineBuilderImpl) GetCdPipelinesForApp(appId int) (cdPipelines *be
 			PostStageConfigMapSecretNames: dbPipeline.PostStageConfigMapSecretNames,
 			RunPreStageInEnv:              dbPipeline.RunPreStageInEnv,
 			RunPostStageInEnv:             dbPipeline.RunPostStageInEnv,
+			ApprovalConfig:                approvalConfig,
+			AutoRollbackConfig:            autoRollbackConfig,
+			CanaryAnalysisConfig:          canaryAnalysisConfig,
 		}
 		pipelines = append(pipelines, pipeline)
 	}
@@ -2105,6 +2219,21 @@ func (impl PipelineBuilderImpl) GetCdPipelineById(pipelineId int) (cdPipeline *b
 			return nil, err
 		}
 	}
+	approvalConfig, err := impl.deploymentApprovalService.GetApprovalConfig(dbPipeline.Id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching deployment approval config", "pipelineId", dbPipeline.Id, "err", err)
+		return nil, err
+	}
+	autoRollbackConfig, err := impl.deploymentAutoRollbackService.GetAutoRollbackConfig(dbPipeline.Id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching auto rollback config", "pipelineId", dbPipeline.Id, "err", err)
+		return nil, err
+	}
+	canaryAnalysisConfig, err := impl.canaryAnalysisService.GetCanaryAnalysisConfig(dbPipeline.Id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching canary analysis config", "pipelineId", dbPipeline.Id, "err", err)
+		return nil, err
+	}
 
 	cdPipeline = &bean.CDPipelineConfigObject{
 		Id:                            dbPipeline.Id,
@@ -2122,6 +2251,9 @@ func (impl PipelineBuilderImpl) GetCdPipelineById(pipelineId int) (cdPipeline *b
 		RunPreStageInEnv:              dbPipeline.RunPreStageInEnv,
 		RunPostStageInEnv:             dbPipeline.RunPostStageInEnv,
 		CdArgoSetup:                   environment.Cluster.CdArgoSetup,
+		ApprovalConfig:                approvalConfig,
+		AutoRollbackConfig:            autoRollbackConfig,
+		CanaryAnalysisConfig:          canaryAnalysisConfig,
 	}
 
 	return cdPipeline, err
@@ -2217,6 +2349,9 @@ func (impl PipelineBuilderImpl) GetCiPipelineById(pipelineId int) (ciPipeline *b
 		BeforeDockerBuildScripts: beforeDockerBuildScripts,
 		AfterDockerBuildScripts:  afterDockerBuildScripts,
 		ScanEnabled:              pipeline.ScanEnabled,
+		CronSchedule:             pipeline.CronSchedule,
+		SkipUnchanged:            pipeline.SkipUnchanged,
+		SbomEnabled:              pipeline.SbomEnabled,
 	}
 	for _, material := range pipeline.CiPipelineMaterials {
 		ciMaterial := &bean.CiMaterial{
@@ -2229,6 +2364,8 @@ func (impl PipelineBuilderImpl) GetCiPipelineById(pipelineId int) (ciPipeline *b
 			ScmName:         material.ScmName,
 			ScmVersion:      material.ScmVersion,
 			Source:          &bean.SourceTypeConfig{Type: material.Type, Value: material.Value},
+			IncludePaths:    material.IncludePaths,
+			ExcludePaths:    material.ExcludePaths,
 		}
 		ciPipeline.CiMaterial = append(ciPipeline.CiMaterial, ciMaterial)
 	}
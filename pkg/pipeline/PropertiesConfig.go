@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"fmt"
 	chartRepoRepository "github.com/devtron-labs/devtron/pkg/chartRepo/repository"
+	"github.com/devtron-labs/devtron/pkg/configHistory"
 	repository2 "github.com/devtron-labs/devtron/pkg/cluster/repository"
 	"github.com/devtron-labs/devtron/pkg/sql"
 	"time"
@@ -50,6 +51,7 @@ type EnvironmentProperties struct {
 	AppMetrics        *bool              `json:"appMetrics"`
 	ChartRefId        int                `json:"chartRefId,omitempty"  validate:"number"`
 	IsOverride        bool               `sql:"isOverride"`
+	Comment           string             `json:"comment,omitempty"`
 }
 
 type EnvironmentPropertiesResponse struct {
@@ -74,7 +76,7 @@ type PropertiesConfigService interface {
 
 	GetAppIdByChartEnvId(chartEnvId int) (*chartConfig.EnvConfigOverride, error)
 	GetLatestEnvironmentProperties(appId, environmentId int) (*EnvironmentProperties, error)
-	ResetEnvironmentProperties(id int) (bool, error)
+	ResetEnvironmentProperties(id int, userId int32) (bool, error)
 	CreateEnvironmentPropertiesWithNamespace(appId int, propertiesRequest *EnvironmentProperties) (*EnvironmentProperties, error)
 
 	EnvMetricsEnableDisable(appMetricRequest *AppMetricEnableDisableRequest) (*AppMetricEnableDisableRequest, error)
@@ -89,6 +91,7 @@ type PropertiesConfigServiceImpl struct {
 	application                  application.ServiceClient
 	envLevelAppMetricsRepository repository.EnvLevelAppMetricsRepository
 	appLevelMetricsRepository    repository.AppLevelMetricsRepository
+	configHistoryService         configHistory.ConfigHistoryService
 }
 
 func NewPropertiesConfigServiceImpl(logger *zap.SugaredLogger,
@@ -100,6 +103,7 @@ func NewPropertiesConfigServiceImpl(logger *zap.SugaredLogger,
 	application application.ServiceClient,
 	envLevelAppMetricsRepository repository.EnvLevelAppMetricsRepository,
 	appLevelMetricsRepository repository.AppLevelMetricsRepository,
+	configHistoryService configHistory.ConfigHistoryService,
 ) *PropertiesConfigServiceImpl {
 	return &PropertiesConfigServiceImpl{
 		logger:                       logger,
@@ -111,6 +115,7 @@ func NewPropertiesConfigServiceImpl(logger *zap.SugaredLogger,
 		application:                  application,
 		envLevelAppMetricsRepository: envLevelAppMetricsRepository,
 		appLevelMetricsRepository:    appLevelMetricsRepository,
+		configHistoryService:         configHistoryService,
 	}
 
 }
@@ -232,6 +237,14 @@ func (impl PropertiesConfigServiceImpl) CreateEnvironmentProperties(appId int, e
 	if err != nil {
 		return nil, err
 	}
+	impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+		AppId:      appId,
+		EnvId:      environmentProperties.EnvironmentId,
+		ConfigType: configHistory.ConfigTypeDeploymentTemplate,
+		Data:       envOverride.EnvOverrideValues,
+		Comment:    environmentProperties.Comment,
+		UserId:     environmentProperties.UserId,
+	})
 
 	r := json.RawMessage{}
 	err = r.UnmarshalJSON([]byte(envOverride.EnvOverrideValues))
@@ -324,6 +337,16 @@ func (impl PropertiesConfigServiceImpl) UpdateEnvironmentProperties(appId int, p
 	override.IsOverride = true
 	impl.logger.Debugw("updating environment override ", "value", override)
 	err = impl.envConfigRepo.UpdateProperties(override)
+	if err == nil {
+		impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+			AppId:      appId,
+			EnvId:      oldEnvOverride.TargetEnvironment,
+			ConfigType: configHistory.ConfigTypeDeploymentTemplate,
+			Data:       override.EnvOverrideValues,
+			Comment:    propertiesRequest.Comment,
+			UserId:     userId,
+		})
+	}
 
 	if oldEnvOverride.Namespace != override.Namespace {
 		return nil, fmt.Errorf("namespace name update not supported")
@@ -499,7 +522,7 @@ func (impl PropertiesConfigServiceImpl) GetLatestEnvironmentProperties(appId, en
 	return environmentProperties, nil
 }
 
-func (impl PropertiesConfigServiceImpl) ResetEnvironmentProperties(id int) (bool, error) {
+func (impl PropertiesConfigServiceImpl) ResetEnvironmentProperties(id int, userId int32) (bool, error) {
 	envOverride, err := impl.envConfigRepo.Get(id)
 	if err != nil {
 		return false, err
@@ -507,10 +530,21 @@ func (impl PropertiesConfigServiceImpl) ResetEnvironmentProperties(id int) (bool
 	envOverride.EnvOverrideValues = "{}"
 	envOverride.IsOverride = false
 	envOverride.Latest = false
+	envOverride.UpdatedBy = userId
+	envOverride.UpdatedOn = time.Now()
 	impl.logger.Infow("reset environment override ", "value", envOverride)
 	err = impl.envConfigRepo.UpdateProperties(envOverride)
 	if err != nil {
 		impl.logger.Warnw("error in update envOverride", "envOverrideId", id)
+	} else {
+		impl.configHistoryService.SaveHistory(&configHistory.ConfigHistoryRequest{
+			AppId:      envOverride.Chart.AppId,
+			EnvId:      envOverride.TargetEnvironment,
+			ConfigType: configHistory.ConfigTypeDeploymentTemplate,
+			Data:       envOverride.EnvOverrideValues,
+			Comment:    "reset to app level template",
+			UserId:     userId,
+		})
 	}
 
 	envLevelAppMetrics, err := impl.envLevelAppMetricsRepository.FindByAppIdAndEnvId(envOverride.Chart.AppId, envOverride.TargetEnvironment)
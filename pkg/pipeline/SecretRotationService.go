@@ -0,0 +1,310 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package pipeline
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/internal/sql/models"
+	"github.com/devtron-labs/devtron/internal/sql/repository/chartConfig"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// config level of a rotated pipeline, app when the env override was created from the app level secret by the rotation
+const (
+	SecretConfigLevelApp = "app"
+	SecretConfigLevelEnv = "env"
+)
+
+type SecretRotationRequest struct {
+	SecretName string                  `json:"secretName" validate:"required"`
+	Key        string                  `json:"key" validate:"required"`
+	Value      string                  `json:"value" validate:"required"` // plain value, encoded before saving in the secret
+	Targets    []*SecretRotationTarget `json:"targets" validate:"required,min=1,dive"`
+	UserId     int32                   `json:"-"`
+}
+
+type SecretRotationTarget struct {
+	AppId int `json:"appId" validate:"number,gt=0"`
+	EnvId int `json:"envId" validate:"number,gt=0"`
+}
+
+type SecretRotationJobDto struct {
+	Id         int                                 `json:"id"`
+	SecretName string                              `json:"secretName"`
+	Key        string                              `json:"key"`
+	Status     pipelineConfig.SecretRotationStatus `json:"status"`
+	Message    string                              `json:"message,omitempty"`
+	CreatedBy  int32                               `json:"createdBy"`
+	CreatedOn  time.Time                           `json:"createdOn"`
+	UpdatedOn  time.Time                           `json:"updatedOn"`
+	Pipelines  []*SecretRotationPipelineDto        `json:"pipelines,omitempty"`
+}
+
+type SecretRotationPipelineDto struct {
+	AppId              int                                 `json:"appId"`
+	EnvId              int                                 `json:"envId"`
+	PipelineId         int                                 `json:"pipelineId,omitempty"`
+	CiArtifactId       int                                 `json:"ciArtifactId,omitempty"`
+	PipelineOverrideId int                                 `json:"pipelineOverrideId,omitempty"`
+	ConfigLevel        string                              `json:"configLevel,omitempty"`
+	Status             pipelineConfig.SecretRotationStatus `json:"status"`
+	Message            string                              `json:"message,omitempty"`
+	UpdatedOn          time.Time                           `json:"updatedOn"`
+}
+
+type SecretRotationService interface {
+	// RotateSecret saves a rotation job and updates the key and redeploys the affected pipelines in background,
+	// progress is tracked on the job
+	RotateSecret(request *SecretRotationRequest) (*SecretRotationJobDto, error)
+	GetRotationJob(id int) (*SecretRotationJobDto, error)
+}
+
+type SecretRotationServiceImpl struct {
+	logger                     *zap.SugaredLogger
+	secretRotationRepository   pipelineConfig.SecretRotationRepository
+	configMapService           ConfigMapService
+	pipelineRepository         pipelineConfig.PipelineRepository
+	pipelineOverrideRepository chartConfig.PipelineOverrideRepository
+	workflowDagExecutor        WorkflowDagExecutor
+	tokenCache                 *util3.TokenCache
+}
+
+func NewSecretRotationServiceImpl(logger *zap.SugaredLogger,
+	secretRotationRepository pipelineConfig.SecretRotationRepository,
+	configMapService ConfigMapService,
+	pipelineRepository pipelineConfig.PipelineRepository,
+	pipelineOverrideRepository chartConfig.PipelineOverrideRepository,
+	workflowDagExecutor WorkflowDagExecutor,
+	tokenCache *util3.TokenCache) *SecretRotationServiceImpl {
+	return &SecretRotationServiceImpl{
+		logger:                     logger,
+		secretRotationRepository:   secretRotationRepository,
+		configMapService:           configMapService,
+		pipelineRepository:         pipelineRepository,
+		pipelineOverrideRepository: pipelineOverrideRepository,
+		workflowDagExecutor:        workflowDagExecutor,
+		tokenCache:                 tokenCache,
+	}
+}
+
+func (impl SecretRotationServiceImpl) RotateSecret(request *SecretRotationRequest) (*SecretRotationJobDto, error) {
+	if len(request.Targets) == 0 {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: "no target for secret rotation", UserMessage: "select at least one app and environment"}
+	}
+	dbConnection := impl.secretRotationRepository.GetConnection()
+	tx, err := dbConnection.Begin()
+	if err != nil {
+		return nil, err
+	}
+	// Rollback tx on error.
+	defer tx.Rollback()
+
+	job := &pipelineConfig.SecretRotationJob{
+		SecretName: request.SecretName,
+		SecretKey:  request.Key,
+		Status:     pipelineConfig.SECRET_ROTATION_RUNNING,
+		AuditLog:   sql.AuditLog{CreatedOn: time.Now(), CreatedBy: request.UserId, UpdatedOn: time.Now(), UpdatedBy: request.UserId},
+	}
+	err = impl.secretRotationRepository.SaveJob(job, tx)
+	if err != nil {
+		impl.logger.Errorw("error in saving secret rotation job", "secretName", request.SecretName, "err", err)
+		return nil, err
+	}
+	var pipelines []*pipelineConfig.SecretRotationPipeline
+	added := make(map[string]bool)
+	for _, target := range request.Targets {
+		targetKey := fmt.Sprintf("%d/%d", target.AppId, target.EnvId)
+		if added[targetKey] {
+			continue
+		}
+		added[targetKey] = true
+		pipeline := &pipelineConfig.SecretRotationPipeline{
+			JobId:    job.Id,
+			AppId:    target.AppId,
+			EnvId:    target.EnvId,
+			Status:   pipelineConfig.SECRET_ROTATION_PENDING,
+			AuditLog: sql.AuditLog{CreatedOn: time.Now(), CreatedBy: request.UserId, UpdatedOn: time.Now(), UpdatedBy: request.UserId},
+		}
+		err = impl.secretRotationRepository.SavePipeline(pipeline, tx)
+		if err != nil {
+			impl.logger.Errorw("error in saving secret rotation pipeline", "jobId", job.Id, "appId", target.AppId, "envId", target.EnvId, "err", err)
+			return nil, err
+		}
+		pipelines = append(pipelines, pipeline)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	value := base64.StdEncoding.EncodeToString([]byte(request.Value))
+	go impl.processRotationJob(job, pipelines, value, request.UserId)
+	return impl.buildJobDto(job, pipelines), nil
+}
+
+// processRotationJob updates the key for every app and env and then redeploys the currently deployed artifact so that
+// pods pick up the new value, a failure of one pipeline does not stop the others
+func (impl SecretRotationServiceImpl) processRotationJob(job *pipelineConfig.SecretRotationJob, pipelines []*pipelineConfig.SecretRotationPipeline, value string, userId int32) {
+	ctx, err := impl.tokenCache.BuildACDSynchContext()
+	if err != nil {
+		impl.logger.Errorw("error in creating acd synch context", "jobId", job.Id, "err", err)
+	}
+	comment := fmt.Sprintf("rotated key %s by secret rotation job %d", job.SecretKey, job.Id)
+	var failed []string
+	for _, pipeline := range pipelines {
+		if err != nil {
+			impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_FAILED, err.Error(), userId)
+		} else {
+			impl.rotatePipeline(ctx, job, pipeline, value, comment, userId)
+		}
+		if pipeline.Status == pipelineConfig.SECRET_ROTATION_FAILED {
+			failed = append(failed, fmt.Sprintf("app %d env %d: %s", pipeline.AppId, pipeline.EnvId, pipeline.Message))
+		}
+	}
+	job.Status = pipelineConfig.SECRET_ROTATION_SUCCEEDED
+	if len(failed) == len(pipelines) {
+		job.Status = pipelineConfig.SECRET_ROTATION_FAILED
+	} else if len(failed) > 0 {
+		job.Status = pipelineConfig.SECRET_ROTATION_PARTIALLY_FAILED
+	}
+	if len(failed) > 0 {
+		job.Message = fmt.Sprintf("%d of %d pipelines failed; %s", len(failed), len(pipelines), strings.Join(failed, "; "))
+		impl.logger.Errorw("secret rotation failed for some pipelines", "jobId", job.Id, "failed", failed)
+	}
+	job.UpdatedOn = time.Now()
+	job.UpdatedBy = userId
+	err = impl.secretRotationRepository.UpdateJob(job)
+	if err != nil {
+		impl.logger.Errorw("error in updating secret rotation job", "jobId", job.Id, "err", err)
+	}
+}
+
+func (impl SecretRotationServiceImpl) rotatePipeline(ctx context.Context, job *pipelineConfig.SecretRotationJob, pipeline *pipelineConfig.SecretRotationPipeline, value string, comment string, userId int32) {
+	overrideCreated, err := impl.configMapService.CSRotateKey(pipeline.AppId, pipeline.EnvId, job.SecretName, job.SecretKey, value, comment, userId)
+	if err != nil {
+		impl.logger.Errorw("error in rotating secret key", "jobId", job.Id, "appId", pipeline.AppId, "envId", pipeline.EnvId, "err", err)
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_FAILED, err.Error(), userId)
+		return
+	}
+	pipeline.ConfigLevel = SecretConfigLevelEnv
+	if overrideCreated {
+		pipeline.ConfigLevel = SecretConfigLevelApp
+	}
+	impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, "", userId)
+
+	cdPipelines, err := impl.pipelineRepository.FindActiveByAppIdAndEnvironmentId(pipeline.AppId, pipeline.EnvId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching cd pipeline", "appId", pipeline.AppId, "envId", pipeline.EnvId, "err", err)
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_FAILED, err.Error(), userId)
+		return
+	} else if len(cdPipelines) == 0 {
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_SKIPPED, "no cd pipeline found for the environment", userId)
+		return
+	}
+	pipeline.PipelineId = cdPipelines[0].Id
+	release, err := impl.pipelineOverrideRepository.GetLatestRelease(pipeline.AppId, pipeline.EnvId)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching latest release", "appId", pipeline.AppId, "envId", pipeline.EnvId, "err", err)
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_FAILED, err.Error(), userId)
+		return
+	} else if util.IsErrNoRows(err) {
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_SKIPPED, "not deployed yet, the new value is used on first deployment", userId)
+		return
+	} else if release.DeploymentType == models.DEPLOYMENTTYPE_STOP {
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_SKIPPED, "app is hibernated, the new value is used on start", userId)
+		return
+	}
+	pipeline.CiArtifactId = release.CiArtifactId
+	overrideRequest := &bean.ValuesOverrideRequest{
+		PipelineId:     pipeline.PipelineId,
+		AppId:          pipeline.AppId,
+		CiArtifactId:   release.CiArtifactId,
+		UserId:         userId,
+		CdWorkflowType: bean.CD_WORKFLOW_TYPE_DEPLOY,
+		DeploymentType: models.DEPLOYMENTTYPE_DEPLOY,
+	}
+	releaseId, err := impl.workflowDagExecutor.ManualCdTrigger(overrideRequest, ctx)
+	if err != nil {
+		impl.logger.Errorw("error in redeploying after secret rotation", "jobId", job.Id, "pipelineId", pipeline.PipelineId, "err", err)
+		impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_FAILED, err.Error(), userId)
+		return
+	}
+	pipeline.PipelineOverrideId = releaseId
+	impl.updatePipelineStatus(pipeline, pipelineConfig.SECRET_ROTATION_TRIGGERED, "", userId)
+}
+
+func (impl SecretRotationServiceImpl) updatePipelineStatus(pipeline *pipelineConfig.SecretRotationPipeline, status pipelineConfig.SecretRotationStatus, message string, userId int32) {
+	pipeline.Status = status
+	pipeline.Message = message
+	pipeline.UpdatedOn = time.Now()
+	pipeline.UpdatedBy = userId
+	err := impl.secretRotationRepository.UpdatePipeline(pipeline)
+	if err != nil {
+		impl.logger.Errorw("error in updating secret rotation pipeline", "id", pipeline.Id, "status", status, "err", err)
+	}
+}
+
+func (impl SecretRotationServiceImpl) GetRotationJob(id int) (*SecretRotationJobDto, error) {
+	job, err := impl.secretRotationRepository.FindJobById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching secret rotation job", "id", id, "err", err)
+		return nil, err
+	}
+	pipelines, err := impl.secretRotationRepository.FindPipelinesByJobId(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching secret rotation pipelines", "jobId", id, "err", err)
+		return nil, err
+	}
+	return impl.buildJobDto(job, pipelines), nil
+}
+
+func (impl SecretRotationServiceImpl) buildJobDto(job *pipelineConfig.SecretRotationJob, pipelines []*pipelineConfig.SecretRotationPipeline) *SecretRotationJobDto {
+	dto := &SecretRotationJobDto{
+		Id:         job.Id,
+		SecretName: job.SecretName,
+		Key:        job.SecretKey,
+		Status:     job.Status,
+		Message:    job.Message,
+		CreatedBy:  job.CreatedBy,
+		CreatedOn:  job.CreatedOn,
+		UpdatedOn:  job.UpdatedOn,
+	}
+	for _, pipeline := range pipelines {
+		dto.Pipelines = append(dto.Pipelines, &SecretRotationPipelineDto{
+			AppId:              pipeline.AppId,
+			EnvId:              pipeline.EnvId,
+			PipelineId:         pipeline.PipelineId,
+			CiArtifactId:       pipeline.CiArtifactId,
+			PipelineOverrideId: pipeline.PipelineOverrideId,
+			ConfigLevel:        pipeline.ConfigLevel,
+			Status:             pipeline.Status,
+			Message:            pipeline.Message,
+			UpdatedOn:          pipeline.UpdatedOn,
+		})
+	}
+	return dto
+}
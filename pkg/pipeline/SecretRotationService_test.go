@@ -0,0 +1,295 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/internal/sql/models"
+	"github.com/devtron-labs/devtron/internal/sql/repository/chartConfig"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/user"
+	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/go-pg/pg"
+)
+
+// environments of the rotation stubs, each one ends the rotation of a pipeline in a different way
+const (
+	rotationEnvDeployed = iota + 1
+	rotationEnvAppLevelSecret
+	rotationEnvKeyNotUpdated
+	rotationEnvNoCdPipeline
+	rotationEnvNotDeployed
+	rotationEnvHibernated
+	rotationEnvTriggerFailed
+)
+
+// rotationRepositoryStub keeps the last status update of every pipeline and the job
+type rotationRepositoryStub struct {
+	pipelineConfig.SecretRotationRepository
+	statuses map[int][]pipelineConfig.SecretRotationStatus
+	job      *pipelineConfig.SecretRotationJob
+}
+
+func (impl *rotationRepositoryStub) UpdatePipeline(pipeline *pipelineConfig.SecretRotationPipeline) error {
+	impl.statuses[pipeline.EnvId] = append(impl.statuses[pipeline.EnvId], pipeline.Status)
+	return nil
+}
+
+func (impl *rotationRepositoryStub) UpdateJob(job *pipelineConfig.SecretRotationJob) error {
+	impl.job = job
+	return nil
+}
+
+type rotationConfigMapServiceStub struct {
+	ConfigMapService
+}
+
+func (impl rotationConfigMapServiceStub) CSRotateKey(appId int, envId int, name string, key string, value string, comment string, userId int32) (bool, error) {
+	if envId == rotationEnvKeyNotUpdated {
+		return false, fmt.Errorf("key %s not found in secret %s", key, name)
+	}
+	return envId == rotationEnvAppLevelSecret, nil
+}
+
+// rotationPipelineRepositoryStub gives the cd pipeline of an env the id env*10
+type rotationPipelineRepositoryStub struct {
+	pipelineConfig.PipelineRepository
+}
+
+func (impl rotationPipelineRepositoryStub) FindActiveByAppIdAndEnvironmentId(appId int, environmentId int) ([]*pipelineConfig.Pipeline, error) {
+	if environmentId == rotationEnvNoCdPipeline {
+		return nil, pg.ErrNoRows
+	}
+	return []*pipelineConfig.Pipeline{{Id: environmentId * 10, AppId: appId, EnvironmentId: environmentId}}, nil
+}
+
+type rotationPipelineOverrideRepositoryStub struct {
+	chartConfig.PipelineOverrideRepository
+}
+
+func (impl rotationPipelineOverrideRepositoryStub) GetLatestRelease(appId, environmentId int) (*chartConfig.PipelineOverride, error) {
+	switch environmentId {
+	case rotationEnvNotDeployed:
+		return nil, pg.ErrNoRows
+	case rotationEnvHibernated:
+		return &chartConfig.PipelineOverride{CiArtifactId: 5, DeploymentType: models.DEPLOYMENTTYPE_STOP}, nil
+	}
+	return &chartConfig.PipelineOverride{CiArtifactId: 5, DeploymentType: models.DEPLOYMENTTYPE_DEPLOY}, nil
+}
+
+// rotationWorkflowDagExecutorStub records the triggers and returns the release id pipeline+1000
+type rotationWorkflowDagExecutorStub struct {
+	WorkflowDagExecutor
+	triggered []*bean.ValuesOverrideRequest
+}
+
+func (impl *rotationWorkflowDagExecutorStub) ManualCdTrigger(overrideRequest *bean.ValuesOverrideRequest, ctx context.Context) (int, error) {
+	if overrideRequest.PipelineId == rotationEnvTriggerFailed*10 {
+		return 0, fmt.Errorf("argo cd unreachable")
+	}
+	impl.triggered = append(impl.triggered, overrideRequest)
+	return overrideRequest.PipelineId + 1000, nil
+}
+
+type rotationUserAuthServiceStub struct {
+	user.UserAuthService
+	err error
+}
+
+func (impl rotationUserAuthServiceStub) HandleLogin(username string, password string) (string, error) {
+	return "token", impl.err
+}
+
+func newSecretRotationServiceStub(repository *rotationRepositoryStub, executor *rotationWorkflowDagExecutorStub, loginErr error) SecretRotationServiceImpl {
+	logger := util.NewSugardLogger()
+	return SecretRotationServiceImpl{
+		logger:                     logger,
+		secretRotationRepository:   repository,
+		configMapService:           rotationConfigMapServiceStub{},
+		pipelineRepository:         rotationPipelineRepositoryStub{},
+		pipelineOverrideRepository: rotationPipelineOverrideRepositoryStub{},
+		workflowDagExecutor:        executor,
+		tokenCache:                 util3.NewTokenCache(logger, &util3.ACDAuthConfig{}, rotationUserAuthServiceStub{err: loginErr}),
+	}
+}
+
+func TestSecretRotationServiceImpl_rotatePipeline(t *testing.T) {
+	tests := []struct {
+		name            string
+		envId           int
+		wantStatuses    []pipelineConfig.SecretRotationStatus
+		wantConfigLevel string
+		wantOverrideId  int
+	}{
+		{
+			name:            "env override is updated and redeployed",
+			envId:           rotationEnvDeployed,
+			wantStatuses:    []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, pipelineConfig.SECRET_ROTATION_TRIGGERED},
+			wantConfigLevel: SecretConfigLevelEnv,
+			wantOverrideId:  rotationEnvDeployed*10 + 1000,
+		},
+		{
+			name:            "env override is created from the app level secret",
+			envId:           rotationEnvAppLevelSecret,
+			wantStatuses:    []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, pipelineConfig.SECRET_ROTATION_TRIGGERED},
+			wantConfigLevel: SecretConfigLevelApp,
+			wantOverrideId:  rotationEnvAppLevelSecret*10 + 1000,
+		},
+		{
+			name:         "key is not updated",
+			envId:        rotationEnvKeyNotUpdated,
+			wantStatuses: []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_FAILED},
+		},
+		{
+			name:            "no cd pipeline",
+			envId:           rotationEnvNoCdPipeline,
+			wantStatuses:    []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, pipelineConfig.SECRET_ROTATION_SKIPPED},
+			wantConfigLevel: SecretConfigLevelEnv,
+		},
+		{
+			name:            "not deployed yet",
+			envId:           rotationEnvNotDeployed,
+			wantStatuses:    []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, pipelineConfig.SECRET_ROTATION_SKIPPED},
+			wantConfigLevel: SecretConfigLevelEnv,
+		},
+		{
+			name:            "hibernated app is not started",
+			envId:           rotationEnvHibernated,
+			wantStatuses:    []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, pipelineConfig.SECRET_ROTATION_SKIPPED},
+			wantConfigLevel: SecretConfigLevelEnv,
+		},
+		{
+			name:            "redeploy fails",
+			envId:           rotationEnvTriggerFailed,
+			wantStatuses:    []pipelineConfig.SecretRotationStatus{pipelineConfig.SECRET_ROTATION_SECRET_UPDATED, pipelineConfig.SECRET_ROTATION_FAILED},
+			wantConfigLevel: SecretConfigLevelEnv,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &rotationRepositoryStub{statuses: map[int][]pipelineConfig.SecretRotationStatus{}}
+			executor := &rotationWorkflowDagExecutorStub{}
+			impl := newSecretRotationServiceStub(repository, executor, nil)
+			job := &pipelineConfig.SecretRotationJob{Id: 1, SecretName: "db-secret", SecretKey: "password"}
+			pipeline := &pipelineConfig.SecretRotationPipeline{JobId: 1, AppId: 3, EnvId: tt.envId}
+			impl.rotatePipeline(context.Background(), job, pipeline, "c2VjcmV0", "comment", 2)
+			if got := repository.statuses[tt.envId]; fmt.Sprint(got) != fmt.Sprint(tt.wantStatuses) {
+				t.Errorf("rotatePipeline() statuses = %v, want %v", got, tt.wantStatuses)
+			}
+			if pipeline.ConfigLevel != tt.wantConfigLevel {
+				t.Errorf("rotatePipeline() configLevel = %s, want %s", pipeline.ConfigLevel, tt.wantConfigLevel)
+			}
+			if pipeline.PipelineOverrideId != tt.wantOverrideId {
+				t.Errorf("rotatePipeline() pipelineOverrideId = %d, want %d", pipeline.PipelineOverrideId, tt.wantOverrideId)
+			}
+			if pipeline.Status == pipelineConfig.SECRET_ROTATION_FAILED && pipeline.Message == "" {
+				t.Errorf("rotatePipeline() failed without a message")
+			}
+			if tt.wantOverrideId != 0 {
+				if len(executor.triggered) != 1 || executor.triggered[0].CiArtifactId != 5 || executor.triggered[0].DeploymentType != models.DEPLOYMENTTYPE_DEPLOY {
+					t.Errorf("rotatePipeline() did not redeploy the latest artifact, triggered %v", executor.triggered)
+				}
+			} else if len(executor.triggered) != 0 {
+				t.Errorf("rotatePipeline() triggered %d deployments, want none", len(executor.triggered))
+			}
+		})
+	}
+}
+
+func TestSecretRotationServiceImpl_processRotationJob(t *testing.T) {
+	tests := []struct {
+		name          string
+		envIds        []int
+		loginErr      error
+		wantStatus    pipelineConfig.SecretRotationStatus
+		wantTriggered int
+	}{
+		{
+			name:          "all pipelines triggered",
+			envIds:        []int{rotationEnvDeployed, rotationEnvAppLevelSecret},
+			wantStatus:    pipelineConfig.SECRET_ROTATION_SUCCEEDED,
+			wantTriggered: 2,
+		},
+		{
+			name:       "skipped pipelines do not fail the job",
+			envIds:     []int{rotationEnvNoCdPipeline, rotationEnvNotDeployed, rotationEnvHibernated},
+			wantStatus: pipelineConfig.SECRET_ROTATION_SUCCEEDED,
+		},
+		{
+			name:          "a failed pipeline does not stop the others",
+			envIds:        []int{rotationEnvKeyNotUpdated, rotationEnvDeployed, rotationEnvTriggerFailed},
+			wantStatus:    pipelineConfig.SECRET_ROTATION_PARTIALLY_FAILED,
+			wantTriggered: 1,
+		},
+		{
+			name:       "all pipelines failed",
+			envIds:     []int{rotationEnvKeyNotUpdated, rotationEnvTriggerFailed},
+			wantStatus: pipelineConfig.SECRET_ROTATION_FAILED,
+		},
+		{
+			name:       "acd login fails",
+			envIds:     []int{rotationEnvDeployed, rotationEnvAppLevelSecret},
+			loginErr:   fmt.Errorf("invalid credentials"),
+			wantStatus: pipelineConfig.SECRET_ROTATION_FAILED,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repository := &rotationRepositoryStub{statuses: map[int][]pipelineConfig.SecretRotationStatus{}}
+			executor := &rotationWorkflowDagExecutorStub{}
+			impl := newSecretRotationServiceStub(repository, executor, tt.loginErr)
+			job := &pipelineConfig.SecretRotationJob{Id: 1, SecretName: "db-secret", SecretKey: "password", Status: pipelineConfig.SECRET_ROTATION_RUNNING}
+			var pipelines []*pipelineConfig.SecretRotationPipeline
+			for _, envId := range tt.envIds {
+				pipelines = append(pipelines, &pipelineConfig.SecretRotationPipeline{JobId: 1, AppId: 3, EnvId: envId, Status: pipelineConfig.SECRET_ROTATION_PENDING})
+			}
+			impl.processRotationJob(job, pipelines, "c2VjcmV0", 2)
+			if repository.job == nil {
+				t.Fatalf("processRotationJob() did not update the job")
+			}
+			if repository.job.Status != tt.wantStatus {
+				t.Errorf("processRotationJob() status = %s, want %s", repository.job.Status, tt.wantStatus)
+			}
+			if (tt.wantStatus == pipelineConfig.SECRET_ROTATION_SUCCEEDED) != (repository.job.Message == "") {
+				t.Errorf("processRotationJob() message = %q", repository.job.Message)
+			}
+			if len(executor.triggered) != tt.wantTriggered {
+				t.Errorf("processRotationJob() triggered %d deployments, want %d", len(executor.triggered), tt.wantTriggered)
+			}
+			for _, pipeline := range pipelines {
+				if pipeline.Status == pipelineConfig.SECRET_ROTATION_PENDING || pipeline.Status == pipelineConfig.SECRET_ROTATION_SECRET_UPDATED {
+					t.Errorf("processRotationJob() left env %d in status %s", pipeline.EnvId, pipeline.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestSecretRotationServiceImpl_RotateSecretWithoutTargets(t *testing.T) {
+	impl := newSecretRotationServiceStub(&rotationRepositoryStub{}, &rotationWorkflowDagExecutorStub{}, nil)
+	_, err := impl.RotateSecret(&SecretRotationRequest{SecretName: "db-secret", Key: "password", Value: "secret"})
+	apiErr, ok := err.(*util.ApiError)
+	if !ok || apiErr.HttpStatusCode != http.StatusBadRequest {
+		t.Errorf("RotateSecret() error = %v, want bad request", err)
+	}
+}
@@ -28,6 +28,8 @@ import (
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	util2 "github.com/devtron-labs/devtron/internal/util"
 	"github.com/devtron-labs/devtron/pkg/app"
+	"github.com/devtron-labs/devtron/pkg/imageSigning"
+	"github.com/devtron-labs/devtron/pkg/sbom"
 	"github.com/devtron-labs/devtron/pkg/sql"
 	"github.com/devtron-labs/devtron/util/event"
 	"go.uber.org/zap"
@@ -37,13 +39,16 @@ import (
 )
 
 type CiArtifactWebhookRequest struct {
-	Image        string          `json:"image"`
-	ImageDigest  string          `json:"imageDigest"`
-	MaterialInfo json.RawMessage `json:"materialInfo"`
-	DataSource   string          `json:"dataSource"`
-	PipelineName string          `json:"pipelineName"`
-	WorkflowId   *int            `json:"workflowId"`
-	UserId       int32           `json:"userId"`
+	Image           string            `json:"image"`
+	ImageDigest     string            `json:"imageDigest"`
+	PlatformDigests map[string]string `json:"platformDigests"` //platform to digest, sent for multi-arch builds
+	MaterialInfo    json.RawMessage   `json:"materialInfo"`
+	DataSource      string            `json:"dataSource"`
+	PipelineName    string            `json:"pipelineName"`
+	WorkflowId      *int              `json:"workflowId"`
+	UserId          int32             `json:"userId"`
+	Sbom            json.RawMessage   `json:"sbom"` //CycloneDX or SPDX json, sent when the pipeline has sbom or scan enabled
+	SbomLocation    string            `json:"-"`    //sbom uploaded by the ci runner to the build logs bucket
 }
 
 type WebhookService interface {
@@ -61,6 +66,8 @@ type WebhookServiceImpl struct {
 	eventFactory         client.EventFactory
 	workflowDagExecutor  WorkflowDagExecutor
 	ciHandler            CiHandler
+	sbomService          sbom.SbomService
+	imageSigningService  imageSigning.ImageSigningService
 }
 
 func NewWebhookServiceImpl(
@@ -70,7 +77,8 @@ func NewWebhookServiceImpl(
 	appService app.AppService, eventClient client.EventClient,
 	eventFactory client.EventFactory,
 	ciWorkflowRepository pipelineConfig.CiWorkflowRepository,
-	workflowDagExecutor WorkflowDagExecutor, ciHandler CiHandler) *WebhookServiceImpl {
+	workflowDagExecutor WorkflowDagExecutor, ciHandler CiHandler, sbomService sbom.SbomService,
+	imageSigningService imageSigning.ImageSigningService) *WebhookServiceImpl {
 	return &WebhookServiceImpl{
 		ciArtifactRepository: ciArtifactRepository,
 		logger:               logger,
@@ -81,6 +89,8 @@ func NewWebhookServiceImpl(
 		ciWorkflowRepository: ciWorkflowRepository,
 		workflowDagExecutor:  workflowDagExecutor,
 		ciHandler:            ciHandler,
+		sbomService:          sbomService,
+		imageSigningService:  imageSigningService,
 	}
 }
 
@@ -152,15 +162,16 @@ func (impl WebhookServiceImpl) SaveCiArtifactWebhook(ciPipelineId int, request *
 	}
 	materialJson = dst.Bytes()
 	artifact := &repository.CiArtifact{
-		Image:        request.Image,
-		ImageDigest:  request.ImageDigest,
-		MaterialInfo: string(materialJson),
-		DataSource:   request.DataSource,
-		PipelineId:   pipeline.Id,
-		WorkflowId:   request.WorkflowId,
-		ScanEnabled:  pipeline.ScanEnabled,
-		Scanned:      false,
-		AuditLog:     sql.AuditLog{CreatedBy: request.UserId, UpdatedBy: request.UserId, CreatedOn: time.Now(), UpdatedOn: time.Now()},
+		Image:           request.Image,
+		ImageDigest:     request.ImageDigest,
+		PlatformDigests: request.PlatformDigests,
+		MaterialInfo:    string(materialJson),
+		DataSource:      request.DataSource,
+		PipelineId:      pipeline.Id,
+		WorkflowId:      request.WorkflowId,
+		ScanEnabled:     pipeline.ScanEnabled,
+		Scanned:         false,
+		AuditLog:        sql.AuditLog{CreatedBy: request.UserId, UpdatedBy: request.UserId, CreatedOn: time.Now(), UpdatedOn: time.Now()},
 	}
 	if pipeline.ScanEnabled {
 		artifact.Scanned = true
@@ -169,6 +180,19 @@ func (impl WebhookServiceImpl) SaveCiArtifactWebhook(ciPipelineId int, request *
 		impl.logger.Errorw("error in saving material", "err", err)
 		return 0, err
 	}
+	// a missing or bad sbom should not fail the build, the artifact is usable without it
+	sbomDocument := request.Sbom
+	if len(sbomDocument) == 0 && len(request.SbomLocation) > 0 && request.WorkflowId != nil {
+		sbomDocument, err = impl.ciHandler.FetchCiWorkflowSbom(*request.WorkflowId, request.SbomLocation)
+		if err != nil {
+			impl.logger.Errorw("error in fetching sbom for artifact", "workflowId", *request.WorkflowId, "location", request.SbomLocation, "err", err)
+		}
+	}
+	if len(sbomDocument) > 0 {
+		if err = impl.sbomService.SaveSbom(artifact, sbomDocument, request.UserId); err != nil {
+			impl.logger.Errorw("error in saving sbom for artifact", "ciArtifactId", artifact.Id, "err", err)
+		}
+	}
 
 	childrenCi, err := impl.ciPipelineRepository.FindByParentCiPipelineId(ciPipelineId)
 	if err != nil && !util2.IsErrNoRows(err) {
@@ -181,6 +205,7 @@ func (impl WebhookServiceImpl) SaveCiArtifactWebhook(ciPipelineId int, request *
 		ciArtifact := &repository.CiArtifact{
 			Image:            request.Image,
 			ImageDigest:      request.ImageDigest,
+			PlatformDigests:  request.PlatformDigests,
 			MaterialInfo:     string(materialJson),
 			DataSource:       request.DataSource,
 			PipelineId:       ci.Id,
@@ -205,6 +230,13 @@ func (impl WebhookServiceImpl) SaveCiArtifactWebhook(ciPipelineId int, request *
 	}
 	ciArtifactArr = append(ciArtifactArr, artifact)
 
+	//only images built by our ci are signed, externally pushed images have to be signed elsewhere
+	if !pipeline.IsExternal {
+		if err = impl.imageSigningService.SignArtifacts(ciArtifactArr, request.UserId); err != nil {
+			impl.logger.Errorw("error in signing ci artifacts", "ciArtifactId", artifact.Id, "err", err)
+		}
+	}
+
 	go impl.WriteCISuccessEvent(request, pipeline, artifact)
 
 	impl.ciHandler.WriteToCreateTestSuites(pipeline.Id, *request.WorkflowId, int(request.UserId))
@@ -26,6 +26,7 @@ import (
 	"github.com/devtron-labs/devtron/pkg/sql"
 	"github.com/devtron-labs/devtron/pkg/user/casbin"
 	util3 "github.com/devtron-labs/devtron/pkg/util"
+	"net/http"
 	"strconv"
 	"strings"
 	"time"
@@ -42,6 +43,8 @@ import (
 	"github.com/devtron-labs/devtron/internal/util"
 	"github.com/devtron-labs/devtron/pkg/app"
 	bean2 "github.com/devtron-labs/devtron/pkg/bean"
+	"github.com/devtron-labs/devtron/pkg/imageSigning"
+	security2 "github.com/devtron-labs/devtron/pkg/security"
 	"github.com/devtron-labs/devtron/pkg/user"
 	util2 "github.com/devtron-labs/devtron/util/event"
 	"github.com/devtron-labs/devtron/util/rbac"
@@ -62,6 +65,8 @@ type WorkflowDagExecutor interface {
 	TriggerBulkDeploymentAsync(requests []*BulkTriggerRequest, UserId int32) (interface{}, error)
 	StopStartApp(stopRequest *StopAppRequest, ctx context.Context) (int, error)
 	TriggerBulkHibernateAsync(request StopDeploymentGroupRequest, ctx context.Context) (interface{}, error)
+	HandleDeploymentApprovalSuccess(pipelineId int, ciArtifactId int, triggeredBy int32) error
+	TriggerAutoRollback(pipeline *pipelineConfig.Pipeline, artifact *repository.CiArtifact, pipelineOverrideId int, reason string) (int, error)
 }
 
 type WorkflowDagExecutorImpl struct {
@@ -86,18 +91,23 @@ type WorkflowDagExecutorImpl struct {
 	eventFactory               client.EventFactory
 	eventClient                client.EventClient
 	cvePolicyRepository        security.CvePolicyRepository
+	cveExceptionRepository     security.CveExceptionRepository
 	scanResultRepository       security.ImageScanResultRepository
 	appWorkflowRepository      appWorkflow.AppWorkflowRepository
+	deploymentApprovalService  DeploymentApprovalService
+	deploymentWindowService    DeploymentWindowService
+	imageSigningService        imageSigning.ImageSigningService
 }
 
 type CiArtifactDTO struct {
-	Id                   int    `json:"id"`
-	PipelineId           int    `json:"pipelineId"` //id of the ci pipeline from which this webhook was triggered
-	Image                string `json:"image"`
-	ImageDigest          string `json:"imageDigest"`
-	MaterialInfo         string `json:"materialInfo"` //git material metadata json array string
-	DataSource           string `json:"dataSource"`
-	WorkflowId           *int   `json:"workflowId"`
+	Id                   int               `json:"id"`
+	PipelineId           int               `json:"pipelineId"` //id of the ci pipeline from which this webhook was triggered
+	Image                string            `json:"image"`
+	ImageDigest          string            `json:"imageDigest"`
+	PlatformDigests      map[string]string `json:"platformDigests,omitempty"` //platform to digest, set for multi-arch images
+	MaterialInfo         string            `json:"materialInfo"`              //git material metadata json array string
+	DataSource           string            `json:"dataSource"`
+	WorkflowId           *int              `json:"workflowId"`
 	ciArtifactRepository repository.CiArtifactRepository
 }
 
@@ -139,8 +149,12 @@ func NewWorkflowDagExecutorImpl(Logger *zap.SugaredLogger, pipelineRepository pi
 	enforcer casbin.Enforcer, enforcerUtil rbac.EnforcerUtil, tokenCache *util3.TokenCache,
 	acdAuthConfig *util3.ACDAuthConfig, eventFactory client.EventFactory,
 	eventClient client.EventClient, cvePolicyRepository security.CvePolicyRepository,
+	cveExceptionRepository security.CveExceptionRepository,
 	scanResultRepository security.ImageScanResultRepository,
-	appWorkflowRepository appWorkflow.AppWorkflowRepository) *WorkflowDagExecutorImpl {
+	appWorkflowRepository appWorkflow.AppWorkflowRepository,
+	deploymentApprovalService DeploymentApprovalService,
+	deploymentWindowService DeploymentWindowService,
+	imageSigningService imageSigning.ImageSigningService) *WorkflowDagExecutorImpl {
 	wde := &WorkflowDagExecutorImpl{logger: Logger,
 		pipelineRepository:         pipelineRepository,
 		cdWorkflowRepository:       cdWorkflowRepository,
@@ -162,8 +176,12 @@ func NewWorkflowDagExecutorImpl(Logger *zap.SugaredLogger, pipelineRepository pi
 		eventFactory:               eventFactory,
 		eventClient:                eventClient,
 		cvePolicyRepository:        cvePolicyRepository,
+		cveExceptionRepository:     cveExceptionRepository,
 		scanResultRepository:       scanResultRepository,
 		appWorkflowRepository:      appWorkflowRepository,
+		deploymentApprovalService:  deploymentApprovalService,
+		deploymentWindowService:    deploymentWindowService,
+		imageSigningService:        imageSigningService,
 	}
 	err := wde.Subscribe()
 	if err != nil {
@@ -242,6 +260,11 @@ func (impl *WorkflowDagExecutorImpl) triggerStage(cdWf *pipelineConfig.CdWorkflo
 	if len(pipeline.PreStageConfig) > 0 {
 		// pre stage exists
 		if pipeline.PreTriggerType == pipelineConfig.TRIGGER_TYPE_AUTOMATIC {
+			//approval gate applies before the pre stage, approval will re-trigger it
+			pendingApproval, err := impl.holdForApproval(pipeline, artifact, triggeredBy)
+			if err != nil || pendingApproval {
+				return err
+			}
 			impl.logger.Debugw("trigger pre stage for pipeline", "artifactId", artifact.Id, "pipelineId", pipeline.Id)
 			err = impl.TriggerPreStage(cdWf, artifact, pipeline, artifact.UpdatedBy, applyAuth) //TODO handle error here
 			return err
@@ -259,6 +282,10 @@ func (impl *WorkflowDagExecutorImpl) triggerStageForBulk(cdWf *pipelineConfig.Cd
 	var err error
 	if len(pipeline.PreStageConfig) > 0 {
 		//pre stage exists
+		pendingApproval, err := impl.holdForApproval(pipeline, artifact, triggeredBy)
+		if err != nil || pendingApproval {
+			return err
+		}
 		impl.logger.Debugw("trigger pre stage for pipeline", "artifactId", artifact.Id, "pipelineId", pipeline.Id)
 		err = impl.TriggerPreStage(cdWf, artifact, pipeline, artifact.UpdatedBy, applyAuth) //TODO handle error here
 		return err
@@ -567,13 +594,14 @@ func (impl *WorkflowDagExecutorImpl) buildWFRequest(runner *pipelineConfig.CdWor
 		DockerRegistryType:    string(ciPipeline.CiTemplate.DockerRegistry.RegistryType),
 		DockerRegistryURL:     ciPipeline.CiTemplate.DockerRegistry.RegistryURL,
 		CiArtifactDTO: CiArtifactDTO{
-			Id:           artifact.Id,
-			PipelineId:   artifact.PipelineId,
-			Image:        artifact.Image,
-			ImageDigest:  artifact.ImageDigest,
-			MaterialInfo: artifact.MaterialInfo,
-			DataSource:   artifact.DataSource,
-			WorkflowId:   artifact.WorkflowId,
+			Id:              artifact.Id,
+			PipelineId:      artifact.PipelineId,
+			Image:           artifact.Image,
+			ImageDigest:     artifact.ImageDigest,
+			PlatformDigests: artifact.PlatformDigests,
+			MaterialInfo:    artifact.MaterialInfo,
+			DataSource:      artifact.DataSource,
+			WorkflowId:      artifact.WorkflowId,
 		},
 		OrchestratorHost:          impl.cdConfig.OrchestratorHost,
 		OrchestratorToken:         impl.cdConfig.OrchestratorToken,
@@ -690,6 +718,12 @@ func (impl *WorkflowDagExecutorImpl) TriggerDeployment(cdWf *pipelineConfig.CdWo
 		}
 	}
 
+	//deployment is held back until the artifact is approved, approval will re-trigger it
+	pendingApproval, err := impl.holdForApproval(pipeline, artifact, triggeredBy)
+	if err != nil || pendingApproval {
+		return err
+	}
+
 	if cdWf == nil {
 		cdWf = &pipelineConfig.CdWorkflow{
 			CiArtifactId: artifact.Id,
@@ -712,7 +746,7 @@ func (impl *WorkflowDagExecutorImpl) TriggerDeployment(cdWf *pipelineConfig.CdWo
 		Namespace:    impl.cdConfig.DefaultNamespace,
 		CdWorkflowId: cdWf.Id,
 	}
-	err := impl.cdWorkflowRepository.SaveWorkFlowRunner(runner)
+	err = impl.cdWorkflowRepository.SaveWorkFlowRunner(runner)
 	if err != nil {
 		return err
 	}
@@ -721,7 +755,7 @@ func (impl *WorkflowDagExecutorImpl) TriggerDeployment(cdWf *pipelineConfig.CdWo
 	isVulnerable := false
 	if len(artifact.ImageDigest) > 0 {
 		var cveStores []*security.CveStore
-		imageScanResult, err := impl.scanResultRepository.FindByImageDigest(artifact.ImageDigest)
+		imageScanResult, err := impl.scanResultRepository.FindByImageDigests(artifact.ScanDigests())
 		if err != nil && err != pg.ErrNoRows {
 			impl.logger.Errorw("error fetching image digest", "digest", artifact.ImageDigest, "err", err)
 			return err
@@ -734,7 +768,7 @@ func (impl *WorkflowDagExecutorImpl) TriggerDeployment(cdWf *pipelineConfig.CdWo
 			impl.logger.Errorw("error while fetching env", "err", err)
 			return err
 		}
-		blockCveList, err := impl.cvePolicyRepository.GetBlockedCVEList(cveStores, env.ClusterId, pipeline.EnvironmentId, pipeline.AppId, false)
+		blockCveList, err := impl.getBlockedCves(cveStores, env.ClusterId, pipeline.EnvironmentId, pipeline.AppId)
 		if err != nil {
 			impl.logger.Errorw("error while fetching blocked cve list", "err", err)
 			return err
@@ -759,6 +793,31 @@ func (impl *WorkflowDagExecutorImpl) TriggerDeployment(cdWf *pipelineConfig.CdWo
 		return nil
 	}
 
+	verified, err := impl.verifyImageSignature(runner, artifact, pipeline)
+	if err != nil {
+		return err
+	} else if !verified {
+		return nil
+	}
+
+	//deployment windows are evaluated at the time of actual trigger, auto and bulk deployments can not override them
+	windowResult, err := impl.deploymentWindowService.CheckDeploymentAllowed(pipeline.AppId, pipeline.EnvironmentId, time.Now())
+	if err != nil {
+		impl.logger.Errorw("error in checking deployment window", "pipelineId", pipeline.Id, "err", err)
+		return err
+	}
+	if !windowResult.Allowed {
+		runner.Status = WorkflowFailed
+		runner.Message = windowResult.Reason
+		runner.FinishedOn = time.Now()
+		err = impl.cdWorkflowRepository.UpdateWorkFlowRunner(runner)
+		if err != nil {
+			impl.logger.Errorw("error in updating status", "err", err)
+			return err
+		}
+		return nil
+	}
+
 	err = impl.appService.TriggerCD(artifact, cdWf.Id, pipeline, async)
 	err1 := impl.updatePreviousDeploymentStatus(runner, pipeline.Id, err)
 	if err1 != nil || err != nil {
@@ -768,6 +827,53 @@ func (impl *WorkflowDagExecutorImpl) TriggerDeployment(cdWf *pipelineConfig.CdWo
 	return nil
 }
 
+// verifyImageSignature records the signature verification on the runner, a blocked image fails the runner
+func (impl *WorkflowDagExecutorImpl) verifyImageSignature(runner *pipelineConfig.CdWorkflowRunner, artifact *repository.CiArtifact, pipeline *pipelineConfig.Pipeline) (bool, error) {
+	clusterId := 0
+	if pipeline.Environment.Id > 0 {
+		clusterId = pipeline.Environment.ClusterId
+	} else {
+		env, err := impl.envRepository.FindById(pipeline.EnvironmentId)
+		if err != nil {
+			impl.logger.Errorw("error while fetching env", "envId", pipeline.EnvironmentId, "err", err)
+			return false, err
+		}
+		clusterId = env.ClusterId
+	}
+	verification, err := impl.imageSigningService.VerifyArtifact(artifact, clusterId, pipeline.EnvironmentId, pipeline.AppId)
+	if err != nil {
+		impl.logger.Errorw("error in verifying image signature", "ciArtifactId", artifact.Id, "pipelineId", pipeline.Id, "err", err)
+		return false, err
+	}
+	runner.ImageSignatureStatus = verification.Status
+	runner.ImageSignatureMessage = verification.Message
+	if !verification.Allowed {
+		runner.Status = WorkflowFailed
+		runner.Message = verification.Message
+		runner.FinishedOn = time.Now()
+	}
+	err = impl.cdWorkflowRepository.UpdateWorkFlowRunner(runner)
+	if err != nil {
+		impl.logger.Errorw("error in updating status", "err", err)
+		return false, err
+	}
+	return verification.Allowed, nil
+}
+
+// getBlockedCves leaves out the cves excepted for the app in the env, same as the vulnerability check of the policy service
+func (impl *WorkflowDagExecutorImpl) getBlockedCves(cveStores []*security.CveStore, clusterId int, envId int, appId int) ([]*security.CveStore, error) {
+	blockedCves, err := impl.cvePolicyRepository.GetBlockedCVEList(cveStores, clusterId, envId, appId, false)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := impl.cveExceptionRepository.FindActive(envId, appId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching cve exceptions", "envId", envId, "appId", appId, "err", err)
+		return nil, err
+	}
+	return security2.RemoveExceptedCves(blockedCves, exceptions), nil
+}
+
 func (impl *WorkflowDagExecutorImpl) updatePreviousDeploymentStatus(currentRunner *pipelineConfig.CdWorkflowRunner, pipelineId int, err error) error {
 	if err != nil {
 		impl.logger.Errorw("error in triggering cd WF, setting wf status as fail ", "wfId", currentRunner.Id, "err", err)
@@ -896,6 +1002,10 @@ func (impl *WorkflowDagExecutorImpl) ManualCdTrigger(overrideRequest *bean.Value
 			impl.logger.Errorw("err", "err", err)
 			return 0, err
 		}
+		err = impl.checkApprovedForManualTrigger(overrideRequest)
+		if err != nil {
+			return 0, err
+		}
 		err = impl.TriggerPreStage(nil, artifact, cdPipeline, overrideRequest.UserId, false)
 		if err != nil {
 			impl.logger.Errorw("err", "err", err)
@@ -905,6 +1015,15 @@ func (impl *WorkflowDagExecutorImpl) ManualCdTrigger(overrideRequest *bean.Value
 		if overrideRequest.DeploymentType == models.DEPLOYMENTTYPE_UNKNOWN {
 			overrideRequest.DeploymentType = models.DEPLOYMENTTYPE_DEPLOY
 		}
+		err = impl.checkApprovedForManualTrigger(overrideRequest)
+		if err != nil {
+			return 0, err
+		}
+		err = impl.checkDeploymentWindowForManualTrigger(cdPipeline, overrideRequest)
+		if err != nil {
+			impl.logger.Errorw("deployment window check failed", "req", overrideRequest, "err", err)
+			return 0, err
+		}
 		cdWf, err := impl.cdWorkflowRepository.FindByWorkflowIdAndRunnerType(overrideRequest.CdWorkflowId, bean.CD_WORKFLOW_TYPE_PRE)
 		if err != nil && !util.IsErrNoRows(err) {
 			impl.logger.Errorw("err", "err", err)
@@ -952,7 +1071,7 @@ func (impl *WorkflowDagExecutorImpl) ManualCdTrigger(overrideRequest *bean.Value
 		isVulnerable := false
 		if len(artifact.ImageDigest) > 0 {
 			var cveStores []*security.CveStore
-			imageScanResult, err := impl.scanResultRepository.FindByImageDigest(artifact.ImageDigest)
+			imageScanResult, err := impl.scanResultRepository.FindByImageDigests(artifact.ScanDigests())
 			if err != nil && err != pg.ErrNoRows {
 				impl.logger.Errorw("error fetching image digest", "digest", artifact.ImageDigest, "err", err)
 				return 0, err
@@ -960,7 +1079,7 @@ func (impl *WorkflowDagExecutorImpl) ManualCdTrigger(overrideRequest *bean.Value
 			for _, item := range imageScanResult {
 				cveStores = append(cveStores, &item.CveStore)
 			}
-			blockCveList, err := impl.cvePolicyRepository.GetBlockedCVEList(cveStores, cdPipeline.Environment.ClusterId, cdPipeline.EnvironmentId, cdPipeline.AppId, false)
+			blockCveList, err := impl.getBlockedCves(cveStores, cdPipeline.Environment.ClusterId, cdPipeline.EnvironmentId, cdPipeline.AppId)
 			if err != nil {
 				impl.logger.Errorw("error while fetching env", "err", err)
 				return 0, err
@@ -989,6 +1108,13 @@ func (impl *WorkflowDagExecutorImpl) ManualCdTrigger(overrideRequest *bean.Value
 			return 0, fmt.Errorf("found vulnerability for image digest %s", artifact.ImageDigest)
 		}
 
+		verified, err := impl.verifyImageSignature(runner, artifact, cdPipeline)
+		if err != nil {
+			return 0, err
+		} else if !verified {
+			return 0, fmt.Errorf("image signature verification failed, %s", runner.ImageSignatureMessage)
+		}
+
 		releaseId, err = impl.appService.TriggerRelease(overrideRequest, ctx)
 		//	return after error handling
 		/*if err != nil {
@@ -1035,9 +1161,54 @@ type BulkTriggerRequest struct {
 	PipelineId   int `sql:"pipeline_id"`
 }
 
+type BulkTriggerBlockedResponse struct {
+	PipelineId int    `json:"pipelineId"`
+	Reason     string `json:"reason"`
+}
+
+func (impl *WorkflowDagExecutorImpl) checkDeploymentWindowForManualTrigger(pipeline *pipelineConfig.Pipeline, overrideRequest *bean.ValuesOverrideRequest) error {
+	windowResult, err := impl.deploymentWindowService.CheckDeploymentAllowed(pipeline.AppId, pipeline.EnvironmentId, time.Now())
+	if err != nil {
+		return err
+	}
+	if windowResult.Allowed {
+		return nil
+	}
+	if overrideRequest.DeploymentWindowOverride {
+		isSuperAdmin, err := impl.user.IsSuperAdmin(int(overrideRequest.UserId))
+		if err != nil {
+			return err
+		}
+		if isSuperAdmin {
+			return impl.deploymentWindowService.SaveOverrideAudit(pipeline, overrideRequest.CiArtifactId, windowResult, overrideRequest.UserId, overrideRequest.DeploymentWindowOverrideReason)
+		}
+	}
+	return &util.ApiError{
+		HttpStatusCode:  http.StatusForbidden,
+		InternalMessage: windowResult.Reason,
+		UserMessage:     windowResult.Reason,
+	}
+}
+
+// TriggerBulkDeploymentAsync enqueues the requests which are inside their deployment windows and returns the blocked ones
 func (impl *WorkflowDagExecutorImpl) TriggerBulkDeploymentAsync(requests []*BulkTriggerRequest, UserId int32) (interface{}, error) {
 	var cdWorkflows []*pipelineConfig.CdWorkflow
+	blocked := make([]*BulkTriggerBlockedResponse, 0)
 	for _, request := range requests {
+		pipeline, err := impl.pipelineRepository.FindById(request.PipelineId)
+		if err != nil {
+			impl.logger.Errorw("error in fetching pipeline", "pipelineId", request.PipelineId, "err", err)
+			return nil, err
+		}
+		windowResult, err := impl.deploymentWindowService.CheckDeploymentAllowed(pipeline.AppId, pipeline.EnvironmentId, time.Now())
+		if err != nil {
+			impl.logger.Errorw("error in checking deployment window", "pipelineId", request.PipelineId, "err", err)
+			return nil, err
+		}
+		if !windowResult.Allowed {
+			blocked = append(blocked, &BulkTriggerBlockedResponse{PipelineId: request.PipelineId, Reason: windowResult.Reason})
+			continue
+		}
 		cdWf := &pipelineConfig.CdWorkflow{
 			CiArtifactId:   request.CiArtifactId,
 			PipelineId:     request.PipelineId,
@@ -1046,13 +1217,16 @@ func (impl *WorkflowDagExecutorImpl) TriggerBulkDeploymentAsync(requests []*Bulk
 		}
 		cdWorkflows = append(cdWorkflows, cdWf)
 	}
+	if len(cdWorkflows) == 0 {
+		return blocked, nil
+	}
 	err := impl.cdWorkflowRepository.SaveWorkFlows(cdWorkflows...)
 	if err != nil {
 		impl.logger.Errorw("error in saving wfs", "req", requests, "err", err)
 		return nil, err
 	}
 	impl.triggerNatsEventForBulkAction(cdWorkflows)
-	return nil, nil
+	return blocked, nil
 	//return
 	//publish nats async
 	//update status
@@ -1208,3 +1382,142 @@ func (impl *WorkflowDagExecutorImpl) subscribeHibernateBulkAction() error {
 func (impl *WorkflowDagExecutorImpl) buildACDSynchContext() (acdContext context.Context, err error) {
 	return impl.tokenCache.BuildACDSynchContext()
 }
+
+// holdForApproval raises an approval request and returns true when the artifact is not yet approved for the pipeline
+func (impl *WorkflowDagExecutorImpl) holdForApproval(pipeline *pipelineConfig.Pipeline, artifact *repository.CiArtifact, triggeredBy int32) (bool, error) {
+	isApproved, err := impl.deploymentApprovalService.CheckApproved(pipeline.Id, artifact.Id)
+	if err != nil {
+		impl.logger.Errorw("error in checking deployment approval", "pipelineId", pipeline.Id, "artifactId", artifact.Id, "err", err)
+		return false, err
+	}
+	if isApproved {
+		return false, nil
+	}
+	approvalRequest := &DeploymentApprovalTriggerRequest{PipelineId: pipeline.Id, CiArtifactId: artifact.Id, UserId: triggeredBy}
+	_, err = impl.deploymentApprovalService.RaiseApprovalRequest(approvalRequest)
+	if err != nil {
+		impl.logger.Errorw("error in raising deployment approval request", "pipelineId", pipeline.Id, "artifactId", artifact.Id, "err", err)
+		return false, err
+	}
+	impl.logger.Infow("deployment pending approval, skipping trigger", "pipelineId", pipeline.Id, "artifactId", artifact.Id)
+	return true, nil
+}
+
+func (impl *WorkflowDagExecutorImpl) checkApprovedForManualTrigger(overrideRequest *bean.ValuesOverrideRequest) error {
+	isApproved, err := impl.deploymentApprovalService.CheckApproved(overrideRequest.PipelineId, overrideRequest.CiArtifactId)
+	if err != nil {
+		impl.logger.Errorw("error in checking deployment approval", "req", overrideRequest, "err", err)
+		return err
+	}
+	if !isApproved {
+		return &util.ApiError{
+			HttpStatusCode:  http.StatusForbidden,
+			InternalMessage: "artifact is not approved for deployment on this pipeline",
+			UserMessage:     "artifact is not approved for deployment on this pipeline, raise an approval request first",
+		}
+	}
+	return nil
+}
+
+// HandleDeploymentApprovalSuccess resumes the automatic trigger, pre stage first if configured, held back for approval
+func (impl *WorkflowDagExecutorImpl) HandleDeploymentApprovalSuccess(pipelineId int, ciArtifactId int, triggeredBy int32) error {
+	pipeline, err := impl.pipelineRepository.FindById(pipelineId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching pipeline", "pipelineId", pipelineId, "err", err)
+		return err
+	}
+	isLatest, err := impl.isLatestForPipeline(pipelineId, ciArtifactId)
+	if err != nil {
+		return err
+	}
+	if !isLatest {
+		impl.logger.Infow("newer artifact deployed or approved on the pipeline, approval is only recorded", "pipelineId", pipelineId, "ciArtifactId", ciArtifactId)
+		return nil
+	}
+	artifact, err := impl.ciArtifactRepository.Get(ciArtifactId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching artifact", "ciArtifactId", ciArtifactId, "err", err)
+		return err
+	}
+	return impl.triggerStage(nil, pipeline, artifact, false, true, triggeredBy)
+}
+
+// isLatestForPipeline returns false when an artifact newer than the given one was deployed or approved on the pipeline,
+// approvals can take long and an approved artifact must not replace what was released after it
+func (impl *WorkflowDagExecutorImpl) isLatestForPipeline(pipelineId int, ciArtifactId int) (bool, error) {
+	wfr, err := impl.cdWorkflowRepository.FindLastStatusByPipelineIdAndRunnerType(pipelineId, bean.CD_WORKFLOW_TYPE_DEPLOY)
+	if err != nil && !util.IsErrNoRows(err) {
+		impl.logger.Errorw("error in fetching last deployment", "pipelineId", pipelineId, "err", err)
+		return false, err
+	}
+	if err == nil && wfr.CdWorkflow != nil && wfr.CdWorkflow.CiArtifactId > ciArtifactId {
+		return false, nil
+	}
+	approvals, err := impl.deploymentApprovalService.FetchApprovalRequests(pipelineId)
+	if err != nil {
+		return false, err
+	}
+	for _, approval := range approvals {
+		if approval.Status == pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED && approval.CiArtifactId > ciArtifactId {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// TriggerAutoRollback redeploys an earlier healthy release, artifact and values, as a system triggered rollback and returns the runner id.
+// Approval, deployment window and vulnerability gates are not applied as the artifact was already released on this pipeline.
+func (impl *WorkflowDagExecutorImpl) TriggerAutoRollback(pipeline *pipelineConfig.Pipeline, artifact *repository.CiArtifact, pipelineOverrideId int, reason string) (int, error) {
+	cdWf := &pipelineConfig.CdWorkflow{
+		CiArtifactId: artifact.Id,
+		PipelineId:   pipeline.Id,
+		AuditLog:     sql.AuditLog{CreatedOn: time.Now(), CreatedBy: 1, UpdatedOn: time.Now(), UpdatedBy: 1},
+	}
+	err := impl.cdWorkflowRepository.SaveWorkFlow(cdWf)
+	if err != nil {
+		impl.logger.Errorw("error in saving cd workflow for auto rollback", "pipelineId", pipeline.Id, "err", err)
+		return 0, err
+	}
+	runner := &pipelineConfig.CdWorkflowRunner{
+		Name:         pipeline.Name,
+		WorkflowType: bean.CD_WORKFLOW_TYPE_DEPLOY,
+		ExecutorType: pipelineConfig.WORKFLOW_EXECUTOR_TYPE_SYSTEM,
+		Status:       WorkflowStarting,
+		Message:      reason,
+		TriggeredBy:  1,
+		StartedOn:    time.Now(),
+		Namespace:    impl.cdConfig.DefaultNamespace,
+		CdWorkflowId: cdWf.Id,
+	}
+	err = impl.cdWorkflowRepository.SaveWorkFlowRunner(runner)
+	if err != nil {
+		impl.logger.Errorw("error in saving cd workflow runner for auto rollback", "pipelineId", pipeline.Id, "err", err)
+		return 0, err
+	}
+	overrideRequest := &bean.ValuesOverrideRequest{
+		PipelineId:                 pipeline.Id,
+		AppId:                      pipeline.AppId,
+		CiArtifactId:               artifact.Id,
+		CdWorkflowId:               cdWf.Id,
+		CdWorkflowType:             bean.CD_WORKFLOW_TYPE_DEPLOY,
+		DeploymentType:             models.DEPLOYMENTTYPE_ROLLBACK,
+		ForceTrigger:               true,
+		UserId:                     1,
+		RollbackPipelineOverrideId: pipelineOverrideId,
+	}
+	ctx, err := impl.buildACDSynchContext()
+	if err != nil {
+		impl.logger.Errorw("error in creating acd synch context", "pipelineId", pipeline.Id, "err", err)
+		return runner.Id, err
+	}
+	_, err = impl.appService.TriggerRelease(overrideRequest, ctx)
+	err1 := impl.updatePreviousDeploymentStatus(runner, pipeline.Id, err)
+	if err1 != nil || err != nil {
+		impl.logger.Errorw("error in triggering auto rollback", "err", err, "runner", runner, "pipelineId", pipeline.Id)
+		if err == nil {
+			err = err1
+		}
+		return runner.Id, err
+	}
+	return runner.Id, nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package pipeline
+
+import (
+	"testing"
+
+	"github.com/devtron-labs/devtron/api/bean"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/go-pg/pg"
+)
+
+// lastDeploymentRepositoryStub returns the last deployment of the pipeline, none when artifactId is 0
+type lastDeploymentRepositoryStub struct {
+	pipelineConfig.CdWorkflowRepository
+	artifactId int
+}
+
+func (impl lastDeploymentRepositoryStub) FindLastStatusByPipelineIdAndRunnerType(pipelineId int, runnerType bean.WorkflowType) (pipelineConfig.CdWorkflowRunner, error) {
+	if impl.artifactId == 0 {
+		return pipelineConfig.CdWorkflowRunner{}, pg.ErrNoRows
+	}
+	return pipelineConfig.CdWorkflowRunner{WorkflowType: runnerType, CdWorkflow: &pipelineConfig.CdWorkflow{PipelineId: pipelineId, CiArtifactId: impl.artifactId}}, nil
+}
+
+type approvalRequestsServiceStub struct {
+	DeploymentApprovalService
+	approvals []*DeploymentApprovalResponse
+}
+
+func (impl approvalRequestsServiceStub) FetchApprovalRequests(pipelineId int) ([]*DeploymentApprovalResponse, error) {
+	return impl.approvals, nil
+}
+
+func TestIsLatestForPipeline(t *testing.T) {
+	tests := []struct {
+		name               string
+		deployedArtifactId int
+		approvals          []*DeploymentApprovalResponse
+		want               bool
+	}{
+		{name: "nothing deployed or approved since", want: true},
+		{name: "older artifact deployed", deployedArtifactId: 5, want: true},
+		{name: "same artifact deployed", deployedArtifactId: 10, want: true},
+		{name: "newer artifact deployed", deployedArtifactId: 11, want: false},
+		{
+			name:      "newer artifact approved",
+			approvals: []*DeploymentApprovalResponse{{CiArtifactId: 12, Status: pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED}, {CiArtifactId: 10, Status: pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED}},
+			want:      false,
+		},
+		{
+			name:      "newer artifact only requested or rejected",
+			approvals: []*DeploymentApprovalResponse{{CiArtifactId: 12, Status: pipelineConfig.DEPLOYMENT_APPROVAL_REQUESTED}, {CiArtifactId: 11, Status: pipelineConfig.DEPLOYMENT_APPROVAL_REJECTED}},
+			want:      true,
+		},
+		{
+			name:      "older artifact approved",
+			approvals: []*DeploymentApprovalResponse{{CiArtifactId: 8, Status: pipelineConfig.DEPLOYMENT_APPROVAL_APPROVED}},
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl := &WorkflowDagExecutorImpl{
+				logger:                    util.NewSugardLogger(),
+				cdWorkflowRepository:      lastDeploymentRepositoryStub{artifactId: tt.deployedArtifactId},
+				deploymentApprovalService: approvalRequestsServiceStub{approvals: tt.approvals},
+			}
+			got, err := impl.isLatestForPipeline(1, 10)
+			if err != nil {
+				t.Fatalf("isLatestForPipeline() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("isLatestForPipeline() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
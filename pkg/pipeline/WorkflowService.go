@@ -32,6 +32,7 @@ import (
 	v12 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
 
@@ -67,8 +68,13 @@ type WorkflowRequest struct {
 	DockerConnection           string             `json:"dockerConnection"`
 	DockerCert                 string             `json:"dockerCert"`
 	DockerBuildArgs            string             `json:"dockerBuildArgs"`
+	SecretBuildArgs            bool               `json:"-"` //build args hold values read from vault, the event is passed through a secret
 	DockerRepository           string             `json:"dockerRepository"`
 	DockerFileLocation         string             `json:"dockerfileLocation"`
+	DockerBuildTargetPlatform  string             `json:"dockerBuildTargetPlatform"`
+	CiBuildType                string             `json:"ciBuildType"`  //DOCKERFILE or BUILDPACK
+	BuilderImage               string             `json:"builderImage"` //cnb builder, set for BUILDPACK builds
+	BuildContext               string             `json:"buildContext"` //source directory the buildpack builds from
 	DockerUsername             string             `json:"dockerUsername"`
 	DockerPassword             string             `json:"dockerPassword"`
 	AwsRegion                  string             `json:"awsRegion"`
@@ -90,6 +96,9 @@ type WorkflowRequest struct {
 	CiArtifactLocation         string             `json:"ciArtifactLocation"`
 	InvalidateCache            bool               `json:"invalidateCache"`
 	ScanEnabled                bool               `json:"scanEnabled"`
+	SbomEnabled                bool               `json:"sbomEnabled"`
+	SbomFormat                 string             `json:"sbomFormat"`
+	SbomLocation               string             `json:"sbomLocation"` //key in the build logs bucket the sbom is uploaded to
 	CloudProvider              string             `json:"cloudProvider"`
 	AzureBlobConfig            *AzureBlobConfig   `json:"azureBlobConfig"`
 	MinioEndpoint              string             `json:"minioEndpoint"`
@@ -185,7 +194,6 @@ func (impl *WorkflowServiceImpl) SubmitWorkflow(workflowRequest *WorkflowRequest
 		impl.Logger.Errorw("err", err)
 		return nil, err
 	}
-	impl.Logger.Debugw("workflowRequest ---->", "workflowJson", string(workflowJson))
 
 	wfClient, err := impl.getClientInstance(workflowRequest.Namespace)
 	if err != nil {
@@ -256,18 +264,91 @@ func (impl *WorkflowServiceImpl) SubmitWorkflow(workflowRequest *WorkflowRequest
 		ciWorkflow.Spec.NodeSelector = impl.ciConfig.NodeLabel
 	}
 
-	wfTemplate, err := json.Marshal(ciWorkflow)
-	if err != nil {
-		impl.Logger.Errorw("marshal error", "err", err)
+	var eventSecret *v12.Secret
+	if workflowRequest.SecretBuildArgs {
+		// secret values are not written to the workflow spec, the container reads the event from a secret
+		eventSecret, err = impl.createEventSecret(workflowRequest, workflowJson)
+		if err != nil {
+			return nil, err
+		}
+		container := ciWorkflow.Spec.Templates[0].Container
+		container.Env = append(container.Env, v12.EnvVar{
+			Name: ciCdEventEnv,
+			ValueFrom: &v12.EnvVarSource{
+				SecretKeyRef: &v12.SecretKeySelector{
+					LocalObjectReference: v12.LocalObjectReference{Name: eventSecret.Name},
+					Key:                  ciCdEventKey,
+				},
+			},
+		})
+		container.Args = []string{"$(" + ciCdEventEnv + ")"}
 	}
-	impl.Logger.Debug("---->", string(wfTemplate))
 
 	createdWf, err := wfClient.Create(&ciWorkflow) // submit the hello world workflow
-	impl.Logger.Debug("workflow submitted: " + createdWf.Name)
 	impl.checkErr(err)
+	if eventSecret != nil {
+		impl.ownEventSecret(eventSecret, createdWf, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	impl.Logger.Debug("workflow submitted: " + createdWf.Name)
 	return createdWf, err
 }
 
+const ciCdEventEnv = "CI_CD_EVENT"
+const ciCdEventKey = "event"
+
+func (impl *WorkflowServiceImpl) createEventSecret(workflowRequest *WorkflowRequest, workflowJson []byte) (*v12.Secret, error) {
+	client, err := kubernetes.NewForConfig(impl.config)
+	if err != nil {
+		impl.Logger.Errorw("cannot build k8s client", "err", err)
+		return nil, err
+	}
+	secret := &v12.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: workflowRequest.WorkflowNamePrefix + "-event-",
+		},
+		Data: map[string][]byte{ciCdEventKey: workflowJson},
+		Type: v12.SecretTypeOpaque,
+	}
+	createdSecret, err := client.CoreV1().Secrets(workflowRequest.Namespace).Create(secret)
+	if err != nil {
+		impl.Logger.Errorw("error in creating ci event secret", "workflowId", workflowRequest.WorkflowId, "err", err)
+		return nil, err
+	}
+	return createdSecret, nil
+}
+
+// ownEventSecret makes the workflow own the event secret so that it is deleted along with the workflow, the secret is
+// deleted right away if the workflow could not be submitted
+func (impl *WorkflowServiceImpl) ownEventSecret(secret *v12.Secret, workflow *v1alpha1.Workflow, submitErr error) {
+	client, err := kubernetes.NewForConfig(impl.config)
+	if err != nil {
+		impl.Logger.Errorw("cannot build k8s client", "err", err)
+		return
+	}
+	if submitErr != nil {
+		err = client.CoreV1().Secrets(secret.Namespace).Delete(secret.Name, &v1.DeleteOptions{})
+		if err != nil {
+			impl.Logger.Errorw("error in deleting ci event secret", "secret", secret.Name, "err", err)
+		}
+		return
+	}
+	ownerDelete := true
+	secret.OwnerReferences = []v1.OwnerReference{{
+		APIVersion:         "argoproj.io/v1alpha1",
+		Kind:               "Workflow",
+		Name:               workflow.Name,
+		UID:                workflow.UID,
+		BlockOwnerDeletion: &ownerDelete,
+	}}
+	_, err = client.CoreV1().Secrets(secret.Namespace).Update(secret)
+	if err != nil {
+		impl.Logger.Errorw("error in setting owner of ci event secret", "secret", secret.Name, "workflow", workflow.Name, "err", err)
+	}
+}
+
 func (impl *WorkflowServiceImpl) getClientInstance(namespace string) (v1alpha12.WorkflowInterface, error) {
 	clientSet, err := versioned.NewForConfig(impl.config)
 	if err != nil {
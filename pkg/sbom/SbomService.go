@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"go.uber.org/zap"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	SbomFormatCycloneDx = "cyclonedx-json"
+	SbomFormatSpdx      = "spdx-json"
+)
+
+type SbomDocument struct {
+	CiArtifactId int
+	AppId        int
+	Format       string
+	FileName     string
+	Content      []byte
+}
+
+type SbomService interface {
+	// SaveSbom parses the document generated by the ci workflow and stores it against the artifact along with its components
+	SaveSbom(artifact *repository.CiArtifact, document json.RawMessage, userId int32) error
+	GetSbomByArtifactId(artifactId int) (*SbomDocument, error)
+	FindDeployedArtifactsByPackage(name string, version string) ([]*security.SbomComponentDeployment, error)
+}
+
+type SbomServiceImpl struct {
+	logger                   *zap.SugaredLogger
+	ciArtifactSbomRepository security.CiArtifactSbomRepository
+	ciArtifactRepository     repository.CiArtifactRepository
+	ciPipelineRepository     pipelineConfig.CiPipelineRepository
+}
+
+func NewSbomServiceImpl(logger *zap.SugaredLogger, ciArtifactSbomRepository security.CiArtifactSbomRepository,
+	ciArtifactRepository repository.CiArtifactRepository, ciPipelineRepository pipelineConfig.CiPipelineRepository) *SbomServiceImpl {
+	return &SbomServiceImpl{
+		logger:                   logger,
+		ciArtifactSbomRepository: ciArtifactSbomRepository,
+		ciArtifactRepository:     ciArtifactRepository,
+		ciPipelineRepository:     ciPipelineRepository,
+	}
+}
+
+type cycloneDxComponent struct {
+	Type       string                `json:"type"`
+	Name       string                `json:"name"`
+	Version    string                `json:"version"`
+	Purl       string                `json:"purl"`
+	Components []*cycloneDxComponent `json:"components"`
+}
+
+type spdxPackage struct {
+	Name                  string `json:"name"`
+	VersionInfo           string `json:"versionInfo"`
+	PrimaryPackagePurpose string `json:"primaryPackagePurpose"`
+	ExternalRefs          []struct {
+		ReferenceType    string `json:"referenceType"`
+		ReferenceLocator string `json:"referenceLocator"`
+	} `json:"externalRefs"`
+}
+
+// sbomDocument holds the fields of both formats needed to tell them apart and list components
+type sbomDocument struct {
+	BomFormat   string                `json:"bomFormat"`
+	SpecVersion string                `json:"specVersion"`
+	Components  []*cycloneDxComponent `json:"components"`
+	SpdxVersion string                `json:"spdxVersion"`
+	Packages    []*spdxPackage        `json:"packages"`
+}
+
+func (impl SbomServiceImpl) SaveSbom(artifact *repository.CiArtifact, document json.RawMessage, userId int32) error {
+	format, specVersion, components, err := parseSbom(document)
+	if err != nil {
+		impl.logger.Errorw("error in parsing sbom", "ciArtifactId", artifact.Id, "err", err)
+		return err
+	}
+	sbom := &security.CiArtifactSbom{
+		CiArtifactId:   artifact.Id,
+		ImageDigest:    artifact.ImageDigest,
+		Format:         format,
+		SpecVersion:    specVersion,
+		Document:       string(document),
+		ComponentCount: len(components),
+		AuditLog:       sql.AuditLog{CreatedBy: userId, UpdatedBy: userId, CreatedOn: time.Now(), UpdatedOn: time.Now()},
+	}
+	err = impl.ciArtifactSbomRepository.Save(sbom, components)
+	if err != nil {
+		impl.logger.Errorw("error in saving sbom", "ciArtifactId", artifact.Id, "err", err)
+		return err
+	}
+	impl.logger.Infow("sbom saved", "ciArtifactId", artifact.Id, "format", format, "components", len(components))
+	return nil
+}
+
+// GetSbomByArtifactId resolves the sbom through the image digest so linked pipelines' artifacts of the same image share it
+func (impl SbomServiceImpl) GetSbomByArtifactId(artifactId int) (*SbomDocument, error) {
+	artifact, err := impl.ciArtifactRepository.Get(artifactId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching ci artifact", "ciArtifactId", artifactId, "err", err)
+		return nil, err
+	}
+	ciPipeline, err := impl.ciPipelineRepository.FindById(artifact.PipelineId)
+	if err != nil {
+		impl.logger.Errorw("error in fetching ci pipeline", "ciPipelineId", artifact.PipelineId, "err", err)
+		return nil, err
+	}
+	sbom, err := impl.ciArtifactSbomRepository.FindLatestByImageDigest(artifact.ImageDigest)
+	if util.IsErrNoRows(err) {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusNotFound, UserMessage: "no sbom generated for this artifact"}
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching sbom", "ciArtifactId", artifactId, "err", err)
+		return nil, err
+	}
+	extension := "cdx.json"
+	if sbom.Format == SbomFormatSpdx {
+		extension = "spdx.json"
+	}
+	return &SbomDocument{
+		CiArtifactId: artifactId,
+		AppId:        ciPipeline.AppId,
+		Format:       sbom.Format,
+		FileName:     fmt.Sprintf("sbom-%d.%s", artifactId, extension),
+		Content:      []byte(sbom.Document),
+	}, nil
+}
+
+func (impl SbomServiceImpl) FindDeployedArtifactsByPackage(name string, version string) ([]*security.SbomComponentDeployment, error) {
+	if len(strings.TrimSpace(name)) == 0 {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "package name is required"}
+	}
+	deployments, err := impl.ciArtifactSbomRepository.FindDeployedByComponent(name, version)
+	if err != nil && !util.IsErrNoRows(err) {
+		return nil, err
+	}
+	if deployments == nil {
+		deployments = make([]*security.SbomComponentDeployment, 0)
+	}
+	return deployments, nil
+}
+
+func parseSbom(document json.RawMessage) (format string, specVersion string, components []*security.CiArtifactSbomComponent, err error) {
+	doc := &sbomDocument{}
+	if err = json.Unmarshal(document, doc); err != nil {
+		return "", "", nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: "invalid sbom document"}
+	}
+	switch {
+	case doc.BomFormat == "CycloneDX":
+		var flatten func(items []*cycloneDxComponent)
+		flatten = func(items []*cycloneDxComponent) {
+			for _, item := range items {
+				components = append(components, &security.CiArtifactSbomComponent{Name: item.Name, Version: item.Version, Purl: item.Purl, Type: item.Type})
+				flatten(item.Components)
+			}
+		}
+		flatten(doc.Components)
+		return SbomFormatCycloneDx, doc.SpecVersion, components, nil
+	case len(doc.SpdxVersion) > 0:
+		for _, pkg := range doc.Packages {
+			component := &security.CiArtifactSbomComponent{Name: pkg.Name, Version: pkg.VersionInfo, Type: pkg.PrimaryPackagePurpose}
+			for _, ref := range pkg.ExternalRefs {
+				if ref.ReferenceType == "purl" {
+					component.Purl = ref.ReferenceLocator
+					break
+				}
+			}
+			components = append(components, component)
+		}
+		return SbomFormatSpdx, doc.SpdxVersion, components, nil
+	default:
+		return "", "", nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "unsupported sbom format, expected CycloneDX or SPDX json"}
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"fmt"
+	"github.com/caarlos0/env"
+	"github.com/devtron-labs/devtron/internal/sql/repository/app"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/cluster"
+	"github.com/devtron-labs/devtron/pkg/notifier"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	repository2 "github.com/devtron-labs/devtron/pkg/user/repository"
+	util2 "github.com/devtron-labs/devtron/pkg/util"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const cveExceptionExpiryJobName = "cve-exception-expiry"
+
+type CveExceptionConfig struct {
+	ExpiryCheckCron   string `env:"CVE_EXCEPTION_EXPIRY_CRON" envDefault:"0 * * * *"`
+	ExpiryNoticeHours int    `env:"CVE_EXCEPTION_EXPIRY_NOTICE_HOURS" envDefault:"72"`
+	MaxValidityDays   int    `env:"CVE_EXCEPTION_MAX_VALIDITY_DAYS" envDefault:"90"`
+}
+
+type CveExceptionRequest struct {
+	CveName       string    `json:"cveName" validate:"required"`
+	AppId         int       `json:"appId,omitempty"`
+	EnvId         int       `json:"envId" validate:"required,number,gt=0"`
+	Justification string    `json:"justification" validate:"required,min=10"`
+	ExpiresOn     time.Time `json:"expiresOn" validate:"required"`
+}
+
+type CveExceptionDto struct {
+	Id            int       `json:"id"`
+	CveName       string    `json:"cveName"`
+	AppId         int       `json:"appId,omitempty"`
+	EnvId         int       `json:"envId"`
+	Justification string    `json:"justification"`
+	Status        string    `json:"status"`
+	Expired       bool      `json:"expired"`
+	ExpiresOn     time.Time `json:"expiresOn"`
+	RequestedBy   int32     `json:"requestedBy"`
+	RequestedOn   time.Time `json:"requestedOn"`
+	ApprovedBy    int32     `json:"approvedBy,omitempty"`
+	ApprovedOn    time.Time `json:"approvedOn,omitempty"`
+}
+
+type CveExceptionService interface {
+	// RequestException records a pending exception, it is applied only once approved by another user
+	RequestException(request *CveExceptionRequest, userId int32) (*CveExceptionDto, error)
+	ApproveException(id int, userId int32) (*CveExceptionDto, error)
+	RejectException(id int, userId int32) (*CveExceptionDto, error)
+	RevokeException(id int, userId int32) (*CveExceptionDto, error)
+	GetException(id int) (*CveExceptionDto, error)
+	GetExceptions(filter *security.CveExceptionFilter) ([]*CveExceptionDto, error)
+	// NotifyExpiringExceptions mails requester and approver of exceptions about to expire, once per exception
+	NotifyExpiringExceptions()
+}
+
+type CveExceptionServiceImpl struct {
+	logger                  *zap.SugaredLogger
+	config                  *CveExceptionConfig
+	cveExceptionRepository  security.CveExceptionRepository
+	cveStoreRepository      security.CveStoreRepository
+	environmentService      cluster.EnvironmentService
+	appRepository           app.AppRepository
+	userRepository          repository2.UserRepository
+	smtpNotificationService notifier.SMTPNotificationService
+}
+
+func NewCveExceptionServiceImpl(logger *zap.SugaredLogger,
+	cveExceptionRepository security.CveExceptionRepository,
+	cveStoreRepository security.CveStoreRepository,
+	environmentService cluster.EnvironmentService,
+	appRepository app.AppRepository, userRepository repository2.UserRepository,
+	smtpNotificationService notifier.SMTPNotificationService,
+	scheduledJobRunner util2.ScheduledJobRunner) (*CveExceptionServiceImpl, error) {
+	cfg := &CveExceptionConfig{}
+	err := env.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	impl := &CveExceptionServiceImpl{
+		logger:                  logger,
+		config:                  cfg,
+		cveExceptionRepository:  cveExceptionRepository,
+		cveStoreRepository:      cveStoreRepository,
+		environmentService:      environmentService,
+		appRepository:           appRepository,
+		userRepository:          userRepository,
+		smtpNotificationService: smtpNotificationService,
+	}
+	err = scheduledJobRunner.Schedule(cveExceptionExpiryJobName, cfg.ExpiryCheckCron, impl.NotifyExpiringExceptions)
+	if err != nil {
+		logger.Errorw("error in starting cve exception expiry cron", "cron", cfg.ExpiryCheckCron, "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+func (impl *CveExceptionServiceImpl) RequestException(request *CveExceptionRequest, userId int32) (*CveExceptionDto, error) {
+	if !request.ExpiresOn.After(time.Now()) {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "expiry must be in the future"}
+	}
+	maxExpiry := time.Now().AddDate(0, 0, impl.config.MaxValidityDays)
+	if request.ExpiresOn.After(maxExpiry) {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("exception can not be valid for more than %d days", impl.config.MaxValidityDays)}
+	}
+	_, err := impl.cveStoreRepository.FindByName(request.CveName)
+	if err == pg.ErrNoRows {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("cve %s not found", request.CveName)}
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching cve", "cveName", request.CveName, "err", err)
+		return nil, err
+	}
+	exception := &security.CveException{
+		CveStoreName:  request.CveName,
+		AppId:         request.AppId,
+		EnvId:         request.EnvId,
+		Justification: request.Justification,
+		Status:        security.CveExceptionPending,
+		ExpiresOn:     request.ExpiresOn,
+		AuditLog:      sql.AuditLog{CreatedOn: time.Now(), CreatedBy: userId, UpdatedOn: time.Now(), UpdatedBy: userId},
+	}
+	if err = impl.cveExceptionRepository.Save(exception); err != nil {
+		impl.logger.Errorw("error in saving cve exception", "request", request, "err", err)
+		return nil, err
+	}
+	return impl.buildDto(exception), nil
+}
+
+func (impl *CveExceptionServiceImpl) ApproveException(id int, userId int32) (*CveExceptionDto, error) {
+	exception, err := impl.findPending(id)
+	if err != nil {
+		return nil, err
+	}
+	if exception.CreatedBy == userId {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusForbidden, UserMessage: "exception can not be approved by its requester"}
+	}
+	if !exception.ExpiresOn.After(time.Now()) {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "exception has already expired"}
+	}
+	exception.Status = security.CveExceptionApproved
+	exception.ApprovedBy = userId
+	exception.ApprovedOn = time.Now()
+	return impl.update(exception, userId)
+}
+
+func (impl *CveExceptionServiceImpl) RejectException(id int, userId int32) (*CveExceptionDto, error) {
+	exception, err := impl.findPending(id)
+	if err != nil {
+		return nil, err
+	}
+	exception.Status = security.CveExceptionRejected
+	return impl.update(exception, userId)
+}
+
+// RevokeException withdraws a pending or approved exception before its expiry, the cve is blocked again right away
+func (impl *CveExceptionServiceImpl) RevokeException(id int, userId int32) (*CveExceptionDto, error) {
+	exception, err := impl.cveExceptionRepository.FindById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching cve exception", "id", id, "err", err)
+		return nil, err
+	}
+	if exception.Status != security.CveExceptionPending && exception.Status != security.CveExceptionApproved {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("exception is already %s", exception.Status)}
+	}
+	exception.Status = security.CveExceptionRevoked
+	return impl.update(exception, userId)
+}
+
+func (impl *CveExceptionServiceImpl) findPending(id int) (*security.CveException, error) {
+	exception, err := impl.cveExceptionRepository.FindById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching cve exception", "id", id, "err", err)
+		return nil, err
+	}
+	if exception.Status != security.CveExceptionPending {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("exception is already %s", exception.Status)}
+	}
+	return exception, nil
+}
+
+func (impl *CveExceptionServiceImpl) update(exception *security.CveException, userId int32) (*CveExceptionDto, error) {
+	exception.UpdatedOn = time.Now()
+	exception.UpdatedBy = userId
+	err := impl.cveExceptionRepository.Update(exception)
+	if err != nil {
+		impl.logger.Errorw("error in updating cve exception", "id", exception.Id, "status", exception.Status, "err", err)
+		return nil, err
+	}
+	return impl.buildDto(exception), nil
+}
+
+func (impl *CveExceptionServiceImpl) GetException(id int) (*CveExceptionDto, error) {
+	exception, err := impl.cveExceptionRepository.FindById(id)
+	if err != nil {
+		impl.logger.Errorw("error in fetching cve exception", "id", id, "err", err)
+		return nil, err
+	}
+	return impl.buildDto(exception), nil
+}
+
+func (impl *CveExceptionServiceImpl) GetExceptions(filter *security.CveExceptionFilter) ([]*CveExceptionDto, error) {
+	exceptions, err := impl.cveExceptionRepository.FindByFilter(filter)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching cve exceptions", "filter", filter, "err", err)
+		return nil, err
+	}
+	dtos := make([]*CveExceptionDto, 0)
+	for _, exception := range exceptions {
+		dtos = append(dtos, impl.buildDto(exception))
+	}
+	return dtos, nil
+}
+
+func (impl *CveExceptionServiceImpl) buildDto(exception *security.CveException) *CveExceptionDto {
+	return &CveExceptionDto{
+		Id:            exception.Id,
+		CveName:       exception.CveStoreName,
+		AppId:         exception.AppId,
+		EnvId:         exception.EnvId,
+		Justification: exception.Justification,
+		Status:        exception.Status,
+		Expired:       !exception.ExpiresOn.After(time.Now()),
+		ExpiresOn:     exception.ExpiresOn,
+		RequestedBy:   exception.CreatedBy,
+		RequestedOn:   exception.CreatedOn,
+		ApprovedBy:    exception.ApprovedBy,
+		ApprovedOn:    exception.ApprovedOn,
+	}
+}
+
+func (impl *CveExceptionServiceImpl) NotifyExpiringExceptions() {
+	before := time.Now().Add(time.Duration(impl.config.ExpiryNoticeHours) * time.Hour)
+	exceptions, err := impl.cveExceptionRepository.FindExpiringBefore(before)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching expiring cve exceptions", "err", err)
+		return
+	}
+	for _, exception := range exceptions {
+		users, err := impl.userRepository.GetByIds([]int32{exception.CreatedBy, exception.ApprovedBy})
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in fetching users for cve exception", "id", exception.Id, "err", err)
+			continue
+		}
+		var recipients []string
+		for _, user := range users {
+			if user.Active && user.UserType != repository2.UserTypeApiToken {
+				recipients = append(recipients, user.EmailId)
+			}
+		}
+		if len(recipients) > 0 {
+			subject := fmt.Sprintf("CVE exception for %s expires on %s", exception.CveStoreName, exception.ExpiresOn.Format(time.RFC1123))
+			if err = impl.smtpNotificationService.SendMail(recipients, subject, impl.expiryMailBody(exception)); err != nil {
+				impl.logger.Errorw("error in sending cve exception expiry mail", "id", exception.Id, "err", err)
+				continue
+			}
+		}
+		exception.ExpiryNotified = true
+		exception.UpdatedOn = time.Now()
+		if err = impl.cveExceptionRepository.Update(exception); err != nil {
+			impl.logger.Errorw("error in marking cve exception notified", "id", exception.Id, "err", err)
+		}
+	}
+}
+
+func (impl *CveExceptionServiceImpl) expiryMailBody(exception *security.CveException) string {
+	scope := fmt.Sprintf("environment %d", exception.EnvId)
+	if env, err := impl.environmentService.FindById(exception.EnvId); err == nil {
+		scope = fmt.Sprintf("environment %s", env.Environment)
+	}
+	if exception.AppId > 0 {
+		if app, err := impl.appRepository.FindById(exception.AppId); err == nil {
+			scope = fmt.Sprintf("app %s in %s", app.AppName, scope)
+		}
+	}
+	return fmt.Sprintf("<p>The exception allowing %s for %s expires on %s.</p><p>Justification: %s</p><p>Images with this vulnerability will be blocked again after expiry unless a new exception is approved.</p>",
+		exception.CveStoreName, scope, exception.ExpiresOn.Format(time.RFC1123), exception.Justification)
+}
+
+// RemoveExceptedCves drops the cves covered by the given exceptions from a blocked list
+func RemoveExceptedCves(cves []*security.CveStore, exceptions []*security.CveException) []*security.CveStore {
+	if len(exceptions) == 0 {
+		return cves
+	}
+	excepted := make(map[string]bool)
+	for _, exception := range exceptions {
+		excepted[exception.CveStoreName] = true
+	}
+	var remaining []*security.CveStore
+	for _, cve := range cves {
+		if !excepted[cve.Name] {
+			remaining = append(remaining, cve)
+		}
+	}
+	return remaining
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package security
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+)
+
+func TestRemoveExceptedCves(t *testing.T) {
+	critical := &security.CveStore{Name: "CVE-2021-3156"}
+	high := &security.CveStore{Name: "CVE-2021-3449"}
+	tests := []struct {
+		name       string
+		cves       []*security.CveStore
+		exceptions []*security.CveException
+		want       []*security.CveStore
+	}{
+		{
+			name: "no exceptions keeps every cve",
+			cves: []*security.CveStore{critical, high},
+			want: []*security.CveStore{critical, high},
+		},
+		{
+			name:       "excepted cve is dropped",
+			cves:       []*security.CveStore{critical, high},
+			exceptions: []*security.CveException{{CveStoreName: "CVE-2021-3449"}},
+			want:       []*security.CveStore{critical},
+		},
+		{
+			name:       "exception for a cve not in the list",
+			cves:       []*security.CveStore{critical},
+			exceptions: []*security.CveException{{CveStoreName: "CVE-2020-1971"}},
+			want:       []*security.CveStore{critical},
+		},
+		{
+			name:       "every cve excepted",
+			cves:       []*security.CveStore{critical, high},
+			exceptions: []*security.CveException{{CveStoreName: "CVE-2021-3156"}, {CveStoreName: "CVE-2021-3449"}},
+			want:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RemoveExceptedCves(tt.cves, tt.exceptions); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RemoveExceptedCves() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"fmt"
+	"github.com/caarlos0/env"
+	"github.com/devtron-labs/devtron/api/bean"
+	client "github.com/devtron-labs/devtron/client/events"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	util2 "github.com/devtron-labs/devtron/pkg/util"
+	util "github.com/devtron-labs/devtron/util/event"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"time"
+)
+
+const imageRescanJobName = "image-rescan"
+
+type ImageRescanConfig struct {
+	RescanEnabled       bool   `env:"IMAGE_RESCAN_ENABLED" envDefault:"true"`
+	RescanCron          string `env:"IMAGE_RESCAN_CRON" envDefault:"0 2 * * *"`
+	RescanIntervalHours int    `env:"IMAGE_RESCAN_INTERVAL_HOURS" envDefault:"24"`
+}
+
+type ImageRescanService interface {
+	// RescanDeployedImages re-scans deployed images whose last scan is older than the interval, so that cves
+	// published after the build are found on running workloads. Newly blocked cves are notified per app+env.
+	RescanDeployedImages()
+}
+
+type ImageRescanServiceImpl struct {
+	logger                        *zap.SugaredLogger
+	config                        *ImageRescanConfig
+	imageScanDeployInfoRepository security.ImageScanDeployInfoRepository
+	scanHistoryRepository         security.ImageScanHistoryRepository
+	scanResultRepository          security.ImageScanResultRepository
+	ciTemplateRepository          pipelineConfig.CiTemplateRepository
+	pipelineRepository            pipelineConfig.PipelineRepository
+	policyService                 PolicyService
+	eventClient                   client.EventClient
+	eventFactory                  client.EventFactory
+}
+
+func NewImageRescanServiceImpl(logger *zap.SugaredLogger,
+	imageScanDeployInfoRepository security.ImageScanDeployInfoRepository,
+	scanHistoryRepository security.ImageScanHistoryRepository,
+	scanResultRepository security.ImageScanResultRepository,
+	ciTemplateRepository pipelineConfig.CiTemplateRepository,
+	pipelineRepository pipelineConfig.PipelineRepository, policyService PolicyService,
+	eventClient client.EventClient, eventFactory client.EventFactory,
+	scheduledJobRunner util2.ScheduledJobRunner) (*ImageRescanServiceImpl, error) {
+	cfg := &ImageRescanConfig{}
+	err := env.Parse(cfg)
+	if err != nil {
+		return nil, err
+	}
+	impl := &ImageRescanServiceImpl{
+		logger:                        logger,
+		config:                        cfg,
+		imageScanDeployInfoRepository: imageScanDeployInfoRepository,
+		scanHistoryRepository:         scanHistoryRepository,
+		scanResultRepository:          scanResultRepository,
+		ciTemplateRepository:          ciTemplateRepository,
+		pipelineRepository:            pipelineRepository,
+		policyService:                 policyService,
+		eventClient:                   eventClient,
+		eventFactory:                  eventFactory,
+	}
+	if !cfg.RescanEnabled {
+		return impl, nil
+	}
+	err = scheduledJobRunner.Schedule(imageRescanJobName, cfg.RescanCron, impl.RescanDeployedImages)
+	if err != nil {
+		logger.Errorw("error in starting image rescan cron", "cron", cfg.RescanCron, "err", err)
+		return nil, err
+	}
+	return impl, nil
+}
+
+func (impl *ImageRescanServiceImpl) RescanDeployedImages() {
+	deployInfos, err := impl.imageScanDeployInfoRepository.FindAll()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching image scan deploy info", "err", err)
+		return
+	}
+	impl.logger.Infow("re-scanning deployed images", "deployInfoCount", len(deployInfos))
+	for _, deployInfo := range deployInfos {
+		impl.rescanDeployInfo(deployInfo)
+	}
+}
+
+// rescanDeployInfo moves the deploy info to the latest scan of each of its images
+func (impl *ImageRescanServiceImpl) rescanDeployInfo(deployInfo *security.ImageScanDeployInfo) {
+	changed := false
+	var historyIds []int
+	var images []string
+	newlyBlocked := make(map[string]*security.CveStore)
+	for _, historyId := range deployInfo.ImageScanExecutionHistoryId {
+		latest, blocked, err := impl.rescanImage(deployInfo, historyId)
+		if err != nil {
+			impl.logger.Errorw("error in re-scanning image", "deployInfoId", deployInfo.Id, "historyId", historyId, "err", err)
+			historyIds = append(historyIds, historyId)
+			continue
+		}
+		historyIds = append(historyIds, latest.Id)
+		if latest.Id == historyId {
+			continue
+		}
+		changed = true
+		for _, cve := range blocked {
+			newlyBlocked[cve.Name] = cve
+		}
+		if len(blocked) > 0 {
+			images = append(images, latest.Image)
+		}
+	}
+	if !changed {
+		return
+	}
+	deployInfo.ImageScanExecutionHistoryId = historyIds
+	deployInfo.UpdatedOn = time.Now()
+	deployInfo.UpdatedBy = 1
+	err := impl.imageScanDeployInfoRepository.Update(deployInfo)
+	if err != nil {
+		impl.logger.Errorw("error in updating image scan deploy info", "id", deployInfo.Id, "err", err)
+		return
+	}
+	if len(newlyBlocked) > 0 {
+		impl.notifyNewlyBlocked(deployInfo, images, newlyBlocked)
+	}
+}
+
+// rescanImage returns the latest scan of the image along with the cves blocked in it but not in the previous scan.
+// The scanner stores a new execution history per scan, the result of an asynchronous scan is picked on the next run.
+func (impl *ImageRescanServiceImpl) rescanImage(deployInfo *security.ImageScanDeployInfo, historyId int) (*security.ImageScanExecutionHistory, []*security.CveStore, error) {
+	history, err := impl.scanHistoryRepository.FindOne(historyId)
+	if err != nil {
+		return nil, nil, err
+	}
+	latest, err := impl.findLatestHistory(history)
+	if err != nil {
+		return nil, nil, err
+	}
+	if latest.Id == history.Id {
+		if time.Since(history.ExecutionTime) < time.Duration(impl.config.RescanIntervalHours)*time.Hour {
+			return history, nil, nil
+		}
+		scanEvent := &ScanEvent{Image: history.Image, ImageDigest: history.ImageHash, EnvId: deployInfo.EnvId, UserId: 1}
+		if deployInfo.ObjectType != security.ScanObjectType_POD {
+			scanEvent.AppId = deployInfo.ScanObjectMetaId
+			ciTemplate, err := impl.ciTemplateRepository.FindByAppId(deployInfo.ScanObjectMetaId)
+			if err != nil && err != pg.ErrNoRows {
+				return nil, nil, err
+			} else if err == nil && ciTemplate.DockerRegistry != nil {
+				scanEvent.DockerRegistryId = ciTemplate.DockerRegistry.Id
+			}
+		}
+		if err = impl.policyService.SendEventToClairUtility(scanEvent); err != nil {
+			return nil, nil, err
+		}
+		latest, err = impl.findLatestHistory(history)
+		if err != nil {
+			return nil, nil, err
+		}
+		if latest.Id == history.Id {
+			return history, nil, nil
+		}
+	}
+	previousBlocked, err := impl.getBlockedCves(deployInfo, history.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	currentBlocked, err := impl.getBlockedCves(deployInfo, latest.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	var newlyBlocked []*security.CveStore
+	for name, cve := range currentBlocked {
+		if _, ok := previousBlocked[name]; !ok {
+			newlyBlocked = append(newlyBlocked, cve)
+		}
+	}
+	return latest, newlyBlocked, nil
+}
+
+func (impl *ImageRescanServiceImpl) findLatestHistory(history *security.ImageScanExecutionHistory) (*security.ImageScanExecutionHistory, error) {
+	var latest *security.ImageScanExecutionHistory
+	var err error
+	if len(history.ImageHash) > 0 {
+		latest, err = impl.scanHistoryRepository.FindByImageDigest(history.ImageHash)
+	} else {
+		latest, err = impl.scanHistoryRepository.FindByImage(history.Image)
+	}
+	if err == pg.ErrNoRows || (err == nil && latest.Id < history.Id) {
+		return history, nil
+	}
+	return latest, err
+}
+
+func (impl *ImageRescanServiceImpl) getBlockedCves(deployInfo *security.ImageScanDeployInfo, historyId int) (map[string]*security.CveStore, error) {
+	results, err := impl.scanResultRepository.FetchByScanExecutionId(historyId)
+	if err != nil && err != pg.ErrNoRows {
+		return nil, err
+	}
+	var cveStores []*security.CveStore
+	for _, result := range results {
+		cveStores = append(cveStores, &result.CveStore)
+	}
+	appId := 0
+	if deployInfo.ObjectType != security.ScanObjectType_POD {
+		appId = deployInfo.ScanObjectMetaId
+	}
+	blockedCves, err := impl.policyService.GetBlockedCVEList(cveStores, deployInfo.ClusterId, deployInfo.EnvId, appId, deployInfo.ObjectType == security.ScanObjectType_CHART)
+	if err != nil {
+		return nil, err
+	}
+	blocked := make(map[string]*security.CveStore)
+	for _, cve := range blockedCves {
+		blocked[cve.Name] = cve
+	}
+	return blocked, nil
+}
+
+// notifyNewlyBlocked raises the event on the cd pipelines of the app in the env, pods and helm charts are only logged
+func (impl *ImageRescanServiceImpl) notifyNewlyBlocked(deployInfo *security.ImageScanDeployInfo, images []string, newlyBlocked map[string]*security.CveStore) {
+	var names []string
+	for name, cve := range newlyBlocked {
+		names = append(names, fmt.Sprintf("%s (%s)", name, cve.Severity.String()))
+	}
+	sort.Strings(names)
+	impl.logger.Infow("newly blocked cves found on deployed images", "deployInfoId", deployInfo.Id, "objectType", deployInfo.ObjectType, "images", images, "cves", names)
+	if deployInfo.ObjectType != security.ScanObjectType_APP || deployInfo.EnvId == 0 {
+		return
+	}
+	pipelines, err := impl.pipelineRepository.FindActiveByAppIdAndEnvironmentId(deployInfo.ScanObjectMetaId, deployInfo.EnvId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching cd pipelines for vulnerability notification", "appId", deployInfo.ScanObjectMetaId, "envId", deployInfo.EnvId, "err", err)
+		return
+	}
+	for _, pipeline := range pipelines {
+		event := impl.eventFactory.Build(util.VulnerabilityFound, &pipeline.Id, pipeline.AppId, &pipeline.EnvironmentId, util.CD)
+		event.UserId = 1
+		event.CdWorkflowType = bean.CD_WORKFLOW_TYPE_DEPLOY
+		event.Payload = &client.Payload{
+			Stage:          string(bean.CD_WORKFLOW_TYPE_DEPLOY),
+			DockerImageUrl: strings.Join(images, ", "),
+			BlockedCves:    strings.Join(names, ", "),
+		}
+		_, evtErr := impl.eventClient.WriteEvent(event)
+		if evtErr != nil {
+			impl.logger.Errorw("error in writing vulnerability event", "pipelineId", pipeline.Id, "err", evtErr)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package security
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	client "github.com/devtron-labs/devtron/client/events"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	util "github.com/devtron-labs/devtron/util/event"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+)
+
+type scanHistoryRepositoryStub struct {
+	security.ImageScanHistoryRepository
+	histories map[int]*security.ImageScanExecutionHistory
+	// latestAfterScan is returned as the latest history once a scan event has been sent
+	latestAfterScan *security.ImageScanExecutionHistory
+	scanned         *bool
+}
+
+func (stub scanHistoryRepositoryStub) FindOne(id int) (*security.ImageScanExecutionHistory, error) {
+	history, ok := stub.histories[id]
+	if !ok {
+		return nil, pg.ErrNoRows
+	}
+	return history, nil
+}
+
+func (stub scanHistoryRepositoryStub) findLatest(match func(*security.ImageScanExecutionHistory) bool) (*security.ImageScanExecutionHistory, error) {
+	if *stub.scanned && stub.latestAfterScan != nil {
+		return stub.latestAfterScan, nil
+	}
+	var latest *security.ImageScanExecutionHistory
+	for _, history := range stub.histories {
+		if match(history) && (latest == nil || history.Id > latest.Id) {
+			latest = history
+		}
+	}
+	if latest == nil {
+		return nil, pg.ErrNoRows
+	}
+	return latest, nil
+}
+
+func (stub scanHistoryRepositoryStub) FindByImageDigest(digest string) (*security.ImageScanExecutionHistory, error) {
+	return stub.findLatest(func(history *security.ImageScanExecutionHistory) bool { return history.ImageHash == digest })
+}
+
+func (stub scanHistoryRepositoryStub) FindByImage(image string) (*security.ImageScanExecutionHistory, error) {
+	return stub.findLatest(func(history *security.ImageScanExecutionHistory) bool { return history.Image == image })
+}
+
+type scanResultRepositoryStub struct {
+	security.ImageScanResultRepository
+	cves map[int][]*security.CveStore
+}
+
+func (stub scanResultRepositoryStub) FetchByScanExecutionId(id int) ([]*security.ImageScanExecutionResult, error) {
+	var results []*security.ImageScanExecutionResult
+	for _, cve := range stub.cves[id] {
+		results = append(results, &security.ImageScanExecutionResult{CveStoreName: cve.Name, ImageScanExecutionHistoryId: id, CveStore: *cve})
+	}
+	return results, nil
+}
+
+// rescanPolicyServiceStub blocks every critical cve and records the scan events sent
+type rescanPolicyServiceStub struct {
+	PolicyService
+	scanEvents []*ScanEvent
+	scanned    *bool
+}
+
+func (stub *rescanPolicyServiceStub) GetBlockedCVEList(cves []*security.CveStore, clusterId, envId, appId int, isAppstore bool) ([]*security.CveStore, error) {
+	var blocked []*security.CveStore
+	for _, cve := range cves {
+		if cve.Severity == security.Critical {
+			blocked = append(blocked, cve)
+		}
+	}
+	return blocked, nil
+}
+
+func (stub *rescanPolicyServiceStub) SendEventToClairUtility(event *ScanEvent) error {
+	stub.scanEvents = append(stub.scanEvents, event)
+	*stub.scanned = true
+	return nil
+}
+
+type ciTemplateRepositoryStub struct {
+	pipelineConfig.CiTemplateRepository
+}
+
+func (stub ciTemplateRepositoryStub) FindByAppId(appId int) (*pipelineConfig.CiTemplate, error) {
+	return &pipelineConfig.CiTemplate{AppId: appId, DockerRegistry: &repository.DockerArtifactStore{Id: "docker-hub"}}, nil
+}
+
+func newRescanServiceForTest(histories []*security.ImageScanExecutionHistory, latestAfterScan *security.ImageScanExecutionHistory,
+	cves map[int][]*security.CveStore) (*ImageRescanServiceImpl, *rescanPolicyServiceStub) {
+	scanned := false
+	historyStub := scanHistoryRepositoryStub{histories: map[int]*security.ImageScanExecutionHistory{}, latestAfterScan: latestAfterScan, scanned: &scanned}
+	for _, history := range histories {
+		historyStub.histories[history.Id] = history
+	}
+	policyStub := &rescanPolicyServiceStub{scanned: &scanned}
+	impl := &ImageRescanServiceImpl{
+		logger:                zap.NewNop().Sugar(),
+		config:                &ImageRescanConfig{RescanEnabled: true, RescanIntervalHours: 24},
+		scanHistoryRepository: historyStub,
+		scanResultRepository:  scanResultRepositoryStub{cves: cves},
+		ciTemplateRepository:  ciTemplateRepositoryStub{},
+		policyService:         policyStub,
+	}
+	return impl, policyStub
+}
+
+func TestImageRescanService_rescanImage(t *testing.T) {
+	critical := &security.CveStore{Name: "CVE-2021-44228", Severity: security.Critical}
+	otherCritical := &security.CveStore{Name: "CVE-2022-22965", Severity: security.Critical}
+	low := &security.CveStore{Name: "CVE-2020-1971", Severity: security.Low}
+	image := "registry/app:1"
+	recent := &security.ImageScanExecutionHistory{Id: 1, Image: image, ImageHash: "sha256:a", ExecutionTime: time.Now().Add(-time.Hour)}
+	old := &security.ImageScanExecutionHistory{Id: 1, Image: image, ImageHash: "sha256:a", ExecutionTime: time.Now().Add(-48 * time.Hour)}
+	newer := &security.ImageScanExecutionHistory{Id: 2, Image: image, ImageHash: "sha256:a", ExecutionTime: time.Now()}
+	deployInfo := &security.ImageScanDeployInfo{Id: 10, ScanObjectMetaId: 3, ObjectType: security.ScanObjectType_APP, EnvId: 4, ClusterId: 1}
+	tests := []struct {
+		name            string
+		histories       []*security.ImageScanExecutionHistory
+		latestAfterScan *security.ImageScanExecutionHistory
+		cves            map[int][]*security.CveStore
+		wantLatestId    int
+		wantScanEvent   bool
+		wantBlocked     []string
+	}{
+		{
+			name:         "recent scan is not re-scanned",
+			histories:    []*security.ImageScanExecutionHistory{recent},
+			wantLatestId: 1,
+		},
+		{
+			name:          "old scan sends a scan event and keeps the history until the scanner stores the result",
+			histories:     []*security.ImageScanExecutionHistory{old},
+			wantLatestId:  1,
+			wantScanEvent: true,
+		},
+		{
+			name:            "old scan moves to the new result and reports the newly blocked cves",
+			histories:       []*security.ImageScanExecutionHistory{old},
+			latestAfterScan: newer,
+			cves:            map[int][]*security.CveStore{1: {critical, low}, 2: {critical, otherCritical, low}},
+			wantLatestId:    2,
+			wantScanEvent:   true,
+			wantBlocked:     []string{"CVE-2022-22965"},
+		},
+		{
+			name:         "newer scan from another run is picked without scanning again",
+			histories:    []*security.ImageScanExecutionHistory{old, newer},
+			cves:         map[int][]*security.CveStore{1: {low}, 2: {critical, low}},
+			wantLatestId: 2,
+			wantBlocked:  []string{"CVE-2021-44228"},
+		},
+		{
+			name:         "cves fixed by the new scan are not reported",
+			histories:    []*security.ImageScanExecutionHistory{old, newer},
+			cves:         map[int][]*security.CveStore{1: {critical, otherCritical}, 2: {critical}},
+			wantLatestId: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl, policyStub := newRescanServiceForTest(tt.histories, tt.latestAfterScan, tt.cves)
+			latest, blocked, err := impl.rescanImage(deployInfo, 1)
+			if err != nil {
+				t.Fatalf("rescanImage() error = %v", err)
+			}
+			if latest.Id != tt.wantLatestId {
+				t.Errorf("rescanImage() latest = %d, want %d", latest.Id, tt.wantLatestId)
+			}
+			if tt.wantScanEvent != (len(policyStub.scanEvents) == 1) {
+				t.Fatalf("rescanImage() scan events = %d, want scan event %v", len(policyStub.scanEvents), tt.wantScanEvent)
+			}
+			if tt.wantScanEvent {
+				scanEvent := policyStub.scanEvents[0]
+				if scanEvent.Image != image || scanEvent.ImageDigest != "sha256:a" || scanEvent.AppId != 3 || scanEvent.EnvId != 4 || scanEvent.DockerRegistryId != "docker-hub" {
+					t.Errorf("rescanImage() scan event = %+v", scanEvent)
+				}
+			}
+			var names []string
+			for _, cve := range blocked {
+				names = append(names, cve.Name)
+			}
+			sort.Strings(names)
+			if len(names) != len(tt.wantBlocked) {
+				t.Fatalf("rescanImage() blocked = %v, want %v", names, tt.wantBlocked)
+			}
+			for i := range names {
+				if names[i] != tt.wantBlocked[i] {
+					t.Errorf("rescanImage() blocked = %v, want %v", names, tt.wantBlocked)
+				}
+			}
+		})
+	}
+}
+
+type rescanPipelineRepositoryStub struct {
+	pipelineConfig.PipelineRepository
+	pipelines []*pipelineConfig.Pipeline
+}
+
+func (stub rescanPipelineRepositoryStub) FindActiveByAppIdAndEnvironmentId(appId int, environmentId int) ([]*pipelineConfig.Pipeline, error) {
+	var pipelines []*pipelineConfig.Pipeline
+	for _, pipeline := range stub.pipelines {
+		if pipeline.AppId == appId && pipeline.EnvironmentId == environmentId {
+			pipelines = append(pipelines, pipeline)
+		}
+	}
+	return pipelines, nil
+}
+
+type rescanEventFactoryStub struct {
+	client.EventFactory
+}
+
+func (stub rescanEventFactoryStub) Build(eventType util.EventType, sourceId *int, appId int, envId *int, pipelineType util.PipelineType) client.Event {
+	return client.Event{EventTypeId: int(eventType), PipelineId: *sourceId, AppId: appId, EnvId: *envId, PipelineType: string(pipelineType)}
+}
+
+type rescanEventClientStub struct {
+	client.EventClient
+	events []client.Event
+}
+
+func (stub *rescanEventClientStub) WriteEvent(event client.Event) (bool, error) {
+	stub.events = append(stub.events, event)
+	return true, nil
+}
+
+func TestImageRescanService_notifyNewlyBlocked(t *testing.T) {
+	newlyBlocked := map[string]*security.CveStore{
+		"CVE-2022-22965": {Name: "CVE-2022-22965", Severity: security.Critical},
+		"CVE-2021-44228": {Name: "CVE-2021-44228", Severity: security.Critical},
+	}
+	images := []string{"registry/app:1", "registry/sidecar:2"}
+	pipelines := []*pipelineConfig.Pipeline{
+		{Id: 7, AppId: 3, EnvironmentId: 4},
+		{Id: 8, AppId: 3, EnvironmentId: 4},
+		{Id: 9, AppId: 3, EnvironmentId: 5},
+	}
+	tests := []struct {
+		name            string
+		deployInfo      *security.ImageScanDeployInfo
+		wantPipelineIds []int
+	}{
+		{
+			name:            "app deployment notifies every cd pipeline of the env",
+			deployInfo:      &security.ImageScanDeployInfo{ScanObjectMetaId: 3, ObjectType: security.ScanObjectType_APP, EnvId: 4},
+			wantPipelineIds: []int{7, 8},
+		},
+		{
+			name:       "helm chart is only logged",
+			deployInfo: &security.ImageScanDeployInfo{ScanObjectMetaId: 3, ObjectType: security.ScanObjectType_CHART, EnvId: 4},
+		},
+		{
+			name:       "pod is only logged",
+			deployInfo: &security.ImageScanDeployInfo{ScanObjectMetaId: 3, ObjectType: security.ScanObjectType_POD, EnvId: 4},
+		},
+		{
+			name:       "app without env is only logged",
+			deployInfo: &security.ImageScanDeployInfo{ScanObjectMetaId: 3, ObjectType: security.ScanObjectType_APP},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eventClient := &rescanEventClientStub{}
+			impl := &ImageRescanServiceImpl{
+				logger:             zap.NewNop().Sugar(),
+				pipelineRepository: rescanPipelineRepositoryStub{pipelines: pipelines},
+				eventClient:        eventClient,
+				eventFactory:       rescanEventFactoryStub{},
+			}
+			impl.notifyNewlyBlocked(tt.deployInfo, images, newlyBlocked)
+			if len(eventClient.events) != len(tt.wantPipelineIds) {
+				t.Fatalf("notifyNewlyBlocked() events = %d, want %d", len(eventClient.events), len(tt.wantPipelineIds))
+			}
+			for i, event := range eventClient.events {
+				if event.PipelineId != tt.wantPipelineIds[i] || event.EventTypeId != int(util.VulnerabilityFound) || event.UserId != 1 {
+					t.Errorf("notifyNewlyBlocked() event = %+v", event)
+				}
+				if event.Payload.BlockedCves != "CVE-2021-44228 (critical), CVE-2022-22965 (critical)" {
+					t.Errorf("notifyNewlyBlocked() blockedCves = %q", event.Payload.BlockedCves)
+				}
+				if event.Payload.DockerImageUrl != "registry/app:1, registry/sidecar:2" {
+					t.Errorf("notifyNewlyBlocked() dockerImageUrl = %q", event.Payload.DockerImageUrl)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,536 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package security
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const (
+	TrendGroupByTeam = "team"
+	TrendGroupByApp  = "app"
+	TrendGroupByEnv  = "env"
+
+	TrendIntervalDay   = "day"
+	TrendIntervalWeek  = "week"
+	TrendIntervalMonth = "month"
+
+	TrendReportTrend    = "trend"
+	TrendReportPackages = "packages"
+
+	defaultTrendRangeDays = 30
+	defaultTopPackages    = 10
+	maxTrendPoints        = 366
+)
+
+type VulnerabilityTrendRequest struct {
+	TeamIds     []int     `json:"teamIds"`
+	AppIds      []int     `json:"appIds"`
+	EnvIds      []int     `json:"envIds"`
+	From        time.Time `json:"from"`
+	To          time.Time `json:"to"`
+	GroupBy     string    `json:"groupBy"`
+	Interval    string    `json:"interval"`
+	TopPackages int       `json:"topPackages"`
+}
+
+type VulnerabilityTrendResponse struct {
+	From        time.Time                  `json:"from"`
+	To          time.Time                  `json:"to"`
+	GroupBy     string                     `json:"groupBy"`
+	Interval    string                     `json:"interval"`
+	Groups      []*VulnerabilityTrendGroup `json:"groups"`
+	TopPackages []*VulnerablePackage       `json:"topPackages"`
+}
+
+type VulnerabilityTrendGroup struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	// Open is the count of cves in the images running at the end of the range
+	Open       *SeverityCount `json:"open"`
+	Introduced int            `json:"introduced"`
+	Fixed      int            `json:"fixed"`
+	// MeanTimeToRemediateHours is the mean time between the deployment which introduced a cve and the one which fixed it,
+	// for the cves fixed in the range
+	MeanTimeToRemediateHours float64                    `json:"meanTimeToRemediateHours"`
+	Trend                    []*VulnerabilityTrendPoint `json:"trend"`
+}
+
+type VulnerabilityTrendPoint struct {
+	Date       time.Time      `json:"date"`
+	Open       *SeverityCount `json:"open"`
+	Introduced int            `json:"introduced"`
+	Fixed      int            `json:"fixed"`
+}
+
+type VulnerablePackage struct {
+	Package     string `json:"package"`
+	Cves        int    `json:"cves"`
+	Critical    int    `json:"critical"`
+	Deployments int    `json:"deployments"`
+}
+
+type VulnerabilityTrendService interface {
+	// FetchDeployedArtifacts returns the deployments the trend is computed from, so that they can be filtered by rbac
+	FetchDeployedArtifacts(request *VulnerabilityTrendRequest) ([]*security.DeployedArtifact, error)
+	GetVulnerabilityTrend(request *VulnerabilityTrendRequest, deployments []*security.DeployedArtifact) (*VulnerabilityTrendResponse, error)
+	ExportVulnerabilityTrend(request *VulnerabilityTrendRequest, deployments []*security.DeployedArtifact, report string) ([]byte, error)
+}
+
+type VulnerabilityTrendServiceImpl struct {
+	logger                       *zap.SugaredLogger
+	vulnerabilityTrendRepository security.VulnerabilityTrendRepository
+	cveExceptionRepository       security.CveExceptionRepository
+}
+
+func NewVulnerabilityTrendServiceImpl(logger *zap.SugaredLogger,
+	vulnerabilityTrendRepository security.VulnerabilityTrendRepository,
+	cveExceptionRepository security.CveExceptionRepository) *VulnerabilityTrendServiceImpl {
+	return &VulnerabilityTrendServiceImpl{
+		logger:                       logger,
+		vulnerabilityTrendRepository: vulnerabilityTrendRepository,
+		cveExceptionRepository:       cveExceptionRepository,
+	}
+}
+
+// appEnvTimeline is the sequence of scanned images deployed on an app+env
+type appEnvTimeline struct {
+	appId       int
+	envId       int
+	groupId     int
+	groupName   string
+	deployments []*security.DeployedArtifact
+	// excepted are the cves with an active exception on the app+env, they are not counted as open
+	excepted map[string]bool
+}
+
+// imageScan is one scan of an image, cves are keyed by name
+type imageScan struct {
+	scannedOn time.Time
+	cves      map[string]*security.ImageCve
+}
+
+type trendBucket struct {
+	start time.Time
+	end   time.Time
+}
+
+func (impl VulnerabilityTrendServiceImpl) FetchDeployedArtifacts(request *VulnerabilityTrendRequest) ([]*security.DeployedArtifact, error) {
+	err := impl.validateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	filter := &security.VulnerabilityTrendFilter{
+		TeamIds: request.TeamIds,
+		AppIds:  request.AppIds,
+		EnvIds:  request.EnvIds,
+		To:      request.To,
+	}
+	return impl.vulnerabilityTrendRepository.FindDeployedArtifacts(filter)
+}
+
+func (impl VulnerabilityTrendServiceImpl) validateRequest(request *VulnerabilityTrendRequest) error {
+	if request.To.IsZero() {
+		request.To = time.Now()
+	}
+	if request.From.IsZero() {
+		request.From = request.To.AddDate(0, 0, -defaultTrendRangeDays)
+	}
+	if !request.From.Before(request.To) {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "from must be before to"}
+	}
+	if len(request.GroupBy) == 0 {
+		request.GroupBy = TrendGroupByApp
+	} else if request.GroupBy != TrendGroupByTeam && request.GroupBy != TrendGroupByApp && request.GroupBy != TrendGroupByEnv {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("unsupported groupBy %s", request.GroupBy)}
+	}
+	if len(request.Interval) == 0 {
+		request.Interval = TrendIntervalDay
+	} else if request.Interval != TrendIntervalDay && request.Interval != TrendIntervalWeek && request.Interval != TrendIntervalMonth {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("unsupported interval %s", request.Interval)}
+	}
+	if request.TopPackages <= 0 {
+		request.TopPackages = defaultTopPackages
+	}
+	if len(impl.getBuckets(request)) > maxTrendPoints {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: "date range is too large for the interval, use a larger interval"}
+	}
+	return nil
+}
+
+func (impl VulnerabilityTrendServiceImpl) getBuckets(request *VulnerabilityTrendRequest) []*trendBucket {
+	var buckets []*trendBucket
+	for start := request.From; start.Before(request.To) && len(buckets) <= maxTrendPoints; {
+		var end time.Time
+		switch request.Interval {
+		case TrendIntervalWeek:
+			end = start.AddDate(0, 0, 7)
+		case TrendIntervalMonth:
+			end = start.AddDate(0, 1, 0)
+		default:
+			end = start.AddDate(0, 0, 1)
+		}
+		if end.After(request.To) {
+			end = request.To
+		}
+		buckets = append(buckets, &trendBucket{start: start, end: end})
+		start = end
+	}
+	return buckets
+}
+
+func (impl VulnerabilityTrendServiceImpl) GetVulnerabilityTrend(request *VulnerabilityTrendRequest, deployments []*security.DeployedArtifact) (*VulnerabilityTrendResponse, error) {
+	err := impl.validateRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	scansByImage, err := impl.getScansByImage(deployments)
+	if err != nil {
+		return nil, err
+	}
+	timelines := impl.buildTimelines(request, deployments, scansByImage)
+	err = impl.setExceptedCves(timelines)
+	if err != nil {
+		return nil, err
+	}
+	buckets := impl.getBuckets(request)
+	groups := make(map[int]*VulnerabilityTrendGroup)
+	remediationTime := make(map[int]time.Duration)
+	packages := make(map[string]*VulnerablePackage)
+	packageCves := make(map[string]map[string]bool)
+	for _, timeline := range timelines {
+		group, ok := groups[timeline.groupId]
+		if !ok {
+			group = &VulnerabilityTrendGroup{Id: timeline.groupId, Name: timeline.groupName, Open: &SeverityCount{}}
+			for _, bucket := range buckets {
+				group.Trend = append(group.Trend, &VulnerabilityTrendPoint{Date: bucket.start, Open: &SeverityCount{}})
+			}
+			groups[timeline.groupId] = group
+		}
+		var open map[string]*security.ImageCve
+		firstSeen := make(map[string]time.Time)
+		for _, changedOn := range impl.getChangePoints(timeline, scansByImage, request.To) {
+			current := impl.getOpenCvesAt(timeline, scansByImage, changedOn)
+			point := impl.getTrendPoint(group, buckets, changedOn)
+			for name := range current {
+				if _, ok := open[name]; ok {
+					continue
+				}
+				firstSeen[name] = changedOn
+				if point != nil {
+					group.Introduced++
+					point.Introduced++
+				}
+			}
+			for name := range open {
+				if _, ok := current[name]; ok {
+					continue
+				}
+				if point != nil {
+					group.Fixed++
+					point.Fixed++
+					remediationTime[group.Id] += changedOn.Sub(firstSeen[name])
+				}
+				delete(firstSeen, name)
+			}
+			open = current
+		}
+		for i, bucket := range buckets {
+			impl.addSeverityCount(group.Trend[i].Open, impl.getOpenCvesAt(timeline, scansByImage, bucket.end))
+		}
+		impl.addSeverityCount(group.Open, open)
+		for _, cve := range open {
+			if len(cve.Package) == 0 {
+				continue
+			}
+			pkg, ok := packages[cve.Package]
+			if !ok {
+				pkg = &VulnerablePackage{Package: cve.Package}
+				packages[cve.Package] = pkg
+				packageCves[cve.Package] = make(map[string]bool)
+			}
+			if !packageCves[cve.Package][cve.Name] {
+				packageCves[cve.Package][cve.Name] = true
+				pkg.Cves++
+				if cve.Severity == security.Critical {
+					pkg.Critical++
+				}
+			}
+		}
+		for pkgName := range impl.getPackages(open) {
+			packages[pkgName].Deployments++
+		}
+	}
+	response := &VulnerabilityTrendResponse{
+		From:        request.From,
+		To:          request.To,
+		GroupBy:     request.GroupBy,
+		Interval:    request.Interval,
+		Groups:      make([]*VulnerabilityTrendGroup, 0, len(groups)),
+		TopPackages: make([]*VulnerablePackage, 0),
+	}
+	for _, group := range groups {
+		if group.Fixed > 0 {
+			hours := remediationTime[group.Id].Hours() / float64(group.Fixed)
+			group.MeanTimeToRemediateHours = math.Round(hours*100) / 100
+		}
+		response.Groups = append(response.Groups, group)
+	}
+	sort.Slice(response.Groups, func(i, j int) bool {
+		return response.Groups[i].Name < response.Groups[j].Name
+	})
+	for _, pkg := range packages {
+		response.TopPackages = append(response.TopPackages, pkg)
+	}
+	sort.Slice(response.TopPackages, func(i, j int) bool {
+		a, b := response.TopPackages[i], response.TopPackages[j]
+		if a.Critical != b.Critical {
+			return a.Critical > b.Critical
+		} else if a.Cves != b.Cves {
+			return a.Cves > b.Cves
+		} else if a.Deployments != b.Deployments {
+			return a.Deployments > b.Deployments
+		}
+		return a.Package < b.Package
+	})
+	if len(response.TopPackages) > request.TopPackages {
+		response.TopPackages = response.TopPackages[:request.TopPackages]
+	}
+	return response, nil
+}
+
+// getScansByImage returns the scans of every deployed image ordered by scan time
+func (impl VulnerabilityTrendServiceImpl) getScansByImage(deployments []*security.DeployedArtifact) (map[string][]*imageScan, error) {
+	imageMap := make(map[string]bool)
+	var images []string
+	for _, deployment := range deployments {
+		if !imageMap[deployment.Image] {
+			imageMap[deployment.Image] = true
+			images = append(images, deployment.Image)
+		}
+	}
+	imageCves, err := impl.vulnerabilityTrendRepository.FindCvesByImages(images)
+	if err != nil {
+		impl.logger.Errorw("error in fetching cves of deployed images", "err", err)
+		return nil, err
+	}
+	scans := make(map[int]*imageScan)
+	scansByImage := make(map[string][]*imageScan)
+	for _, imageCve := range imageCves {
+		scan, ok := scans[imageCve.ScanId]
+		if !ok {
+			scan = &imageScan{scannedOn: imageCve.ScannedOn, cves: make(map[string]*security.ImageCve)}
+			scans[imageCve.ScanId] = scan
+			scansByImage[imageCve.Image] = append(scansByImage[imageCve.Image], scan)
+		}
+		if len(imageCve.Name) > 0 {
+			scan.cves[imageCve.Name] = imageCve
+		}
+	}
+	return scansByImage, nil
+}
+
+func (impl VulnerabilityTrendServiceImpl) setExceptedCves(timelines []*appEnvTimeline) error {
+	for _, timeline := range timelines {
+		exceptions, err := impl.cveExceptionRepository.FindActive(timeline.envId, timeline.appId)
+		if err != nil && err != pg.ErrNoRows {
+			impl.logger.Errorw("error in fetching cve exceptions", "appId", timeline.appId, "envId", timeline.envId, "err", err)
+			return err
+		}
+		timeline.excepted = make(map[string]bool)
+		for _, exception := range exceptions {
+			timeline.excepted[exception.CveStoreName] = true
+		}
+	}
+	return nil
+}
+
+// buildTimelines groups deployments by app+env. Images without scan results and redeployments of the running image
+// are left out, an unscanned image would otherwise show up as all cves fixed
+func (impl VulnerabilityTrendServiceImpl) buildTimelines(request *VulnerabilityTrendRequest, deployments []*security.DeployedArtifact,
+	scansByImage map[string][]*imageScan) []*appEnvTimeline {
+	timelineMap := make(map[string]*appEnvTimeline)
+	var timelines []*appEnvTimeline
+	for _, deployment := range deployments {
+		if len(scansByImage[deployment.Image]) == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%d-%d", deployment.AppId, deployment.EnvId)
+		timeline, ok := timelineMap[key]
+		if !ok {
+			timeline = &appEnvTimeline{appId: deployment.AppId, envId: deployment.EnvId}
+			switch request.GroupBy {
+			case TrendGroupByTeam:
+				timeline.groupId, timeline.groupName = deployment.TeamId, deployment.TeamName
+			case TrendGroupByEnv:
+				timeline.groupId, timeline.groupName = deployment.EnvId, deployment.EnvName
+			default:
+				timeline.groupId, timeline.groupName = deployment.AppId, deployment.AppName
+			}
+			timelineMap[key] = timeline
+			timelines = append(timelines, timeline)
+		}
+		if n := len(timeline.deployments); n > 0 && timeline.deployments[n-1].Image == deployment.Image {
+			continue
+		}
+		timeline.deployments = append(timeline.deployments, deployment)
+	}
+	return timelines
+}
+
+// getTrendPoint returns the point of the bucket the time falls in, nil when it is outside the requested range
+func (impl VulnerabilityTrendServiceImpl) getTrendPoint(group *VulnerabilityTrendGroup, buckets []*trendBucket, t time.Time) *VulnerabilityTrendPoint {
+	i := sort.Search(len(buckets), func(i int) bool {
+		return buckets[i].end.After(t)
+	})
+	if i < len(buckets) && !t.Before(buckets[i].start) {
+		return group.Trend[i]
+	} else if n := len(buckets); n > 0 && t.Equal(buckets[n-1].end) {
+		return group.Trend[n-1]
+	}
+	return nil
+}
+
+// getChangePoints returns the times the open cves of the app+env can change at, the deployments and the rescans of
+// the running image till the end of the range
+func (impl VulnerabilityTrendServiceImpl) getChangePoints(timeline *appEnvTimeline, scansByImage map[string][]*imageScan, to time.Time) []time.Time {
+	var points []time.Time
+	for i, deployment := range timeline.deployments {
+		points = append(points, deployment.DeployedOn)
+		until := to
+		if i+1 < len(timeline.deployments) {
+			until = timeline.deployments[i+1].DeployedOn
+		}
+		for _, scan := range scansByImage[deployment.Image] {
+			if scan.scannedOn.After(deployment.DeployedOn) && scan.scannedOn.Before(until) {
+				points = append(points, scan.scannedOn)
+			}
+		}
+	}
+	return points
+}
+
+// getOpenCvesAt returns the cves of the image running at the time as per the scan in effect then, excepted cves left out
+func (impl VulnerabilityTrendServiceImpl) getOpenCvesAt(timeline *appEnvTimeline, scansByImage map[string][]*imageScan, t time.Time) map[string]*security.ImageCve {
+	i := sort.Search(len(timeline.deployments), func(i int) bool {
+		return timeline.deployments[i].DeployedOn.After(t)
+	})
+	if i == 0 {
+		return nil
+	}
+	scan := impl.getScanAt(scansByImage[timeline.deployments[i-1].Image], t)
+	if scan == nil {
+		return nil
+	}
+	open := make(map[string]*security.ImageCve)
+	for name, cve := range scan.cves {
+		if !timeline.excepted[name] {
+			open[name] = cve
+		}
+	}
+	return open
+}
+
+// getScanAt returns the latest scan done till the time, the first scan for an image deployed before it was scanned
+func (impl VulnerabilityTrendServiceImpl) getScanAt(scans []*imageScan, t time.Time) *imageScan {
+	if len(scans) == 0 {
+		return nil
+	}
+	i := sort.Search(len(scans), func(i int) bool {
+		return scans[i].scannedOn.After(t)
+	})
+	if i == 0 {
+		return scans[0]
+	}
+	return scans[i-1]
+}
+
+func (impl VulnerabilityTrendServiceImpl) addSeverityCount(count *SeverityCount, cves map[string]*security.ImageCve) {
+	for _, cve := range cves {
+		switch cve.Severity {
+		case security.Critical:
+			count.High++
+		case security.Moderate:
+			count.Moderate++
+		case security.Low:
+			count.Low++
+		}
+	}
+}
+
+func (impl VulnerabilityTrendServiceImpl) getPackages(cves map[string]*security.ImageCve) map[string]bool {
+	packages := make(map[string]bool)
+	for _, cve := range cves {
+		if len(cve.Package) > 0 {
+			packages[cve.Package] = true
+		}
+	}
+	return packages
+}
+
+func (impl VulnerabilityTrendServiceImpl) ExportVulnerabilityTrend(request *VulnerabilityTrendRequest, deployments []*security.DeployedArtifact, report string) ([]byte, error) {
+	if report != TrendReportTrend && report != TrendReportPackages {
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("unsupported report %s", report)}
+	}
+	response, err := impl.GetVulnerabilityTrend(request, deployments)
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	writer := csv.NewWriter(buf)
+	if report == TrendReportPackages {
+		err = writer.Write([]string{"package", "cves", "critical", "deployments"})
+		if err != nil {
+			return nil, err
+		}
+		for _, pkg := range response.TopPackages {
+			err = writer.Write([]string{pkg.Package, strconv.Itoa(pkg.Cves), strconv.Itoa(pkg.Critical), strconv.Itoa(pkg.Deployments)})
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		err = writer.Write([]string{response.GroupBy + "Id", response.GroupBy, "date", "critical", "moderate", "low", "introduced", "fixed", "meanTimeToRemediateHours"})
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range response.Groups {
+			mttr := strconv.FormatFloat(group.MeanTimeToRemediateHours, 'f', 2, 64)
+			for _, point := range group.Trend {
+				err = writer.Write([]string{strconv.Itoa(group.Id), group.Name, point.Date.Format(time.RFC3339), strconv.Itoa(point.Open.High),
+					strconv.Itoa(point.Open.Moderate), strconv.Itoa(point.Open.Low), strconv.Itoa(point.Introduced), strconv.Itoa(point.Fixed), mttr})
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
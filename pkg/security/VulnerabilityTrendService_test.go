@@ -0,0 +1,275 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository/security"
+	"go.uber.org/zap"
+)
+
+var trendStart = time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func trendDay(day int) time.Time {
+	return trendStart.AddDate(0, 0, day)
+}
+
+func TestVulnerabilityTrendService_getBuckets(t *testing.T) {
+	impl := VulnerabilityTrendServiceImpl{}
+	tests := []struct {
+		name      string
+		request   *VulnerabilityTrendRequest
+		wantCount int
+		wantLast  *trendBucket
+	}{
+		{
+			name:      "daily buckets",
+			request:   &VulnerabilityTrendRequest{From: trendDay(0), To: trendDay(3), Interval: TrendIntervalDay},
+			wantCount: 3,
+			wantLast:  &trendBucket{start: trendDay(2), end: trendDay(3)},
+		},
+		{
+			name:      "last weekly bucket is cut at the end of the range",
+			request:   &VulnerabilityTrendRequest{From: trendDay(0), To: trendDay(10), Interval: TrendIntervalWeek},
+			wantCount: 2,
+			wantLast:  &trendBucket{start: trendDay(7), end: trendDay(10)},
+		},
+		{
+			name:      "monthly buckets follow calendar months",
+			request:   &VulnerabilityTrendRequest{From: trendDay(0), To: trendStart.AddDate(0, 2, 0), Interval: TrendIntervalMonth},
+			wantCount: 2,
+			wantLast:  &trendBucket{start: trendStart.AddDate(0, 1, 0), end: trendStart.AddDate(0, 2, 0)},
+		},
+		{
+			name:      "too many buckets stop past the limit",
+			request:   &VulnerabilityTrendRequest{From: trendDay(0), To: trendDay(1000), Interval: TrendIntervalDay},
+			wantCount: maxTrendPoints + 1,
+			wantLast:  &trendBucket{start: trendDay(maxTrendPoints), end: trendDay(maxTrendPoints + 1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buckets := impl.getBuckets(tt.request)
+			if len(buckets) != tt.wantCount {
+				t.Fatalf("getBuckets() count = %d, want %d", len(buckets), tt.wantCount)
+			}
+			if buckets[0].start != tt.request.From {
+				t.Errorf("getBuckets() first start = %v, want %v", buckets[0].start, tt.request.From)
+			}
+			last := buckets[len(buckets)-1]
+			if last.start != tt.wantLast.start || last.end != tt.wantLast.end {
+				t.Errorf("getBuckets() last = %v-%v, want %v-%v", last.start, last.end, tt.wantLast.start, tt.wantLast.end)
+			}
+			for i := 1; i < len(buckets); i++ {
+				if buckets[i].start != buckets[i-1].end {
+					t.Errorf("getBuckets() bucket %d starts at %v, previous ends at %v", i, buckets[i].start, buckets[i-1].end)
+				}
+			}
+		})
+	}
+}
+
+func TestVulnerabilityTrendService_getTrendPoint(t *testing.T) {
+	impl := VulnerabilityTrendServiceImpl{}
+	buckets := impl.getBuckets(&VulnerabilityTrendRequest{From: trendDay(0), To: trendDay(3), Interval: TrendIntervalDay})
+	group := &VulnerabilityTrendGroup{}
+	for _, bucket := range buckets {
+		group.Trend = append(group.Trend, &VulnerabilityTrendPoint{Date: bucket.start})
+	}
+	tests := []struct {
+		name      string
+		t         time.Time
+		wantIndex int
+	}{
+		{name: "before the range", t: trendDay(-1), wantIndex: -1},
+		{name: "start of the range", t: trendDay(0), wantIndex: 0},
+		{name: "within a bucket", t: trendDay(1).Add(5 * time.Hour), wantIndex: 1},
+		{name: "bucket boundary belongs to the next bucket", t: trendDay(2), wantIndex: 2},
+		{name: "end of the range", t: trendDay(3), wantIndex: 2},
+		{name: "after the range", t: trendDay(3).Add(time.Second), wantIndex: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			point := impl.getTrendPoint(group, buckets, tt.t)
+			if tt.wantIndex < 0 {
+				if point != nil {
+					t.Errorf("getTrendPoint() = %v, want nil", point.Date)
+				}
+				return
+			}
+			if point != group.Trend[tt.wantIndex] {
+				t.Errorf("getTrendPoint() = %v, want point %d", point, tt.wantIndex)
+			}
+		})
+	}
+}
+
+func TestVulnerabilityTrendService_buildTimelines(t *testing.T) {
+	impl := VulnerabilityTrendServiceImpl{}
+	scansByImage := map[string][]*imageScan{
+		"app:1": {{scannedOn: trendDay(0)}},
+		"app:2": {{scannedOn: trendDay(1)}},
+	}
+	deployments := []*security.DeployedArtifact{
+		{AppId: 1, AppName: "app", TeamId: 5, TeamName: "team", EnvId: 2, EnvName: "prod", Image: "app:1", DeployedOn: trendDay(0)},
+		{AppId: 1, AppName: "app", TeamId: 5, TeamName: "team", EnvId: 3, EnvName: "qa", Image: "app:1", DeployedOn: trendDay(0)},
+		{AppId: 1, AppName: "app", TeamId: 5, TeamName: "team", EnvId: 2, EnvName: "prod", Image: "app:1", DeployedOn: trendDay(1)},
+		{AppId: 1, AppName: "app", TeamId: 5, TeamName: "team", EnvId: 2, EnvName: "prod", Image: "app:unscanned", DeployedOn: trendDay(2)},
+		{AppId: 1, AppName: "app", TeamId: 5, TeamName: "team", EnvId: 2, EnvName: "prod", Image: "app:2", DeployedOn: trendDay(3)},
+	}
+	tests := []struct {
+		name          string
+		groupBy       string
+		wantGroups    []string
+		wantDeployOns [][]time.Time
+	}{
+		{
+			name:          "grouped by app",
+			groupBy:       TrendGroupByApp,
+			wantGroups:    []string{"app", "app"},
+			wantDeployOns: [][]time.Time{{trendDay(0), trendDay(3)}, {trendDay(0)}},
+		},
+		{
+			name:          "grouped by env",
+			groupBy:       TrendGroupByEnv,
+			wantGroups:    []string{"prod", "qa"},
+			wantDeployOns: [][]time.Time{{trendDay(0), trendDay(3)}, {trendDay(0)}},
+		},
+		{
+			name:          "grouped by team",
+			groupBy:       TrendGroupByTeam,
+			wantGroups:    []string{"team", "team"},
+			wantDeployOns: [][]time.Time{{trendDay(0), trendDay(3)}, {trendDay(0)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timelines := impl.buildTimelines(&VulnerabilityTrendRequest{GroupBy: tt.groupBy}, deployments, scansByImage)
+			if len(timelines) != len(tt.wantGroups) {
+				t.Fatalf("buildTimelines() count = %d, want %d", len(timelines), len(tt.wantGroups))
+			}
+			for i, timeline := range timelines {
+				if timeline.groupName != tt.wantGroups[i] {
+					t.Errorf("buildTimelines() group = %s, want %s", timeline.groupName, tt.wantGroups[i])
+				}
+				if timeline.appId != 1 {
+					t.Errorf("buildTimelines() appId = %d, want 1", timeline.appId)
+				}
+				if len(timeline.deployments) != len(tt.wantDeployOns[i]) {
+					t.Fatalf("buildTimelines() deployments = %d, want %d", len(timeline.deployments), len(tt.wantDeployOns[i]))
+				}
+				for j, deployment := range timeline.deployments {
+					if deployment.DeployedOn != tt.wantDeployOns[i][j] {
+						t.Errorf("buildTimelines() deployment %d on %v, want %v", j, deployment.DeployedOn, tt.wantDeployOns[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+type vulnerabilityTrendRepositoryStub struct {
+	security.VulnerabilityTrendRepository
+	imageCves []*security.ImageCve
+}
+
+func (stub vulnerabilityTrendRepositoryStub) FindCvesByImages(images []string) ([]*security.ImageCve, error) {
+	return stub.imageCves, nil
+}
+
+type trendCveExceptionRepositoryStub struct {
+	security.CveExceptionRepository
+	exceptions []*security.CveException
+}
+
+func (stub trendCveExceptionRepositoryStub) FindActive(envId int, appId int) ([]*security.CveException, error) {
+	return stub.exceptions, nil
+}
+
+func TestVulnerabilityTrendService_GetVulnerabilityTrend(t *testing.T) {
+	log4j := "CVE-2021-44228"
+	spring := "CVE-2022-22965"
+	openssl := "CVE-2020-1971"
+	// app:1 is deployed on day 0, a rescan on day 2 finds a new cve in it and app:2 deployed on day 4 fixes it.
+	// app:2 is rescanned after the range which must not change the trend.
+	imageCves := []*security.ImageCve{
+		{ScanId: 1, ScannedOn: trendDay(0), Image: "app:1", Name: log4j, Severity: security.Critical, Package: "log4j"},
+		{ScanId: 1, ScannedOn: trendDay(0), Image: "app:1", Name: openssl, Severity: security.Low, Package: "openssl"},
+		{ScanId: 2, ScannedOn: trendDay(2), Image: "app:1", Name: log4j, Severity: security.Critical, Package: "log4j"},
+		{ScanId: 2, ScannedOn: trendDay(2), Image: "app:1", Name: spring, Severity: security.Critical, Package: "spring"},
+		{ScanId: 2, ScannedOn: trendDay(2), Image: "app:1", Name: openssl, Severity: security.Low, Package: "openssl"},
+		{ScanId: 3, ScannedOn: trendDay(3), Image: "app:2"},
+		{ScanId: 4, ScannedOn: trendDay(10), Image: "app:2", Name: spring, Severity: security.Critical, Package: "spring"},
+	}
+	deployments := []*security.DeployedArtifact{
+		{AppId: 1, AppName: "app", EnvId: 2, EnvName: "prod", Image: "app:1", DeployedOn: trendDay(0).Add(time.Hour)},
+		{AppId: 1, AppName: "app", EnvId: 2, EnvName: "prod", Image: "app:2", DeployedOn: trendDay(4)},
+	}
+	tests := []struct {
+		name           string
+		exceptions     []*security.CveException
+		wantIntroduced []int
+		wantFixed      []int
+		// open cves are counted at the end of each day
+		wantCritical []int
+		wantLow      []int
+	}{
+		{
+			name:           "cves found by a rescan are introduced when the rescan ran",
+			wantIntroduced: []int{2, 0, 1, 0, 0},
+			wantFixed:      []int{0, 0, 0, 0, 3},
+			wantCritical:   []int{1, 2, 2, 0, 0},
+			wantLow:        []int{1, 1, 1, 0, 0},
+		},
+		{
+			name:           "excepted cves are not counted",
+			exceptions:     []*security.CveException{{CveStoreName: spring}, {CveStoreName: openssl}},
+			wantIntroduced: []int{1, 0, 0, 0, 0},
+			wantFixed:      []int{0, 0, 0, 0, 1},
+			wantCritical:   []int{1, 1, 1, 0, 0},
+			wantLow:        []int{0, 0, 0, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl := NewVulnerabilityTrendServiceImpl(zap.NewNop().Sugar(), vulnerabilityTrendRepositoryStub{imageCves: imageCves},
+				trendCveExceptionRepositoryStub{exceptions: tt.exceptions})
+			request := &VulnerabilityTrendRequest{From: trendDay(0), To: trendDay(5), GroupBy: TrendGroupByApp, Interval: TrendIntervalDay}
+			response, err := impl.GetVulnerabilityTrend(request, deployments)
+			if err != nil {
+				t.Fatalf("GetVulnerabilityTrend() error = %v", err)
+			}
+			if len(response.Groups) != 1 {
+				t.Fatalf("GetVulnerabilityTrend() groups = %d, want 1", len(response.Groups))
+			}
+			group := response.Groups[0]
+			for i, point := range group.Trend {
+				if point.Introduced != tt.wantIntroduced[i] || point.Fixed != tt.wantFixed[i] {
+					t.Errorf("GetVulnerabilityTrend() day %d introduced/fixed = %d/%d, want %d/%d", i, point.Introduced, point.Fixed, tt.wantIntroduced[i], tt.wantFixed[i])
+				}
+				if point.Open.High != tt.wantCritical[i] || point.Open.Low != tt.wantLow[i] {
+					t.Errorf("GetVulnerabilityTrend() day %d open critical/low = %d/%d, want %d/%d", i, point.Open.High, point.Open.Low, tt.wantCritical[i], tt.wantLow[i])
+				}
+			}
+			if group.Open.High != 0 || group.Open.Low != 0 {
+				t.Errorf("GetVulnerabilityTrend() open = %+v, want none", group.Open)
+			}
+		})
+	}
+}
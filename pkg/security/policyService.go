@@ -27,17 +27,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/caarlos0/env"
 	"github.com/devtron-labs/devtron/api/bean"
 	"github.com/devtron-labs/devtron/internal/sql/repository"
 	"github.com/devtron-labs/devtron/internal/sql/repository/chartConfig"
 	"github.com/devtron-labs/devtron/internal/sql/repository/pipelineConfig"
 	"github.com/devtron-labs/devtron/internal/sql/repository/security"
 	"github.com/devtron-labs/devtron/pkg/cluster"
-	"github.com/devtron-labs/devtron/pkg/pipeline"
 	"github.com/go-pg/pg"
 	"go.uber.org/zap"
 )
 
+// ImageScannerConfig reads the same env as pipeline.CiConfig, the ci runner is given the endpoint from there
+type ImageScannerConfig struct {
+	ImageScannerEndpoint string `env:"IMAGE_SCANNER_ENDPOINT" envDefault:"http://image-scanner-new-demo-devtroncd-service.devtroncd:80"`
+}
+
+func GetImageScannerConfig() (*ImageScannerConfig, error) {
+	cfg := &ImageScannerConfig{}
+	err := env.Parse(cfg)
+	return cfg, err
+}
+
 type PolicyService interface {
 	SavePolicy(request bean.CreateVulnerabilityPolicyRequest, userId int32) (*bean.IdVulnerabilityPolicyResult, error)
 	UpdatePolicy(updatePolicyParams bean.UpdatePolicyParams, userId int32) (*bean.IdVulnerabilityPolicyResult, error)
@@ -45,6 +56,8 @@ type PolicyService interface {
 	GetPolicies(policyLevel security.PolicyLevel, clusterId, environmentId, appId int) (*bean.GetVulnerabilityPolicyResult, error)
 	GetBlockedCVEList(cves []*security.CveStore, clusterId, envId, appId int, isAppstore bool) ([]*security.CveStore, error)
 	VerifyImage(verifyImageRequest *VerifyImageRequest) (map[string][]*VerifyImageResponse, error)
+	// SendEventToClairUtility asks the image scanner to scan an image, the scanner stores the execution history and results
+	SendEventToClairUtility(event *ScanEvent) error
 	GetCvePolicy(id int, userId int32) (*security.CvePolicy, error)
 }
 type PolicyServiceImpl struct {
@@ -60,10 +73,11 @@ type PolicyServiceImpl struct {
 	imageScanObjectMetaRepository security.ImageScanObjectMetaRepository
 	client                        *http.Client
 	ciArtifactRepository          repository.CiArtifactRepository
-	ciConfig                      *pipeline.CiConfig
+	imageScannerConfig            *ImageScannerConfig
 	scanHistoryRepository         security.ImageScanHistoryRepository
 	cveStoreRepository            security.CveStoreRepository
 	ciTemplateRepository          pipelineConfig.CiTemplateRepository
+	cveExceptionRepository        security.CveExceptionRepository
 }
 
 func NewPolicyServiceImpl(environmentService cluster.EnvironmentService,
@@ -76,9 +90,10 @@ func NewPolicyServiceImpl(environmentService cluster.EnvironmentService,
 	scanResultRepository security.ImageScanResultRepository,
 	imageScanDeployInfoRepository security.ImageScanDeployInfoRepository,
 	imageScanObjectMetaRepository security.ImageScanObjectMetaRepository, client *http.Client,
-	ciArtifactRepository repository.CiArtifactRepository, ciConfig *pipeline.CiConfig,
+	ciArtifactRepository repository.CiArtifactRepository, imageScannerConfig *ImageScannerConfig,
 	scanHistoryRepository security.ImageScanHistoryRepository, cveStoreRepository security.CveStoreRepository,
-	ciTemplateRepository pipelineConfig.CiTemplateRepository) *PolicyServiceImpl {
+	ciTemplateRepository pipelineConfig.CiTemplateRepository,
+	cveExceptionRepository security.CveExceptionRepository) *PolicyServiceImpl {
 	return &PolicyServiceImpl{
 		environmentService:            environmentService,
 		logger:                        logger,
@@ -92,10 +107,11 @@ func NewPolicyServiceImpl(environmentService cluster.EnvironmentService,
 		imageScanObjectMetaRepository: imageScanObjectMetaRepository,
 		client:                        client,
 		ciArtifactRepository:          ciArtifactRepository,
-		ciConfig:                      ciConfig,
+		imageScannerConfig:            imageScannerConfig,
 		scanHistoryRepository:         scanHistoryRepository,
 		cveStoreRepository:            cveStoreRepository,
 		ciTemplateRepository:          ciTemplateRepository,
+		cveExceptionRepository:        cveExceptionRepository,
 	}
 }
 
@@ -148,7 +164,7 @@ func (impl *PolicyServiceImpl) SendEventToClairUtility(event *ScanEvent) error {
 		return err
 	}
 	impl.logger.Debugw("request", "body", string(reqBody))
-	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s", impl.ciConfig.ImageScannerEndpoint, "scanner/image"), bytes.NewBuffer(reqBody))
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/%s", impl.imageScannerConfig.ImageScannerEndpoint, "scanner/image"), bytes.NewBuffer(reqBody))
 	if err != nil {
 		impl.logger.Errorw("error while writing test suites", "err", err)
 		return err
@@ -159,6 +175,7 @@ func (impl *PolicyServiceImpl) SendEventToClairUtility(event *ScanEvent) error {
 		impl.logger.Errorw("error while UpdateJiraTransition request ", "err", err)
 		return err
 	}
+	defer resp.Body.Close()
 	impl.logger.Debugw("response from test suit create api", "status code", resp.StatusCode)
 	return err
 }
@@ -197,6 +214,11 @@ func (impl *PolicyServiceImpl) VerifyImage(verifyImageRequest *VerifyImageReques
 	if err != nil {
 		impl.logger.Errorw("error in generating applicable policy", "err", err)
 	}
+	exceptions, err := impl.cveExceptionRepository.FindActive(envId, appId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching cve exceptions", "envId", envId, "appId", appId, "err", err)
+		return nil, err
+	}
 
 	var objectType string
 	var typeId int
@@ -247,7 +269,7 @@ func (impl *PolicyServiceImpl) VerifyImage(verifyImageRequest *VerifyImageReques
 				scanResultsIdMap[scanResult.ImageScanExecutionHistoryId] = scanResult.ImageScanExecutionHistoryId
 			}
 		}
-		blockedCves := impl.enforceCvePolicy(cveStores, cvePolicy, severityPolicy)
+		blockedCves := RemoveExceptedCves(impl.enforceCvePolicy(cveStores, cvePolicy, severityPolicy), exceptions)
 		impl.logger.Debugw("blocked cve for image", "image", image, "blocked", blockedCves)
 		for _, cve := range blockedCves {
 			vr := &VerifyImageResponse{
@@ -705,7 +727,12 @@ func (impl *PolicyServiceImpl) GetBlockedCVEList(cves []*security.CveStore, clus
 		return nil, err
 	}
 	blockedCve := impl.enforceCvePolicy(cves, cvePolicy, severityPolicy)
-	return blockedCve, nil
+	exceptions, err := impl.cveExceptionRepository.FindActive(envId, appId)
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching cve exceptions", "envId", envId, "appId", appId, "err", err)
+		return nil, err
+	}
+	return RemoveExceptedCves(blockedCve, exceptions), nil
 }
 
 func (impl *PolicyServiceImpl) GetCvePolicy(id int, userId int32) (*security.CvePolicy, error) {
@@ -59,13 +59,15 @@ type UserServiceImpl struct {
 	roleGroupRepository repository2.RoleGroupRepository
 	sessionManager2     *middleware.SessionManager
 	userCommonService   UserCommonService
+	apiTokenRepository  repository2.ApiTokenRepository
 }
 
 func NewUserServiceImpl(userAuthRepository repository2.UserAuthRepository,
 	logger *zap.SugaredLogger,
 	userRepository repository2.UserRepository,
 	userGroupRepository repository2.RoleGroupRepository,
-	sessionManager2 *middleware.SessionManager, userCommonService UserCommonService) *UserServiceImpl {
+	sessionManager2 *middleware.SessionManager, userCommonService UserCommonService,
+	apiTokenRepository repository2.ApiTokenRepository) *UserServiceImpl {
 	serviceImpl := &UserServiceImpl{
 		userAuthRepository:  userAuthRepository,
 		logger:              logger,
@@ -73,6 +75,7 @@ func NewUserServiceImpl(userAuthRepository repository2.UserAuthRepository,
 		roleGroupRepository: userGroupRepository,
 		sessionManager2:     sessionManager2,
 		userCommonService:   userCommonService,
+		apiTokenRepository:  apiTokenRepository,
 	}
 	cStore = sessions.NewCookieStore(randKey())
 	return serviceImpl
@@ -180,6 +183,7 @@ func (impl UserServiceImpl) createUserIfNotExists(userInfo *bean.UserInfo, email
 	model := &repository2.UserModel{
 		EmailId:     emailId,
 		AccessToken: userInfo.AccessToken,
+		UserType:    userInfo.UserType,
 	}
 	model.Active = true
 	model.CreatedBy = userInfo.UserId
@@ -629,7 +633,7 @@ func (impl UserServiceImpl) GetById(id int32) (*bean.UserInfo, error) {
 		if len(role.Team) > 0 {
 			key = fmt.Sprintf("%s_%s_%s", role.Team, role.Action, role.AccessType)
 		} else if len(role.Entity) > 0 {
-			key = fmt.Sprintf("%s_%s_%s", role.Entity, role.Action)
+			key = fmt.Sprintf("%s_%s", role.Entity, role.Action)
 		}
 		if _, ok := roleFilterMap[key]; ok {
 			envArr := strings.Split(roleFilterMap[key].Environment, ",")
@@ -805,6 +809,12 @@ func (impl UserServiceImpl) GetUserByToken(token string) (int32, error) {
 
 	if email == "" && (sub == "admin" || sub == "admin:login") {
 		email = "admin"
+	} else if email == "" && strings.HasPrefix(sub, casbin2.ApiTokenUserEmailPrefix) {
+		err = impl.verifyApiToken(token)
+		if err != nil {
+			return http.StatusUnauthorized, err
+		}
+		email = sub
 	}
 
 	userInfo, err := impl.GetUserByEmail(email)
@@ -820,6 +830,42 @@ func (impl UserServiceImpl) GetUserByToken(token string) (int32, error) {
 	return userInfo.Id, nil
 }
 
+// verifyApiToken rejects revoked and expired api tokens, the signature is already verified by the session manager
+func (impl UserServiceImpl) verifyApiToken(token string) error {
+	apiToken, err := impl.apiTokenRepository.FindByTokenHash(repository2.HashApiToken(token))
+	if err != nil {
+		impl.logger.Errorw("error in fetching api token", "err", err)
+		return &util.ApiError{
+			Code:            constants.UserApiTokenInvalid,
+			InternalMessage: "api token not found",
+			UserMessage:     "invalid api token",
+		}
+	}
+	if apiToken.Revoked {
+		return &util.ApiError{
+			Code:            constants.UserApiTokenInvalid,
+			InternalMessage: "api token revoked",
+			UserMessage:     fmt.Sprintf("api token %s has been revoked", apiToken.Name),
+		}
+	}
+	now := time.Now()
+	if !apiToken.ExpireAt.IsZero() && apiToken.ExpireAt.Before(now) {
+		return &util.ApiError{
+			Code:            constants.UserApiTokenExpired,
+			InternalMessage: "api token expired",
+			UserMessage:     fmt.Sprintf("api token %s has expired", apiToken.Name),
+		}
+	}
+	// avoid a write on every api call made with the token
+	if now.Sub(apiToken.LastUsedAt) > time.Minute {
+		err = impl.apiTokenRepository.UpdateLastUsedAt(apiToken.Id, now)
+		if err != nil {
+			impl.logger.Errorw("error in updating api token last used time", "id", apiToken.Id, "err", err)
+		}
+	}
+	return nil
+}
+
 func (impl UserServiceImpl) GetByIds(ids []int32) ([]bean.UserInfo, error) {
 	var beans []bean.UserInfo
 	models, err := impl.userRepository.GetByIds(ids)
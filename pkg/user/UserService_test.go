@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/devtron-labs/devtron/internal/constants"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/devtron-labs/devtron/pkg/user/repository"
+	"github.com/go-pg/pg"
+)
+
+// apiTokenRepositoryStub keeps tokens by hash and records last used updates
+type apiTokenRepositoryStub struct {
+	repository.ApiTokenRepository
+	tokens   map[string]*repository.ApiToken
+	lastUsed map[int]time.Time
+}
+
+func (impl apiTokenRepositoryStub) FindByTokenHash(tokenHash string) (*repository.ApiToken, error) {
+	apiToken, ok := impl.tokens[tokenHash]
+	if !ok {
+		return nil, pg.ErrNoRows
+	}
+	return apiToken, nil
+}
+
+func (impl apiTokenRepositoryStub) UpdateLastUsedAt(id int, lastUsedAt time.Time) error {
+	impl.lastUsed[id] = lastUsedAt
+	return nil
+}
+
+func TestVerifyApiToken(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name         string
+		saved        *repository.ApiToken
+		token        string
+		wantCode     string
+		wantLastUsed bool
+	}{
+		{
+			name:     "unknown token",
+			saved:    &repository.ApiToken{Id: 1, Name: "ci"},
+			token:    "other-token",
+			wantCode: constants.UserApiTokenInvalid,
+		},
+		{
+			name:     "revoked token",
+			saved:    &repository.ApiToken{Id: 1, Name: "ci", Revoked: true, ExpireAt: now.Add(time.Hour)},
+			wantCode: constants.UserApiTokenInvalid,
+		},
+		{
+			name:     "expired token",
+			saved:    &repository.ApiToken{Id: 1, Name: "ci", ExpireAt: now.Add(-time.Minute)},
+			wantCode: constants.UserApiTokenExpired,
+		},
+		{
+			name:         "token without expiry",
+			saved:        &repository.ApiToken{Id: 1, Name: "ci"},
+			wantLastUsed: true,
+		},
+		{
+			name:         "token expiring in future",
+			saved:        &repository.ApiToken{Id: 1, Name: "ci", ExpireAt: now.Add(time.Hour), LastUsedAt: now.Add(-time.Hour)},
+			wantLastUsed: true,
+		},
+		{
+			name:  "token used within a minute is not written again",
+			saved: &repository.ApiToken{Id: 1, Name: "ci", LastUsedAt: now.Add(-10 * time.Second)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issued := "issued-token"
+			tt.saved.TokenHash = repository.HashApiToken(issued)
+			stub := apiTokenRepositoryStub{
+				tokens:   map[string]*repository.ApiToken{tt.saved.TokenHash: tt.saved},
+				lastUsed: map[int]time.Time{},
+			}
+			impl := UserServiceImpl{logger: util.NewSugardLogger(), apiTokenRepository: stub}
+			token := tt.token
+			if len(token) == 0 {
+				token = issued
+			}
+			err := impl.verifyApiToken(token)
+			if len(tt.wantCode) > 0 {
+				apiErr, ok := err.(*util.ApiError)
+				if !ok || apiErr.Code != tt.wantCode {
+					t.Errorf("verifyApiToken() error = %v, want code %s", err, tt.wantCode)
+				}
+			} else if err != nil {
+				t.Errorf("verifyApiToken() error = %v", err)
+			}
+			if _, ok := stub.lastUsed[tt.saved.Id]; ok != tt.wantLastUsed {
+				t.Errorf("verifyApiToken() last used updated = %v, want %v", ok, tt.wantLastUsed)
+			}
+		})
+	}
+}
+
+func TestHashApiToken(t *testing.T) {
+	hash := repository.HashApiToken("issued-token")
+	if len(hash) != 64 {
+		t.Errorf("HashApiToken() = %s, want hex sha256", hash)
+	}
+	if hash != repository.HashApiToken("issued-token") {
+		t.Errorf("HashApiToken() is not stable")
+	}
+	if hash == repository.HashApiToken("issued-token2") {
+		t.Errorf("HashApiToken() is same for different tokens")
+	}
+}
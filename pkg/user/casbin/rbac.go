@@ -28,6 +28,9 @@ import (
 	"strings"
 )
 
+// ApiTokenUserEmailPrefix is prepended to the token name to form the email of the service user bound to an api token
+const ApiTokenUserEmailPrefix = "api-token:"
+
 type Enforcer interface {
 	Enforce(rvals ...interface{}) bool
 	EnforceErr(rvals ...interface{}) error
@@ -98,6 +101,8 @@ func (e *EnforcerImpl) enforce(enf *casbin.Enforcer, rvals ...interface{}) bool
 	sub := jwt.GetField(mapClaims, "sub")
 	if email == "" && (sub == "admin" || sub == "admin:login") {
 		email = "admin"
+	} else if email == "" && strings.HasPrefix(sub, ApiTokenUserEmailPrefix) {
+		email = sub
 	}
 	rvals[0] = strings.ToLower(email)
 	return enf.Enforce(rvals...)
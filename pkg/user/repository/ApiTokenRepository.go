@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package repository
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"github.com/devtron-labs/devtron/pkg/sql"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"time"
+)
+
+// ApiToken only keeps the sha256 of the issued token, the token itself is shown once at creation
+type ApiToken struct {
+	TableName   struct{}  `sql:"api_token" pg:",discard_unknown_columns"`
+	Id          int       `sql:"id,pk"`
+	UserId      int32     `sql:"user_id,notnull"`
+	Name        string    `sql:"name,notnull"`
+	Description string    `sql:"description"`
+	TokenHash   string    `sql:"token_hash,notnull"`
+	ExpireAt    time.Time `sql:"expire_at"`
+	LastUsedAt  time.Time `sql:"last_used_at"`
+	Revoked     bool      `sql:"revoked,notnull"`
+	User        *UserModel
+	sql.AuditLog
+}
+
+// HashApiToken is the token_hash saved for a token and looked up when it is used
+func HashApiToken(token string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(token)))
+}
+
+type ApiTokenRepository interface {
+	Save(apiToken *ApiToken) error
+	Update(apiToken *ApiToken) error
+	FindAllActive() ([]*ApiToken, error)
+	FindActiveById(id int) (*ApiToken, error)
+	FindActiveByName(name string) (*ApiToken, error)
+	FindByTokenHash(tokenHash string) (*ApiToken, error)
+	UpdateLastUsedAt(id int, lastUsedAt time.Time) error
+}
+
+type ApiTokenRepositoryImpl struct {
+	dbConnection *pg.DB
+	logger       *zap.SugaredLogger
+}
+
+func NewApiTokenRepositoryImpl(dbConnection *pg.DB, logger *zap.SugaredLogger) *ApiTokenRepositoryImpl {
+	return &ApiTokenRepositoryImpl{dbConnection: dbConnection, logger: logger}
+}
+
+func (impl ApiTokenRepositoryImpl) Save(apiToken *ApiToken) error {
+	return impl.dbConnection.Insert(apiToken)
+}
+
+func (impl ApiTokenRepositoryImpl) Update(apiToken *ApiToken) error {
+	return impl.dbConnection.Update(apiToken)
+}
+
+func (impl ApiTokenRepositoryImpl) FindAllActive() ([]*ApiToken, error) {
+	var apiTokens []*ApiToken
+	err := impl.dbConnection.Model(&apiTokens).
+		Column("api_token.*", "User").
+		Where("api_token.revoked = ?", false).
+		Order("api_token.id DESC").
+		Select()
+	return apiTokens, err
+}
+
+func (impl ApiTokenRepositoryImpl) FindActiveById(id int) (*ApiToken, error) {
+	apiToken := &ApiToken{}
+	err := impl.dbConnection.Model(apiToken).
+		Column("api_token.*", "User").
+		Where("api_token.id = ?", id).
+		Where("api_token.revoked = ?", false).
+		Select()
+	return apiToken, err
+}
+
+func (impl ApiTokenRepositoryImpl) FindActiveByName(name string) (*ApiToken, error) {
+	apiToken := &ApiToken{}
+	err := impl.dbConnection.Model(apiToken).
+		Where("name = ?", name).
+		Where("revoked = ?", false).
+		Select()
+	return apiToken, err
+}
+
+func (impl ApiTokenRepositoryImpl) FindByTokenHash(tokenHash string) (*ApiToken, error) {
+	apiToken := &ApiToken{}
+	err := impl.dbConnection.Model(apiToken).
+		Where("token_hash = ?", tokenHash).
+		Select()
+	return apiToken, err
+}
+
+func (impl ApiTokenRepositoryImpl) UpdateLastUsedAt(id int, lastUsedAt time.Time) error {
+	_, err := impl.dbConnection.Model(&ApiToken{}).
+		Set("last_used_at = ?", lastUsedAt).
+		Where("id = ?", id).
+		Update()
+	return err
+}
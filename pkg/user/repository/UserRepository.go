@@ -24,6 +24,7 @@ import (
 	"github.com/devtron-labs/devtron/api/bean"
 	"github.com/devtron-labs/devtron/pkg/sql"
 	"github.com/go-pg/pg"
+	"github.com/go-pg/pg/orm"
 	"go.uber.org/zap"
 )
 
@@ -56,8 +57,12 @@ type UserModel struct {
 	EmailId     string   `sql:"email_id,notnull"`
 	AccessToken string   `sql:"access_token"`
 	Active      bool     `sql:"active,notnull"`
+	UserType    string   `sql:"user_type"`
 	sql.AuditLog
 }
+
+const UserTypeApiToken = "apiToken"
+
 type UserRoleModel struct {
 	TableName struct{} `sql:"user_roles"`
 	Id        int      `sql:"id,pk"`
@@ -101,7 +106,11 @@ func (impl UserRepositoryImpl) GetByIdIncludeDeleted(id int32) (*UserModel, erro
 
 func (impl UserRepositoryImpl) GetAll() ([]UserModel, error) {
 	var userModel []UserModel
-	err := impl.dbConnection.Model(&userModel).Where("active = ?", true).Order("updated_on desc").Select()
+	err := impl.dbConnection.Model(&userModel).Where("active = ?", true).
+		WhereGroup(func(q *orm.Query) (*orm.Query, error) {
+			q = q.WhereOr("user_type IS NULL").WhereOr("user_type != ?", UserTypeApiToken)
+			return q, nil
+		}).Order("updated_on desc").Select()
 	return userModel, err
 }
 
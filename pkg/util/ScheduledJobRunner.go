@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package util
+
+import (
+	"os"
+	"time"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/robfig/cron/v3"
+	uuid "github.com/satori/go.uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// scheduledJobLeaseTtl bounds how long a job of a crashed replica stays claimed, running jobs renew it
+	scheduledJobLeaseTtl         = 2 * time.Minute
+	scheduledJobLeaseRenewPeriod = 30 * time.Second
+	// scheduledJobLeaseMargin leaves the next occurrence claimable by replicas whose ticks fire slightly earlier
+	scheduledJobLeaseMargin = 5 * time.Second
+)
+
+// ScheduledJobRunner runs background jobs of the orchestrator on a single replica at a time
+type ScheduledJobRunner interface {
+	// Schedule runs job on cronExpr, each occurrence is run by only one of the orchestrator replicas
+	Schedule(jobName string, cronExpr string, job func()) error
+	// Acquire claims jobName for a run outside of its schedule, release must be called once the run is over.
+	// acquired is false while the job is running on this or another replica.
+	Acquire(jobName string) (release func(), acquired bool, err error)
+}
+
+type ScheduledJobRunnerImpl struct {
+	logger                      *zap.SugaredLogger
+	cron                        *cron.Cron
+	scheduledJobLeaseRepository repository.ScheduledJobLeaseRepository
+	holder                      string
+}
+
+func NewScheduledJobRunnerImpl(logger *zap.SugaredLogger, scheduledJobLeaseRepository repository.ScheduledJobLeaseRepository) *ScheduledJobRunnerImpl {
+	cron := cron.New(
+		cron.WithChain(cron.SkipIfStillRunning(cron.DefaultLogger)))
+	cron.Start()
+	hostname, _ := os.Hostname()
+	return &ScheduledJobRunnerImpl{
+		logger:                      logger,
+		cron:                        cron,
+		scheduledJobLeaseRepository: scheduledJobLeaseRepository,
+		holder:                      hostname + "/" + uuid.NewV4().String(),
+	}
+}
+
+func (impl *ScheduledJobRunnerImpl) Schedule(jobName string, cronExpr string, job func()) error {
+	schedule, err := cron.ParseStandard(cronExpr)
+	if err != nil {
+		impl.logger.Errorw("invalid cron expression for scheduled job", "job", jobName, "cron", cronExpr, "err", err)
+		return err
+	}
+	impl.cron.Schedule(schedule, cron.FuncJob(func() {
+		next := schedule.Next(time.Now())
+		release, acquired, err := impl.acquire(jobName)
+		if err != nil || !acquired {
+			return
+		}
+		// the occurrence stays claimed after the run so that replicas firing later for it skip it
+		defer release(time.Until(next) - scheduledJobLeaseMargin)
+		job()
+	}))
+	return nil
+}
+
+func (impl *ScheduledJobRunnerImpl) Acquire(jobName string) (func(), bool, error) {
+	release, acquired, err := impl.acquire(jobName)
+	if err != nil || !acquired {
+		return nil, acquired, err
+	}
+	return func() { release(0) }, true, nil
+}
+
+// acquire claims the lease of jobName and keeps renewing it until release is called with how long it should still be held
+func (impl *ScheduledJobRunnerImpl) acquire(jobName string) (func(hold time.Duration), bool, error) {
+	acquired, err := impl.scheduledJobLeaseRepository.Acquire(jobName, impl.holder, int(scheduledJobLeaseTtl.Seconds()))
+	if err != nil {
+		impl.logger.Errorw("error in acquiring scheduled job lease", "job", jobName, "err", err)
+		return nil, false, err
+	}
+	if !acquired {
+		impl.logger.Debugw("scheduled job is held by another run, skipping", "job", jobName)
+		return nil, false, nil
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(scheduledJobLeaseRenewPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := impl.scheduledJobLeaseRepository.Extend(jobName, impl.holder, int(scheduledJobLeaseTtl.Seconds()))
+				if err != nil {
+					impl.logger.Errorw("error in renewing scheduled job lease", "job", jobName, "err", err)
+				}
+			}
+		}
+	}()
+	release := func(hold time.Duration) {
+		close(done)
+		if hold < 0 {
+			hold = 0
+		}
+		err := impl.scheduledJobLeaseRepository.Extend(jobName, impl.holder, int(hold.Seconds()))
+		if err != nil {
+			impl.logger.Errorw("error in releasing scheduled job lease", "job", jobName, "err", err)
+		}
+	}
+	return release, true, nil
+}
@@ -0,0 +1,595 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package vault
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+	"github.com/go-pg/pg"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AuthMethodToken      = "token"
+	AuthMethodAppRole    = "approle"
+	AuthMethodKubernetes = "kubernetes"
+
+	// ReferencePrefix marks a value as a reference to a vault secret, vault://path#key with the path relative to the mount
+	ReferencePrefix = "vault://"
+
+	vaultSecretMask      = "**********"
+	kubernetesTokenPath  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	vaultRequestTimeout  = 10 * time.Second
+	tokenRenewBeforeTime = 30 * time.Second
+)
+
+type VaultConfigDto struct {
+	Id             int    `json:"id"`
+	Address        string `json:"address" validate:"required"`
+	AuthMethod     string `json:"authMethod" validate:"required"`
+	AuthMount      string `json:"authMount,omitempty"`
+	Token          string `json:"token,omitempty"`
+	RoleId         string `json:"roleId,omitempty"`
+	SecretId       string `json:"secretId,omitempty"`
+	KubernetesRole string `json:"kubernetesRole,omitempty"`
+	Mount          string `json:"mount" validate:"required"`
+	KvVersion      int    `json:"kvVersion"`
+	Namespace      string `json:"namespace,omitempty"`
+	UserId         int32  `json:"-"`
+}
+
+// ExternalSecretEntry is an item of the data of a kubernetes external secret with the vault backend
+type ExternalSecretEntry struct {
+	Key      string `json:"key"`      //path of the secret in vault, including the mount
+	Name     string `json:"name"`     //key of the value in the kubernetes secret
+	Property string `json:"property"` //key of the value in the vault secret
+	IsBinary bool   `json:"isBinary"`
+}
+
+// SecretPreview tells whether a reference resolves, the value itself is masked
+type SecretPreview struct {
+	Reference string `json:"reference"`
+	Resolved  bool   `json:"resolved"`
+	Preview   string `json:"preview,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type VaultService interface {
+	// SaveConfig logs in to vault with the given config before saving it, there is a single active config
+	SaveConfig(request *VaultConfigDto) (*VaultConfigDto, error)
+	GetConfig() (*VaultConfigDto, error)
+	PreviewReferences(references []string) ([]*SecretPreview, error)
+	// ValidateSecretData fails when a reference in secret data does not resolve or secret data mixes references with values,
+	// values are base64 encoded in secret data
+	ValidateSecretData(data json.RawMessage) error
+	// PreviewSecretData returns the previews of the references in secret data by key, nil if there are none
+	PreviewSecretData(data json.RawMessage) map[string]*SecretPreview
+	// ExternalSecretData returns the external secret entries for the references in secret data, nil if there are none.
+	// Secret values are not read, the external secrets controller of the cluster reads them from vault when the secret is applied.
+	ExternalSecretData(data json.RawMessage) ([]*ExternalSecretEntry, error)
+	// ResolveBuildArgs replaces the references in the docker build args json, it reports whether the args had references
+	// so that the resolved args are handed to the build as a secret
+	ResolveBuildArgs(args string) (string, bool, error)
+}
+
+type VaultServiceImpl struct {
+	logger                *zap.SugaredLogger
+	vaultConfigRepository repository.VaultConfigRepository
+	httpClient            *http.Client
+	tokenLock             *sync.Mutex
+	token                 string
+	tokenExpiry           time.Time
+	tokenConfigVersion    string
+}
+
+func NewVaultServiceImpl(logger *zap.SugaredLogger, vaultConfigRepository repository.VaultConfigRepository) *VaultServiceImpl {
+	return &VaultServiceImpl{
+		logger:                logger,
+		vaultConfigRepository: vaultConfigRepository,
+		httpClient:            &http.Client{Timeout: vaultRequestTimeout},
+		tokenLock:             &sync.Mutex{},
+	}
+}
+
+func (impl *VaultServiceImpl) SaveConfig(request *VaultConfigDto) (*VaultConfigDto, error) {
+	existing, err := impl.vaultConfigRepository.FindActive()
+	if err != nil && err != pg.ErrNoRows {
+		impl.logger.Errorw("error in fetching vault config", "err", err)
+		return nil, err
+	}
+	model := &repository.VaultConfig{}
+	if err == nil {
+		model = existing
+	}
+	model.Address = strings.TrimSuffix(request.Address, "/")
+	model.AuthMethod = request.AuthMethod
+	model.AuthMount = strings.Trim(request.AuthMount, "/")
+	model.KubernetesRole = request.KubernetesRole
+	model.RoleId = request.RoleId
+	model.Mount = strings.Trim(request.Mount, "/")
+	model.KvVersion = request.KvVersion
+	model.Namespace = request.Namespace
+	model.Active = true
+	if request.Token != vaultSecretMask {
+		model.Token = request.Token
+	}
+	if request.SecretId != vaultSecretMask {
+		model.SecretId = request.SecretId
+	}
+	if model.KvVersion == 0 {
+		model.KvVersion = 2
+	}
+	if err = impl.validateConfig(model); err != nil {
+		return nil, err
+	}
+	if _, _, err = impl.login(model); err != nil {
+		impl.logger.Errorw("error in login to vault", "address", model.Address, "authMethod", model.AuthMethod, "err", err)
+		return nil, &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: fmt.Sprintf("could not login to vault, %s", err.Error())}
+	}
+	model.UpdatedBy = request.UserId
+	model.UpdatedOn = time.Now()
+	if model.Id > 0 {
+		err = impl.vaultConfigRepository.Update(model)
+	} else {
+		model.CreatedBy = request.UserId
+		model.CreatedOn = time.Now()
+		err = impl.vaultConfigRepository.Save(model)
+	}
+	if err != nil {
+		impl.logger.Errorw("error in saving vault config", "err", err)
+		return nil, err
+	}
+	return impl.adaptConfig(model), nil
+}
+
+func (impl *VaultServiceImpl) validateConfig(model *repository.VaultConfig) error {
+	var message string
+	switch {
+	case !strings.HasPrefix(model.Address, "http://") && !strings.HasPrefix(model.Address, "https://"):
+		message = "address must be an http or https url"
+	case model.AuthMethod == AuthMethodToken && len(model.Token) == 0:
+		message = "token is required for token auth"
+	case model.AuthMethod == AuthMethodAppRole && (len(model.RoleId) == 0 || len(model.SecretId) == 0):
+		message = "roleId and secretId are required for approle auth"
+	case model.AuthMethod == AuthMethodKubernetes && len(model.KubernetesRole) == 0:
+		message = "kubernetesRole is required for kubernetes auth"
+	case model.AuthMethod != AuthMethodToken && model.AuthMethod != AuthMethodAppRole && model.AuthMethod != AuthMethodKubernetes:
+		message = fmt.Sprintf("unsupported auth method %s", model.AuthMethod)
+	case model.KvVersion != 1 && model.KvVersion != 2:
+		message = "kvVersion must be 1 or 2"
+	default:
+		return nil
+	}
+	return &util.ApiError{HttpStatusCode: http.StatusBadRequest, UserMessage: message}
+}
+
+func (impl *VaultServiceImpl) GetConfig() (*VaultConfigDto, error) {
+	model, err := impl.vaultConfigRepository.FindActive()
+	if err != nil {
+		return nil, err
+	}
+	return impl.adaptConfig(model), nil
+}
+
+func (impl *VaultServiceImpl) adaptConfig(model *repository.VaultConfig) *VaultConfigDto {
+	dto := &VaultConfigDto{
+		Id:             model.Id,
+		Address:        model.Address,
+		AuthMethod:     model.AuthMethod,
+		AuthMount:      model.AuthMount,
+		RoleId:         model.RoleId,
+		KubernetesRole: model.KubernetesRole,
+		Mount:          model.Mount,
+		KvVersion:      model.KvVersion,
+		Namespace:      model.Namespace,
+	}
+	if len(model.Token) > 0 {
+		dto.Token = vaultSecretMask
+	}
+	if len(model.SecretId) > 0 {
+		dto.SecretId = vaultSecretMask
+	}
+	return dto
+}
+
+func (impl *VaultServiceImpl) PreviewReferences(references []string) ([]*SecretPreview, error) {
+	resolver := impl.newResolver()
+	previews := make([]*SecretPreview, 0, len(references))
+	for _, reference := range references {
+		previews = append(previews, resolver.preview(reference))
+	}
+	return previews, nil
+}
+
+func (impl *VaultServiceImpl) ValidateSecretData(data json.RawMessage) error {
+	references, err := secretDataReferences(decodeDataMap(data))
+	if err != nil {
+		return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(), UserMessage: err.Error()}
+	}
+	resolver := impl.newResolver()
+	for key, reference := range references {
+		if _, err := resolver.resolve(reference); err != nil {
+			return &util.ApiError{HttpStatusCode: http.StatusBadRequest, InternalMessage: err.Error(),
+				UserMessage: fmt.Sprintf("vault reference %s of key %s could not be resolved, %s", reference, key, err.Error())}
+		}
+	}
+	return nil
+}
+
+func (impl *VaultServiceImpl) PreviewSecretData(data json.RawMessage) map[string]*SecretPreview {
+	dataMap := decodeDataMap(data)
+	var previews map[string]*SecretPreview
+	resolver := impl.newResolver()
+	for key, value := range dataMap {
+		reference, ok := secretDataReference(value)
+		if !ok {
+			continue
+		}
+		if previews == nil {
+			previews = make(map[string]*SecretPreview)
+		}
+		previews[key] = resolver.preview(reference)
+	}
+	return previews
+}
+
+func (impl *VaultServiceImpl) ExternalSecretData(data json.RawMessage) ([]*ExternalSecretEntry, error) {
+	references, err := secretDataReferences(decodeDataMap(data))
+	if err != nil || len(references) == 0 {
+		return nil, err
+	}
+	config, err := impl.vaultConfigRepository.FindActive()
+	if err == pg.ErrNoRows {
+		return nil, fmt.Errorf("vault is not configured")
+	} else if err != nil {
+		impl.logger.Errorw("error in fetching vault config", "err", err)
+		return nil, err
+	}
+	names := make([]string, 0, len(references))
+	for name := range references {
+		names = append(names, name)
+	}
+	// sorted so that the same references always render the same values
+	sort.Strings(names)
+	entries := make([]*ExternalSecretEntry, 0, len(names))
+	for _, name := range names {
+		path, key, err := parseReference(references[name])
+		if err != nil {
+			return nil, fmt.Errorf("vault reference %s of key %s is invalid, %s", references[name], name, err.Error())
+		}
+		entries = append(entries, &ExternalSecretEntry{Key: kvPath(config, path), Name: name, Property: key})
+	}
+	return entries, nil
+}
+
+func (impl *VaultServiceImpl) ResolveBuildArgs(args string) (string, bool, error) {
+	dataMap := decodeDataMap(json.RawMessage(args))
+	resolver := impl.newResolver()
+	found := false
+	for key, value := range dataMap {
+		if !strings.HasPrefix(value, ReferencePrefix) {
+			continue
+		}
+		secret, err := resolver.resolve(value)
+		if err != nil {
+			return "", false, fmt.Errorf("vault reference %s of build arg %s could not be resolved, %s", value, key, err.Error())
+		}
+		dataMap[key] = secret
+		found = true
+	}
+	if !found {
+		return args, false, nil
+	}
+	resolved, err := json.Marshal(dataMap)
+	return string(resolved), true, err
+}
+
+// decodeDataMap returns nil for data which is not a map of strings, such data has no references
+func decodeDataMap(data json.RawMessage) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+	dataMap := make(map[string]string)
+	if err := json.Unmarshal(data, &dataMap); err != nil {
+		return nil
+	}
+	return dataMap
+}
+
+// secretDataReferences returns the references in secret data by key, a secret holding references can not hold values as
+// it is created by the external secrets controller
+func secretDataReferences(dataMap map[string]string) (map[string]string, error) {
+	references := make(map[string]string)
+	for key, value := range dataMap {
+		if reference, ok := secretDataReference(value); ok {
+			references[key] = reference
+		}
+	}
+	if len(references) > 0 && len(references) < len(dataMap) {
+		return nil, fmt.Errorf("a secret with vault references can hold only vault references")
+	}
+	return references, nil
+}
+
+// secretDataReference accepts the reference as it is or base64 encoded, which is how values are stored in secret data
+func secretDataReference(value string) (string, bool) {
+	if strings.HasPrefix(value, ReferencePrefix) {
+		return value, true
+	}
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err == nil && strings.HasPrefix(string(decoded), ReferencePrefix) {
+		return string(decoded), true
+	}
+	return "", false
+}
+
+func parseReference(reference string) (string, string, error) {
+	ref := strings.TrimPrefix(reference, ReferencePrefix)
+	i := strings.LastIndex(ref, "#")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", fmt.Errorf("invalid reference, expected %spath#key", ReferencePrefix)
+	}
+	path := strings.Trim(ref[:i], "/")
+	if strings.ContainsAny(path, "#?%\\") {
+		return "", "", fmt.Errorf("invalid reference, path must not contain #, ?, %% or \\")
+	}
+	// the path is relative to the configured mount and must stay within it
+	for _, segment := range strings.Split(path, "/") {
+		if len(segment) == 0 || segment == "." || segment == ".." {
+			return "", "", fmt.Errorf("invalid reference, path must not contain empty, . or .. segments")
+		}
+	}
+	return path, ref[i+1:], nil
+}
+
+// kvPath is the api path of the secret under the mount, kv v2 serves secret data under data/
+func kvPath(config *repository.VaultConfig, path string) string {
+	if config.KvVersion == 2 {
+		return config.Mount + "/data/" + path
+	}
+	return config.Mount + "/" + path
+}
+
+func maskValue(value string) string {
+	if len(value) < 12 {
+		return vaultSecretMask
+	}
+	return vaultSecretMask + value[len(value)-2:]
+}
+
+// resolver reads each secret path once, the config and token are looked up on first use so that data without
+// references does not need vault to be configured
+type resolver struct {
+	impl    *VaultServiceImpl
+	config  *repository.VaultConfig
+	token   string
+	err     error
+	loaded  bool
+	secrets map[string]map[string]string
+}
+
+func (impl *VaultServiceImpl) newResolver() *resolver {
+	return &resolver{impl: impl, secrets: make(map[string]map[string]string)}
+}
+
+func (r *resolver) resolve(reference string) (string, error) {
+	path, key, err := parseReference(reference)
+	if err != nil {
+		return "", err
+	}
+	if !r.loaded {
+		r.loaded = true
+		r.config, r.err = r.impl.vaultConfigRepository.FindActive()
+		if r.err == pg.ErrNoRows {
+			r.err = fmt.Errorf("vault is not configured")
+		} else if r.err == nil {
+			r.token, r.err = r.impl.getToken(r.config)
+		}
+	}
+	if r.err != nil {
+		return "", r.err
+	}
+	secret, ok := r.secrets[path]
+	if !ok {
+		secret, err = r.impl.readSecret(r.config, r.token, path)
+		if err != nil {
+			return "", err
+		}
+		r.secrets[path] = secret
+	}
+	value, ok := secret[key]
+	if !ok {
+		return "", fmt.Errorf("key %s not found at path %s", key, path)
+	}
+	return value, nil
+}
+
+func (r *resolver) preview(reference string) *SecretPreview {
+	preview := &SecretPreview{Reference: reference}
+	value, err := r.resolve(reference)
+	if err != nil {
+		preview.Error = err.Error()
+		return preview
+	}
+	preview.Resolved = true
+	preview.Preview = maskValue(value)
+	return preview
+}
+
+// getToken reuses the login token till it is about to expire or the config changes
+func (impl *VaultServiceImpl) getToken(config *repository.VaultConfig) (string, error) {
+	impl.tokenLock.Lock()
+	defer impl.tokenLock.Unlock()
+	version := fmt.Sprintf("%d-%d", config.Id, config.UpdatedOn.UnixNano())
+	if len(impl.token) > 0 && impl.tokenConfigVersion == version && time.Now().Before(impl.tokenExpiry) {
+		return impl.token, nil
+	}
+	token, leaseDuration, err := impl.login(config)
+	if err != nil {
+		impl.logger.Errorw("error in login to vault", "address", config.Address, "authMethod", config.AuthMethod, "err", err)
+		return "", err
+	}
+	impl.token = token
+	impl.tokenConfigVersion = version
+	if leaseDuration > 0 {
+		impl.tokenExpiry = time.Now().Add(leaseDuration - tokenRenewBeforeTime)
+	} else {
+		impl.tokenExpiry = time.Now().Add(time.Hour)
+	}
+	return token, nil
+}
+
+type vaultLoginResponse struct {
+	Auth *struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+	} `json:"auth"`
+}
+
+// login returns the client token and its lease duration, token auth is checked by looking the token up
+func (impl *VaultServiceImpl) login(config *repository.VaultConfig) (string, time.Duration, error) {
+	var path string
+	var payload map[string]string
+	switch config.AuthMethod {
+	case AuthMethodToken:
+		_, err := impl.doRequest(config, config.Token, http.MethodGet, "/v1/auth/token/lookup-self", nil)
+		return config.Token, 0, err
+	case AuthMethodAppRole:
+		path = impl.authPath(config, AuthMethodAppRole)
+		payload = map[string]string{"role_id": config.RoleId, "secret_id": config.SecretId}
+	case AuthMethodKubernetes:
+		jwt, err := ioutil.ReadFile(kubernetesTokenPath)
+		if err != nil {
+			return "", 0, fmt.Errorf("could not read service account token, %s", err.Error())
+		}
+		path = impl.authPath(config, AuthMethodKubernetes)
+		payload = map[string]string{"role": config.KubernetesRole, "jwt": strings.TrimSpace(string(jwt))}
+	default:
+		return "", 0, fmt.Errorf("unsupported auth method %s", config.AuthMethod)
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", 0, err
+	}
+	resBody, err := impl.doRequest(config, "", http.MethodPost, path, body)
+	if err != nil {
+		return "", 0, err
+	}
+	response := &vaultLoginResponse{}
+	if err = json.Unmarshal(resBody, response); err != nil {
+		return "", 0, err
+	}
+	if response.Auth == nil || len(response.Auth.ClientToken) == 0 {
+		return "", 0, fmt.Errorf("no token in vault login response")
+	}
+	return response.Auth.ClientToken, time.Duration(response.Auth.LeaseDuration) * time.Second, nil
+}
+
+func (impl *VaultServiceImpl) authPath(config *repository.VaultConfig, method string) string {
+	mount := method
+	if len(config.AuthMount) > 0 {
+		mount = config.AuthMount
+	}
+	return "/v1/auth/" + mount + "/login"
+}
+
+// readSecret returns the key values stored at the path
+func (impl *VaultServiceImpl) readSecret(config *repository.VaultConfig, token string, path string) (map[string]string, error) {
+	resBody, err := impl.doRequest(config, token, http.MethodGet, "/v1/"+kvPath(config, path), nil)
+	if err != nil {
+		return nil, err
+	}
+	return parseSecretResponse(resBody, config.KvVersion, path)
+}
+
+// parseSecretResponse reads the key values of a kv read response, non string values are returned as json
+func parseSecretResponse(resBody []byte, kvVersion int, path string) (map[string]string, error) {
+	response := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(resBody, &response); err != nil {
+		return nil, err
+	}
+	data := response["data"]
+	if kvVersion == 2 && len(data) > 0 && string(data) != "null" {
+		versioned := make(map[string]json.RawMessage)
+		if err := json.Unmarshal(data, &versioned); err != nil {
+			return nil, err
+		}
+		data = versioned["data"]
+	}
+	values := make(map[string]interface{})
+	if len(data) == 0 || string(data) == "null" {
+		return nil, fmt.Errorf("no data found at path %s", path)
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	secret := make(map[string]string)
+	for key, value := range values {
+		if s, ok := value.(string); ok {
+			secret[key] = s
+			continue
+		}
+		valueJson, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		secret[key] = string(valueJson)
+	}
+	return secret, nil
+}
+
+func (impl *VaultServiceImpl) doRequest(config *repository.VaultConfig, token string, method string, path string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, config.Address+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(token) > 0 {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if len(config.Namespace) > 0 {
+		req.Header.Set("X-Vault-Namespace", config.Namespace)
+	}
+	resp, err := impl.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	resBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("path not found in vault")
+	} else if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusUnauthorized {
+		return nil, fmt.Errorf("permission denied by vault")
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("vault responded with status %d", resp.StatusCode)
+	}
+	return resBody, nil
+}
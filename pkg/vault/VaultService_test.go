@@ -0,0 +1,189 @@
+/*
+ * Copyright (c) 2020 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package vault
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/devtron-labs/devtron/internal/sql/repository"
+	"github.com/devtron-labs/devtron/internal/util"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		reference string
+		wantPath  string
+		wantKey   string
+		wantErr   bool
+	}{
+		{name: "path and key", reference: "vault://apps/payments#password", wantPath: "apps/payments", wantKey: "password"},
+		{name: "surrounding slashes are trimmed", reference: "vault:///apps/payments/#password", wantPath: "apps/payments", wantKey: "password"},
+		{name: "hash in path", reference: "vault://apps/payments#db#password", wantErr: true},
+		{name: "missing key", reference: "vault://apps/payments#", wantErr: true},
+		{name: "missing path", reference: "vault://#password", wantErr: true},
+		{name: "no separator", reference: "vault://apps/payments", wantErr: true},
+		{name: "parent segment", reference: "vault://apps/../sys/policy#token", wantErr: true},
+		{name: "parent segment at the start", reference: "vault://../secret/apps#token", wantErr: true},
+		{name: "current segment", reference: "vault://apps/./payments#password", wantErr: true},
+		{name: "empty segment", reference: "vault://apps//payments#password", wantErr: true},
+		{name: "query in path", reference: "vault://apps/payments?version=1#password", wantErr: true},
+		{name: "escaped path", reference: "vault://apps/%2e%2e/payments#password", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, key, err := parseReference(tt.reference)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReference() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if path != tt.wantPath || key != tt.wantKey {
+				t.Errorf("parseReference() = %q, %q, want %q, %q", path, key, tt.wantPath, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestSecretDataReferences(t *testing.T) {
+	encoded := func(value string) string {
+		return base64.StdEncoding.EncodeToString([]byte(value))
+	}
+	tests := []struct {
+		name    string
+		dataMap map[string]string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "no data", want: map[string]string{}},
+		{name: "plain values", dataMap: map[string]string{"user": encoded("admin")}, want: map[string]string{}},
+		{
+			name:    "plain and encoded references",
+			dataMap: map[string]string{"user": "vault://apps/db#user", "password": encoded("vault://apps/db#password")},
+			want:    map[string]string{"user": "vault://apps/db#user", "password": "vault://apps/db#password"},
+		},
+		{
+			name:    "references mixed with values",
+			dataMap: map[string]string{"user": encoded("admin"), "password": "vault://apps/db#password"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := secretDataReferences(tt.dataMap)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("secretDataReferences() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("secretDataReferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSecretResponse(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		kvVersion int
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "kv v1",
+			body:      `{"data":{"user":"admin","port":5432}}`,
+			kvVersion: 1,
+			want:      map[string]string{"user": "admin", "port": "5432"},
+		},
+		{
+			name:      "kv v2",
+			body:      `{"data":{"data":{"user":"admin","tls":{"enabled":true}},"metadata":{"version":3}}}`,
+			kvVersion: 2,
+			want:      map[string]string{"user": "admin", "tls": `{"enabled":true}`},
+		},
+		{name: "kv v2 deleted version", body: `{"data":{"data":null,"metadata":{"version":3}}}`, kvVersion: 2, wantErr: true},
+		{name: "no data", body: `{"data":null}`, kvVersion: 1, wantErr: true},
+		{name: "invalid body", body: `<html>`, kvVersion: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSecretResponse([]byte(tt.body), tt.kvVersion, "apps/db")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseSecretResponse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseSecretResponse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type vaultConfigRepositoryStub struct {
+	repository.VaultConfigRepository
+	config *repository.VaultConfig
+}
+
+func (impl vaultConfigRepositoryStub) FindActive() (*repository.VaultConfig, error) {
+	return impl.config, nil
+}
+
+func TestExternalSecretData(t *testing.T) {
+	tests := []struct {
+		name      string
+		kvVersion int
+		data      string
+		want      []*ExternalSecretEntry
+		wantErr   bool
+	}{
+		{name: "secret without references", kvVersion: 2, data: `{"user":"YWRtaW4="}`},
+		{
+			name:      "kv v2 entries are sorted by name",
+			kvVersion: 2,
+			data:      `{"user":"vault://apps/db#user","password":"vault://apps/db#password"}`,
+			want: []*ExternalSecretEntry{
+				{Key: "secret/data/apps/db", Name: "password", Property: "password"},
+				{Key: "secret/data/apps/db", Name: "user", Property: "user"},
+			},
+		},
+		{
+			name:      "kv v1",
+			kvVersion: 1,
+			data:      `{"user":"vault://apps/db#user"}`,
+			want:      []*ExternalSecretEntry{{Key: "secret/apps/db", Name: "user", Property: "user"}},
+		},
+		{name: "invalid reference", kvVersion: 2, data: `{"user":"vault://apps/../db#user"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			impl := &VaultServiceImpl{
+				logger:                util.NewSugardLogger(),
+				vaultConfigRepository: vaultConfigRepositoryStub{config: &repository.VaultConfig{Mount: "secret", KvVersion: tt.kvVersion}},
+			}
+			got, err := impl.ExternalSecretData([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExternalSecretData() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExternalSecretData() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+DELETE FROM "public"."notification_templates" WHERE event_type_id = 4;
+
+DELETE FROM "public"."event" WHERE id = 4;
+
+SELECT pg_catalog.setval('public.notification_templates_id_seq', 12, true);
+
+DROP TABLE "public"."deployment_approval_user_data" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_approval_user_data;
+
+DROP TABLE "public"."deployment_approval_request" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_approval_request;
+
+DROP TABLE "public"."deployment_approval_config" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_approval_config;
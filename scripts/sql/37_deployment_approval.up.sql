@@ -0,0 +1,96 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_approval_config;
+
+CREATE TABLE "public"."deployment_approval_config" (
+    "id"              int4 NOT NULL DEFAULT nextval('id_seq_deployment_approval_config'::regclass),
+    "pipeline_id"     int4 NOT NULL,
+    "required_count"  int4 NOT NULL DEFAULT 1,
+    "approver_emails" text[],
+    "approver_groups" text[],
+    "active"          bool NOT NULL,
+    "created_on"      timestamptz,
+    "created_by"      int4,
+    "updated_on"      timestamptz,
+    "updated_by"      int4,
+    CONSTRAINT "deployment_approval_config_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_approval_request;
+
+CREATE TABLE "public"."deployment_approval_request" (
+    "id"             int4 NOT NULL DEFAULT nextval('id_seq_deployment_approval_request'::regclass),
+    "pipeline_id"    int4 NOT NULL,
+    "ci_artifact_id" int4 NOT NULL,
+    "status"         varchar(50) NOT NULL,
+    "active"         bool NOT NULL,
+    "created_on"     timestamptz,
+    "created_by"     int4,
+    "updated_on"     timestamptz,
+    "updated_by"     int4,
+    CONSTRAINT "deployment_approval_request_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    CONSTRAINT "deployment_approval_request_ci_artifact_id_fkey" FOREIGN KEY ("ci_artifact_id") REFERENCES "public"."ci_artifact" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS deployment_approval_request_pipeline_artifact_idx ON "public"."deployment_approval_request" ("pipeline_id", "ci_artifact_id");
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_approval_user_data;
+
+CREATE TABLE "public"."deployment_approval_user_data" (
+    "id"                  int4 NOT NULL DEFAULT nextval('id_seq_deployment_approval_user_data'::regclass),
+    "approval_request_id" int4 NOT NULL,
+    "user_id"             int4 NOT NULL,
+    "user_response"       varchar(50) NOT NULL,
+    "comment"             text,
+    "created_on"          timestamptz,
+    "created_by"          int4,
+    "updated_on"          timestamptz,
+    "updated_by"          int4,
+    CONSTRAINT "deployment_approval_user_data_approval_request_id_fkey" FOREIGN KEY ("approval_request_id") REFERENCES "public"."deployment_approval_request" ("id"),
+    CONSTRAINT "deployment_approval_user_data_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id"),
+    PRIMARY KEY ("id")
+);
+
+INSERT INTO "public"."event" ("id", "event_type", "description") VALUES ('4', 'APPROVAL', '');
+
+INSERT INTO "public"."notification_templates" ("id", "channel_type", "node_type", "event_type_id", "template_name", "template_payload") VALUES
+('13', 'slack', 'CD', '4', 'CD approval template', '{
+    "text": ":raised_hand: Deployment approval requested | Application > {{appName}} | Environment > {{envName}}",
+    "blocks": [{
+            "type": "section",
+            "text": {
+                "type": "mrkdwn",
+                "text": ":raised_hand: *Deployment approval requested for {{envName}}*\n{{eventTime}} \n by {{triggeredBy}}"
+            }
+        },
+        {
+            "type": "divider"
+        },
+        {
+            "type": "section",
+            "fields": [{
+                    "type": "mrkdwn",
+                    "text": "*Application*\n{{appName}}\n*Pipeline*\n{{pipelineName}}"
+                },
+                {
+                    "type": "mrkdwn",
+                    "text": "*Environment*\n{{envName}}\n*Approvers*\n{{approvers}}"
+                }
+            ]
+        },
+        {
+            "type": "section",
+            "text": {
+                "type": "mrkdwn",
+                "text": "*Docker Image*\n`{{dockerImg}}`"
+            }
+        }
+    ]
+}'),
+('14', 'ses', 'CD', '4', 'CD approval ses template', '{"from": "{{fromEmail}}",
+ "to": "{{toEmail}}",
+ "subject": "Deployment approval requested for app: {{appName}} on environment: {{environmentName}}",
+ "html": "<b>Deployment approval requested for app: {{appName}} on environment: {{environmentName}}</b> <br> <b>Docker image: {{{dockerImageUrl}}}</b> <br> <b>pipeline: {{pipelineName}}</b> <br> <b>Approvers: {{approvers}}</b>"
+}');
+
+SELECT pg_catalog.setval('public.notification_templates_id_seq', 14, true);
@@ -0,0 +1,7 @@
+DROP TABLE "public"."deployment_window_override_audit" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_window_override_audit;
+
+DROP TABLE "public"."deployment_window" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_window;
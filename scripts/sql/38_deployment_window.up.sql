@@ -0,0 +1,41 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_window;
+
+CREATE TABLE "public"."deployment_window" (
+    "id"               int4 NOT NULL DEFAULT nextval('id_seq_deployment_window'::regclass),
+    "name"             varchar(250) NOT NULL,
+    "environment_id"   int4 NOT NULL,
+    "app_id"           int4,
+    "window_type"      varchar(20) NOT NULL,
+    "cron_expression"  varchar(100),
+    "duration_minutes" int4,
+    "start_time"       timestamptz,
+    "end_time"         timestamptz,
+    "time_zone"        varchar(100) NOT NULL DEFAULT 'UTC',
+    "active"           bool NOT NULL,
+    "created_on"       timestamptz,
+    "created_by"       int4,
+    "updated_on"       timestamptz,
+    "updated_by"       int4,
+    CONSTRAINT "deployment_window_environment_id_fkey" FOREIGN KEY ("environment_id") REFERENCES "public"."environment" ("id"),
+    CONSTRAINT "deployment_window_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_window_override_audit;
+
+CREATE TABLE "public"."deployment_window_override_audit" (
+    "id"                   int4 NOT NULL DEFAULT nextval('id_seq_deployment_window_override_audit'::regclass),
+    "deployment_window_id" int4,
+    "environment_id"       int4 NOT NULL,
+    "pipeline_id"          int4 NOT NULL,
+    "ci_artifact_id"       int4 NOT NULL,
+    "reason"               text,
+    "window_message"       text,
+    "created_on"           timestamptz,
+    "created_by"           int4,
+    "updated_on"           timestamptz,
+    "updated_by"           int4,
+    CONSTRAINT "deployment_window_override_audit_window_id_fkey" FOREIGN KEY ("deployment_window_id") REFERENCES "public"."deployment_window" ("id"),
+    CONSTRAINT "deployment_window_override_audit_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    PRIMARY KEY ("id")
+);
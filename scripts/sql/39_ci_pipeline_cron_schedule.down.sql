@@ -0,0 +1,7 @@
+ALTER TABLE ci_pipeline
+DROP COLUMN IF EXISTS cron_schedule;
+
+ALTER TABLE ci_pipeline
+DROP COLUMN IF EXISTS skip_unchanged_scheduled_build;
+
+DROP TABLE IF EXISTS "public"."scheduled_job_lease";
@@ -0,0 +1,13 @@
+ALTER TABLE ci_pipeline
+ADD COLUMN IF NOT EXISTS cron_schedule varchar(250);
+
+ALTER TABLE ci_pipeline
+ADD COLUMN IF NOT EXISTS skip_unchanged_scheduled_build bool NOT NULL DEFAULT false;
+
+-- claimed by the orchestrator replica running a scheduled job so that every occurrence runs once across replicas
+CREATE TABLE "public"."scheduled_job_lease" (
+    "job_name"     varchar(100) NOT NULL,
+    "leased_by"    varchar(250) NOT NULL,
+    "leased_until" timestamptz NOT NULL,
+    PRIMARY KEY ("job_name")
+);
@@ -0,0 +1,13 @@
+DELETE FROM "public"."notification_templates" WHERE event_type_id = 5;
+
+DELETE FROM "public"."event" WHERE id = 5;
+
+SELECT pg_catalog.setval('public.notification_templates_id_seq', 14, true);
+
+DROP TABLE "public"."deployment_auto_rollback" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_auto_rollback;
+
+DROP TABLE "public"."deployment_auto_rollback_config" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_auto_rollback_config;
@@ -0,0 +1,81 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_auto_rollback_config;
+
+CREATE TABLE "public"."deployment_auto_rollback_config" (
+    "id"                       int4 NOT NULL DEFAULT nextval('id_seq_deployment_auto_rollback_config'::regclass),
+    "pipeline_id"              int4 NOT NULL,
+    "degraded_timeout_minutes" int4 NOT NULL,
+    "active"                   bool NOT NULL,
+    "created_on"               timestamptz,
+    "created_by"               int4,
+    "updated_on"               timestamptz,
+    "updated_by"               int4,
+    CONSTRAINT "deployment_auto_rollback_config_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_auto_rollback;
+
+CREATE TABLE "public"."deployment_auto_rollback" (
+    "id"                             int4 NOT NULL DEFAULT nextval('id_seq_deployment_auto_rollback'::regclass),
+    "pipeline_id"                    int4 NOT NULL,
+    "pipeline_override_id"           int4 NOT NULL,
+    "ci_artifact_id"                 int4 NOT NULL,
+    "status"                         varchar(50) NOT NULL,
+    "degraded_since"                 timestamptz NOT NULL,
+    "rollback_ci_artifact_id"        int4,
+    "rollback_cd_workflow_runner_id" int4,
+    "message"                        text,
+    "created_on"                     timestamptz,
+    "created_by"                     int4,
+    "updated_on"                     timestamptz,
+    "updated_by"                     int4,
+    CONSTRAINT "deployment_auto_rollback_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    CONSTRAINT "deployment_auto_rollback_pipeline_override_id_fkey" FOREIGN KEY ("pipeline_override_id") REFERENCES "public"."pipeline_config_override" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS deployment_auto_rollback_status_idx ON "public"."deployment_auto_rollback" ("status");
+
+INSERT INTO "public"."event" ("id", "event_type", "description") VALUES ('5', 'AUTO_ROLLBACK', '');
+
+INSERT INTO "public"."notification_templates" ("id", "channel_type", "node_type", "event_type_id", "template_name", "template_payload") VALUES
+('15', 'slack', 'CD', '5', 'CD auto rollback template', '{
+    "text": ":rewind: Deployment rolled back automatically | Application > {{appName}} | Environment > {{envName}}",
+    "blocks": [{
+            "type": "section",
+            "text": {
+                "type": "mrkdwn",
+                "text": ":rewind: *Deployment rolled back automatically on {{envName}}*\n{{eventTime}}"
+            }
+        },
+        {
+            "type": "divider"
+        },
+        {
+            "type": "section",
+            "fields": [{
+                    "type": "mrkdwn",
+                    "text": "*Application*\n{{appName}}\n*Pipeline*\n{{pipelineName}}"
+                },
+                {
+                    "type": "mrkdwn",
+                    "text": "*Environment*\n{{envName}}\n*Reason*\n{{rollbackReason}}"
+                }
+            ]
+        },
+        {
+            "type": "section",
+            "text": {
+                "type": "mrkdwn",
+                "text": "*Rolled back to*\n`{{dockerImg}}`"
+            }
+        }
+    ]
+}'),
+('16', 'ses', 'CD', '5', 'CD auto rollback ses template', '{"from": "{{fromEmail}}",
+ "to": "{{toEmail}}",
+ "subject": "Deployment rolled back automatically for app: {{appName}} on environment: {{environmentName}}",
+ "html": "<b>Deployment rolled back automatically for app: {{appName}} on environment: {{environmentName}}</b> <br> <b>Rolled back to: {{{dockerImageUrl}}}</b> <br> <b>pipeline: {{pipelineName}}</b> <br> <b>Reason: {{rollbackReason}}</b>"
+}');
+
+SELECT pg_catalog.setval('public.notification_templates_id_seq', 16, true);
@@ -0,0 +1,9 @@
+DROP TABLE "public"."canary_analysis_step_result" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_canary_analysis_step_result;
+
+ALTER TABLE "public"."cd_workflow_runner" DROP COLUMN IF EXISTS "canary_analysis_status";
+
+DROP TABLE "public"."canary_analysis_metric" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_canary_analysis_metric;
@@ -0,0 +1,40 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_canary_analysis_metric;
+
+CREATE TABLE "public"."canary_analysis_metric" (
+    "id"          int4 NOT NULL DEFAULT nextval('id_seq_canary_analysis_metric'::regclass),
+    "pipeline_id" int4 NOT NULL,
+    "name"        varchar(250) NOT NULL,
+    "query"       text NOT NULL,
+    "operator"    varchar(10) NOT NULL,
+    "threshold"   float8 NOT NULL,
+    "active"      bool NOT NULL,
+    "created_on"  timestamptz,
+    "created_by"  int4,
+    "updated_on"  timestamptz,
+    "updated_by"  int4,
+    CONSTRAINT "canary_analysis_metric_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    PRIMARY KEY ("id")
+);
+
+ALTER TABLE "public"."cd_workflow_runner" ADD COLUMN IF NOT EXISTS "canary_analysis_status" varchar(50);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_canary_analysis_step_result;
+
+CREATE TABLE "public"."canary_analysis_step_result" (
+    "id"                    int4 NOT NULL DEFAULT nextval('id_seq_canary_analysis_step_result'::regclass),
+    "cd_workflow_runner_id" int4 NOT NULL,
+    "step_index"            int4 NOT NULL,
+    "canary_weight"         int4,
+    "passed"                bool NOT NULL,
+    "action"                varchar(50) NOT NULL,
+    "metric_results"        text,
+    "message"               text,
+    "created_on"            timestamptz,
+    "created_by"            int4,
+    "updated_on"            timestamptz,
+    "updated_by"            int4,
+    CONSTRAINT "canary_analysis_step_result_cd_workflow_runner_id_fkey" FOREIGN KEY ("cd_workflow_runner_id") REFERENCES "public"."cd_workflow_runner" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS canary_analysis_step_result_runner_step_idx ON "public"."canary_analysis_step_result" ("cd_workflow_runner_id", "step_index");
@@ -0,0 +1,7 @@
+DROP TABLE "public"."deployment_policy_violation" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_policy_violation;
+
+DROP TABLE "public"."deployment_policy" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_deployment_policy;
@@ -0,0 +1,41 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_policy;
+
+CREATE TABLE "public"."deployment_policy" (
+    "id"         int4 NOT NULL DEFAULT nextval('id_seq_deployment_policy'::regclass),
+    "global"     bool NOT NULL,
+    "cluster_id" int4,
+    "env_id"     int4,
+    "app_id"     int4,
+    "rule"       varchar(100) NOT NULL,
+    "action"     int4 NOT NULL,
+    "config"     text,
+    "deleted"    bool NOT NULL,
+    "created_on" timestamptz,
+    "created_by" int4,
+    "updated_on" timestamptz,
+    "updated_by" int4,
+    CONSTRAINT "deployment_policy_cluster_id_fkey" FOREIGN KEY ("cluster_id") REFERENCES "public"."cluster" ("id"),
+    CONSTRAINT "deployment_policy_env_id_fkey" FOREIGN KEY ("env_id") REFERENCES "public"."environment" ("id"),
+    CONSTRAINT "deployment_policy_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_deployment_policy_violation;
+
+CREATE TABLE "public"."deployment_policy_violation" (
+    "id"                   int4 NOT NULL DEFAULT nextval('id_seq_deployment_policy_violation'::regclass),
+    "pipeline_id"          int4 NOT NULL,
+    "pipeline_override_id" int4,
+    "rule"                 varchar(100) NOT NULL,
+    "action"               int4 NOT NULL,
+    "policy_origin"        varchar(250),
+    "message"              text,
+    "created_on"           timestamptz,
+    "created_by"           int4,
+    "updated_on"           timestamptz,
+    "updated_by"           int4,
+    CONSTRAINT "deployment_policy_violation_pipeline_id_fkey" FOREIGN KEY ("pipeline_id") REFERENCES "public"."pipeline" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS deployment_policy_violation_pipeline_id_idx ON "public"."deployment_policy_violation" ("pipeline_id");
@@ -0,0 +1,3 @@
+DROP TABLE "public"."audit_log" CASCADE;
+
+DROP SEQUENCE IF EXISTS id_seq_audit_log;
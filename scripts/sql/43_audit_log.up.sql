@@ -0,0 +1,22 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_audit_log;
+
+CREATE TABLE "public"."audit_log" (
+    "id"             int4 NOT NULL DEFAULT nextval('id_seq_audit_log'::regclass),
+    "user_id"        int4 NOT NULL,
+    "email_id"       varchar(250),
+    "resource"       varchar(100) NOT NULL,
+    "action"         varchar(50) NOT NULL,
+    "entity_type"    varchar(100) NOT NULL,
+    "entity_id"      varchar(250),
+    "request_method" varchar(10),
+    "request_path"   text,
+    "previous_value" text,
+    "current_value"  text,
+    "diff"           text,
+    "created_on"     timestamptz NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_on_idx ON "public"."audit_log" ("created_on");
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON "public"."audit_log" ("entity_type", "entity_id");
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON "public"."audit_log" ("user_id");
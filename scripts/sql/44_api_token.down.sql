@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "public"."api_token";
+
+DROP SEQUENCE IF EXISTS id_seq_api_token;
+
+ALTER TABLE "public"."users" DROP COLUMN IF EXISTS "user_type";
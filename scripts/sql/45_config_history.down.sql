@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."config_history";
+
+DROP SEQUENCE IF EXISTS id_seq_config_history;
@@ -0,0 +1,20 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_config_history;
+
+CREATE TABLE "public"."config_history" (
+    "id"                   int4 NOT NULL DEFAULT nextval('id_seq_config_history'::regclass),
+    "app_id"               int4 NOT NULL,
+    "env_id"               int4,
+    "config_type"          varchar(50) NOT NULL,
+    "version"              int4 NOT NULL,
+    "data"                 text,
+    "comment"              text,
+    "pipeline_override_id" int4,
+    "created_on"           timestamptz NOT NULL,
+    "created_by"           int4 NOT NULL,
+    CONSTRAINT "config_history_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    CONSTRAINT "config_history_env_id_fkey" FOREIGN KEY ("env_id") REFERENCES "public"."environment" ("id"),
+    CONSTRAINT "config_history_pipeline_override_id_fkey" FOREIGN KEY ("pipeline_override_id") REFERENCES "public"."pipeline_config_override" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS config_history_version_idx ON "public"."config_history" ("app_id", COALESCE("env_id", 0), "config_type", "version");
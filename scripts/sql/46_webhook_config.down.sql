@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."webhook_config";
+
+DROP SEQUENCE IF EXISTS id_seq_webhook_config;
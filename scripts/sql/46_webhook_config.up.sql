@@ -0,0 +1,18 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_webhook_config;
+
+CREATE TABLE "public"."webhook_config" (
+    "id"           int4 NOT NULL DEFAULT nextval('id_seq_webhook_config'::regclass),
+    "web_hook_url" varchar(500) NOT NULL,
+    "config_name"  varchar(250) NOT NULL,
+    "header"       text,
+    "payload"      text,
+    "secret"       text,
+    "description"  varchar(500),
+    "owner_id"     int4,
+    "deleted"      bool NOT NULL DEFAULT false,
+    "created_on"   timestamptz NOT NULL,
+    "created_by"   int4 NOT NULL,
+    "updated_on"   timestamptz NOT NULL,
+    "updated_by"   int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."smtp_config";
+
+DROP SEQUENCE IF EXISTS id_seq_smtp_config;
@@ -0,0 +1,21 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_smtp_config;
+
+CREATE TABLE "public"."smtp_config" (
+    "id"            int4 NOT NULL DEFAULT nextval('id_seq_smtp_config'::regclass),
+    "host"          varchar(250) NOT NULL,
+    "port"          varchar(10) NOT NULL,
+    "tls_mode"      varchar(20) NOT NULL,
+    "auth_user"     varchar(250),
+    "auth_password" text,
+    "from_email"    varchar(250) NOT NULL,
+    "config_name"   varchar(250) NOT NULL,
+    "description"   varchar(500),
+    "owner_id"      int4,
+    "default"       bool NOT NULL DEFAULT false,
+    "deleted"       bool NOT NULL DEFAULT false,
+    "created_on"    timestamptz NOT NULL,
+    "created_by"    int4 NOT NULL,
+    "updated_on"    timestamptz NOT NULL,
+    "updated_by"    int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
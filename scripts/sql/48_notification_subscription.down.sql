@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "public"."notification_digest_event";
+
+DROP SEQUENCE IF EXISTS id_seq_notification_digest_event;
+
+DROP TABLE IF EXISTS "public"."notification_subscription";
+
+DROP SEQUENCE IF EXISTS id_seq_notification_subscription;
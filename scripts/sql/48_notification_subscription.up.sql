@@ -0,0 +1,40 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_notification_subscription;
+
+CREATE TABLE "public"."notification_subscription" (
+    "id"             int4 NOT NULL DEFAULT nextval('id_seq_notification_subscription'::regclass),
+    "user_id"        int4 NOT NULL,
+    "app_id"         int4 NOT NULL,
+    "env_id"         int4,
+    "pipeline_type"  varchar(10),
+    "event_type_ids" int4[] NOT NULL,
+    "digest"         bool NOT NULL DEFAULT false,
+    "active"         bool NOT NULL DEFAULT true,
+    "created_on"     timestamptz NOT NULL,
+    "created_by"     int4 NOT NULL,
+    "updated_on"     timestamptz NOT NULL,
+    "updated_by"     int4 NOT NULL,
+    CONSTRAINT "notification_subscription_user_id_fkey" FOREIGN KEY ("user_id") REFERENCES "public"."users" ("id"),
+    CONSTRAINT "notification_subscription_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    CONSTRAINT "notification_subscription_env_id_fkey" FOREIGN KEY ("env_id") REFERENCES "public"."environment" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS notification_subscription_app_id_idx ON "public"."notification_subscription" ("app_id") WHERE "active" = true;
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_notification_digest_event;
+
+CREATE TABLE "public"."notification_digest_event" (
+    "id"              int4 NOT NULL DEFAULT nextval('id_seq_notification_digest_event'::regclass),
+    "subscription_id" int4 NOT NULL,
+    "user_id"         int4 NOT NULL,
+    "app_id"          int4 NOT NULL,
+    "event_type_id"   int4 NOT NULL,
+    "summary"         text NOT NULL,
+    "link"            text,
+    "sent"            bool NOT NULL DEFAULT false,
+    "created_on"      timestamptz NOT NULL,
+    CONSTRAINT "notification_digest_event_subscription_id_fkey" FOREIGN KEY ("subscription_id") REFERENCES "public"."notification_subscription" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS notification_digest_event_pending_idx ON "public"."notification_digest_event" ("user_id") WHERE "sent" = false;
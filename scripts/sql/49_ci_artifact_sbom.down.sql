@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS "public"."ci_artifact_sbom_component";
+
+DROP SEQUENCE IF EXISTS id_seq_ci_artifact_sbom_component;
+
+DROP TABLE IF EXISTS "public"."ci_artifact_sbom";
+
+DROP SEQUENCE IF EXISTS id_seq_ci_artifact_sbom;
+
+ALTER TABLE ci_pipeline
+DROP COLUMN IF EXISTS sbom_enabled;
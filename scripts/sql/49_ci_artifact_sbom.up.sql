@@ -0,0 +1,39 @@
+ALTER TABLE ci_pipeline
+ADD COLUMN IF NOT EXISTS sbom_enabled bool NOT NULL DEFAULT false;
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_ci_artifact_sbom;
+
+CREATE TABLE "public"."ci_artifact_sbom" (
+    "id"              int4 NOT NULL DEFAULT nextval('id_seq_ci_artifact_sbom'::regclass),
+    "ci_artifact_id"  int4 NOT NULL,
+    "image_digest"    text NOT NULL,
+    "format"          varchar(50) NOT NULL,
+    "spec_version"    varchar(50),
+    "document"        text NOT NULL,
+    "component_count" int4 NOT NULL DEFAULT 0,
+    "created_on"      timestamptz NOT NULL,
+    "created_by"      int4 NOT NULL,
+    "updated_on"      timestamptz NOT NULL,
+    "updated_by"      int4 NOT NULL,
+    CONSTRAINT "ci_artifact_sbom_ci_artifact_id_fkey" FOREIGN KEY ("ci_artifact_id") REFERENCES "public"."ci_artifact" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS ci_artifact_sbom_image_digest_idx ON "public"."ci_artifact_sbom" ("image_digest");
+
+CREATE INDEX IF NOT EXISTS ci_artifact_sbom_ci_artifact_id_idx ON "public"."ci_artifact_sbom" ("ci_artifact_id");
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_ci_artifact_sbom_component;
+
+CREATE TABLE "public"."ci_artifact_sbom_component" (
+    "id"      int4 NOT NULL DEFAULT nextval('id_seq_ci_artifact_sbom_component'::regclass),
+    "sbom_id" int4 NOT NULL,
+    "name"    text NOT NULL,
+    "version" text,
+    "purl"    text,
+    "type"    varchar(50),
+    CONSTRAINT "ci_artifact_sbom_component_sbom_id_fkey" FOREIGN KEY ("sbom_id") REFERENCES "public"."ci_artifact_sbom" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS ci_artifact_sbom_component_name_idx ON "public"."ci_artifact_sbom_component" (lower("name"), "version");
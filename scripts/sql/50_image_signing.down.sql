@@ -0,0 +1,15 @@
+ALTER TABLE cd_workflow_runner
+DROP COLUMN IF EXISTS image_signature_message;
+
+ALTER TABLE cd_workflow_runner
+DROP COLUMN IF EXISTS image_signature_status;
+
+DROP TABLE IF EXISTS "public"."image_signature_policy";
+
+DROP SEQUENCE IF EXISTS id_seq_image_signature_policy;
+
+DROP TABLE IF EXISTS "public"."image_signature";
+
+DROP SEQUENCE IF EXISTS id_seq_image_signature;
+
+DROP TABLE IF EXISTS "public"."image_signing_key";
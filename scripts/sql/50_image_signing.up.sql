@@ -0,0 +1,60 @@
+CREATE TABLE "public"."image_signing_key" (
+    "id"          varchar(250) NOT NULL,
+    "algorithm"   varchar(50) NOT NULL,
+    "public_key"  text NOT NULL,
+    "private_key" text NOT NULL,
+    "is_default"  bool NOT NULL DEFAULT false,
+    "active"      bool NOT NULL DEFAULT true,
+    "created_on"  timestamptz NOT NULL,
+    "created_by"  int4 NOT NULL,
+    "updated_on"  timestamptz NOT NULL,
+    "updated_by"  int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_image_signature;
+
+CREATE TABLE "public"."image_signature" (
+    "id"             int4 NOT NULL DEFAULT nextval('id_seq_image_signature'::regclass),
+    "ci_artifact_id" int4 NOT NULL,
+    "key_id"         varchar(250) NOT NULL,
+    "image"          text NOT NULL,
+    "image_digest"   text NOT NULL,
+    "signature"      text NOT NULL,
+    "created_on"     timestamptz NOT NULL,
+    "created_by"     int4 NOT NULL,
+    "updated_on"     timestamptz NOT NULL,
+    "updated_by"     int4 NOT NULL,
+    CONSTRAINT "image_signature_ci_artifact_id_fkey" FOREIGN KEY ("ci_artifact_id") REFERENCES "public"."ci_artifact" ("id"),
+    CONSTRAINT "image_signature_key_id_fkey" FOREIGN KEY ("key_id") REFERENCES "public"."image_signing_key" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS image_signature_ci_artifact_id_idx ON "public"."image_signature" ("ci_artifact_id");
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_image_signature_policy;
+
+CREATE TABLE "public"."image_signature_policy" (
+    "id"         int4 NOT NULL DEFAULT nextval('id_seq_image_signature_policy'::regclass),
+    "global"     bool NOT NULL DEFAULT false,
+    "cluster_id" int4,
+    "env_id"     int4,
+    "app_id"     int4,
+    "action"     int4 NOT NULL,
+    "key_ids"    text[],
+    "deleted"    bool NOT NULL DEFAULT false,
+    "created_on" timestamptz NOT NULL,
+    "created_by" int4 NOT NULL,
+    "updated_on" timestamptz NOT NULL,
+    "updated_by" int4 NOT NULL,
+    CONSTRAINT "image_signature_policy_cluster_id_fkey" FOREIGN KEY ("cluster_id") REFERENCES "public"."cluster" ("id"),
+    CONSTRAINT "image_signature_policy_env_id_fkey" FOREIGN KEY ("env_id") REFERENCES "public"."environment" ("id"),
+    CONSTRAINT "image_signature_policy_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    PRIMARY KEY ("id")
+);
+
+ALTER TABLE cd_workflow_runner
+ADD COLUMN IF NOT EXISTS image_signature_status varchar(50);
+
+ALTER TABLE cd_workflow_runner
+ADD COLUMN IF NOT EXISTS image_signature_message text;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."cve_exception";
+
+DROP SEQUENCE IF EXISTS id_seq_cve_exception;
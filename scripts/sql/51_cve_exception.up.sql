@@ -0,0 +1,24 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_cve_exception;
+
+CREATE TABLE "public"."cve_exception" (
+    "id"              int4 NOT NULL DEFAULT nextval('id_seq_cve_exception'::regclass),
+    "cve_store_name"  varchar(255) NOT NULL,
+    "app_id"          int4,
+    "env_id"          int4 NOT NULL,
+    "justification"   text NOT NULL,
+    "status"          varchar(20) NOT NULL,
+    "approved_by"     int4,
+    "approved_on"     timestamptz,
+    "expires_on"      timestamptz NOT NULL,
+    "expiry_notified" bool NOT NULL DEFAULT false,
+    "created_on"      timestamptz NOT NULL,
+    "created_by"      int4 NOT NULL,
+    "updated_on"      timestamptz NOT NULL,
+    "updated_by"      int4 NOT NULL,
+    CONSTRAINT "cve_exception_cve_store_name_fkey" FOREIGN KEY ("cve_store_name") REFERENCES "public"."cve_store" ("name"),
+    CONSTRAINT "cve_exception_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    CONSTRAINT "cve_exception_env_id_fkey" FOREIGN KEY ("env_id") REFERENCES "public"."environment" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS cve_exception_env_id_idx ON "public"."cve_exception" ("env_id") WHERE "status" = 'APPROVED';
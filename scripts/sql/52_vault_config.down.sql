@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "public"."vault_config";
+
+DROP SEQUENCE IF EXISTS id_seq_vault_config;
@@ -0,0 +1,21 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_vault_config;
+
+CREATE TABLE "public"."vault_config" (
+    "id"              int4 NOT NULL DEFAULT nextval('id_seq_vault_config'::regclass),
+    "address"         varchar(250) NOT NULL,
+    "auth_method"     varchar(50) NOT NULL,
+    "auth_mount"      varchar(250),
+    "token"           text,
+    "role_id"         varchar(250),
+    "secret_id"       text,
+    "kubernetes_role" varchar(250),
+    "mount"           varchar(250) NOT NULL,
+    "kv_version"      int4 NOT NULL DEFAULT 2,
+    "namespace"       varchar(250),
+    "active"          bool NOT NULL DEFAULT true,
+    "created_on"      timestamptz NOT NULL,
+    "created_by"      int4 NOT NULL,
+    "updated_on"      timestamptz NOT NULL,
+    "updated_by"      int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
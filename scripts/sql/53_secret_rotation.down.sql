@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "public"."secret_rotation_pipeline";
+
+DROP SEQUENCE IF EXISTS id_seq_secret_rotation_pipeline;
+
+DROP TABLE IF EXISTS "public"."secret_rotation_job";
+
+DROP SEQUENCE IF EXISTS id_seq_secret_rotation_job;
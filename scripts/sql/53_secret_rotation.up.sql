@@ -0,0 +1,39 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_secret_rotation_job;
+
+CREATE TABLE "public"."secret_rotation_job" (
+    "id"          int4 NOT NULL DEFAULT nextval('id_seq_secret_rotation_job'::regclass),
+    "secret_name" varchar(250) NOT NULL,
+    "secret_key"  varchar(250) NOT NULL,
+    "status"      varchar(50) NOT NULL,
+    "message"     text,
+    "created_on"  timestamptz NOT NULL,
+    "created_by"  int4 NOT NULL,
+    "updated_on"  timestamptz NOT NULL,
+    "updated_by"  int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_secret_rotation_pipeline;
+
+CREATE TABLE "public"."secret_rotation_pipeline" (
+    "id"                    int4 NOT NULL DEFAULT nextval('id_seq_secret_rotation_pipeline'::regclass),
+    "job_id"                int4 NOT NULL,
+    "app_id"                int4 NOT NULL,
+    "env_id"                int4 NOT NULL,
+    "pipeline_id"           int4,
+    "ci_artifact_id"        int4,
+    "pipeline_override_id"  int4,
+    "config_level"          varchar(50),
+    "status"                varchar(50) NOT NULL,
+    "message"               text,
+    "created_on"            timestamptz NOT NULL,
+    "created_by"            int4 NOT NULL,
+    "updated_on"            timestamptz NOT NULL,
+    "updated_by"            int4 NOT NULL,
+    CONSTRAINT "secret_rotation_pipeline_job_id_fkey" FOREIGN KEY ("job_id") REFERENCES "public"."secret_rotation_job" ("id"),
+    CONSTRAINT "secret_rotation_pipeline_app_id_fkey" FOREIGN KEY ("app_id") REFERENCES "public"."app" ("id"),
+    CONSTRAINT "secret_rotation_pipeline_env_id_fkey" FOREIGN KEY ("env_id") REFERENCES "public"."environment" ("id"),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX IF NOT EXISTS secret_rotation_pipeline_job_id_idx ON "public"."secret_rotation_pipeline" ("job_id");
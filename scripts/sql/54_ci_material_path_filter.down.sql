@@ -0,0 +1,3 @@
+ALTER TABLE "public"."ci_pipeline_material" DROP COLUMN IF EXISTS "include_paths";
+
+ALTER TABLE "public"."ci_pipeline_material" DROP COLUMN IF EXISTS "exclude_paths";
@@ -0,0 +1,3 @@
+ALTER TABLE "public"."ci_pipeline_material" ADD COLUMN IF NOT EXISTS "include_paths" text[];
+
+ALTER TABLE "public"."ci_pipeline_material" ADD COLUMN IF NOT EXISTS "exclude_paths" text[];
@@ -0,0 +1,3 @@
+ALTER TABLE "public"."ci_template" DROP COLUMN IF EXISTS "target_platform";
+
+ALTER TABLE "public"."ci_artifact" DROP COLUMN IF EXISTS "platform_digests";
@@ -0,0 +1,3 @@
+ALTER TABLE "public"."ci_template" ADD COLUMN IF NOT EXISTS "target_platform" varchar(1000);
+
+ALTER TABLE "public"."ci_artifact" ADD COLUMN IF NOT EXISTS "platform_digests" jsonb;
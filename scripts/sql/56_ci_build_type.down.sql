@@ -0,0 +1,3 @@
+ALTER TABLE "public"."ci_template" DROP COLUMN IF EXISTS "builder_image";
+
+ALTER TABLE "public"."ci_template" DROP COLUMN IF EXISTS "build_type";
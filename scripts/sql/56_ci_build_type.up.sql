@@ -0,0 +1,3 @@
+ALTER TABLE "public"."ci_template" ADD COLUMN IF NOT EXISTS "build_type" varchar(50) NOT NULL DEFAULT 'DOCKERFILE';
+
+ALTER TABLE "public"."ci_template" ADD COLUMN IF NOT EXISTS "builder_image" varchar(250);
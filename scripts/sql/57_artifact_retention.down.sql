@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "public"."artifact_retention_run";
+
+DROP SEQUENCE IF EXISTS id_seq_artifact_retention_run;
+
+DROP TABLE IF EXISTS "public"."artifact_retention_policy";
+
+DROP SEQUENCE IF EXISTS id_seq_artifact_retention_policy;
@@ -0,0 +1,37 @@
+CREATE SEQUENCE IF NOT EXISTS id_seq_artifact_retention_policy;
+
+-- app_id 0 is the global policy, an app policy replaces it for the pipelines of that app
+CREATE TABLE "public"."artifact_retention_policy" (
+    "id"                  int4 NOT NULL DEFAULT nextval('id_seq_artifact_retention_policy'::regclass),
+    "app_id"              int4 NOT NULL DEFAULT 0,
+    "keep_artifact_count" int4 NOT NULL DEFAULT 0,
+    "log_retention_days"  int4 NOT NULL DEFAULT 0,
+    "active"              bool NOT NULL,
+    "created_on"          timestamptz NOT NULL,
+    "created_by"          int4 NOT NULL,
+    "updated_on"          timestamptz NOT NULL,
+    "updated_by"          int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS artifact_retention_policy_app_id_uniq ON "public"."artifact_retention_policy" ("app_id") WHERE "active" = true;
+
+CREATE SEQUENCE IF NOT EXISTS id_seq_artifact_retention_run;
+
+CREATE TABLE "public"."artifact_retention_run" (
+    "id"                int4 NOT NULL DEFAULT nextval('id_seq_artifact_retention_run'::regclass),
+    "status"            varchar(50) NOT NULL,
+    "dry_run"           bool NOT NULL DEFAULT false,
+    "artifacts_deleted" int4 NOT NULL DEFAULT 0,
+    "logs_deleted"      int4 NOT NULL DEFAULT 0,
+    "failed_count"      int4 NOT NULL DEFAULT 0,
+    "report"            jsonb,
+    "message"           text,
+    "started_on"        timestamptz NOT NULL,
+    "finished_on"       timestamptz,
+    "created_on"        timestamptz NOT NULL,
+    "created_by"        int4 NOT NULL,
+    "updated_on"        timestamptz NOT NULL,
+    "updated_by"        int4 NOT NULL,
+    PRIMARY KEY ("id")
+);
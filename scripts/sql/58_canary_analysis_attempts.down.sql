@@ -0,0 +1 @@
+ALTER TABLE "public"."canary_analysis_step_result" DROP COLUMN IF EXISTS "attempts";
@@ -0,0 +1,2 @@
+-- a step is re-evaluated while its metrics are inconclusive, attempts counts the evaluations of the step
+ALTER TABLE "public"."canary_analysis_step_result" ADD COLUMN IF NOT EXISTS "attempts" int4 NOT NULL DEFAULT 1;
@@ -0,0 +1,5 @@
+DELETE FROM "public"."notification_templates" WHERE event_type_id = 6;
+
+DELETE FROM "public"."event" WHERE id = 6;
+
+SELECT pg_catalog.setval('public.notification_templates_id_seq', 16, true);
@@ -0,0 +1,43 @@
+INSERT INTO "public"."event" ("id", "event_type", "description") VALUES ('6', 'VULNERABILITY_FOUND', '');
+
+INSERT INTO "public"."notification_templates" ("id", "channel_type", "node_type", "event_type_id", "template_name", "template_payload") VALUES
+('17', 'slack', 'CD', '6', 'CD vulnerability found template', '{
+    "text": ":warning: New vulnerabilities found on deployed image | Application > {{appName}} | Environment > {{envName}}",
+    "blocks": [{
+            "type": "section",
+            "text": {
+                "type": "mrkdwn",
+                "text": ":warning: *New vulnerabilities found on the image deployed to {{envName}}*\n{{eventTime}}"
+            }
+        },
+        {
+            "type": "divider"
+        },
+        {
+            "type": "section",
+            "fields": [{
+                    "type": "mrkdwn",
+                    "text": "*Application*\n{{appName}}\n*Pipeline*\n{{pipelineName}}"
+                },
+                {
+                    "type": "mrkdwn",
+                    "text": "*Environment*\n{{envName}}\n*Blocked CVEs*\n{{blockedCves}}"
+                }
+            ]
+        },
+        {
+            "type": "section",
+            "text": {
+                "type": "mrkdwn",
+                "text": "*Docker Image*\n`{{dockerImg}}`"
+            }
+        }
+    ]
+}'),
+('18', 'ses', 'CD', '6', 'CD vulnerability found ses template', '{"from": "{{fromEmail}}",
+ "to": "{{toEmail}}",
+ "subject": "New vulnerabilities found for app: {{appName}} on environment: {{environmentName}}",
+ "html": "<b>New vulnerabilities found on the image deployed for app: {{appName}} on environment: {{environmentName}}</b> <br> <b>Docker image: {{{dockerImageUrl}}}</b> <br> <b>pipeline: {{pipelineName}}</b> <br> <b>Blocked CVEs: {{blockedCves}}</b>"
+}');
+
+SELECT pg_catalog.setval('public.notification_templates_id_seq', 18, true);
@@ -22,6 +22,9 @@ type EventType int
 const Trigger EventType = 1
 const Success EventType = 2
 const Fail EventType = 3
+const Approval EventType = 4
+const AutoRollback EventType = 5
+const VulnerabilityFound EventType = 6
 
 type PipelineType string
 
@@ -33,8 +36,10 @@ type Level string
 type Channel string
 
 const (
-	Slack Channel = "slack"
-	SES   Channel = "ses"
+	Slack   Channel = "slack"
+	SES     Channel = "ses"
+	Webhook Channel = "webhook"
+	SMTP    Channel = "smtp"
 )
 
 type UpdateType string